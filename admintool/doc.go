@@ -0,0 +1,3 @@
+// Package admintool provides an opt-in HTTP debug and operations surface for a
+// recourse executor.
+package admintool
@@ -0,0 +1,320 @@
+package admintool
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultOverrideTTL is how long a MaxAttempts override set without a ttl lasts.
+const DefaultOverrideTTL = 15 * time.Minute
+
+// Handler returns an http.Handler exposing exec's runtime state as JSON:
+//
+//	GET    /policies[?key=]          effective policy per key the executor has called
+//	GET    /circuits                 circuit breaker state per key
+//	POST   /circuits/open?key=       force a key's circuit open
+//	GET    /budgets                  registered budgets and, for token buckets, tokens left
+//	GET    /overrides                active MaxAttempts overrides
+//	POST   /overrides?key=&max_attempts=[&ttl=]   override MaxAttempts (default ttl 15m)
+//	DELETE /overrides?key=           remove an override
+//	GET    /timelines[?key=&limit=]  recent call timelines, newest first
+//
+// Timelines are served when the executor's observer is, or includes (via
+// MultiObserver), an *observe.TimelineBuffer.
+//
+// The handler can change retry behavior; mount it only on an internal, authenticated
+// listener, under a prefix with http.StripPrefix.
+func Handler(exec *retry.Executor) http.Handler {
+	h := &handler{exec: exec, timelines: findTimelineBuffer(exec.Observer())}
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /policies", h.policies)
+	mux.HandleFunc("GET /circuits", h.circuits)
+	mux.HandleFunc("POST /circuits/open", h.openCircuit)
+	mux.HandleFunc("GET /budgets", h.budgets)
+	mux.HandleFunc("GET /overrides", h.overrides)
+	mux.HandleFunc("POST /overrides", h.setOverride)
+	mux.HandleFunc("DELETE /overrides", h.clearOverride)
+	mux.HandleFunc("GET /timelines", h.recentTimelines)
+	return mux
+}
+
+type handler struct {
+	exec      *retry.Executor
+	timelines *observe.TimelineBuffer
+}
+
+func findTimelineBuffer(obs observe.Observer) *observe.TimelineBuffer {
+	switch o := obs.(type) {
+	case *observe.TimelineBuffer:
+		return o
+	case observe.MultiObserver:
+		for _, m := range o.Observers {
+			if b := findTimelineBuffer(m); b != nil {
+				return b
+			}
+		}
+	case *observe.MultiObserver:
+		return findTimelineBuffer(*o)
+	}
+	return nil
+}
+
+type policyView struct {
+	Key    string                 `json:"key"`
+	Source policy.PolicySource    `json:"source,omitempty"`
+	Policy policy.EffectivePolicy `json:"policy"`
+	Error  string                 `json:"error,omitempty"`
+}
+
+func (h *handler) policies(w http.ResponseWriter, r *http.Request) {
+	keys := h.exec.Keys()
+	if raw := strings.TrimSpace(r.URL.Query().Get("key")); raw != "" {
+		keys = []policy.PolicyKey{policy.ParseKey(raw)}
+	}
+	out := make([]policyView, 0, len(keys))
+	for _, key := range keys {
+		pol, err := h.exec.EffectivePolicy(r.Context(), key)
+		v := policyView{Key: key.String(), Source: pol.Meta.Source, Policy: pol}
+		if err != nil {
+			v.Error = err.Error()
+		}
+		out = append(out, v)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+type circuitView struct {
+	Key   string `json:"key"`
+	State string `json:"state"`
+}
+
+func (h *handler) circuits(w http.ResponseWriter, _ *http.Request) {
+	reg := h.exec.Circuits()
+	out := []circuitView{}
+	for _, key := range reg.Keys() {
+		if cb, ok := reg.Lookup(key); ok {
+			out = append(out, circuitView{Key: key.String(), State: cb.State().String()})
+		}
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) openCircuit(w http.ResponseWriter, r *http.Request) {
+	key, ok := requireKey(w, r)
+	if !ok {
+		return
+	}
+	cb, ok := h.exec.Circuits().Lookup(key)
+	if !ok {
+		writeError(w, http.StatusNotFound, "no circuit for key (circuit disabled or not yet called)")
+		return
+	}
+	opener, ok := cb.(interface{ ForceOpen() })
+	if !ok {
+		writeError(w, http.StatusNotImplemented, fmt.Sprintf("circuit breaker %T cannot be forced open", cb))
+		return
+	}
+	opener.ForceOpen()
+	writeJSON(w, http.StatusOK, circuitView{Key: key.String(), State: cb.State().String()})
+}
+
+type budgetView struct {
+	Name      string   `json:"name"`
+	Type      string   `json:"type"`
+	Available *float64 `json:"available,omitempty"`
+	Capacity  *float64 `json:"capacity,omitempty"`
+}
+
+func (h *handler) budgets(w http.ResponseWriter, _ *http.Request) {
+	reg := h.exec.Budgets()
+	out := []budgetView{}
+	for _, name := range reg.Names() {
+		b, ok := reg.Get(name)
+		if !ok {
+			continue
+		}
+		v := budgetView{Name: name, Type: fmt.Sprintf("%T", b)}
+		if tb, ok := b.(*budget.TokenBucketBudget); ok {
+			available, capacity := tb.Tokens()
+			v.Available, v.Capacity = &available, &capacity
+		}
+		out = append(out, v)
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) overrides(w http.ResponseWriter, _ *http.Request) {
+	out := make(map[string]retry.KeyOverride)
+	for k, o := range h.exec.KeyOverrides() {
+		out[k.String()] = o
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func (h *handler) setOverride(w http.ResponseWriter, r *http.Request) {
+	key, ok := requireKey(w, r)
+	if !ok {
+		return
+	}
+	q := r.URL.Query()
+	n, err := strconv.Atoi(q.Get("max_attempts"))
+	if err != nil || n < 1 {
+		writeError(w, http.StatusBadRequest, "max_attempts must be a positive integer")
+		return
+	}
+	ttl := DefaultOverrideTTL
+	if raw := q.Get("ttl"); raw != "" {
+		if ttl, err = time.ParseDuration(raw); err != nil || ttl <= 0 {
+			writeError(w, http.StatusBadRequest, "ttl must be a positive duration")
+			return
+		}
+	}
+	o := retry.KeyOverride{MaxAttempts: n, Until: time.Now().Add(ttl)}
+	h.exec.SetKeyOverride(key, o)
+	writeJSON(w, http.StatusOK, map[string]retry.KeyOverride{key.String(): o})
+}
+
+func (h *handler) clearOverride(w http.ResponseWriter, r *http.Request) {
+	key, ok := requireKey(w, r)
+	if !ok {
+		return
+	}
+	h.exec.ClearKeyOverride(key)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+type timelineView struct {
+	Key        string            `json:"key"`
+	PolicyID   string            `json:"policy_id,omitempty"`
+	Start      time.Time         `json:"start"`
+	Duration   string            `json:"duration"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Attempts   []attemptView     `json:"attempts"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type attemptView struct {
+	Attempt  int    `json:"attempt"`
+	Hedge    bool   `json:"hedge,omitempty"`
+	Outcome  string `json:"outcome"`
+	Reason   string `json:"reason,omitempty"`
+	Duration string `json:"duration"`
+	Backoff  string `json:"backoff,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+func (h *handler) recentTimelines(w http.ResponseWriter, r *http.Request) {
+	if h.timelines == nil {
+		writeError(w, http.StatusNotFound, "no observe.TimelineBuffer among the executor's observers")
+		return
+	}
+	q := r.URL.Query()
+	limit := -1
+	if raw := q.Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a non-negative integer")
+			return
+		}
+		limit = n
+	}
+	var filter *policy.PolicyKey
+	if raw := strings.TrimSpace(q.Get("key")); raw != "" {
+		k := policy.ParseKey(raw)
+		filter = &k
+	}
+
+	out := []timelineView{}
+	for _, tl := range h.timelines.Timelines() {
+		if limit >= 0 && len(out) >= limit {
+			break
+		}
+		if filter != nil && tl.Key != *filter {
+			continue
+		}
+		out = append(out, viewTimeline(tl))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func viewTimeline(tl observe.Timeline) timelineView {
+	v := timelineView{
+		Key:        tl.Key.String(),
+		PolicyID:   tl.PolicyID,
+		Start:      tl.Start,
+		Duration:   tl.Duration.String(),
+		Attributes: tl.Attributes,
+		Attempts:   make([]attemptView, 0, len(tl.Attempts)),
+		Error:      errString(tl.FinalErr),
+	}
+	for _, a := range tl.Attempts {
+		av := attemptView{
+			Attempt:  a.Attempt,
+			Hedge:    a.IsHedge,
+			Outcome:  outcomeKind(a.Outcome.Kind),
+			Reason:   a.Outcome.Reason,
+			Duration: a.Duration.String(),
+			Error:    errString(a.Err),
+		}
+		if a.Backoff > 0 {
+			av.Backoff = a.Backoff.String()
+		}
+		v.Attempts = append(v.Attempts, av)
+	}
+	return v
+}
+
+func outcomeKind(k classify.OutcomeKind) string {
+	switch k {
+	case classify.OutcomeSuccess:
+		return "success"
+	case classify.OutcomeRetryable:
+		return "retryable"
+	case classify.OutcomeNonRetryable:
+		return "non_retryable"
+	case classify.OutcomeAbort:
+		return "abort"
+	case classify.OutcomePartial:
+		return "partial"
+	case classify.OutcomeThrottled:
+		return "throttled"
+	default:
+		return "unknown"
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
+
+func requireKey(w http.ResponseWriter, r *http.Request) (policy.PolicyKey, bool) {
+	raw := strings.TrimSpace(r.URL.Query().Get("key"))
+	if raw == "" {
+		writeError(w, http.StatusBadRequest, "key is required")
+		return policy.PolicyKey{}, false
+	}
+	return policy.ParseKey(raw), true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
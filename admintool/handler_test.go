@@ -0,0 +1,113 @@
+package admintool_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/admintool"
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestHandler(t *testing.T) {
+	key := policy.ParseKey("svc.Admin")
+	pol := policy.NewFromKey(key, policy.MaxAttempts(3), policy.ConstantBackoff(time.Millisecond), policy.Budget("retries"))
+	pol.Circuit = policy.CircuitPolicy{Enabled: true, Threshold: 5, Cooldown: time.Minute}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("retries", budget.NewTokenBucketBudget(10, 1))
+	timelines := observe.NewTimelineBuffer(10)
+	exec := retry.NewExecutor(
+		retry.WithProvider(staticProvider{key: pol}),
+		retry.WithBudgetRegistry(budgets),
+		retry.WithObserver(observe.MultiObserver{Observers: []observe.Observer{timelines}}),
+	)
+	_ = exec.Do(context.Background(), key, func(context.Context) error { return errors.New("boom") })
+
+	srv := httptest.NewServer(http.StripPrefix("/debug/recourse", admintool.Handler(exec)))
+	defer srv.Close()
+	call := func(method, path string, want int, out any) {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+"/debug/recourse"+path, nil)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("%s %s: status %d, want %d", method, path, resp.StatusCode, want)
+		}
+		if out != nil {
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				t.Fatalf("%s %s: %v", method, path, err)
+			}
+		}
+	}
+
+	var policies []struct {
+		Key    string                 `json:"key"`
+		Policy policy.EffectivePolicy `json:"policy"`
+	}
+	call("GET", "/policies", 200, &policies)
+	if len(policies) != 1 || policies[0].Key != "svc.Admin" || policies[0].Policy.Retry.MaxAttempts != 3 {
+		t.Fatalf("policies = %+v", policies)
+	}
+
+	var circuits []struct{ Key, State string }
+	call("POST", "/circuits/open?key=svc.Admin", 200, nil)
+	call("GET", "/circuits", 200, &circuits)
+	if len(circuits) != 1 || circuits[0].State != "open" {
+		t.Fatalf("circuits = %+v, want svc.Admin open", circuits)
+	}
+	call("POST", "/circuits/open?key=svc.Other", 404, nil)
+
+	var budgetsOut []struct {
+		Name      string
+		Available *float64
+		Capacity  *float64
+	}
+	call("GET", "/budgets", 200, &budgetsOut)
+	if len(budgetsOut) != 1 || budgetsOut[0].Capacity == nil || *budgetsOut[0].Capacity != 10 || *budgetsOut[0].Available >= 10 {
+		t.Fatalf("budgets = %+v, want retries budget partly drawn", budgetsOut)
+	}
+
+	call("POST", "/overrides?key=svc.Admin&max_attempts=1&ttl=1m", 200, nil)
+	call("POST", "/overrides?key=svc.Admin&max_attempts=zero", 400, nil)
+	call("GET", "/policies?key=svc.Admin", 200, &policies)
+	if policies[0].Policy.Retry.MaxAttempts != 1 {
+		t.Fatalf("max attempts = %d, want override 1", policies[0].Policy.Retry.MaxAttempts)
+	}
+	var overrides map[string]retry.KeyOverride
+	call("GET", "/overrides", 200, &overrides)
+	if overrides["svc.Admin"].MaxAttempts != 1 {
+		t.Fatalf("overrides = %+v", overrides)
+	}
+	call("DELETE", "/overrides?key=svc.Admin", 204, nil)
+	overrides = nil
+	call("GET", "/overrides", 200, &overrides)
+	if len(overrides) != 0 {
+		t.Fatalf("overrides after delete = %+v", overrides)
+	}
+
+	var tls []struct {
+		Key      string
+		Attempts []struct{ Outcome, Error string }
+		Error    string
+	}
+	call("GET", "/timelines?key=svc.Admin&limit=5", 200, &tls)
+	if len(tls) != 1 || len(tls[0].Attempts) != 3 || tls[0].Attempts[0].Outcome != "retryable" || tls[0].Error != "boom" {
+		t.Fatalf("timelines = %+v", tls)
+	}
+}
+
+type staticProvider map[policy.PolicyKey]policy.EffectivePolicy
+
+func (p staticProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	return p[key], nil
+}
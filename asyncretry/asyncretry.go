@@ -0,0 +1,187 @@
+package asyncretry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"time"
+
+	"github.com/aponysus/recourse/asyncretry/store"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Defaults for Options.
+const (
+	DefaultLeaseFor     = time.Minute
+	DefaultPollInterval = time.Second
+	DefaultBatchSize    = 10
+	DefaultMaxRuns      = 10
+)
+
+// Handler runs a job. It may run more than once if a lease expires mid-run, so it
+// should be idempotent.
+type Handler func(ctx context.Context, job store.Job) error
+
+// Options configures a Scheduler.
+type Options struct {
+	// Owner identifies this replica in leases. Defaults to hostname, PID, and a
+	// random suffix.
+	Owner string
+	// LeaseFor is how long a leased job belongs to this replica. Each run is bounded
+	// by it, so a job is not run elsewhere while it is still running here.
+	// Defaults to DefaultLeaseFor.
+	LeaseFor time.Duration
+	// PollInterval is how often Run looks for due jobs. Defaults to DefaultPollInterval.
+	PollInterval time.Duration
+	// BatchSize is the maximum number of jobs leased per poll. Defaults to DefaultBatchSize.
+	BatchSize int
+
+	// MaxRuns is the number of failed runs after which a job is dead-lettered.
+	// Defaults to DefaultMaxRuns.
+	MaxRuns int
+	// Backoff returns the delay before the next run after the given number of failed
+	// runs. Defaults to exponential backoff from 1s, capped at 1h, with full jitter.
+	Backoff func(runs int) time.Duration
+
+	// DeadLetter, if set, receives jobs that failed terminally or ran out of runs,
+	// before they are deleted.
+	DeadLetter func(ctx context.Context, job store.Job, err error)
+
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Scheduler leases due jobs from a store and runs them. It is safe for concurrent use,
+// and any number of replicas may share a store.
+type Scheduler struct {
+	store   store.Store
+	exec    *retry.Executor
+	handler Handler
+	opts    Options
+}
+
+// New returns a Scheduler. If exec is nil, the default executor is used.
+func New(st store.Store, exec *retry.Executor, handler Handler, opts Options) *Scheduler {
+	if opts.Owner == "" {
+		host, _ := os.Hostname()
+		opts.Owner = fmt.Sprintf("%s-%d-%08x", host, os.Getpid(), rand.Uint32())
+	}
+	if opts.LeaseFor <= 0 {
+		opts.LeaseFor = DefaultLeaseFor
+	}
+	if opts.PollInterval <= 0 {
+		opts.PollInterval = DefaultPollInterval
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.MaxRuns <= 0 {
+		opts.MaxRuns = DefaultMaxRuns
+	}
+	if opts.Backoff == nil {
+		opts.Backoff = defaultBackoff
+	}
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	return &Scheduler{store: st, exec: exec, handler: handler, opts: opts}
+}
+
+// Enqueue stores job. A zero RunAt means now.
+func (s *Scheduler) Enqueue(ctx context.Context, job store.Job) error {
+	if job.ID == "" {
+		return errors.New("asyncretry: job ID is required")
+	}
+	if job.RunAt.IsZero() {
+		job.RunAt = s.opts.Clock()
+	}
+	return s.store.Put(ctx, job)
+}
+
+// Run polls for due jobs until ctx is done, and returns ctx.Err().
+// Store errors are retried at the next poll.
+func (s *Scheduler) Run(ctx context.Context) error {
+	t := time.NewTicker(s.opts.PollInterval)
+	defer t.Stop()
+	for {
+		// Drain full batches before waiting for the next tick.
+		for {
+			n, err := s.RunOnce(ctx)
+			if err != nil || n < s.opts.BatchSize {
+				break
+			}
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// RunOnce leases one batch of due jobs, runs them, and returns how many it ran.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	jobs, err := s.store.Lease(ctx, s.opts.Owner, s.opts.Clock(), s.opts.LeaseFor, s.opts.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+	for _, job := range jobs {
+		if err := s.runJob(ctx, job); err != nil && ctx.Err() != nil {
+			return len(jobs), ctx.Err()
+		}
+	}
+	return len(jobs), nil
+}
+
+func (s *Scheduler) runJob(ctx context.Context, job store.Job) error {
+	exec := s.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+
+	runCtx, cancel := context.WithTimeout(ctx, s.opts.LeaseFor)
+	err := exec.Do(runCtx, job.Key, func(ctx context.Context) error { return s.handler(ctx, job) })
+	cancel()
+
+	if err == nil {
+		return s.store.Delete(ctx, s.opts.Owner, job.ID)
+	}
+	if ctx.Err() != nil {
+		// Shutting down: leave the job leased; it is picked up when the lease expires.
+		return ctx.Err()
+	}
+
+	job.Runs++
+	job.LastError = err.Error()
+	if deferrable(err) && job.Runs < s.opts.MaxRuns {
+		job.RunAt = s.opts.Clock().Add(s.opts.Backoff(job.Runs))
+		return s.store.Reschedule(ctx, s.opts.Owner, job)
+	}
+
+	if s.opts.DeadLetter != nil {
+		s.opts.DeadLetter(ctx, job, err)
+	}
+	return s.store.Delete(ctx, s.opts.Owner, job.ID)
+}
+
+// deferrable reports whether a failed call may succeed if run later.
+func deferrable(err error) bool {
+	return errors.Is(err, retry.ErrAttemptsExhausted) ||
+		errors.Is(err, retry.ErrBudgetDenied) ||
+		errors.Is(err, retry.ErrCircuitOpen) ||
+		errors.Is(err, retry.ErrOverallTimeout) ||
+		errors.Is(err, context.DeadlineExceeded)
+}
+
+func defaultBackoff(runs int) time.Duration {
+	d := time.Second
+	for i := 1; i < runs && d < time.Hour; i++ {
+		d *= 2
+	}
+	if d > time.Hour {
+		d = time.Hour
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
@@ -0,0 +1,200 @@
+package asyncretry_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/asyncretry"
+	"github.com/aponysus/recourse/asyncretry/store"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+var key = policy.PolicyKey{Namespace: "jobs", Name: "send"}
+
+// badRequest is a terminal HTTP error under the default classifier.
+type badRequest struct{}
+
+func (badRequest) Error() string                     { return "400 bad request" }
+func (badRequest) HTTPStatusCode() int               { return 400 }
+func (badRequest) HTTPMethod() string                { return "POST" }
+func (badRequest) RetryAfter() (time.Duration, bool) { return 0, false }
+
+// clock is a settable test clock.
+type clock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func newExecutor() *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(2),
+		policy.Backoff(time.Microsecond, time.Microsecond, 1),
+	))
+}
+
+type deadLetter struct {
+	job store.Job
+	err error
+}
+
+func TestSchedulerReschedulesThenDeadLetters(t *testing.T) {
+	ctx := context.Background()
+	clk := &clock{now: time.Unix(1_700_000_000, 0)}
+	st := store.NewMemory()
+
+	var (
+		calls int
+		dead  []deadLetter
+	)
+	s := asyncretry.New(st, newExecutor(), func(ctx context.Context, job store.Job) error {
+		calls++
+		return errors.New("unavailable")
+	}, asyncretry.Options{
+		Owner:      "w1",
+		MaxRuns:    3,
+		Backoff:    func(runs int) time.Duration { return time.Duration(runs) * time.Minute },
+		DeadLetter: func(_ context.Context, job store.Job, err error) { dead = append(dead, deadLetter{job, err}) },
+		Clock:      clk.Now,
+	})
+
+	if err := s.Enqueue(ctx, store.Job{ID: "j1", Key: key}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	for run, wait := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		clk.Advance(wait - time.Second)
+		if n, err := s.RunOnce(ctx); err != nil || n != 0 {
+			t.Fatalf("run %d: ran %d jobs before due (err %v)", run, n, err)
+		}
+		clk.Advance(time.Second)
+		if n, err := s.RunOnce(ctx); err != nil || n != 1 {
+			t.Fatalf("run %d: RunOnce = %d, %v; want 1 job", run, n, err)
+		}
+	}
+
+	if calls != 6 {
+		t.Fatalf("handler calls = %d, want 6 (2 attempts x 3 runs)", calls)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("dead-lettered %d jobs, want 1", len(dead))
+	}
+	if dead[0].job.Runs != 3 || dead[0].job.LastError == "" || !errors.Is(dead[0].err, retry.ErrAttemptsExhausted) {
+		t.Fatalf("dead letter = %+v", dead[0])
+	}
+	if st.Len() != 0 {
+		t.Fatalf("store holds %d jobs after dead-lettering", st.Len())
+	}
+}
+
+func TestSchedulerTerminalErrorDeadLettersImmediately(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+
+	var dead []deadLetter
+	s := asyncretry.New(st, newExecutor(), func(ctx context.Context, job store.Job) error {
+		return badRequest{}
+	}, asyncretry.Options{
+		DeadLetter: func(_ context.Context, job store.Job, err error) { dead = append(dead, deadLetter{job, err}) },
+	})
+
+	_ = s.Enqueue(ctx, store.Job{ID: "j1", Key: key})
+	if _, err := s.RunOnce(ctx); err != nil {
+		t.Fatalf("RunOnce: %v", err)
+	}
+	if len(dead) != 1 || dead[0].job.Runs != 1 || !errors.As(dead[0].err, new(badRequest)) {
+		t.Fatalf("dead letters = %+v", dead)
+	}
+	if st.Len() != 0 {
+		t.Fatalf("store holds %d jobs", st.Len())
+	}
+}
+
+func TestSchedulerSuccessDeletesJob(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+
+	var got []string
+	s := asyncretry.New(st, newExecutor(), func(ctx context.Context, job store.Job) error {
+		got = append(got, string(job.Payload))
+		return nil
+	}, asyncretry.Options{})
+
+	_ = s.Enqueue(ctx, store.Job{ID: "a", Key: key, Payload: []byte("hello")})
+	_ = s.Enqueue(ctx, store.Job{ID: "b", Key: key, RunAt: time.Now().Add(time.Hour)})
+
+	if n, err := s.RunOnce(ctx); err != nil || n != 1 {
+		t.Fatalf("RunOnce = %d, %v; want 1 job", n, err)
+	}
+	if len(got) != 1 || got[0] != "hello" {
+		t.Fatalf("handled %q", got)
+	}
+	if st.Len() != 1 {
+		t.Fatalf("store holds %d jobs, want the future one", st.Len())
+	}
+}
+
+func TestSchedulerLeaseSeparatesReplicas(t *testing.T) {
+	ctx := context.Background()
+	st := store.NewMemory()
+	for _, id := range []string{"a", "b", "c", "d", "e", "f"} {
+		_ = st.Put(ctx, store.Job{ID: id, Key: key, RunAt: time.Now()})
+	}
+
+	var (
+		mu   sync.Mutex
+		runs = make(map[string]int)
+	)
+	handler := func(ctx context.Context, job store.Job) error {
+		mu.Lock()
+		runs[job.ID]++
+		mu.Unlock()
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	for _, owner := range []string{"w1", "w2", "w3"} {
+		s := asyncretry.New(st, newExecutor(), handler, asyncretry.Options{Owner: owner, BatchSize: 1})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				if n, _ := s.RunOnce(ctx); n == 0 {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(runs) != 6 {
+		t.Fatalf("ran %d jobs, want 6", len(runs))
+	}
+	for id, n := range runs {
+		if n != 1 {
+			t.Fatalf("job %s ran %d times", id, n)
+		}
+	}
+}
+
+func TestEnqueueRequiresID(t *testing.T) {
+	s := asyncretry.New(store.NewMemory(), nil, func(context.Context, store.Job) error { return nil }, asyncretry.Options{})
+	if err := s.Enqueue(context.Background(), store.Job{Key: key}); err == nil {
+		t.Fatal("Enqueue without ID succeeded")
+	}
+}
@@ -0,0 +1,19 @@
+// Package asyncretry runs background jobs whose retries outlive the process.
+//
+// A job runs as one executor call under its policy key, so in-process retries, budgets,
+// and circuits apply as usual. When the call gives up for a transient reason (attempts
+// exhausted, budget denied, circuit open, overall timeout), the job is rescheduled in a
+// store.Store and run again later, by this process or another replica after a restart.
+// Terminal failures and jobs out of runs go to the dead-letter callback.
+//
+// Usage:
+//
+//	s := asyncretry.New(store.NewSQL(db, store.SQLOptions{Placeholder: store.DollarPlaceholder}), exec,
+//		func(ctx context.Context, job store.Job) error {
+//			return sendWebhook(ctx, job.Payload)
+//		},
+//		asyncretry.Options{MaxRuns: 10},
+//	)
+//	_ = s.Enqueue(ctx, store.Job{ID: deliveryID, Key: recourse.ParseKey("webhooks.Send"), Payload: body})
+//	go s.Run(ctx)
+package asyncretry
@@ -0,0 +1,9 @@
+// Package store persists asyncretry jobs so scheduled retries survive process restarts.
+//
+// Every backend implements leasing: a job returned by Lease belongs to the leasing owner
+// until the lease expires, so replicas sharing a store do not run it twice. Updates from
+// an owner whose lease was taken over fail with ErrLeaseLost.
+//
+// Backends: Memory (single process, tests), File (replicas on one host), SQL
+// (database/sql), and the redisstore module.
+package store
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	fileLockRetry = 5 * time.Millisecond
+	// fileLockStale is the age after which a lock file left by a crashed process is
+	// removed. Every operation holds the lock for one read-modify-write of the file.
+	fileLockStale = 30 * time.Second
+)
+
+// File is a Store backed by a JSON file. Processes on the same host may share it:
+// each operation takes an exclusive lock file (path + ".lock") and rewrites the file
+// atomically. It suits modest job counts; every operation reads the whole file.
+type File struct {
+	path string
+}
+
+var _ Store = (*File)(nil)
+
+// NewFile returns a store persisted at path. The file is created on first write.
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (f *File) Put(ctx context.Context, job Job) error {
+	return f.update(ctx, func(m jobs) error {
+		m.put(job)
+		return nil
+	})
+}
+
+func (f *File) Lease(ctx context.Context, owner string, now time.Time, ttl time.Duration, limit int) ([]Job, error) {
+	var out []Job
+	err := f.update(ctx, func(m jobs) error {
+		out = m.lease(owner, now, ttl, limit)
+		return nil
+	})
+	return out, err
+}
+
+func (f *File) Reschedule(ctx context.Context, owner string, job Job) error {
+	return f.update(ctx, func(m jobs) error { return m.reschedule(owner, job) })
+}
+
+func (f *File) Delete(ctx context.Context, owner string, id string) error {
+	return f.update(ctx, func(m jobs) error { return m.delete(owner, id) })
+}
+
+// update runs fn on the stored jobs under the lock and writes them back if fn succeeds.
+func (f *File) update(ctx context.Context, fn func(jobs) error) error {
+	unlock, err := f.lock(ctx)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	m := make(jobs)
+	data, err := os.ReadFile(f.path)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return err
+	default:
+		if err := json.Unmarshal(data, &m); err != nil {
+			return fmt.Errorf("store: decode %s: %w", f.path, err)
+		}
+	}
+
+	if err := fn(m); err != nil {
+		return err
+	}
+
+	data, err = json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".tmp*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), f.path)
+}
+
+func (f *File) lock(ctx context.Context) (func(), error) {
+	name := f.path + ".lock"
+	for {
+		lf, err := os.OpenFile(name, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			lf.Close()
+			return func() { os.Remove(name) }, nil
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return nil, err
+		}
+		if fi, err := os.Stat(name); err == nil && time.Since(fi.ModTime()) > fileLockStale {
+			os.Remove(name)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(fileLockRetry):
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+)
+
+// record is a stored job with its lease.
+type record struct {
+	Job        Job       `json:"job"`
+	LeaseOwner string    `json:"lease_owner,omitempty"`
+	LeaseUntil time.Time `json:"lease_until,omitempty"`
+}
+
+// jobs implements the Store operations over a map; Memory and File share it.
+type jobs map[string]*record
+
+func (m jobs) put(job Job) {
+	m[job.ID] = &record{Job: job}
+}
+
+func (m jobs) lease(owner string, now time.Time, ttl time.Duration, limit int) []Job {
+	var due []*record
+	for _, r := range m {
+		if !r.Job.RunAt.After(now) && !r.LeaseUntil.After(now) {
+			due = append(due, r)
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].Job.RunAt.Before(due[j].Job.RunAt) })
+	if limit > 0 && len(due) > limit {
+		due = due[:limit]
+	}
+
+	out := make([]Job, 0, len(due))
+	for _, r := range due {
+		r.LeaseOwner = owner
+		r.LeaseUntil = now.Add(ttl)
+		out = append(out, r.Job)
+	}
+	return out
+}
+
+func (m jobs) reschedule(owner string, job Job) error {
+	r, ok := m[job.ID]
+	if !ok || r.LeaseOwner != owner {
+		return ErrLeaseLost
+	}
+	r.Job.Runs = job.Runs
+	r.Job.RunAt = job.RunAt
+	r.Job.LastError = job.LastError
+	r.LeaseOwner = ""
+	r.LeaseUntil = time.Time{}
+	return nil
+}
+
+func (m jobs) delete(owner, id string) error {
+	r, ok := m[id]
+	if !ok || r.LeaseOwner != owner {
+		return ErrLeaseLost
+	}
+	delete(m, id)
+	return nil
+}
+
+// Memory is an in-process Store. It is safe for concurrent use.
+type Memory struct {
+	mu   sync.Mutex
+	jobs jobs
+}
+
+var _ Store = (*Memory)(nil)
+
+// NewMemory returns an empty in-process store.
+func NewMemory() *Memory {
+	return &Memory{jobs: make(jobs)}
+}
+
+func (m *Memory) Put(_ context.Context, job Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.jobs.put(job)
+	return nil
+}
+
+func (m *Memory) Lease(_ context.Context, owner string, now time.Time, ttl time.Duration, limit int) ([]Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs.lease(owner, now, ttl, limit), nil
+}
+
+func (m *Memory) Reschedule(_ context.Context, owner string, job Job) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs.reschedule(owner, job)
+}
+
+func (m *Memory) Delete(_ context.Context, owner string, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.jobs.delete(owner, id)
+}
+
+// Len returns the number of stored jobs.
+func (m *Memory) Len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.jobs)
+}
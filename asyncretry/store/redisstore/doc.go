@@ -0,0 +1,13 @@
+// Package redisstore is a Redis backend for asyncretry/store.
+//
+// Each job is a hash holding its JSON encoding and lease, and a sorted set orders job
+// IDs by when they are next eligible (their RunAt, or their lease expiry while leased).
+// Lease, Reschedule, and Delete are Lua scripts, so replicas sharing a Redis instance
+// never lease the same job twice.
+//
+// Usage:
+//
+//	rdb := redis.NewClient(&redis.Options{Addr: "localhost:6379"})
+//	st := redisstore.New(rdb, redisstore.Options{})
+//	s := asyncretry.New(st, exec, handler, asyncretry.Options{})
+package redisstore
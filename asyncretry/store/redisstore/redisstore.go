@@ -0,0 +1,141 @@
+package redisstore
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/aponysus/recourse/asyncretry/store"
+	"github.com/redis/go-redis/v9"
+)
+
+// DefaultPrefix is the key prefix used when Options.Prefix is empty. The braces make
+// every key hash to the same Redis Cluster slot.
+const DefaultPrefix = "{recourse}:jobs"
+
+// Options configures a Store.
+type Options struct {
+	// Prefix namespaces the keys: Prefix+":due" is the sorted set and Prefix+":job:"+id
+	// are the job hashes. On Redis Cluster it must contain a hash tag.
+	// Defaults to DefaultPrefix.
+	Prefix string
+}
+
+// Store is a store.Store backed by Redis.
+type Store struct {
+	rdb    redis.Scripter
+	prefix string
+}
+
+var _ store.Store = (*Store)(nil)
+
+// New returns a store using rdb (a *redis.Client, *redis.ClusterClient, or
+// redis.UniversalClient).
+func New(rdb redis.Scripter, opts Options) *Store {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	return &Store{rdb: rdb, prefix: prefix}
+}
+
+// Scores are Unix milliseconds; jobs keep full precision in their JSON encoding.
+
+var putScript = redis.NewScript(`
+redis.call('HSET', KEYS[2], 'job', ARGV[2], 'owner', '', 'until', 0)
+redis.call('ZADD', KEYS[1], ARGV[3], ARGV[1])
+return 1
+`)
+
+var leaseScript = redis.NewScript(`
+local ids = redis.call('ZRANGEBYSCORE', KEYS[1], '-inf', ARGV[1], 'LIMIT', 0, tonumber(ARGV[4]))
+local out = {}
+for _, id in ipairs(ids) do
+	local key = ARGV[5] .. id
+	local job = redis.call('HGET', key, 'job')
+	if job then
+		redis.call('HSET', key, 'owner', ARGV[2], 'until', ARGV[3])
+		redis.call('ZADD', KEYS[1], ARGV[3], id)
+		table.insert(out, job)
+	else
+		redis.call('ZREM', KEYS[1], id)
+	end
+end
+return out
+`)
+
+var rescheduleScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'owner') ~= ARGV[2] then
+	return 0
+end
+redis.call('HSET', KEYS[2], 'job', ARGV[3], 'owner', '', 'until', 0)
+redis.call('ZADD', KEYS[1], ARGV[4], ARGV[1])
+return 1
+`)
+
+var deleteScript = redis.NewScript(`
+if redis.call('HGET', KEYS[2], 'owner') ~= ARGV[2] then
+	return 0
+end
+redis.call('DEL', KEYS[2])
+redis.call('ZREM', KEYS[1], ARGV[1])
+return 1
+`)
+
+func (s *Store) Put(ctx context.Context, job store.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	return putScript.Run(ctx, s.rdb, s.keys(job.ID), job.ID, data, job.RunAt.UnixMilli()).Err()
+}
+
+func (s *Store) Lease(ctx context.Context, owner string, now time.Time, ttl time.Duration, limit int) ([]store.Job, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	res, err := leaseScript.Run(ctx, s.rdb, []string{s.due()},
+		now.UnixMilli(), owner, now.Add(ttl).UnixMilli(), limit, s.prefix+":job:").StringSlice()
+	if err != nil {
+		return nil, err
+	}
+	out := make([]store.Job, 0, len(res))
+	for _, data := range res {
+		var job store.Job
+		if err := json.Unmarshal([]byte(data), &job); err != nil {
+			return out, err
+		}
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func (s *Store) Reschedule(ctx context.Context, owner string, job store.Job) error {
+	data, err := json.Marshal(job)
+	if err != nil {
+		return err
+	}
+	n, err := rescheduleScript.Run(ctx, s.rdb, s.keys(job.ID), job.ID, owner, data, job.RunAt.UnixMilli()).Int()
+	return leaseResult(n, err)
+}
+
+func (s *Store) Delete(ctx context.Context, owner string, id string) error {
+	n, err := deleteScript.Run(ctx, s.rdb, s.keys(id), id, owner).Int()
+	return leaseResult(n, err)
+}
+
+func (s *Store) due() string { return s.prefix + ":due" }
+
+func (s *Store) keys(id string) []string {
+	return []string{s.due(), s.prefix + ":job:" + id}
+}
+
+func leaseResult(n int, err error) error {
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return store.ErrLeaseLost
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+package redisstore_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/aponysus/recourse/asyncretry/store"
+	"github.com/aponysus/recourse/asyncretry/store/redisstore"
+	"github.com/aponysus/recourse/policy"
+	"github.com/redis/go-redis/v9"
+)
+
+func newStore(t *testing.T) (*redisstore.Store, *miniredis.Miniredis) {
+	t.Helper()
+	mr := miniredis.RunT(t)
+	rdb := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	t.Cleanup(func() { rdb.Close() })
+	return redisstore.New(rdb, redisstore.Options{}), mr
+}
+
+func TestLeasing(t *testing.T) {
+	ctx := context.Background()
+	st, _ := newStore(t)
+	now := time.Unix(1_700_000_000, 0)
+	key := policy.PolicyKey{Namespace: "jobs", Name: "send"}
+
+	_ = st.Put(ctx, store.Job{ID: "late", Key: key, RunAt: now.Add(-time.Second)})
+	_ = st.Put(ctx, store.Job{ID: "early", Key: key, Payload: []byte("p"), RunAt: now.Add(-2 * time.Second)})
+	_ = st.Put(ctx, store.Job{ID: "future", Key: key, RunAt: now.Add(time.Minute)})
+
+	got, err := st.Lease(ctx, "a", now, time.Minute, 10)
+	if err != nil {
+		t.Fatalf("Lease: %v", err)
+	}
+	if len(got) != 2 || got[0].ID != "early" || got[1].ID != "late" {
+		t.Fatalf("leased %+v, want early, late", got)
+	}
+	if got[0].Key != key || string(got[0].Payload) != "p" {
+		t.Fatalf("job not round-tripped: %+v", got[0])
+	}
+
+	if got, _ := st.Lease(ctx, "b", now.Add(30*time.Second), time.Minute, 10); len(got) != 0 {
+		t.Fatalf("b leased %+v while a holds the lease", got)
+	}
+	got, err = st.Lease(ctx, "b", now.Add(90*time.Second), time.Minute, 1)
+	if err != nil || len(got) != 1 || got[0].ID != "early" {
+		t.Fatalf("Lease after expiry = %+v, %v; want early", got, err)
+	}
+
+	if err := st.Delete(ctx, "a", "early"); !errors.Is(err, store.ErrLeaseLost) {
+		t.Fatalf("Delete by stale owner = %v, want ErrLeaseLost", err)
+	}
+	job := got[0]
+	job.Runs, job.RunAt, job.LastError = 1, now.Add(time.Hour), "boom"
+	if err := st.Reschedule(ctx, "a", job); !errors.Is(err, store.ErrLeaseLost) {
+		t.Fatalf("Reschedule by stale owner = %v, want ErrLeaseLost", err)
+	}
+	if err := st.Reschedule(ctx, "b", job); err != nil {
+		t.Fatalf("Reschedule: %v", err)
+	}
+
+	got, _ = st.Lease(ctx, "c", now.Add(2*time.Hour), time.Minute, 10)
+	if len(got) != 3 {
+		t.Fatalf("leased %d jobs, want 3", len(got))
+	}
+	for _, j := range got {
+		if j.ID == "early" && (j.Runs != 1 || j.LastError != "boom") {
+			t.Fatalf("rescheduled job = %+v", j)
+		}
+		if err := st.Delete(ctx, "c", j.ID); err != nil {
+			t.Fatalf("Delete %s: %v", j.ID, err)
+		}
+	}
+	if got, _ := st.Lease(ctx, "c", now.Add(24*time.Hour), time.Minute, 10); len(got) != 0 {
+		t.Fatalf("leased %+v after deleting all jobs", got)
+	}
+}
+
+func TestLeaseSkipsMissingJobs(t *testing.T) {
+	ctx := context.Background()
+	st, mr := newStore(t)
+	now := time.Now()
+
+	_ = st.Put(ctx, store.Job{ID: "gone", RunAt: now})
+	_ = st.Put(ctx, store.Job{ID: "kept", RunAt: now})
+	mr.Del(redisstore.DefaultPrefix + ":job:gone")
+
+	got, err := st.Lease(ctx, "a", now, time.Minute, 10)
+	if err != nil || len(got) != 1 || got[0].ID != "kept" {
+		t.Fatalf("Lease = %+v, %v; want kept", got, err)
+	}
+	if members, _ := mr.ZMembers(redisstore.DefaultPrefix + ":due"); len(members) != 1 {
+		t.Fatalf("due set = %v, want the orphan removed", members)
+	}
+}
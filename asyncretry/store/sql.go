@@ -0,0 +1,209 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSQLTable is the table used by SQL when SQLOptions.Table is empty.
+const DefaultSQLTable = "recourse_jobs"
+
+// QuestionPlaceholder writes "?" placeholders (MySQL, SQLite).
+func QuestionPlaceholder(int) string { return "?" }
+
+// DollarPlaceholder writes "$n" placeholders (PostgreSQL).
+func DollarPlaceholder(n int) string { return "$" + strconv.Itoa(n) }
+
+// SQLOptions configures SQL.
+type SQLOptions struct {
+	// Table is the jobs table. It is interpolated into queries and must be a trusted
+	// identifier. Defaults to DefaultSQLTable.
+	Table string
+	// Placeholder returns the bind parameter for the nth (1-based) argument.
+	// Defaults to QuestionPlaceholder.
+	Placeholder func(n int) string
+}
+
+// SQL is a Store backed by a database/sql table. Replicas sharing the database lease
+// jobs with a conditional UPDATE, so no row locks or transactions are needed.
+//
+// The table must have these columns (adjust types to the database):
+//
+//	CREATE TABLE recourse_jobs (
+//		id          VARCHAR(255) PRIMARY KEY,
+//		namespace   VARCHAR(255) NOT NULL,
+//		name        VARCHAR(255) NOT NULL,
+//		payload     BLOB,          -- BYTEA on PostgreSQL
+//		runs        INTEGER NOT NULL,
+//		run_at      BIGINT NOT NULL, -- Unix nanoseconds
+//		last_error  TEXT NOT NULL,
+//		lease_owner VARCHAR(255) NOT NULL,
+//		lease_until BIGINT NOT NULL  -- Unix nanoseconds, 0 when not leased
+//	);
+//	CREATE INDEX recourse_jobs_due ON recourse_jobs (run_at);
+type SQL struct {
+	db *sql.DB
+
+	insert, update, due, claim, get, reschedule, del string
+}
+
+var _ Store = (*SQL)(nil)
+
+// NewSQL returns a store using db.
+func NewSQL(db *sql.DB, opts SQLOptions) *SQL {
+	table := opts.Table
+	if table == "" {
+		table = DefaultSQLTable
+	}
+	ph := opts.Placeholder
+	if ph == nil {
+		ph = QuestionPlaceholder
+	}
+	q := func(query string) string { return rebind(strings.ReplaceAll(query, "{table}", table), ph) }
+
+	return &SQL{
+		db: db,
+		insert: q(`INSERT INTO {table} (id, namespace, name, payload, runs, run_at, last_error, lease_owner, lease_until)
+			VALUES (?, ?, ?, ?, ?, ?, ?, '', 0)`),
+		update: q(`UPDATE {table} SET namespace = ?, name = ?, payload = ?, runs = ?, run_at = ?, last_error = ?,
+			lease_owner = '', lease_until = 0 WHERE id = ?`),
+		due:   q(`SELECT id FROM {table} WHERE run_at <= ? AND lease_until <= ? ORDER BY run_at LIMIT `),
+		claim: q(`UPDATE {table} SET lease_owner = ?, lease_until = ? WHERE id = ? AND run_at <= ? AND lease_until <= ?`),
+		get: q(`SELECT id, namespace, name, payload, runs, run_at, last_error FROM {table}
+			WHERE id = ? AND lease_owner = ?`),
+		reschedule: q(`UPDATE {table} SET runs = ?, run_at = ?, last_error = ?, lease_owner = '', lease_until = 0
+			WHERE id = ? AND lease_owner = ?`),
+		del: q(`DELETE FROM {table} WHERE id = ? AND lease_owner = ?`),
+	}
+}
+
+func (s *SQL) Put(ctx context.Context, job Job) error {
+	_, err := s.db.ExecContext(ctx, s.insert,
+		job.ID, job.Key.Namespace, job.Key.Name, job.Payload, job.Runs, unixNano(job.RunAt), job.LastError)
+	if err == nil {
+		return nil
+	}
+	// Most likely the job exists; replace it.
+	res, uerr := s.db.ExecContext(ctx, s.update,
+		job.Key.Namespace, job.Key.Name, job.Payload, job.Runs, unixNano(job.RunAt), job.LastError, job.ID)
+	if uerr != nil {
+		return uerr
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return err
+	}
+	return nil
+}
+
+func (s *SQL) Lease(ctx context.Context, owner string, now time.Time, ttl time.Duration, limit int) ([]Job, error) {
+	if limit <= 0 {
+		limit = 100
+	}
+	nowN := now.UnixNano()
+	rows, err := s.db.QueryContext(ctx, s.due+strconv.Itoa(limit), nowN, nowN)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	var out []Job
+	until := now.Add(ttl).UnixNano()
+	for _, id := range ids {
+		res, err := s.db.ExecContext(ctx, s.claim, owner, until, id, nowN, nowN)
+		if err != nil {
+			return out, err
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue // claimed by another replica
+		}
+
+		var (
+			job   Job
+			runAt int64
+		)
+		err = s.db.QueryRowContext(ctx, s.get, id, owner).Scan(
+			&job.ID, &job.Key.Namespace, &job.Key.Name, &job.Payload, &job.Runs, &runAt, &job.LastError)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return out, err
+		}
+		job.RunAt = fromUnixNano(runAt)
+		out = append(out, job)
+	}
+	return out, nil
+}
+
+func (s *SQL) Reschedule(ctx context.Context, owner string, job Job) error {
+	res, err := s.db.ExecContext(ctx, s.reschedule, job.Runs, unixNano(job.RunAt), job.LastError, job.ID, owner)
+	return leaseResult(res, err)
+}
+
+func (s *SQL) Delete(ctx context.Context, owner string, id string) error {
+	res, err := s.db.ExecContext(ctx, s.del, id, owner)
+	return leaseResult(res, err)
+}
+
+func leaseResult(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrLeaseLost
+	}
+	return nil
+}
+
+// rebind replaces each "?" in query with ph(n) and collapses whitespace.
+func rebind(query string, ph func(int) string) string {
+	var b strings.Builder
+	n := 0
+	for _, field := range strings.Fields(query) {
+		if b.Len() > 0 {
+			b.WriteByte(' ')
+		}
+		for _, r := range field {
+			if r == '?' {
+				n++
+				b.WriteString(ph(n))
+				continue
+			}
+			b.WriteRune(r)
+		}
+	}
+	// Keep the trailing space of "LIMIT " for the appended limit.
+	if strings.HasSuffix(query, " ") {
+		b.WriteByte(' ')
+	}
+	return b.String()
+}
+
+func unixNano(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.UnixNano()
+}
+
+func fromUnixNano(n int64) time.Time {
+	if n == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, n)
+}
@@ -0,0 +1,43 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// ErrLeaseLost is returned when a job is updated or deleted by an owner that no longer
+// holds its lease.
+var ErrLeaseLost = errors.New("store: lease lost")
+
+// Job is a unit of background work and its retry state.
+type Job struct {
+	ID      string           `json:"id"`
+	Key     policy.PolicyKey `json:"key"`
+	Payload []byte           `json:"payload,omitempty"`
+
+	// Runs is the number of executor calls that ran the job and failed.
+	Runs int `json:"runs"`
+	// RunAt is when the job is next due.
+	RunAt time.Time `json:"run_at"`
+	// LastError is the error message of the last failed run.
+	LastError string `json:"last_error,omitempty"`
+}
+
+// Store persists jobs and leases due ones to workers.
+type Store interface {
+	// Put inserts job, or replaces the job with the same ID and clears its lease.
+	Put(ctx context.Context, job Job) error
+
+	// Lease claims up to limit jobs due at now that are not leased (or whose lease
+	// expired) for owner until now+ttl, earliest RunAt first.
+	Lease(ctx context.Context, owner string, now time.Time, ttl time.Duration, limit int) ([]Job, error)
+
+	// Reschedule stores job's Runs, RunAt, and LastError and releases owner's lease.
+	Reschedule(ctx context.Context, owner string, job Job) error
+
+	// Delete removes the job leased by owner.
+	Delete(ctx context.Context, owner string, id string) error
+}
@@ -0,0 +1,316 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func testStores(t *testing.T) map[string]Store {
+	return map[string]Store{
+		"memory": NewMemory(),
+		"file":   NewFile(filepath.Join(t.TempDir(), "jobs.json")),
+		"sql":    NewSQL(newFakeDB(t), SQLOptions{}),
+	}
+}
+
+func TestStoreLeasing(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+	key := policy.PolicyKey{Namespace: "jobs", Name: "send"}
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			for i, at := range []time.Time{now.Add(-time.Second), now.Add(-2 * time.Second), now.Add(time.Minute)} {
+				job := Job{ID: "j" + strconv.Itoa(i), Key: key, Payload: []byte("p"), RunAt: at}
+				if err := st.Put(ctx, job); err != nil {
+					t.Fatalf("Put: %v", err)
+				}
+			}
+
+			got, err := st.Lease(ctx, "a", now, time.Minute, 10)
+			if err != nil {
+				t.Fatalf("Lease: %v", err)
+			}
+			if len(got) != 2 || got[0].ID != "j1" || got[1].ID != "j0" {
+				t.Fatalf("leased %+v, want j1, j0", got)
+			}
+			if got[0].Key != key || string(got[0].Payload) != "p" || !got[0].RunAt.Equal(now.Add(-2*time.Second)) {
+				t.Fatalf("job not round-tripped: %+v", got[0])
+			}
+
+			// Leased jobs are not handed to another owner until the lease expires.
+			if got, _ := st.Lease(ctx, "b", now.Add(30*time.Second), time.Minute, 10); len(got) != 0 {
+				t.Fatalf("b leased %+v while a holds the lease", got)
+			}
+			got, err = st.Lease(ctx, "b", now.Add(2*time.Minute), time.Minute, 1)
+			if err != nil || len(got) != 1 || got[0].ID != "j1" {
+				t.Fatalf("Lease after expiry = %+v, %v; want j1", got, err)
+			}
+
+			// a's lease on j1 expired and b took it over.
+			if err := st.Delete(ctx, "a", "j1"); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("Delete by stale owner = %v, want ErrLeaseLost", err)
+			}
+			job := got[0]
+			job.Runs, job.RunAt, job.LastError = 1, now.Add(time.Hour), "boom"
+			if err := st.Reschedule(ctx, "a", job); !errors.Is(err, ErrLeaseLost) {
+				t.Fatalf("Reschedule by stale owner = %v, want ErrLeaseLost", err)
+			}
+			if err := st.Reschedule(ctx, "b", job); err != nil {
+				t.Fatalf("Reschedule: %v", err)
+			}
+
+			got, _ = st.Lease(ctx, "c", now.Add(2*time.Hour), time.Minute, 10)
+			if len(got) != 3 {
+				t.Fatalf("leased %d jobs, want 3", len(got))
+			}
+			for _, j := range got {
+				if j.ID == "j1" && (j.Runs != 1 || j.LastError != "boom") {
+					t.Fatalf("rescheduled job = %+v", j)
+				}
+				if err := st.Delete(ctx, "c", j.ID); err != nil {
+					t.Fatalf("Delete %s: %v", j.ID, err)
+				}
+			}
+			if got, _ := st.Lease(ctx, "c", now.Add(24*time.Hour), time.Minute, 10); len(got) != 0 {
+				t.Fatalf("leased %+v after deleting all jobs", got)
+			}
+		})
+	}
+}
+
+func TestStorePutReplaces(t *testing.T) {
+	ctx := context.Background()
+	now := time.Unix(1_700_000_000, 0)
+
+	for name, st := range testStores(t) {
+		t.Run(name, func(t *testing.T) {
+			_ = st.Put(ctx, Job{ID: "j", RunAt: now})
+			if got, _ := st.Lease(ctx, "a", now, time.Minute, 10); len(got) != 1 {
+				t.Fatalf("leased %d jobs, want 1", len(got))
+			}
+			if err := st.Put(ctx, Job{ID: "j", Payload: []byte("v2"), RunAt: now}); err != nil {
+				t.Fatalf("Put: %v", err)
+			}
+			// Put clears the lease.
+			got, _ := st.Lease(ctx, "b", now, time.Minute, 10)
+			if len(got) != 1 || string(got[0].Payload) != "v2" {
+				t.Fatalf("leased %+v, want replaced job", got)
+			}
+		})
+	}
+}
+
+func TestFileSharedAcrossInstances(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	now := time.Now()
+
+	a, b := NewFile(path), NewFile(path)
+	for i := 0; i < 20; i++ {
+		_ = a.Put(ctx, Job{ID: strconv.Itoa(i), RunAt: now})
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		seen = make(map[string]int)
+	)
+	for i, st := range []*File{a, b, a, b} {
+		wg.Add(1)
+		go func(st *File, owner string) {
+			defer wg.Done()
+			for {
+				got, err := st.Lease(ctx, owner, now, time.Minute, 3)
+				if err != nil {
+					t.Errorf("Lease: %v", err)
+					return
+				}
+				if len(got) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, j := range got {
+					seen[j.ID]++
+				}
+				mu.Unlock()
+			}
+		}(st, strconv.Itoa(i))
+	}
+	wg.Wait()
+
+	if len(seen) != 20 {
+		t.Fatalf("leased %d distinct jobs, want 20", len(seen))
+	}
+	for id, n := range seen {
+		if n != 1 {
+			t.Fatalf("job %s leased %d times", id, n)
+		}
+	}
+}
+
+func TestRebind(t *testing.T) {
+	got := rebind("UPDATE t SET a = ?,\n\tb = ? WHERE id = ?", DollarPlaceholder)
+	if want := "UPDATE t SET a = $1, b = $2 WHERE id = $3"; got != want {
+		t.Fatalf("rebind = %q, want %q", got, want)
+	}
+	if got := rebind("SELECT id FROM t LIMIT ", QuestionPlaceholder); got != "SELECT id FROM t LIMIT " {
+		t.Fatalf("rebind dropped trailing space: %q", got)
+	}
+}
+
+// fakeDriver is a database/sql driver that executes the statements issued by SQL
+// against an in-memory table.
+type fakeDriver struct {
+	mu   sync.Mutex
+	rows map[string][]driver.Value // id -> id, namespace, name, payload, runs, run_at, last_error, lease_owner, lease_until
+}
+
+func newFakeDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(&fakeDriver{rows: make(map[string][]driver.Value)})
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return nil, errors.New("not supported") }
+
+const (
+	colRuns = iota + 4
+	colRunAt
+	colLastError
+	colLeaseOwner
+	colLeaseUntil
+)
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, nv []driver.NamedValue) (driver.Result, error) {
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	a := args(nv)
+	switch {
+	case strings.HasPrefix(query, "INSERT"):
+		id := a[0].(string)
+		if _, ok := d.rows[id]; ok {
+			return nil, errors.New("duplicate key")
+		}
+		d.rows[id] = append(a[:7:7], "", int64(0))
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "SET namespace"):
+		r, ok := d.rows[a[6].(string)]
+		if !ok {
+			return driver.RowsAffected(0), nil
+		}
+		copy(r[1:7], a[:6])
+		r[colLeaseOwner], r[colLeaseUntil] = "", int64(0)
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "SET lease_owner"):
+		r, ok := d.rows[a[2].(string)]
+		if !ok || r[colRunAt].(int64) > a[3].(int64) || r[colLeaseUntil].(int64) > a[4].(int64) {
+			return driver.RowsAffected(0), nil
+		}
+		r[colLeaseOwner], r[colLeaseUntil] = a[0], a[1]
+		return driver.RowsAffected(1), nil
+	case strings.Contains(query, "SET runs"):
+		r, ok := d.rows[a[3].(string)]
+		if !ok || r[colLeaseOwner] != a[4] {
+			return driver.RowsAffected(0), nil
+		}
+		r[colRuns], r[colRunAt], r[colLastError] = a[0], a[1], a[2]
+		r[colLeaseOwner], r[colLeaseUntil] = "", int64(0)
+		return driver.RowsAffected(1), nil
+	case strings.HasPrefix(query, "DELETE"):
+		r, ok := d.rows[a[0].(string)]
+		if !ok || r[colLeaseOwner] != a[1] {
+			return driver.RowsAffected(0), nil
+		}
+		delete(d.rows, a[0].(string))
+		return driver.RowsAffected(1), nil
+	}
+	return nil, errors.New("unexpected exec: " + query)
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, nv []driver.NamedValue) (driver.Rows, error) {
+	d := c.d
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	a := args(nv)
+	switch {
+	case strings.HasPrefix(query, "SELECT id FROM"):
+		limit, err := strconv.Atoi(query[strings.LastIndex(query, " ")+1:])
+		if err != nil {
+			return nil, err
+		}
+		var due [][]driver.Value
+		for _, r := range d.rows {
+			if r[colRunAt].(int64) <= a[0].(int64) && r[colLeaseUntil].(int64) <= a[1].(int64) {
+				due = append(due, r)
+			}
+		}
+		// Order by run_at.
+		for i := 1; i < len(due); i++ {
+			for j := i; j > 0 && due[j][colRunAt].(int64) < due[j-1][colRunAt].(int64); j-- {
+				due[j], due[j-1] = due[j-1], due[j]
+			}
+		}
+		if len(due) > limit {
+			due = due[:limit]
+		}
+		out := &fakeRows{cols: []string{"id"}}
+		for _, r := range due {
+			out.rows = append(out.rows, []driver.Value{r[0]})
+		}
+		return out, nil
+	case strings.HasPrefix(query, "SELECT id, namespace"):
+		out := &fakeRows{cols: []string{"id", "namespace", "name", "payload", "runs", "run_at", "last_error"}}
+		if r, ok := d.rows[a[0].(string)]; ok && r[colLeaseOwner] == a[1] {
+			out.rows = append(out.rows, append([]driver.Value(nil), r[:7]...))
+		}
+		return out, nil
+	}
+	return nil, errors.New("unexpected query: " + query)
+}
+
+func args(nv []driver.NamedValue) []driver.Value {
+	out := make([]driver.Value, len(nv))
+	for i, v := range nv {
+		out[i] = v.Value
+	}
+	return out
+}
+
+type fakeRows struct {
+	cols []string
+	rows [][]driver.Value
+}
+
+func (r *fakeRows) Columns() []string { return r.cols }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	copy(dest, r.rows[0])
+	r.rows = r.rows[1:]
+	return nil
+}
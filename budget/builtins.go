@@ -3,7 +3,7 @@ package budget
 import (
 	"context"
 	"math"
-	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aponysus/recourse/policy"
@@ -20,16 +20,34 @@ func (UnlimitedBudget) AllowAttempt(_ context.Context, _ policy.PolicyKey, _ int
 //
 // It starts full (capacity tokens) and refills at refillPerSecond tokens/second.
 // Each attempt consumes ref.Cost tokens (defaulting to 1).
+//
+// It is lock-free: the bucket's tokens and last refill are packed into a single word
+// that AllowAttempt updates with compare-and-swap, so concurrent attempts never wait on
+// each other and never spend more than capacity plus refill.
 type TokenBucketBudget struct {
-	mu sync.Mutex
-
 	capacity        float64
 	refillPerSecond float64
 
-	tokens float64
-	last   time.Time
+	// start is the monotonic reference for state.
+	start time.Time
+
+	// state packs the bucket into one word. With refill, it holds the nanoseconds since
+	// start at which the bucket was empty, so the tokens at t are
+	// min(capacity, (t-empty)*refillPerSecond) and one value captures both the tokens
+	// and when they were last refilled. Without refill, it holds the float64 bits of the
+	// tokens left.
+	state atomic.Uint64
+
+	thresholds PriorityThresholds
 }
 
+// maxFillNanos bounds how long a bucket takes to fill from empty, keeping state within
+// int64. Buckets that would take longer (over a century) cap out below capacity.
+const maxFillNanos = 1 << 62
+
+// tokenEpsilon absorbs the rounding of packed state to whole nanoseconds.
+const tokenEpsilon = 1e-9
+
 func NewTokenBucketBudget(capacity int, refillPerSecond float64) *TokenBucketBudget {
 	if capacity < 0 {
 		capacity = 0
@@ -43,58 +61,100 @@ func NewTokenBucketBudget(capacity int, refillPerSecond float64) *TokenBucketBud
 	b := &TokenBucketBudget{
 		capacity:        float64(capacity),
 		refillPerSecond: refillPerSecond,
-		tokens:          float64(capacity),
-		last:            time.Now(),
+		start:           time.Now(),
 	}
+	b.state.Store(b.pack(b.capacity, 0))
 	return b
 }
 
-func (b *TokenBucketBudget) AllowAttempt(_ context.Context, _ policy.PolicyKey, _ int, _ AttemptKind, ref policy.BudgetRef) Decision {
-	if b == nil {
-		return Decision{Allowed: false, Reason: ReasonBudgetNil}
-	}
+// WithPriorityThresholds makes the bucket shed retries and hedges of lower-priority
+// calls (see WithPriority) while it runs low, and returns b. Call it before the bucket
+// is used.
+func (b *TokenBucketBudget) WithPriorityThresholds(t PriorityThresholds) *TokenBucketBudget {
+	b.thresholds = t
+	return b
+}
 
-	b.mu.Lock()
-	defer b.mu.Unlock()
+// now returns the monotonic nanoseconds since the bucket was created.
+func (b *TokenBucketBudget) now() int64 {
+	return int64(time.Since(b.start))
+}
 
-	now := time.Now()
-	// Sanity check state
-	if math.IsNaN(b.tokens) || math.IsInf(b.tokens, 0) {
-		b.tokens = 0
+// pack encodes tokens available at now.
+func (b *TokenBucketBudget) pack(tokens float64, now int64) uint64 {
+	if b.refillPerSecond <= 0 {
+		return math.Float64bits(tokens)
+	}
+	fill := math.Round(tokens * 1e9 / b.refillPerSecond)
+	if fill > maxFillNanos {
+		fill = maxFillNanos
 	}
+	return uint64(now - int64(fill))
+}
 
-	if b.last.IsZero() {
-		b.tokens = b.capacity
-		b.last = now
-	} else if b.refillPerSecond > 0 && !now.Before(b.last) {
-		elapsed := now.Sub(b.last).Seconds()
-		added := elapsed * b.refillPerSecond
-		if math.IsNaN(added) || math.IsInf(added, 0) || added < 0 {
-			added = 0
-		}
+// unpack decodes the tokens available at now.
+func (b *TokenBucketBudget) unpack(state uint64, now int64) float64 {
+	if b.refillPerSecond <= 0 {
+		return math.Float64frombits(state)
+	}
+	tokens := float64(now-int64(state)) * b.refillPerSecond / 1e9
+	if tokens < 0 {
+		return 0
+	}
+	return math.Min(tokens, b.capacity)
+}
 
-		b.tokens += added
-		if b.tokens > b.capacity {
-			b.tokens = b.capacity
-		}
-		b.last = now
-	} else {
-		// Advance last on skew or no refill.
-		b.last = now
+// Tokens returns the tokens currently available, including refill since the last
+// attempt, and the bucket capacity.
+func (b *TokenBucketBudget) Tokens() (available, capacity float64) {
+	if b == nil {
+		return 0, 0
+	}
+	return b.unpack(b.state.Load(), b.now()), b.capacity
+}
+
+func (b *TokenBucketBudget) AllowAttempt(ctx context.Context, _ policy.PolicyKey, attemptIdx int, kind AttemptKind, ref policy.BudgetRef) Decision {
+	if b == nil {
+		return Decision{Allowed: false, Reason: ReasonBudgetNil}
 	}
 
-	cost := 1
+	need := 1.0
 	if ref.Cost > 0 {
-		cost = ref.Cost
+		need = float64(ref.Cost)
 	}
-	need := float64(cost)
-	if need <= 0 {
-		need = 1
+	// Tokens that must remain after this attempt, held for higher-priority calls.
+	var reserve float64
+	if b.thresholds != (PriorityThresholds{}) && (attemptIdx > 0 || kind == KindHedge) {
+		reserve = b.thresholds.threshold(PriorityFromContext(ctx)) * b.capacity
 	}
 
-	if b.tokens >= need {
-		b.tokens -= need
-		return Decision{Allowed: true, Reason: ReasonAllowed}
+	for {
+		old := b.state.Load()
+		now := b.now()
+		tokens := b.unpack(old, now)
+		if tokens < need-tokenEpsilon {
+			return Decision{Allowed: false, Reason: ReasonBudgetDenied}
+		}
+		if reserve > 0 && tokens-need < reserve-tokenEpsilon {
+			return Decision{Allowed: false, Reason: ReasonPriorityShed}
+		}
+		if b.state.CompareAndSwap(old, b.pack(math.Max(tokens-need, 0), now)) {
+			return Decision{Allowed: true, Reason: ReasonAllowed}
+		}
+	}
+}
+
+// Refund returns ref.Cost tokens to the bucket, up to its capacity.
+func (b *TokenBucketBudget) Refund(_ context.Context, _ policy.PolicyKey, _ AttemptKind, ref policy.BudgetRef) {
+	if b == nil || ref.Cost <= 0 {
+		return
+	}
+	for {
+		old := b.state.Load()
+		now := b.now()
+		tokens := math.Min(b.unpack(old, now)+float64(ref.Cost), b.capacity)
+		if b.state.CompareAndSwap(old, b.pack(tokens, now)) {
+			return
+		}
 	}
-	return Decision{Allowed: false, Reason: ReasonBudgetDenied}
 }
@@ -3,6 +3,7 @@ package budget
 import (
 	"context"
 	"math/rand"
+	"slices"
 	"sync"
 	"sync/atomic"
 	"testing"
@@ -49,3 +50,107 @@ func TestTokenBucketBudget_ConcurrentUsage(t *testing.T) {
 		t.Errorf("deniedCount=%d, want 1000", deniedCount)
 	}
 }
+
+func TestTokenBucketBudget_RefillCappedAtCapacity(t *testing.T) {
+	b := NewTokenBucketBudget(2, 1000) // 1 token per millisecond
+	ctx := context.Background()
+	ref := policy.BudgetRef{Cost: 1}
+
+	for i := 0; i < 2; i++ {
+		if !b.AllowAttempt(ctx, policy.PolicyKey{}, 0, KindRetry, ref).Allowed {
+			t.Fatalf("attempt %d denied on a full bucket", i)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if available, capacity := b.Tokens(); available != capacity || capacity != 2 {
+		t.Fatalf("Tokens() = %v, %v; want 2, 2", available, capacity)
+	}
+
+	allowed := 0
+	for i := 0; i < 5; i++ {
+		if b.AllowAttempt(ctx, policy.PolicyKey{}, 0, KindRetry, ref).Allowed {
+			allowed++
+		}
+	}
+	if allowed < 2 || allowed > 3 {
+		t.Fatalf("allowed %d of 5 attempts after refill, want capacity (2) plus at most one refilled token", allowed)
+	}
+}
+
+func TestTokenBucketBudget_PriorityThresholds(t *testing.T) {
+	b := NewTokenBucketBudget(10, 0).WithPriorityThresholds(PriorityThresholds{Low: 0.5, Normal: 0.2})
+	low := WithPriority(context.Background(), PriorityLow)
+	high := WithPriority(context.Background(), PriorityHigh)
+	normal := context.Background()
+	key := policy.PolicyKey{}
+
+	allow := func(ctx context.Context, attemptIdx int) Decision {
+		return b.AllowAttempt(ctx, key, attemptIdx, KindRetry, policy.BudgetRef{})
+	}
+
+	// 10 tokens: low-priority retries may spend down to 5.
+	for i := 0; i < 5; i++ {
+		if d := allow(low, 1); !d.Allowed {
+			t.Fatalf("low retry %d denied: %q", i, d.Reason)
+		}
+	}
+	if d := allow(low, 1); d.Allowed || d.Reason != ReasonPriorityShed {
+		t.Fatalf("low retry at reserve = %+v, want %q", d, ReasonPriorityShed)
+	}
+	// First attempts are never held back.
+	if d := allow(low, 0); !d.Allowed {
+		t.Fatalf("low first attempt denied: %q", d.Reason)
+	}
+	// 4 tokens: normal-priority retries may spend down to 2.
+	for i := 0; i < 2; i++ {
+		if d := allow(normal, 1); !d.Allowed {
+			t.Fatalf("normal retry %d denied: %q", i, d.Reason)
+		}
+	}
+	if d := allow(normal, 1); d.Allowed || d.Reason != ReasonPriorityShed {
+		t.Fatalf("normal retry at reserve = %+v, want %q", d, ReasonPriorityShed)
+	}
+	// High-priority retries use the rest.
+	for i := 0; i < 2; i++ {
+		if d := allow(high, 1); !d.Allowed {
+			t.Fatalf("high retry %d denied: %q", i, d.Reason)
+		}
+	}
+	if d := allow(high, 1); d.Allowed || d.Reason != ReasonBudgetDenied {
+		t.Fatalf("high retry on empty bucket = %+v, want %q", d, ReasonBudgetDenied)
+	}
+}
+
+func TestTokenBucketBudget_Refund(t *testing.T) {
+	b := NewTokenBucketBudget(4, 0)
+	ctx := context.Background()
+
+	if !b.AllowAttempt(ctx, policy.PolicyKey{}, 0, KindRetry, policy.BudgetRef{Cost: 3}).Allowed {
+		t.Fatal("reservation of 3 denied on a full bucket")
+	}
+	b.Refund(ctx, policy.PolicyKey{}, KindRetry, policy.BudgetRef{Cost: 2})
+	if available, _ := b.Tokens(); available != 3 {
+		t.Fatalf("available = %v after refunding 2 of 3, want 3", available)
+	}
+	b.Refund(ctx, policy.PolicyKey{}, KindRetry, policy.BudgetRef{Cost: 5})
+	if available, capacity := b.Tokens(); available != capacity {
+		t.Fatalf("available = %v, want refunds capped at capacity %v", available, capacity)
+	}
+}
+
+func TestKnownReasons(t *testing.T) {
+	reasons := KnownReasons()
+	for _, r := range []string{ReasonAllowed, ReasonBudgetDenied, ReasonPriorityShed, ReasonReserved} {
+		if !slices.Contains(reasons, r) {
+			t.Errorf("KnownReasons() = %v, missing %q", reasons, r)
+		}
+	}
+	if !slices.IsSorted(reasons) {
+		t.Errorf("KnownReasons() = %v, want sorted", reasons)
+	}
+	reasons[0] = "changed"
+	if KnownReasons()[0] == "changed" {
+		t.Error("KnownReasons returned the catalog itself, want a copy")
+	}
+}
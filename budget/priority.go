@@ -0,0 +1,63 @@
+package budget
+
+import "context"
+
+// Priority ranks calls for budgets that shed retries by priority. The zero value is
+// PriorityNormal, the priority of calls whose context carries none.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+func (p Priority) String() string {
+	switch {
+	case p < PriorityNormal:
+		return "low"
+	case p > PriorityNormal:
+		return "high"
+	default:
+		return "normal"
+	}
+}
+
+type priorityKey struct{}
+
+// WithPriority returns a context whose calls' retries and hedges are gated at priority
+// p by budgets with priority thresholds.
+func WithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority set by WithPriority, or PriorityNormal.
+func PriorityFromContext(ctx context.Context) Priority {
+	if ctx == nil {
+		return PriorityNormal
+	}
+	p, _ := ctx.Value(priorityKey{}).(Priority)
+	return p
+}
+
+// PriorityThresholds hold back retries and hedges of lower-priority calls while a
+// budget runs low, so the remaining capacity goes to higher-priority calls first. Each
+// field is the fraction of capacity (0 to 1) that must remain after a retry or hedge
+// of that priority; high-priority calls and first attempts are never held back.
+// Attempts held back are denied with ReasonPriorityShed.
+type PriorityThresholds struct {
+	Low    float64
+	Normal float64
+}
+
+// threshold returns the fraction of capacity a retry at p must leave.
+func (t PriorityThresholds) threshold(p Priority) float64 {
+	switch {
+	case p < PriorityNormal:
+		return t.Low
+	case p == PriorityNormal:
+		return t.Normal
+	default:
+		return 0
+	}
+}
@@ -1,5 +1,7 @@
 package budget
 
+import "slices"
+
 // Standard Decision.Reason strings.
 const (
 	ReasonAllowed           = "allowed"
@@ -9,4 +11,12 @@ const (
 	ReasonPanicInBudget     = "panic_in_budget"
 	ReasonBudgetRegistryNil = "budget_registry_nil"
 	ReasonBudgetNil         = "budget_nil"
+	ReasonPriorityShed      = "priority_shed"
+	ReasonReserved          = "reserved" // Allowed from the call's up-front reservation.
 )
+
+// KnownReasons returns every Decision.Reason the built-in budgets and the executor
+// produce, sorted, for dashboards and tools that validate reason values. The list is
+// generated from source (see docs/reference/reason-codes.md); custom budgets may add
+// their own.
+func KnownReasons() []string { return slices.Clone(knownReasons) }
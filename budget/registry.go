@@ -2,20 +2,47 @@ package budget
 
 import (
 	"errors"
+	"sort"
 	"strings"
 	"sync"
+	"sync/atomic"
 
 	"github.com/aponysus/recourse/internal"
 )
 
 // Registry is a thread-safe name → Budget map.
+//
+// Budgets are registered rarely and looked up on every attempt, so the map is
+// copy-on-write: Get reads the current snapshot without locking, and writers replace it.
 type Registry struct {
-	mu sync.RWMutex
-	m  map[string]Budget
+	mu sync.Mutex // serializes writers
+	m  atomic.Pointer[map[string]Budget]
 }
 
 func NewRegistry() *Registry {
-	return &Registry{m: make(map[string]Budget)}
+	return &Registry{}
+}
+
+// update replaces the map with a modified copy. Callers hold r.mu.
+func (r *Registry) update(fn func(m map[string]Budget)) {
+	var old map[string]Budget
+	if p := r.m.Load(); p != nil {
+		old = *p
+	}
+	next := make(map[string]Budget, len(old)+1)
+	for k, v := range old {
+		next[k] = v
+	}
+	fn(next)
+	r.m.Store(&next)
+}
+
+// snapshot returns the current map; it must not be modified.
+func (r *Registry) snapshot() map[string]Budget {
+	if p := r.m.Load(); p != nil {
+		return *p
+	}
+	return nil
 }
 
 // Register registers a budget with validation.
@@ -34,11 +61,7 @@ func (r *Registry) Register(name string, b Budget) error {
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
-
-	if r.m == nil {
-		r.m = make(map[string]Budget)
-	}
-	r.m[name] = b
+	r.update(func(m map[string]Budget) { m[name] = b })
 	return nil
 }
 
@@ -49,6 +72,19 @@ func (r *Registry) MustRegister(name string, b Budget) {
 	}
 }
 
+// Unregister removes the budget registered under name, if any.
+func (r *Registry) Unregister(name string) {
+	if r == nil {
+		return
+	}
+	name = strings.TrimSpace(name)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.snapshot()[name]; ok {
+		r.update(func(m map[string]Budget) { delete(m, name) })
+	}
+}
+
 func (r *Registry) Get(name string) (Budget, bool) {
 	if r == nil {
 		return nil, false
@@ -58,8 +94,20 @@ func (r *Registry) Get(name string) (Budget, bool) {
 		return nil, false
 	}
 
-	r.mu.RLock()
-	b, ok := r.m[name]
-	r.mu.RUnlock()
+	b, ok := r.snapshot()[name]
 	return b, ok && b != nil
 }
+
+// Names returns the registered budget names in sorted order.
+func (r *Registry) Names() []string {
+	if r == nil {
+		return nil
+	}
+	m := r.snapshot()
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -21,7 +21,9 @@ type Decision struct {
 	Allowed bool
 	Reason  string
 
-	// Release, when non-nil, is called exactly once after an allowed attempt finishes.
+	// Release, when non-nil, is called exactly once after an allowed attempt finishes,
+	// or, for a hedged-group attempt canceled because the group finished, as soon as it
+	// is canceled, even if the operation keeps running.
 	Release func()
 }
 
@@ -29,3 +31,10 @@ type Decision struct {
 type Budget interface {
 	AllowAttempt(ctx context.Context, key policy.PolicyKey, attemptIdx int, kind AttemptKind, ref policy.BudgetRef) Decision
 }
+
+// Refunder is implemented by budgets that can take back units, such as the unused part
+// of a call's up-front reservation (see policy.BudgetRef.Reserve). Refund returns
+// ref.Cost units; budgets without it keep reserved units that go unused.
+type Refunder interface {
+	Refund(ctx context.Context, key policy.PolicyKey, kind AttemptKind, ref policy.BudgetRef)
+}
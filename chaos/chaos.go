@@ -0,0 +1,216 @@
+package chaos
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// ErrInjected is the error injected by faults without their own Err.
+var ErrInjected = errors.New("recourse: chaos: injected fault")
+
+// Faults configures fault injection for one key. Each fault is applied to an attempt
+// independently with its probability (0 to 1), in order: latency, panic, error. An
+// attempt that survives every fault runs the operation.
+type Faults struct {
+	// LatencyProbability is the chance to delay an attempt by Latency before it runs.
+	LatencyProbability float64
+	Latency            time.Duration
+	// ErrorProbability is the chance to fail an attempt with Err (ErrInjected if nil)
+	// instead of running the operation.
+	ErrorProbability float64
+	Err              error
+	// PanicProbability is the chance to panic in an attempt with a Panic value.
+	PanicProbability float64
+	// ForceCircuitOpen makes executors with fault injection enabled reject calls as if
+	// the key's circuit were open (see retry.WithFaultInjection). Wrapped operations
+	// ignore it.
+	ForceCircuitOpen bool
+}
+
+// Panic is the value of injected panics.
+type Panic struct {
+	Key policy.PolicyKey
+}
+
+func (p Panic) String() string { return fmt.Sprintf("recourse: chaos: injected panic for %s", p.Key) }
+
+// FaultsFromSpec converts a control-plane fault spec.
+func FaultsFromSpec(spec controlplane.FaultSpec) Faults {
+	f := Faults{
+		LatencyProbability: spec.LatencyProbability,
+		Latency:            spec.Latency,
+		ErrorProbability:   spec.ErrorProbability,
+		PanicProbability:   spec.PanicProbability,
+		ForceCircuitOpen:   spec.ForceCircuitOpen,
+	}
+	if spec.Error != "" {
+		f.Err = fmt.Errorf("%w: %s", ErrInjected, spec.Error)
+	}
+	return f
+}
+
+// Injector applies faults to wrapped operations. Faults set in code (Set) take
+// precedence over faults from the control plane (Watch). It is safe for concurrent use.
+type Injector struct {
+	float func() float64
+	sleep func(context.Context, time.Duration) error
+
+	mu     sync.RWMutex
+	local  map[policy.PolicyKey]Faults
+	remote map[policy.PolicyKey]Faults
+}
+
+// Option configures an Injector.
+type Option func(*Injector)
+
+// WithRand sets the source of random numbers in [0, 1). Defaults to math/rand/v2.
+func WithRand(float func() float64) Option {
+	return func(i *Injector) {
+		i.float = float
+	}
+}
+
+// WithSleeper sets how injected latency waits, e.g. on a recoursetest.Clock.
+// Defaults to real timers.
+func WithSleeper(s retry.Sleeper) Option {
+	return func(i *Injector) {
+		i.sleep = s.Sleep
+	}
+}
+
+// NewInjector returns an Injector with no faults.
+func NewInjector(opts ...Option) *Injector {
+	i := &Injector{
+		float:  rand.Float64,
+		sleep:  sleep,
+		local:  make(map[policy.PolicyKey]Faults),
+		remote: make(map[policy.PolicyKey]Faults),
+	}
+	for _, opt := range opts {
+		opt(i)
+	}
+	return i
+}
+
+// Set configures the faults for key, replacing any set before.
+func (i *Injector) Set(key policy.PolicyKey, f Faults) {
+	i.mu.Lock()
+	i.local[key] = f
+	i.mu.Unlock()
+}
+
+// Clear removes the faults set for key. Faults from the control plane still apply.
+func (i *Injector) Clear(key policy.PolicyKey) {
+	i.mu.Lock()
+	delete(i.local, key)
+	i.mu.Unlock()
+}
+
+// Watch applies the faults in rp's resources ("faults" in bundles), now and whenever
+// they change. Keys are parsed with policy.ParseKey.
+func (i *Injector) Watch(rp controlplane.ResourceProvider) {
+	rp.WatchResources(func(r controlplane.Resources) {
+		remote := make(map[policy.PolicyKey]Faults, len(r.Faults))
+		for key, spec := range r.Faults {
+			remote[policy.ParseKey(key)] = FaultsFromSpec(spec)
+		}
+		i.mu.Lock()
+		i.remote = remote
+		i.mu.Unlock()
+	})
+}
+
+// Faults returns the faults in effect for key.
+func (i *Injector) Faults(key policy.PolicyKey) (Faults, bool) {
+	if i == nil {
+		return Faults{}, false
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	if f, ok := i.local[key]; ok {
+		return f, true
+	}
+	f, ok := i.remote[key]
+	return f, ok
+}
+
+// Inject applies key's faults to one attempt: it may wait, panic, or return an error.
+// A nil error means the operation should run. If ctx is done during injected latency,
+// Inject returns ctx.Err().
+func (i *Injector) Inject(ctx context.Context, key policy.PolicyKey) error {
+	f, ok := i.Faults(key)
+	if !ok {
+		return nil
+	}
+	if f.Latency > 0 && i.roll(f.LatencyProbability) {
+		if err := i.sleep(ctx, f.Latency); err != nil {
+			return err
+		}
+	}
+	if i.roll(f.PanicProbability) {
+		panic(Panic{Key: key})
+	}
+	if i.roll(f.ErrorProbability) {
+		if f.Err != nil {
+			return f.Err
+		}
+		return ErrInjected
+	}
+	return nil
+}
+
+// CircuitForcedOpen reports whether key's faults force its circuit open.
+func (i *Injector) CircuitForcedOpen(key policy.PolicyKey) bool {
+	f, _ := i.Faults(key)
+	return f.ForceCircuitOpen
+}
+
+func (i *Injector) roll(p float64) bool {
+	return p > 0 && (p >= 1 || i.float() < p)
+}
+
+// Wrap returns op with key's faults injected into every call.
+func Wrap(i *Injector, key policy.PolicyKey, op retry.Operation) retry.Operation {
+	if i == nil {
+		return op
+	}
+	return func(ctx context.Context) error {
+		if err := i.Inject(ctx, key); err != nil {
+			return err
+		}
+		return op(ctx)
+	}
+}
+
+// WrapValue returns op with key's faults injected into every call.
+func WrapValue[T any](i *Injector, key policy.PolicyKey, op retry.OperationValue[T]) retry.OperationValue[T] {
+	if i == nil {
+		return op
+	}
+	return func(ctx context.Context) (T, error) {
+		if err := i.Inject(ctx, key); err != nil {
+			var zero T
+			return zero, err
+		}
+		return op(ctx)
+	}
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,174 @@
+package chaos_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/chaos"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/recoursetest"
+	"github.com/aponysus/recourse/retry"
+)
+
+var key = policy.ParseKey("svc.Get")
+
+func TestInjector_RetriesAbsorbInjectedErrors(t *testing.T) {
+	// Rolls: attempt 1 fails (0.1 < 0.5), attempt 2 passes (0.9).
+	rolls := []float64{0.1, 0.9}
+	inj := chaos.NewInjector(chaos.WithRand(func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}))
+	inj.Set(key, chaos.Faults{ErrorProbability: 0.5})
+
+	exec := retry.NewExecutor(retry.WithPolicy("svc.Get", policy.MaxAttempts(3), policy.ConstantBackoff(time.Millisecond)))
+	op := recoursetest.NewScript[string]().Succeed("ok")
+	v, err := retry.DoValue(context.Background(), exec, key, chaos.WrapValue(inj, key, op.Value))
+	if err != nil || v != "ok" || op.Calls() != 1 {
+		t.Fatalf("DoValue = %q, %v with %d op calls; want ok after one injected failure", v, err, op.Calls())
+	}
+
+	inj.Set(key, chaos.Faults{ErrorProbability: 1})
+	err = exec.Do(context.Background(), key, chaos.Wrap(inj, key, op.Call))
+	if !errors.Is(err, chaos.ErrInjected) || !errors.Is(err, retry.ErrAttemptsExhausted) {
+		t.Fatalf("Do = %v, want exhausted injected errors", err)
+	}
+
+	inj.Clear(key)
+	if err := chaos.Wrap(inj, key, op.Call)(context.Background()); err != nil {
+		t.Fatalf("after Clear: %v", err)
+	}
+}
+
+func TestInjector_LatencyAndPanic(t *testing.T) {
+	clock := recoursetest.NewClock(time.Time{})
+	inj := chaos.NewInjector(chaos.WithSleeper(clock))
+	inj.Set(key, chaos.Faults{LatencyProbability: 1, Latency: time.Second})
+
+	done := make(chan error, 1)
+	go func() { done <- inj.Inject(context.Background(), key) }()
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := clock.BlockUntil(ctx, 1); err != nil {
+		t.Fatalf("no injected latency: %v", err)
+	}
+	clock.Advance(time.Second)
+	if err := <-done; err != nil {
+		t.Fatalf("Inject = %v", err)
+	}
+
+	inj.Set(key, chaos.Faults{PanicProbability: 1})
+	defer func() {
+		if p, ok := recover().(chaos.Panic); !ok || p.Key != key {
+			t.Fatalf("recovered %v, want chaos.Panic for %s", p, key)
+		}
+	}()
+	_ = inj.Inject(context.Background(), key)
+	t.Fatal("Inject did not panic")
+}
+
+func TestInjector_Watch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"faults": {"svc.Get": {"error_probability": 1, "error": "boom"}}}`)
+	p := controlplane.NewFileProvider(path)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	inj := chaos.NewInjector()
+	inj.Watch(p)
+	err := inj.Inject(context.Background(), key)
+	if !errors.Is(err, chaos.ErrInjected) || err.Error() != chaos.ErrInjected.Error()+": boom" {
+		t.Fatalf("Inject = %v, want injected boom", err)
+	}
+
+	// Local faults win over the control plane.
+	inj.Set(key, chaos.Faults{})
+	if err := inj.Inject(context.Background(), key); err != nil {
+		t.Fatalf("Inject with local override = %v", err)
+	}
+	inj.Clear(key)
+
+	write(`{}`)
+	if err := p.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if err := inj.Inject(context.Background(), key); err != nil {
+		t.Fatalf("Inject after faults removed = %v", err)
+	}
+}
+
+func TestExecutor_FaultInjectionFromControlPlane(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bundle.json")
+	p := controlplane.NewFileProvider(path)
+	write := func(body string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := p.Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write(`{"faults": {"svc.Get": {"error_probability": 1}}}`)
+
+	exec := retry.NewExecutorFromOptions(retry.ExecutorOptions{
+		Provider:          p,
+		MissingPolicyMode: retry.FailureFallback,
+		Sleeper:           noSleep{},
+		FaultInjector:     chaos.NewInjector(),
+	})
+	ctx := context.Background()
+
+	// Operations need no wrapping: the executor injects the control plane's faults.
+	calls := 0
+	op := func(context.Context) error { calls++; return nil }
+	err := exec.Do(ctx, key, op)
+	if !errors.Is(err, chaos.ErrInjected) || calls != 0 {
+		t.Fatalf("Do = %v with %d op calls, want injected errors only", err, calls)
+	}
+
+	write(`{"faults": {"svc.Get": {"force_circuit_open": true}}}`)
+	err = exec.Do(ctx, key, op)
+	var open retry.CircuitOpenError
+	if !errors.As(err, &open) || open.Reason != circuit.ReasonCircuitForcedOpen || calls != 0 {
+		t.Fatalf("Do = %v with %d op calls, want forced open circuit", err, calls)
+	}
+
+	write(`{}`)
+	if err := exec.Do(ctx, key, op); err != nil || calls != 1 {
+		t.Fatalf("Do after faults removed = %v with %d op calls", err, calls)
+	}
+}
+
+// noSleep skips backoff.
+type noSleep struct{}
+
+func (noSleep) Sleep(context.Context, time.Duration) error { return nil }
+
+func (noSleep) After(time.Duration) <-chan time.Time {
+	ch := make(chan time.Time, 1)
+	ch <- time.Time{}
+	return ch
+}
+
+func TestWrap_NilInjector(t *testing.T) {
+	calls := 0
+	op := chaos.Wrap(nil, key, func(context.Context) error { calls++; return nil })
+	if err := op(context.Background()); err != nil || calls != 1 {
+		t.Fatalf("op = %v, calls %d", err, calls)
+	}
+}
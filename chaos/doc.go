@@ -0,0 +1,16 @@
+// Package chaos injects faults (latency, errors, panics) into operations by policy key,
+// to verify that policies behave as intended under failure: that retries, hedges,
+// budgets, and circuit breakers kick in where expected.
+//
+// Wrap operations with an Injector and configure faults per key, in code or from the
+// control plane:
+//
+//	inj := chaos.NewInjector()
+//	inj.Set(key, chaos.Faults{ErrorProbability: 0.2, LatencyProbability: 0.5, Latency: 300 * time.Millisecond})
+//
+//	user, err := retry.DoValue(ctx, exec, key, chaos.WrapValue(inj, key, getUser))
+//
+// Faults apply per attempt, so hedged and retried attempts each roll again. A nil
+// Injector, or one with no faults for a key, runs operations unchanged; keep wrappers
+// in place and enable faults only in the environments under test.
+package chaos
@@ -110,6 +110,24 @@ func (cb *ConsecutiveFailureBreaker) RecordFailure(ctx context.Context) {
 	}
 }
 
+// CancelProbe frees the slot of a half-open probe that ended without a verdict.
+func (cb *ConsecutiveFailureBreaker) CancelProbe(ctx context.Context) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.updateStateLocked() == StateHalfOpen && cb.probesSent > 0 {
+		cb.probesSent--
+	}
+}
+
+// ForceOpen opens the breaker now, e.g. from an operator tool. It half-opens after the
+// usual cooldown.
+func (cb *ConsecutiveFailureBreaker) ForceOpen() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+	cb.transitionTo(StateOpen)
+}
+
 func (cb *ConsecutiveFailureBreaker) updateStateLocked() State {
 	if cb.state == StateOpen {
 		if time.Since(cb.openTime) >= cb.cooldown {
@@ -2,6 +2,7 @@ package circuit
 
 import (
 	"context"
+	"slices"
 	"testing"
 	"time"
 )
@@ -91,3 +92,31 @@ func TestConsecutiveFailureBreaker_Transitions(t *testing.T) {
 		t.Fatalf("expected allowed=true after closing")
 	}
 }
+
+func TestBreaker_CancelProbe(t *testing.T) {
+	ctx := context.Background()
+	cb := NewConsecutiveFailureBreaker(1, time.Millisecond)
+	cb.RecordFailure(ctx)
+	time.Sleep(5 * time.Millisecond)
+
+	if d := cb.Allow(ctx); !d.Allowed || d.State != StateHalfOpen {
+		t.Fatalf("Allow = %+v, want a half-open probe", d)
+	}
+	if d := cb.Allow(ctx); d.Allowed {
+		t.Fatalf("Allow = %+v, want the probe limit", d)
+	}
+	cb.CancelProbe(ctx)
+	if d := cb.Allow(ctx); !d.Allowed {
+		t.Fatalf("Allow = %+v after CancelProbe, want a new probe", d)
+	}
+	if cb.State() != StateHalfOpen {
+		t.Fatalf("state = %v, want half-open", cb.State())
+	}
+}
+
+func TestKnownReasons(t *testing.T) {
+	want := []string{ReasonCircuitForcedOpen, ReasonCircuitHalfOpenProbeLimit, ReasonCircuitOpen, ReasonCircuitProbeFailed}
+	if got := KnownReasons(); !slices.Equal(got, want) {
+		t.Fatalf("KnownReasons() = %v, want %v", got, want)
+	}
+}
@@ -1,22 +1,40 @@
 package circuit
 
 import (
+	"sort"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/aponysus/recourse/policy"
 )
 
 // Registry manages circuit breakers for different policies.
+//
+// Lookups of existing breakers are lock-free, so many goroutines calling through the
+// same executor do not contend on the registry; only creating a breaker and changing
+// the override take the mutex.
 type Registry struct {
-	mu       sync.RWMutex
-	breakers map[policy.PolicyKey]CircuitBreaker
+	mu       sync.Mutex
+	breakers atomic.Pointer[sync.Map] // policy.PolicyKey -> CircuitBreaker
+
+	threshold int           // override for CircuitPolicy.Threshold when > 0; guarded by mu
+	cooldown  time.Duration // override for CircuitPolicy.Cooldown when > 0; guarded by mu
 }
 
 // NewRegistry creates a new circuit breaker registry.
 func NewRegistry() *Registry {
-	return &Registry{
-		breakers: make(map[policy.PolicyKey]CircuitBreaker),
+	r := &Registry{}
+	r.breakers.Store(new(sync.Map))
+	return r
+}
+
+func (r *Registry) load() *sync.Map {
+	if m := r.breakers.Load(); m != nil {
+		return m
 	}
+	r.breakers.CompareAndSwap(nil, new(sync.Map))
+	return r.breakers.Load()
 }
 
 // Get returns an existing breaker or creates a new one for the given policy.
@@ -25,24 +43,61 @@ func (r *Registry) Get(key policy.PolicyKey, config policy.CircuitPolicy) Circui
 		return nil
 	}
 
-	r.mu.RLock()
-	cb, ok := r.breakers[key]
-	r.mu.RUnlock()
-
-	if ok {
-		return cb
+	if cb, ok := r.load().Load(key); ok {
+		return cb.(CircuitBreaker)
 	}
 
 	r.mu.Lock()
 	defer r.mu.Unlock()
 
-	// Double check
-	if cb, ok := r.breakers[key]; ok {
-		return cb
+	// Double check; SetOverride may have swapped the map.
+	m := r.load()
+	if cb, ok := m.Load(key); ok {
+		return cb.(CircuitBreaker)
 	}
 
 	// Create new breaker
-	cb = NewConsecutiveFailureBreaker(config.Threshold, config.Cooldown)
-	r.breakers[key] = cb
+	if r.threshold > 0 {
+		config.Threshold = r.threshold
+	}
+	if r.cooldown > 0 {
+		config.Cooldown = r.cooldown
+	}
+	cb := CircuitBreaker(NewConsecutiveFailureBreaker(config.Threshold, config.Cooldown))
+	m.Store(key, cb)
 	return cb
 }
+
+// SetOverride sets global circuit parameters that take precedence over every policy's
+// threshold and cooldown; zero keeps the policy's value, so SetOverride(0, 0) clears the
+// override. When the parameters change, existing breakers are discarded and recreated,
+// closed, on next use.
+func (r *Registry) SetOverride(threshold int, cooldown time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if threshold == r.threshold && cooldown == r.cooldown {
+		return
+	}
+	r.threshold, r.cooldown = threshold, cooldown
+	r.breakers.Store(new(sync.Map))
+}
+
+// Lookup returns the breaker for key, if one has been created.
+func (r *Registry) Lookup(key policy.PolicyKey) (CircuitBreaker, bool) {
+	cb, ok := r.load().Load(key)
+	if !ok {
+		return nil, false
+	}
+	return cb.(CircuitBreaker), true
+}
+
+// Keys returns the keys that have a breaker, sorted.
+func (r *Registry) Keys() []policy.PolicyKey {
+	keys := []policy.PolicyKey{}
+	r.load().Range(func(k, _ any) bool {
+		keys = append(keys, k.(policy.PolicyKey))
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
@@ -0,0 +1,68 @@
+package circuit
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestRegistry_ConcurrentGetSharesBreaker(t *testing.T) {
+	reg := NewRegistry()
+	key := policy.ParseKey("svc.Method")
+	cfg := policy.CircuitPolicy{Enabled: true, Threshold: 3, Cooldown: time.Second}
+
+	const n = 32
+	got := make([]CircuitBreaker, n)
+	var wg sync.WaitGroup
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			got[i] = reg.Get(key, cfg)
+		}(i)
+	}
+	wg.Wait()
+
+	for i := 1; i < n; i++ {
+		if got[i] != got[0] {
+			t.Fatalf("Get returned different breakers for the same key")
+		}
+	}
+	if keys := reg.Keys(); len(keys) != 1 || keys[0] != key {
+		t.Fatalf("Keys() = %v, want [%v]", keys, key)
+	}
+}
+
+func TestRegistry_SetOverrideRecreatesBreakers(t *testing.T) {
+	reg := NewRegistry()
+	key := policy.ParseKey("svc.Method")
+	cfg := policy.CircuitPolicy{Enabled: true, Threshold: 3, Cooldown: time.Second}
+
+	before := reg.Get(key, cfg)
+	reg.SetOverride(1, time.Minute)
+	if _, ok := reg.Lookup(key); ok {
+		t.Fatalf("Lookup found a breaker after SetOverride")
+	}
+	after := reg.Get(key, cfg)
+	if after == before {
+		t.Fatalf("Get returned the pre-override breaker")
+	}
+	if cb := after.(*ConsecutiveFailureBreaker); cb.threshold != 1 || cb.cooldown != time.Minute {
+		t.Fatalf("breaker threshold=%d cooldown=%v, want override 1/1m", cb.threshold, cb.cooldown)
+	}
+
+	reg.SetOverride(1, time.Minute)
+	if cb, _ := reg.Lookup(key); cb != after {
+		t.Fatalf("SetOverride with unchanged parameters discarded breakers")
+	}
+}
+
+func TestRegistry_ZeroValue(t *testing.T) {
+	var reg Registry
+	key := policy.ParseKey("svc.Method")
+	if reg.Get(key, policy.CircuitPolicy{Enabled: true}) == nil {
+		t.Fatalf("zero Registry did not create a breaker")
+	}
+}
@@ -2,6 +2,7 @@ package circuit
 
 import (
 	"context"
+	"slices"
 )
 
 // State represents the state of a circuit breaker.
@@ -16,8 +17,15 @@ const (
 const (
 	ReasonCircuitOpen               = "circuit_open"
 	ReasonCircuitHalfOpenProbeLimit = "circuit_half_open_probe_limit"
+	ReasonCircuitProbeFailed        = "circuit_probe_failed"
+	ReasonCircuitForcedOpen         = "circuit_forced_open"
 )
 
+// KnownReasons returns every Decision.Reason the built-in breakers and the executor
+// reject calls with, sorted. The list is generated from source (see
+// docs/reference/reason-codes.md).
+func KnownReasons() []string { return slices.Clone(knownReasons) }
+
 func (s State) String() string {
 	switch s {
 	case StateClosed:
@@ -52,3 +60,11 @@ type CircuitBreaker interface {
 	// State returns the current state of the breaker.
 	State() State
 }
+
+// ProbeCanceler is implemented by breakers that can free a half-open probe slot when
+// the probe ends without a verdict, e.g. because its call was canceled. Without it, a
+// probe that records neither success nor failure holds its slot until the breaker
+// next opens.
+type ProbeCanceler interface {
+	CancelProbe(ctx context.Context)
+}
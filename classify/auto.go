@@ -1,10 +1,13 @@
 package classify
 
+import "errors"
+
 // AutoClassifier delegates to a specific classifier based on the error type,
 // or falls back to a generic default.
 //
 // Behavior:
 // - If error implements HTTPError: uses HTTPClassifier.
+// - If error wraps a SQLError: uses SQLClassifier.
 // - Otherwise: uses AlwaysRetryOnError.
 type AutoClassifier struct{}
 
@@ -12,5 +15,9 @@ func (AutoClassifier) Classify(val any, err error) Outcome {
 	if _, ok := err.(HTTPError); ok {
 		return HTTPClassifier{}.Classify(val, err)
 	}
+	var se SQLError
+	if errors.As(err, &se) {
+		return SQLClassifier{}.Classify(val, err)
+	}
 	return AlwaysRetryOnError{}.Classify(val, err)
 }
@@ -9,6 +9,7 @@ import (
 const (
 	ClassifierAlwaysRetryOnError = "always"
 	ClassifierHTTP               = "http"
+	ClassifierSQL                = "sql"
 )
 
 // RegisterBuiltins registers core classifiers into reg.
@@ -18,6 +19,7 @@ func RegisterBuiltins(reg *Registry) {
 	}
 	reg.Register(ClassifierAlwaysRetryOnError, AlwaysRetryOnError{})
 	reg.Register(ClassifierHTTP, HTTPClassifier{})
+	reg.Register(ClassifierSQL, SQLClassifier{})
 	reg.Register("auto", AutoClassifier{})
 }
 
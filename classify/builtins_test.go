@@ -2,7 +2,12 @@ package classify
 
 import (
 	"context"
+	"database/sql"
+	"database/sql/driver"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -88,6 +93,31 @@ func TestHTTPClassifier_429_RetryAfter_Override(t *testing.T) {
 	}
 }
 
+func TestHTTPClassifier_ThrottleStatuses(t *testing.T) {
+	c := HTTPClassifier{ThrottleStatuses: map[int]struct{}{429: {}}}
+	out := c.Classify(nil, testHTTPError{status: 429, method: "GET", retryAfter: time.Second, hasRetry: true})
+	if out.Kind != OutcomeThrottled || out.Reason != "http_429" || out.BackoffOverride != time.Second {
+		t.Fatalf("out=%+v, want throttled http_429 with the Retry-After backoff", out)
+	}
+	if out := c.Classify(nil, testHTTPError{status: 503, method: "GET"}); out.Kind != OutcomeRetryable {
+		t.Fatalf("503 kind=%v, want %v", out.Kind, OutcomeRetryable)
+	}
+	if out := c.Classify(nil, testHTTPError{status: 429, method: "POST"}); out.Kind != OutcomeNonRetryable {
+		t.Fatalf("non-idempotent 429 kind=%v, want %v", out.Kind, OutcomeNonRetryable)
+	}
+}
+
+func TestHTTPClassifier_503_RetryAfter_Override(t *testing.T) {
+	c := HTTPClassifier{}
+	out := c.Classify(nil, testHTTPError{status: 503, method: "GET", retryAfter: 3 * time.Second, hasRetry: true})
+	if out.Kind != OutcomeRetryable || out.Reason != "http_5xx" {
+		t.Fatalf("out=%+v want retryable http_5xx", out)
+	}
+	if out.BackoffOverride != 3*time.Second {
+		t.Fatalf("BackoffOverride=%v want 3s", out.BackoffOverride)
+	}
+}
+
 func TestHTTPClassifier_TypeMismatch(t *testing.T) {
 	c := HTTPClassifier{}
 	out := c.Classify(nil, errors.New("nope"))
@@ -98,3 +128,76 @@ func TestHTTPClassifier_TypeMismatch(t *testing.T) {
 		t.Fatalf("expected type mismatch attributes")
 	}
 }
+
+type testSQLError string
+
+func (e testSQLError) Error() string    { return "sql error" }
+func (e testSQLError) SQLState() string { return string(e) }
+
+func TestSQLClassifier(t *testing.T) {
+	c := SQLClassifier{RetryableStates: map[string]struct{}{"XX001": {}}}
+
+	tests := []struct {
+		err    error
+		kind   OutcomeKind
+		reason string
+	}{
+		{nil, OutcomeSuccess, "success"},
+		{context.Canceled, OutcomeAbort, "context_canceled"},
+		{driver.ErrBadConn, OutcomeRetryable, "sql_bad_conn"},
+		{sql.ErrNoRows, OutcomeNonRetryable, "sql_no_rows"},
+		{sql.ErrTxDone, OutcomeNonRetryable, "sql_tx_done"},
+		{testSQLError("40001"), OutcomeRetryable, "sql_serialization_failure"},
+		{fmt.Errorf("wrapped: %w", testSQLError("40P01")), OutcomeRetryable, "sql_deadlock"},
+		{testSQLError("08006"), OutcomeRetryable, "sql_connection_exception"},
+		{testSQLError("53300"), OutcomeRetryable, "sql_insufficient_resources"},
+		{testSQLError("57P01"), OutcomeRetryable, "sql_server_shutdown"},
+		{testSQLError("XX001"), OutcomeRetryable, "sql_retryable_state"},
+		{testSQLError("23505"), OutcomeNonRetryable, "sql_error"},
+		{errors.New("nope"), OutcomeNonRetryable, "sql_error"},
+	}
+	for _, tt := range tests {
+		out := c.Classify(nil, tt.err)
+		if out.Kind != tt.kind || out.Reason != tt.reason {
+			t.Errorf("Classify(%v)=%v/%q, want %v/%q", tt.err, out.Kind, out.Reason, tt.kind, tt.reason)
+		}
+	}
+}
+
+func TestAutoClassifier_SQLError(t *testing.T) {
+	out := AutoClassifier{}.Classify(nil, fmt.Errorf("query: %w", testSQLError("23505")))
+	if out.Kind != OutcomeNonRetryable || out.Reason != "sql_error" {
+		t.Fatalf("out=%+v want nonretryable sql_error", out)
+	}
+}
+
+func TestHTTPStatusReason_Interned(t *testing.T) {
+	if got := HTTPStatusReason(429); got != "http_429" {
+		t.Fatalf("HTTPStatusReason(429)=%q", got)
+	}
+	if got := HTTPStatusReason(42); got != "http_42" {
+		t.Fatalf("HTTPStatusReason(42)=%q", got)
+	}
+	if allocs := testing.AllocsPerRun(100, func() { _ = HTTPStatusReason(503) }); allocs != 0 {
+		t.Fatalf("HTTPStatusReason allocs=%v, want 0", allocs)
+	}
+}
+
+func TestKnownReasons(t *testing.T) {
+	reasons := KnownReasons()
+	for _, r := range []string{ReasonSuccess, ReasonThrottled, ReasonHTTP5xx, "retryable_error", "sql_deadlock"} {
+		if !slices.Contains(reasons, r) {
+			t.Errorf("KnownReasons() = %v, missing %q", reasons, r)
+		}
+	}
+
+	// Open-ended reasons match a pattern's prefix.
+	reason := HTTPStatusReason(503)
+	matched := slices.ContainsFunc(KnownReasonPatterns(), func(p string) bool {
+		prefix, _, _ := strings.Cut(p, "<")
+		return strings.HasPrefix(reason, prefix)
+	})
+	if !matched {
+		t.Errorf("no pattern in %v matches %q", KnownReasonPatterns(), reason)
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"errors"
 	"reflect"
-	"strconv"
 	"strings"
 	"time"
 )
@@ -27,24 +26,28 @@ type HTTPClassifier struct {
 	// Retryable4xx is an optional set of additional retryable 4xx status codes.
 	// If nil, defaults to {408, 429}.
 	Retryable4xx map[int]struct{}
+
+	// ThrottleStatuses is an optional set of retryable status codes (typically 429 and
+	// 503) reported as OutcomeThrottled, so the executor also paces later calls.
+	ThrottleStatuses map[int]struct{}
 }
 
 func (c HTTPClassifier) Classify(_ any, err error) Outcome {
 	if err == nil {
-		return Outcome{Kind: OutcomeSuccess, Reason: "success"}
+		return Outcome{Kind: OutcomeSuccess, Reason: ReasonSuccess}
 	}
 	if errors.Is(err, context.Canceled) {
-		return Outcome{Kind: OutcomeAbort, Reason: "context_canceled"}
+		return Outcome{Kind: OutcomeAbort, Reason: ReasonContextCanceled}
 	}
 	if errors.Is(err, context.DeadlineExceeded) {
-		return Outcome{Kind: OutcomeRetryable, Reason: "context_deadline_exceeded"}
+		return Outcome{Kind: OutcomeRetryable, Reason: ReasonContextDeadlineExceeded}
 	}
 
 	he, ok := err.(HTTPError)
 	if !ok {
 		return Outcome{
 			Kind:   OutcomeNonRetryable,
-			Reason: "classifier_type_mismatch",
+			Reason: ReasonClassifierTypeMismatch,
 			Attributes: map[string]string{
 				"expected_type": "classify.HTTPError",
 				"got_type":      typeString(err),
@@ -58,52 +61,50 @@ func (c HTTPClassifier) Classify(_ any, err error) Outcome {
 
 	out := Outcome{
 		Kind:   OutcomeNonRetryable,
-		Reason: "http_non_retryable_status",
+		Reason: ReasonHTTPNonRetryableStatus,
 		Attributes: map[string]string{
-			"status": strconv.Itoa(status),
+			"status": httpStatusText(status),
 			"method": method,
 		},
 	}
 
 	if status >= 200 && status < 300 {
 		out.Kind = OutcomeSuccess
-		out.Reason = "success"
+		out.Reason = ReasonSuccess
 		return out
 	}
 
 	if status == 0 {
 		if idempotent {
 			out.Kind = OutcomeRetryable
-			out.Reason = "http_transport_error"
+			out.Reason = ReasonHTTPTransportError
 		} else {
 			out.Kind = OutcomeNonRetryable
-			out.Reason = "http_non_idempotent"
+			out.Reason = ReasonHTTPNonIdempotent
 		}
 		return out
 	}
 
 	if status >= 500 && status <= 599 {
 		if idempotent {
-			out.Kind = OutcomeRetryable
-			out.Reason = "http_5xx"
+			out.Kind = c.retryableKind(status)
+			out.Reason = ReasonHTTP5xx
+			applyRetryAfter(&out, he)
 		} else {
 			out.Kind = OutcomeNonRetryable
-			out.Reason = "http_non_idempotent"
+			out.Reason = ReasonHTTPNonIdempotent
 		}
 		return out
 	}
 
 	if status == 408 || status == 429 || c.retryable4xx(status) {
 		if idempotent {
-			out.Kind = OutcomeRetryable
-			out.Reason = "http_" + strconv.Itoa(status)
-			if d, ok := he.RetryAfter(); ok && d > 0 {
-				out.BackoffOverride = d
-				out.Attributes["retry_after"] = d.String()
-			}
+			out.Kind = c.retryableKind(status)
+			out.Reason = HTTPStatusReason(status)
+			applyRetryAfter(&out, he)
 		} else {
 			out.Kind = OutcomeNonRetryable
-			out.Reason = "http_non_idempotent"
+			out.Reason = ReasonHTTPNonIdempotent
 		}
 		return out
 	}
@@ -112,6 +113,23 @@ func (c HTTPClassifier) Classify(_ any, err error) Outcome {
 	return out
 }
 
+// applyRetryAfter uses the server's retry delay hint (Retry-After, RateLimit-Reset) as the
+// backoff for this attempt.
+func applyRetryAfter(out *Outcome, he HTTPError) {
+	if d, ok := he.RetryAfter(); ok && d > 0 {
+		out.BackoffOverride = d
+		out.Attributes["retry_after"] = d.String()
+	}
+}
+
+// retryableKind returns the outcome kind for a retryable status.
+func (c HTTPClassifier) retryableKind(status int) OutcomeKind {
+	if _, ok := c.ThrottleStatuses[status]; ok {
+		return OutcomeThrottled
+	}
+	return OutcomeRetryable
+}
+
 func (c HTTPClassifier) retryable4xx(status int) bool {
 	if c.Retryable4xx == nil {
 		return false
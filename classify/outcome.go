@@ -12,11 +12,20 @@ const (
 	OutcomeRetryable
 	OutcomeNonRetryable
 	OutcomeAbort
+	// OutcomePartial marks a usable partial value returned alongside an error (e.g. a
+	// page of results before a shard failed). Policies with RetryPolicy.AcceptPartial
+	// return it at once; others retry it like OutcomeRetryable, returning the last
+	// partial value if attempts run out.
+	OutcomePartial
+	// OutcomeThrottled marks an attempt the server rejected to shed load (e.g. HTTP 429).
+	// It is retried like OutcomeRetryable and also paces the key's later calls for a
+	// short window (see retry.WithClientThrottle).
+	OutcomeThrottled
 )
 
 // Outcome describes the classification of an attempt.
 type Outcome struct {
-	Kind   OutcomeKind
+	Kind OutcomeKind
 	// Reason is a low-cardinality reason code used for observability.
 	// It is part of the v1 telemetry contract.
 	Reason string
@@ -0,0 +1,57 @@
+package classify
+
+import (
+	"slices"
+	"strconv"
+)
+
+// Standard Outcome.Reason strings used by the built-in classifiers. Pattern reasons built
+// from an open-ended value use HTTPStatusReason ("http_<status>") and, in the gRPC
+// integration, "grpc_<Code>".
+const (
+	ReasonSuccess                 = "success"
+	ReasonContextCanceled         = "context_canceled"
+	ReasonContextDeadlineExceeded = "context_deadline_exceeded"
+	ReasonClassifierTypeMismatch  = "classifier_type_mismatch"
+	ReasonPartialResult           = "partial_result"
+	ReasonThrottled               = "throttled"
+
+	ReasonHTTP5xx                = "http_5xx"
+	ReasonHTTPTransportError     = "http_transport_error"
+	ReasonHTTPNonIdempotent      = "http_non_idempotent"
+	ReasonHTTPNonRetryableStatus = "http_non_retryable_status"
+)
+
+// KnownReasons returns every fixed Outcome.Reason that recourse's classifiers,
+// executor, and integrations produce, sorted, for dashboards and tools that validate
+// reason values. The list is generated from source (see
+// docs/reference/reason-codes.md); custom classifiers may add their own. Reasons built
+// from an open-ended value are listed by KnownReasonPatterns.
+func KnownReasons() []string { return slices.Clone(knownReasons) }
+
+// KnownReasonPatterns returns the patterns of open-ended outcome reasons, such as
+// "http_<status>", sorted. The text before "<" is the reason prefix.
+func KnownReasonPatterns() []string { return slices.Clone(knownReasonPatterns) }
+
+// httpStatusReasons holds the interned "http_<status>" reasons for statuses 100-599,
+// so classifying a response does not format a new string per attempt.
+var httpStatusReasons = func() (r [600]string) {
+	for status := 100; status < len(r); status++ {
+		r[status] = "http_" + strconv.Itoa(status)
+	}
+	return r
+}()
+
+// HTTPStatusReason returns the outcome reason "http_<status>" (e.g. "http_429"). Reasons
+// for statuses 100-599 are interned; others are formatted on each call.
+func HTTPStatusReason(status int) string {
+	if status >= 100 && status < len(httpStatusReasons) {
+		return httpStatusReasons[status]
+	}
+	return "http_" + strconv.Itoa(status)
+}
+
+// httpStatusText returns status as a decimal string, interned like HTTPStatusReason.
+func httpStatusText(status int) string {
+	return HTTPStatusReason(status)[len("http_"):]
+}
@@ -0,0 +1,39 @@
+// Code generated by scripts/gen_reference.go; DO NOT EDIT.
+
+package classify
+
+// knownReasons lists the fixed outcome reasons, sorted.
+var knownReasons = []string{
+	"abort",
+	"classifier_type_mismatch",
+	"context_canceled",
+	"context_deadline_exceeded",
+	"http_5xx",
+	"http_non_idempotent",
+	"http_non_retryable_status",
+	"http_transport_error",
+	"non_retryable_error",
+	"panic_in_classifier",
+	"partial_result",
+	"retryable_error",
+	"sql_bad_conn",
+	"sql_connection_exception",
+	"sql_deadlock",
+	"sql_error",
+	"sql_insufficient_resources",
+	"sql_lock_not_available",
+	"sql_no_rows",
+	"sql_retryable_state",
+	"sql_serialization_failure",
+	"sql_server_shutdown",
+	"sql_tx_done",
+	"success",
+	"throttled",
+	"unknown_outcome",
+}
+
+// knownReasonPatterns lists the open-ended outcome reason patterns, sorted.
+var knownReasonPatterns = []string{
+	"grpc_<code>",
+	"http_<status>",
+}
@@ -47,3 +47,17 @@ func (r *Registry) Get(name string) (Classifier, bool) {
 	r.mu.RUnlock()
 	return c, ok && c != nil
 }
+
+// Clone returns a copy of r that can be changed without affecting r.
+func (r *Registry) Clone() *Registry {
+	c := NewRegistry()
+	if r == nil {
+		return c
+	}
+	r.mu.RLock()
+	for name, cls := range r.m {
+		c.m[name] = cls
+	}
+	r.mu.RUnlock()
+	return c
+}
@@ -0,0 +1,90 @@
+package classify
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+)
+
+// SQLError is a classify-owned interface that allows SQL classifiers to recognize
+// database errors without importing drivers. It matches the SQLState method exposed
+// by common PostgreSQL drivers (pgx, lib/pq).
+type SQLError interface {
+	SQLState() string
+}
+
+// SQLClassifier classifies outcomes for database/sql operations.
+//
+// Transient failures are retryable: driver.ErrBadConn, serialization failures (40001),
+// deadlocks (40P01), lock timeouts (55P03), connection exceptions (class 08), insufficient
+// resources (class 53), and server shutdown (57P01-57P03). sql.ErrNoRows, sql.ErrTxDone, and
+// all other SQL states are non-retryable.
+//
+// Serialization failures and deadlocks abort the whole transaction; retry the transaction,
+// not the statement (see integrations/sqlretry).
+type SQLClassifier struct {
+	// RetryableStates is an optional set of additional retryable SQLSTATE codes.
+	RetryableStates map[string]struct{}
+}
+
+func (c SQLClassifier) Classify(_ any, err error) Outcome {
+	if err == nil {
+		return Outcome{Kind: OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return Outcome{Kind: OutcomeAbort, Reason: "context_canceled"}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return Outcome{Kind: OutcomeRetryable, Reason: "context_deadline_exceeded"}
+	}
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) {
+		return Outcome{Kind: OutcomeRetryable, Reason: "sql_bad_conn"}
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return Outcome{Kind: OutcomeNonRetryable, Reason: "sql_no_rows"}
+	}
+	if errors.Is(err, sql.ErrTxDone) {
+		return Outcome{Kind: OutcomeNonRetryable, Reason: "sql_tx_done"}
+	}
+
+	var se SQLError
+	if !errors.As(err, &se) {
+		return Outcome{Kind: OutcomeNonRetryable, Reason: "sql_error"}
+	}
+
+	state := strings.ToUpper(strings.TrimSpace(se.SQLState()))
+	out := Outcome{
+		Kind:       OutcomeRetryable,
+		Attributes: map[string]string{"sqlstate": state},
+	}
+	switch {
+	case state == "40001":
+		out.Reason = "sql_serialization_failure"
+	case state == "40P01":
+		out.Reason = "sql_deadlock"
+	case state == "55P03":
+		out.Reason = "sql_lock_not_available"
+	case strings.HasPrefix(state, "08"):
+		out.Reason = "sql_connection_exception"
+	case strings.HasPrefix(state, "53"):
+		out.Reason = "sql_insufficient_resources"
+	case state == "57P01" || state == "57P02" || state == "57P03":
+		out.Reason = "sql_server_shutdown"
+	case c.retryableState(state):
+		out.Reason = "sql_retryable_state"
+	default:
+		out.Kind = OutcomeNonRetryable
+		out.Reason = "sql_error"
+	}
+	return out
+}
+
+func (c SQLClassifier) retryableState(state string) bool {
+	if c.RetryableStates == nil {
+		return false
+	}
+	_, ok := c.RetryableStates[state]
+	return ok
+}
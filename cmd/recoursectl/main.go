@@ -0,0 +1,118 @@
+// Command recoursectl validates and flattens recourse policy bundle documents.
+//
+// Usage:
+//
+//	recoursectl validate FILE...   check that each bundle loads, with its includes
+//	recoursectl flatten FILE       print the merged, self-contained bundle as JSON
+//	recoursectl lint [-layers N] [-max-amplification N] FILE...
+//	                               report dangerous policy configurations (see policy.Lint)
+//
+// A flattened bundle has its defaults, namespace sections, and includes resolved into
+// one policy per key, ready to be served to controlplane.HTTPProvider.
+//
+// lint exits with status 1 if any bundle fails to load or has warnings. -layers is the
+// number of retrying layers calls pass through, this one included.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+const usage = `usage:
+  recoursectl validate FILE...
+  recoursectl flatten FILE
+  recoursectl lint [-layers N] [-max-amplification N] FILE...
+`
+
+func run(args []string, stdout, stderr io.Writer) int {
+	if len(args) < 2 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	switch args[0] {
+	case "validate":
+		failed := false
+		for _, path := range args[1:] {
+			b, err := controlplane.LoadBundle(path)
+			if err != nil {
+				fmt.Fprintf(stderr, "%s: %v\n", path, err)
+				failed = true
+				continue
+			}
+			fmt.Fprintf(stdout, "%s: ok (%d policies", path, len(b.Policies))
+			if b.Version != "" {
+				fmt.Fprintf(stdout, ", version %s", b.Version)
+			}
+			if b.KillSwitch {
+				fmt.Fprint(stdout, ", kill switch on")
+			}
+			fmt.Fprintln(stdout, ")")
+		}
+		if failed {
+			return 1
+		}
+		return 0
+	case "flatten":
+		if len(args) != 2 {
+			fmt.Fprint(stderr, usage)
+			return 2
+		}
+		b, err := controlplane.LoadBundle(args[1])
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", args[1], err)
+			return 1
+		}
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(b); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return 0
+	case "lint":
+		return lint(args[1:], stdout, stderr)
+	default:
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+}
+
+func lint(args []string, stdout, stderr io.Writer) int {
+	var opts policy.LintOptions
+	fs := flag.NewFlagSet("lint", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.IntVar(&opts.Layers, "layers", 1, "retrying layers calls pass through")
+	fs.IntVar(&opts.MaxAmplification, "max-amplification", policy.DefaultMaxAmplification, "most attempts per call before warning")
+	if err := fs.Parse(args); err != nil || fs.NArg() == 0 {
+		fmt.Fprint(stderr, usage)
+		return 2
+	}
+	failed := false
+	for _, path := range fs.Args() {
+		b, err := controlplane.LoadBundle(path)
+		if err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, err)
+			failed = true
+			continue
+		}
+		for _, w := range b.Lint(opts) {
+			fmt.Fprintf(stdout, "%s: %s\n", path, w)
+			failed = true
+		}
+	}
+	if failed {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aponysus/recourse/controlplane"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, body string) string {
+		path := filepath.Join(dir, name)
+		if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	write("common.json", `{"defaults": {"retry": {"max_attempts": 4}}}`)
+	good := write("good.json", `{"version": "v1", "include": ["common.json"], "namespaces": {"svc": {"policies": {"Get": {}}}}}`)
+	bad := write("bad.json", `{"policies": [{"retry": {"max_attempts": 2}}]}`)
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"validate", good, bad}, &stdout, &stderr); code != 1 {
+		t.Fatalf("validate exit = %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "good.json: ok (1 policies, version v1)") || !strings.Contains(stderr.String(), "bad.json: ") {
+		t.Fatalf("stdout = %q, stderr = %q", stdout.String(), stderr.String())
+	}
+
+	stdout.Reset()
+	if code := run([]string{"flatten", good}, &stdout, &stderr); code != 0 {
+		t.Fatalf("flatten exit = %d: %s", code, stderr.String())
+	}
+	b, err := controlplane.ParseBundle(stdout.Bytes())
+	if err != nil {
+		t.Fatalf("flattened bundle does not parse: %v", err)
+	}
+	if len(b.Policies) != 1 || b.Policies[0].Retry.MaxAttempts != 4 {
+		t.Fatalf("flattened policies = %+v", b.Policies)
+	}
+
+	if code := run([]string{"frob", good}, &stdout, &stderr); code != 2 {
+		t.Fatalf("unknown command exit = %d, want 2", code)
+	}
+}
+
+func TestRunLint(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "bundle.json")
+	body := `{"policies": [
+		{"key": {"namespace": "payments", "name": "Charge"}, "hedge": {"enabled": true, "max_hedges": 1, "hedge_delay": 1000000}},
+		{"key": {"namespace": "payments", "name": "Get"}, "retry": {"budget": {"name": "missing"}}}
+	]}`
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	if code := run([]string{"lint", path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("lint exit = %d, want 1: %s", code, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "payments.Charge: hedge.enabled: ") || !strings.Contains(out, `payments.Get: retry.budget.name: budget "missing" is not registered`) {
+		t.Fatalf("stdout = %q", out)
+	}
+	if strings.Contains(out, "amplification") || strings.Count(out, "\n") != 2 {
+		t.Fatalf("stdout = %q, want exactly two warnings", out)
+	}
+
+	stdout.Reset()
+	if code := run([]string{"lint", "-layers", "2", path}, &stdout, &stderr); code != 1 {
+		t.Fatalf("lint exit = %d, want 1", code)
+	}
+	if !strings.Contains(stdout.String(), "payments.Charge: hedge.max_hedges: up to 6 attempts per layer across 2 layer(s)") {
+		t.Fatalf("stdout = %q, want an amplification warning", stdout.String())
+	}
+}
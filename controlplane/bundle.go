@@ -0,0 +1,108 @@
+package controlplane
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Bundle is a policy payload served by a remote control plane:
+//
+//	{"version": "2024-06-01.3", "policies": [{"key": {"namespace": "svc", "name": "Get"}, "retry": {...}}]}
+//
+// Durations are JSON numbers in nanoseconds, as in policy.EffectivePolicy. Bundles are
+// parsed as bundle documents, which may also define defaults and namespace sections
+// (see LoadBundle); a Bundle holds the merged result.
+type Bundle struct {
+	// SchemaVersion is the payload schema (see SchemaVersion). Parsed bundles carry
+	// the current version, whatever the document declared.
+	SchemaVersion int `json:"schema_version,omitempty"`
+	// Version identifies the bundle revision; it is informational.
+	Version string `json:"version,omitempty"`
+	// Policies holds one policy per key.
+	Policies []policy.EffectivePolicy `json:"policies"`
+	// KillSwitch, when true, suppresses retries and hedging for every key
+	// (see KillSwitchProvider).
+	KillSwitch bool `json:"kill_switch,omitempty"`
+	// Budgets defines named budgets (see ResourceProvider).
+	Budgets map[string]BudgetSpec `json:"budgets,omitempty"`
+	// Circuit holds global circuit parameters (see ResourceProvider).
+	Circuit *CircuitSpec `json:"circuit,omitempty"`
+	// Faults configures fault injection by policy key (see ResourceProvider).
+	Faults map[string]FaultSpec `json:"faults,omitempty"`
+}
+
+// ParseBundle decodes a bundle document, merges its defaults and namespace sections
+// into its policies, and validates the result. Every policy must have a non-empty,
+// unique key and must normalize without error. Documents received this way must be
+// self-contained: include is only supported by LoadBundle.
+func ParseBundle(data []byte) (Bundle, error) {
+	d, err := parseDocument(data)
+	if err != nil {
+		return Bundle{}, err
+	}
+	if len(d.Include) > 0 {
+		return Bundle{}, errors.New("controlplane: bundle include is only supported for bundle files")
+	}
+	return d.bundle()
+}
+
+// DecodePolicy decodes one JSON-encoded policy (a bundle entry) stored under key, as
+// read by key-value providers. The document's own key is replaced by key; the result
+// is upgraded to the current SchemaVersion, normalized, and tagged with PolicySourceRemote.
+func DecodePolicy(key policy.PolicyKey, data []byte) (policy.EffectivePolicy, error) {
+	data, err := upgradePolicy(data)
+	if err != nil {
+		return policy.EffectivePolicy{}, fmt.Errorf("controlplane: decode policy %q: %w", key, err)
+	}
+	var pol policy.EffectivePolicy
+	if err := json.Unmarshal(data, &pol); err != nil {
+		return policy.EffectivePolicy{}, fmt.Errorf("controlplane: decode policy %q: %w", key, err)
+	}
+	pol.Key = key
+	normalized, err := pol.Normalize()
+	if err != nil {
+		return policy.EffectivePolicy{}, fmt.Errorf("controlplane: policy %q: %w", key, err)
+	}
+	normalized.Meta.Source = policy.PolicySourceRemote
+	return normalized, nil
+}
+
+// policyMap returns the bundle's normalized policies by key, tagged with source.
+func (b Bundle) policyMap(source policy.PolicySource) (map[policy.PolicyKey]policy.EffectivePolicy, error) {
+	m := make(map[policy.PolicyKey]policy.EffectivePolicy, len(b.Policies))
+	for i, pol := range b.Policies {
+		if pol.Key == (policy.PolicyKey{}) {
+			return nil, fmt.Errorf("controlplane: bundle policy %d has no key", i)
+		}
+		if _, dup := m[pol.Key]; dup {
+			return nil, fmt.Errorf("controlplane: bundle has duplicate key %q", pol.Key)
+		}
+		normalized, err := pol.Normalize()
+		if err != nil {
+			return nil, fmt.Errorf("controlplane: bundle policy %q: %w", pol.Key, err)
+		}
+		normalized.Meta.Source = source
+		m[pol.Key] = normalized
+	}
+	return m, nil
+}
+
+// Lint runs policy.Lint over the bundle's policies, in order. Unless opts.HasBudget is
+// set, budget references are checked against the bundle's Budgets, so budgets
+// registered in code rather than in the bundle are reported too.
+func (b Bundle) Lint(opts policy.LintOptions) []policy.Warning {
+	if opts.HasBudget == nil {
+		opts.HasBudget = func(name string) bool {
+			_, ok := b.Budgets[name]
+			return ok
+		}
+	}
+	var warnings []policy.Warning
+	for _, pol := range b.Policies {
+		warnings = append(warnings, policy.Lint(pol, opts)...)
+	}
+	return warnings
+}
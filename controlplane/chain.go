@@ -0,0 +1,119 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultChainCooldown is how long a ChainProvider skips a provider after it fails.
+const DefaultChainCooldown = 30 * time.Second
+
+// ChainProvider tries providers in order and returns the first policy resolved without
+// error, recording the serving provider's name in Meta.Provider.
+//
+// A provider that fails with anything but ErrPolicyNotFound is skipped for Cooldown,
+// so a down control plane costs one failed lookup per cooldown instead of one per call.
+// When every provider is cooling down they are all tried anyway, in order.
+// ErrPolicyNotFound moves on to the next provider without affecting health.
+type ChainProvider struct {
+	// Cooldown is how long a failed provider is skipped. Defaults to DefaultChainCooldown.
+	Cooldown time.Duration
+
+	links []chainLink
+	now   func() time.Time
+}
+
+type chainLink struct {
+	name     string
+	provider PolicyProvider
+	until    *atomic.Int64 // unix nanoseconds until which the provider is skipped
+}
+
+type namedProvider struct {
+	PolicyProvider
+	name string
+}
+
+// Named names p for Meta.Provider when it is part of a chain. Unnamed providers are
+// named after their type, e.g. "*controlplane.HTTPProvider".
+func Named(name string, p PolicyProvider) PolicyProvider {
+	return namedProvider{PolicyProvider: p, name: name}
+}
+
+// Chain returns a provider that tries providers in order.
+func Chain(providers ...PolicyProvider) *ChainProvider {
+	c := &ChainProvider{now: time.Now}
+	for _, p := range providers {
+		link := chainLink{provider: p, until: new(atomic.Int64)}
+		if n, ok := p.(namedProvider); ok {
+			link.name, link.provider = n.name, n.PolicyProvider
+		} else {
+			link.name = fmt.Sprintf("%T", p)
+		}
+		c.links = append(c.links, link)
+	}
+	return c
+}
+
+// GetEffectivePolicy returns the policy from the first provider that resolves key. If
+// none does, it returns the joined errors of every provider tried, along with the first
+// fallback policy a provider returned alongside its error, if any.
+func (c *ChainProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	now := c.now().UnixNano()
+	var (
+		errs     []error
+		fallback policy.EffectivePolicy
+		skipped  []int
+	)
+	try := func(i int) (policy.EffectivePolicy, bool) {
+		link := c.links[i]
+		pol, err := link.provider.GetEffectivePolicy(ctx, key)
+		if err == nil {
+			link.until.Store(0)
+			pol.Meta.Provider = link.name
+			return pol, true
+		}
+		if !errors.Is(err, ErrPolicyNotFound) {
+			link.until.Store(c.now().Add(c.cooldown()).UnixNano())
+		}
+		if isZeroEffectivePolicy(fallback) && !isZeroEffectivePolicy(pol) {
+			fallback = pol
+			fallback.Meta.Provider = link.name
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", link.name, err))
+		return policy.EffectivePolicy{}, false
+	}
+
+	for i, link := range c.links {
+		if link.until.Load() > now {
+			skipped = append(skipped, i)
+			continue
+		}
+		if pol, ok := try(i); ok {
+			return pol, nil
+		}
+	}
+	if len(skipped) == len(c.links) {
+		for _, i := range skipped {
+			if pol, ok := try(i); ok {
+				return pol, nil
+			}
+		}
+	}
+	if len(errs) == 0 {
+		return policy.EffectivePolicy{}, ErrPolicyNotFound
+	}
+	return fallback, errors.Join(errs...)
+}
+
+func (c *ChainProvider) cooldown() time.Duration {
+	if c.Cooldown > 0 {
+		return c.Cooldown
+	}
+	return DefaultChainCooldown
+}
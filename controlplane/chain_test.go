@@ -0,0 +1,76 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// countingProvider counts calls to a switchProvider.
+type countingProvider struct {
+	*switchProvider
+	calls int
+}
+
+func (p *countingProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.calls++
+	return p.switchProvider.GetEffectivePolicy(ctx, key)
+}
+
+func TestChainProvider(t *testing.T) {
+	ctx := context.Background()
+	key := policy.ParseKey("svc.Get")
+	only := policy.ParseKey("svc.OnlySecondary")
+
+	primary := &countingProvider{switchProvider: &switchProvider{policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		key: policy.New("svc.Get", policy.MaxAttempts(4)),
+	}}}
+	secondary := &countingProvider{switchProvider: &switchProvider{policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		key:  policy.New("svc.Get", policy.MaxAttempts(2)),
+		only: policy.New("svc.OnlySecondary", policy.MaxAttempts(3)),
+	}}}
+	now := time.Unix(1000, 0)
+	c := Chain(Named("remote", primary), secondary)
+	c.Cooldown = time.Minute
+	c.now = func() time.Time { return now }
+
+	pol, err := c.GetEffectivePolicy(ctx, key)
+	if err != nil || pol.Retry.MaxAttempts != 4 || pol.Meta.Provider != "remote" {
+		t.Fatalf("healthy chain = %+v, %v; want primary policy", pol, err)
+	}
+	// Not found falls through without marking the primary bad.
+	pol, err = c.GetEffectivePolicy(ctx, only)
+	if err != nil || pol.Retry.MaxAttempts != 3 || pol.Meta.Provider != "*controlplane.countingProvider" {
+		t.Fatalf("fall-through = %+v, %v; want secondary policy", pol, err)
+	}
+
+	primary.err = ErrProviderUnavailable
+	for range 3 {
+		if pol, err := c.GetEffectivePolicy(ctx, key); err != nil || pol.Retry.MaxAttempts != 2 {
+			t.Fatalf("primary down = %+v, %v; want secondary policy", pol, err)
+		}
+	}
+	if primary.calls != 3 {
+		t.Fatalf("primary calls = %d, want 3 (skipped during cooldown)", primary.calls)
+	}
+
+	// After the cooldown the primary is probed and recovers.
+	primary.err = nil
+	now = now.Add(2 * time.Minute)
+	if pol, _ := c.GetEffectivePolicy(ctx, key); pol.Meta.Provider != "remote" {
+		t.Fatalf("after cooldown served by %q, want remote", pol.Meta.Provider)
+	}
+
+	// When every provider is cooling down they are all tried.
+	primary.err, secondary.err = ErrProviderUnavailable, ErrPolicyFetchFailed
+	if _, err := c.GetEffectivePolicy(ctx, key); !errors.Is(err, ErrProviderUnavailable) || !errors.Is(err, ErrPolicyFetchFailed) {
+		t.Fatalf("all down: err = %v, want both provider errors", err)
+	}
+	primary.err = nil
+	if pol, err := c.GetEffectivePolicy(ctx, key); err != nil || pol.Meta.Provider != "remote" {
+		t.Fatalf("all cooling down = %+v, %v; want primary retried", pol, err)
+	}
+}
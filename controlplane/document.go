@@ -0,0 +1,331 @@
+package controlplane
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// document is a bundle document before merging. Policy layers are kept as JSON objects.
+type document struct {
+	Version    string
+	Include    []string
+	KillSwitch *bool
+	Budgets    map[string]BudgetSpec
+	Circuit    *CircuitSpec
+	Faults     map[string]FaultSpec
+	Defaults   map[string]any
+	Namespaces map[string]namespaceSection
+	Policies   map[policy.PolicyKey]map[string]any
+	order      []policy.PolicyKey // top-level policies in document order
+}
+
+type namespaceSection struct {
+	Defaults map[string]any
+	Policies map[string]map[string]any
+}
+
+type rawDocument struct {
+	Version    string                  `json:"version"`
+	Include    []string                `json:"include"`
+	KillSwitch *bool                   `json:"kill_switch"`
+	Budgets    map[string]BudgetSpec   `json:"budgets"`
+	Circuit    *CircuitSpec            `json:"circuit"`
+	Faults     map[string]FaultSpec    `json:"faults"`
+	Defaults   json.RawMessage         `json:"defaults"`
+	Namespaces map[string]rawNamespace `json:"namespaces"`
+	Policies   []json.RawMessage       `json:"policies"`
+}
+
+type rawNamespace struct {
+	Defaults json.RawMessage            `json:"defaults"`
+	Policies map[string]json.RawMessage `json:"policies"`
+}
+
+func parseDocument(data []byte) (*document, error) {
+	data, err := upgradeDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("controlplane: decode bundle: %w", err)
+	}
+	var raw rawDocument
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("controlplane: decode bundle: %w", err)
+	}
+	d := &document{
+		Version:    raw.Version,
+		Include:    raw.Include,
+		KillSwitch: raw.KillSwitch,
+		Budgets:    raw.Budgets,
+		Circuit:    raw.Circuit,
+		Faults:     raw.Faults,
+		Namespaces: make(map[string]namespaceSection, len(raw.Namespaces)),
+		Policies:   make(map[policy.PolicyKey]map[string]any, len(raw.Policies)),
+	}
+	if d.Defaults, err = decodeLayer("defaults", raw.Defaults); err != nil {
+		return nil, err
+	}
+	for ns, sec := range raw.Namespaces {
+		if ns == "" {
+			return nil, errors.New("controlplane: bundle has an empty namespace section name")
+		}
+		out := namespaceSection{Policies: make(map[string]map[string]any, len(sec.Policies))}
+		if out.Defaults, err = decodeLayer("namespaces."+ns+".defaults", sec.Defaults); err != nil {
+			return nil, err
+		}
+		for name, entry := range sec.Policies {
+			if name == "" {
+				return nil, fmt.Errorf("controlplane: bundle namespace %q has a policy with an empty name", ns)
+			}
+			layer, err := decodeLayer("namespaces."+ns+".policies."+name, entry)
+			if err != nil {
+				return nil, err
+			}
+			delete(layer, "key")
+			out.Policies[name] = layer
+		}
+		d.Namespaces[ns] = out
+	}
+	for i, entry := range raw.Policies {
+		var head struct {
+			Key policy.PolicyKey `json:"key"`
+		}
+		if err := json.Unmarshal(entry, &head); err != nil {
+			return nil, fmt.Errorf("controlplane: bundle policy %d: %w", i, err)
+		}
+		if head.Key == (policy.PolicyKey{}) {
+			return nil, fmt.Errorf("controlplane: bundle policy %d has no key", i)
+		}
+		if _, dup := d.Policies[head.Key]; dup {
+			return nil, fmt.Errorf("controlplane: bundle has duplicate key %q", head.Key)
+		}
+		layer, err := decodeLayer(fmt.Sprintf("policies[%d]", i), entry)
+		if err != nil {
+			return nil, err
+		}
+		delete(layer, "key")
+		d.Policies[head.Key] = layer
+		d.order = append(d.order, head.Key)
+	}
+	return d, nil
+}
+
+// merge layers top over d: top's settings win, and top's new policies follow d's.
+func (d *document) merge(top *document) {
+	if top.Version != "" {
+		d.Version = top.Version
+	}
+	if top.KillSwitch != nil {
+		d.KillSwitch = top.KillSwitch
+	}
+	for name, spec := range top.Budgets {
+		if d.Budgets == nil {
+			d.Budgets = make(map[string]BudgetSpec)
+		}
+		d.Budgets[name] = spec
+	}
+	if top.Circuit != nil {
+		d.Circuit = top.Circuit
+	}
+	for key, spec := range top.Faults {
+		if d.Faults == nil {
+			d.Faults = make(map[string]FaultSpec)
+		}
+		d.Faults[key] = spec
+	}
+	d.Defaults = mergeLayer(d.Defaults, top.Defaults)
+	for ns, sec := range top.Namespaces {
+		cur, ok := d.Namespaces[ns]
+		if !ok {
+			cur = namespaceSection{Policies: make(map[string]map[string]any)}
+		}
+		cur.Defaults = mergeLayer(cur.Defaults, sec.Defaults)
+		for name, layer := range sec.Policies {
+			cur.Policies[name] = mergeLayer(cur.Policies[name], layer)
+		}
+		d.Namespaces[ns] = cur
+	}
+	for _, key := range top.order {
+		if _, ok := d.Policies[key]; !ok {
+			d.order = append(d.order, key)
+		}
+		d.Policies[key] = mergeLayer(d.Policies[key], top.Policies[key])
+	}
+}
+
+// bundle resolves every listed key of the merged document into a Bundle. Keys from
+// namespace sections come first, sorted, followed by top-level keys in document order.
+func (d *document) bundle() (Bundle, error) {
+	var keys []policy.PolicyKey
+	seen := make(map[policy.PolicyKey]bool)
+	nss := make([]string, 0, len(d.Namespaces))
+	for ns := range d.Namespaces {
+		nss = append(nss, ns)
+	}
+	sort.Strings(nss)
+	for _, ns := range nss {
+		names := make([]string, 0, len(d.Namespaces[ns].Policies))
+		for name := range d.Namespaces[ns].Policies {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			key := policy.PolicyKey{Namespace: ns, Name: name}
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	for _, key := range d.order {
+		if !seen[key] {
+			keys = append(keys, key)
+		}
+	}
+
+	b := Bundle{
+		SchemaVersion: SchemaVersion,
+		Version:       d.Version,
+		Policies:      make([]policy.EffectivePolicy, 0, len(keys)),
+		KillSwitch:    d.KillSwitch != nil && *d.KillSwitch,
+		Budgets:       d.Budgets,
+		Circuit:       d.Circuit,
+		Faults:        d.Faults,
+	}
+	if err := b.validateResources(); err != nil {
+		return Bundle{}, err
+	}
+	for _, key := range keys {
+		layer := mergeLayer(nil, d.Defaults)
+		if sec, ok := d.Namespaces[key.Namespace]; ok {
+			layer = mergeLayer(layer, sec.Defaults)
+			layer = mergeLayer(layer, sec.Policies[key.Name])
+		}
+		layer = mergeLayer(layer, d.Policies[key])
+		data, err := json.Marshal(layer)
+		if err != nil {
+			return Bundle{}, fmt.Errorf("controlplane: bundle policy %q: %w", key, err)
+		}
+		var pol policy.EffectivePolicy
+		if err := json.Unmarshal(data, &pol); err != nil {
+			return Bundle{}, fmt.Errorf("controlplane: bundle policy %q: %w", key, err)
+		}
+		pol.Key = key
+		b.Policies = append(b.Policies, pol)
+	}
+	if _, err := b.policyMap(policy.PolicySourceRemote); err != nil {
+		return Bundle{}, err
+	}
+	return b, nil
+}
+
+// LoadBundle reads the bundle document at path and returns the merged, validated
+// bundle. A bundle document is the authored form of a Bundle. Besides the flat policy list it
+// may contain shared settings that policies inherit:
+//
+//	{
+//	  "schema_version": 1,
+//	  "version": "2024-06-01.3",
+//	  "include": ["common.json"],
+//	  "defaults": {"retry": {"max_attempts": 3, "jitter": "full"}},
+//	  "namespaces": {
+//	    "payments": {
+//	      "defaults": {"retry": {"overall_timeout": 2000000000}},
+//	      "policies": {"Charge": {"retry": {"max_attempts": 5}}}
+//	    }
+//	  },
+//	  "policies": [{"key": {"namespace": "payments", "name": "Refund"}, "hedge": {"enabled": true}}]
+//	}
+//
+// Each key listed in a namespace section or in policies is resolved by merging, in
+// order: defaults, the namespace's defaults, the namespace's entry for the key, and the
+// key's policies entry. Later layers override earlier ones field by field; unset fields
+// are inherited. Keys not listed anywhere are not part of the bundle.
+//
+// Included documents, resolved relative to the including file, are merged first, in
+// order, with the including document on top; includes may nest but not cycle. Only
+// documents loaded from files (LoadBundle, FileProvider) may include others.
+func LoadBundle(path string) (Bundle, error) {
+	d, err := loadDocument(path, nil)
+	if err != nil {
+		return Bundle{}, err
+	}
+	return d.bundle()
+}
+
+func loadDocument(path string, stack []string) (*document, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return nil, fmt.Errorf("controlplane: bundle %s: %w", path, err)
+	}
+	for _, p := range stack {
+		if p == abs {
+			return nil, fmt.Errorf("controlplane: bundle include cycle at %s", path)
+		}
+	}
+	data, err := os.ReadFile(abs)
+	if err != nil {
+		return nil, fmt.Errorf("controlplane: read bundle: %w", err)
+	}
+	top, err := parseDocument(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	merged := &document{
+		Namespaces: make(map[string]namespaceSection),
+		Policies:   make(map[policy.PolicyKey]map[string]any),
+	}
+	for _, inc := range top.Include {
+		if !filepath.IsAbs(inc) {
+			inc = filepath.Join(filepath.Dir(abs), inc)
+		}
+		d, err := loadDocument(inc, append(stack, abs))
+		if err != nil {
+			return nil, err
+		}
+		merged.merge(d)
+	}
+	merged.merge(top)
+	return merged, nil
+}
+
+func decodeLayer(field string, data json.RawMessage) (map[string]any, error) {
+	if len(data) == 0 || bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var layer map[string]any
+	if err := dec.Decode(&layer); err != nil {
+		return nil, fmt.Errorf("controlplane: bundle %s: %w", field, err)
+	}
+	return layer, nil
+}
+
+// mergeLayer returns a copy of base with over merged on top: nested objects merge
+// recursively, other values replace.
+func mergeLayer(base, over map[string]any) map[string]any {
+	if base == nil && over == nil {
+		return nil
+	}
+	out := make(map[string]any, len(base)+len(over))
+	for k, v := range base {
+		out[k] = v
+	}
+	for k, v := range over {
+		if sub, ok := v.(map[string]any); ok {
+			if cur, ok := out[k].(map[string]any); ok {
+				out[k] = mergeLayer(cur, sub)
+				continue
+			}
+			out[k] = mergeLayer(nil, sub)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
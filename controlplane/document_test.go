@@ -0,0 +1,125 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func writeBundleFile(t *testing.T, dir, name, body string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestParseBundle_Layers(t *testing.T) {
+	b, err := ParseBundle([]byte(`{
+		"defaults": {"retry": {"max_attempts": 3, "jitter": "full", "max_backoff": 1000000000}},
+		"namespaces": {
+			"payments": {
+				"defaults": {"retry": {"max_attempts": 5}},
+				"policies": {"Charge": {"retry": {"jitter": "equal"}}, "Refund": {}}
+			}
+		},
+		"policies": [
+			{"key": {"namespace": "payments", "name": "Refund"}, "retry": {"max_attempts": 2}},
+			{"key": {"namespace": "search", "name": "Query"}}
+		]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseBundle: %v", err)
+	}
+	got := make(map[string]policy.RetryPolicy)
+	var order []string
+	for _, pol := range b.Policies {
+		got[pol.Key.String()] = pol.Retry
+		order = append(order, pol.Key.String())
+	}
+	if strings.Join(order, ",") != "payments.Charge,payments.Refund,search.Query" {
+		t.Fatalf("order = %v", order)
+	}
+	for key, want := range map[string]policy.RetryPolicy{
+		"payments.Charge": {MaxAttempts: 5, Jitter: policy.JitterEqual, MaxBackoff: time.Second},
+		"payments.Refund": {MaxAttempts: 2, Jitter: policy.JitterFull, MaxBackoff: time.Second},
+		"search.Query":    {MaxAttempts: 3, Jitter: policy.JitterFull, MaxBackoff: time.Second},
+	} {
+		r := got[key]
+		if r.MaxAttempts != want.MaxAttempts || r.Jitter != want.Jitter || r.MaxBackoff != want.MaxBackoff {
+			t.Errorf("%s retry = %+v, want %+v", key, r, want)
+		}
+	}
+
+	if _, err := ParseBundle([]byte(`{"include": ["other.json"], "policies": []}`)); err == nil {
+		t.Fatal("ParseBundle accepted an include")
+	}
+}
+
+func TestLoadBundle_Includes(t *testing.T) {
+	dir := t.TempDir()
+	writeBundleFile(t, dir, "base/defaults.json", `{"version": "base", "defaults": {"retry": {"max_attempts": 4, "jitter": "full"}}}`)
+	writeBundleFile(t, dir, "base/payments.json", `{"include": ["defaults.json"], "namespaces": {"payments": {"policies": {"Charge": {"retry": {"max_attempts": 6}}}}}}`)
+	main := writeBundleFile(t, dir, "main.json", `{
+		"version": "main",
+		"include": ["base/payments.json"],
+		"defaults": {"retry": {"jitter": "equal"}},
+		"namespaces": {"payments": {"policies": {"Charge": {"hedge": {"enabled": true}}}}}
+	}`)
+
+	b, err := LoadBundle(main)
+	if err != nil {
+		t.Fatalf("LoadBundle: %v", err)
+	}
+	if b.Version != "main" || len(b.Policies) != 1 {
+		t.Fatalf("bundle = %+v", b)
+	}
+	pol := b.Policies[0]
+	if pol.Retry.MaxAttempts != 6 || pol.Retry.Jitter != policy.JitterEqual || !pol.Hedge.Enabled {
+		t.Fatalf("policy = %+v, want included max_attempts, overriding jitter, and own hedge", pol)
+	}
+
+	a := writeBundleFile(t, dir, "cycle/a.json", `{"include": ["b.json"]}`)
+	writeBundleFile(t, dir, "cycle/b.json", `{"include": ["a.json"]}`)
+	if _, err := LoadBundle(a); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("LoadBundle with cycle = %v, want cycle error", err)
+	}
+}
+
+func TestFileProvider(t *testing.T) {
+	ctx := context.Background()
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	path := writeBundleFile(t, t.TempDir(), "bundle.json", `{"version": "v1", "namespaces": {"svc": {"policies": {"Get": {"retry": {"max_attempts": 4}}}}}}`)
+
+	p := NewFileProvider(path)
+	if _, err := p.GetEffectivePolicy(ctx, key); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("before load: err = %v, want ErrProviderUnavailable", err)
+	}
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	pol, err := p.GetEffectivePolicy(ctx, key)
+	if err != nil || pol.Retry.MaxAttempts != 4 || pol.Meta.Source != policy.PolicySourceRemote {
+		t.Fatalf("policy = %+v, %v", pol, err)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"policies": [`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reload(); !errors.Is(err, ErrPolicyFetchFailed) {
+		t.Fatalf("Reload of broken bundle = %v, want ErrPolicyFetchFailed", err)
+	}
+	if p.Version() != "v1" {
+		t.Fatalf("version = %q, want last good bundle kept", p.Version())
+	}
+}
@@ -0,0 +1,110 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// FileProvider serves policies from a bundle document on disk, including the documents
+// it includes (see LoadBundle). Reload re-reads the files; a failed reload keeps the
+// current policies.
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the first bundle loads,
+// GetEffectivePolicy returns ErrProviderUnavailable; afterwards keys missing from the
+// bundle return ErrPolicyNotFound.
+type FileProvider struct {
+	path string
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	version  string
+	kill     bool
+	loaded   bool
+	lastErr  error
+
+	resources resourceWatchers
+}
+
+// NewFileProvider returns a provider for the bundle document at path. Call Reload or
+// Run before resolving policies.
+func NewFileProvider(path string) *FileProvider {
+	return &FileProvider{path: path}
+}
+
+// GetEffectivePolicy returns the policy for key from the current bundle.
+func (p *FileProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		if p.lastErr != nil {
+			return policy.EffectivePolicy{}, fmt.Errorf("%w: %v", ErrProviderUnavailable, p.lastErr)
+		}
+		return policy.EffectivePolicy{}, ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Version returns the version of the current bundle.
+func (p *FileProvider) Version() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// KillSwitch reports whether the current bundle sets kill_switch.
+func (p *FileProvider) KillSwitch() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.kill
+}
+
+// Reload reads and applies the bundle. On error the current policies are kept.
+func (p *FileProvider) Reload() error {
+	b, err := LoadBundle(p.path)
+	var policies map[policy.PolicyKey]policy.EffectivePolicy
+	if err == nil {
+		policies, err = b.policyMap(policy.PolicySourceRemote)
+	}
+	if err != nil {
+		err = fmt.Errorf("%w: %w", ErrPolicyFetchFailed, err)
+	}
+
+	p.mu.Lock()
+	p.lastErr = err
+	if err != nil {
+		p.mu.Unlock()
+		return err
+	}
+	p.policies = policies
+	p.version = b.Version
+	p.kill = b.KillSwitch
+	p.loaded = true
+	p.mu.Unlock()
+	p.resources.publish(b.Resources())
+	return nil
+}
+
+// Run reloads the bundle immediately and then every interval until ctx is done. It
+// returns ctx.Err().
+func (p *FileProvider) Run(ctx context.Context, interval time.Duration) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		_ = p.Reload()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+var _ PolicyProvider = (*FileProvider)(nil)
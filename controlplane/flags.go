@@ -0,0 +1,94 @@
+package controlplane
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Default flag names read by FlagProvider.
+const (
+	DefaultKillSwitchFlag   = "recourse.kill_switch"
+	DefaultHedgeEnabledFlag = "recourse.hedge.enabled"
+	DefaultMaxAttemptsFlag  = "recourse.retry.max_attempts"
+)
+
+// FlagSource evaluates feature flags for a policy key. Adapt the organization's flag
+// system (OpenFeature, LaunchDarkly, Unleash, ...) to it; integrations/openfeature
+// provides an OpenFeature adapter.
+//
+// Implementations return def when the flag is not set for key or cannot be evaluated,
+// and should pass key to the flag system as targeting context so flags can vary per key.
+type FlagSource interface {
+	BoolFlag(ctx context.Context, flag string, key policy.PolicyKey, def bool) bool
+	IntFlag(ctx context.Context, flag string, key policy.PolicyKey, def int) int
+}
+
+// FlagOptions names the flags read by FlagProvider. Empty names use the defaults;
+// set a name to "-" to ignore that flag.
+type FlagOptions struct {
+	// KillSwitch, when true, disables retries (MaxAttempts 1) and hedging.
+	KillSwitch string
+	// HedgeEnabled overrides Hedge.Enabled.
+	HedgeEnabled string
+	// MaxAttempts overrides Retry.MaxAttempts when positive.
+	MaxAttempts string
+}
+
+// FlagProvider overrides fields of the policies returned by Provider with feature
+// flags, evaluated on every resolution so flipped flags apply to the next call.
+//
+// Flags default to the policy's own values, so keys without flag rules keep their
+// policy. Enabling hedging through a flag uses the policy's hedge settings, or the
+// normalization defaults when it has none.
+type FlagProvider struct {
+	Provider PolicyProvider
+	Flags    FlagSource
+	Options  FlagOptions
+}
+
+// NewFlagProvider returns a provider that applies flags to p's policies.
+func NewFlagProvider(p PolicyProvider, flags FlagSource, opts FlagOptions) *FlagProvider {
+	return &FlagProvider{Provider: p, Flags: flags, Options: opts}
+}
+
+func (p *FlagProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	pol, err := p.Provider.GetEffectivePolicy(ctx, key)
+	if p.Flags == nil || (err != nil && isZeroEffectivePolicy(pol)) {
+		return pol, err
+	}
+
+	meta := pol.Meta
+	if name := flagName(p.Options.MaxAttempts, DefaultMaxAttemptsFlag); name != "" {
+		if n := p.Flags.IntFlag(ctx, name, key, pol.Retry.MaxAttempts); n > 0 {
+			pol.Retry.MaxAttempts = n
+		}
+	}
+	if name := flagName(p.Options.HedgeEnabled, DefaultHedgeEnabledFlag); name != "" {
+		pol.Hedge.Enabled = p.Flags.BoolFlag(ctx, name, key, pol.Hedge.Enabled)
+	}
+	if name := flagName(p.Options.KillSwitch, DefaultKillSwitchFlag); name != "" {
+		if p.Flags.BoolFlag(ctx, name, key, false) {
+			pol.Retry.MaxAttempts = 1
+			pol.Hedge.Enabled = false
+		}
+	}
+
+	normalized, nerr := pol.Normalize()
+	if nerr != nil {
+		return pol, nerr
+	}
+	normalized.Meta.Source = meta.Source
+	return normalized, err
+}
+
+func flagName(name, def string) string {
+	switch name {
+	case "":
+		return def
+	case "-":
+		return ""
+	default:
+		return name
+	}
+}
@@ -0,0 +1,73 @@
+package controlplane
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// mapFlags serves flags from maps keyed by flag name and then policy key.
+type mapFlags struct {
+	bools map[string]map[policy.PolicyKey]bool
+	ints  map[string]map[policy.PolicyKey]int
+}
+
+func (f mapFlags) BoolFlag(_ context.Context, flag string, key policy.PolicyKey, def bool) bool {
+	if v, ok := f.bools[flag][key]; ok {
+		return v
+	}
+	return def
+}
+
+func (f mapFlags) IntFlag(_ context.Context, flag string, key policy.PolicyKey, def int) int {
+	if v, ok := f.ints[flag][key]; ok {
+		return v
+	}
+	return def
+}
+
+func TestFlagProvider(t *testing.T) {
+	ctx := context.Background()
+	plain := policy.PolicyKey{Namespace: "svc", Name: "Plain"}
+	hedged := policy.PolicyKey{Namespace: "svc", Name: "Hedged"}
+	killed := policy.PolicyKey{Namespace: "svc", Name: "Killed"}
+
+	base := &StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		plain:  policy.New("svc.Plain", policy.MaxAttempts(4)),
+		hedged: policy.New("svc.Hedged", policy.MaxAttempts(4)),
+		killed: policy.New("svc.Killed", policy.MaxAttempts(4), policy.EnableHedging()),
+	}}
+	flags := mapFlags{
+		bools: map[string]map[policy.PolicyKey]bool{
+			DefaultHedgeEnabledFlag: {hedged: true},
+			DefaultKillSwitchFlag:   {killed: true},
+		},
+		ints: map[string]map[policy.PolicyKey]int{
+			DefaultMaxAttemptsFlag: {hedged: 2},
+		},
+	}
+	p := NewFlagProvider(base, flags, FlagOptions{})
+
+	want, _ := base.GetEffectivePolicy(ctx, plain)
+	pol, err := p.GetEffectivePolicy(ctx, plain)
+	if err != nil || !pol.Retry.Equal(want.Retry) || pol.Hedge != want.Hedge || pol.Meta.Source != want.Meta.Source {
+		t.Fatalf("unflagged policy changed: %+v, %v", pol, err)
+	}
+
+	pol, err = p.GetEffectivePolicy(ctx, hedged)
+	if err != nil || pol.Retry.MaxAttempts != 2 || !pol.Hedge.Enabled || pol.Hedge.MaxHedges == 0 {
+		t.Fatalf("flagged policy = %+v, %v; want 2 attempts, hedging with defaults", pol, err)
+	}
+
+	pol, err = p.GetEffectivePolicy(ctx, killed)
+	if err != nil || pol.Retry.MaxAttempts != 1 || pol.Hedge.Enabled {
+		t.Fatalf("killed policy = %+v, %v; want 1 attempt, no hedging", pol, err)
+	}
+
+	// "-" ignores a flag.
+	p = NewFlagProvider(base, flags, FlagOptions{KillSwitch: "-"})
+	if pol, _ := p.GetEffectivePolicy(ctx, killed); pol.Retry.MaxAttempts != 4 || !pol.Hedge.Enabled {
+		t.Fatalf("ignored kill switch applied: %+v", pol)
+	}
+}
@@ -0,0 +1,230 @@
+package controlplane
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for HTTPProviderOptions.
+const (
+	DefaultHTTPPollInterval   = 30 * time.Second
+	DefaultHTTPPollJitter     = 0.1
+	DefaultHTTPFailureBackoff = time.Second
+	DefaultHTTPMaxBackoff     = 5 * time.Minute
+	DefaultHTTPMaxBundleSize  = 4 << 20
+)
+
+// HTTPProviderOptions configures an HTTPProvider. Zero values use the defaults.
+type HTTPProviderOptions struct {
+	// Client performs the requests. Defaults to http.DefaultClient.
+	Client *http.Client
+	// Header is added to every request (e.g. Authorization).
+	Header http.Header
+	// Verify, when set, checks the raw bundle and response headers before the bundle
+	// is applied, e.g. a detached signature. A failed check keeps the current policies.
+	Verify func(body []byte, header http.Header) error
+	// Jitter randomizes each wait by up to ±Jitter of its length. Defaults to 0.1;
+	// negative disables jitter.
+	Jitter float64
+	// FailureBackoff is the wait after the first failed fetch; it doubles per
+	// consecutive failure up to MaxBackoff.
+	FailureBackoff time.Duration
+	// MaxBackoff caps the wait between failed fetches.
+	MaxBackoff time.Duration
+	// MaxBundleSize bounds the response body size in bytes.
+	MaxBundleSize int64
+}
+
+// HTTPProvider serves policies from a Bundle polled over HTTP. It sends the last ETag
+// in If-None-Match, so an unchanged bundle costs a 304, and retries failed fetches with
+// exponential backoff while keeping the last good bundle.
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the first bundle loads,
+// GetEffectivePolicy returns ErrProviderUnavailable; afterwards keys missing from the
+// bundle return ErrPolicyNotFound.
+type HTTPProvider struct {
+	url      string
+	interval time.Duration
+	opts     HTTPProviderOptions
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	version  string
+	kill     bool
+	etag     string
+	loaded   bool
+	lastErr  error
+
+	resources resourceWatchers
+}
+
+// NewHTTPProvider returns a provider for the bundle at url, polled every interval by
+// Run (DefaultHTTPPollInterval if interval <= 0). Call Refresh or Run before resolving
+// policies.
+func NewHTTPProvider(url string, interval time.Duration, opts HTTPProviderOptions) *HTTPProvider {
+	if interval <= 0 {
+		interval = DefaultHTTPPollInterval
+	}
+	if opts.Client == nil {
+		opts.Client = http.DefaultClient
+	}
+	if opts.Jitter == 0 {
+		opts.Jitter = DefaultHTTPPollJitter
+	}
+	if opts.FailureBackoff <= 0 {
+		opts.FailureBackoff = DefaultHTTPFailureBackoff
+	}
+	if opts.MaxBackoff <= 0 {
+		opts.MaxBackoff = DefaultHTTPMaxBackoff
+	}
+	if opts.MaxBundleSize <= 0 {
+		opts.MaxBundleSize = DefaultHTTPMaxBundleSize
+	}
+	return &HTTPProvider{url: url, interval: interval, opts: opts}
+}
+
+// GetEffectivePolicy returns the policy for key from the current bundle.
+func (p *HTTPProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		if p.lastErr != nil {
+			return policy.EffectivePolicy{}, fmt.Errorf("%w: %v", ErrProviderUnavailable, p.lastErr)
+		}
+		return policy.EffectivePolicy{}, ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Version returns the version of the current bundle.
+func (p *HTTPProvider) Version() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// KillSwitch reports whether the current bundle sets kill_switch.
+func (p *HTTPProvider) KillSwitch() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.kill
+}
+
+// Refresh fetches the bundle once. It returns nil when the bundle was applied or is
+// unchanged (304); on error the current policies are kept.
+func (p *HTTPProvider) Refresh(ctx context.Context) error {
+	err := p.fetch(ctx)
+	p.mu.Lock()
+	p.lastErr = err
+	p.mu.Unlock()
+	return err
+}
+
+func (p *HTTPProvider) fetch(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.url, nil)
+	if err != nil {
+		return err
+	}
+	for k, vs := range p.opts.Header {
+		req.Header[k] = append([]string(nil), vs...)
+	}
+	req.Header.Set("Accept", "application/json")
+	p.mu.RLock()
+	if p.etag != "" {
+		req.Header.Set("If-None-Match", p.etag)
+	}
+	p.mu.RUnlock()
+
+	resp, err := p.opts.Client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode == http.StatusNotModified:
+		return nil
+	case resp.StatusCode != http.StatusOK:
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4<<10))
+		if resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests {
+			return fmt.Errorf("%w: %s returned %s", ErrProviderUnavailable, p.url, resp.Status)
+		}
+		return fmt.Errorf("%w: %s returned %s", ErrPolicyFetchFailed, p.url, resp.Status)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, p.opts.MaxBundleSize+1))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	if int64(len(body)) > p.opts.MaxBundleSize {
+		return fmt.Errorf("%w: bundle exceeds %d bytes", ErrPolicyFetchFailed, p.opts.MaxBundleSize)
+	}
+	if p.opts.Verify != nil {
+		if err := p.opts.Verify(body, resp.Header); err != nil {
+			return fmt.Errorf("%w: verify bundle: %w", ErrPolicyFetchFailed, err)
+		}
+	}
+	b, err := ParseBundle(body)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrPolicyFetchFailed, err)
+	}
+	policies, _ := b.policyMap(policy.PolicySourceRemote)
+
+	p.mu.Lock()
+	p.policies = policies
+	p.version = b.Version
+	p.kill = b.KillSwitch
+	p.etag = resp.Header.Get("ETag")
+	p.loaded = true
+	p.mu.Unlock()
+	p.resources.publish(b.Resources())
+	return nil
+}
+
+// Run fetches the bundle immediately and then every interval, with jitter, until ctx
+// is done. After a failed fetch it waits FailureBackoff, doubling per consecutive
+// failure up to MaxBackoff. It returns ctx.Err().
+func (p *HTTPProvider) Run(ctx context.Context) error {
+	failures := 0
+	for {
+		wait := p.interval
+		if err := p.Refresh(ctx); err != nil && ctx.Err() == nil {
+			wait = p.opts.FailureBackoff << failures
+			if wait <= 0 || wait > p.opts.MaxBackoff {
+				wait = p.opts.MaxBackoff
+			} else {
+				failures++
+			}
+		} else {
+			failures = 0
+		}
+
+		t := time.NewTimer(p.jitter(wait))
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+func (p *HTTPProvider) jitter(d time.Duration) time.Duration {
+	if p.opts.Jitter <= 0 || d <= 0 {
+		return d
+	}
+	return d + time.Duration((rand.Float64()*2-1)*p.opts.Jitter*float64(d))
+}
+
+var _ PolicyProvider = (*HTTPProvider)(nil)
@@ -0,0 +1,216 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// bundleServer serves body with ETag etag, answering 304 to a matching If-None-Match.
+type bundleServer struct {
+	mu       sync.Mutex
+	body     string
+	etag     string
+	status   int
+	requests atomic.Int32
+	notMod   atomic.Int32
+}
+
+func (s *bundleServer) set(body, etag string, status int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.body, s.etag, s.status = body, etag, status
+}
+
+func (s *bundleServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.requests.Add(1)
+	s.mu.Lock()
+	body, etag, status := s.body, s.etag, s.status
+	s.mu.Unlock()
+	if status != 0 && status != http.StatusOK {
+		w.WriteHeader(status)
+		return
+	}
+	if etag != "" && r.Header.Get("If-None-Match") == etag {
+		s.notMod.Add(1)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	w.Header().Set("ETag", etag)
+	w.Write([]byte(body))
+}
+
+const bundleV1 = `{"version": "v1", "policies": [{"key": {"namespace": "svc", "name": "Get"}, "retry": {"max_attempts": 4}}]}`
+const bundleV2 = `{"version": "v2", "policies": [{"key": {"namespace": "svc", "name": "Get"}, "retry": {"max_attempts": 2}}]}`
+
+func TestHTTPProvider_Refresh(t *testing.T) {
+	ctx := context.Background()
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	srv := &bundleServer{}
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{})
+	if _, err := p.GetEffectivePolicy(ctx, key); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("before load: err = %v, want ErrProviderUnavailable", err)
+	}
+
+	srv.set(bundleV1, `"v1"`, http.StatusOK)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	pol, err := p.GetEffectivePolicy(ctx, key)
+	if err != nil || pol.Retry.MaxAttempts != 4 || pol.Meta.Source != policy.PolicySourceRemote || p.Version() != "v1" {
+		t.Fatalf("policy = %+v, %v (version %q)", pol, err, p.Version())
+	}
+	if _, err := p.GetEffectivePolicy(ctx, policy.ParseKey("svc.Other")); !errors.Is(err, ErrPolicyNotFound) {
+		t.Fatalf("missing key: err = %v, want ErrPolicyNotFound", err)
+	}
+
+	// Unchanged bundle is a 304.
+	if err := p.Refresh(ctx); err != nil || srv.notMod.Load() != 1 {
+		t.Fatalf("Refresh = %v, 304s = %d; want nil, 1", err, srv.notMod.Load())
+	}
+
+	// Failures keep the last good bundle.
+	srv.set("", "", http.StatusServiceUnavailable)
+	if err := p.Refresh(ctx); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("Refresh on 503 = %v, want ErrProviderUnavailable", err)
+	}
+	srv.set(`{"policies": [{"key": {}}]}`, `"bad"`, http.StatusOK)
+	if err := p.Refresh(ctx); !errors.Is(err, ErrPolicyFetchFailed) {
+		t.Fatalf("Refresh on invalid bundle = %v, want ErrPolicyFetchFailed", err)
+	}
+	if pol, _ := p.GetEffectivePolicy(ctx, key); pol.Retry.MaxAttempts != 4 {
+		t.Fatalf("policy after failures = %+v, want last good bundle", pol)
+	}
+}
+
+func TestHTTPProvider_Verify(t *testing.T) {
+	ctx := context.Background()
+	srv := &bundleServer{}
+	srv.set(bundleV1, `"v1"`, http.StatusOK)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	errBadSig := errors.New("bad signature")
+	p := NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{
+		Header: http.Header{"Authorization": {"Bearer token"}},
+		Verify: func(body []byte, header http.Header) error {
+			if header.Get("ETag") != `"v1"` {
+				return errBadSig
+			}
+			return nil
+		},
+	})
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	srv.set(bundleV2, `"v2"`, http.StatusOK)
+	if err := p.Refresh(ctx); !errors.Is(err, ErrPolicyFetchFailed) {
+		t.Fatalf("Refresh with failed verification = %v, want ErrPolicyFetchFailed", err)
+	}
+	if p.Version() != "v1" {
+		t.Fatalf("version = %q, want unverified bundle rejected", p.Version())
+	}
+}
+
+func TestHTTPProvider_Run(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	srv := &bundleServer{}
+	srv.set("", "", http.StatusInternalServerError)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL, 5*time.Millisecond, HTTPProviderOptions{
+		FailureBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- p.Run(ctx) }()
+
+	waitFor := func(attempts int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			if pol, err := p.GetEffectivePolicy(ctx, key); err == nil && pol.Retry.MaxAttempts == attempts {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("policy with %d attempts not loaded", attempts)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	srv.set(bundleV1, `"v1"`, http.StatusOK)
+	waitFor(4)
+	srv.set(bundleV2, `"v2"`, http.StatusOK)
+	waitFor(2)
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
+
+func TestHTTPProvider_DefaultInterval(t *testing.T) {
+	for _, interval := range []time.Duration{0, -time.Second} {
+		if p := NewHTTPProvider("http://config.invalid", interval, HTTPProviderOptions{}); p.interval != DefaultHTTPPollInterval {
+			t.Errorf("interval %v: polls every %v, want %v", interval, p.interval, DefaultHTTPPollInterval)
+		}
+	}
+}
+
+func TestParseBundle(t *testing.T) {
+	for name, body := range map[string]string{
+		"syntax":    `{"policies": [`,
+		"no key":    `{"policies": [{"retry": {"max_attempts": 2}}]}`,
+		"duplicate": `{"policies": [{"key": {"name": "a"}}, {"key": {"name": "a"}}]}`,
+	} {
+		if _, err := ParseBundle([]byte(body)); err == nil {
+			t.Errorf("%s: ParseBundle succeeded, want error", name)
+		}
+	}
+	b, err := ParseBundle([]byte(bundleV1))
+	if err != nil || b.Version != "v1" || len(b.Policies) != 1 {
+		t.Fatalf("ParseBundle = %+v, %v", b, err)
+	}
+}
+
+func TestHTTPProvider_KillSwitch(t *testing.T) {
+	ctx := context.Background()
+	srv := &bundleServer{}
+	srv.set(`{"version": "v1", "kill_switch": true, "policies": []}`, `"v1"`, http.StatusOK)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	p := NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{})
+	if KillSwitch(p) {
+		t.Fatal("kill switch on before the first bundle")
+	}
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if !KillSwitch(p) || !KillSwitch(Chain(&StaticProvider{}, p)) {
+		t.Fatal("kill switch not reported")
+	}
+
+	srv.set(`{"version": "v2", "policies": []}`, `"v2"`, http.StatusOK)
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if KillSwitch(p) {
+		t.Fatal("kill switch still on after bundle cleared it")
+	}
+}
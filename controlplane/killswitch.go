@@ -0,0 +1,33 @@
+package controlplane
+
+// KillSwitchProvider is an optional PolicyProvider extension for control planes that
+// can suppress retries globally during incidents. While KillSwitch reports true, an
+// executor using the provider forces MaxAttempts=1 and disables hedging for every key.
+//
+// HTTPProvider and FileProvider report the bundle's kill_switch field. LKGProvider, CachingProvider,
+// and ChainProvider forward the switch of the providers they wrap.
+type KillSwitchProvider interface {
+	KillSwitch() bool
+}
+
+// KillSwitch reports whether p is a KillSwitchProvider with its switch on.
+func KillSwitch(p PolicyProvider) bool {
+	ks, ok := p.(KillSwitchProvider)
+	return ok && ks.KillSwitch()
+}
+
+// KillSwitch reports whether the wrapped provider's kill switch is on.
+func (p *LKGProvider) KillSwitch() bool { return KillSwitch(p.provider) }
+
+// KillSwitch reports whether the wrapped provider's kill switch is on.
+func (c *CachingProvider) KillSwitch() bool { return KillSwitch(c.provider) }
+
+// KillSwitch reports whether the kill switch of any provider in the chain is on.
+func (c *ChainProvider) KillSwitch() bool {
+	for _, link := range c.links {
+		if KillSwitch(link.provider) {
+			return true
+		}
+	}
+	return false
+}
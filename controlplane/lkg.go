@@ -0,0 +1,163 @@
+package controlplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// LKGProvider wraps a provider with a last-known-good snapshot on disk. Every policy the
+// wrapped provider resolves successfully is recorded, and the snapshot file is rewritten
+// when a recorded policy changes. When the provider fails (unavailable, fetch failure,
+// or before its first load) the snapshot is served instead, with Meta.Source
+// PolicySourceLKG and a nil error, so it applies under any MissingPolicyMode; the
+// executor reports it in the "policy_source" timeline attribute.
+//
+// ErrPolicyNotFound is authoritative: it is passed through and the key is dropped from
+// the snapshot. The snapshot is a Bundle document.
+type LKGProvider struct {
+	provider PolicyProvider
+	path     string
+	onError  func(error)
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+
+	saveMu sync.Mutex
+}
+
+// LKGOption configures an LKGProvider.
+type LKGOption func(*LKGProvider)
+
+// WithLKGErrorHandler sets a function called when the snapshot cannot be written.
+// By default write errors are ignored; the next change retries the write.
+func WithLKGErrorHandler(fn func(error)) LKGOption {
+	return func(l *LKGProvider) {
+		l.onError = fn
+	}
+}
+
+// NewLKGProvider returns a provider that falls back to the snapshot at path, loading it
+// if the file exists. It returns an error if an existing snapshot cannot be read.
+func NewLKGProvider(p PolicyProvider, path string, opts ...LKGOption) (*LKGProvider, error) {
+	l := &LKGProvider{provider: p, path: path, policies: make(map[policy.PolicyKey]policy.EffectivePolicy)}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return l, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("controlplane: read lkg snapshot: %w", err)
+	}
+	if data, err = upgradeDocument(data); err != nil {
+		return nil, fmt.Errorf("controlplane: lkg snapshot %s: %w", path, err)
+	}
+	var b Bundle
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("controlplane: decode lkg snapshot %s: %w", path, err)
+	}
+	policies, err := b.policyMap(policy.PolicySourceLKG)
+	if err != nil {
+		return nil, fmt.Errorf("controlplane: lkg snapshot %s: %w", path, err)
+	}
+	l.policies = policies
+	return l, nil
+}
+
+// GetEffectivePolicy resolves key from the wrapped provider, falling back to the snapshot.
+func (l *LKGProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	pol, err := l.provider.GetEffectivePolicy(ctx, key)
+	switch {
+	case err == nil:
+		l.record(key, pol)
+		return pol, nil
+	case errors.Is(err, ErrPolicyNotFound):
+		l.forget(key)
+		return pol, err
+	}
+
+	l.mu.RLock()
+	lkg, ok := l.policies[key]
+	l.mu.RUnlock()
+	if !ok {
+		return pol, err
+	}
+	lkg.Meta.Source = policy.PolicySourceLKG
+	return lkg, nil
+}
+
+func (l *LKGProvider) record(key policy.PolicyKey, pol policy.EffectivePolicy) {
+	pol.Key = key
+	l.mu.Lock()
+	prev, ok := l.policies[key]
+	if ok && samePolicy(prev, pol) {
+		l.mu.Unlock()
+		return
+	}
+	l.policies[key] = pol
+	l.mu.Unlock()
+	l.save()
+}
+
+func (l *LKGProvider) forget(key policy.PolicyKey) {
+	l.mu.Lock()
+	_, ok := l.policies[key]
+	delete(l.policies, key)
+	l.mu.Unlock()
+	if ok {
+		l.save()
+	}
+}
+
+// save writes the current snapshot atomically (write to a temporary file, then rename).
+func (l *LKGProvider) save() {
+	l.saveMu.Lock()
+	defer l.saveMu.Unlock()
+
+	l.mu.RLock()
+	b := Bundle{SchemaVersion: SchemaVersion, Policies: make([]policy.EffectivePolicy, 0, len(l.policies))}
+	for _, pol := range l.policies {
+		b.Policies = append(b.Policies, pol)
+	}
+	l.mu.RUnlock()
+	sort.Slice(b.Policies, func(i, j int) bool { return b.Policies[i].Key.String() < b.Policies[j].Key.String() })
+
+	err := func() error {
+		data, err := json.MarshalIndent(b, "", "  ")
+		if err != nil {
+			return err
+		}
+		tmp, err := os.CreateTemp(filepath.Dir(l.path), filepath.Base(l.path)+".tmp*")
+		if err != nil {
+			return err
+		}
+		defer os.Remove(tmp.Name())
+		if _, err := tmp.Write(data); err != nil {
+			tmp.Close()
+			return err
+		}
+		if err := tmp.Close(); err != nil {
+			return err
+		}
+		return os.Rename(tmp.Name(), l.path)
+	}()
+	if err != nil && l.onError != nil {
+		l.onError(fmt.Errorf("controlplane: write lkg snapshot: %w", err))
+	}
+}
+
+func samePolicy(a, b policy.EffectivePolicy) bool {
+	return a.Key == b.Key && a.ID == b.ID && a.Retry.Equal(b.Retry) && a.Hedge == b.Hedge && a.Circuit == b.Circuit && a.Limits == b.Limits &&
+		reflect.DeepEqual(a.Rollout, b.Rollout)
+}
@@ -0,0 +1,75 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// switchProvider returns err when set, otherwise policies or ErrPolicyNotFound.
+type switchProvider struct {
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	err      error
+}
+
+func (p *switchProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	if p.err != nil {
+		return policy.EffectivePolicy{}, p.err
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+func TestLKGProvider(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "lkg.json")
+	key := policy.ParseKey("svc.Get")
+	gone := policy.ParseKey("svc.Gone")
+
+	remote := &switchProvider{policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		key:  policy.New("svc.Get", policy.MaxAttempts(4)),
+		gone: policy.New("svc.Gone", policy.MaxAttempts(2)),
+	}}
+	lkg, err := NewLKGProvider(remote, path)
+	if err != nil {
+		t.Fatalf("NewLKGProvider: %v", err)
+	}
+	for _, k := range []policy.PolicyKey{key, gone} {
+		if _, err := lkg.GetEffectivePolicy(ctx, k); err != nil {
+			t.Fatalf("GetEffectivePolicy(%s): %v", k, err)
+		}
+	}
+
+	// Not found is authoritative and drops the key.
+	delete(remote.policies, gone)
+	if _, err := lkg.GetEffectivePolicy(ctx, gone); !errors.Is(err, ErrPolicyNotFound) {
+		t.Fatalf("removed key: err = %v, want ErrPolicyNotFound", err)
+	}
+
+	// An outage serves the snapshot, also after a restart.
+	remote.err = ErrProviderUnavailable
+	for _, p := range []PolicyProvider{lkg, mustLKG(t, remote, path)} {
+		pol, err := p.GetEffectivePolicy(ctx, key)
+		if err != nil || pol.Retry.MaxAttempts != 4 || pol.Meta.Source != policy.PolicySourceLKG {
+			t.Fatalf("during outage: %+v, %v; want LKG policy", pol, err)
+		}
+		if _, err := p.GetEffectivePolicy(ctx, gone); !errors.Is(err, ErrProviderUnavailable) {
+			t.Fatalf("dropped key during outage: err = %v, want ErrProviderUnavailable", err)
+		}
+	}
+}
+
+func mustLKG(t *testing.T, p PolicyProvider, path string) *LKGProvider {
+	t.Helper()
+	l, err := NewLKGProvider(p, path)
+	if err != nil {
+		t.Fatalf("NewLKGProvider: %v", err)
+	}
+	return l
+}
@@ -48,6 +48,6 @@ func (p *StaticProvider) GetEffectivePolicy(_ context.Context, key policy.Policy
 func isZeroEffectivePolicy(pol policy.EffectivePolicy) bool {
 	return pol.Key == (policy.PolicyKey{}) &&
 		pol.ID == "" &&
-		pol.Retry == (policy.RetryPolicy{}) &&
+		pol.Retry.Equal(policy.RetryPolicy{}) &&
 		pol.Hedge == (policy.HedgePolicy{})
 }
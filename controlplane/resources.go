@@ -0,0 +1,183 @@
+package controlplane
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Budget types for BudgetSpec.Type.
+const (
+	BudgetTypeTokenBucket = "token_bucket"
+	BudgetTypeUnlimited   = "unlimited"
+)
+
+// BudgetSpec defines a named budget in a bundle's "budgets" section:
+//
+//	"budgets": {"payments": {"type": "token_bucket", "capacity": 100, "refill_per_second": 10}}
+type BudgetSpec struct {
+	Type            string  `json:"type,omitempty"`              // BudgetTypeTokenBucket (default) or BudgetTypeUnlimited.
+	Capacity        int     `json:"capacity,omitempty"`          // Token bucket size.
+	RefillPerSecond float64 `json:"refill_per_second,omitempty"` // Token bucket refill rate.
+
+	// Priority thresholds: the fraction of capacity a low- or normal-priority retry
+	// must leave in the bucket (see budget.PriorityThresholds).
+	LowPriorityThreshold    float64 `json:"low_priority_threshold,omitempty"`
+	NormalPriorityThreshold float64 `json:"normal_priority_threshold,omitempty"`
+}
+
+// CircuitSpec holds a bundle's global circuit parameters ("circuit" section). When
+// set, every circuit breaker, for keys whose policy enables circuit breaking, uses them
+// instead of the policy's threshold and cooldown. Zero fields keep the policy's value.
+type CircuitSpec struct {
+	Threshold int           `json:"threshold,omitempty"` // Consecutive failures to open.
+	Cooldown  time.Duration `json:"cooldown,omitempty"`  // Open duration before a probe (nanoseconds).
+}
+
+// FaultSpec configures fault injection for one policy key in a bundle's "faults"
+// section, for chaos testing (see the chaos package). Each fault is applied to an
+// attempt independently with its probability (0 to 1):
+//
+//	"faults": {"payments.Charge": {"error_probability": 0.1, "latency_probability": 0.5, "latency": 200000000}}
+//
+// Executors apply them to every call when fault injection is enabled (see
+// retry.WithFaultInjection); otherwise only operations wrapped with a chaos.Injector
+// see them.
+type FaultSpec struct {
+	LatencyProbability float64       `json:"latency_probability,omitempty"` // Chance to delay an attempt.
+	Latency            time.Duration `json:"latency,omitempty"`             // Added delay (nanoseconds).
+	ErrorProbability   float64       `json:"error_probability,omitempty"`   // Chance to fail an attempt.
+	Error              string        `json:"error,omitempty"`               // Message of the injected error.
+	PanicProbability   float64       `json:"panic_probability,omitempty"`   // Chance to panic in an attempt.
+	ForceCircuitOpen   bool          `json:"force_circuit_open,omitempty"`  // Reject calls as if the key's circuit were open.
+}
+
+// Resources are the settings of a bundle beyond per-key policies: named budgets,
+// global circuit parameters, and fault injection.
+type Resources struct {
+	Budgets map[string]BudgetSpec
+	Circuit *CircuitSpec
+	Faults  map[string]FaultSpec
+}
+
+// ResourceProvider is an optional PolicyProvider extension for control planes that also
+// define Resources. An executor using such a provider materializes budgets and circuit
+// parameters into its registries whenever they change, so capacity knobs need not be
+// fixed at build time; a chaos.Injector watching it applies the faults.
+//
+// HTTPProvider and FileProvider implement it from their bundle; LKGProvider,
+// CachingProvider, and ChainProvider forward it to the providers they wrap.
+type ResourceProvider interface {
+	// WatchResources calls fn with the current resources, if a bundle has loaded, and
+	// again after every change.
+	WatchResources(fn func(Resources))
+}
+
+func (b Bundle) validateResources() error {
+	for name, spec := range b.Budgets {
+		if name == "" {
+			return fmt.Errorf("controlplane: bundle has a budget with an empty name")
+		}
+		switch spec.Type {
+		case "", BudgetTypeTokenBucket:
+			if spec.Capacity <= 0 || spec.RefillPerSecond < 0 {
+				return fmt.Errorf("controlplane: budget %q: token bucket needs a positive capacity and a non-negative refill", name)
+			}
+			for _, t := range []float64{spec.LowPriorityThreshold, spec.NormalPriorityThreshold} {
+				if t < 0 || t > 1 {
+					return fmt.Errorf("controlplane: budget %q: priority thresholds must be between 0 and 1", name)
+				}
+			}
+		case BudgetTypeUnlimited:
+		default:
+			return fmt.Errorf("controlplane: budget %q: unknown type %q", name, spec.Type)
+		}
+	}
+	if c := b.Circuit; c != nil && (c.Threshold < 0 || c.Cooldown < 0) {
+		return fmt.Errorf("controlplane: circuit parameters must not be negative")
+	}
+	for key, f := range b.Faults {
+		if key == "" {
+			return fmt.Errorf("controlplane: bundle has faults with an empty key")
+		}
+		for _, p := range []float64{f.LatencyProbability, f.ErrorProbability, f.PanicProbability} {
+			if p < 0 || p > 1 {
+				return fmt.Errorf("controlplane: faults %q: probabilities must be between 0 and 1", key)
+			}
+		}
+		if f.Latency < 0 {
+			return fmt.Errorf("controlplane: faults %q: latency must not be negative", key)
+		}
+	}
+	return nil
+}
+
+// Resources returns the bundle's budgets, circuit parameters, and faults.
+func (b Bundle) Resources() Resources {
+	r := Resources{Budgets: maps.Clone(b.Budgets), Faults: maps.Clone(b.Faults)}
+	if b.Circuit != nil {
+		c := *b.Circuit
+		r.Circuit = &c
+	}
+	return r
+}
+
+// resourceWatchers publishes Resources to WatchResources callbacks.
+type resourceWatchers struct {
+	mu      sync.Mutex
+	fns     []func(Resources)
+	current *Resources
+}
+
+func (w *resourceWatchers) watch(fn func(Resources)) {
+	w.mu.Lock()
+	w.fns = append(w.fns, fn)
+	cur := w.current
+	w.mu.Unlock()
+	if cur != nil {
+		fn(*cur)
+	}
+}
+
+// publish records r and notifies the watchers if it differs from the last resources.
+func (w *resourceWatchers) publish(r Resources) {
+	w.mu.Lock()
+	if w.current != nil && reflect.DeepEqual(*w.current, r) {
+		w.mu.Unlock()
+		return
+	}
+	w.current = &r
+	fns := slices.Clone(w.fns)
+	w.mu.Unlock()
+	for _, fn := range fns {
+		fn(r)
+	}
+}
+
+// WatchResources calls fn with the bundle's resources, now if loaded and after changes.
+func (p *HTTPProvider) WatchResources(fn func(Resources)) { p.resources.watch(fn) }
+
+// WatchResources calls fn with the bundle's resources, now if loaded and after changes.
+func (p *FileProvider) WatchResources(fn func(Resources)) { p.resources.watch(fn) }
+
+// WatchResources forwards to the wrapped provider, if it is a ResourceProvider.
+func (l *LKGProvider) WatchResources(fn func(Resources)) { watchResources(l.provider, fn) }
+
+// WatchResources forwards to the wrapped provider, if it is a ResourceProvider.
+func (c *CachingProvider) WatchResources(fn func(Resources)) { watchResources(c.provider, fn) }
+
+// WatchResources forwards to every provider in the chain that is a ResourceProvider.
+func (c *ChainProvider) WatchResources(fn func(Resources)) {
+	for _, link := range c.links {
+		watchResources(link.provider, fn)
+	}
+}
+
+func watchResources(p PolicyProvider, fn func(Resources)) {
+	if rp, ok := p.(ResourceProvider); ok {
+		rp.WatchResources(fn)
+	}
+}
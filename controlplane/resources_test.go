@@ -0,0 +1,79 @@
+package controlplane
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestParseBundle_Resources(t *testing.T) {
+	b, err := ParseBundle([]byte(`{
+		"budgets": {
+			"payments": {"capacity": 100, "refill_per_second": 10},
+			"free": {"type": "unlimited"}
+		},
+		"circuit": {"threshold": 3, "cooldown": 5000000000}
+	}`))
+	if err != nil {
+		t.Fatalf("ParseBundle: %v", err)
+	}
+	r := b.Resources()
+	if got := r.Budgets["payments"]; got != (BudgetSpec{Capacity: 100, RefillPerSecond: 10}) {
+		t.Fatalf("payments = %+v", got)
+	}
+	if got := r.Budgets["free"]; got.Type != BudgetTypeUnlimited {
+		t.Fatalf("free = %+v", got)
+	}
+	if r.Circuit == nil || r.Circuit.Threshold != 3 || r.Circuit.Cooldown != 5*time.Second {
+		t.Fatalf("circuit = %+v", r.Circuit)
+	}
+
+	for name, body := range map[string]string{
+		"no capacity":      `{"budgets": {"a": {"refill_per_second": 1}}}`,
+		"unknown type":     `{"budgets": {"a": {"type": "leaky"}}}`,
+		"negative circuit": `{"circuit": {"threshold": -1}}`,
+	} {
+		if _, err := ParseBundle([]byte(body)); err == nil {
+			t.Errorf("%s: ParseBundle succeeded, want error", name)
+		}
+	}
+}
+
+func TestFileProvider_WatchResources(t *testing.T) {
+	path := writeBundleFile(t, t.TempDir(), "bundle.json", `{"budgets": {"a": {"capacity": 10}}}`)
+	p := NewFileProvider(path)
+
+	var got []Resources
+	Chain(&StaticProvider{}, p).WatchResources(func(r Resources) { got = append(got, r) })
+	if len(got) != 0 {
+		t.Fatalf("watcher called %d times before load", len(got))
+	}
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(got) != 1 || got[0].Budgets["a"].Capacity != 10 {
+		t.Fatalf("after load: %+v", got)
+	}
+
+	// Reloading an unchanged bundle does not notify.
+	if err := p.Reload(); err != nil || len(got) != 1 {
+		t.Fatalf("Reload = %v, notifications = %d; want nil, 1", err, len(got))
+	}
+
+	if err := os.WriteFile(path, []byte(`{"circuit": {"threshold": 2}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Reload(); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if len(got) != 2 || len(got[1].Budgets) != 0 || got[1].Circuit.Threshold != 2 {
+		t.Fatalf("after change: %+v", got)
+	}
+
+	// A late watcher sees the current resources immediately.
+	var late Resources
+	p.WatchResources(func(r Resources) { late = r })
+	if late.Circuit == nil || late.Circuit.Threshold != 2 {
+		t.Fatalf("late watcher = %+v", late)
+	}
+}
@@ -0,0 +1,107 @@
+package controlplane
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// SchemaVersion is the policy payload schema this package reads and writes. Bundle
+// documents and DecodePolicy payloads declare theirs in a top-level "schema_version";
+// payloads without one are version 0, the format used before schemas were versioned.
+//
+// Older payloads are upgraded on decode, so a control plane may keep serving them
+// after clients upgrade. Payloads from a newer schema are rejected with
+// ErrUnsupportedSchema rather than misread; providers keep their current policies, and
+// an LKGProvider serves its snapshot, until a supported payload arrives. Upgrade
+// clients before the control plane starts emitting a new schema.
+const SchemaVersion = 1
+
+// ErrUnsupportedSchema indicates a payload whose schema_version is newer than
+// SchemaVersion (or invalid).
+var ErrUnsupportedSchema = errors.New("recourse: unsupported policy schema version")
+
+// schemaMigrations[v] upgrades one policy object (a policy, or a defaults or namespace
+// layer of a bundle document) from schema version v to v+1, in place.
+var schemaMigrations = [SchemaVersion]func(pol map[string]any) error{
+	// Version 1 declares the unversioned format; the fields are unchanged.
+	0: func(map[string]any) error { return nil },
+}
+
+// schemaVersionOf returns the payload's declared schema version.
+func schemaVersionOf(data []byte) (int, error) {
+	var head struct {
+		SchemaVersion *int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil {
+		return 0, err
+	}
+	switch {
+	case head.SchemaVersion == nil:
+		return 0, nil
+	case *head.SchemaVersion < 0 || *head.SchemaVersion > SchemaVersion:
+		return 0, fmt.Errorf("%w: %d (supported: 0 to %d)", ErrUnsupportedSchema, *head.SchemaVersion, SchemaVersion)
+	}
+	return *head.SchemaVersion, nil
+}
+
+// upgradePolicy upgrades a policy payload to SchemaVersion.
+func upgradePolicy(data []byte) ([]byte, error) {
+	return upgrade(data, func(doc map[string]any, step func(map[string]any) error) error {
+		return step(doc)
+	})
+}
+
+// upgradeDocument upgrades a bundle document to SchemaVersion, migrating each of its
+// policy layers.
+func upgradeDocument(data []byte) ([]byte, error) {
+	return upgrade(data, func(doc map[string]any, step func(map[string]any) error) error {
+		layers := []any{doc["defaults"]}
+		if nss, ok := doc["namespaces"].(map[string]any); ok {
+			for _, ns := range nss {
+				sec, _ := ns.(map[string]any)
+				layers = append(layers, sec["defaults"])
+				if pols, ok := sec["policies"].(map[string]any); ok {
+					for _, pol := range pols {
+						layers = append(layers, pol)
+					}
+				}
+			}
+		}
+		if pols, ok := doc["policies"].([]any); ok {
+			layers = append(layers, pols...)
+		}
+		for _, layer := range layers {
+			if m, ok := layer.(map[string]any); ok {
+				if err := step(m); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
+
+// upgrade applies the migrations from the payload's version to SchemaVersion, using
+// apply to run each step over the payload's policy objects. Current payloads are
+// returned unchanged.
+func upgrade(data []byte, apply func(doc map[string]any, step func(map[string]any) error) error) ([]byte, error) {
+	from, err := schemaVersionOf(data)
+	if err != nil || from == SchemaVersion {
+		return data, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	var doc map[string]any
+	if err := dec.Decode(&doc); err != nil {
+		return nil, err
+	}
+	for v := from; v < SchemaVersion; v++ {
+		if err := apply(doc, schemaMigrations[v]); err != nil {
+			return nil, fmt.Errorf("upgrade schema %d to %d: %w", v, v+1, err)
+		}
+	}
+	doc["schema_version"] = SchemaVersion
+	return json.Marshal(doc)
+}
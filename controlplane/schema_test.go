@@ -0,0 +1,124 @@
+package controlplane
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestParseBundle_SchemaVersion(t *testing.T) {
+	for _, body := range []string{
+		`{"policies": [{"key": {"name": "a"}}]}`,
+		`{"schema_version": 0, "policies": [{"key": {"name": "a"}}]}`,
+		`{"schema_version": 1, "policies": [{"key": {"name": "a"}}]}`,
+	} {
+		b, err := ParseBundle([]byte(body))
+		if err != nil || b.SchemaVersion != SchemaVersion {
+			t.Fatalf("ParseBundle(%s) = schema %d, %v; want %d", body, b.SchemaVersion, err, SchemaVersion)
+		}
+	}
+	for _, body := range []string{
+		`{"schema_version": 2, "policies": []}`,
+		`{"schema_version": -1, "policies": []}`,
+	} {
+		if _, err := ParseBundle([]byte(body)); !errors.Is(err, ErrUnsupportedSchema) {
+			t.Fatalf("ParseBundle(%s) = %v, want ErrUnsupportedSchema", body, err)
+		}
+	}
+	if _, err := DecodePolicy(policy.ParseKey("svc.Get"), []byte(`{"schema_version": 99}`)); !errors.Is(err, ErrUnsupportedSchema) {
+		t.Fatalf("DecodePolicy of future schema = %v, want ErrUnsupportedSchema", err)
+	}
+}
+
+func TestUpgradeDocument_MigratesEveryLayer(t *testing.T) {
+	saved := schemaMigrations
+	defer func() { schemaMigrations = saved }()
+	// Pretend version 0 named the field "attempts".
+	schemaMigrations[0] = func(pol map[string]any) error {
+		if r, ok := pol["retry"].(map[string]any); ok {
+			if v, ok := r["attempts"]; ok {
+				r["max_attempts"] = v
+				delete(r, "attempts")
+			}
+		}
+		return nil
+	}
+
+	b, err := ParseBundle([]byte(`{
+		"defaults": {"retry": {"attempts": 2}},
+		"namespaces": {"svc": {"policies": {"A": {}, "B": {"retry": {"attempts": 3}}}}},
+		"policies": [{"key": {"namespace": "svc", "name": "C"}, "retry": {"attempts": 4}}]
+	}`))
+	if err != nil {
+		t.Fatalf("ParseBundle: %v", err)
+	}
+	got := map[string]int{}
+	for _, pol := range b.Policies {
+		got[pol.Key.Name] = pol.Retry.MaxAttempts
+	}
+	if want := map[string]int{"A": 2, "B": 3, "C": 4}; len(got) != 3 || got["A"] != want["A"] || got["B"] != want["B"] || got["C"] != want["C"] {
+		t.Fatalf("max attempts = %v, want %v", got, want)
+	}
+
+	pol, err := DecodePolicy(policy.ParseKey("svc.D"), []byte(`{"retry": {"attempts": 5}}`))
+	if err != nil || pol.Retry.MaxAttempts != 5 {
+		t.Fatalf("DecodePolicy = %+v, %v", pol.Retry, err)
+	}
+}
+
+func TestHTTPProvider_FutureSchemaFallsBackToLKG(t *testing.T) {
+	ctx := context.Background()
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	srv := &bundleServer{}
+	srv.set(bundleV1, `"v1"`, http.StatusOK)
+	ts := httptest.NewServer(srv)
+	defer ts.Close()
+
+	snapshot := filepath.Join(t.TempDir(), "lkg.json")
+	p := NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{})
+	l, err := NewLKGProvider(p, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if _, err := l.GetEffectivePolicy(ctx, key); err != nil {
+		t.Fatalf("GetEffectivePolicy: %v", err)
+	}
+
+	// A restarted client that only ever sees a future bundle serves the snapshot.
+	srv.set(`{"schema_version": 2, "policies": []}`, `"v2"`, http.StatusOK)
+	p = NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{})
+	if l, err = NewLKGProvider(p, snapshot); err != nil {
+		t.Fatalf("reload snapshot: %v", err)
+	}
+	if err := p.Refresh(ctx); !errors.Is(err, ErrUnsupportedSchema) || !errors.Is(err, ErrPolicyFetchFailed) {
+		t.Fatalf("Refresh of future bundle = %v, want ErrUnsupportedSchema and ErrPolicyFetchFailed", err)
+	}
+	pol, err := l.GetEffectivePolicy(ctx, key)
+	if err != nil || pol.Meta.Source != policy.PolicySourceLKG || pol.Retry.MaxAttempts != 4 {
+		t.Fatalf("policy = %+v, %v; want the LKG snapshot", pol, err)
+	}
+}
+
+func TestSchemaVersion_WrittenOnOutput(t *testing.T) {
+	b, err := ParseBundle([]byte(bundleV1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(b)
+	var head struct {
+		SchemaVersion int `json:"schema_version"`
+	}
+	if err := json.Unmarshal(data, &head); err != nil || head.SchemaVersion != SchemaVersion {
+		t.Fatalf("marshaled schema_version = %d, %v", head.SchemaVersion, err)
+	}
+}
@@ -0,0 +1,163 @@
+package controlplane
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// SignatureHeader is the response header that carries a bundle's detached signature.
+const SignatureHeader = "Recourse-Signature"
+
+// ErrBadSignature indicates a bundle whose signature is missing or does not verify
+// against any trusted key.
+var ErrBadSignature = errors.New("recourse: bundle signature invalid")
+
+// SignatureVerifier checks detached Ed25519 signatures on policy bundles, protecting
+// against a compromised config endpoint. Use its Verify method as
+// HTTPProviderOptions.Verify: a bundle that fails verification is rejected and the
+// provider keeps its last good bundle (wrap it in an LKGProvider to survive restarts).
+//
+// The signature is either the base64-encoded Ed25519 signature of the body, or a
+// detached compact JWS (RFC 7515 appendix F, "<header>..<signature>") with alg
+// "EdDSA", as produced by SignBundle. A JWS "kid" selects the trusted key; otherwise
+// every trusted key is tried.
+type SignatureVerifier struct {
+	// Header names the response header carrying the signature. Defaults to SignatureHeader.
+	Header string
+	// OnFailure, if set, is called with every verification failure, e.g. to alert on
+	// tampering. The bundle is rejected regardless.
+	OnFailure func(error)
+
+	keys map[string]ed25519.PublicKey
+	ids  []string
+}
+
+// NewSignatureVerifier returns a verifier trusting keys, indexed by key ID.
+func NewSignatureVerifier(keys map[string]ed25519.PublicKey) *SignatureVerifier {
+	v := &SignatureVerifier{keys: make(map[string]ed25519.PublicKey, len(keys))}
+	for id, k := range keys {
+		v.keys[id] = k
+		v.ids = append(v.ids, id)
+	}
+	sort.Strings(v.ids)
+	return v
+}
+
+// Verify checks the signature in header against body. It matches the
+// HTTPProviderOptions.Verify signature.
+func (v *SignatureVerifier) Verify(body []byte, header http.Header) error {
+	name := v.Header
+	if name == "" {
+		name = SignatureHeader
+	}
+	return v.VerifySignature(body, header.Get(name))
+}
+
+// VerifySignature checks sig against body, for transports other than HTTP.
+func (v *SignatureVerifier) VerifySignature(body []byte, sig string) error {
+	err := v.verify(body, strings.TrimSpace(sig))
+	if err != nil && v.OnFailure != nil {
+		v.OnFailure(err)
+	}
+	return err
+}
+
+func (v *SignatureVerifier) verify(body []byte, sig string) error {
+	if sig == "" {
+		return fmt.Errorf("%w: no signature", ErrBadSignature)
+	}
+	if protected, rawSig, ok := strings.Cut(sig, ".."); ok {
+		return v.verifyJWS(body, protected, rawSig)
+	}
+	raw, err := decodeBase64(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrBadSignature, err)
+	}
+	if v.match("", body, raw) {
+		return nil
+	}
+	return fmt.Errorf("%w: no trusted key matches", ErrBadSignature)
+}
+
+func (v *SignatureVerifier) verifyJWS(body []byte, protected, rawSig string) error {
+	hdrJSON, err := base64.RawURLEncoding.DecodeString(protected)
+	if err != nil {
+		return fmt.Errorf("%w: jws header: %v", ErrBadSignature, err)
+	}
+	var hdr struct {
+		Alg  string   `json:"alg"`
+		Kid  string   `json:"kid"`
+		B64  *bool    `json:"b64"`
+		Crit []string `json:"crit"`
+	}
+	if err := json.Unmarshal(hdrJSON, &hdr); err != nil {
+		return fmt.Errorf("%w: jws header: %v", ErrBadSignature, err)
+	}
+	if hdr.Alg != "EdDSA" {
+		return fmt.Errorf("%w: unsupported jws alg %q", ErrBadSignature, hdr.Alg)
+	}
+	for _, c := range hdr.Crit {
+		if c != "b64" {
+			return fmt.Errorf("%w: unsupported critical jws header %q", ErrBadSignature, c)
+		}
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(rawSig)
+	if err != nil {
+		return fmt.Errorf("%w: jws signature: %v", ErrBadSignature, err)
+	}
+
+	payload := base64.RawURLEncoding.EncodeToString(body)
+	if hdr.B64 != nil && !*hdr.B64 {
+		payload = string(body) // RFC 7797 unencoded payload
+	}
+	if v.match(hdr.Kid, []byte(protected+"."+payload), raw) {
+		return nil
+	}
+	if hdr.Kid != "" {
+		if _, ok := v.keys[hdr.Kid]; !ok {
+			return fmt.Errorf("%w: untrusted key %q", ErrBadSignature, hdr.Kid)
+		}
+	}
+	return fmt.Errorf("%w: no trusted key matches", ErrBadSignature)
+}
+
+// match reports whether sig signs msg under the key kid, or any trusted key if kid is empty.
+func (v *SignatureVerifier) match(kid string, msg, sig []byte) bool {
+	if kid != "" {
+		k, ok := v.keys[kid]
+		return ok && len(k) == ed25519.PublicKeySize && ed25519.Verify(k, msg, sig)
+	}
+	for _, id := range v.ids {
+		if k := v.keys[id]; len(k) == ed25519.PublicKeySize && ed25519.Verify(k, msg, sig) {
+			return true
+		}
+	}
+	return false
+}
+
+// SignBundle returns a detached compact JWS over body, signed with key and labeled
+// with kid, for serving in SignatureHeader alongside the bundle.
+func SignBundle(key ed25519.PrivateKey, kid string, body []byte) string {
+	hdr, _ := json.Marshal(struct {
+		Alg string `json:"alg"`
+		Kid string `json:"kid,omitempty"`
+	}{Alg: "EdDSA", Kid: kid})
+	protected := base64.RawURLEncoding.EncodeToString(hdr)
+	sig := ed25519.Sign(key, []byte(protected+"."+base64.RawURLEncoding.EncodeToString(body)))
+	return protected + ".." + base64.RawURLEncoding.EncodeToString(sig)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	for _, enc := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if b, err := enc.DecodeString(s); err == nil {
+			return b, nil
+		}
+	}
+	return nil, errors.New("signature is not base64")
+}
@@ -0,0 +1,87 @@
+package controlplane
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSignatureVerifier(t *testing.T) {
+	trusted := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	rogue := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	body := []byte(bundleV1)
+
+	var failures int
+	v := NewSignatureVerifier(map[string]ed25519.PublicKey{"k1": trusted.Public().(ed25519.PublicKey)})
+	v.OnFailure = func(error) { failures++ }
+
+	for name, sig := range map[string]string{
+		"jws":        SignBundle(trusted, "k1", body),
+		"jws no kid": SignBundle(trusted, "", body),
+		"raw":        base64.StdEncoding.EncodeToString(ed25519.Sign(trusted, body)),
+	} {
+		if err := v.VerifySignature(body, sig); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+
+	for name, sig := range map[string]string{
+		"missing":     "",
+		"rogue key":   SignBundle(rogue, "k1", body),
+		"unknown kid": SignBundle(trusted, "k2", body),
+		"tampered":    SignBundle(trusted, "k1", []byte(bundleV2)),
+		"garbage":     "not a signature!",
+	} {
+		if err := v.VerifySignature(body, sig); !errors.Is(err, ErrBadSignature) {
+			t.Errorf("%s: err = %v, want ErrBadSignature", name, err)
+		}
+	}
+	if failures != 5 {
+		t.Fatalf("OnFailure calls = %d, want 5", failures)
+	}
+}
+
+func TestHTTPProvider_SignedBundle(t *testing.T) {
+	ctx := context.Background()
+	key := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	var (
+		mu        sync.Mutex
+		body, sig string
+	)
+	serve := func(b, s string) {
+		mu.Lock()
+		defer mu.Unlock()
+		body, sig = b, s
+	}
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		w.Header().Set(SignatureHeader, sig)
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	v := NewSignatureVerifier(map[string]ed25519.PublicKey{"k1": key.Public().(ed25519.PublicKey)})
+	p := NewHTTPProvider(ts.URL, time.Minute, HTTPProviderOptions{Verify: v.Verify})
+
+	serve(bundleV1, SignBundle(key, "k1", []byte(bundleV1)))
+	if err := p.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+
+	// A compromised endpoint serving an unsigned bundle is rejected.
+	serve(bundleV2, SignBundle(key, "k1", []byte(bundleV1)))
+	if err := p.Refresh(ctx); !errors.Is(err, ErrPolicyFetchFailed) || !errors.Is(err, ErrBadSignature) {
+		t.Fatalf("Refresh = %v, want signature rejection", err)
+	}
+	if p.Version() != "v1" {
+		t.Fatalf("version = %q, want last verified bundle kept", p.Version())
+	}
+}
@@ -0,0 +1,98 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// CachingProvider is a stale-while-revalidate cache in front of a provider. A cached
+// policy is served as is for ttl; for staleFor after that it is still served instantly
+// while a single background refresh fetches a new one. Only a key that was never
+// resolved, or whose entry outlived staleFor, waits for the provider.
+//
+// Policies and ErrPolicyNotFound are cached. Other errors are returned uncached; a
+// failed background refresh keeps the stale entry until it outlives staleFor.
+type CachingProvider struct {
+	provider PolicyProvider
+	ttl      time.Duration
+	staleFor time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[policy.PolicyKey]*swrEntry
+}
+
+type swrEntry struct {
+	pol        policy.EffectivePolicy
+	err        error // nil or ErrPolicyNotFound
+	fetched    time.Time
+	refreshing bool
+}
+
+// Cache returns a stale-while-revalidate cache in front of p.
+func Cache(p PolicyProvider, ttl, staleFor time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: p,
+		ttl:      ttl,
+		staleFor: staleFor,
+		now:      time.Now,
+		entries:  make(map[policy.PolicyKey]*swrEntry),
+	}
+}
+
+// GetEffectivePolicy returns the cached policy for key, fetching it on a miss.
+func (c *CachingProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	now := c.now()
+	c.mu.Lock()
+	if e, ok := c.entries[key]; ok {
+		age := now.Sub(e.fetched)
+		if age < c.ttl+c.staleFor {
+			if age >= c.ttl && !e.refreshing {
+				e.refreshing = true
+				go c.refresh(context.WithoutCancel(ctx), key)
+			}
+			pol, err := e.pol, e.err
+			c.mu.Unlock()
+			return pol, err
+		}
+	}
+	c.mu.Unlock()
+
+	return c.fetch(ctx, key)
+}
+
+// Invalidate drops the cached entry for key.
+func (c *CachingProvider) Invalidate(key policy.PolicyKey) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, key)
+}
+
+func (c *CachingProvider) refresh(ctx context.Context, key policy.PolicyKey) {
+	if _, err := c.fetch(ctx, key); err != nil && !errors.Is(err, ErrPolicyNotFound) {
+		c.mu.Lock()
+		if e, ok := c.entries[key]; ok {
+			e.refreshing = false
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *CachingProvider) fetch(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	pol, err := c.provider.GetEffectivePolicy(ctx, key)
+	if err != nil && !errors.Is(err, ErrPolicyNotFound) {
+		return pol, err
+	}
+	if err != nil {
+		pol, err = policy.EffectivePolicy{}, ErrPolicyNotFound
+	}
+
+	c.mu.Lock()
+	c.entries[key] = &swrEntry{pol: pol, err: err, fetched: c.now()}
+	c.mu.Unlock()
+	return pol, err
+}
@@ -0,0 +1,115 @@
+package controlplane
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// gatedProvider blocks each call until the test releases it, unless ungated.
+type gatedProvider struct {
+	mu       sync.Mutex
+	attempts int
+	err      error
+	ungated  bool
+	release  chan struct{}
+	calls    chan struct{}
+}
+
+func (p *gatedProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.Lock()
+	ungated := p.ungated
+	p.mu.Unlock()
+	if !ungated {
+		p.calls <- struct{}{}
+		<-p.release
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.err != nil {
+		return policy.EffectivePolicy{}, p.err
+	}
+	return policy.New(key.String(), policy.MaxAttempts(p.attempts)), nil
+}
+
+func (p *gatedProvider) set(attempts int, err error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.attempts, p.err = attempts, err
+}
+
+func TestCachingProvider(t *testing.T) {
+	ctx := context.Background()
+	key := policy.ParseKey("svc.Get")
+	src := &gatedProvider{attempts: 4, release: make(chan struct{}), calls: make(chan struct{}, 8)}
+
+	var mu sync.Mutex
+	now := time.Unix(1000, 0)
+	c := Cache(src, time.Minute, time.Hour)
+	c.now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return now
+	}
+	advance := func(d time.Duration) {
+		mu.Lock()
+		now = now.Add(d)
+		mu.Unlock()
+	}
+	attempts := func() int {
+		t.Helper()
+		pol, err := c.GetEffectivePolicy(ctx, key)
+		if err != nil {
+			t.Fatalf("GetEffectivePolicy: %v", err)
+		}
+		return pol.Retry.MaxAttempts
+	}
+
+	// Cold miss waits for the provider.
+	go func() { <-src.calls; src.release <- struct{}{} }()
+	if got := attempts(); got != 4 {
+		t.Fatalf("cold = %d, want 4", got)
+	}
+
+	// Stale entries are served instantly while one refresh runs in the background.
+	src.set(2, nil)
+	advance(2 * time.Minute)
+	if got := attempts(); got != 4 {
+		t.Fatalf("stale = %d, want cached 4", got)
+	}
+	<-src.calls
+	if got := attempts(); got != 4 {
+		t.Fatalf("during refresh = %d, want cached 4", got)
+	}
+	select {
+	case <-src.calls:
+		t.Fatal("second refresh started while one was in flight")
+	default:
+	}
+	src.release <- struct{}{}
+	deadline := time.Now().Add(2 * time.Second)
+	for attempts() != 2 {
+		if time.Now().After(deadline) {
+			t.Fatal("refreshed policy not served")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A failed refresh keeps the stale entry; an expired entry surfaces the error.
+	src.set(0, ErrProviderUnavailable)
+	src.mu.Lock()
+	src.ungated = true
+	src.mu.Unlock()
+	advance(2 * time.Minute)
+	if got := attempts(); got != 2 {
+		t.Fatalf("stale after failure = %d, want 2", got)
+	}
+	advance(2 * time.Hour)
+	if _, err := c.GetEffectivePolicy(ctx, key); !errors.Is(err, ErrProviderUnavailable) {
+		t.Fatalf("expired: err = %v, want ErrProviderUnavailable", err)
+	}
+}
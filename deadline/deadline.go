@@ -0,0 +1,56 @@
+// Package deadline propagates a call's remaining time budget across service hops.
+//
+// A client sends the time left on its context with each outgoing request (the http
+// and grpc integrations do this on every attempt); the server applies it to its own
+// request context. A recourse-enabled server then sizes its retries, backoff, and
+// hedges within the caller's budget instead of working on a request the caller has
+// already given up on: the executor stops retrying once the context's deadline passes.
+//
+// The wire format is the remaining time in whole milliseconds, so non-Go services can
+// read and write it.
+package deadline
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalid is returned by Parse for values that are not a non-negative number of
+// milliseconds.
+var ErrInvalid = errors.New("recourse: invalid deadline value")
+
+// Remaining returns the time left before ctx's deadline, and false if ctx has none.
+// An expired deadline returns zero.
+func Remaining(ctx context.Context) (time.Duration, bool) {
+	dl, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return max(time.Until(dl), 0), true
+}
+
+// Format encodes a remaining duration for the wire, rounded down to milliseconds.
+func Format(d time.Duration) string {
+	return strconv.FormatInt(max(d.Milliseconds(), 0), 10)
+}
+
+// Parse decodes a value written by Format.
+func Parse(s string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64)
+	if err != nil || ms < 0 || ms > int64(time.Duration(1<<63-1)/time.Millisecond) {
+		return 0, ErrInvalid
+	}
+	return time.Duration(ms) * time.Millisecond, nil
+}
+
+// Apply returns a context that expires once remaining has elapsed, unless ctx already
+// expires sooner. The cancel func must be called, as with context.WithTimeout.
+func Apply(ctx context.Context, remaining time.Duration) (context.Context, context.CancelFunc) {
+	if d, ok := Remaining(ctx); ok && d <= remaining {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, remaining)
+}
@@ -0,0 +1,60 @@
+package deadline_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/deadline"
+)
+
+func TestRemaining(t *testing.T) {
+	if _, ok := deadline.Remaining(context.Background()); ok {
+		t.Fatal("Remaining reported a deadline for Background")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if d, ok := deadline.Remaining(ctx); !ok || d <= 59*time.Second || d > time.Minute {
+		t.Fatalf("Remaining = %v, %v; want about 1m", d, ok)
+	}
+	expired, cancel2 := context.WithDeadline(context.Background(), time.Now().Add(-time.Second))
+	defer cancel2()
+	if d, ok := deadline.Remaining(expired); !ok || d != 0 {
+		t.Fatalf("Remaining(expired) = %v, %v; want 0, true", d, ok)
+	}
+}
+
+func TestFormatParse(t *testing.T) {
+	if got := deadline.Format(1500*time.Millisecond + 999*time.Microsecond); got != "1500" {
+		t.Fatalf("Format = %q, want 1500", got)
+	}
+	if got := deadline.Format(-time.Second); got != "0" {
+		t.Fatalf("Format(negative) = %q, want 0", got)
+	}
+	if d, err := deadline.Parse(" 250 "); err != nil || d != 250*time.Millisecond {
+		t.Fatalf("Parse = %v, %v; want 250ms", d, err)
+	}
+	for _, bad := range []string{"", "-1", "1.5", "10s", "99999999999999999999"} {
+		if _, err := deadline.Parse(bad); !errors.Is(err, deadline.ErrInvalid) {
+			t.Errorf("Parse(%q) = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx, cancel := deadline.Apply(context.Background(), time.Second)
+	defer cancel()
+	if d, ok := deadline.Remaining(ctx); !ok || d > time.Second {
+		t.Fatalf("Apply to Background: remaining %v, %v", d, ok)
+	}
+
+	// A tighter existing deadline wins.
+	tight, cancel2 := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel2()
+	ctx, cancel3 := deadline.Apply(tight, time.Hour)
+	defer cancel3()
+	if d, _ := deadline.Remaining(ctx); d > 10*time.Millisecond {
+		t.Fatalf("Apply loosened the deadline to %v", d)
+	}
+}
@@ -0,0 +1,131 @@
+package cache
+
+import (
+	"context"
+	"maps"
+	"strconv"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Timeline attributes set when a stale entry is served.
+const (
+	AttrServedStale = "served_stale"
+	AttrStaleAge    = "stale_age"
+)
+
+// Entry is a cached value and the time it was stored.
+type Entry[T any] struct {
+	Value    T
+	StoredAt time.Time
+}
+
+// Store is the pluggable storage behind a Cache.
+//
+// Implementations must be safe for concurrent use. Set receives the total lifetime of the
+// entry (TTL plus stale window); zero means the entry does not expire.
+type Store[T any] interface {
+	Get(ctx context.Context, key string) (Entry[T], bool, error)
+	Set(ctx context.Context, key string, e Entry[T], lifetime time.Duration) error
+}
+
+// Options configures a Cache.
+type Options struct {
+	// TTL is how long an entry is fresh. Fresh entries are returned without calling the
+	// operation. Zero means every call runs the operation.
+	TTL time.Duration
+
+	// StaleWindow is how long after TTL an entry may still be served when the operation
+	// fails. Zero means stale entries are served regardless of age.
+	StaleWindow time.Duration
+
+	// Clock returns the current time. Defaults to time.Now.
+	Clock func() time.Time
+}
+
+// Cache stores successful results and serves them when later calls fail.
+type Cache[T any] struct {
+	store Store[T]
+	opts  Options
+}
+
+// New creates a Cache backed by store.
+func New[T any](store Store[T], opts Options) *Cache[T] {
+	if opts.Clock == nil {
+		opts.Clock = time.Now
+	}
+	return &Cache[T]{store: store, opts: opts}
+}
+
+// DoValue returns the value for cacheKey, calling op under the policy for key when there is
+// no fresh entry.
+//
+// On success the result is stored. When op fails after all attempts and an entry within the
+// stale window exists, that entry's value is returned with a nil error. The error is
+// returned instead when the caller's context has ended. Store errors are treated as misses
+// and never fail the call. If exec is nil, the default executor is used.
+func (c *Cache[T]) DoValue(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, cacheKey string, op retry.OperationValue[T]) (T, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+
+	now := c.opts.Clock()
+	entry, found, err := c.store.Get(ctx, cacheKey)
+	if err != nil {
+		found = false
+	}
+	if found && c.opts.TTL > 0 && now.Sub(entry.StoredAt) < c.opts.TTL {
+		return entry.Value, nil
+	}
+
+	callerCapture, _ := observe.TimelineCaptureFromContext(ctx)
+	callCtx, capture := observe.RecordTimeline(ctx)
+
+	val, err := retry.DoValue(callCtx, exec, key, op)
+	tl := capture.Timeline()
+
+	if err == nil {
+		_ = c.store.Set(ctx, cacheKey, Entry[T]{Value: val, StoredAt: c.opts.Clock()}, c.lifetime())
+		observe.StoreTimelineCapture(callerCapture, tl)
+		return val, nil
+	}
+
+	age := c.opts.Clock().Sub(entry.StoredAt)
+	if !found || ctx.Err() != nil || !c.usable(age) {
+		observe.StoreTimelineCapture(callerCapture, tl)
+		var zero T
+		return zero, err
+	}
+
+	if callerCapture != nil && tl != nil {
+		stale := *tl
+		stale.Attributes = maps.Clone(tl.Attributes)
+		if stale.Attributes == nil {
+			stale.Attributes = make(map[string]string, 2)
+		}
+		stale.Attributes[AttrServedStale] = strconv.FormatBool(true)
+		stale.Attributes[AttrStaleAge] = age.String()
+		observe.StoreTimelineCapture(callerCapture, &stale)
+	}
+	return entry.Value, nil
+}
+
+func (c *Cache[T]) usable(age time.Duration) bool {
+	if c.opts.StaleWindow <= 0 {
+		return true
+	}
+	return age < c.opts.TTL+c.opts.StaleWindow
+}
+
+func (c *Cache[T]) lifetime() time.Duration {
+	if c.opts.StaleWindow <= 0 {
+		return 0
+	}
+	return c.opts.TTL + c.opts.StaleWindow
+}
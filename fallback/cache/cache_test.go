@@ -0,0 +1,167 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+type fakeClock struct{ now time.Time }
+
+func (c *fakeClock) Now() time.Time          { return c.now }
+func (c *fakeClock) Advance(d time.Duration) { c.now = c.now.Add(d) }
+
+func newTestExecutor(key policy.PolicyKey) *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(2), policy.Backoff(time.Microsecond, time.Microsecond, 1)))
+}
+
+func TestCache_FreshHitSkipsCall(t *testing.T) {
+	key := policy.PolicyKey{Name: "profile"}
+	exec := newTestExecutor(key)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := New[string](NewMemoryStore[string](), Options{TTL: time.Minute, Clock: clock.Now})
+
+	calls := 0
+	op := func(context.Context) (string, error) {
+		calls++
+		return "v1", nil
+	}
+
+	for i := 0; i < 2; i++ {
+		v, err := c.DoValue(context.Background(), exec, key, "k", op)
+		if err != nil || v != "v1" {
+			t.Fatalf("call %d: got %q, %v", i, v, err)
+		}
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestCache_ServesStaleOnFailure(t *testing.T) {
+	key := policy.PolicyKey{Name: "profile"}
+	exec := newTestExecutor(key)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := New[string](NewMemoryStore[string](), Options{TTL: time.Second, StaleWindow: time.Hour, Clock: clock.Now})
+
+	if _, err := c.DoValue(context.Background(), exec, key, "k", func(context.Context) (string, error) {
+		return "good", nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	clock.Advance(time.Minute)
+	ctx, capture := observe.RecordTimeline(context.Background())
+	calls := 0
+	v, err := c.DoValue(ctx, exec, key, "k", func(context.Context) (string, error) {
+		calls++
+		return "", errors.New("down")
+	})
+	if err != nil {
+		t.Fatalf("expected stale value, got error %v", err)
+	}
+	if v != "good" {
+		t.Fatalf("expected stale value %q, got %q", "good", v)
+	}
+	if calls != 2 {
+		t.Fatalf("expected the operation to be retried, got %d calls", calls)
+	}
+
+	tl := capture.Timeline()
+	if tl == nil {
+		t.Fatal("expected captured timeline")
+	}
+	if tl.Attributes[AttrServedStale] != "true" {
+		t.Fatalf("expected %s attribute, got %v", AttrServedStale, tl.Attributes)
+	}
+	if tl.Attributes[AttrStaleAge] != time.Minute.String() {
+		t.Fatalf("expected stale age %s, got %q", time.Minute, tl.Attributes[AttrStaleAge])
+	}
+	if len(tl.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts in timeline, got %d", len(tl.Attempts))
+	}
+}
+
+func TestCache_ReturnsErrorOutsideStaleWindow(t *testing.T) {
+	key := policy.PolicyKey{Name: "profile"}
+	exec := newTestExecutor(key)
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	c := New[string](NewMemoryStore[string](), Options{TTL: time.Second, StaleWindow: time.Minute, Clock: clock.Now})
+
+	_, _ = c.DoValue(context.Background(), exec, key, "k", func(context.Context) (string, error) {
+		return "good", nil
+	})
+
+	clock.Advance(2 * time.Minute)
+	_, err := c.DoValue(context.Background(), exec, key, "k", func(context.Context) (string, error) {
+		return "", errors.New("down")
+	})
+	if !errors.Is(err, retry.ErrAttemptsExhausted) {
+		t.Fatalf("expected attempts exhausted error, got %v", err)
+	}
+}
+
+func TestCache_NoEntryReturnsError(t *testing.T) {
+	key := policy.PolicyKey{Name: "profile"}
+	exec := newTestExecutor(key)
+	c := New[string](NewMemoryStore[string](), Options{TTL: time.Second})
+
+	ctx, capture := observe.RecordTimeline(context.Background())
+	_, err := c.DoValue(ctx, exec, key, "missing", func(context.Context) (string, error) {
+		return "", errors.New("down")
+	})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	tl := capture.Timeline()
+	if tl == nil {
+		t.Fatal("expected captured timeline")
+	}
+	if _, ok := tl.Attributes[AttrServedStale]; ok {
+		t.Fatal("did not expect served_stale attribute")
+	}
+}
+
+func TestCache_CanceledCallerGetsError(t *testing.T) {
+	key := policy.PolicyKey{Name: "profile"}
+	exec := newTestExecutor(key)
+	c := New[string](NewMemoryStore[string](), Options{})
+
+	_, _ = c.DoValue(context.Background(), exec, key, "k", func(context.Context) (string, error) {
+		return "good", nil
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	_, err := c.DoValue(ctx, exec, key, "k", func(context.Context) (string, error) {
+		cancel()
+		return "", errors.New("down")
+	})
+	if err == nil {
+		t.Fatal("expected error after caller cancellation")
+	}
+}
+
+func TestMemoryStore_Expiry(t *testing.T) {
+	s := NewMemoryStore[int]()
+	clock := &fakeClock{now: time.Unix(1000, 0)}
+	s.clock = clock.Now
+
+	_ = s.Set(context.Background(), "a", Entry[int]{Value: 1}, time.Second)
+	_ = s.Set(context.Background(), "b", Entry[int]{Value: 2}, 0)
+
+	clock.Advance(2 * time.Second)
+	if _, ok, _ := s.Get(context.Background(), "a"); ok {
+		t.Fatal("expected a to be expired")
+	}
+	if e, ok, _ := s.Get(context.Background(), "b"); !ok || e.Value != 2 {
+		t.Fatalf("expected b to be present, got %v %v", e, ok)
+	}
+	if s.Len() != 1 {
+		t.Fatalf("expected expired entry to be dropped, len=%d", s.Len())
+	}
+}
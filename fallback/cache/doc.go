@@ -0,0 +1,21 @@
+// Package cache serves the last good value when a call fails.
+//
+// A Cache wraps retry.DoValue. Successful results are stored under a caller-supplied
+// cache key. While an entry is younger than TTL it is returned without calling the
+// operation; after that the operation runs, and if it still fails after all attempts the
+// entry is served instead of the error as long as it is inside the stale window.
+//
+// Calls answered from a stale entry return a nil error. If the caller requested a
+// timeline (observe.RecordTimeline), it carries the "served_stale" attribute.
+//
+// Usage:
+//
+//	profiles := cache.New[Profile](cache.NewMemoryStore[Profile](), cache.Options{
+//		TTL:         30 * time.Second,
+//		StaleWindow: 10 * time.Minute,
+//	})
+//
+//	p, err := profiles.DoValue(ctx, exec, key, "profile:"+userID, func(ctx context.Context) (Profile, error) {
+//		return client.GetProfile(ctx, userID)
+//	})
+package cache
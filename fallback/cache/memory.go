@@ -0,0 +1,68 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-process Store.
+//
+// Expired entries are dropped when read. It does not bound the number of keys, so cache keys
+// should be bounded by the caller.
+type MemoryStore[T any] struct {
+	mu      sync.RWMutex
+	entries map[string]memoryEntry[T]
+	clock   func() time.Time
+}
+
+type memoryEntry[T any] struct {
+	entry     Entry[T]
+	expiresAt time.Time // zero means no expiry
+}
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore[T any]() *MemoryStore[T] {
+	return &MemoryStore[T]{
+		entries: make(map[string]memoryEntry[T]),
+		clock:   time.Now,
+	}
+}
+
+// Get returns the entry for key, if present and not expired.
+func (s *MemoryStore[T]) Get(_ context.Context, key string) (Entry[T], bool, error) {
+	s.mu.RLock()
+	e, ok := s.entries[key]
+	s.mu.RUnlock()
+	if !ok {
+		return Entry[T]{}, false, nil
+	}
+	if !e.expiresAt.IsZero() && !s.clock().Before(e.expiresAt) {
+		s.mu.Lock()
+		if cur, ok := s.entries[key]; ok && cur.expiresAt.Equal(e.expiresAt) {
+			delete(s.entries, key)
+		}
+		s.mu.Unlock()
+		return Entry[T]{}, false, nil
+	}
+	return e.entry, true, nil
+}
+
+// Set stores e under key for lifetime (zero means no expiry).
+func (s *MemoryStore[T]) Set(_ context.Context, key string, e Entry[T], lifetime time.Duration) error {
+	me := memoryEntry[T]{entry: e}
+	if lifetime > 0 {
+		me.expiresAt = s.clock().Add(lifetime)
+	}
+	s.mu.Lock()
+	s.entries[key] = me
+	s.mu.Unlock()
+	return nil
+}
+
+// Len returns the number of stored entries, including expired ones not yet read.
+func (s *MemoryStore[T]) Len() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.entries)
+}
@@ -0,0 +1,8 @@
+// Package health lets health sources pause retries to unhealthy targets.
+//
+// A health source (a gRPC health watch, a Kubernetes endpoints watch, a load balancer
+// API) marks policy keys unhealthy in a Registry. An executor configured with
+// retry.WithHealth stops retrying those keys, failing with reason "target_unhealthy",
+// until the source marks them healthy again. First attempts still run, so a call is
+// never rejected on the health source's word alone.
+package health
@@ -0,0 +1,89 @@
+package health
+
+import (
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// ReasonTargetUnhealthy is the outcome reason of calls whose retries were skipped
+// because their target was marked unhealthy.
+const ReasonTargetUnhealthy = "target_unhealthy"
+
+// Status describes a target marked unhealthy.
+type Status struct {
+	Reason string    // Why the source marked the target unhealthy.
+	Since  time.Time // When it was marked.
+}
+
+// Registry holds the targets health sources marked unhealthy. It is safe for
+// concurrent use.
+//
+// A key with an empty Name marks every key in its namespace.
+type Registry struct {
+	mu        sync.RWMutex
+	unhealthy map[policy.PolicyKey]Status
+}
+
+// NewRegistry returns a registry with every target healthy.
+func NewRegistry() *Registry {
+	return &Registry{unhealthy: make(map[policy.PolicyKey]Status)}
+}
+
+// MarkUnhealthy marks key unhealthy. Marking an already unhealthy key keeps its
+// original Since.
+func (r *Registry) MarkUnhealthy(key policy.PolicyKey, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if s, ok := r.unhealthy[key]; ok {
+		s.Reason = reason
+		r.unhealthy[key] = s
+		return
+	}
+	r.unhealthy[key] = Status{Reason: reason, Since: time.Now()}
+}
+
+// MarkHealthy clears key's mark.
+func (r *Registry) MarkHealthy(key policy.PolicyKey) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.unhealthy, key)
+}
+
+// Set marks key healthy or unhealthy, for sources that report a boolean.
+func (r *Registry) Set(key policy.PolicyKey, healthy bool, reason string) {
+	if healthy {
+		r.MarkHealthy(key)
+	} else {
+		r.MarkUnhealthy(key, reason)
+	}
+}
+
+// Unhealthy reports whether key, or its whole namespace, is marked unhealthy.
+func (r *Registry) Unhealthy(key policy.PolicyKey) (Status, bool) {
+	if r == nil {
+		return Status{}, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if len(r.unhealthy) == 0 {
+		return Status{}, false
+	}
+	if s, ok := r.unhealthy[key]; ok {
+		return s, true
+	}
+	s, ok := r.unhealthy[policy.PolicyKey{Namespace: key.Namespace}]
+	return s, ok
+}
+
+// Snapshot returns the keys currently marked unhealthy.
+func (r *Registry) Snapshot() map[policy.PolicyKey]Status {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[policy.PolicyKey]Status, len(r.unhealthy))
+	for k, s := range r.unhealthy {
+		out[k] = s
+	}
+	return out
+}
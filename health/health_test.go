@@ -0,0 +1,47 @@
+package health
+
+import (
+	"testing"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	other := policy.PolicyKey{Namespace: "svc", Name: "Put"}
+
+	if _, unhealthy := r.Unhealthy(key); unhealthy {
+		t.Fatal("new registry reports key unhealthy")
+	}
+
+	r.MarkUnhealthy(key, "not_serving")
+	first, _ := r.Unhealthy(key)
+	r.MarkUnhealthy(key, "draining")
+	st, unhealthy := r.Unhealthy(key)
+	if !unhealthy || st.Reason != "draining" || !st.Since.Equal(first.Since) {
+		t.Fatalf("Unhealthy = %+v, %v; want draining since first mark", st, unhealthy)
+	}
+	if _, unhealthy := r.Unhealthy(other); unhealthy {
+		t.Fatal("mark leaked to another key")
+	}
+
+	r.Set(policy.PolicyKey{Namespace: "svc"}, false, "endpoints_empty")
+	if st, unhealthy := r.Unhealthy(other); !unhealthy || st.Reason != "endpoints_empty" {
+		t.Fatalf("namespace mark not applied: %+v, %v", st, unhealthy)
+	}
+	if len(r.Snapshot()) != 2 {
+		t.Fatalf("Snapshot = %v", r.Snapshot())
+	}
+
+	r.MarkHealthy(key)
+	r.Set(policy.PolicyKey{Namespace: "svc"}, true, "")
+	if _, unhealthy := r.Unhealthy(key); unhealthy {
+		t.Fatal("key still unhealthy after MarkHealthy")
+	}
+
+	var nilRegistry *Registry
+	if _, unhealthy := nilRegistry.Unhealthy(key); unhealthy {
+		t.Fatal("nil registry reports key unhealthy")
+	}
+}
@@ -0,0 +1,144 @@
+package awsv2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddle "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// MiddlewareID is the ID of the recourse retry middleware in the Finalize step.
+const MiddlewareID = "RecourseRetry"
+
+// sdkRetryID is the ID of the SDK's own retry middleware.
+const sdkRetryID = "Retry"
+
+// KeyFunc maps an AWS service ID and operation name to a policy key.
+type KeyFunc func(service, operation string) policy.PolicyKey
+
+// DefaultKeyFunc maps ("S3", "GetObject") -> {Namespace: "S3", Name: "GetObject"}.
+func DefaultKeyFunc(service, operation string) policy.PolicyKey {
+	return policy.PolicyKey{Namespace: service, Name: operation}
+}
+
+// Configure installs recourse on cfg: it disables the SDK's retryer and adds
+// APIOption to cfg.APIOptions. Clients created from cfg afterwards use exec.
+func Configure(cfg *aws.Config, exec *retry.Executor, keyFunc KeyFunc) {
+	cfg.Retryer = func() aws.Retryer { return aws.NopRetryer{} }
+	cfg.APIOptions = append(cfg.APIOptions, APIOption(exec, keyFunc))
+}
+
+// APIOption returns a stack mutation that replaces the SDK's retry middleware with the
+// recourse middleware. Use it directly for per-client or per-operation options.
+// If exec is nil, the default executor is used; if keyFunc is nil, DefaultKeyFunc is used.
+func APIOption(exec *retry.Executor, keyFunc KeyFunc) func(*middleware.Stack) error {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return func(stack *middleware.Stack) error {
+		mw := &retryMiddleware{exec: exec, keyFunc: keyFunc}
+		if _, ok := stack.Finalize.Get(sdkRetryID); ok {
+			_, err := stack.Finalize.Swap(sdkRetryID, mw)
+			return err
+		}
+		// Retries must run before signing so every attempt is signed afresh.
+		if _, ok := stack.Finalize.Get("Signing"); ok {
+			return stack.Finalize.Insert(mw, "Signing", middleware.Before)
+		}
+		return stack.Finalize.Add(mw, middleware.Before)
+	}
+}
+
+type retryMiddleware struct {
+	exec    *retry.Executor
+	keyFunc KeyFunc
+}
+
+func (m *retryMiddleware) ID() string { return MiddlewareID }
+
+type finalizeResult struct {
+	out      middleware.FinalizeOutput
+	metadata middleware.Metadata
+}
+
+func (m *retryMiddleware) HandleFinalize(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (
+	middleware.FinalizeOutput, middleware.Metadata, error,
+) {
+	exec := m.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+	key := m.keyFunc(awsmiddle.GetServiceID(ctx), awsmiddle.GetOperationName(ctx))
+
+	var attempts atomic.Int32
+	res, err := retry.DoValue(ctx, exec, key, func(ctx context.Context) (finalizeResult, error) {
+		attemptIn := in
+		attemptIn.Request = smithyhttp.RequestCloner(in.Request)
+
+		// Every attempt after the first must send the payload from the start.
+		if attempts.Add(1) > 1 {
+			if rewindable, ok := attemptIn.Request.(interface{ RewindStream() error }); ok {
+				if err := rewindable.RewindStream(); err != nil {
+					return finalizeResult{}, fmt.Errorf("awsv2: failed to rewind request stream: %w", err)
+				}
+			}
+		}
+
+		out, metadata, err := next.HandleFinalize(ctx, attemptIn)
+		return finalizeResult{out: out, metadata: metadata}, err
+	})
+	return res.out, res.metadata, err
+}
+
+// Classifier implements classify.Classifier for AWS SDK errors, using the SDK's own
+// retryable and throttle error tables.
+type Classifier struct{}
+
+var (
+	retryables = awsretry.IsErrorRetryables(awsretry.DefaultRetryables)
+	throttles  = awsretry.IsErrorThrottles(awsretry.DefaultThrottles)
+)
+
+func (Classifier) Classify(_ any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "context_deadline_exceeded"}
+	}
+
+	out := classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "aws_error"}
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		out.Attributes = map[string]string{"aws_error_code": apiErr.ErrorCode()}
+	}
+
+	switch {
+	case throttles.IsErrorThrottle(err) == aws.TrueTernary:
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "aws_throttled"
+	case retryables.IsErrorRetryable(err) == aws.TrueTernary:
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "aws_retryable_error"
+	}
+	return out
+}
+
+// WithClassifier returns an option to register the AWS classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
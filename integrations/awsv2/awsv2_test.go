@@ -0,0 +1,169 @@
+package awsv2_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddle "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	awsretry "github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	smithyhttp "github.com/aws/smithy-go/transport/http"
+
+	"github.com/aponysus/recourse/classify"
+	integration "github.com/aponysus/recourse/integrations/awsv2"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// terminal is the innermost handler of the stack; it plays the role of the HTTP client.
+type terminal func(req *smithyhttp.Request) error
+
+func (t terminal) Handle(_ context.Context, in any) (any, middleware.Metadata, error) {
+	return "ok", middleware.Metadata{}, t(in.(*smithyhttp.Request))
+}
+
+func newStack(t *testing.T, exec *retry.Executor) *middleware.Stack {
+	t.Helper()
+	stack := middleware.NewStack("GetObject", smithyhttp.NewStackRequest)
+	if err := stack.Initialize.Add(&awsmiddle.RegisterServiceMetadata{ServiceID: "S3", OperationName: "GetObject"}, middleware.Before); err != nil {
+		t.Fatal(err)
+	}
+	signing := middleware.FinalizeMiddlewareFunc("Signing", func(ctx context.Context, in middleware.FinalizeInput, next middleware.FinalizeHandler) (middleware.FinalizeOutput, middleware.Metadata, error) {
+		return next.HandleFinalize(ctx, in)
+	})
+	if err := stack.Finalize.Add(signing, middleware.After); err != nil {
+		t.Fatal(err)
+	}
+	if err := awsretry.AddRetryMiddlewares(stack, awsretry.AddRetryMiddlewaresOptions{Retryer: awsretry.NewStandard()}); err != nil {
+		t.Fatal(err)
+	}
+	if err := integration.APIOption(exec, nil)(stack); err != nil {
+		t.Fatal(err)
+	}
+	return stack
+}
+
+func newExec() *retry.Executor {
+	return retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicyKey(policy.PolicyKey{Namespace: "S3", Name: "GetObject"},
+			policy.MaxAttempts(3),
+			policy.Backoff(time.Millisecond, time.Millisecond, 1),
+		),
+	)
+}
+
+func responseError(status int) error {
+	return &awshttp.ResponseError{ResponseError: &smithyhttp.ResponseError{
+		Response: &smithyhttp.Response{Response: &http.Response{StatusCode: status, Header: http.Header{}}},
+		Err:      errors.New("status " + http.StatusText(status)),
+	}}
+}
+
+func TestAPIOption_ReplacesSDKRetry(t *testing.T) {
+	stack := newStack(t, newExec())
+	if _, ok := stack.Finalize.Get("Retry"); ok {
+		t.Fatal("SDK retry middleware still installed")
+	}
+	if _, ok := stack.Finalize.Get(integration.MiddlewareID); !ok {
+		t.Fatal("recourse middleware not installed")
+	}
+}
+
+func TestMiddleware_RetriesAndRewindsBody(t *testing.T) {
+	stack := newStack(t, newExec())
+
+	var bodies []string
+	h := middleware.DecorateHandler(terminal(func(req *smithyhttp.Request) error {
+		b, _ := io.ReadAll(req.GetStream())
+		bodies = append(bodies, string(b))
+		if len(bodies) < 3 {
+			return responseError(http.StatusServiceUnavailable)
+		}
+		return nil
+	}), stack)
+
+	// The request body is set on the stack's request by the serialize step in real clients.
+	stack.Serialize.Add(middleware.SerializeMiddlewareFunc("body", func(ctx context.Context, in middleware.SerializeInput, next middleware.SerializeHandler) (middleware.SerializeOutput, middleware.Metadata, error) {
+		req := in.Request.(*smithyhttp.Request)
+		r, err := req.SetStream(strings.NewReader("payload"))
+		if err != nil {
+			return middleware.SerializeOutput{}, middleware.Metadata{}, err
+		}
+		in.Request = r
+		return next.HandleSerialize(ctx, in)
+	}), middleware.After)
+
+	if _, _, err := h.Handle(context.Background(), struct{}{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(bodies) != 3 {
+		t.Fatalf("attempts=%d, want 3", len(bodies))
+	}
+	for i, b := range bodies {
+		if b != "payload" {
+			t.Fatalf("attempt %d body=%q, want payload", i, b)
+		}
+	}
+}
+
+func TestMiddleware_NonRetryableError(t *testing.T) {
+	stack := newStack(t, newExec())
+
+	calls := 0
+	accessDenied := &smithy.GenericAPIError{Code: "AccessDenied", Message: "denied"}
+	h := middleware.DecorateHandler(terminal(func(*smithyhttp.Request) error {
+		calls++
+		return accessDenied
+	}), stack)
+
+	_, _, err := h.Handle(context.Background(), struct{}{})
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) || apiErr.ErrorCode() != "AccessDenied" {
+		t.Fatalf("err=%v, want AccessDenied", err)
+	}
+	if calls != 1 {
+		t.Fatalf("calls=%d, want 1", calls)
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	cfg := aws.Config{}
+	integration.Configure(&cfg, newExec(), nil)
+	if _, ok := cfg.Retryer().(aws.NopRetryer); !ok {
+		t.Fatalf("Retryer=%T, want aws.NopRetryer", cfg.Retryer())
+	}
+	if len(cfg.APIOptions) != 1 {
+		t.Fatalf("APIOptions=%d, want 1", len(cfg.APIOptions))
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	c := integration.Classifier{}
+	tests := []struct {
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{nil, classify.OutcomeSuccess, "success"},
+		{context.Canceled, classify.OutcomeAbort, "context_canceled"},
+		{&smithy.GenericAPIError{Code: "ThrottlingException"}, classify.OutcomeRetryable, "aws_throttled"},
+		{&smithy.GenericAPIError{Code: "RequestTimeout"}, classify.OutcomeRetryable, "aws_retryable_error"},
+		{responseError(http.StatusBadGateway), classify.OutcomeRetryable, "aws_retryable_error"},
+		{&smithy.GenericAPIError{Code: "ValidationException"}, classify.OutcomeNonRetryable, "aws_error"},
+	}
+	for _, tt := range tests {
+		out := c.Classify(nil, tt.err)
+		if out.Kind != tt.kind || out.Reason != tt.reason {
+			t.Errorf("Classify(%v)=%v/%q, want %v/%q", tt.err, out.Kind, out.Reason, tt.kind, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+// Package awsv2 installs recourse as the retry stage of AWS SDK for Go v2 clients.
+//
+// Usage:
+//
+//	cfg, _ := config.LoadDefaultConfig(ctx)
+//	awsv2.Configure(&cfg, exec, nil)
+//	s3Client := s3.NewFromConfig(cfg)
+//
+// Configure replaces the SDK's retry middleware with one that runs each operation through
+// the executor under a service+operation policy key (for example {Namespace: "S3",
+// Name: "GetObject"}), and sets a no-op SDK retryer. Without that, SDK retries and recourse
+// retries would multiply.
+//
+// This package lives in a separate module to keep the AWS SDK dependency opt-in.
+package awsv2
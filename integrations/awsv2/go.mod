@@ -0,0 +1,11 @@
+module github.com/aponysus/recourse/integrations/awsv2
+
+go 1.24.0
+
+replace github.com/aponysus/recourse => ../../
+
+require (
+	github.com/aponysus/recourse v0.0.0-00010101000000-000000000000
+	github.com/aws/aws-sdk-go-v2 v1.47.1
+	github.com/aws/smithy-go v1.28.2
+)
@@ -0,0 +1,155 @@
+package connect
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"connectrpc.com/connect"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Request headers set by Interceptor on every attempt.
+const (
+	// HeaderAttempt carries the 0-based attempt index.
+	HeaderAttempt = "X-Recourse-Attempt"
+	// HeaderHedge carries the hedge index within the attempt group (0 for the primary).
+	HeaderHedge = "X-Recourse-Hedge"
+	// HeaderIdempotencyKey carries the key shared by all attempts of a hedged group, when
+	// the policy sets Hedge.IdempotencyKeys.
+	HeaderIdempotencyKey = "Idempotency-Key"
+)
+
+// pushbackHeader is the gRPC server pushback trailer, surfaced in connect.Error metadata.
+const pushbackHeader = "Grpc-Retry-Pushback-Ms"
+
+// DefaultKeyFunc maps procedures to policy keys.
+// "/acme.foo.v1.FooService/Bar" -> {Namespace: "acme.foo.v1.FooService", Name: "Bar"}
+func DefaultKeyFunc(procedure string) policy.PolicyKey {
+	procedure = strings.TrimPrefix(procedure, "/")
+	parts := strings.Split(procedure, "/")
+	if len(parts) == 2 {
+		return policy.PolicyKey{Namespace: parts[0], Name: parts[1]}
+	}
+	return policy.PolicyKey{Name: procedure}
+}
+
+// Interceptor is a connect.Interceptor that retries unary client calls using an executor.
+// Handler-side calls and streaming calls pass through unchanged.
+type Interceptor struct {
+	exec    *retry.Executor
+	keyFunc func(procedure string) policy.PolicyKey
+}
+
+var _ connect.Interceptor = (*Interceptor)(nil)
+
+// NewInterceptor returns an Interceptor. If exec is nil, the default executor is used;
+// if keyFunc is nil, DefaultKeyFunc is used.
+func NewInterceptor(exec *retry.Executor, keyFunc func(procedure string) policy.PolicyKey) *Interceptor {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return &Interceptor{exec: exec, keyFunc: keyFunc}
+}
+
+// WrapUnary implements connect.Interceptor.
+func (i *Interceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		if !req.Spec().IsClient {
+			return next(ctx, req)
+		}
+		exec := i.exec
+		if exec == nil {
+			exec = retry.DefaultExecutor()
+		}
+		key := i.keyFunc(req.Spec().Procedure)
+		return retry.DoValue(ctx, exec, key, func(ctx context.Context) (connect.AnyResponse, error) {
+			if info, ok := observe.AttemptFromContext(ctx); ok {
+				req.Header().Set(HeaderAttempt, strconv.Itoa(info.Attempt))
+				req.Header().Set(HeaderHedge, strconv.Itoa(info.HedgeIndex))
+				if info.IdempotencyKey != "" {
+					req.Header().Set(HeaderIdempotencyKey, info.IdempotencyKey)
+				}
+			}
+			return next(ctx, req)
+		})
+	}
+}
+
+// WrapStreamingClient implements connect.Interceptor. Streams are not retried.
+func (i *Interceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return next
+}
+
+// WrapStreamingHandler implements connect.Interceptor. Handlers are not affected.
+func (i *Interceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return next
+}
+
+// Classifier implements classify.Classifier for connect.Error codes.
+type Classifier struct{}
+
+func (Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+
+	// Non-connect errors are delegated to the AutoClassifier.
+	var ce *connect.Error
+	if !errors.As(err, &ce) {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+
+	code := ce.Code()
+	outcome := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "connect_" + code.String(),
+		Attributes: map[string]string{"connect_code": code.String()},
+	}
+
+	switch code {
+	case connect.CodeUnavailable, connect.CodeResourceExhausted:
+		outcome.Kind = classify.OutcomeRetryable
+		if d, ok := pushback(ce); ok {
+			if d < 0 {
+				// The server asked clients not to retry.
+				outcome.Kind = classify.OutcomeNonRetryable
+			} else {
+				outcome.BackoffOverride = d
+				outcome.Attributes["retry_after"] = d.String()
+			}
+		}
+	case connect.CodeDeadlineExceeded:
+		outcome.Kind = classify.OutcomeRetryable
+		outcome.Reason = "context_deadline_exceeded"
+	case connect.CodeCanceled:
+		outcome.Kind = classify.OutcomeAbort
+		outcome.Reason = "context_canceled"
+	}
+
+	return outcome
+}
+
+// pushback returns the server's retry pushback. A negative duration means "do not retry".
+func pushback(ce *connect.Error) (time.Duration, bool) {
+	v := ce.Meta().Get(pushbackHeader)
+	if v == "" {
+		return 0, false
+	}
+	ms, err := strconv.Atoi(v)
+	if err != nil || ms < 0 {
+		return -1, true
+	}
+	return time.Duration(ms) * time.Millisecond, true
+}
+
+// WithClassifier returns an option to register the connect classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
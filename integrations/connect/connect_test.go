@@ -0,0 +1,128 @@
+package connect_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/aponysus/recourse/classify"
+	integration "github.com/aponysus/recourse/integrations/connect"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+const procedure = "/test.v1.EchoService/Echo"
+
+func newServer(t *testing.T, handle func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error)) *httptest.Server {
+	t.Helper()
+	mux := http.NewServeMux()
+	mux.Handle(procedure, connect.NewUnaryHandler(procedure, handle))
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func newClient(srv *httptest.Server, exec *retry.Executor) *connect.Client[wrapperspb.StringValue, wrapperspb.StringValue] {
+	return connect.NewClient[wrapperspb.StringValue, wrapperspb.StringValue](
+		srv.Client(), srv.URL+procedure,
+		connect.WithInterceptors(integration.NewInterceptor(exec, nil)),
+	)
+}
+
+func newExec() *retry.Executor {
+	return retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicyKey(integration.DefaultKeyFunc(procedure),
+			policy.MaxAttempts(3),
+			policy.Backoff(time.Millisecond, time.Millisecond, 1),
+		),
+	)
+}
+
+func TestInterceptor_RetriesUnavailable(t *testing.T) {
+	var calls atomic.Int32
+	var lastAttempt atomic.Value
+	srv := newServer(t, func(_ context.Context, req *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+		lastAttempt.Store(req.Header().Get(integration.HeaderAttempt))
+		if calls.Add(1) < 3 {
+			return nil, connect.NewError(connect.CodeUnavailable, errors.New("down"))
+		}
+		return connect.NewResponse(wrapperspb.String("echo: " + req.Msg.GetValue())), nil
+	})
+
+	resp, err := newClient(srv, newExec()).CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hi")))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Msg.GetValue() != "echo: hi" {
+		t.Fatalf("resp=%q", resp.Msg.GetValue())
+	}
+	if calls.Load() != 3 {
+		t.Fatalf("calls=%d, want 3", calls.Load())
+	}
+	if got := lastAttempt.Load(); got != "2" {
+		t.Fatalf("%s=%v, want 2", integration.HeaderAttempt, got)
+	}
+}
+
+func TestInterceptor_NonRetryableCode(t *testing.T) {
+	var calls atomic.Int32
+	srv := newServer(t, func(context.Context, *connect.Request[wrapperspb.StringValue]) (*connect.Response[wrapperspb.StringValue], error) {
+		calls.Add(1)
+		return nil, connect.NewError(connect.CodeInvalidArgument, errors.New("bad"))
+	})
+
+	_, err := newClient(srv, newExec()).CallUnary(context.Background(), connect.NewRequest(wrapperspb.String("hi")))
+	if connect.CodeOf(err) != connect.CodeInvalidArgument {
+		t.Fatalf("err=%v, want InvalidArgument", err)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d, want 1", calls.Load())
+	}
+}
+
+func TestDefaultKeyFunc(t *testing.T) {
+	got := integration.DefaultKeyFunc("/acme.foo.v1.FooService/Bar")
+	want := policy.PolicyKey{Namespace: "acme.foo.v1.FooService", Name: "Bar"}
+	if got != want {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	c := integration.Classifier{}
+
+	pushedBack := connect.NewError(connect.CodeResourceExhausted, errors.New("shed"))
+	pushedBack.Meta().Set("Grpc-Retry-Pushback-Ms", "250")
+	noRetry := connect.NewError(connect.CodeUnavailable, errors.New("shed"))
+	noRetry.Meta().Set("Grpc-Retry-Pushback-Ms", "-1")
+
+	tests := []struct {
+		err     error
+		kind    classify.OutcomeKind
+		reason  string
+		backoff time.Duration
+	}{
+		{nil, classify.OutcomeSuccess, "success", 0},
+		{connect.NewError(connect.CodeUnavailable, nil), classify.OutcomeRetryable, "connect_unavailable", 0},
+		{pushedBack, classify.OutcomeRetryable, "connect_resource_exhausted", 250 * time.Millisecond},
+		{noRetry, classify.OutcomeNonRetryable, "connect_unavailable", 0},
+		{connect.NewError(connect.CodeDeadlineExceeded, nil), classify.OutcomeRetryable, "context_deadline_exceeded", 0},
+		{connect.NewError(connect.CodeCanceled, nil), classify.OutcomeAbort, "context_canceled", 0},
+		{connect.NewError(connect.CodeNotFound, nil), classify.OutcomeNonRetryable, "connect_not_found", 0},
+		{errors.New("plain"), classify.OutcomeRetryable, "retryable_error", 0},
+	}
+	for _, tt := range tests {
+		out := c.Classify(nil, tt.err)
+		if out.Kind != tt.kind || out.Reason != tt.reason || out.BackoffOverride != tt.backoff {
+			t.Errorf("Classify(%v)=%v/%q/%v, want %v/%q/%v", tt.err, out.Kind, out.Reason, out.BackoffOverride, tt.kind, tt.reason, tt.backoff)
+		}
+	}
+}
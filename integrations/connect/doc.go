@@ -0,0 +1,11 @@
+// Package connect provides opt-in connect-go integrations for recourse.
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor(recourseconnect.WithClassifier())
+//	client := foov1connect.NewFooServiceClient(http.DefaultClient, url,
+//		connect.WithInterceptors(recourseconnect.NewInterceptor(exec, nil)),
+//	)
+//
+// This package lives in a separate module to keep the connect-go dependency opt-in.
+package connect
@@ -0,0 +1,12 @@
+module github.com/aponysus/recourse/integrations/connect
+
+go 1.25.0
+
+replace github.com/aponysus/recourse => ../../
+
+require (
+	connectrpc.com/connect v1.21.0
+	github.com/aponysus/recourse v0.0.0-00010101000000-000000000000
+)
+
+require google.golang.org/protobuf v1.36.11
@@ -0,0 +1,159 @@
+package consul
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultPrefix is the key prefix used when Options.Prefix is empty.
+const DefaultPrefix = "recourse/policies/"
+
+// DefaultWaitTime bounds each blocking query when Options.WaitTime is zero.
+const DefaultWaitTime = 5 * time.Minute
+
+const (
+	retryMin = 100 * time.Millisecond
+	retryMax = 30 * time.Second
+)
+
+// KV is the subset of *api.KV used by Provider.
+type KV interface {
+	List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error)
+}
+
+// Options configures a Provider.
+type Options struct {
+	// Prefix under which policies are stored, one key per policy: Prefix + "namespace.name".
+	// Defaults to DefaultPrefix.
+	Prefix string
+	// WaitTime bounds each blocking query. Defaults to DefaultWaitTime.
+	WaitTime time.Duration
+	// OnInvalid, if set, is called for values that do not decode to a valid policy.
+	// Invalid values are skipped and the key keeps its previous policy.
+	OnInvalid func(key policy.PolicyKey, err error)
+}
+
+// Provider serves policies stored as JSON documents (controlplane.DecodePolicy) under a
+// Consul KV prefix, kept current by blocking queries.
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the first listing completes,
+// GetEffectivePolicy returns controlplane.ErrProviderUnavailable; afterwards missing
+// keys return controlplane.ErrPolicyNotFound. While Consul is unreachable the last
+// policies keep being served.
+type Provider struct {
+	kv   KV
+	opts Options
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	loaded   bool
+}
+
+// NewProvider returns a provider reading from kv. Call Run to load and follow policies.
+func NewProvider(kv KV, opts Options) *Provider {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	if opts.WaitTime <= 0 {
+		opts.WaitTime = DefaultWaitTime
+	}
+	return &Provider{kv: kv, opts: opts}
+}
+
+// GetEffectivePolicy returns the current policy for key.
+func (p *Provider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		return policy.EffectivePolicy{}, controlplane.ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Run lists the prefix with blocking queries until ctx is done, applying each change.
+// Failed queries are retried with backoff. It returns ctx.Err().
+func (p *Provider) Run(ctx context.Context) error {
+	var index uint64
+	wait := retryMin
+	for {
+		q := (&api.QueryOptions{WaitIndex: index, WaitTime: p.opts.WaitTime}).WithContext(ctx)
+		pairs, meta, err := p.kv.List(p.opts.Prefix, q)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			t := time.NewTimer(wait)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return ctx.Err()
+			case <-t.C:
+			}
+			wait = min(wait*2, retryMax)
+			continue
+		}
+		wait = retryMin
+
+		// Consul may return the same index on timeout; an index going backwards
+		// (e.g. after a snapshot restore) restarts the blocking sequence.
+		if meta.LastIndex == index && p.isLoaded() {
+			continue
+		}
+		index = meta.LastIndex
+		if index < 1 {
+			index = 1
+		}
+		p.apply(pairs)
+	}
+}
+
+func (p *Provider) isLoaded() bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.loaded
+}
+
+// apply replaces the policy set with pairs.
+func (p *Provider) apply(pairs api.KVPairs) {
+	policies := make(map[policy.PolicyKey]policy.EffectivePolicy, len(pairs))
+	var invalid []policy.PolicyKey
+	for _, pair := range pairs {
+		name := strings.TrimPrefix(pair.Key, p.opts.Prefix)
+		if name == "" || strings.HasSuffix(name, "/") {
+			continue // the prefix itself or a folder
+		}
+		key := policy.ParseKey(name)
+		pol, err := controlplane.DecodePolicy(key, pair.Value)
+		if err != nil {
+			if p.opts.OnInvalid != nil {
+				p.opts.OnInvalid(key, err)
+			}
+			invalid = append(invalid, key)
+			continue
+		}
+		policies[key] = pol
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range invalid {
+		if prev, ok := p.policies[key]; ok {
+			policies[key] = prev
+		}
+	}
+	p.policies = policies
+	p.loaded = true
+}
+
+var _ controlplane.PolicyProvider = (*Provider)(nil)
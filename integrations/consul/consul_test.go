@@ -0,0 +1,136 @@
+package consul_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/consul"
+	"github.com/aponysus/recourse/policy"
+)
+
+// fakeKV answers blocking List queries from an in-memory key set.
+type fakeKV struct {
+	mu    sync.Mutex
+	cond  *sync.Cond
+	index uint64
+	pairs map[string]string
+}
+
+func newFakeKV() *fakeKV {
+	f := &fakeKV{index: 1, pairs: map[string]string{}}
+	f.cond = sync.NewCond(&f.mu)
+	return f
+}
+
+func (f *fakeKV) set(key, value string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if value == "" {
+		delete(f.pairs, key)
+	} else {
+		f.pairs[key] = value
+	}
+	f.index++
+	f.cond.Broadcast()
+}
+
+func (f *fakeKV) List(prefix string, q *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	ctx := q.Context()
+	stop := context.AfterFunc(ctx, func() {
+		f.mu.Lock()
+		f.cond.Broadcast()
+		f.mu.Unlock()
+	})
+	defer stop()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for f.index <= q.WaitIndex && ctx.Err() == nil {
+		f.cond.Wait()
+	}
+	if ctx.Err() != nil {
+		return nil, nil, ctx.Err()
+	}
+	var pairs api.KVPairs
+	for k, v := range f.pairs {
+		pairs = append(pairs, &api.KVPair{Key: k, Value: []byte(v)})
+	}
+	return pairs, &api.QueryMeta{LastIndex: f.index}, nil
+}
+
+func TestProvider(t *testing.T) {
+	get := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	put := policy.PolicyKey{Namespace: "svc", Name: "Put"}
+	kv := newFakeKV()
+	kv.pairs["recourse/policies/"] = "" // folder entry, skipped
+	kv.set("recourse/policies/svc.Get", `{"retry": {"max_attempts": 4}}`)
+
+	var mu sync.Mutex
+	var invalid []policy.PolicyKey
+	p := integration.NewProvider(kv, integration.Options{
+		OnInvalid: func(key policy.PolicyKey, err error) {
+			mu.Lock()
+			invalid = append(invalid, key)
+			mu.Unlock()
+		},
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.GetEffectivePolicy(ctx, get); !errors.Is(err, controlplane.ErrProviderUnavailable) {
+		t.Fatalf("before listing: err = %v, want ErrProviderUnavailable", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- p.Run(ctx) }()
+
+	waitFor := func(key policy.PolicyKey, attempts int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			pol, err := p.GetEffectivePolicy(ctx, key)
+			if attempts == 0 && errors.Is(err, controlplane.ErrPolicyNotFound) {
+				return
+			}
+			if err == nil && pol.Retry.MaxAttempts == attempts && pol.Meta.Source == policy.PolicySourceRemote {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s = %+v, %v; want %d attempts", key, pol, err, attempts)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor(get, 4)
+	kv.set("recourse/policies/svc.Put", `{"retry": {"max_attempts": 2}}`)
+	waitFor(put, 2)
+
+	// An invalid update keeps the previous policy.
+	kv.set("recourse/policies/svc.Put", `{`)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		mu.Lock()
+		n := len(invalid)
+		mu.Unlock()
+		if n > 0 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("OnInvalid not called")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	waitFor(put, 2)
+
+	kv.set("recourse/policies/svc.Get", "")
+	waitFor(get, 0)
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
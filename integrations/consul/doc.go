@@ -0,0 +1,14 @@
+// Package consul provides a recourse policy provider backed by Consul KV.
+//
+// Each policy is a JSON document (the format of a controlplane.Bundle entry) stored
+// under a prefix, keyed by the policy key:
+//
+//	consul kv put recourse/policies/payments.Charge '{"retry": {"max_attempts": 4}}'
+//
+// The provider follows the prefix with blocking queries, so a change takes effect as
+// soon as Consul reports it:
+//
+//	p := consul.NewProvider(client.KV(), consul.Options{})
+//	go p.Run(ctx)
+//	exec := retry.NewDefaultExecutor(retry.WithProvider(p))
+package consul
@@ -0,0 +1,92 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Handler processes a single message.
+type Handler[M any] func(ctx context.Context, msg M) error
+
+// Failure describes a message whose handling failed after all attempts.
+type Failure struct {
+	Key      policy.PolicyKey // Policy key used for the message.
+	Err      error            // Final error (a *retry.CallError).
+	Reason   string           // Outcome reason of the last attempt.
+	Timeline observe.Timeline // Timeline of the attempts.
+}
+
+// DeadLetterSink receives messages that exhausted their attempts.
+type DeadLetterSink[M any] interface {
+	DeadLetter(ctx context.Context, msg M, f Failure) error
+}
+
+// DeadLetterFunc adapts a function to a DeadLetterSink.
+type DeadLetterFunc[M any] func(ctx context.Context, msg M, f Failure) error
+
+func (fn DeadLetterFunc[M]) DeadLetter(ctx context.Context, msg M, f Failure) error {
+	return fn(ctx, msg, f)
+}
+
+// Options configures Wrap.
+type Options[M any] struct {
+	// DeadLetter receives messages that failed all attempts. If nil, the final error is
+	// returned so the broker redelivers the message.
+	DeadLetter DeadLetterSink[M]
+
+	// OnTimeline, if set, is called with the timeline of every message.
+	OnTimeline func(ctx context.Context, msg M, tl observe.Timeline)
+}
+
+// Wrap returns a Handler that runs h under the policy for keyFunc(msg) and routes messages
+// that fail all attempts to opts.DeadLetter.
+//
+// The wrapped handler returns nil when the message was handled or dead-lettered, so the
+// caller can acknowledge it. It returns an error (leave the message for redelivery) when the
+// caller's context ended, when no dead-letter sink is configured, or when the sink fails.
+// If exec is nil, the default executor is used.
+func Wrap[M any](exec *retry.Executor, keyFunc func(M) policy.PolicyKey, h Handler[M], opts Options[M]) Handler[M] {
+	return func(ctx context.Context, msg M) error {
+		e := exec
+		if e == nil {
+			e = retry.DefaultExecutor()
+		}
+		key := keyFunc(msg)
+
+		callCtx, capture := observe.RecordTimeline(ctx)
+		err := e.Do(callCtx, key, func(ctx context.Context) error {
+			return h(ctx, msg)
+		})
+
+		var tl observe.Timeline
+		if t := capture.Timeline(); t != nil {
+			tl = *t
+		}
+		if opts.OnTimeline != nil {
+			opts.OnTimeline(ctx, msg, tl)
+		}
+
+		if err == nil {
+			return nil
+		}
+		// Shutdown or caller cancellation: do not dead-letter, let the broker redeliver.
+		if ctx.Err() != nil || opts.DeadLetter == nil {
+			return err
+		}
+
+		f := Failure{Key: key, Err: err, Timeline: tl}
+		var ce *retry.CallError
+		if errors.As(err, &ce) {
+			f.Reason = ce.LastReason
+		}
+		if dlqErr := opts.DeadLetter.DeadLetter(ctx, msg, f); dlqErr != nil {
+			return fmt.Errorf("consumer: dead-letter failed: %w", errors.Join(dlqErr, err))
+		}
+		return nil
+	}
+}
@@ -0,0 +1,116 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+type message struct{ id string }
+
+var key = policy.PolicyKey{Namespace: "orders", Name: "Process"}
+
+func newExec() *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(3),
+		policy.Backoff(time.Millisecond, time.Millisecond, 1),
+	))
+}
+
+func keyOf(message) policy.PolicyKey { return key }
+
+func TestWrap_RetriesThenSucceeds(t *testing.T) {
+	calls := 0
+	var timelines []observe.Timeline
+	handle := Wrap(newExec(), keyOf, func(context.Context, message) error {
+		calls++
+		if calls < 2 {
+			return errors.New("transient")
+		}
+		return nil
+	}, Options[message]{
+		DeadLetter: DeadLetterFunc[message](func(context.Context, message, Failure) error {
+			t.Fatal("unexpected dead letter")
+			return nil
+		}),
+		OnTimeline: func(_ context.Context, _ message, tl observe.Timeline) {
+			timelines = append(timelines, tl)
+		},
+	})
+
+	if err := handle(context.Background(), message{id: "1"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("calls=%d, want 2", calls)
+	}
+	if len(timelines) != 1 || len(timelines[0].Attempts) != 2 {
+		t.Fatalf("timelines=%+v, want one timeline with 2 attempts", timelines)
+	}
+}
+
+func TestWrap_ExhaustedMessageIsDeadLettered(t *testing.T) {
+	var dead []Failure
+	handle := Wrap(newExec(), keyOf, func(context.Context, message) error {
+		return errors.New("still failing")
+	}, Options[message]{
+		DeadLetter: DeadLetterFunc[message](func(_ context.Context, m message, f Failure) error {
+			if m.id != "1" {
+				t.Fatalf("dead-lettered %q, want 1", m.id)
+			}
+			dead = append(dead, f)
+			return nil
+		}),
+	})
+
+	if err := handle(context.Background(), message{id: "1"}); err != nil {
+		t.Fatalf("err=%v, want nil (message dead-lettered)", err)
+	}
+	if len(dead) != 1 {
+		t.Fatalf("dead letters=%d, want 1", len(dead))
+	}
+	f := dead[0]
+	if f.Key != key || !errors.Is(f.Err, retry.ErrAttemptsExhausted) || len(f.Timeline.Attempts) != 3 {
+		t.Fatalf("failure=%+v", f)
+	}
+	if f.Reason == "" {
+		t.Fatal("expected failure reason")
+	}
+}
+
+func TestWrap_DeadLetterErrorIsReturned(t *testing.T) {
+	sinkErr := errors.New("dlq unavailable")
+	handle := Wrap(newExec(), keyOf, func(context.Context, message) error {
+		return errors.New("still failing")
+	}, Options[message]{
+		DeadLetter: DeadLetterFunc[message](func(context.Context, message, Failure) error {
+			return sinkErr
+		}),
+	})
+
+	if err := handle(context.Background(), message{}); !errors.Is(err, sinkErr) {
+		t.Fatalf("err=%v, want dlq error", err)
+	}
+}
+
+func TestWrap_CanceledContextIsNotDeadLettered(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	handle := Wrap(newExec(), keyOf, func(context.Context, message) error {
+		cancel()
+		return context.Canceled
+	}, Options[message]{
+		DeadLetter: DeadLetterFunc[message](func(context.Context, message, Failure) error {
+			t.Fatal("unexpected dead letter")
+			return nil
+		}),
+	})
+
+	if err := handle(ctx, message{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("err=%v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,17 @@
+// Package consumer provides a retry-and-dead-letter wrapper for message handlers.
+//
+// It is broker-agnostic: wrap the per-message handler of an SQS, Pub/Sub, or Kafka consumer,
+// and acknowledge the message when the wrapped handler returns nil.
+//
+// Usage:
+//
+//	handle := consumer.Wrap(exec, func(m *sqs.Message) policy.PolicyKey {
+//		return recourse.ParseKey("orders.Process")
+//	}, processOrder, consumer.Options[*sqs.Message]{
+//		DeadLetter: consumer.DeadLetterFunc[*sqs.Message](sendToDLQ),
+//	})
+//
+//	if err := handle(ctx, msg); err == nil {
+//		ack(msg)
+//	}
+package consumer
@@ -0,0 +1,120 @@
+package elasticsearch
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Error types that mean the cluster rejected the request before running it. They are
+// retried for every operation, including _bulk.
+var rejectionTypes = []struct{ typ, reason string }{
+	{"circuit_breaking_exception", "es_circuit_breaking"},
+	{"es_rejected_execution_exception", "es_rejected_execution"},
+	{"rejected_execution_exception", "es_rejected_execution"},
+}
+
+// Error types that mean no node could serve the request.
+var unavailableTypes = map[string]struct{}{
+	"no_shard_available_action_exception":      {},
+	"unavailable_shards_exception":             {},
+	"node_not_connected_exception":             {},
+	"node_disconnected_exception":              {},
+	"master_not_discovered_exception":          {},
+	"cluster_manager_not_discovered_exception": {},
+	"connect_transport_exception":              {},
+	"receive_timeout_transport_exception":      {},
+	"node_closed_exception":                    {},
+}
+
+// Classifier implements classify.Classifier for Elasticsearch/OpenSearch errors.
+//
+// Rejections (429, circuit_breaking_exception, es_rejected_execution_exception) are
+// retryable for every request. Node-unavailable failures (transport errors, 502/503/504,
+// and unavailable-shard or disconnected-node error types) are retryable only for
+// idempotent requests. Other errors are terminal. Errors not produced by Transport are
+// delegated to the AutoClassifier.
+type Classifier struct{}
+
+func (Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+
+	var e *Error
+	if !errors.As(err, &e) || isContextErr(e.Err) {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+
+	outcome := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "es_error",
+		Attributes: map[string]string{"status": strconv.Itoa(e.StatusCode), "method": e.Method},
+	}
+	if e.Type != "" {
+		outcome.Attributes["es_error_type"] = e.Type
+	}
+
+	for _, r := range rejectionTypes {
+		if e.HasType(r.typ) {
+			outcome.Kind = classify.OutcomeRetryable
+			outcome.Reason = r.reason
+			applyRetryAfter(&outcome, e)
+			return outcome
+		}
+	}
+	if e.StatusCode == http.StatusTooManyRequests {
+		outcome.Kind = classify.OutcomeRetryable
+		outcome.Reason = "es_too_many_requests"
+		applyRetryAfter(&outcome, e)
+		return outcome
+	}
+
+	if !nodeUnavailable(e) {
+		return outcome
+	}
+	if !e.Idempotent {
+		outcome.Reason = "es_non_idempotent"
+		return outcome
+	}
+	outcome.Kind = classify.OutcomeRetryable
+	outcome.Reason = "es_node_unavailable"
+	applyRetryAfter(&outcome, e)
+	return outcome
+}
+
+func nodeUnavailable(e *Error) bool {
+	switch e.StatusCode {
+	case 0, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	if _, ok := unavailableTypes[e.Type]; ok {
+		return true
+	}
+	for _, rc := range e.RootCauses {
+		if _, ok := unavailableTypes[rc]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func isContextErr(err error) bool {
+	return err != nil && (errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded))
+}
+
+func applyRetryAfter(out *classify.Outcome, e *Error) {
+	if d, ok := e.RetryAfter(); ok && d > 0 {
+		out.BackoffOverride = d
+		out.Attributes["retry_after"] = d.String()
+	}
+}
+
+// WithClassifier returns an option to register the Elasticsearch classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
@@ -0,0 +1,19 @@
+// Package elasticsearch provides a retrying transport for the official Elasticsearch and
+// OpenSearch Go clients.
+//
+// Transport is an http.RoundTripper; pass it as the client's Transport and disable the
+// client's own retries so attempts are not multiplied. Requests are keyed per index and
+// operation (see DefaultKeyFunc), and Classifier recognizes 429 rejections,
+// circuit_breaking_exception, and node-unavailable failures from the error body.
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor(recoursees.WithClassifier())
+//	es, err := elasticsearch.NewClient(elasticsearch.Config{
+//		Addresses:    addrs,
+//		Transport:    recoursees.NewTransport(exec, recoursees.Options{}),
+//		DisableRetry: true,
+//	})
+//
+// The package has no dependency on either client; it works at the HTTP layer.
+package elasticsearch
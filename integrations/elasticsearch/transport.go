@@ -0,0 +1,276 @@
+package elasticsearch
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/integrations/httpclient"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultMaxBufferedBody is the default limit for buffering request bodies that have no GetBody.
+const DefaultMaxBufferedBody = 16 << 20
+
+// maxErrorBody bounds how much of a failed response body is read to find the error type.
+const maxErrorBody = 64 << 10
+
+// KeyFunc maps an outgoing request to a policy key.
+type KeyFunc func(*http.Request) policy.PolicyKey
+
+// Options configures a Transport.
+type Options struct {
+	// Base is the underlying transport. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Key maps requests to policy keys. Defaults to DefaultKeyFunc.
+	Key KeyFunc
+
+	// MaxBufferedBody bounds how many bytes of a non-replayable request body are buffered
+	// so it can be resent. Larger bodies are sent once without retries.
+	// Defaults to DefaultMaxBufferedBody; negative disables buffering.
+	MaxBufferedBody int64
+}
+
+// Transport is an http.RoundTripper that executes Elasticsearch/OpenSearch requests with a
+// recourse executor. It is an httpclient.Transport that sends every request through the
+// executor, keyed per index and operation, and reports failed attempts as *Error values so
+// Classifier can decide which are safe to retry.
+//
+// When the call gives up on an HTTP failure, the last response is returned with a nil error
+// so the client can decode it as usual.
+type Transport struct {
+	http *httpclient.Transport
+}
+
+// NewTransport returns a Transport that runs requests through exec.
+// If exec is nil, the global default executor is used.
+func NewTransport(exec *retry.Executor, opts Options) *Transport {
+	key := opts.Key
+	if key == nil {
+		key = DefaultKeyFunc
+	}
+	maxBuffered := opts.MaxBufferedBody
+	if maxBuffered == 0 {
+		maxBuffered = DefaultMaxBufferedBody
+	}
+	return &Transport{http: httpclient.NewTransport(exec, httpclient.Options{
+		Base: opts.Base,
+		Key: func(req *http.Request) (policy.PolicyKey, bool) {
+			return key(req), true
+		},
+		// Classifier, not the transport, decides which operations may be retried.
+		RetryNonIdempotent: true,
+		MaxBufferedBody:    maxBuffered,
+		AttemptError:       attemptError,
+	})}
+}
+
+// namespacedAPIs are cluster-level APIs whose second path segment names the operation
+// ("/_cluster/health", "/_cat/indices").
+var namespacedAPIs = map[string]struct{}{
+	"cluster": {},
+	"cat":     {},
+	"nodes":   {},
+	"ingest":  {},
+	"tasks":   {},
+}
+
+// DefaultKeyFunc keys requests by index and operation:
+//
+//	GET  /orders/_search     -> {Namespace: "orders", Name: "search"}
+//	PUT  /orders/_doc/42     -> {Namespace: "orders", Name: "doc.put"}
+//	POST /_bulk              -> {Namespace: "_all", Name: "bulk"}
+//	GET  /_cluster/health    -> {Namespace: "_all", Name: "cluster.health"}
+//	PUT  /orders             -> {Namespace: "orders", Name: "put"}
+//
+// Document IDs are never part of the key. Time-based index names (logs-2024.01.01) are,
+// so use a custom KeyFunc to strip the date when keys would be unbounded.
+func DefaultKeyFunc(req *http.Request) policy.PolicyKey {
+	method := strings.ToLower(req.Method)
+	if method == "" {
+		method = "get"
+	}
+	segments := strings.Split(strings.Trim(req.URL.Path, "/"), "/")
+
+	var index []string
+	for i, seg := range segments {
+		if !strings.HasPrefix(seg, "_") {
+			index = append(index, seg)
+			continue
+		}
+		op := strings.TrimPrefix(seg, "_")
+		if _, ok := namespacedAPIs[op]; ok && i == 0 && len(segments) > 1 && !strings.HasPrefix(segments[1], "_") {
+			op += "." + segments[1]
+		}
+		if op == "doc" || op == "source" {
+			op += "." + method
+		}
+		return policy.PolicyKey{Namespace: indexName(index), Name: op}
+	}
+	return policy.PolicyKey{Namespace: indexName(index), Name: method}
+}
+
+func indexName(segments []string) string {
+	if len(segments) == 0 || segments[0] == "" {
+		return "_all"
+	}
+	return segments[0]
+}
+
+// readOperations are operations sent with POST that do not modify data.
+var readOperations = map[string]struct{}{
+	"search":           {},
+	"msearch":          {},
+	"search_template":  {},
+	"msearch_template": {},
+	"count":            {},
+	"mget":             {},
+	"field_caps":       {},
+	"explain":          {},
+	"validate":         {},
+	"termvectors":      {},
+	"mtermvectors":     {},
+	"rank_eval":        {},
+	"refresh":          {},
+	"flush":            {},
+}
+
+// Idempotent reports whether req may be resent after a failure that could have reached the
+// cluster. GET, HEAD, PUT, and DELETE are idempotent, as are read operations sent with POST
+// (_search, _count, _mget, ...). _bulk, _update, and POST /_doc are not.
+func Idempotent(req *http.Request) bool {
+	switch strings.ToUpper(req.Method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions:
+		return true
+	case http.MethodPost:
+		for _, seg := range strings.Split(strings.Trim(req.URL.Path, "/"), "/") {
+			if !strings.HasPrefix(seg, "_") {
+				continue
+			}
+			_, ok := readOperations[strings.TrimPrefix(seg, "_")]
+			return ok
+		}
+	}
+	return false
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	return t.http.RoundTrip(req)
+}
+
+// attemptError reports a failed attempt as an *Error.
+func attemptError(req *http.Request, resp *http.Response, err error) error {
+	if resp == nil {
+		return &Error{Err: err, Method: req.Method, Idempotent: Idempotent(req)}
+	}
+	return newError(resp, req.Method, Idempotent(req))
+}
+
+// Error describes a failed Elasticsearch/OpenSearch request. It implements classify.HTTPError,
+// so HTTP classifiers can also handle it.
+type Error struct {
+	StatusCode int         // HTTP status; 0 for transport errors.
+	Type       string      // error.type from the response body, if any.
+	Reason     string      // error.reason from the response body, if any.
+	RootCauses []string    // error.root_cause[].type from the response body.
+	Method     string      // Request method.
+	Idempotent bool        // Whether the request may be resent (see Idempotent).
+	Header     http.Header // Response headers.
+	Err        error       // Transport error, if any.
+}
+
+func (e *Error) Error() string {
+	if e.Err != nil {
+		return "elasticsearch: " + e.Err.Error()
+	}
+	msg := "elasticsearch: status " + strconv.Itoa(e.StatusCode)
+	if e.Type != "" {
+		msg += ": " + e.Type
+	}
+	if e.Reason != "" {
+		msg += ": " + e.Reason
+	}
+	return msg
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// HasType reports whether the error or any of its root causes has type typ.
+func (e *Error) HasType(typ string) bool {
+	if e.Type == typ {
+		return true
+	}
+	for _, rc := range e.RootCauses {
+		if rc == typ {
+			return true
+		}
+	}
+	return false
+}
+
+func (e *Error) HTTPStatusCode() int { return e.StatusCode }
+
+// HTTPMethod reports the method to HTTP classifiers. Idempotent POST operations (_search)
+// are reported as PUT so those classifiers allow retries.
+func (e *Error) HTTPMethod() string {
+	if e.Idempotent && e.Method == http.MethodPost {
+		return http.MethodPut
+	}
+	return e.Method
+}
+
+// RetryAfter returns the server-provided retry delay from the response headers.
+func (e *Error) RetryAfter() (time.Duration, bool) {
+	return recoursehttp.ParseRetryAfter(e.Header, time.Now())
+}
+
+type errorBody struct {
+	Error json.RawMessage `json:"error"`
+}
+
+type errorDetail struct {
+	Type      string `json:"type"`
+	Reason    string `json:"reason"`
+	RootCause []struct {
+		Type string `json:"type"`
+	} `json:"root_cause"`
+}
+
+// newError reads the head of resp's body to find the error type, then restores the body so
+// it can be returned to the caller intact.
+func newError(resp *http.Response, method string, idempotent bool) *Error {
+	e := &Error{StatusCode: resp.StatusCode, Method: method, Idempotent: idempotent, Header: resp.Header}
+
+	head, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(head), resp.Body), resp.Body}
+
+	var body errorBody
+	if json.Unmarshal(head, &body) != nil || len(body.Error) == 0 {
+		return e
+	}
+	var detail errorDetail
+	if json.Unmarshal(body.Error, &detail) != nil {
+		// Some endpoints return "error" as a plain string.
+		_ = json.Unmarshal(body.Error, &e.Reason)
+		return e
+	}
+	e.Type = detail.Type
+	e.Reason = detail.Reason
+	for _, rc := range detail.RootCause {
+		if rc.Type != "" {
+			e.RootCauses = append(e.RootCauses, rc.Type)
+		}
+	}
+	return e
+}
@@ -0,0 +1,189 @@
+package elasticsearch
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+const circuitBreaking = `{"error":{"root_cause":[{"type":"circuit_breaking_exception","reason":"[parent] Data too large"}],"type":"circuit_breaking_exception","reason":"[parent] Data too large"},"status":429}`
+
+type reply struct {
+	status int
+	body   string
+}
+
+func newServer(t *testing.T, replies ...reply) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		n := int(calls.Add(1)) - 1
+		if n >= len(replies) {
+			n = len(replies) - 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(replies[n].status)
+		_, _ = io.WriteString(w, replies[n].body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func newExecutor(keys ...policy.PolicyKey) *retry.Executor {
+	opts := []retry.ExecutorOption{WithClassifier()}
+	for _, key := range keys {
+		opts = append(opts, retry.WithPolicyKey(key, policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Millisecond, 1)))
+	}
+	return retry.NewDefaultExecutor(opts...)
+}
+
+func TestDefaultKeyFunc(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         policy.PolicyKey
+	}{
+		{"GET", "/orders/_search", policy.PolicyKey{Namespace: "orders", Name: "search"}},
+		{"PUT", "/orders/_doc/42", policy.PolicyKey{Namespace: "orders", Name: "doc.put"}},
+		{"GET", "/orders/_doc/42", policy.PolicyKey{Namespace: "orders", Name: "doc.get"}},
+		{"POST", "/_bulk", policy.PolicyKey{Namespace: "_all", Name: "bulk"}},
+		{"POST", "/orders/_update/42", policy.PolicyKey{Namespace: "orders", Name: "update"}},
+		{"GET", "/_cluster/health", policy.PolicyKey{Namespace: "_all", Name: "cluster.health"}},
+		{"GET", "/_cat/indices", policy.PolicyKey{Namespace: "_all", Name: "cat.indices"}},
+		{"PUT", "/orders", policy.PolicyKey{Namespace: "orders", Name: "put"}},
+		{"HEAD", "/", policy.PolicyKey{Namespace: "_all", Name: "head"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := DefaultKeyFunc(req); got != tt.want {
+			t.Errorf("%s %s: got %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestIdempotent(t *testing.T) {
+	tests := []struct {
+		method, path string
+		want         bool
+	}{
+		{"POST", "/orders/_search", true},
+		{"POST", "/_mget", true},
+		{"POST", "/_bulk", false},
+		{"POST", "/orders/_doc", false},
+		{"POST", "/orders/_update/1", false},
+		{"PUT", "/orders/_doc/1", true},
+		{"DELETE", "/orders/_doc/1", true},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if got := Idempotent(req); got != tt.want {
+			t.Errorf("%s %s: got %v, want %v", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestTransport_RetriesCircuitBreakingOnBulk(t *testing.T) {
+	srv, calls := newServer(t, reply{429, circuitBreaking}, reply{200, `{"errors":false}`})
+	client := &http.Client{Transport: NewTransport(newExecutor(policy.PolicyKey{Namespace: "_all", Name: "bulk"}), Options{})}
+
+	resp, err := client.Post(srv.URL+"/_bulk", "application/x-ndjson", strings.NewReader("{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 || calls.Load() != 2 {
+		t.Fatalf("expected success after 2 calls, got status %d after %d", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_NoRetryOn503ForBulk(t *testing.T) {
+	srv, calls := newServer(t, reply{503, `{"error":"unavailable"}`}, reply{200, `{}`})
+	client := &http.Client{Transport: NewTransport(newExecutor(policy.PolicyKey{Namespace: "_all", Name: "bulk"}), Options{})}
+
+	resp, err := client.Post(srv.URL+"/_bulk", "application/x-ndjson", strings.NewReader("{}\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 503 || calls.Load() != 1 {
+		t.Fatalf("expected a single 503, got status %d after %d calls", resp.StatusCode, calls.Load())
+	}
+}
+
+func TestTransport_ReturnsLastResponseWithBody(t *testing.T) {
+	srv, calls := newServer(t, reply{503, `{"error":{"type":"no_shard_available_action_exception","reason":"no shards"},"status":503}`})
+	client := &http.Client{Transport: NewTransport(newExecutor(policy.PolicyKey{Namespace: "orders", Name: "search"}), Options{})}
+
+	resp, err := client.Post(srv.URL+"/orders/_search", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if calls.Load() != 3 {
+		t.Fatalf("expected 3 calls, got %d", calls.Load())
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "no_shard_available_action_exception") {
+		t.Fatalf("expected error body to be preserved, got %q", body)
+	}
+}
+
+func TestTransport_SetsAttemptHeaders(t *testing.T) {
+	var attempts []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts = append(attempts, r.Header.Get(recoursehttp.HeaderAttempt))
+		if len(attempts) == 1 {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = io.WriteString(w, circuitBreaking)
+			return
+		}
+		_, _ = io.WriteString(w, `{}`)
+	}))
+	t.Cleanup(srv.Close)
+	client := &http.Client{Transport: NewTransport(newExecutor(policy.PolicyKey{Namespace: "orders", Name: "search"}), Options{})}
+
+	resp, err := client.Post(srv.URL+"/orders/_search", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if strings.Join(attempts, ",") != "0,1" {
+		t.Fatalf("attempt headers = %q, want 0 then 1", attempts)
+	}
+}
+
+func TestClassifier_Outcomes(t *testing.T) {
+	tests := []struct {
+		name   string
+		err    *Error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{"429", &Error{StatusCode: 429, Method: "POST"}, classify.OutcomeRetryable, "es_too_many_requests"},
+		{"circuit breaking root cause", &Error{StatusCode: 503, Type: "search_phase_execution_exception", RootCauses: []string{"circuit_breaking_exception"}}, classify.OutcomeRetryable, "es_circuit_breaking"},
+		{"rejected execution", &Error{StatusCode: 429, Type: "es_rejected_execution_exception"}, classify.OutcomeRetryable, "es_rejected_execution"},
+		{"node unavailable idempotent", &Error{StatusCode: 503, Idempotent: true}, classify.OutcomeRetryable, "es_node_unavailable"},
+		{"node unavailable write", &Error{StatusCode: 503}, classify.OutcomeNonRetryable, "es_non_idempotent"},
+		{"transport error", &Error{Err: errors.New("connection refused"), Idempotent: true}, classify.OutcomeRetryable, "es_node_unavailable"},
+		{"no shards", &Error{StatusCode: 500, Type: "no_shard_available_action_exception", Idempotent: true}, classify.OutcomeRetryable, "es_node_unavailable"},
+		{"bad request", &Error{StatusCode: 400, Type: "parsing_exception", Idempotent: true}, classify.OutcomeNonRetryable, "es_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Classifier{}.Classify(nil, tt.err)
+			if out.Kind != tt.kind || out.Reason != tt.reason {
+				t.Fatalf("got %v/%s, want %v/%s", out.Kind, out.Reason, tt.kind, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,14 @@
+// Package etcd provides a recourse policy provider backed by etcd.
+//
+// Each policy is a JSON document (the format of a controlplane.Bundle entry) stored
+// under a prefix, keyed by the policy key:
+//
+//	etcdctl put recourse/policies/payments.Charge '{"retry": {"max_attempts": 4}}'
+//
+// The provider lists the prefix once and then applies watch events, so a put takes
+// effect on the next call:
+//
+//	p := etcd.NewProvider(client, etcd.Options{})
+//	go p.Run(ctx)
+//	exec := retry.NewDefaultExecutor(retry.WithProvider(p))
+package etcd
@@ -0,0 +1,174 @@
+package etcd
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultPrefix is the key prefix used when Options.Prefix is empty.
+const DefaultPrefix = "recourse/policies/"
+
+const (
+	rewatchMin = 100 * time.Millisecond
+	rewatchMax = 30 * time.Second
+)
+
+// Client is the subset of *clientv3.Client used by Provider.
+type Client interface {
+	clientv3.KV
+	clientv3.Watcher
+}
+
+// Options configures a Provider.
+type Options struct {
+	// Prefix under which policies are stored, one key per policy: Prefix + "namespace.name".
+	// Defaults to DefaultPrefix.
+	Prefix string
+	// OnInvalid, if set, is called for values that do not decode to a valid policy.
+	// Invalid values are skipped and the key keeps its previous policy.
+	OnInvalid func(key policy.PolicyKey, err error)
+}
+
+// Provider serves policies stored as JSON documents (controlplane.DecodePolicy) under an
+// etcd prefix, kept current by a watch.
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the first listing completes,
+// GetEffectivePolicy returns controlplane.ErrProviderUnavailable; afterwards missing
+// keys return controlplane.ErrPolicyNotFound. While etcd is unreachable the last
+// policies keep being served.
+type Provider struct {
+	client Client
+	opts   Options
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	loaded   bool
+}
+
+// NewProvider returns a provider reading from c. Call Run to load and watch policies.
+func NewProvider(c Client, opts Options) *Provider {
+	if opts.Prefix == "" {
+		opts.Prefix = DefaultPrefix
+	}
+	return &Provider{client: c, opts: opts}
+}
+
+// GetEffectivePolicy returns the current policy for key.
+func (p *Provider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		return policy.EffectivePolicy{}, controlplane.ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Run lists the prefix and then watches it until ctx is done. When the watch fails
+// (including compaction past the watched revision) it lists again, with backoff.
+// It returns ctx.Err().
+func (p *Provider) Run(ctx context.Context) error {
+	wait := rewatchMin
+	for {
+		if rev, err := p.list(ctx); err == nil {
+			wait = rewatchMin
+			p.watch(ctx, rev)
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		wait = min(wait*2, rewatchMax)
+	}
+}
+
+// list replaces the policy set with the prefix's contents and returns the revision.
+func (p *Provider) list(ctx context.Context) (int64, error) {
+	resp, err := p.client.Get(ctx, p.opts.Prefix, clientv3.WithPrefix())
+	if err != nil {
+		return 0, err
+	}
+
+	policies := make(map[policy.PolicyKey]policy.EffectivePolicy, len(resp.Kvs))
+	var invalid []policy.PolicyKey
+	for _, kv := range resp.Kvs {
+		key := p.policyKey(kv.Key)
+		if pol, ok := p.decode(key, kv.Value); ok {
+			policies[key] = pol
+		} else {
+			invalid = append(invalid, key)
+		}
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range invalid {
+		if prev, ok := p.policies[key]; ok {
+			policies[key] = prev
+		}
+	}
+	p.policies = policies
+	p.loaded = true
+	return resp.Header.Revision, nil
+}
+
+// watch applies changes after rev until the watch ends.
+func (p *Provider) watch(ctx context.Context, rev int64) {
+	ctx, cancel := context.WithCancel(clientv3.WithRequireLeader(ctx))
+	defer cancel()
+
+	for resp := range p.client.Watch(ctx, p.opts.Prefix, clientv3.WithPrefix(), clientv3.WithRev(rev+1)) {
+		if resp.Err() != nil {
+			return
+		}
+		for _, ev := range resp.Events {
+			key := p.policyKey(ev.Kv.Key)
+			switch ev.Type {
+			case clientv3.EventTypeDelete:
+				p.mu.Lock()
+				delete(p.policies, key)
+				p.mu.Unlock()
+			case clientv3.EventTypePut:
+				if pol, ok := p.decode(key, ev.Kv.Value); ok {
+					p.mu.Lock()
+					p.policies[key] = pol
+					p.mu.Unlock()
+				}
+			}
+		}
+	}
+}
+
+func (p *Provider) policyKey(k []byte) policy.PolicyKey {
+	return policy.ParseKey(strings.TrimPrefix(string(k), p.opts.Prefix))
+}
+
+func (p *Provider) decode(key policy.PolicyKey, value []byte) (policy.EffectivePolicy, bool) {
+	pol, err := controlplane.DecodePolicy(key, value)
+	if err != nil {
+		if p.opts.OnInvalid != nil {
+			p.opts.OnInvalid(key, err)
+		}
+		return policy.EffectivePolicy{}, false
+	}
+	return pol, true
+}
+
+var _ controlplane.PolicyProvider = (*Provider)(nil)
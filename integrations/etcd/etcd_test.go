@@ -0,0 +1,120 @@
+package etcd_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	pb "go.etcd.io/etcd/api/v3/etcdserverpb"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/etcd"
+	"github.com/aponysus/recourse/policy"
+)
+
+// fakeEtcd serves Get from a fixed listing and Watch from a channel fed by the test.
+type fakeEtcd struct {
+	clientv3.KV
+	clientv3.Watcher
+
+	kvs    []*mvccpb.KeyValue
+	events chan clientv3.WatchResponse
+
+	mu       sync.Mutex
+	watchRev int64
+}
+
+func (f *fakeEtcd) Get(ctx context.Context, key string, opts ...clientv3.OpOption) (*clientv3.GetResponse, error) {
+	return &clientv3.GetResponse{Header: &pb.ResponseHeader{Revision: 7}, Kvs: f.kvs}, nil
+}
+
+func (f *fakeEtcd) Watch(ctx context.Context, key string, opts ...clientv3.OpOption) clientv3.WatchChan {
+	op := clientv3.OpGet(key, opts...)
+	f.mu.Lock()
+	f.watchRev = op.Rev()
+	f.mu.Unlock()
+
+	ch := make(chan clientv3.WatchResponse)
+	go func() {
+		defer close(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case resp := <-f.events:
+				ch <- resp
+			}
+		}
+	}()
+	return ch
+}
+
+func event(typ mvccpb.Event_EventType, key, value string) clientv3.WatchResponse {
+	return clientv3.WatchResponse{Events: []*clientv3.Event{{Type: typ, Kv: &mvccpb.KeyValue{Key: []byte(key), Value: []byte(value)}}}}
+}
+
+func TestProvider(t *testing.T) {
+	get := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	put := policy.PolicyKey{Namespace: "svc", Name: "Put"}
+	fake := &fakeEtcd{
+		kvs: []*mvccpb.KeyValue{
+			{Key: []byte("recourse/policies/svc.Get"), Value: []byte(`{"retry": {"max_attempts": 4}}`)},
+			{Key: []byte("recourse/policies/svc.Bad"), Value: []byte(`{`)},
+		},
+		events: make(chan clientv3.WatchResponse),
+	}
+	var invalid []policy.PolicyKey
+	p := integration.NewProvider(fake, integration.Options{
+		OnInvalid: func(key policy.PolicyKey, err error) { invalid = append(invalid, key) },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.GetEffectivePolicy(ctx, get); !errors.Is(err, controlplane.ErrProviderUnavailable) {
+		t.Fatalf("before listing: err = %v, want ErrProviderUnavailable", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- p.Run(ctx) }()
+
+	fake.events <- event(mvccpb.PUT, "recourse/policies/svc.Put", `{"retry": {"max_attempts": 2}}`)
+	pol, err := p.GetEffectivePolicy(ctx, get)
+	if err != nil || pol.Retry.MaxAttempts != 4 || pol.Meta.Source != policy.PolicySourceRemote {
+		t.Fatalf("listed policy = %+v, %v", pol, err)
+	}
+	fake.mu.Lock()
+	if fake.watchRev != 8 {
+		t.Errorf("watch started at revision %d, want 8", fake.watchRev)
+	}
+	fake.mu.Unlock()
+	if len(invalid) != 1 || invalid[0] != (policy.PolicyKey{Namespace: "svc", Name: "Bad"}) {
+		t.Errorf("OnInvalid keys = %v, want [svc.Bad]", invalid)
+	}
+
+	fake.events <- event(mvccpb.DELETE, "recourse/policies/svc.Get", "")
+	fake.events <- clientv3.WatchResponse{} // wait for the delete to be applied
+
+	waitFor := func(key policy.PolicyKey, attempts int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			pol, err := p.GetEffectivePolicy(ctx, key)
+			if (attempts == 0 && errors.Is(err, controlplane.ErrPolicyNotFound)) || (err == nil && pol.Retry.MaxAttempts == attempts) {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s = %+v, %v; want %d attempts", key, pol, err, attempts)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+	waitFor(put, 2)
+	waitFor(get, 0)
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
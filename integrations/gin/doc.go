@@ -0,0 +1,12 @@
+// Package gin provides opt-in gin integrations for recourse.
+//
+// Usage:
+//
+//	r := gin.New()
+//	r.Use(recoursegin.Middleware(recoursehttp.ServerOptions{
+//		Provider:      provider,
+//		MaxConcurrent: 100,
+//	}))
+//
+// This package lives in a separate module to keep the gin dependency opt-in.
+package gin
@@ -0,0 +1,58 @@
+package gin
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aponysus/recourse/deadline"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultKeyFunc maps requests to policy keys by their gin route:
+// GET "/users/:id" -> {Namespace: "http", Name: "GET /users/:id"}. Unmatched requests
+// share {Namespace: "http", Name: "*"}.
+func DefaultKeyFunc(c *gin.Context) policy.PolicyKey {
+	if path := c.FullPath(); path != "" {
+		return policy.PolicyKey{Namespace: "http", Name: c.Request.Method + " " + path}
+	}
+	return policy.PolicyKey{Namespace: "http", Name: "*"}
+}
+
+// Middleware returns gin middleware with the behavior of integrations/http.Middleware:
+// it stores the client's attempt info in the request context, bounds the context by the
+// client's remaining deadline (see recoursehttp.IncomingDeadline), and sheds overloaded
+// keys with 429 Too Many Requests and Retry-After.
+//
+// opts.KeyFunc, if set, is called with c.Request; otherwise DefaultKeyFunc is used.
+func Middleware(opts recoursehttp.ServerOptions) gin.HandlerFunc {
+	s := recoursehttp.NewShedder(opts)
+	return func(c *gin.Context) {
+		if info, ok := recoursehttp.IncomingAttempt(c.Request); ok {
+			c.Request = c.Request.WithContext(observe.WithAttemptInfo(c.Request.Context(), info))
+		}
+		if d, ok := recoursehttp.IncomingDeadline(c.Request); ok {
+			ctx, cancel := deadline.Apply(c.Request.Context(), d)
+			defer cancel()
+			c.Request = c.Request.WithContext(ctx)
+		}
+
+		key := DefaultKeyFunc(c)
+		if opts.KeyFunc != nil {
+			key = opts.KeyFunc(c.Request)
+		}
+		done, reason := s.Admit(c.Request.Context(), key)
+		if reason != "" {
+			s.WriteShed(c.Writer, reason)
+			c.Abort()
+			return
+		}
+
+		status := http.StatusInternalServerError // if a handler panics
+		defer func() { done(status) }()
+		c.Next()
+		status = c.Writer.Status()
+	}
+}
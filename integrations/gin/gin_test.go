@@ -0,0 +1,88 @@
+package gin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/aponysus/recourse/controlplane"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func init() { gin.SetMode(gin.TestMode) }
+
+func TestMiddleware_ShedsWhenCircuitOpen(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "http", Name: "GET /items/:id"}
+	r := gin.New()
+	r.Use(Middleware(recoursehttp.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: time.Minute}},
+		}},
+	}))
+	calls := 0
+	r.GET("/items/:id", func(c *gin.Context) {
+		calls++
+		c.Status(http.StatusInternalServerError)
+	})
+
+	rec := httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/1", nil))
+	if rec.Code != http.StatusInternalServerError {
+		t.Fatalf("status=%d, want 500", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	r.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items/2", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("status=%d Retry-After=%q, want 429 with Retry-After", rec.Code, rec.Header().Get("Retry-After"))
+	}
+	if calls != 1 {
+		t.Fatalf("handler calls=%d, want 1", calls)
+	}
+}
+
+func TestMiddleware_AttemptInfo(t *testing.T) {
+	r := gin.New()
+	r.Use(Middleware(recoursehttp.ServerOptions{}))
+	var info observe.AttemptInfo
+	r.GET("/", func(c *gin.Context) {
+		info, _ = observe.AttemptFromContext(c.Request.Context())
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(recoursehttp.HeaderAttempt, "1")
+	req.Header.Set(recoursehttp.HeaderHedge, "2")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if info.Attempt != 1 || !info.IsHedge || info.HedgeIndex != 2 {
+		t.Fatalf("unexpected attempt info: %+v", info)
+	}
+}
+
+func TestMiddleware_DeadlineFromClient(t *testing.T) {
+	r := gin.New()
+	r.Use(Middleware(recoursehttp.ServerOptions{}))
+	var remaining time.Duration
+	var ok bool
+	r.GET("/", func(c *gin.Context) {
+		var dl time.Time
+		dl, ok = c.Request.Context().Deadline()
+		remaining = time.Until(dl)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(recoursehttp.HeaderTimeout, "5000")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	if !ok || remaining <= 0 || remaining > 5*time.Second {
+		t.Fatalf("deadline ok=%v remaining=%v, want within the client's 5s", ok, remaining)
+	}
+
+	r.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+	if ok {
+		t.Fatal("request without a client deadline got one")
+	}
+}
@@ -0,0 +1,11 @@
+// Package gokit provides opt-in go-kit integrations for recourse.
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor()
+//	var getUser endpoint.Endpoint
+//	getUser = makeGetUserEndpoint(client)
+//	getUser = recoursegokit.Middleware(exec, policy.PolicyKey{Namespace: "users", Name: "GetUser"})(getUser)
+//
+// This package lives in a separate module to keep the go-kit dependency opt-in.
+package gokit
@@ -0,0 +1,10 @@
+module github.com/aponysus/recourse/integrations/gokit
+
+go 1.23.0
+
+replace github.com/aponysus/recourse => ../../
+
+require (
+	github.com/aponysus/recourse v0.0.0-00010101000000-000000000000
+	github.com/go-kit/kit v0.13.0
+)
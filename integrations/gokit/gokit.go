@@ -0,0 +1,31 @@
+package gokit
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Middleware returns an endpoint.Middleware that runs the wrapped endpoint under key's
+// policy. If exec is nil, the default executor is used.
+//
+// Only the endpoint's error is classified. Responses that report a business error
+// through endpoint.Failer are successful calls, as they are to go-kit transports; to
+// retry on them, return the error from the endpoint or register a classifier that
+// inspects the response.
+func Middleware(exec *retry.Executor, key policy.PolicyKey) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (interface{}, error) {
+			e := exec
+			if e == nil {
+				e = retry.DefaultExecutor()
+			}
+			return retry.DoValue(ctx, e, key, func(ctx context.Context) (interface{}, error) {
+				return next(ctx, request)
+			})
+		}
+	}
+}
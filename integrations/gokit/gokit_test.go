@@ -0,0 +1,59 @@
+package gokit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestMiddleware_Retries(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "users", Name: "GetUser"}
+	exec := retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(3), policy.Backoff(time.Microsecond, time.Microsecond, 1)))
+
+	calls := 0
+	var ep endpoint.Endpoint = func(_ context.Context, req interface{}) (interface{}, error) {
+		calls++
+		if calls < 3 {
+			return nil, errors.New("unavailable")
+		}
+		return "user:" + req.(string), nil
+	}
+	ep = endpoint.Chain(Middleware(exec, key))(ep)
+
+	resp, err := ep(context.Background(), "42")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "user:42" || calls != 3 {
+		t.Fatalf("got %v after %d calls", resp, calls)
+	}
+}
+
+type failedResponse struct{ err error }
+
+func (r failedResponse) Failed() error { return r.err }
+
+func TestMiddleware_FailerResponseIsNotRetried(t *testing.T) {
+	key := policy.PolicyKey{Name: "CreateUser"}
+	exec := retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(3)))
+
+	calls := 0
+	ep := Middleware(exec, key)(func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return failedResponse{err: errors.New("email taken")}, nil
+	})
+
+	resp, err := ep(context.Background(), nil)
+	if err != nil || calls != 1 {
+		t.Fatalf("expected one successful call, got %d calls, err %v", calls, err)
+	}
+	if f, ok := resp.(endpoint.Failer); !ok || f.Failed() == nil {
+		t.Fatalf("expected the Failer response to be returned, got %#v", resp)
+	}
+}
@@ -0,0 +1,151 @@
+package graphql
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Error codes (errors[].extensions.code) that mean the server rejected the operation
+// before running it. They are retried for queries and mutations alike.
+var defaultRateLimitCodes = map[string]struct{}{
+	"RATE_LIMITED":      {},
+	"THROTTLED":         {},
+	"TOO_MANY_REQUESTS": {},
+}
+
+// Error codes that indicate a transient server-side failure. Mutations are not retried on
+// these, since the server may have applied them.
+var defaultRetryableCodes = map[string]struct{}{
+	"INTERNAL":              {},
+	"INTERNAL_SERVER_ERROR": {},
+	"SERVICE_UNAVAILABLE":   {},
+	"UNAVAILABLE":           {},
+	"TIMEOUT":               {},
+	"GATEWAY_TIMEOUT":       {},
+}
+
+// Classifier implements classify.Classifier for GraphQL responses.
+//
+// A *ResponseError is retryable only if every error in it has a rate-limit or retryable
+// code; any other code makes it terminal, as does a 2xx response whose errors carry no
+// codes. Non-GraphQL errors, and non-2xx responses without codes, are delegated to the
+// AutoClassifier (HTTP status rules).
+type Classifier struct {
+	// RetryableCodes is an optional set of additional transient error codes.
+	RetryableCodes map[string]struct{}
+}
+
+func (c Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+	if !hasCodes(re) {
+		// Without codes, a non-2xx status is the only signal left.
+		if _, ok := err.(classify.HTTPError); ok {
+			return classify.AutoClassifier{}.Classify(val, err)
+		}
+		return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "graphql_error"}
+	}
+
+	codes := re.Codes()
+	outcome := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "graphql_error",
+		Attributes: map[string]string{"graphql_code": strings.Join(codes, ",")},
+	}
+
+	rateLimited := false
+	for _, code := range codes {
+		switch {
+		case isCode(defaultRateLimitCodes, code):
+			rateLimited = true
+		case isCode(defaultRetryableCodes, code) || isCode(c.RetryableCodes, code):
+		default:
+			return outcome
+		}
+	}
+
+	if rateLimited {
+		outcome.Kind = classify.OutcomeRetryable
+		outcome.Reason = "graphql_rate_limited"
+		if d, ok := retryAfter(re); ok && d > 0 {
+			outcome.BackoffOverride = d
+			outcome.Attributes["retry_after"] = d.String()
+		}
+		return outcome
+	}
+
+	if re.Mutation {
+		outcome.Reason = "graphql_non_idempotent"
+		return outcome
+	}
+	outcome.Kind = classify.OutcomeRetryable
+	outcome.Reason = "graphql_retryable_error"
+	return outcome
+}
+
+func hasCodes(re *ResponseError) bool {
+	for _, ge := range re.Response.Errors {
+		if ge.Code() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func isCode(set map[string]struct{}, code string) bool {
+	if set == nil {
+		return false
+	}
+	_, ok := set[code]
+	return ok
+}
+
+// retryAfter reads a retry delay from errors[].extensions (retryAfter or retry_after, in
+// seconds) and falls back to the HTTP response headers.
+func retryAfter(re *ResponseError) (time.Duration, bool) {
+	for _, ge := range re.Response.Errors {
+		for _, name := range []string{"retryAfter", "retry_after"} {
+			if d, ok := seconds(ge.Extensions[name]); ok {
+				return d, true
+			}
+		}
+	}
+	return recoursehttp.ParseRetryAfter(re.Response.Header, time.Now())
+}
+
+func seconds(v any) (time.Duration, bool) {
+	var secs float64
+	switch v := v.(type) {
+	case float64:
+		secs = v
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return 0, false
+		}
+		secs = f
+	default:
+		return 0, false
+	}
+	if secs < 0 {
+		return 0, false
+	}
+	return time.Duration(secs * float64(time.Second)), true
+}
+
+// WithClassifier returns an option to register the GraphQL classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
@@ -0,0 +1,22 @@
+// Package graphql provides a retrying GraphQL-over-HTTP client for recourse.
+//
+// GraphQL servers usually answer with HTTP 200 even when the operation failed, so the
+// client decodes the response and returns a *ResponseError for a non-empty errors array.
+// Classifier then decides per error code (errors[].extensions.code): RATE_LIMITED and
+// INTERNAL-style codes are retried, validation and auth codes are not. Transport and
+// non-2xx HTTP failures are classified like integrations/http.
+//
+// Calls are keyed by operation name (see DefaultKeyFunc). Mutations are treated as
+// non-idempotent: only errors that show the server did not run them are retried.
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor(graphql.WithClassifier())
+//	client := graphql.New(exec, "https://api.example.com/graphql", graphql.Options{})
+//
+//	resp, err := client.Do(ctx, graphql.Request{
+//		Query:         `query GetUser($id: ID!) { user(id: $id) { name } }`,
+//		OperationName: "GetUser",
+//		Variables:     map[string]any{"id": id},
+//	})
+package graphql
@@ -0,0 +1,328 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultNamespace is the policy key namespace used by DefaultKeyFunc.
+const DefaultNamespace = "graphql"
+
+// maxResponseBody bounds how much of a response body is decoded.
+const maxResponseBody = 32 << 20
+
+// Request is a GraphQL operation.
+type Request struct {
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName,omitempty"`
+	Variables     map[string]any `json:"variables,omitempty"`
+}
+
+// Response is a decoded GraphQL response.
+type Response struct {
+	Data       json.RawMessage `json:"data,omitempty"`
+	Errors     []Error         `json:"errors,omitempty"`
+	Extensions map[string]any  `json:"extensions,omitempty"`
+
+	// Header holds the HTTP response headers.
+	Header http.Header `json:"-"`
+}
+
+// Error is a single entry of a response's errors array.
+type Error struct {
+	Message    string         `json:"message"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+// Code returns extensions.code, or "" if absent.
+func (e Error) Code() string {
+	code, _ := e.Extensions["code"].(string)
+	return code
+}
+
+// ResponseError is returned when a response carries a non-empty errors array.
+type ResponseError struct {
+	Response *Response // The full response, including any partial data.
+	Mutation bool      // Whether the operation was a mutation.
+}
+
+func (e *ResponseError) Error() string {
+	errs := e.Response.Errors
+	msg := errs[0].Message
+	if code := errs[0].Code(); code != "" {
+		msg = code + ": " + msg
+	}
+	if len(errs) > 1 {
+		return fmt.Sprintf("graphql: %s (and %d more errors)", msg, len(errs)-1)
+	}
+	return "graphql: " + msg
+}
+
+// Codes returns the distinct error codes in the response, in order of appearance.
+func (e *ResponseError) Codes() []string {
+	var codes []string
+	seen := make(map[string]struct{})
+	for _, ge := range e.Response.Errors {
+		code := ge.Code()
+		if _, ok := seen[code]; ok {
+			continue
+		}
+		seen[code] = struct{}{}
+		codes = append(codes, code)
+	}
+	return codes
+}
+
+// DefaultKeyFunc maps operation names to policy keys.
+// "GetUser" -> {Namespace: "graphql", Name: "GetUser"}. Anonymous operations use "anonymous".
+func DefaultKeyFunc(operationName string) policy.PolicyKey {
+	if operationName == "" {
+		operationName = "anonymous"
+	}
+	return policy.PolicyKey{Namespace: DefaultNamespace, Name: operationName}
+}
+
+// Options configures a Client.
+type Options struct {
+	// HTTPClient sends requests. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Header is added to every request (for example, Authorization).
+	Header http.Header
+
+	// KeyFunc maps operation names to policy keys. Defaults to DefaultKeyFunc.
+	KeyFunc func(operationName string) policy.PolicyKey
+}
+
+// Client sends GraphQL operations over HTTP POST with retries.
+type Client struct {
+	exec     *retry.Executor
+	endpoint string
+	client   *http.Client
+	header   http.Header
+	keyFunc  func(operationName string) policy.PolicyKey
+}
+
+// New returns a Client for endpoint. If exec is nil, the default executor is used.
+func New(exec *retry.Executor, endpoint string, opts Options) *Client {
+	c := &Client{
+		exec:     exec,
+		endpoint: endpoint,
+		client:   opts.HTTPClient,
+		header:   opts.Header.Clone(),
+		keyFunc:  opts.KeyFunc,
+	}
+	if c.client == nil {
+		c.client = http.DefaultClient
+	}
+	if c.keyFunc == nil {
+		c.keyFunc = DefaultKeyFunc
+	}
+	return c
+}
+
+// Do sends req under the policy for its operation name.
+//
+// It returns the decoded response on success. When the final attempt carried GraphQL
+// errors, the error is a *retry.CallError wrapping a *ResponseError, and the response is
+// also returned so partial data is not lost.
+func (c *Client) Do(ctx context.Context, req Request) (*Response, error) {
+	exec := c.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+
+	body, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("graphql: encode request: %w", err)
+	}
+	mutation := IsMutation(req.Query, req.OperationName)
+	// HTTP classifiers decide idempotency from the method. Queries are reads, so they are
+	// reported as GET even though they are sent as POST.
+	method := http.MethodGet
+	if mutation {
+		method = http.MethodPost
+	}
+
+	resp, err := retry.DoValue(ctx, exec, c.keyFunc(req.OperationName), func(ctx context.Context) (*Response, error) {
+		httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint, bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		for k, vs := range c.header {
+			httpReq.Header[k] = append([]string(nil), vs...)
+		}
+		httpReq.Header.Set("Content-Type", "application/json")
+		if httpReq.Header.Get("Accept") == "" {
+			httpReq.Header.Set("Accept", "application/graphql-response+json, application/json")
+		}
+
+		httpResp, err := c.client.Do(httpReq)
+		if err != nil {
+			return nil, &recoursehttp.StatusError{Err: err, Method: method}
+		}
+		defer httpResp.Body.Close()
+
+		out, decodeErr := decodeResponse(httpResp)
+		if httpResp.StatusCode < 200 || httpResp.StatusCode > 299 {
+			// GraphQL-over-HTTP servers may still include errors with a non-2xx status.
+			statusErr := &recoursehttp.StatusError{Code: httpResp.StatusCode, Method: method, Header: httpResp.Header}
+			if decodeErr == nil && len(out.Errors) > 0 {
+				statusErr.Err = &ResponseError{Response: out, Mutation: mutation}
+			}
+			return nil, statusErr
+		}
+		if decodeErr != nil {
+			return nil, decodeErr
+		}
+		if len(out.Errors) > 0 {
+			return nil, &ResponseError{Response: out, Mutation: mutation}
+		}
+		return out, nil
+	})
+	if err != nil {
+		var re *ResponseError
+		if errors.As(err, &re) {
+			return re.Response, err
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+func decodeResponse(resp *http.Response) (*Response, error) {
+	var out Response
+	dec := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBody))
+	if err := dec.Decode(&out); err != nil {
+		return nil, fmt.Errorf("graphql: decode response: %w", err)
+	}
+	out.Header = resp.Header
+	return &out, nil
+}
+
+// IsMutation reports whether the operation named operationName in query is a mutation.
+// With an empty operationName, the first operation in the document is used.
+func IsMutation(query, operationName string) bool {
+	return operationType(query, operationName) == "mutation"
+}
+
+// operationType returns "query", "mutation", or "subscription" for the selected operation.
+// It scans top-level definitions only; it is not a full GraphQL parser.
+func operationType(query, operationName string) string {
+	depth := 0 // braces and parentheses
+	for i := 0; i < len(query); {
+		ch := query[i]
+		switch {
+		case ch == '#':
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case ch == '"':
+			i = skipString(query, i)
+		case ch == '{' || ch == '(' || ch == '[':
+			if depth == 0 && ch == '{' && operationName == "" {
+				// Shorthand query: "{ field }".
+				return "query"
+			}
+			depth++
+			i++
+		case ch == '}' || ch == ')' || ch == ']':
+			depth--
+			i++
+		case isNameStart(ch):
+			start := i
+			for i < len(query) && isNameChar(query[i]) {
+				i++
+			}
+			word := query[start:i]
+			if depth != 0 {
+				continue
+			}
+			switch word {
+			case "query", "mutation", "subscription":
+				name := nextName(query, i)
+				if operationName == "" || name == operationName {
+					return word
+				}
+			case "fragment":
+				// Skip the fragment's selection set so its name and type do not match.
+				i = skipDefinition(query, i)
+			}
+		default:
+			i++
+		}
+	}
+	return ""
+}
+
+func nextName(s string, i int) string {
+	for i < len(s) && strings.IndexByte(" \t\r\n,", s[i]) >= 0 {
+		i++
+	}
+	start := i
+	for i < len(s) && isNameChar(s[i]) {
+		i++
+	}
+	return s[start:i]
+}
+
+func skipDefinition(s string, i int) int {
+	for i < len(s) && s[i] != '{' {
+		i++
+	}
+	depth := 0
+	for i < len(s) {
+		switch s[i] {
+		case '"':
+			i = skipString(s, i)
+			continue
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+		i++
+	}
+	return i
+}
+
+func skipString(s string, i int) int {
+	if strings.HasPrefix(s[i:], `"""`) {
+		end := strings.Index(s[i+3:], `"""`)
+		if end < 0 {
+			return len(s)
+		}
+		return i + 3 + end + 3
+	}
+	for i++; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return i
+}
+
+func isNameStart(ch byte) bool {
+	return ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z')
+}
+
+func isNameChar(ch byte) bool {
+	return isNameStart(ch) || (ch >= '0' && ch <= '9')
+}
@@ -0,0 +1,190 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func newTestExecutor(name string) *retry.Executor {
+	return retry.NewDefaultExecutor(
+		WithClassifier(),
+		retry.WithPolicyKey(DefaultKeyFunc(name), policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Millisecond, 1)),
+	)
+}
+
+// server replies with the given bodies in order, repeating the last one.
+func server(t *testing.T, status int, bodies ...string) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		n := int(calls.Add(1)) - 1
+		if n >= len(bodies) {
+			n = len(bodies) - 1
+		}
+		w.Header().Set("Content-Type", "application/json")
+		if n == len(bodies)-1 {
+			w.WriteHeader(http.StatusOK)
+		} else {
+			w.WriteHeader(status)
+		}
+		_, _ = w.Write([]byte(bodies[n]))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+const (
+	internalErr   = `{"errors":[{"message":"boom","extensions":{"code":"INTERNAL"}}]}`
+	rateLimited   = `{"errors":[{"message":"slow down","extensions":{"code":"RATE_LIMITED","retryAfter":0.001}}]}`
+	validationErr = `{"errors":[{"message":"bad","extensions":{"code":"GRAPHQL_VALIDATION_FAILED"}}]}`
+	okData        = `{"data":{"user":{"name":"ada"}}}`
+)
+
+func TestClient_RetriesInternalErrorForQuery(t *testing.T) {
+	srv, calls := server(t, http.StatusOK, internalErr, okData)
+	c := New(newTestExecutor("GetUser"), srv.URL, Options{})
+
+	resp, err := c.Do(context.Background(), Request{Query: `query GetUser { user { name } }`, OperationName: "GetUser"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(resp.Data) != `{"user":{"name":"ada"}}` {
+		t.Fatalf("unexpected data %s", resp.Data)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestClient_DoesNotRetryInternalErrorForMutation(t *testing.T) {
+	srv, calls := server(t, http.StatusOK, internalErr, okData)
+	c := New(newTestExecutor("SetName"), srv.URL, Options{})
+
+	resp, err := c.Do(context.Background(), Request{Query: `mutation SetName { setName(name: "x") }`, OperationName: "SetName"})
+	var re *ResponseError
+	if !errors.As(err, &re) {
+		t.Fatalf("expected ResponseError, got %v", err)
+	}
+	if resp == nil || len(resp.Errors) != 1 {
+		t.Fatalf("expected the error response to be returned, got %+v", resp)
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestClient_RetriesRateLimitedMutation(t *testing.T) {
+	srv, calls := server(t, http.StatusOK, rateLimited, okData)
+	c := New(newTestExecutor("SetName"), srv.URL, Options{})
+
+	if _, err := c.Do(context.Background(), Request{Query: `mutation SetName { setName(name: "x") }`, OperationName: "SetName"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestClient_DoesNotRetryValidationError(t *testing.T) {
+	srv, calls := server(t, http.StatusOK, validationErr, okData)
+	c := New(newTestExecutor("GetUser"), srv.URL, Options{})
+
+	_, err := c.Do(context.Background(), Request{Query: `query GetUser { user { name } }`, OperationName: "GetUser"})
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected 1 call, got %d", calls.Load())
+	}
+}
+
+func TestClient_RetriesHTTP503(t *testing.T) {
+	srv, calls := server(t, http.StatusServiceUnavailable, `{}`, okData)
+	c := New(newTestExecutor("GetUser"), srv.URL, Options{})
+
+	if _, err := c.Do(context.Background(), Request{Query: `query GetUser { user { name } }`, OperationName: "GetUser"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected 2 calls, got %d", calls.Load())
+	}
+}
+
+func TestClassifier_Outcomes(t *testing.T) {
+	respErr := func(mutation bool, codes ...string) error {
+		r := &Response{}
+		for _, code := range codes {
+			r.Errors = append(r.Errors, Error{Message: "x", Extensions: map[string]any{"code": code}})
+		}
+		return &ResponseError{Response: r, Mutation: mutation}
+	}
+
+	tests := []struct {
+		name   string
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{"rate limited", respErr(false, "RATE_LIMITED"), classify.OutcomeRetryable, "graphql_rate_limited"},
+		{"internal query", respErr(false, "INTERNAL"), classify.OutcomeRetryable, "graphql_retryable_error"},
+		{"internal mutation", respErr(true, "INTERNAL"), classify.OutcomeNonRetryable, "graphql_non_idempotent"},
+		{"mixed codes", respErr(false, "INTERNAL", "FORBIDDEN"), classify.OutcomeNonRetryable, "graphql_error"},
+		{"unauthenticated", respErr(false, "UNAUTHENTICATED"), classify.OutcomeNonRetryable, "graphql_error"},
+		{"no codes", &ResponseError{Response: &Response{Errors: []Error{{Message: "x"}}}}, classify.OutcomeNonRetryable, "graphql_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := Classifier{}.Classify(nil, tt.err)
+			if out.Kind != tt.kind || out.Reason != tt.reason {
+				t.Fatalf("got %v/%s, want %v/%s", out.Kind, out.Reason, tt.kind, tt.reason)
+			}
+		})
+	}
+}
+
+func TestClassifier_RetryAfterExtension(t *testing.T) {
+	err := &ResponseError{Response: &Response{Errors: []Error{{
+		Message:    "slow down",
+		Extensions: map[string]any{"code": "THROTTLED", "retryAfter": float64(2)},
+	}}}}
+	out := Classifier{}.Classify(nil, err)
+	if out.BackoffOverride != 2*time.Second {
+		t.Fatalf("expected 2s backoff override, got %v", out.BackoffOverride)
+	}
+}
+
+func TestIsMutation(t *testing.T) {
+	tests := []struct {
+		query string
+		op    string
+		want  bool
+	}{
+		{`{ user { name } }`, "", false},
+		{`query Q { user { name } }`, "", false},
+		{`mutation M { setName(name: "{") }`, "", true},
+		{"# mutation in a comment\nquery Q { a }", "", false},
+		{`query Q { a } mutation M { b }`, "M", true},
+		{`query Q { a } mutation M { b }`, "Q", false},
+		{`fragment F on mutation { a } mutation M($v: In = {a: 1}) { ...F }`, "", true},
+	}
+	for _, tt := range tests {
+		if got := IsMutation(tt.query, tt.op); got != tt.want {
+			t.Errorf("IsMutation(%q, %q) = %v, want %v", tt.query, tt.op, got, tt.want)
+		}
+	}
+}
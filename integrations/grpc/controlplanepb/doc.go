@@ -0,0 +1,6 @@
+// Package controlplanepb holds the generated code for controlplane.proto, the
+// recourse.controlplane.v1.PolicyService streaming policy API.
+//
+// Regenerate with protoc-gen-go and protoc-gen-go-grpc (paths=source_relative) after
+// editing controlplane.proto.
+package controlplanepb
@@ -2,13 +2,18 @@ package grpc
 
 import (
 	"context"
+	"strconv"
 	"strings"
+	"time"
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/deadline"
+	"github.com/aponysus/recourse/observe"
 	"github.com/aponysus/recourse/policy"
 	"github.com/aponysus/recourse/retry"
 )
@@ -25,24 +30,133 @@ func DefaultKeyFunc(method string) policy.PolicyKey {
 	return policy.PolicyKey{Name: method}
 }
 
+// Outgoing metadata keys set by UnaryClientInterceptor on every attempt.
+const (
+	// MetadataAttempt carries the 0-based attempt index.
+	MetadataAttempt = "x-recourse-attempt"
+	// MetadataHedge carries the hedge index within the attempt group (0 for the primary).
+	MetadataHedge = "x-recourse-hedge"
+	// MetadataPolicyID carries the policy ID, when the policy sets one.
+	MetadataPolicyID = "x-recourse-policy-id"
+	// MetadataTimeout carries the attempt's remaining deadline in milliseconds, when
+	// its context has one (see the deadline package). gRPC propagates its own deadline
+	// between gRPC peers; this copy survives hops through proxies and HTTP bridges that
+	// drop it, and is read by IncomingDeadline.
+	MetadataTimeout = "x-recourse-timeout-ms"
+	// MetadataIdempotencyKey carries the key shared by all attempts of a hedged group,
+	// when the policy sets Hedge.IdempotencyKeys, so servers can deduplicate them.
+	MetadataIdempotencyKey = "idempotency-key"
+)
+
 // UnaryClientInterceptor returns a gRPC interceptor that retries calls using the executor.
+//
+// The policy key is derived from the full method name via keyFunc (DefaultKeyFunc if nil),
+// so the policy's retry, hedging, and circuit settings apply to every stub method. Each
+// attempt's outgoing metadata is annotated with the attempt and hedge index so servers can
+// tell retries apart (see IncomingAttempt). If exec is nil, the default executor is used.
 func UnaryClientInterceptor(exec *retry.Executor, keyFunc func(method string) policy.PolicyKey) grpc.UnaryClientInterceptor {
 	if keyFunc == nil {
 		keyFunc = DefaultKeyFunc
 	}
 	return func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		e := exec
+		if e == nil {
+			e = retry.DefaultExecutor()
+		}
 		key := keyFunc(method)
 		op := func(ctx context.Context) error {
-			return invoker(ctx, method, req, reply, cc, opts...)
+			return invoker(annotateAttempt(ctx), method, req, reply, cc, opts...)
 		}
 		// retry.Do handles the retry loop.
-		return exec.Do(ctx, key, op)
+		return e.Do(ctx, key, op)
 	}
 }
 
+// IncomingAttempt returns the attempt metadata sent by a recourse client interceptor.
+func IncomingAttempt(ctx context.Context) (observe.AttemptInfo, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return observe.AttemptInfo{}, false
+	}
+	vals := md.Get(MetadataAttempt)
+	if len(vals) == 0 {
+		return observe.AttemptInfo{}, false
+	}
+	attempt, err := strconv.Atoi(vals[0])
+	if err != nil {
+		return observe.AttemptInfo{}, false
+	}
+
+	info := observe.AttemptInfo{Attempt: attempt, RetryIndex: attempt}
+	if v := md.Get(MetadataHedge); len(v) > 0 {
+		if idx, err := strconv.Atoi(v[0]); err == nil && idx > 0 {
+			info.IsHedge = true
+			info.HedgeIndex = idx
+		}
+	}
+	if v := md.Get(MetadataPolicyID); len(v) > 0 {
+		info.PolicyID = v[0]
+	}
+	if v := md.Get(MetadataIdempotencyKey); len(v) > 0 {
+		info.IdempotencyKey = v[0]
+	}
+	return info, true
+}
+
+func annotateAttempt(ctx context.Context) context.Context {
+	info, ok := observe.AttemptFromContext(ctx)
+	if !ok {
+		return ctx
+	}
+	kv := []string{
+		MetadataAttempt, strconv.Itoa(info.Attempt),
+		MetadataHedge, strconv.Itoa(info.HedgeIndex),
+	}
+	if info.PolicyID != "" {
+		kv = append(kv, MetadataPolicyID, info.PolicyID)
+	}
+	if info.IdempotencyKey != "" {
+		kv = append(kv, MetadataIdempotencyKey, info.IdempotencyKey)
+	}
+	if d, ok := deadline.Remaining(ctx); ok {
+		kv = append(kv, MetadataTimeout, deadline.Format(d))
+	}
+	return metadata.AppendToOutgoingContext(ctx, kv...)
+}
+
+// IncomingDeadline returns the remaining deadline sent in MetadataTimeout.
+func IncomingDeadline(ctx context.Context) (time.Duration, bool) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return 0, false
+	}
+	vals := md.Get(MetadataTimeout)
+	if len(vals) == 0 {
+		return 0, false
+	}
+	d, err := deadline.Parse(vals[0])
+	return d, err == nil
+}
+
 // Classifier implements classify.Classifier for gRPC status codes.
 type Classifier struct{}
 
+// codeReasons holds the interned "grpc_<Code>" reasons for the standard codes.
+var codeReasons = func() (r [codes.Unauthenticated + 1]string) {
+	for c := range r {
+		r[c] = "grpc_" + codes.Code(c).String()
+	}
+	return r
+}()
+
+// codeReason returns the outcome reason for code, e.g. "grpc_Unavailable".
+func codeReason(code codes.Code) string {
+	if int(code) < len(codeReasons) {
+		return codeReasons[code]
+	}
+	return "grpc_" + code.String()
+}
+
 func (Classifier) Classify(val any, err error) classify.Outcome {
 	if err == nil {
 		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
@@ -63,7 +177,7 @@ func (Classifier) Classify(val any, err error) classify.Outcome {
 	code := st.Code()
 	outcome := classify.Outcome{
 		Kind:       classify.OutcomeNonRetryable,
-		Reason:     "grpc_" + code.String(),
+		Reason:     codeReason(code),
 		Attributes: map[string]string{"grpc_code": code.String()},
 	}
 
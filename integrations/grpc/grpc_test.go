@@ -7,10 +7,13 @@ import (
 
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/aponysus/recourse/classify"
 	integration "github.com/aponysus/recourse/integrations/grpc"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
 	"github.com/aponysus/recourse/retry"
 )
 
@@ -122,3 +125,44 @@ func TestUnaryClientInterceptor_ContextCanceled(t *testing.T) {
 		t.Errorf("expected 0 attempts, got %d", attempts)
 	}
 }
+
+func TestUnaryClientInterceptor_AnnotatesAttemptMetadata(t *testing.T) {
+	exec := retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicy("Service.Method", policy.MaxAttempts(3), policy.PolicyID("svc-v1")),
+	)
+	interceptor := integration.UnaryClientInterceptor(exec, nil)
+
+	var seen []observe.AttemptInfo
+	mockInvoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		// Simulate the server side: outgoing metadata becomes incoming metadata.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		info, ok := integration.IncomingAttempt(metadata.NewIncomingContext(ctx, md))
+		if !ok {
+			t.Fatalf("missing attempt metadata: %v", md)
+		}
+		seen = append(seen, info)
+		if len(seen) < 2 {
+			return status.Error(codes.Unavailable, "transient failure")
+		}
+		return nil
+	}
+
+	if err := interceptor(context.Background(), "/Service/Method", nil, nil, nil, mockInvoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(seen))
+	}
+	for i, info := range seen {
+		if info.Attempt != i || info.IsHedge || info.PolicyID != "svc-v1" {
+			t.Errorf("attempt %d metadata = %+v", i, info)
+		}
+	}
+}
+
+func TestIncomingAttempt_NoMetadata(t *testing.T) {
+	if _, ok := integration.IncomingAttempt(context.Background()); ok {
+		t.Fatal("expected no attempt info")
+	}
+}
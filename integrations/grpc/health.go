@@ -0,0 +1,81 @@
+package grpc
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+
+	"github.com/aponysus/recourse/health"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Reasons recorded by WatchHealth when it marks a key unhealthy.
+const (
+	// ReasonHealthWatchFailed means the health stream broke; the target is treated as
+	// unhealthy until the stream reports SERVING again.
+	ReasonHealthWatchFailed = "grpc_health_watch_failed"
+)
+
+const (
+	healthRewatchMin = 100 * time.Millisecond
+	healthRewatchMax = 30 * time.Second
+)
+
+// WatchHealth streams the gRPC health status of service (grpc.health.v1.Health/Watch)
+// over conn into reg under key until ctx is done. Use an empty service for the whole
+// server and a namespace-only key to pause retries for every method of a service.
+//
+// SERVING marks key healthy; any other status marks it unhealthy with reason
+// "grpc_health_<status>" (e.g. "grpc_health_not_serving"). A broken stream marks key
+// unhealthy and is re-established with backoff. The mark is cleared when WatchHealth
+// returns.
+//
+// It returns ctx.Err() when ctx ends, or the error if the server does not implement
+// health checking.
+func WatchHealth(ctx context.Context, conn grpc.ClientConnInterface, service string, key policy.PolicyKey, reg *health.Registry) error {
+	defer reg.MarkHealthy(key)
+
+	client := healthpb.NewHealthClient(conn)
+	wait := healthRewatchMin
+	for {
+		stream, err := client.Watch(ctx, &healthpb.HealthCheckRequest{Service: service})
+		for err == nil {
+			var resp *healthpb.HealthCheckResponse
+			resp, err = stream.Recv()
+			if err != nil {
+				break
+			}
+			wait = healthRewatchMin
+			if resp.GetStatus() == healthpb.HealthCheckResponse_SERVING {
+				reg.MarkHealthy(key)
+			} else {
+				reg.MarkUnhealthy(key, "grpc_health_"+strings.ToLower(resp.GetStatus().String()))
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return err
+		}
+		reg.MarkUnhealthy(key, ReasonHealthWatchFailed)
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		wait *= 2
+		if wait > healthRewatchMax {
+			wait = healthRewatchMax
+		}
+	}
+}
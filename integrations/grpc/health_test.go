@@ -0,0 +1,74 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/aponysus/recourse/health"
+	integration "github.com/aponysus/recourse/integrations/grpc"
+	"github.com/aponysus/recourse/policy"
+)
+
+func waitForHealth(t *testing.T, reg *health.Registry, key policy.PolicyKey, wantUnhealthy bool, wantReason string) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		st, unhealthy := reg.Unhealthy(key)
+		if unhealthy == wantUnhealthy && st.Reason == wantReason {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("health = %v (%q), want %v (%q)", unhealthy, st.Reason, wantUnhealthy, wantReason)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestWatchHealth(t *testing.T) {
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer()
+	hs := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(srv, hs)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	key := policy.PolicyKey{Namespace: "orders.Orders"}
+	reg := health.NewRegistry()
+	hs.SetServingStatus("orders.Orders", healthpb.HealthCheckResponse_NOT_SERVING)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	errc := make(chan error, 1)
+	go func() { errc <- integration.WatchHealth(ctx, conn, "orders.Orders", key, reg) }()
+
+	waitForHealth(t, reg, policy.PolicyKey{Namespace: "orders.Orders", Name: "Get"}, true, "grpc_health_not_serving")
+	hs.SetServingStatus("orders.Orders", healthpb.HealthCheckResponse_SERVING)
+	waitForHealth(t, reg, key, false, "")
+	hs.SetServingStatus("orders.Orders", healthpb.HealthCheckResponse_NOT_SERVING)
+	waitForHealth(t, reg, key, true, "grpc_health_not_serving")
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("WatchHealth = %v, want context.Canceled", err)
+	}
+	if _, unhealthy := reg.Unhealthy(key); unhealthy {
+		t.Fatal("mark not cleared when WatchHealth returned")
+	}
+}
@@ -0,0 +1,159 @@
+package grpc
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/integrations/grpc/controlplanepb"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for StreamingProviderOptions.
+const (
+	DefaultResubscribeMin = 100 * time.Millisecond
+	DefaultResubscribeMax = 30 * time.Second
+)
+
+// StreamingProviderOptions configures a StreamingProvider.
+type StreamingProviderOptions struct {
+	// Keys to subscribe to. Empty subscribes to every key the server has.
+	Keys []policy.PolicyKey
+	// ResubscribeMin and ResubscribeMax bound the exponential backoff between broken
+	// streams. Default 100ms and 30s.
+	ResubscribeMin time.Duration
+	ResubscribeMax time.Duration
+}
+
+// StreamingProvider serves policies pushed over a recourse.controlplane.v1.PolicyService
+// Subscribe stream, so changes apply as soon as the server publishes them.
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the first snapshot arrives,
+// GetEffectivePolicy returns controlplane.ErrProviderUnavailable; afterwards keys the
+// server does not have return controlplane.ErrPolicyNotFound. While the stream is down
+// the last received policies keep being served.
+type StreamingProvider struct {
+	client controlplanepb.PolicyServiceClient
+	opts   StreamingProviderOptions
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	version  string
+	loaded   bool
+}
+
+// NewStreamingProvider returns a provider that subscribes over conn. Call Run to
+// start the subscription.
+func NewStreamingProvider(conn grpc.ClientConnInterface, opts StreamingProviderOptions) *StreamingProvider {
+	if opts.ResubscribeMin <= 0 {
+		opts.ResubscribeMin = DefaultResubscribeMin
+	}
+	if opts.ResubscribeMax < opts.ResubscribeMin {
+		opts.ResubscribeMax = max(DefaultResubscribeMax, opts.ResubscribeMin)
+	}
+	return &StreamingProvider{client: controlplanepb.NewPolicyServiceClient(conn), opts: opts}
+}
+
+// GetEffectivePolicy returns the last policy received for key.
+func (p *StreamingProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		return policy.EffectivePolicy{}, controlplane.ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Version returns the server version of the last applied update.
+func (p *StreamingProvider) Version() string {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return p.version
+}
+
+// Run keeps a subscription open until ctx is done, resubscribing with backoff when the
+// stream breaks. It returns ctx.Err(), or the error if the server does not implement
+// PolicyService.
+func (p *StreamingProvider) Run(ctx context.Context) error {
+	req := &controlplanepb.SubscribeRequest{}
+	for _, k := range p.opts.Keys {
+		req.Keys = append(req.Keys, k.String())
+	}
+
+	wait := p.opts.ResubscribeMin
+	for {
+		stream, err := p.client.Subscribe(ctx, req)
+		for first := true; err == nil; first = false {
+			var update *controlplanepb.PolicyUpdate
+			update, err = stream.Recv()
+			if err != nil {
+				break
+			}
+			if err = p.apply(update, first); err == nil {
+				wait = p.opts.ResubscribeMin
+			}
+		}
+
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if status.Code(err) == codes.Unimplemented {
+			return err
+		}
+
+		t := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+		wait = min(wait*2, p.opts.ResubscribeMax)
+	}
+}
+
+// apply decodes update and merges it into the policy set. The first update on a stream
+// must be a snapshot. An invalid update is rejected whole, which breaks the stream so
+// the next subscription starts from a fresh snapshot.
+func (p *StreamingProvider) apply(update *controlplanepb.PolicyUpdate, first bool) error {
+	if first && !update.GetSnapshot() {
+		return fmt.Errorf("recourse: policy stream did not start with a snapshot")
+	}
+	decoded := make(map[policy.PolicyKey]policy.EffectivePolicy, len(update.GetPolicies()))
+	for _, pb := range update.GetPolicies() {
+		key := policy.ParseKey(pb.GetKey())
+		pol, err := controlplane.DecodePolicy(key, pb.GetPolicyJson())
+		if err != nil {
+			return err
+		}
+		decoded[key] = pol
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if update.GetSnapshot() {
+		p.policies = decoded
+	} else {
+		for _, k := range update.GetRemoved() {
+			delete(p.policies, policy.ParseKey(k))
+		}
+		for k, v := range decoded {
+			p.policies[k] = v
+		}
+	}
+	p.version = update.GetVersion()
+	p.loaded = true
+	return nil
+}
+
+var _ controlplane.PolicyProvider = (*StreamingProvider)(nil)
@@ -0,0 +1,91 @@
+package grpc_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/grpc"
+	"github.com/aponysus/recourse/integrations/grpc/controlplanepb"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestStreamingProvider(t *testing.T) {
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer()
+	ps := integration.NewPolicyServer()
+	controlplanepb.RegisterPolicyServiceServer(srv, ps)
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	get := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	put := policy.PolicyKey{Namespace: "svc", Name: "Put"}
+	other := policy.PolicyKey{Namespace: "svc", Name: "Other"}
+	if err := ps.Publish("v1", policy.New("svc.Get", policy.MaxAttempts(4)), policy.New("svc.Other", policy.MaxAttempts(5))); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+
+	p := integration.NewStreamingProvider(conn, integration.StreamingProviderOptions{Keys: []policy.PolicyKey{get, put}})
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.GetEffectivePolicy(ctx, get); !errors.Is(err, controlplane.ErrProviderUnavailable) {
+		t.Fatalf("before snapshot: err = %v, want ErrProviderUnavailable", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- p.Run(ctx) }()
+
+	waitFor := func(key policy.PolicyKey, attempts int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for {
+			pol, err := p.GetEffectivePolicy(ctx, key)
+			if attempts == 0 && errors.Is(err, controlplane.ErrPolicyNotFound) {
+				return
+			}
+			if err == nil && pol.Retry.MaxAttempts == attempts {
+				if pol.Meta.Source != policy.PolicySourceRemote {
+					t.Fatalf("source = %q, want remote", pol.Meta.Source)
+				}
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s: policy = %+v, %v; want %d attempts", key, pol, err, attempts)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor(get, 4)
+	waitFor(put, 0)
+	ps.Publish("v2", policy.New("svc.Put", policy.MaxAttempts(2)))
+	waitFor(put, 2)
+	ps.Remove("v3", get)
+	waitFor(get, 0)
+	if p.Version() != "v3" {
+		t.Fatalf("version = %q, want v3", p.Version())
+	}
+	// Unsubscribed keys are not delivered.
+	if _, err := p.GetEffectivePolicy(ctx, other); !errors.Is(err, controlplane.ErrPolicyNotFound) {
+		t.Fatalf("unsubscribed key: err = %v, want ErrPolicyNotFound", err)
+	}
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
@@ -0,0 +1,144 @@
+package grpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/aponysus/recourse/integrations/grpc/controlplanepb"
+	"github.com/aponysus/recourse/policy"
+)
+
+// PolicyServer is an in-memory recourse.controlplane.v1.PolicyService. Register it on a
+// grpc.Server and call Publish and Remove as the policy source changes; subscribers
+// receive a snapshot on Subscribe and an update after every change to their keys.
+//
+// Updates to a slow subscriber are coalesced: it receives the net change since the
+// last update it consumed.
+type PolicyServer struct {
+	controlplanepb.UnimplementedPolicyServiceServer
+
+	mu       sync.Mutex
+	policies map[string][]byte
+	version  string
+	subs     map[chan struct{}]struct{}
+}
+
+// NewPolicyServer returns an empty PolicyServer.
+func NewPolicyServer() *PolicyServer {
+	return &PolicyServer{
+		policies: make(map[string][]byte),
+		subs:     make(map[chan struct{}]struct{}),
+	}
+}
+
+// Publish adds or replaces policies, keyed by their Key, and sets the server version.
+func (s *PolicyServer) Publish(version string, policies ...policy.EffectivePolicy) error {
+	encoded := make(map[string][]byte, len(policies))
+	for _, pol := range policies {
+		if pol.Key == (policy.PolicyKey{}) {
+			return fmt.Errorf("recourse: publish policy without key")
+		}
+		b, err := json.Marshal(pol)
+		if err != nil {
+			return err
+		}
+		encoded[pol.Key.String()] = b
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for k, b := range encoded {
+		s.policies[k] = b
+	}
+	s.version = version
+	s.notifyLocked()
+	return nil
+}
+
+// Remove deletes the policies for keys and sets the server version.
+func (s *PolicyServer) Remove(version string, keys ...policy.PolicyKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, k := range keys {
+		delete(s.policies, k.String())
+	}
+	s.version = version
+	s.notifyLocked()
+}
+
+func (s *PolicyServer) notifyLocked() {
+	for ch := range s.subs {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Subscribe implements controlplanepb.PolicyServiceServer.
+func (s *PolicyServer) Subscribe(req *controlplanepb.SubscribeRequest, stream controlplanepb.PolicyService_SubscribeServer) error {
+	var want map[string]bool
+	if len(req.GetKeys()) > 0 {
+		want = make(map[string]bool, len(req.GetKeys()))
+		for _, k := range req.GetKeys() {
+			want[policy.ParseKey(k).String()] = true
+		}
+	}
+
+	wake := make(chan struct{}, 1)
+	s.mu.Lock()
+	s.subs[wake] = struct{}{}
+	s.mu.Unlock()
+	defer func() {
+		s.mu.Lock()
+		delete(s.subs, wake)
+		s.mu.Unlock()
+	}()
+
+	// sent mirrors what the subscriber holds, so each update is the diff against it.
+	sent := make(map[string][]byte)
+	update := s.diff(want, sent)
+	update.Snapshot = true
+	for {
+		if err := stream.Send(update); err != nil {
+			return err
+		}
+		for {
+			select {
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			case <-wake:
+			}
+			if update = s.diff(want, sent); len(update.Policies) > 0 || len(update.Removed) > 0 {
+				break
+			}
+		}
+	}
+}
+
+// diff returns the changes to the wanted keys since sent and updates sent to match.
+func (s *PolicyServer) diff(want map[string]bool, sent map[string][]byte) *controlplanepb.PolicyUpdate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	update := &controlplanepb.PolicyUpdate{Version: s.version}
+	for k, b := range s.policies {
+		if want != nil && !want[k] {
+			continue
+		}
+		if prev, ok := sent[k]; ok && bytes.Equal(prev, b) {
+			continue
+		}
+		sent[k] = b
+		update.Policies = append(update.Policies, &controlplanepb.Policy{Key: k, PolicyJson: b})
+	}
+	for k := range sent {
+		if _, ok := s.policies[k]; !ok {
+			delete(sent, k)
+			update.Removed = append(update.Removed, k)
+		}
+	}
+	return update
+}
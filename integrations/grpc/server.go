@@ -0,0 +1,141 @@
+package grpc
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/deadline"
+	"github.com/aponysus/recourse/internal/admission"
+	"github.com/aponysus/recourse/policy"
+)
+
+// PushbackTrailer is the gRPC trailer that tells retrying clients when to try again
+// (milliseconds; negative means "do not retry").
+const PushbackTrailer = "grpc-retry-pushback-ms"
+
+// Shedding reasons reported in the RESOURCE_EXHAUSTED status message.
+const (
+	ReasonShedCircuitOpen  = admission.ReasonCircuitOpen
+	ReasonShedConcurrency  = admission.ReasonConcurrency
+	ReasonShedBudgetDenied = admission.ReasonBudgetDenied
+)
+
+const defaultServerPushback = time.Second
+
+// ServerOptions configures UnaryServerInterceptor.
+type ServerOptions struct {
+	// KeyFunc maps methods to policy keys. Defaults to DefaultKeyFunc.
+	KeyFunc func(method string) policy.PolicyKey
+
+	// Provider resolves the policy for each method. Its Circuit settings enable a
+	// per-method breaker and its Retry.Budget names the admission budget.
+	// If nil, only the concurrency limit applies.
+	Provider controlplane.PolicyProvider
+	// Circuits holds per-method breakers. Defaults to a new registry.
+	Circuits *circuit.Registry
+	// Budgets resolves Retry.Budget references. Missing budgets admit the request.
+	Budgets *budget.Registry
+
+	// MaxConcurrent bounds in-flight requests per method (a bulkhead). Zero disables it.
+	MaxConcurrent int
+
+	// Pushback is the retry delay advertised to clients via PushbackTrailer. Default 1s;
+	// negative tells clients not to retry.
+	Pushback time.Duration
+}
+
+// UnaryServerInterceptor returns a server interceptor that sheds load for a method when its
+// circuit is open, its concurrency limit is reached, or its budget denies admission.
+//
+// Shed requests fail with RESOURCE_EXHAUSTED and carry PushbackTrailer so well-behaved
+// clients back off. Handler errors with server-side codes (Unavailable, Internal,
+// DeadlineExceeded, ...) and handler panics count as circuit failures.
+//
+// The handler's context is bounded by the client's remaining deadline when it sent
+// MetadataTimeout (see IncomingDeadline).
+func UnaryServerInterceptor(opts ServerOptions) grpc.UnaryServerInterceptor {
+	s := newShedder(opts)
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		key := s.keyFunc(info.FullMethod)
+
+		done, reason := s.admit(ctx, key)
+		if reason != "" {
+			_ = grpc.SetTrailer(ctx, metadata.Pairs(PushbackTrailer, s.pushback))
+			return nil, status.Error(codes.ResourceExhausted, "recourse: "+reason)
+		}
+
+		if d, ok := IncomingDeadline(ctx); ok {
+			var cancel context.CancelFunc
+			ctx, cancel = deadline.Apply(ctx, d)
+			defer cancel()
+		}
+		// A panicking handler still releases its admission, as a server failure.
+		err = status.Error(codes.Internal, "recourse: handler panicked")
+		defer func() { done(err) }()
+		resp, err = handler(ctx, req)
+		return resp, err
+	}
+}
+
+type shedder struct {
+	keyFunc   func(method string) policy.PolicyKey
+	admission *admission.Controller
+	pushback  string
+}
+
+func newShedder(opts ServerOptions) *shedder {
+	s := &shedder{
+		keyFunc: opts.KeyFunc,
+		admission: admission.New(admission.Options{
+			Provider:      opts.Provider,
+			Circuits:      opts.Circuits,
+			Budgets:       opts.Budgets,
+			MaxConcurrent: opts.MaxConcurrent,
+		}),
+	}
+	if s.keyFunc == nil {
+		s.keyFunc = DefaultKeyFunc
+	}
+	pushback := opts.Pushback
+	if pushback == 0 {
+		pushback = defaultServerPushback
+	}
+	if pushback < 0 {
+		s.pushback = "-1"
+	} else {
+		s.pushback = strconv.FormatInt(pushback.Milliseconds(), 10)
+	}
+	return s
+}
+
+// admit runs the admission checks for key. On success it returns a completion
+// callback; otherwise it returns the shedding reason.
+func (s *shedder) admit(ctx context.Context, key policy.PolicyKey) (func(error), string) {
+	done, reason := s.admission.Admit(ctx, key)
+	if reason != "" {
+		return nil, reason
+	}
+	return func(err error) { done(isServerFailure(err)) }, ""
+}
+
+// isServerFailure reports whether err indicates the server (not the caller) failed.
+func isServerFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	switch status.Code(err) {
+	case codes.Unavailable, codes.Internal, codes.Unknown, codes.DeadlineExceeded, codes.DataLoss, codes.ResourceExhausted:
+		return true
+	default:
+		return false
+	}
+}
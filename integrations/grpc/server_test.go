@@ -0,0 +1,247 @@
+package grpc_test
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/deadline"
+	integration "github.com/aponysus/recourse/integrations/grpc"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+var serviceInfo = &grpc.UnaryServerInfo{FullMethod: "/Service/Method"}
+
+func TestUnaryServerInterceptor_ShedsWhenCircuitOpen(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "Service", Name: "Method"}
+	interceptor := integration.UnaryServerInterceptor(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 2, Cooldown: time.Minute}},
+		}},
+	})
+
+	calls := 0
+	failing := func(context.Context, any) (any, error) {
+		calls++
+		return nil, status.Error(codes.Unavailable, "down")
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := interceptor(context.Background(), nil, serviceInfo, failing); status.Code(err) != codes.Unavailable {
+			t.Fatalf("call %d: err=%v, want Unavailable", i, err)
+		}
+	}
+
+	_, err := interceptor(context.Background(), nil, serviceInfo, failing)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err=%v, want ResourceExhausted", err)
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls=%d, want 2", calls)
+	}
+}
+
+func TestUnaryServerInterceptor_ConcurrencyLimit(t *testing.T) {
+	interceptor := integration.UnaryServerInterceptor(integration.ServerOptions{MaxConcurrent: 1})
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := func(context.Context, any) (any, error) {
+		close(entered)
+		<-unblock
+		return "ok", nil
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := interceptor(context.Background(), nil, serviceInfo, blocking)
+		done <- err
+	}()
+	<-entered
+
+	_, err := interceptor(context.Background(), nil, serviceInfo, func(context.Context, any) (any, error) {
+		return "ok", nil
+	})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err=%v, want ResourceExhausted", err)
+	}
+
+	close(unblock)
+	if err := <-done; err != nil {
+		t.Fatalf("first call err=%v", err)
+	}
+
+	// Slot released: the next call is admitted.
+	if _, err := interceptor(context.Background(), nil, serviceInfo, func(context.Context, any) (any, error) {
+		return "ok", nil
+	}); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestUnaryServerInterceptor_HandlerPanic(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "Service", Name: "Method"}
+	interceptor := integration.UnaryServerInterceptor(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 2, Cooldown: time.Minute}},
+		}},
+		MaxConcurrent: 1,
+	})
+
+	panicking := func(context.Context, any) (any, error) { panic("boom") }
+	call := func() (recovered any) {
+		defer func() { recovered = recover() }()
+		_, _ = interceptor(context.Background(), nil, serviceInfo, panicking)
+		return nil
+	}
+	if r := call(); r != "boom" {
+		t.Fatalf("recovered %v, want the handler's panic", r)
+	}
+
+	// The slot was released; the second panic opens the circuit (threshold 2).
+	if r := call(); r != "boom" {
+		t.Fatalf("second call recovered %v, want the handler's panic (slot released)", r)
+	}
+	_, err := interceptor(context.Background(), nil, serviceInfo, func(context.Context, any) (any, error) { return "ok", nil })
+	if status.Convert(err).Message() != "recourse: "+integration.ReasonShedCircuitOpen {
+		t.Fatalf("err=%v, want %s", err, integration.ReasonShedCircuitOpen)
+	}
+}
+
+func TestUnaryServerInterceptor_ShedProbeFreesSlot(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "Service", Name: "Method"}
+	circuitPol := policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: 10 * time.Millisecond}
+	circuits := circuit.NewRegistry()
+	interceptor := integration.UnaryServerInterceptor(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: circuitPol},
+		}},
+		Circuits:      circuits,
+		MaxConcurrent: 1,
+	})
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, _ = interceptor(context.Background(), nil, serviceInfo, func(context.Context, any) (any, error) {
+			close(entered)
+			<-unblock
+			return "ok", nil
+		})
+	}()
+	<-entered
+	defer func() {
+		close(unblock)
+		<-done
+	}()
+
+	// Open the breaker and let it half-open while the concurrency limit is full.
+	cb := circuits.Get(key, circuitPol)
+	cb.RecordFailure(context.Background())
+	time.Sleep(2 * circuitPol.Cooldown)
+
+	_, err := interceptor(context.Background(), nil, serviceInfo, func(context.Context, any) (any, error) { return "ok", nil })
+	if status.Convert(err).Message() != "recourse: "+integration.ReasonShedConcurrency {
+		t.Fatalf("err=%v, want %s", err, integration.ReasonShedConcurrency)
+	}
+	if d := cb.Allow(context.Background()); !d.Allowed || d.State != circuit.StateHalfOpen {
+		t.Fatalf("Allow after the shed probe = %+v, want the probe slot free", d)
+	}
+}
+
+func TestUnaryServerInterceptor_BudgetDenied(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "Service", Name: "Method"}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("admission", budget.NewTokenBucketBudget(1, 0))
+
+	interceptor := integration.UnaryServerInterceptor(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{Budget: policy.BudgetRef{Name: "admission", Cost: 1}}},
+		}},
+		Budgets: budgets,
+	})
+
+	ok := func(context.Context, any) (any, error) { return "ok", nil }
+	if _, err := interceptor(context.Background(), nil, serviceInfo, ok); err != nil {
+		t.Fatalf("first call err=%v", err)
+	}
+	_, err := interceptor(context.Background(), nil, serviceInfo, ok)
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err=%v, want ResourceExhausted", err)
+	}
+}
+
+func TestUnaryServerInterceptor_SetsPushbackTrailer(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "grpc.health.v1.Health", Name: "Check"}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("closed", budget.NewTokenBucketBudget(0, 0))
+
+	lis := bufconn.Listen(1 << 16)
+	srv := grpc.NewServer(grpc.UnaryInterceptor(integration.UnaryServerInterceptor(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{Budget: policy.BudgetRef{Name: "closed", Cost: 1}}},
+		}},
+		Budgets:  budgets,
+		Pushback: 250 * time.Millisecond,
+	})))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go srv.Serve(lis)
+	defer srv.Stop()
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer conn.Close()
+
+	var trailer metadata.MD
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{}, grpc.Trailer(&trailer))
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Fatalf("err=%v, want ResourceExhausted", err)
+	}
+	if got := trailer.Get(integration.PushbackTrailer); len(got) != 1 || got[0] != "250" {
+		t.Fatalf("pushback trailer=%v, want [250]", got)
+	}
+}
+
+func TestUnaryServerInterceptor_DeadlineFromClient(t *testing.T) {
+	exec := retry.NewDefaultExecutor(retry.WithPolicy("Service.Method", policy.MaxAttempts(1), policy.PerAttemptTimeout(2*time.Second)))
+	client := integration.UnaryClientInterceptor(exec, nil)
+	server := integration.UnaryServerInterceptor(integration.ServerOptions{})
+
+	var remaining time.Duration
+	invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		// Hand the outgoing metadata to the server without gRPC's own deadline.
+		md, _ := metadata.FromOutgoingContext(ctx)
+		_, err := server(metadata.NewIncomingContext(context.Background(), md), nil, serviceInfo, func(ctx context.Context, _ any) (any, error) {
+			remaining, _ = deadline.Remaining(ctx)
+			return nil, nil
+		})
+		return err
+	}
+	if err := client(context.Background(), "/Service/Method", nil, nil, nil, invoker); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if remaining <= 0 || remaining > 2*time.Second {
+		t.Fatalf("handler deadline = %v, want within the client's 2s attempt timeout", remaining)
+	}
+}
@@ -0,0 +1,64 @@
+package grpc
+
+import (
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy/grpcconfig"
+	"github.com/aponysus/recourse/retry"
+)
+
+// CodesClassifier retries exactly the listed gRPC status codes, as a service config's
+// retryableStatusCodes (or nonFatalStatusCodes) does. Other codes are terminal, and
+// non-gRPC errors are delegated to the AutoClassifier.
+type CodesClassifier struct {
+	Retryable map[codes.Code]struct{}
+}
+
+func (c CodesClassifier) Classify(val any, err error) classify.Outcome {
+	if err == nil || status.Code(err) == codes.OK {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+
+	code := st.Code()
+	outcome := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     codeReason(code),
+		Attributes: map[string]string{"grpc_code": code.String()},
+	}
+	if _, ok := c.Retryable[code]; ok {
+		outcome.Kind = classify.OutcomeRetryable
+	} else if code == codes.Canceled {
+		outcome.Kind = classify.OutcomeAbort
+		outcome.Reason = "context_canceled"
+	}
+	return outcome
+}
+
+// ServiceConfigOptions returns executor options that apply a parsed gRPC service config:
+// the config as policy provider, plus a CodesClassifier for each status code set its
+// policies reference.
+func ServiceConfigOptions(cfg *grpcconfig.Config) []retry.ExecutorOption {
+	opts := []retry.ExecutorOption{retry.WithProvider(cfg)}
+	for name, names := range cfg.Classifiers() {
+		opts = append(opts, retry.WithClassifier(name, codesClassifier(names)))
+	}
+	return opts
+}
+
+func codesClassifier(names []string) CodesClassifier {
+	c := CodesClassifier{Retryable: make(map[codes.Code]struct{}, len(names))}
+	for _, name := range names {
+		var code codes.Code
+		// Names come from grpcconfig in canonical form, which UnmarshalJSON accepts quoted.
+		if code.UnmarshalJSON([]byte(`"`+name+`"`)) == nil {
+			c.Retryable[code] = struct{}{}
+		}
+	}
+	return c
+}
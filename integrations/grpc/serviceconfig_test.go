@@ -0,0 +1,70 @@
+package grpc_test
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/aponysus/recourse/classify"
+	integration "github.com/aponysus/recourse/integrations/grpc"
+	"github.com/aponysus/recourse/policy/grpcconfig"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestServiceConfigOptions(t *testing.T) {
+	cfg, err := grpcconfig.Parse([]byte(`{"methodConfig": [{
+		"name": [{"service": "acme.Users"}],
+		"retryPolicy": {
+			"maxAttempts": 3,
+			"initialBackoff": "0.001s",
+			"maxBackoff": "0.001s",
+			"backoffMultiplier": 1,
+			"retryableStatusCodes": ["ABORTED"]
+		}
+	}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := retry.NewDefaultExecutor(integration.ServiceConfigOptions(cfg)...)
+	interceptor := integration.UnaryClientInterceptor(exec, nil)
+
+	run := func(code codes.Code) int {
+		attempts := 0
+		invoker := func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			attempts++
+			return status.Error(code, "fail")
+		}
+		_ = interceptor(context.Background(), "/acme.Users/Get", nil, nil, nil, invoker)
+		return attempts
+	}
+
+	if got := run(codes.Aborted); got != 3 {
+		t.Errorf("ABORTED: expected 3 attempts, got %d", got)
+	}
+	// UNAVAILABLE is retryable by default, but not listed in this config.
+	if got := run(codes.Unavailable); got != 1 {
+		t.Errorf("UNAVAILABLE: expected 1 attempt, got %d", got)
+	}
+}
+
+func TestCodesClassifier(t *testing.T) {
+	c := integration.CodesClassifier{Retryable: map[codes.Code]struct{}{codes.Aborted: {}}}
+
+	tests := []struct {
+		err  error
+		want classify.OutcomeKind
+	}{
+		{nil, classify.OutcomeSuccess},
+		{status.Error(codes.Aborted, "x"), classify.OutcomeRetryable},
+		{status.Error(codes.Unavailable, "x"), classify.OutcomeNonRetryable},
+		{status.Error(codes.Canceled, "x"), classify.OutcomeAbort},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(nil, tt.err).Kind; got != tt.want {
+			t.Errorf("Classify(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
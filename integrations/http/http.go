@@ -6,6 +6,7 @@ import (
 	"io"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/aponysus/recourse/observe"
@@ -15,8 +16,14 @@ import (
 
 // DoHTTP executes an HTTP request with retries.
 // It automatically handles request cloning, body draining/closing on retryable errors,
-// and status code classification.
+// and status code classification. Each attempt carries HeaderAttempt and HeaderHedge so
+// servers can tell retries apart (see Middleware).
 func DoHTTP(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, client *http.Client, req *http.Request) (*http.Response, observe.Timeline, error) {
+	return doHTTP(ctx, exec, key, client, req, nil)
+}
+
+// doHTTP implements DoHTTP; prepare, if set, adjusts each attempt's request.
+func doHTTP(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, client *http.Client, req *http.Request, prepare func(context.Context, *http.Request)) (*http.Response, observe.Timeline, error) {
 	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
 		return nil, observe.Timeline{}, errors.New("recourse: request body is not replayable (GetBody is nil)")
 	}
@@ -31,6 +38,10 @@ func DoHTTP(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, cli
 			}
 			outReq.Body = body
 		}
+		SetAttemptHeaders(ctx, outReq.Header)
+		if prepare != nil {
+			prepare(ctx, outReq)
+		}
 
 		resp, err := client.Do(outReq)
 		if err != nil {
@@ -91,28 +102,61 @@ func (e *StatusError) Unwrap() error { return e.Err }
 func (e *StatusError) HTTPStatusCode() int { return e.Code }
 func (e *StatusError) HTTPMethod() string  { return e.Method }
 
+// RetryAfter returns the server-provided retry delay from the response headers.
+// See ParseRetryAfter.
 func (e *StatusError) RetryAfter() (time.Duration, bool) {
-	if e.Header == nil {
-		return 0, false
-	}
-	s := e.Header.Get("Retry-After")
-	if s == "" {
+	return ParseRetryAfter(e.Header, time.Now())
+}
+
+// ParseRetryAfter extracts a retry delay hint from response headers.
+//
+// It reads Retry-After (delay-seconds or HTTP-date) and falls back to RateLimit-Reset
+// and X-RateLimit-Reset (delta-seconds, or a Unix timestamp for large values).
+// Dates in the past yield a zero delay.
+func ParseRetryAfter(h http.Header, now time.Time) (time.Duration, bool) {
+	if h == nil {
 		return 0, false
 	}
 
-	// Try seconds
-	if secs, err := strconv.Atoi(s); err == nil && secs >= 0 {
-		return time.Duration(secs) * time.Second, true
+	if s := strings.TrimSpace(h.Get("Retry-After")); s != "" {
+		// Try seconds
+		if secs, err := strconv.ParseInt(s, 10, 64); err == nil && secs >= 0 {
+			return time.Duration(secs) * time.Second, true
+		}
+
+		// Try HTTP date
+		if t, err := http.ParseTime(s); err == nil {
+			return untilOrZero(t, now), true
+		}
 	}
 
-	// Try HTTP date
-	if t, err := http.ParseTime(s); err == nil {
-		d := time.Until(t)
-		if d < 0 {
-			d = 0
+	for _, name := range []string{"RateLimit-Reset", "X-RateLimit-Reset"} {
+		s := strings.TrimSpace(h.Get(name))
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			continue
+		}
+		// Some APIs send an absolute reset time instead of delta-seconds.
+		if n >= unixResetThreshold {
+			return untilOrZero(time.Unix(n, 0), now), true
 		}
-		return d, true
+		return time.Duration(n) * time.Second, true
 	}
 
 	return 0, false
 }
+
+// unixResetThreshold separates delta-seconds from Unix timestamps in reset headers
+// (~1 year of seconds; no real window is that long).
+const unixResetThreshold = 365 * 24 * 60 * 60
+
+func untilOrZero(t, now time.Time) time.Duration {
+	d := t.Sub(now)
+	if d < 0 {
+		d = 0
+	}
+	return d
+}
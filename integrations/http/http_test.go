@@ -2,10 +2,12 @@ package http_test
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"strconv"
 	"strings"
 	"testing"
 	"time"
@@ -98,6 +100,67 @@ func TestDoHTTP_RespectsRetryAfter(t *testing.T) {
 	}
 }
 
+func TestDoHTTP_RecordsRetryAfterInTimeline(t *testing.T) {
+	attempts := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts == 1 {
+			w.Header().Set("RateLimit-Reset", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	// MaxBackoff caps the actual sleep so the test stays fast; the hint is still recorded.
+	exec := retry.NewDefaultExecutor(
+		retry.WithPolicy("test", policy.MaxBackoff(10*time.Millisecond)),
+	)
+	req, _ := http.NewRequest("GET", server.URL, nil)
+	_, tl, err := integration.DoHTTP(context.Background(), exec, policy.PolicyKey{Name: "test"}, server.Client(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(tl.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %d", len(tl.Attempts))
+	}
+	if tl.Attempts[0].RetryAfter != time.Second {
+		t.Errorf("RetryAfter=%v, want 1s", tl.Attempts[0].RetryAfter)
+	}
+	if tl.Attempts[1].RetryAfter != 0 {
+		t.Errorf("second attempt RetryAfter=%v, want 0", tl.Attempts[1].RetryAfter)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name   string
+		header http.Header
+		want   time.Duration
+		ok     bool
+	}{
+		{"none", http.Header{}, 0, false},
+		{"seconds", http.Header{"Retry-After": {"7"}}, 7 * time.Second, true},
+		{"http date", http.Header{"Retry-After": {now.Add(90 * time.Second).Format(http.TimeFormat)}}, 90 * time.Second, true},
+		{"past date", http.Header{"Retry-After": {now.Add(-time.Minute).Format(http.TimeFormat)}}, 0, true},
+		{"invalid retry-after falls back", http.Header{"Retry-After": {"soon"}, "Ratelimit-Reset": {"4"}}, 4 * time.Second, true},
+		{"ratelimit reset", http.Header{"Ratelimit-Reset": {"12"}}, 12 * time.Second, true},
+		{"x-ratelimit reset unix", http.Header{"X-Ratelimit-Reset": {strconv.FormatInt(now.Add(30*time.Second).Unix(), 10)}}, 30 * time.Second, true},
+		{"retry-after wins", http.Header{"Retry-After": {"1"}, "Ratelimit-Reset": {"60"}}, time.Second, true},
+		{"negative", http.Header{"Ratelimit-Reset": {"-1"}}, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := integration.ParseRetryAfter(tt.header, now)
+			if got != tt.want || ok != tt.ok {
+				t.Fatalf("got %v/%v, want %v/%v", got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
 func TestDoHTTP_DrainsAndClosesExample(t *testing.T) {
 	// Ensure response bodies are drained/closed to avoid leaks.
 
@@ -147,8 +210,8 @@ func TestDoHTTP_NonRetryableStatus(t *testing.T) {
 		t.Fatal("expected error for 404")
 	}
 
-	st, ok := err.(*integration.StatusError)
-	if !ok || st.Code != 404 {
+	var st *integration.StatusError
+	if !errors.As(err, &st) || st.Code != 404 {
 		t.Errorf("expected 404 StatusError, got %v", err)
 	}
 
@@ -0,0 +1,269 @@
+package http
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/deadline"
+	"github.com/aponysus/recourse/internal/admission"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Request headers set by DoHTTP (and integrations/httpclient) on every attempt.
+const (
+	// HeaderAttempt carries the 0-based attempt index.
+	HeaderAttempt = "X-Recourse-Attempt"
+	// HeaderHedge carries the hedge index within the attempt group (0 for the primary).
+	HeaderHedge = "X-Recourse-Hedge"
+	// HeaderPolicyID carries the policy ID, when the policy sets one.
+	HeaderPolicyID = "X-Recourse-Policy-Id"
+	// HeaderTimeout carries the attempt's remaining deadline in milliseconds, when its
+	// context has one (see the deadline package).
+	HeaderTimeout = "X-Recourse-Timeout-Ms"
+	// HeaderIdempotencyKey carries the key shared by all attempts of a hedged group, when
+	// the policy sets Hedge.IdempotencyKeys, so servers can deduplicate them.
+	HeaderIdempotencyKey = "Idempotency-Key"
+
+	// HeaderRetryAttempt is a generic attempt header, read by IncomingAttempt when
+	// HeaderAttempt is absent so non-recourse clients can participate.
+	HeaderRetryAttempt = "X-Retry-Attempt"
+)
+
+// Shedding reasons written in the body of shed responses.
+const (
+	ReasonShedCircuitOpen  = admission.ReasonCircuitOpen
+	ReasonShedConcurrency  = admission.ReasonConcurrency
+	ReasonShedBudgetDenied = admission.ReasonBudgetDenied
+)
+
+const defaultServerRetryAfter = time.Second
+
+// SetAttemptHeaders sets HeaderAttempt, HeaderHedge, HeaderPolicyID, and
+// HeaderIdempotencyKey from the attempt info in ctx, and HeaderTimeout from its
+// deadline. It does nothing outside an executor attempt.
+func SetAttemptHeaders(ctx context.Context, h http.Header) {
+	info, ok := observe.AttemptFromContext(ctx)
+	if !ok {
+		return
+	}
+	h.Set(HeaderAttempt, strconv.Itoa(info.Attempt))
+	h.Set(HeaderHedge, strconv.Itoa(info.HedgeIndex))
+	if info.PolicyID != "" {
+		h.Set(HeaderPolicyID, info.PolicyID)
+	}
+	if info.IdempotencyKey != "" {
+		h.Set(HeaderIdempotencyKey, info.IdempotencyKey)
+	}
+	SetDeadlineHeader(ctx, h)
+}
+
+// SetDeadlineHeader sets HeaderTimeout to the time left on ctx's deadline. It does
+// nothing if ctx has no deadline.
+func SetDeadlineHeader(ctx context.Context, h http.Header) {
+	if d, ok := deadline.Remaining(ctx); ok {
+		h.Set(HeaderTimeout, deadline.Format(d))
+	}
+}
+
+// IncomingDeadline returns the remaining deadline sent by the client in HeaderTimeout.
+func IncomingDeadline(r *http.Request) (time.Duration, bool) {
+	d, err := deadline.Parse(r.Header.Get(HeaderTimeout))
+	return d, err == nil
+}
+
+// WithIncomingDeadline returns r's context bounded by the client's remaining deadline,
+// if it sent one. The cancel func must be called.
+func WithIncomingDeadline(r *http.Request) (context.Context, context.CancelFunc) {
+	if d, ok := IncomingDeadline(r); ok {
+		return deadline.Apply(r.Context(), d)
+	}
+	return context.WithCancel(r.Context())
+}
+
+// IncomingAttempt returns the attempt info sent by a retrying client.
+func IncomingAttempt(r *http.Request) (observe.AttemptInfo, bool) {
+	v := r.Header.Get(HeaderAttempt)
+	if v == "" {
+		v = r.Header.Get(HeaderRetryAttempt)
+	}
+	if v == "" {
+		return observe.AttemptInfo{}, false
+	}
+	attempt, err := strconv.Atoi(v)
+	if err != nil || attempt < 0 {
+		return observe.AttemptInfo{}, false
+	}
+
+	info := observe.AttemptInfo{Attempt: attempt, RetryIndex: attempt}
+	if idx, err := strconv.Atoi(r.Header.Get(HeaderHedge)); err == nil && idx > 0 {
+		info.IsHedge = true
+		info.HedgeIndex = idx
+	}
+	info.PolicyID = r.Header.Get(HeaderPolicyID)
+	info.IdempotencyKey = r.Header.Get(HeaderIdempotencyKey)
+	return info, true
+}
+
+// DefaultServerKeyFunc maps requests to policy keys by their ServeMux pattern:
+// "GET /users/{id}" -> {Namespace: "http", Name: "GET /users/{id}"}. Requests that
+// were not routed by a ServeMux (for example when the middleware wraps the mux itself)
+// share {Namespace: "http", Name: "*"}.
+func DefaultServerKeyFunc(r *http.Request) policy.PolicyKey {
+	if r.Pattern != "" {
+		return policy.PolicyKey{Namespace: "http", Name: r.Pattern}
+	}
+	return policy.PolicyKey{Namespace: "http", Name: "*"}
+}
+
+// ServerOptions configures Middleware and NewShedder.
+type ServerOptions struct {
+	// KeyFunc maps requests to policy keys. Defaults to DefaultServerKeyFunc.
+	// Keys should have bounded cardinality: each one gets its own breaker and limit.
+	KeyFunc func(r *http.Request) policy.PolicyKey
+
+	// Provider resolves the policy for each key. Its Circuit settings enable a per-key
+	// breaker and its Retry.Budget names the admission budget.
+	// If nil, only the concurrency limit applies.
+	Provider controlplane.PolicyProvider
+	// Circuits holds per-key breakers. Defaults to a new registry.
+	Circuits *circuit.Registry
+	// Budgets resolves Retry.Budget references. Missing budgets admit the request.
+	Budgets *budget.Registry
+
+	// MaxConcurrent bounds in-flight requests per key (a bulkhead). Zero disables it.
+	MaxConcurrent int
+
+	// RetryAfter is the delay advertised in shed responses' Retry-After header
+	// (rounded up to whole seconds). Default 1s; negative omits the header.
+	RetryAfter time.Duration
+}
+
+// Middleware returns net/http middleware (also usable with chi and other routers that
+// accept func(http.Handler) http.Handler) that:
+//
+//   - stores the attempt info sent by retrying clients (see IncomingAttempt) in the
+//     request context, where handlers read it with observe.AttemptFromContext,
+//   - bounds the request context by the client's remaining deadline (see
+//     IncomingDeadline), so executors in the handler retry within it, and
+//   - sheds load for a key when its circuit is open, its concurrency limit is reached,
+//     or its budget denies admission, answering 429 Too Many Requests with Retry-After.
+//
+// 5xx and 429 responses from the handler (and panics) count as circuit failures.
+func Middleware(opts ServerOptions) func(http.Handler) http.Handler {
+	s := NewShedder(opts)
+	keyFunc := opts.KeyFunc
+	if keyFunc == nil {
+		keyFunc = DefaultServerKeyFunc
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if info, ok := IncomingAttempt(r); ok {
+				r = r.WithContext(observe.WithAttemptInfo(r.Context(), info))
+			}
+			if d, ok := IncomingDeadline(r); ok {
+				ctx, cancel := deadline.Apply(r.Context(), d)
+				defer cancel()
+				r = r.WithContext(ctx)
+			}
+
+			done, reason := s.Admit(r.Context(), keyFunc(r))
+			if reason != "" {
+				s.WriteShed(w, reason)
+				return
+			}
+
+			sw := &statusWriter{ResponseWriter: w}
+			status := http.StatusInternalServerError // if the handler panics
+			defer func() { done(status) }()
+			next.ServeHTTP(sw, r)
+			status = sw.Status()
+		})
+	}
+}
+
+// Shedder makes the admission decisions behind Middleware, for frameworks whose
+// middleware is not func(http.Handler) http.Handler. It is safe for concurrent use.
+type Shedder struct {
+	admission  *admission.Controller
+	retryAfter string
+}
+
+// NewShedder returns a Shedder. opts.KeyFunc is not used.
+func NewShedder(opts ServerOptions) *Shedder {
+	s := &Shedder{admission: admission.New(admission.Options{
+		Provider:      opts.Provider,
+		Circuits:      opts.Circuits,
+		Budgets:       opts.Budgets,
+		MaxConcurrent: opts.MaxConcurrent,
+	})}
+	retryAfter := opts.RetryAfter
+	if retryAfter == 0 {
+		retryAfter = defaultServerRetryAfter
+	}
+	if retryAfter > 0 {
+		secs := int64((retryAfter + time.Second - 1) / time.Second)
+		s.retryAfter = strconv.FormatInt(secs, 10)
+	}
+	return s
+}
+
+// Admit runs the admission checks for key. If the request is admitted it returns a
+// completion callback, which must be called with the response status; otherwise it
+// returns the shedding reason.
+func (s *Shedder) Admit(ctx context.Context, key policy.PolicyKey) (done func(status int), reason string) {
+	admitted, reason := s.admission.Admit(ctx, key)
+	if reason != "" {
+		return nil, reason
+	}
+	return func(status int) { admitted(isServerFailure(status)) }, ""
+}
+
+// WriteShed writes the 429 response for a request shed with reason.
+func (s *Shedder) WriteShed(w http.ResponseWriter, reason string) {
+	if s.retryAfter != "" {
+		w.Header().Set("Retry-After", s.retryAfter)
+	}
+	http.Error(w, "recourse: "+reason, http.StatusTooManyRequests)
+}
+
+// isServerFailure reports whether status indicates the server (not the caller) failed.
+func isServerFailure(status int) bool {
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// statusWriter records the response status. Unwrap lets http.ResponseController reach
+// the underlying writer's Flush, Hijack, and deadline methods.
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(code int) {
+	if w.status == 0 {
+		w.status = code
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *statusWriter) Write(b []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *statusWriter) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+// Status returns the status written so far (200 if the handler wrote nothing).
+func (w *statusWriter) Status() int {
+	if w.status == 0 {
+		return http.StatusOK
+	}
+	return w.status
+}
@@ -0,0 +1,213 @@
+package http_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/deadline"
+	integration "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestMiddleware_ShedsWhenCircuitOpen(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "http", Name: "GET /items"}
+	mw := integration.Middleware(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 2, Cooldown: time.Minute}},
+		}},
+		RetryAfter: 1500 * time.Millisecond,
+	})
+
+	calls := 0
+	mux := http.NewServeMux()
+	mux.Handle("GET /items", mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})))
+
+	for i := 0; i < 3; i++ {
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+		if i < 2 && rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("call %d: status=%d, want 503", i, rec.Code)
+		}
+		if i == 2 {
+			if rec.Code != http.StatusTooManyRequests {
+				t.Fatalf("status=%d, want 429", rec.Code)
+			}
+			if got := rec.Header().Get("Retry-After"); got != "2" {
+				t.Fatalf("Retry-After=%q, want 2", got)
+			}
+		}
+	}
+	if calls != 2 {
+		t.Fatalf("handler calls=%d, want 2", calls)
+	}
+}
+
+func TestMiddleware_ConcurrencyLimit(t *testing.T) {
+	mw := integration.Middleware(integration.ServerOptions{MaxConcurrent: 1})
+
+	entered := make(chan struct{})
+	unblock := make(chan struct{})
+	blocking := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(entered)
+		<-unblock
+	}))
+	ok := mw(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	done := make(chan int, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		blocking.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+		done <- rec.Code
+	}()
+	<-entered
+
+	rec := httptest.NewRecorder()
+	ok.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") != "1" {
+		t.Fatalf("status=%d Retry-After=%q, want 429 with Retry-After 1", rec.Code, rec.Header().Get("Retry-After"))
+	}
+
+	close(unblock)
+	if code := <-done; code != http.StatusOK {
+		t.Fatalf("blocked request status=%d, want 200", code)
+	}
+
+	rec = httptest.NewRecorder()
+	ok.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status=%d after release, want 200", rec.Code)
+	}
+}
+
+func TestMiddleware_ShedProbeFreesSlot(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "http", Name: "GET /items"}
+	circuitPol := policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: 10 * time.Millisecond}
+	circuits := circuit.NewRegistry()
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("closed", budget.NewTokenBucketBudget(0, 0))
+	mw := integration.Middleware(integration.ServerOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Circuit: circuitPol, Retry: policy.RetryPolicy{Budget: policy.BudgetRef{Name: "closed", Cost: 1}}},
+		}},
+		Circuits: circuits,
+		Budgets:  budgets,
+	})
+	mux := http.NewServeMux()
+	mux.Handle("GET /items", mw(http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})))
+
+	// Half-open the breaker; the budget then sheds the probe it admits.
+	cb := circuits.Get(key, circuitPol)
+	cb.RecordFailure(context.Background())
+	time.Sleep(2 * circuitPol.Cooldown)
+
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/items", nil))
+	if rec.Code != http.StatusTooManyRequests || !strings.Contains(rec.Body.String(), integration.ReasonShedBudgetDenied) {
+		t.Fatalf("status=%d body=%q, want 429 %s", rec.Code, rec.Body.String(), integration.ReasonShedBudgetDenied)
+	}
+	if d := cb.Allow(context.Background()); !d.Allowed || d.State != circuit.StateHalfOpen {
+		t.Fatalf("Allow after the shed probe = %+v, want the probe slot free", d)
+	}
+}
+
+func TestMiddleware_AttemptInfoFromClient(t *testing.T) {
+	var infos []observe.AttemptInfo
+	server := httptest.NewServer(integration.Middleware(integration.ServerOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		info, _ := observe.AttemptFromContext(r.Context())
+		infos = append(infos, info)
+		if len(infos) < 2 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})))
+	defer server.Close()
+
+	key := policy.PolicyKey{Name: "attempts"}
+	exec := retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(2), policy.Backoff(time.Microsecond, time.Microsecond, 1)))
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, _, err := integration.DoHTTP(context.Background(), exec, key, server.Client(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if len(infos) != 2 || infos[0].Attempt != 0 || infos[1].Attempt != 1 || infos[1].IsHedge {
+		t.Fatalf("unexpected attempt infos: %+v", infos)
+	}
+}
+
+func TestIncomingAttempt_GenericHeader(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := integration.IncomingAttempt(req); ok {
+		t.Fatal("expected no attempt info without headers")
+	}
+
+	req.Header.Set(integration.HeaderRetryAttempt, "2")
+	req.Header.Set(integration.HeaderHedge, "1")
+	info, ok := integration.IncomingAttempt(req)
+	if !ok || info.Attempt != 2 || !info.IsHedge || info.HedgeIndex != 1 {
+		t.Fatalf("unexpected attempt info: %+v %v", info, ok)
+	}
+}
+
+func TestAttemptHeaders_IdempotencyKey(t *testing.T) {
+	ctx := observe.WithAttemptInfo(context.Background(), observe.AttemptInfo{Attempt: 1, IsHedge: true, HedgeIndex: 1, IdempotencyKey: "abc"})
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	integration.SetAttemptHeaders(ctx, req.Header)
+	if got := req.Header.Get(integration.HeaderIdempotencyKey); got != "abc" {
+		t.Fatalf("%s = %q, want abc", integration.HeaderIdempotencyKey, got)
+	}
+	info, ok := integration.IncomingAttempt(req)
+	if !ok || info.IdempotencyKey != "abc" {
+		t.Fatalf("unexpected attempt info: %+v %v", info, ok)
+	}
+}
+
+func TestMiddleware_DeadlineFromClient(t *testing.T) {
+	remaining := make(chan time.Duration, 1)
+	server := httptest.NewServer(integration.Middleware(integration.ServerOptions{})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		d, _ := deadline.Remaining(r.Context())
+		remaining <- d
+	})))
+	defer server.Close()
+
+	key := policy.PolicyKey{Name: "deadline"}
+	exec := retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(1), policy.PerAttemptTimeout(2*time.Second)))
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, _, err := integration.DoHTTP(context.Background(), exec, key, server.Client(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+
+	if d := <-remaining; d <= 0 || d > 2*time.Second {
+		t.Fatalf("server deadline = %v, want within the client's 2s attempt timeout", d)
+	}
+}
+
+func TestIncomingDeadline(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	if _, ok := integration.IncomingDeadline(req); ok {
+		t.Fatal("expected no deadline without the header")
+	}
+	req.Header.Set(integration.HeaderTimeout, "1500")
+	if d, ok := integration.IncomingDeadline(req); !ok || d != 1500*time.Millisecond {
+		t.Fatalf("IncomingDeadline = %v, %v; want 1.5s", d, ok)
+	}
+	ctx, cancel := integration.WithIncomingDeadline(req)
+	defer cancel()
+	if d, ok := deadline.Remaining(ctx); !ok || d > 1500*time.Millisecond {
+		t.Fatalf("WithIncomingDeadline remaining = %v, %v", d, ok)
+	}
+}
@@ -0,0 +1,227 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// TargetStrategy selects which base URL each attempt of a call is sent to.
+type TargetStrategy int
+
+const (
+	// TargetPriority sends the primary attempt to the first base URL and hedge i to the
+	// (i+1)th, wrapping around. Use it for a preferred replica or region with fallbacks.
+	TargetPriority TargetStrategy = iota
+
+	// TargetRoundRobin rotates the primary attempt across base URLs call by call; hedges
+	// go to the targets following the call's primary.
+	TargetRoundRobin
+)
+
+// Targets is an ordered set of alternate base URLs for hedged attempts.
+//
+// Each attempt's request URL keeps its path and query but takes the scheme and host
+// (and any path prefix) of the selected base URL. Retries of a call stay on the call's
+// primary target; only hedges move to alternates. A Targets is safe for concurrent use.
+type Targets struct {
+	strategy TargetStrategy
+	bases    []*url.URL
+	next     atomic.Uint64
+
+	stickyWindow time.Duration
+	mu           sync.Mutex
+	sticky       map[policy.PolicyKey]stickyTarget
+}
+
+// stickyTarget is the target a hedge won on, preferred until the deadline.
+type stickyTarget struct {
+	idx   int
+	until time.Time
+}
+
+// NewTargets returns a target set over baseURLs, which must be absolute URLs.
+func NewTargets(strategy TargetStrategy, baseURLs ...string) (*Targets, error) {
+	if len(baseURLs) == 0 {
+		return nil, errors.New("recourse: at least one target base URL is required")
+	}
+	t := &Targets{strategy: strategy, bases: make([]*url.URL, 0, len(baseURLs))}
+	for _, s := range baseURLs {
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, fmt.Errorf("recourse: invalid target %q: %w", s, err)
+		}
+		if u.Scheme == "" || u.Host == "" {
+			return nil, fmt.Errorf("recourse: target %q must be an absolute URL", s)
+		}
+		t.bases = append(t.bases, u)
+	}
+	return t, nil
+}
+
+// Sticky makes the targets remember, per key, the target of a hedge that won a call,
+// and send the primary attempts of the key's calls there for window after the win.
+// Hedges then go to the targets following it. A slow or failing preferred target is
+// left as soon as a hedge beats it again, and the preference decays after window, so
+// routing returns to the strategy's choice once the original primary recovers.
+// A window <= 0 disables stickiness. Sticky returns t and should be called before t is
+// used.
+func (t *Targets) Sticky(window time.Duration) *Targets {
+	t.stickyWindow = window
+	return t
+}
+
+// Preferred returns the base URL that key's primaries are sent to because a hedge won
+// there, if a sticky preference is active.
+func (t *Targets) Preferred(key policy.PolicyKey) (string, bool) {
+	idx, ok := t.preferred(key)
+	if !ok {
+		return "", false
+	}
+	return t.bases[idx].String(), true
+}
+
+func (t *Targets) preferred(key policy.PolicyKey) (int, bool) {
+	if t.stickyWindow <= 0 {
+		return 0, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	st, ok := t.sticky[key]
+	if !ok {
+		return 0, false
+	}
+	if !time.Now().Before(st.until) {
+		delete(t.sticky, key)
+		return 0, false
+	}
+	return st.idx, true
+}
+
+// start returns the index of the primary target for a new call for key.
+func (t *Targets) start(key policy.PolicyKey) int {
+	if idx, ok := t.preferred(key); ok {
+		return idx
+	}
+	if t.strategy != TargetRoundRobin {
+		return 0
+	}
+	return int((t.next.Add(1) - 1) % uint64(len(t.bases)))
+}
+
+// observe records the target of the winning attempt in tl, for a call whose primary
+// target was start, when a hedge won.
+func (t *Targets) observe(key policy.PolicyKey, start int, tl observe.Timeline) {
+	if t.stickyWindow <= 0 || tl.FinalErr != nil {
+		return
+	}
+	for _, rec := range tl.Attempts {
+		if rec.Outcome.Kind != classify.OutcomeSuccess {
+			continue
+		}
+		if !rec.IsHedge {
+			return
+		}
+		idx := (start + rec.HedgeIndex) % len(t.bases)
+		t.mu.Lock()
+		if t.sticky == nil {
+			t.sticky = make(map[policy.PolicyKey]stickyTarget)
+		}
+		t.sticky[key] = stickyTarget{idx: idx, until: time.Now().Add(t.stickyWindow)}
+		t.mu.Unlock()
+		return
+	}
+}
+
+// target returns the base URL for the attempt described by ctx, for a call whose
+// primary target is start.
+func (t *Targets) target(ctx context.Context, start int) *url.URL {
+	idx := start
+	if info, ok := observe.AttemptFromContext(ctx); ok && info.IsHedge {
+		idx += info.HedgeIndex
+	}
+	return t.bases[idx%len(t.bases)]
+}
+
+// rewrite points req at base, preserving req's path and query.
+func rewrite(req *http.Request, base *url.URL) {
+	u := *req.URL
+	u.Scheme = base.Scheme
+	u.Host = base.Host
+	if prefix := strings.TrimSuffix(base.Path, "/"); prefix != "" {
+		u.Path = prefix + "/" + strings.TrimPrefix(u.Path, "/")
+		u.RawPath = ""
+	}
+	req.URL = &u
+	// Let the Host header follow the target.
+	req.Host = ""
+}
+
+// TargetRegistry holds Targets per policy key.
+//
+// Lookups try the exact key, then the namespace-wide entry ({Namespace: ns}).
+// A TargetRegistry is safe for concurrent use.
+type TargetRegistry struct {
+	mu    sync.RWMutex
+	byKey map[policy.PolicyKey]*Targets
+}
+
+// NewTargetRegistry returns an empty registry.
+func NewTargetRegistry() *TargetRegistry {
+	return &TargetRegistry{byKey: make(map[policy.PolicyKey]*Targets)}
+}
+
+// Register sets the targets for key. Use a key with an empty Name to configure every
+// key in a namespace. A nil t removes the entry.
+func (r *TargetRegistry) Register(key policy.PolicyKey, t *Targets) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t == nil {
+		delete(r.byKey, key)
+		return
+	}
+	r.byKey[key] = t
+}
+
+// Get returns the targets for key, if any.
+func (r *TargetRegistry) Get(key policy.PolicyKey) (*Targets, bool) {
+	if r == nil {
+		return nil, false
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if t, ok := r.byKey[key]; ok {
+		return t, true
+	}
+	t, ok := r.byKey[policy.PolicyKey{Namespace: key.Namespace}]
+	return t, ok
+}
+
+// DoHTTPTargets is DoHTTP with attempts spread across the targets registered for key.
+// Keys without targets behave exactly like DoHTTP. With Targets.Sticky, a call won by a
+// hedge moves key's later primaries to the winning target.
+//
+// Hedging must be enabled in key's policy for alternate targets to receive traffic.
+func DoHTTPTargets(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, client *http.Client, req *http.Request, targets *TargetRegistry) (*http.Response, observe.Timeline, error) {
+	t, ok := targets.Get(key)
+	if !ok {
+		return DoHTTP(ctx, exec, key, client, req)
+	}
+	start := t.start(key)
+	resp, tl, err := doHTTP(ctx, exec, key, client, req, func(ctx context.Context, outReq *http.Request) {
+		rewrite(outReq, t.target(ctx, start))
+	})
+	t.observe(key, start, tl)
+	return resp, tl, err
+}
@@ -0,0 +1,191 @@
+package http_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestDoHTTPTargets_HedgeGoesToAlternate(t *testing.T) {
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+			return
+		}
+		fmt.Fprint(w, "slow")
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "fast"+r.URL.Path)
+	}))
+	defer fast.Close()
+
+	key := policy.PolicyKey{Namespace: "users", Name: "get"}
+	exec := retry.NewExecutorFromOptions(retry.ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {
+				Retry: policy.RetryPolicy{MaxAttempts: 1},
+				Hedge: policy.HedgePolicy{Enabled: true, MaxHedges: 1, HedgeDelay: 20 * time.Millisecond},
+			},
+		}},
+	})
+
+	targets, err := integration.NewTargets(integration.TargetPriority, slow.URL, fast.URL+"/v2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg := integration.NewTargetRegistry()
+	reg.Register(policy.PolicyKey{Namespace: "users"}, targets)
+
+	req, _ := http.NewRequest(http.MethodGet, "http://placeholder/users/1", nil)
+	start := time.Now()
+	resp, _, err := integration.DoHTTPTargets(context.Background(), exec, key, http.DefaultClient, req, reg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "fast/v2/users/1" {
+		t.Fatalf("got body %q, want response from alternate target", body)
+	}
+	if time.Since(start) > time.Second {
+		t.Fatal("expected hedge to avoid the slow target")
+	}
+}
+
+func TestDoHTTPTargets_RoundRobin(t *testing.T) {
+	var hits [2]int
+	servers := make([]string, 2)
+	for i := range servers {
+		i := i
+		s := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hits[i]++
+		}))
+		defer s.Close()
+		servers[i] = s.URL
+	}
+
+	targets, err := integration.NewTargets(integration.TargetRoundRobin, servers...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := policy.PolicyKey{Name: "rr"}
+	reg := integration.NewTargetRegistry()
+	reg.Register(key, targets)
+
+	exec := retry.NewDefaultExecutor()
+	for i := 0; i < 4; i++ {
+		req, _ := http.NewRequest(http.MethodGet, "http://placeholder/", nil)
+		resp, _, err := integration.DoHTTPTargets(context.Background(), exec, key, http.DefaultClient, req, reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+	if hits[0] != 2 || hits[1] != 2 {
+		t.Fatalf("expected calls to alternate between targets, got %v", hits)
+	}
+}
+
+func TestDoHTTPTargets_UnregisteredKeyUsesRequestURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "direct")
+	}))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL, nil)
+	resp, _, err := integration.DoHTTPTargets(context.Background(), retry.NewDefaultExecutor(), policy.PolicyKey{Name: "none"}, server.Client(), req, integration.NewTargetRegistry())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "direct" {
+		t.Fatalf("got body %q", body)
+	}
+}
+
+func TestNewTargets_Errors(t *testing.T) {
+	if _, err := integration.NewTargets(integration.TargetPriority); err == nil {
+		t.Fatal("expected error for empty target list")
+	}
+	if _, err := integration.NewTargets(integration.TargetPriority, "/relative"); err == nil || !strings.Contains(err.Error(), "absolute") {
+		t.Fatalf("expected absolute URL error, got %v", err)
+	}
+}
+
+func TestDoHTTPTargets_StickyAfterHedgeWin(t *testing.T) {
+	var slowHits, fastHits atomic.Int32
+	slow := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		slowHits.Add(1)
+		select {
+		case <-time.After(2 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer slow.Close()
+	fast := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fastHits.Add(1)
+	}))
+	defer fast.Close()
+
+	key := policy.PolicyKey{Namespace: "users", Name: "get"}
+	exec := retry.NewExecutorFromOptions(retry.ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {
+				Retry: policy.RetryPolicy{MaxAttempts: 1},
+				Hedge: policy.HedgePolicy{Enabled: true, MaxHedges: 1, HedgeDelay: 100 * time.Millisecond},
+			},
+		}},
+	})
+
+	const window = 300 * time.Millisecond
+	targets, err := integration.NewTargets(integration.TargetPriority, slow.URL, fast.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	targets.Sticky(window)
+	reg := integration.NewTargetRegistry()
+	reg.Register(key, targets)
+
+	call := func() {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodGet, "http://placeholder/", nil)
+		resp, _, err := integration.DoHTTPTargets(context.Background(), exec, key, http.DefaultClient, req, reg)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	call() // The hedge to fast wins.
+	if got, ok := targets.Preferred(key); !ok || got != fast.URL {
+		t.Fatalf("Preferred=%q,%v, want %q", got, ok, fast.URL)
+	}
+
+	slowHits.Store(0)
+	fastHits.Store(0)
+	start := time.Now()
+	call() // The primary goes straight to fast; no hedge is needed.
+	if time.Since(start) > 80*time.Millisecond || slowHits.Load() != 0 || fastHits.Load() != 1 {
+		t.Fatalf("slow=%d fast=%d after %v, want the primary on fast", slowHits.Load(), fastHits.Load(), time.Since(start))
+	}
+
+	time.Sleep(window)
+	if _, ok := targets.Preferred(key); ok {
+		t.Fatal("expected the preference to decay after the window")
+	}
+}
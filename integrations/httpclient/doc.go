@@ -0,0 +1,21 @@
+// Package httpclient provides an http.RoundTripper that runs outgoing requests through a
+// recourse executor.
+//
+// Usage:
+//
+//	client := &http.Client{
+//		Transport: httpclient.NewTransport(exec, httpclient.Options{
+//			Key: httpclient.Routes(map[string]policy.PolicyKey{
+//				"GET api.example.com/users/": recourse.ParseKey("users.Get"),
+//				"PUT api.example.com/users/": recourse.ParseKey("users.Put"),
+//			}),
+//		}),
+//	}
+//
+// The transport clones the request for each attempt, replays the body via GetBody (buffering
+// bodies that are not replayable), drains and closes responses of failed attempts, and only
+// retries requests that are safe to repeat. Non-2xx responses are classified as failures so
+// that HTTP classifiers can decide on retries; if the call still fails, the last response is
+// returned to the caller unchanged, as any RoundTripper would. Like DoHTTP, each attempt
+// carries the X-Recourse-Attempt and X-Recourse-Hedge headers.
+package httpclient
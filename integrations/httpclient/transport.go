@@ -0,0 +1,336 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultMaxBufferedBody is the default limit for buffering request bodies that have no GetBody.
+const DefaultMaxBufferedBody = 1 << 20
+
+// maxDrain bounds how much of a failed response body is read before closing it.
+const maxDrain = 4096
+
+// KeyFunc maps an outgoing request to a policy key.
+// Returning false sends the request once through the base transport, bypassing the executor.
+type KeyFunc func(*http.Request) (policy.PolicyKey, bool)
+
+// Options configures a Transport.
+type Options struct {
+	// Base is the underlying transport. Defaults to http.DefaultTransport.
+	Base http.RoundTripper
+
+	// Key maps requests to policy keys. Defaults to HostKey.
+	Key KeyFunc
+
+	// RetryNonIdempotent allows retries for requests whose method is not idempotent
+	// (POST, PATCH, ...). Such requests are otherwise sent exactly once, unless they
+	// carry an Idempotency-Key header.
+	RetryNonIdempotent bool
+
+	// MaxBufferedBody bounds how many bytes of a non-replayable request body are buffered
+	// so it can be resent. Larger bodies are sent once without retries.
+	// Defaults to DefaultMaxBufferedBody; negative disables buffering.
+	MaxBufferedBody int64
+
+	// AttemptError, if set, builds the error a failed attempt reports to the executor in
+	// place of *recoursehttp.StatusError, e.g. to classify on an API's error body. resp is
+	// the non-2xx response, or nil when the base transport failed with err. It may read
+	// resp.Body if it leaves the body readable from the start. Errors with an
+	// HTTPStatusCode method (see classify.HTTPError) let the transport return resp when
+	// the call gives up on its status.
+	AttemptError func(req *http.Request, resp *http.Response, err error) error
+}
+
+// Transport is an http.RoundTripper that executes requests with a recourse executor.
+type Transport struct {
+	exec          *retry.Executor
+	base          http.RoundTripper
+	key           KeyFunc
+	nonIdempotent bool
+	maxBuffered   int64
+	attemptError  func(req *http.Request, resp *http.Response, err error) error
+}
+
+// NewTransport returns a Transport that runs requests through exec.
+// If exec is nil, the global default executor is used.
+func NewTransport(exec *retry.Executor, opts Options) *Transport {
+	t := &Transport{
+		exec:          exec,
+		base:          opts.Base,
+		key:           opts.Key,
+		nonIdempotent: opts.RetryNonIdempotent,
+		maxBuffered:   opts.MaxBufferedBody,
+		attemptError:  opts.AttemptError,
+	}
+	if t.base == nil {
+		t.base = http.DefaultTransport
+	}
+	if t.key == nil {
+		t.key = HostKey
+	}
+	if t.maxBuffered == 0 {
+		t.maxBuffered = DefaultMaxBufferedBody
+	}
+	return t
+}
+
+// HostKey keys requests by host: {Namespace: "http", Name: <host>}.
+func HostKey(req *http.Request) (policy.PolicyKey, bool) {
+	return policy.PolicyKey{Namespace: "http", Name: requestHost(req)}, true
+}
+
+// Routes returns a KeyFunc that matches requests against http.ServeMux patterns
+// ("[METHOD ][HOST]/[PATH]") and returns the key of the most specific match.
+// Requests that match no pattern bypass the executor.
+func Routes(routes map[string]policy.PolicyKey) KeyFunc {
+	mux := http.NewServeMux()
+	noop := http.HandlerFunc(func(http.ResponseWriter, *http.Request) {})
+	for pattern := range routes {
+		mux.Handle(pattern, noop)
+	}
+	return func(req *http.Request) (policy.PolicyKey, bool) {
+		probe := &http.Request{Method: req.Method, URL: req.URL, Host: requestHost(req)}
+		_, pattern := mux.Handler(probe)
+		key, ok := routes[pattern]
+		return key, ok
+	}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	key, ok := t.key(req)
+	if !ok || !t.retryable(req) {
+		return t.base.RoundTrip(req)
+	}
+
+	req, ok, err := t.replayable(req)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	exec := t.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+
+	// Hedged attempts can each produce a response, and a losing attempt can finish after
+	// the call returned. Every response is tracked here so all but the one returned to
+	// the caller are drained and closed.
+	var (
+		mu        sync.Mutex
+		held      *http.Response   // last failed response, kept in case the call gives up
+		succeeded []*http.Response // 2xx responses; the executor returns at most one
+		finished  bool             // the call returned; late responses are discarded
+	)
+	hold := func(resp *http.Response) {
+		mu.Lock()
+		prev := held
+		if finished {
+			prev = resp
+		} else {
+			held = resp
+		}
+		mu.Unlock()
+		discard(prev)
+	}
+	succeed := func(resp *http.Response) bool {
+		mu.Lock()
+		defer mu.Unlock()
+		if !finished {
+			succeeded = append(succeeded, resp)
+		}
+		return !finished
+	}
+
+	attemptError := t.attemptError
+	if attemptError == nil {
+		method := classificationMethod(req)
+		attemptError = func(_ *http.Request, resp *http.Response, err error) error {
+			if resp == nil {
+				return &recoursehttp.StatusError{Err: err, Method: method}
+			}
+			return &recoursehttp.StatusError{Code: resp.StatusCode, Method: method, Header: resp.Header}
+		}
+	}
+	op := func(ctx context.Context) (*http.Response, error) {
+		attemptCtx, detach, cancel := attemptContext(ctx, req.Context())
+
+		out := req.Clone(attemptCtx)
+		if req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				cancel()
+				return nil, err
+			}
+			out.Body = body
+		}
+		recoursehttp.SetAttemptHeaders(ctx, out.Header)
+
+		resp, err := t.base.RoundTrip(out)
+		if err != nil {
+			cancel()
+			return nil, attemptError(req, nil, err)
+		}
+
+		// The response outlives the attempt: stop executor cancellation from reaching it
+		// and release the context when the caller closes the body.
+		detach()
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			if !succeed(resp) {
+				discard(resp)
+				return nil, context.Canceled
+			}
+			return resp, nil
+		}
+		err = attemptError(req, resp, nil)
+		hold(resp)
+		return nil, err
+	}
+
+	resp, err := retry.DoValue(req.Context(), exec, key, op)
+
+	mu.Lock()
+	finished = true
+	last := held
+	held = nil
+	others := succeeded
+	succeeded = nil
+	mu.Unlock()
+	for _, r := range others {
+		if err != nil || r != resp {
+			discard(r)
+		}
+	}
+
+	if err == nil {
+		discard(last)
+		return resp, nil
+	}
+
+	var se interface{ HTTPStatusCode() int }
+	if last != nil && errors.As(err, &se) && se.HTTPStatusCode() == last.StatusCode {
+		return last, nil
+	}
+	discard(last)
+	return nil, err
+}
+
+// retryable reports whether req may be sent more than once.
+func (t *Transport) retryable(req *http.Request) bool {
+	if t.nonIdempotent || isIdempotent(req.Method) {
+		return true
+	}
+	return req.Header.Get("Idempotency-Key") != "" || req.Header.Get("X-Idempotency-Key") != ""
+}
+
+// replayable ensures req has a GetBody, buffering the body if needed.
+// It returns false (and a request whose body can be sent once) if the body is too large.
+func (t *Transport) replayable(req *http.Request) (*http.Request, bool, error) {
+	if req.Body == nil || req.Body == http.NoBody || req.GetBody != nil {
+		return req, true, nil
+	}
+	if t.maxBuffered < 0 {
+		return req, false, nil
+	}
+
+	buf, err := io.ReadAll(io.LimitReader(req.Body, t.maxBuffered+1))
+	if err != nil {
+		req.Body.Close()
+		return nil, false, err
+	}
+
+	out := req.Clone(req.Context())
+	if int64(len(buf)) > t.maxBuffered {
+		out.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(buf), req.Body), req.Body}
+		return out, false, nil
+	}
+
+	req.Body.Close()
+	out.ContentLength = int64(len(buf))
+	out.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(buf)), nil
+	}
+	out.Body, _ = out.GetBody()
+	return out, true, nil
+}
+
+// attemptContext derives the context for one attempt. It carries the values of the
+// attempt context and is canceled with it until detach is called; after that, only
+// the caller's request context (or cancel) ends it.
+func attemptContext(attempt, caller context.Context) (ctx context.Context, detach, cancel func()) {
+	ctx, cancelFn := context.WithCancel(context.WithoutCancel(attempt))
+	stopAttempt := context.AfterFunc(attempt, cancelFn)
+	stopCaller := context.AfterFunc(caller, cancelFn)
+	cancel = func() {
+		stopAttempt()
+		stopCaller()
+		cancelFn()
+	}
+	return ctx, func() { stopAttempt() }, cancel
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel func()
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+func discard(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	_, _ = io.CopyN(io.Discard, resp.Body, maxDrain)
+	resp.Body.Close()
+}
+
+// classificationMethod returns the method reported to HTTP classifiers. Requests that
+// reached the executor were already judged safe to repeat, so non-idempotent methods
+// are reported as PUT to keep classify.HTTPClassifier from refusing retries.
+func classificationMethod(req *http.Request) string {
+	if isIdempotent(req.Method) {
+		return req.Method
+	}
+	return http.MethodPut
+}
+
+func isIdempotent(method string) bool {
+	switch strings.ToUpper(method) {
+	case "", http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete, http.MethodOptions, http.MethodTrace:
+		return true
+	default:
+		return false
+	}
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	if req.URL != nil {
+		return req.URL.Host
+	}
+	return ""
+}
@@ -0,0 +1,293 @@
+package httpclient
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func newExec(key policy.PolicyKey, attempts int) *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(attempts),
+		policy.Backoff(time.Millisecond, time.Millisecond, 1),
+		policy.OverallTimeout(5*time.Second),
+	))
+}
+
+func TestTransport_RetriesAndReplaysBody(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if string(body) != "payload" {
+			t.Errorf("attempt %d body=%q, want payload", calls.Load(), body)
+		}
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			io.WriteString(w, "unavailable")
+			return
+		}
+		io.WriteString(w, "ok")
+	}))
+	defer srv.Close()
+
+	key := policy.PolicyKey{Namespace: "http", Name: "put"}
+	client := &http.Client{Transport: NewTransport(newExec(key, 3), Options{
+		Base: srv.Client().Transport,
+		Key:  func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+	})}
+
+	// A plain io.Reader leaves GetBody nil, so the transport must buffer it.
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, io.MultiReader(strings.NewReader("payload")))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// The body must stay readable after the executor has cancelled its contexts.
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "ok" {
+		t.Fatalf("body=%q err=%v, want ok", body, err)
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("calls=%d, want 3", got)
+	}
+}
+
+func TestTransport_ReturnsLastResponseWhenExhausted(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+		io.WriteString(w, "still down")
+	}))
+	defer srv.Close()
+
+	key := policy.PolicyKey{Namespace: "http", Name: "get"}
+	client := &http.Client{Transport: NewTransport(newExec(key, 2), Options{
+		Base: srv.Client().Transport,
+		Key:  func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+	})}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status=%d, want 503", resp.StatusCode)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "still down" {
+		t.Fatalf("body=%q, want still down", body)
+	}
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("calls=%d, want 2", got)
+	}
+}
+
+type apiError struct{ code int }
+
+func (e *apiError) Error() string       { return "api error" }
+func (e *apiError) HTTPStatusCode() int { return e.code }
+
+func TestTransport_AttemptError(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusConflict)
+		io.WriteString(w, "conflict")
+	}))
+	defer srv.Close()
+
+	key := policy.PolicyKey{Namespace: "http", Name: "conflict"}
+	var built atomic.Int32
+	client := &http.Client{Transport: NewTransport(newExec(key, 3), Options{
+		Base: srv.Client().Transport,
+		Key:  func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+		AttemptError: func(_ *http.Request, resp *http.Response, err error) error {
+			built.Add(1)
+			if resp == nil {
+				return err
+			}
+			return &apiError{code: resp.StatusCode}
+		},
+	})}
+
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if body, _ := io.ReadAll(resp.Body); resp.StatusCode != http.StatusConflict || string(body) != "conflict" {
+		t.Fatalf("status=%d body=%q, want the last 409 response", resp.StatusCode, body)
+	}
+	if calls.Load() != 3 || built.Load() != 3 {
+		t.Fatalf("calls=%d errors built=%d, want 3 each", calls.Load(), built.Load())
+	}
+}
+
+func TestTransport_NonIdempotentGating(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	key := policy.PolicyKey{Namespace: "http", Name: "post"}
+	client := &http.Client{Transport: NewTransport(newExec(key, 3), Options{
+		Base: srv.Client().Transport,
+		Key:  func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+	})}
+
+	resp, err := client.Post(srv.URL, "text/plain", strings.NewReader("x"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if got := calls.Swap(0); got != 1 {
+		t.Fatalf("POST calls=%d, want 1", got)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, strings.NewReader("x"))
+	req.Header.Set("Idempotency-Key", "abc")
+	resp, err = client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("POST with Idempotency-Key calls=%d, want 3", got)
+	}
+}
+
+func TestTransport_LargeBodySentOnce(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		body, _ := io.ReadAll(r.Body)
+		if len(body) != 32 {
+			t.Errorf("body len=%d, want 32", len(body))
+		}
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	key := policy.PolicyKey{Namespace: "http", Name: "put"}
+	client := &http.Client{Transport: NewTransport(newExec(key, 3), Options{
+		Base:            srv.Client().Transport,
+		Key:             func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+		MaxBufferedBody: 16,
+	})}
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL, io.MultiReader(strings.NewReader(strings.Repeat("a", 32))))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	resp.Body.Close()
+	if got := calls.Load(); got != 1 {
+		t.Fatalf("calls=%d, want 1", got)
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// trackedBody records whether it was closed.
+type trackedBody struct {
+	io.Reader
+	closed chan struct{}
+}
+
+func (b *trackedBody) Close() error {
+	close(b.closed)
+	return nil
+}
+
+func TestTransport_ClosesLosingHedgeResponses(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "http", Name: "hedged"}
+	exec := retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(1),
+		policy.EnableHedging(), policy.HedgeMaxAttempts(1), policy.HedgeDelay(time.Millisecond),
+	))
+
+	release := make(chan struct{})
+	bodies := make(chan *trackedBody, 2)
+	var calls atomic.Int32
+	base := roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		name := "hedge"
+		if calls.Add(1) == 1 {
+			name = "primary"
+			<-release // The primary loses the race but still completes with a 200.
+		}
+		body := &trackedBody{Reader: strings.NewReader(name), closed: make(chan struct{})}
+		bodies <- body
+		return &http.Response{StatusCode: http.StatusOK, Body: body, Request: req}, nil
+	})
+	client := &http.Client{Transport: NewTransport(exec, Options{
+		Base: base,
+		Key:  func(*http.Request) (policy.PolicyKey, bool) { return key, true },
+	})}
+
+	resp, err := client.Get("http://hedged.example/")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	winner := <-bodies
+	body, _ := io.ReadAll(resp.Body)
+	if string(body) != "hedge" {
+		t.Fatalf("body=%q, want the hedge's response", body)
+	}
+
+	close(release)
+	loser := <-bodies
+	select {
+	case <-loser.closed:
+	case <-time.After(5 * time.Second):
+		t.Fatal("losing response body was not closed")
+	}
+	select {
+	case <-winner.closed:
+		t.Fatal("winning response body closed before the caller closed it")
+	default:
+	}
+	resp.Body.Close()
+}
+
+func TestRoutes(t *testing.T) {
+	users := policy.PolicyKey{Namespace: "users", Name: "Get"}
+	orders := policy.PolicyKey{Namespace: "orders", Name: "Any"}
+	keyFn := Routes(map[string]policy.PolicyKey{
+		"GET api.example.com/users/": users,
+		"api.example.com/orders/":    orders,
+	})
+
+	tests := []struct {
+		method, url string
+		want        policy.PolicyKey
+		ok          bool
+	}{
+		{http.MethodGet, "https://api.example.com/users/42", users, true},
+		{http.MethodDelete, "https://api.example.com/orders/7", orders, true},
+		{http.MethodPost, "https://api.example.com/users/42", policy.PolicyKey{}, false},
+		{http.MethodGet, "https://other.example.com/users/42", policy.PolicyKey{}, false},
+	}
+	for _, tt := range tests {
+		req, _ := http.NewRequest(tt.method, tt.url, nil)
+		got, ok := keyFn(req)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("%s %s: got %v/%v, want %v/%v", tt.method, tt.url, got, ok, tt.want, tt.ok)
+		}
+	}
+}
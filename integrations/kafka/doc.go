@@ -0,0 +1,20 @@
+// Package kafka provides opt-in Kafka producer integrations for recourse, built on franz-go.
+//
+// Producer wraps synchronous produce calls so delivery retries run under recourse policies
+// (keyed per topic) with the Kafka classifier, instead of the client's opaque internal retries:
+//
+//	cl, _ := kgo.NewClient(
+//		kgo.SeedBrokers("localhost:9092"),
+//		kgo.RecordRetries(1),                       // let recourse own retries
+//		kgo.RecordDeliveryTimeout(5*time.Second),
+//	)
+//	exec := retry.NewDefaultExecutor(kafka.WithClassifier())
+//	p := kafka.NewProducer(cl, exec, nil)
+//
+//	results := p.ProduceSync(ctx, &kgo.Record{Topic: "orders", Value: payload})
+//	if err := results.FirstErr(); err != nil {
+//		// handle
+//	}
+//
+// This package lives in a separate module to keep the franz-go dependency opt-in.
+package kafka
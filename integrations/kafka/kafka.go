@@ -0,0 +1,168 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// SyncProducer is the subset of *kgo.Client used by Producer.
+type SyncProducer interface {
+	ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults
+}
+
+// DefaultKeyFunc maps topics to policy keys.
+// "orders" -> {Namespace: "kafka", Name: "orders"}
+func DefaultKeyFunc(topic string) policy.PolicyKey {
+	return policy.PolicyKey{Namespace: "kafka", Name: topic}
+}
+
+// Producer retries synchronous produce calls using a recourse executor.
+type Producer struct {
+	client  SyncProducer
+	exec    *retry.Executor
+	keyFunc func(topic string) policy.PolicyKey
+}
+
+// NewProducer returns a Producer that sends records through client.
+// If exec is nil, the default executor is used; if keyFunc is nil, DefaultKeyFunc is used.
+func NewProducer(client SyncProducer, exec *retry.Executor, keyFunc func(topic string) policy.PolicyKey) *Producer {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return &Producer{client: client, exec: exec, keyFunc: keyFunc}
+}
+
+// ProduceSync produces rs and waits for delivery, retrying failed records.
+//
+// Records are grouped by topic and each group runs as one call under the topic's policy
+// key. Only records that failed are produced again on the next attempt. The results are
+// returned in the order of rs; a record's Err is its final error (wrapped by the executor
+// when the call fails).
+func (p *Producer) ProduceSync(ctx context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	results := make(kgo.ProduceResults, len(rs))
+
+	var topics []string
+	byTopic := make(map[string][]int)
+	for i, r := range rs {
+		results[i].Record = r
+		if _, ok := byTopic[r.Topic]; !ok {
+			topics = append(topics, r.Topic)
+		}
+		byTopic[r.Topic] = append(byTopic[r.Topic], i)
+	}
+
+	for _, topic := range topics {
+		p.produceTopic(ctx, topic, rs, byTopic[topic], results)
+	}
+	return results
+}
+
+func (p *Producer) produceTopic(ctx context.Context, topic string, rs []*kgo.Record, idx []int, results kgo.ProduceResults) {
+	exec := p.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+
+	// pending holds the records still to be produced. Hedged attempts may run
+	// concurrently, so it is guarded by mu.
+	var mu sync.Mutex
+	pending := idx
+	err := exec.Do(ctx, p.keyFunc(topic), func(ctx context.Context) error {
+		mu.Lock()
+		attempt := pending
+		mu.Unlock()
+
+		batch := make([]*kgo.Record, len(attempt))
+		for i, j := range attempt {
+			batch[i] = rs[j]
+		}
+		produced := p.client.ProduceSync(ctx, batch...)
+
+		mu.Lock()
+		defer mu.Unlock()
+		var failed []int
+		var firstErr error
+		for i, res := range produced {
+			j := attempt[i]
+			results[j].Err = res.Err
+			if res.Err != nil {
+				failed = append(failed, j)
+				if firstErr == nil {
+					firstErr = res.Err
+				}
+			}
+		}
+		pending = failed
+		return firstErr
+	})
+	if err == nil {
+		return
+	}
+
+	// Records that were never delivered (including records no attempt was allowed
+	// to send) report the call's final error.
+	mu.Lock()
+	defer mu.Unlock()
+	for _, j := range pending {
+		results[j].Err = err
+	}
+}
+
+// Classifier implements classify.Classifier for franz-go produce errors.
+type Classifier struct{}
+
+func (Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "context_deadline_exceeded"}
+	}
+
+	switch {
+	case errors.Is(err, kgo.ErrRecordTimeout):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "kafka_record_timeout"}
+	case errors.Is(err, kgo.ErrRecordRetries):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "kafka_record_retries"}
+	case errors.Is(err, kgo.ErrMaxBuffered):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "kafka_max_buffered"}
+	case errors.Is(err, kgo.ErrClientClosed), errors.Is(err, kgo.ErrAborting):
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "kafka_client_closed"}
+	}
+
+	var ke *kerr.Error
+	if !errors.As(err, &ke) {
+		return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "kafka_error"}
+	}
+
+	out := classify.Outcome{
+		Kind:   classify.OutcomeNonRetryable,
+		Reason: "kafka_error",
+		Attributes: map[string]string{
+			"kafka_error": ke.Message,
+			"kafka_code":  strconv.Itoa(int(ke.Code)),
+		},
+	}
+	if ke.Retriable {
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "kafka_retriable_error"
+	}
+	return out
+}
+
+// WithClassifier returns an option to register the Kafka classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
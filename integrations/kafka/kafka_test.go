@@ -0,0 +1,129 @@
+package kafka_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kgo"
+
+	"github.com/aponysus/recourse/classify"
+	integration "github.com/aponysus/recourse/integrations/kafka"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// fakeClient fails each record value according to a script of errors.
+type fakeClient struct {
+	failures map[string][]error
+	sent     []string
+}
+
+func (c *fakeClient) ProduceSync(_ context.Context, rs ...*kgo.Record) kgo.ProduceResults {
+	out := make(kgo.ProduceResults, len(rs))
+	for i, r := range rs {
+		v := string(r.Value)
+		c.sent = append(c.sent, r.Topic+"/"+v)
+		out[i].Record = r
+		if errs := c.failures[v]; len(errs) > 0 {
+			c.failures[v] = errs[1:]
+			out[i].Err = errs[0]
+		}
+	}
+	return out
+}
+
+func newExec(opts ...policy.Option) *retry.Executor {
+	base := []policy.Option{policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Millisecond, 1)}
+	return retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicyKey(policy.PolicyKey{Namespace: "kafka", Name: "orders"}, append(base, opts...)...),
+		retry.WithPolicyKey(policy.PolicyKey{Namespace: "kafka", Name: "audit"}, append(base, opts...)...),
+	)
+}
+
+func TestProducer_RetriesOnlyFailedRecords(t *testing.T) {
+	client := &fakeClient{failures: map[string][]error{
+		"b": {kerr.NotLeaderForPartition},
+	}}
+	p := integration.NewProducer(client, newExec(), nil)
+
+	results := p.ProduceSync(context.Background(),
+		&kgo.Record{Topic: "orders", Value: []byte("a")},
+		&kgo.Record{Topic: "orders", Value: []byte("b")},
+		&kgo.Record{Topic: "audit", Value: []byte("c")},
+	)
+	if err := results.FirstErr(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	want := []string{"orders/a", "orders/b", "orders/b", "audit/c"}
+	if len(client.sent) != len(want) {
+		t.Fatalf("sent=%v, want %v", client.sent, want)
+	}
+	for i := range want {
+		if client.sent[i] != want[i] {
+			t.Fatalf("sent=%v, want %v", client.sent, want)
+		}
+	}
+	if string(results[2].Record.Value) != "c" {
+		t.Fatalf("results out of order: %v", results)
+	}
+}
+
+func TestProducer_NonRetriableErrorReturned(t *testing.T) {
+	client := &fakeClient{failures: map[string][]error{
+		"a": {kerr.MessageTooLarge},
+	}}
+	p := integration.NewProducer(client, newExec(), nil)
+
+	results := p.ProduceSync(context.Background(), &kgo.Record{Topic: "orders", Value: []byte("a")})
+	if !errors.Is(results[0].Err, kerr.MessageTooLarge) {
+		t.Fatalf("err=%v, want MessageTooLarge", results[0].Err)
+	}
+	if len(client.sent) != 1 {
+		t.Fatalf("sent=%v, want one attempt", client.sent)
+	}
+}
+
+func TestProducer_ExhaustedAttempts(t *testing.T) {
+	client := &fakeClient{failures: map[string][]error{
+		"a": {kgo.ErrRecordTimeout, kgo.ErrRecordTimeout, kgo.ErrRecordTimeout},
+	}}
+	p := integration.NewProducer(client, newExec(), nil)
+
+	results := p.ProduceSync(context.Background(), &kgo.Record{Topic: "orders", Value: []byte("a")})
+	if !errors.Is(results[0].Err, retry.ErrAttemptsExhausted) || !errors.Is(results[0].Err, kgo.ErrRecordTimeout) {
+		t.Fatalf("err=%v, want exhausted record timeout", results[0].Err)
+	}
+	if len(client.sent) != 3 {
+		t.Fatalf("sent=%v, want 3 attempts", client.sent)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	c := integration.Classifier{}
+	tests := []struct {
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{nil, classify.OutcomeSuccess, "success"},
+		{context.Canceled, classify.OutcomeAbort, "context_canceled"},
+		{kgo.ErrRecordTimeout, classify.OutcomeRetryable, "kafka_record_timeout"},
+		{kgo.ErrRecordRetries, classify.OutcomeRetryable, "kafka_record_retries"},
+		{kgo.ErrMaxBuffered, classify.OutcomeRetryable, "kafka_max_buffered"},
+		{kgo.ErrClientClosed, classify.OutcomeAbort, "kafka_client_closed"},
+		{kerr.NotEnoughReplicas, classify.OutcomeRetryable, "kafka_retriable_error"},
+		{kerr.TopicAuthorizationFailed, classify.OutcomeNonRetryable, "kafka_error"},
+		{errors.New("boom"), classify.OutcomeNonRetryable, "kafka_error"},
+	}
+	for _, tt := range tests {
+		out := c.Classify(nil, tt.err)
+		if out.Kind != tt.kind || out.Reason != tt.reason {
+			t.Errorf("Classify(%v)=%v/%q, want %v/%q", tt.err, out.Kind, out.Reason, tt.kind, tt.reason)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package kubernetes provides a recourse policy provider backed by a Kubernetes
+// ConfigMap, so retry policy can be managed with GitOps alongside deployments.
+//
+// Each data entry of the ConfigMap is one policy, named by its key:
+//
+//	apiVersion: v1
+//	kind: ConfigMap
+//	metadata:
+//	  name: recourse-policies
+//	data:
+//	  payments.Charge: '{"retry": {"max_attempts": 4}}'
+//
+// The provider watches the ConfigMap with an informer:
+//
+//	p := kubernetes.NewProvider(clientset, kubernetes.Options{Namespace: "payments"})
+//	go p.Run(ctx)
+//	exec := retry.NewDefaultExecutor(retry.WithProvider(p))
+//
+// The service account needs get, list, and watch on configmaps in the namespace.
+package kubernetes
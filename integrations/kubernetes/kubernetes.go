@@ -0,0 +1,158 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	k8s "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/tools/cache"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultName is the ConfigMap name used when Options.Name is empty.
+const DefaultName = "recourse-policies"
+
+// Options configures a Provider.
+type Options struct {
+	// Namespace of the ConfigMap. Required.
+	Namespace string
+	// Name of the ConfigMap. Defaults to DefaultName.
+	Name string
+	// Resync is the informer resync period. Zero disables resyncs.
+	Resync time.Duration
+	// OnInvalid, if set, is called for entries that do not decode to a valid policy.
+	// Invalid entries are skipped and the key keeps its previous policy.
+	OnInvalid func(key policy.PolicyKey, err error)
+}
+
+// Provider serves policies from the data of one ConfigMap, kept current by an informer.
+// Each data entry is a policy: the entry name is the policy key ("namespace.name") and
+// the value a JSON policy document (controlplane.DecodePolicy).
+//
+// Policies carry Meta.Source PolicySourceRemote. Until the informer has synced,
+// GetEffectivePolicy returns controlplane.ErrProviderUnavailable; afterwards missing
+// keys, or a missing ConfigMap, return controlplane.ErrPolicyNotFound. While the API
+// server is unreachable the last policies keep being served.
+type Provider struct {
+	client k8s.Interface
+	opts   Options
+
+	mu       sync.RWMutex
+	policies map[policy.PolicyKey]policy.EffectivePolicy
+	loaded   bool
+}
+
+// NewProvider returns a provider for the ConfigMap in opts. Call Run to start watching.
+func NewProvider(client k8s.Interface, opts Options) *Provider {
+	if opts.Name == "" {
+		opts.Name = DefaultName
+	}
+	return &Provider{client: client, opts: opts}
+}
+
+// GetEffectivePolicy returns the current policy for key.
+func (p *Provider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	if !p.loaded {
+		return policy.EffectivePolicy{}, controlplane.ErrProviderUnavailable
+	}
+	pol, ok := p.policies[key]
+	if !ok {
+		return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+	}
+	return pol, nil
+}
+
+// Run watches the ConfigMap until ctx is done and returns ctx.Err(). The informer
+// relists and rewatches on its own after API server errors.
+func (p *Provider) Run(ctx context.Context) error {
+	if p.opts.Namespace == "" {
+		return fmt.Errorf("recourse: kubernetes provider requires a namespace")
+	}
+	factory := informers.NewSharedInformerFactoryWithOptions(p.client, p.opts.Resync,
+		informers.WithNamespace(p.opts.Namespace),
+		informers.WithTweakListOptions(func(o *metav1.ListOptions) {
+			o.FieldSelector = fields.OneTermEqualSelector("metadata.name", p.opts.Name).String()
+		}),
+	)
+	informer := factory.Core().V1().ConfigMaps().Informer()
+	reg, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    func(obj any) { p.apply(obj) },
+		UpdateFunc: func(_, obj any) { p.apply(obj) },
+		DeleteFunc: func(obj any) {
+			if p.configMap(obj) != nil {
+				p.set(nil, nil)
+			}
+		},
+	})
+	if err != nil {
+		return err
+	}
+
+	factory.Start(ctx.Done())
+	defer factory.Shutdown()
+	if cache.WaitForCacheSync(ctx.Done(), reg.HasSynced) {
+		p.mu.Lock()
+		p.loaded = true
+		p.mu.Unlock()
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+// configMap returns obj as the watched ConfigMap, or nil.
+func (p *Provider) configMap(obj any) *corev1.ConfigMap {
+	if tomb, ok := obj.(cache.DeletedFinalStateUnknown); ok {
+		obj = tomb.Obj
+	}
+	cm, ok := obj.(*corev1.ConfigMap)
+	if !ok || cm.Namespace != p.opts.Namespace || cm.Name != p.opts.Name {
+		return nil
+	}
+	return cm
+}
+
+// apply replaces the policy set with the ConfigMap's entries.
+func (p *Provider) apply(obj any) {
+	cm := p.configMap(obj)
+	if cm == nil {
+		return
+	}
+	policies := make(map[policy.PolicyKey]policy.EffectivePolicy, len(cm.Data))
+	var invalid []policy.PolicyKey
+	for name, value := range cm.Data {
+		key := policy.ParseKey(name)
+		pol, err := controlplane.DecodePolicy(key, []byte(value))
+		if err != nil {
+			if p.opts.OnInvalid != nil {
+				p.opts.OnInvalid(key, err)
+			}
+			invalid = append(invalid, key)
+			continue
+		}
+		policies[key] = pol
+	}
+	p.set(policies, invalid)
+}
+
+func (p *Provider) set(policies map[policy.PolicyKey]policy.EffectivePolicy, keep []policy.PolicyKey) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for _, key := range keep {
+		if prev, ok := p.policies[key]; ok {
+			policies[key] = prev
+		}
+	}
+	p.policies = policies
+}
+
+var _ controlplane.PolicyProvider = (*Provider)(nil)
@@ -0,0 +1,74 @@
+package kubernetes_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/kubernetes"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestProvider(t *testing.T) {
+	get := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	put := policy.PolicyKey{Namespace: "svc", Name: "Put"}
+	cm := &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "payments", Name: integration.DefaultName},
+		Data:       map[string]string{"svc.Get": `{"retry": {"max_attempts": 4}}`},
+	}
+	client := fake.NewClientset(cm)
+	p := integration.NewProvider(client, integration.Options{Namespace: "payments"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if _, err := p.GetEffectivePolicy(ctx, get); !errors.Is(err, controlplane.ErrProviderUnavailable) {
+		t.Fatalf("before sync: err = %v, want ErrProviderUnavailable", err)
+	}
+	errc := make(chan error, 1)
+	go func() { errc <- p.Run(ctx) }()
+
+	waitFor := func(key policy.PolicyKey, attempts int) {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			pol, err := p.GetEffectivePolicy(ctx, key)
+			if attempts == 0 && errors.Is(err, controlplane.ErrPolicyNotFound) {
+				return
+			}
+			if err == nil && pol.Retry.MaxAttempts == attempts && pol.Meta.Source == policy.PolicySourceRemote {
+				return
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("%s = %+v, %v; want %d attempts", key, pol, err, attempts)
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	waitFor(get, 4)
+	waitFor(put, 0)
+
+	cm = cm.DeepCopy()
+	cm.Data["svc.Put"] = `{"retry": {"max_attempts": 2}}`
+	cm.Data["svc.Get"] = `{` // invalid: keeps the previous policy
+	if _, err := client.CoreV1().ConfigMaps("payments").Update(ctx, cm, metav1.UpdateOptions{}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	waitFor(put, 2)
+	waitFor(get, 4)
+
+	if err := client.CoreV1().ConfigMaps("payments").Delete(ctx, cm.Name, metav1.DeleteOptions{}); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	waitFor(put, 0)
+
+	cancel()
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
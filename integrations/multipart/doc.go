@@ -0,0 +1,25 @@
+// Package multipart retries chunked and multipart uploads (S3, GCS, Azure block blobs)
+// part by part.
+//
+// Each part is its own executor call, so a failed part is retried without re-sending the
+// parts that already succeeded. The upload as a whole shares one deadline, an optional
+// retry allowance across all parts, and one timeline. A Progress records completed parts
+// so an interrupted upload can resume where it stopped.
+//
+// Usage:
+//
+//	parts, err := multipart.Upload(ctx, exec, recourse.ParseKey("s3.UploadPart"), numParts,
+//		func(ctx context.Context, n int) (multipart.Part, error) {
+//			out, err := client.UploadPart(ctx, &s3.UploadPartInput{
+//				Bucket: &bucket, Key: &object, UploadId: &uploadID,
+//				PartNumber: aws.Int32(int32(n)), Body: partReader(n),
+//			})
+//			if err != nil {
+//				return multipart.Part{}, err
+//			}
+//			return multipart.Part{Number: n, ETag: aws.ToString(out.ETag)}, nil
+//		},
+//		multipart.Options{Concurrency: 4, MaxRetries: 10, Progress: progress},
+//	)
+//	// Complete the upload with parts.
+package multipart
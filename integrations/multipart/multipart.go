@@ -0,0 +1,231 @@
+package multipart
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultConcurrency is the number of parts uploaded at once when Options.Concurrency
+// is unset.
+const DefaultConcurrency = 4
+
+// ErrRetriesExhausted is returned (wrapped in a *PartError) when the upload used up
+// Options.MaxRetries.
+var ErrRetriesExhausted = errors.New("multipart: upload retries exhausted")
+
+// Part is an uploaded part.
+type Part struct {
+	Number int    // Part number, starting at 1.
+	ETag   string // Identifier returned by the store, needed to complete the upload.
+	Size   int64  // Size in bytes, if known.
+}
+
+// UploadFunc uploads part number n. It is called once per attempt, so it must supply
+// the part's data afresh on every call.
+type UploadFunc func(ctx context.Context, n int) (Part, error)
+
+// Progress records completed parts so an interrupted upload can resume.
+type Progress interface {
+	// Completed returns the parts already uploaded.
+	Completed(ctx context.Context) ([]Part, error)
+	// Record stores a completed part.
+	Record(ctx context.Context, part Part) error
+}
+
+// PartError reports the part that failed an upload.
+type PartError struct {
+	Number int
+	Err    error
+}
+
+func (e *PartError) Error() string {
+	return fmt.Sprintf("multipart: part %d: %v", e.Number, e.Err)
+}
+
+func (e *PartError) Unwrap() error { return e.Err }
+
+// Options configures Upload.
+type Options struct {
+	// Concurrency is the number of parts uploaded at once. Defaults to DefaultConcurrency.
+	Concurrency int
+
+	// MaxRetries caps the retries and hedges of all parts together, on top of the
+	// per-part policy. Zero means no upload-wide cap.
+	MaxRetries int
+
+	// Timeout bounds the whole upload. Zero means no bound beyond ctx.
+	Timeout time.Duration
+
+	// Progress, if set, skips the parts it reports as completed and records each part
+	// as it completes.
+	Progress Progress
+}
+
+// Upload uploads parts 1 through n, each as one executor call under key, and returns
+// every part (including resumed ones) in order.
+//
+// The first part that fails stops the upload and is returned as a *PartError. The
+// upload's timeline, with the attempts of every part in part order, is stored in any
+// capture requested on ctx (observe.RecordTimeline). If exec is nil, the default
+// executor is used.
+func Upload(ctx context.Context, exec *retry.Executor, key policy.PolicyKey, n int, upload UploadFunc, opts Options) ([]Part, error) {
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+	concurrency := opts.Concurrency
+	if concurrency <= 0 {
+		concurrency = DefaultConcurrency
+	}
+
+	start := time.Now()
+	tl := observe.Timeline{Key: key, Start: start, Attributes: map[string]string{"parts": strconv.Itoa(n)}}
+	finish := func(parts []Part, err error) ([]Part, error) {
+		tl.End = time.Now()
+		tl.Duration = time.Since(start)
+		tl.FinalErr = err
+		if capture, ok := observe.TimelineCaptureFromContext(ctx); ok {
+			observe.StoreTimelineCapture(capture, &tl)
+		}
+		return parts, err
+	}
+
+	done := make(map[int]Part, n)
+	if opts.Progress != nil {
+		completed, err := opts.Progress.Completed(ctx)
+		if err != nil {
+			return finish(nil, fmt.Errorf("multipart: load progress: %w", err))
+		}
+		for _, p := range completed {
+			if p.Number >= 1 && p.Number <= n {
+				done[p.Number] = p
+			}
+		}
+		tl.Attributes["parts_resumed"] = strconv.Itoa(len(done))
+	}
+
+	uploadCtx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	if opts.Timeout > 0 {
+		var cancelTimeout context.CancelFunc
+		uploadCtx, cancelTimeout = context.WithTimeout(uploadCtx, opts.Timeout)
+		defer cancelTimeout()
+	}
+
+	var (
+		retries   atomic.Int64
+		mu        sync.Mutex
+		timelines = make(map[int]*observe.Timeline)
+		wg        sync.WaitGroup
+		sem       = make(chan struct{}, concurrency)
+	)
+
+	for num := 1; num <= n; num++ {
+		mu.Lock()
+		_, ok := done[num]
+		mu.Unlock()
+		if ok {
+			continue
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-uploadCtx.Done():
+		}
+		if uploadCtx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(num int) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			partCtx, capture := observe.RecordTimeline(uploadCtx)
+			part, err := retry.DoValue(partCtx, exec, key, func(ctx context.Context) (Part, error) {
+				if info, ok := observe.AttemptFromContext(ctx); ok && (info.RetryIndex > 0 || info.IsHedge) &&
+					opts.MaxRetries > 0 && retries.Add(1) > int64(opts.MaxRetries) {
+					cancel(&PartError{Number: num, Err: ErrRetriesExhausted})
+					return Part{}, ErrRetriesExhausted
+				}
+				return upload(ctx, num)
+			})
+			if err == nil {
+				part.Number = num
+				if opts.Progress != nil {
+					if rerr := opts.Progress.Record(uploadCtx, part); rerr != nil {
+						err = fmt.Errorf("record progress: %w", rerr)
+					}
+				}
+			}
+
+			mu.Lock()
+			timelines[num] = capture.Timeline()
+			if err == nil {
+				done[num] = part
+			}
+			mu.Unlock()
+
+			if err != nil {
+				cancel(&PartError{Number: num, Err: err})
+			}
+		}(num)
+	}
+	wg.Wait()
+
+	for num := 1; num <= n; num++ {
+		if t := timelines[num]; t != nil {
+			tl.Attempts = append(tl.Attempts, t.Attempts...)
+		}
+	}
+
+	// Parts are missing only if the upload was stopped: by the first failure, the
+	// caller's cancellation, or the timeout.
+	if len(done) < n {
+		return finish(nil, context.Cause(uploadCtx))
+	}
+
+	parts := make([]Part, 0, len(done))
+	for _, p := range done {
+		parts = append(parts, p)
+	}
+	sort.Slice(parts, func(i, j int) bool { return parts[i].Number < parts[j].Number })
+	return finish(parts, nil)
+}
+
+// MemoryProgress is an in-process Progress, for uploads that only need to resume within
+// one process. It is safe for concurrent use.
+type MemoryProgress struct {
+	mu    sync.Mutex
+	parts map[int]Part
+}
+
+var _ Progress = (*MemoryProgress)(nil)
+
+func (p *MemoryProgress) Completed(context.Context) ([]Part, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	out := make([]Part, 0, len(p.parts))
+	for _, part := range p.parts {
+		out = append(out, part)
+	}
+	return out, nil
+}
+
+func (p *MemoryProgress) Record(_ context.Context, part Part) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.parts == nil {
+		p.parts = make(map[int]Part)
+	}
+	p.parts[part.Number] = part
+	return nil
+}
@@ -0,0 +1,129 @@
+package multipart_test
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	integration "github.com/aponysus/recourse/integrations/multipart"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+var key = policy.PolicyKey{Namespace: "s3", Name: "UploadPart"}
+
+func newExecutor(attempts int) *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(attempts),
+		policy.Backoff(time.Microsecond, time.Microsecond, 1),
+	))
+}
+
+// flakyStore fails each part's first failures[n] uploads.
+type flakyStore struct {
+	mu       sync.Mutex
+	failures map[int]int
+	calls    map[int]int
+}
+
+func (s *flakyStore) upload(_ context.Context, n int) (integration.Part, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.calls == nil {
+		s.calls = make(map[int]int)
+	}
+	s.calls[n]++
+	if s.calls[n] <= s.failures[n] {
+		return integration.Part{}, errors.New("503 slow down")
+	}
+	return integration.Part{ETag: "etag-" + strconv.Itoa(n)}, nil
+}
+
+func TestUploadRetriesFailedPartsOnly(t *testing.T) {
+	st := &flakyStore{failures: map[int]int{2: 2, 4: 1}}
+	ctx, capture := observe.RecordTimeline(context.Background())
+
+	parts, err := integration.Upload(ctx, newExecutor(3), key, 5, st.upload, integration.Options{Concurrency: 2})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if len(parts) != 5 {
+		t.Fatalf("got %d parts, want 5", len(parts))
+	}
+	for i, p := range parts {
+		if p.Number != i+1 || p.ETag != "etag-"+strconv.Itoa(i+1) {
+			t.Fatalf("part %d = %+v", i, p)
+		}
+	}
+	for n, want := range map[int]int{1: 1, 2: 3, 3: 1, 4: 2, 5: 1} {
+		if st.calls[n] != want {
+			t.Fatalf("part %d uploaded %d times, want %d", n, st.calls[n], want)
+		}
+	}
+
+	tl := capture.Timeline()
+	if tl == nil || len(tl.Attempts) != 8 || tl.Attributes["parts"] != "5" || tl.FinalErr != nil {
+		t.Fatalf("timeline = %+v", tl)
+	}
+}
+
+func TestUploadResumesFromProgress(t *testing.T) {
+	progress := &integration.MemoryProgress{}
+	_ = progress.Record(context.Background(), integration.Part{Number: 1, ETag: "old-1"})
+	_ = progress.Record(context.Background(), integration.Part{Number: 3, ETag: "old-3"})
+
+	st := &flakyStore{}
+	parts, err := integration.Upload(context.Background(), newExecutor(1), key, 3, st.upload,
+		integration.Options{Progress: progress})
+	if err != nil {
+		t.Fatalf("Upload: %v", err)
+	}
+	if st.calls[1] != 0 || st.calls[2] != 1 || st.calls[3] != 0 {
+		t.Fatalf("uploads = %v, want part 2 only", st.calls)
+	}
+	if parts[0].ETag != "old-1" || parts[1].ETag != "etag-2" || parts[2].ETag != "old-3" {
+		t.Fatalf("parts = %+v", parts)
+	}
+	if recorded, _ := progress.Completed(context.Background()); len(recorded) != 3 {
+		t.Fatalf("progress holds %d parts, want 3", len(recorded))
+	}
+}
+
+func TestUploadStopsAtFirstFailedPart(t *testing.T) {
+	st := &flakyStore{failures: map[int]int{2: 10}}
+	progress := &integration.MemoryProgress{}
+
+	_, err := integration.Upload(context.Background(), newExecutor(2), key, 3, st.upload,
+		integration.Options{Concurrency: 1, Progress: progress})
+
+	var pe *integration.PartError
+	if !errors.As(err, &pe) || pe.Number != 2 || !errors.Is(err, retry.ErrAttemptsExhausted) {
+		t.Fatalf("Upload error = %v, want part 2 attempts exhausted", err)
+	}
+	if st.calls[3] != 0 {
+		t.Fatalf("part 3 uploaded after part 2 failed")
+	}
+	// Part 1 is kept for the next try.
+	if recorded, _ := progress.Completed(context.Background()); len(recorded) != 1 || recorded[0].Number != 1 {
+		t.Fatalf("progress = %+v, want part 1", recorded)
+	}
+}
+
+func TestUploadSharesRetryAllowance(t *testing.T) {
+	st := &flakyStore{failures: map[int]int{1: 1, 2: 1, 3: 1}}
+
+	_, err := integration.Upload(context.Background(), newExecutor(5), key, 3, st.upload,
+		integration.Options{Concurrency: 1, MaxRetries: 2})
+
+	var pe *integration.PartError
+	if !errors.As(err, &pe) || pe.Number != 3 || !errors.Is(err, integration.ErrRetriesExhausted) {
+		t.Fatalf("Upload error = %v, want part 3 out of upload retries", err)
+	}
+	if st.calls[3] != 1 {
+		t.Fatalf("part 3 uploaded %d times, want 1", st.calls[3])
+	}
+}
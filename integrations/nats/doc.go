@@ -0,0 +1,20 @@
+// Package nats provides opt-in NATS integrations for recourse, built on nats.go.
+//
+// Requester retries request-reply calls and Publisher retries JetStream publishes under
+// recourse policies (keyed per subject) with the NATS classifier:
+//
+//	nc, _ := nats.Connect(nats.DefaultURL)
+//	js, _ := jetstream.New(nc)
+//	exec := retry.NewDefaultExecutor(recoursenats.WithClassifier())
+//
+//	users := recoursenats.NewRequester(nc, exec, nil)
+//	reply, err := users.Request(ctx, "users.get", payload)
+//
+//	orders := recoursenats.NewPublisher(js, exec, nil)
+//	ack, err := orders.Publish(ctx, "orders.created", payload, jetstream.WithMsgID(orderID))
+//
+// Set a per-attempt timeout in the policy: a request without a deadline waits for a
+// reply indefinitely.
+//
+// This package lives in a separate module to keep the nats.go dependency opt-in.
+package nats
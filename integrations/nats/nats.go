@@ -0,0 +1,180 @@
+package nats
+
+import (
+	"context"
+	"errors"
+	"strconv"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Conn is the subset of *nats.Conn used by Requester.
+type Conn interface {
+	RequestMsgWithContext(ctx context.Context, msg *nats.Msg) (*nats.Msg, error)
+}
+
+// JetStream is the subset of jetstream.JetStream used by Publisher.
+type JetStream interface {
+	PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error)
+}
+
+// DefaultKeyFunc maps subjects to policy keys.
+// "users.get" -> {Namespace: "nats", Name: "users.get"}
+//
+// Subjects that embed IDs ("orders.123.created") need a KeyFunc that drops them, since
+// every key gets its own circuit and latency tracker.
+func DefaultKeyFunc(subject string) policy.PolicyKey {
+	return policy.PolicyKey{Namespace: "nats", Name: subject}
+}
+
+// Requester retries request-reply calls using a recourse executor.
+type Requester struct {
+	conn    Conn
+	exec    *retry.Executor
+	keyFunc func(subject string) policy.PolicyKey
+}
+
+// NewRequester returns a Requester that sends requests through conn.
+// If exec is nil, the default executor is used; if keyFunc is nil, DefaultKeyFunc is used.
+func NewRequester(conn Conn, exec *retry.Executor, keyFunc func(subject string) policy.PolicyKey) *Requester {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return &Requester{conn: conn, exec: exec, keyFunc: keyFunc}
+}
+
+// Request sends data to subj and waits for a reply.
+func (r *Requester) Request(ctx context.Context, subj string, data []byte) (*nats.Msg, error) {
+	return r.RequestMsg(ctx, &nats.Msg{Subject: subj, Data: data})
+}
+
+// RequestMsg sends msg and waits for a reply. Each attempt sends a copy of msg.
+func (r *Requester) RequestMsg(ctx context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	exec := r.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+	return retry.DoValue(ctx, exec, r.keyFunc(msg.Subject), func(ctx context.Context) (*nats.Msg, error) {
+		return r.conn.RequestMsgWithContext(ctx, copyMsg(msg))
+	})
+}
+
+// Publisher retries JetStream publishes using a recourse executor.
+//
+// A publish whose ack was lost may have been stored, so retries can duplicate messages
+// unless the message carries an ID (jetstream.WithMsgID) inside the stream's
+// duplicate window.
+type Publisher struct {
+	js      JetStream
+	exec    *retry.Executor
+	keyFunc func(subject string) policy.PolicyKey
+}
+
+// NewPublisher returns a Publisher that publishes through js.
+// If exec is nil, the default executor is used; if keyFunc is nil, DefaultKeyFunc is used.
+//
+// jetstream retries publishes that find no responders on its own (see
+// jetstream.WithRetryAttempts); pass jetstream.WithRetryAttempts(0) to leave retries to
+// the policy.
+func NewPublisher(js JetStream, exec *retry.Executor, keyFunc func(subject string) policy.PolicyKey) *Publisher {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return &Publisher{js: js, exec: exec, keyFunc: keyFunc}
+}
+
+// Publish publishes data to subj and waits for the stream's ack.
+func (p *Publisher) Publish(ctx context.Context, subj string, data []byte, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	return p.PublishMsg(ctx, &nats.Msg{Subject: subj, Data: data}, opts...)
+}
+
+// PublishMsg publishes msg and waits for the stream's ack. Each attempt publishes a copy
+// of msg, since publish options write to its headers.
+func (p *Publisher) PublishMsg(ctx context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	exec := p.exec
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+	return retry.DoValue(ctx, exec, p.keyFunc(msg.Subject), func(ctx context.Context) (*jetstream.PubAck, error) {
+		return p.js.PublishMsg(ctx, copyMsg(msg), opts...)
+	})
+}
+
+func copyMsg(msg *nats.Msg) *nats.Msg {
+	out := &nats.Msg{Subject: msg.Subject, Reply: msg.Reply, Data: msg.Data}
+	if msg.Header != nil {
+		out.Header = make(nats.Header, len(msg.Header))
+		for k, v := range msg.Header {
+			out.Header[k] = append([]string(nil), v...)
+		}
+	}
+	return out
+}
+
+// JetStream API error codes that indicate a failed expectation rather than a failure.
+var wrongLastSequence = map[jetstream.ErrorCode]struct{}{
+	jetstream.JSErrCodeStreamWrongLastSequence:         {},
+	jetstream.JSErrCodeStreamWrongLastSequenceConstant: {},
+}
+
+// Classifier implements classify.Classifier for NATS request and JetStream publish errors.
+type Classifier struct{}
+
+func (Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "context_deadline_exceeded"}
+	}
+
+	switch {
+	case errors.Is(err, nats.ErrTimeout):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "nats_timeout"}
+	case errors.Is(err, nats.ErrNoResponders):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "nats_no_responders"}
+	case errors.Is(err, jetstream.ErrNoStreamResponse):
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "nats_no_stream_response"}
+	case errors.Is(err, nats.ErrConnectionClosed), errors.Is(err, nats.ErrConnectionDraining), errors.Is(err, nats.ErrInvalidConnection):
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "nats_connection_closed"}
+	case errors.Is(err, nats.ErrBadSubject), errors.Is(err, nats.ErrMaxPayload), errors.Is(err, nats.ErrInvalidMsg):
+		return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "nats_invalid_message"}
+	case errors.Is(err, jetstream.ErrInvalidJSAck):
+		// The message may have been stored; only an ID makes a retry safe.
+		return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "nats_invalid_ack"}
+	}
+
+	var apiErr *jetstream.APIError
+	if !errors.As(err, &apiErr) {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+
+	out := classify.Outcome{
+		Kind:   classify.OutcomeNonRetryable,
+		Reason: "nats_jetstream_error",
+		Attributes: map[string]string{
+			"jetstream_code":     strconv.Itoa(apiErr.Code),
+			"jetstream_err_code": strconv.Itoa(int(apiErr.ErrorCode)),
+		},
+	}
+	if _, ok := wrongLastSequence[apiErr.ErrorCode]; ok {
+		out.Reason = "nats_jetstream_wrong_last_sequence"
+	} else if apiErr.Code >= 500 {
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "nats_jetstream_unavailable"
+	}
+	return out
+}
+
+// WithClassifier returns an option to register the NATS classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
@@ -0,0 +1,134 @@
+package nats_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+
+	"github.com/aponysus/recourse/classify"
+	integration "github.com/aponysus/recourse/integrations/nats"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+type fakeConn struct {
+	errs  []error
+	calls int
+}
+
+func (c *fakeConn) RequestMsgWithContext(_ context.Context, msg *nats.Msg) (*nats.Msg, error) {
+	c.calls++
+	if len(c.errs) > 0 {
+		err := c.errs[0]
+		c.errs = c.errs[1:]
+		return nil, err
+	}
+	return &nats.Msg{Subject: msg.Reply, Data: append([]byte("re:"), msg.Data...)}, nil
+}
+
+type fakeJetStream struct {
+	errs    []error
+	headers []nats.Header
+}
+
+func (js *fakeJetStream) PublishMsg(_ context.Context, msg *nats.Msg, opts ...jetstream.PublishOpt) (*jetstream.PubAck, error) {
+	if msg.Header == nil {
+		msg.Header = nats.Header{}
+	}
+	msg.Header.Add("Attempt", fmt.Sprint(len(js.headers)))
+	js.headers = append(js.headers, msg.Header)
+	if len(js.errs) > 0 {
+		err := js.errs[0]
+		js.errs = js.errs[1:]
+		return nil, err
+	}
+	return &jetstream.PubAck{Stream: "ORDERS", Sequence: 1}, nil
+}
+
+func newExec(subject string) *retry.Executor {
+	return retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicyKey(integration.DefaultKeyFunc(subject), policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Millisecond, 1)),
+	)
+}
+
+func TestRequester_RetriesNoResponders(t *testing.T) {
+	conn := &fakeConn{errs: []error{nats.ErrNoResponders, nats.ErrTimeout}}
+	r := integration.NewRequester(conn, newExec("users.get"), nil)
+
+	reply, err := r.Request(context.Background(), "users.get", []byte("42"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(reply.Data) != "re:42" || conn.calls != 3 {
+		t.Fatalf("got %q after %d calls", reply.Data, conn.calls)
+	}
+}
+
+func TestRequester_BadSubjectNotRetried(t *testing.T) {
+	conn := &fakeConn{errs: []error{nats.ErrBadSubject}}
+	r := integration.NewRequester(conn, newExec("users get"), nil)
+
+	if _, err := r.Request(context.Background(), "users get", nil); !errors.Is(err, nats.ErrBadSubject) {
+		t.Fatalf("expected ErrBadSubject, got %v", err)
+	}
+	if conn.calls != 1 {
+		t.Fatalf("expected 1 call, got %d", conn.calls)
+	}
+}
+
+func TestPublisher_RetriesWithFreshMessage(t *testing.T) {
+	js := &fakeJetStream{errs: []error{jetstream.ErrNoStreamResponse}}
+	p := integration.NewPublisher(js, newExec("orders.created"), nil)
+
+	msg := &nats.Msg{Subject: "orders.created", Data: []byte("{}"), Header: nats.Header{"Nats-Msg-Id": {"o-1"}}}
+	ack, err := p.PublishMsg(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ack.Stream != "ORDERS" || len(js.headers) != 2 {
+		t.Fatalf("unexpected ack %+v after %d publishes", ack, len(js.headers))
+	}
+	if got := js.headers[1].Values("Attempt"); len(got) != 1 {
+		t.Fatalf("expected each attempt to get a copy of the headers, got %v", got)
+	}
+	if len(msg.Header.Values("Attempt")) != 0 || msg.Header.Get("Nats-Msg-Id") != "o-1" {
+		t.Fatalf("caller's message was modified: %v", msg.Header)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	unavailable := &jetstream.APIError{Code: 503, ErrorCode: 10008, Description: "jetstream temporarily unavailable"}
+	wrongSeq := &jetstream.APIError{Code: 400, ErrorCode: jetstream.JSErrCodeStreamWrongLastSequence, Description: "wrong last sequence: 4"}
+
+	tests := []struct {
+		name   string
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{"no responders", nats.ErrNoResponders, classify.OutcomeRetryable, "nats_no_responders"},
+		{"timeout", nats.ErrTimeout, classify.OutcomeRetryable, "nats_timeout"},
+		{"deadline", context.DeadlineExceeded, classify.OutcomeRetryable, "context_deadline_exceeded"},
+		{"no stream response", jetstream.ErrNoStreamResponse, classify.OutcomeRetryable, "nats_no_stream_response"},
+		{"closed", nats.ErrConnectionClosed, classify.OutcomeAbort, "nats_connection_closed"},
+		{"max payload", nats.ErrMaxPayload, classify.OutcomeNonRetryable, "nats_invalid_message"},
+		{"invalid ack", jetstream.ErrInvalidJSAck, classify.OutcomeNonRetryable, "nats_invalid_ack"},
+		{"unavailable", fmt.Errorf("nats: %w", unavailable), classify.OutcomeRetryable, "nats_jetstream_unavailable"},
+		{"wrong last sequence", fmt.Errorf("nats: %w", wrongSeq), classify.OutcomeNonRetryable, "nats_jetstream_wrong_last_sequence"},
+		{"not found", jetstream.ErrStreamNotFound, classify.OutcomeNonRetryable, "nats_jetstream_error"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := integration.Classifier{}.Classify(nil, tt.err)
+			if out.Kind != tt.kind || out.Reason != tt.reason {
+				t.Fatalf("got %v/%s, want %v/%s", out.Kind, out.Reason, tt.kind, tt.reason)
+			}
+		})
+	}
+}
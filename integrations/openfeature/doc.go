@@ -0,0 +1,15 @@
+// Package openfeature evaluates recourse policy flags with OpenFeature.
+//
+// FlagSource adapts an OpenFeature client to controlplane.FlagSource, so the flags read
+// by controlplane.FlagProvider (kill switch, hedging, max attempts) come from whatever
+// provider the organization already runs (flagd, LaunchDarkly, Unleash, ...).
+//
+// Each evaluation uses the policy key as targeting key ("namespace.name") and sets the
+// "namespace" and "name" attributes, so flag rules can target one key or a namespace.
+//
+// Usage:
+//
+//	flags := openfeature.NewFlagSource(of.NewClient("recourse"))
+//	provider := controlplane.NewFlagProvider(base, flags, controlplane.FlagOptions{})
+//	exec := retry.NewDefaultExecutor(retry.WithProvider(provider))
+package openfeature
@@ -0,0 +1,12 @@
+module github.com/aponysus/recourse/integrations/openfeature
+
+go 1.26.0
+
+replace github.com/aponysus/recourse => ../../
+
+require (
+	github.com/aponysus/recourse v0.0.0-00010101000000-000000000000
+	github.com/open-feature/go-sdk v1.19.0
+)
+
+require go.uber.org/mock v0.6.0 // indirect
@@ -0,0 +1,53 @@
+package openfeature
+
+import (
+	"context"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Client is the subset of *openfeature.Client used by FlagSource.
+type Client interface {
+	BooleanValue(ctx context.Context, flag string, defaultValue bool, evalCtx of.EvaluationContext, options ...of.Option) (bool, error)
+	IntValue(ctx context.Context, flag string, defaultValue int64, evalCtx of.EvaluationContext, options ...of.Option) (int64, error)
+}
+
+// FlagSource is a controlplane.FlagSource backed by an OpenFeature client.
+type FlagSource struct {
+	client Client
+}
+
+var _ controlplane.FlagSource = (*FlagSource)(nil)
+
+// NewFlagSource returns a FlagSource that evaluates flags with client.
+func NewFlagSource(client Client) *FlagSource {
+	return &FlagSource{client: client}
+}
+
+// BoolFlag evaluates flag for key. Evaluation errors return def.
+func (s *FlagSource) BoolFlag(ctx context.Context, flag string, key policy.PolicyKey, def bool) bool {
+	v, err := s.client.BooleanValue(ctx, flag, def, evaluationContext(key))
+	if err != nil {
+		return def
+	}
+	return v
+}
+
+// IntFlag evaluates flag for key. Evaluation errors return def.
+func (s *FlagSource) IntFlag(ctx context.Context, flag string, key policy.PolicyKey, def int) int {
+	v, err := s.client.IntValue(ctx, flag, int64(def), evaluationContext(key))
+	if err != nil {
+		return def
+	}
+	return int(v)
+}
+
+func evaluationContext(key policy.PolicyKey) of.EvaluationContext {
+	return of.NewEvaluationContext(key.String(), map[string]any{
+		"namespace": key.Namespace,
+		"name":      key.Name,
+	})
+}
@@ -0,0 +1,70 @@
+package openfeature_test
+
+import (
+	"context"
+	"testing"
+
+	of "github.com/open-feature/go-sdk/openfeature"
+	"github.com/open-feature/go-sdk/openfeature/memprovider"
+
+	"github.com/aponysus/recourse/controlplane"
+	integration "github.com/aponysus/recourse/integrations/openfeature"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestFlagSourceTargetsKeys(t *testing.T) {
+	// The kill switch is on for the "payments" namespace only.
+	killPayments := func(flag memprovider.InMemoryFlag, flat of.FlattenedContext) (any, of.ProviderResolutionDetail) {
+		variant := "off"
+		if flat["namespace"] == "payments" {
+			variant = "on"
+		}
+		return flag.Variants[variant], of.ProviderResolutionDetail{Variant: variant, Reason: of.TargetingMatchReason}
+	}
+	provider := memprovider.NewInMemoryProvider(map[string]memprovider.InMemoryFlag{
+		controlplane.DefaultKillSwitchFlag: {
+			Key:              controlplane.DefaultKillSwitchFlag,
+			State:            memprovider.Enabled,
+			DefaultVariant:   "off",
+			Variants:         map[string]any{"on": true, "off": false},
+			ContextEvaluator: &killPayments,
+		},
+		controlplane.DefaultMaxAttemptsFlag: {
+			Key:            controlplane.DefaultMaxAttemptsFlag,
+			State:          memprovider.Enabled,
+			DefaultVariant: "five",
+			Variants:       map[string]any{"five": int64(5)},
+		},
+	})
+	if err := of.SetNamedProviderAndWait(t.Name(), provider); err != nil {
+		t.Fatalf("set provider: %v", err)
+	}
+
+	flags := integration.NewFlagSource(of.NewClient(t.Name()))
+	ctx := context.Background()
+	payments := policy.PolicyKey{Namespace: "payments", Name: "Charge"}
+	users := policy.PolicyKey{Namespace: "users", Name: "Get"}
+
+	if !flags.BoolFlag(ctx, controlplane.DefaultKillSwitchFlag, payments, false) {
+		t.Fatal("kill switch off for payments")
+	}
+	if flags.BoolFlag(ctx, controlplane.DefaultKillSwitchFlag, users, false) {
+		t.Fatal("kill switch on for users")
+	}
+	if got := flags.IntFlag(ctx, controlplane.DefaultMaxAttemptsFlag, users, 3); got != 5 {
+		t.Fatalf("max attempts = %d, want 5", got)
+	}
+	if got := flags.IntFlag(ctx, "unknown.flag", users, 3); got != 3 {
+		t.Fatalf("unknown flag = %d, want default 3", got)
+	}
+
+	p := controlplane.NewFlagProvider(&controlplane.StaticProvider{}, flags, controlplane.FlagOptions{})
+	pol, err := p.GetEffectivePolicy(ctx, payments)
+	if err != nil || pol.Retry.MaxAttempts != 1 {
+		t.Fatalf("payments policy = %+v, %v; want kill switch applied", pol.Retry, err)
+	}
+	pol, err = p.GetEffectivePolicy(ctx, users)
+	if err != nil || pol.Retry.MaxAttempts != 5 {
+		t.Fatalf("users policy = %+v, %v; want 5 attempts", pol.Retry, err)
+	}
+}
@@ -0,0 +1,47 @@
+package otel
+
+import (
+	"context"
+	"net/url"
+	"strconv"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Baggage members written by AttemptBaggage.
+const (
+	AttemptKey  = "recourse.attempt"   // Attempt index; 0 for a call's first attempt.
+	HedgeKey    = "recourse.hedge"     // "true" for hedged attempts, else "false".
+	PolicyIDKey = "recourse.policy_id" // Policy ID; omitted when the policy has none.
+)
+
+var _ retry.AttemptContextFunc = AttemptBaggage
+
+// AttemptBaggage returns ctx with info added to its baggage, replacing members of the
+// same keys and keeping any others. If the baggage cannot hold the members (the W3C
+// limits on size are exceeded), ctx is returned unchanged.
+func AttemptBaggage(ctx context.Context, info observe.AttemptInfo) context.Context {
+	b := baggage.FromContext(ctx)
+	members := [...][2]string{
+		{AttemptKey, strconv.Itoa(info.Attempt)},
+		{HedgeKey, strconv.FormatBool(info.IsHedge)},
+		{PolicyIDKey, info.PolicyID},
+	}
+	for _, kv := range members {
+		if kv[1] == "" {
+			b = b.DeleteMember(kv[0])
+			continue
+		}
+		m, err := baggage.NewMember(kv[0], url.PathEscape(kv[1]))
+		if err != nil {
+			return ctx
+		}
+		if b, err = b.SetMember(m); err != nil {
+			return ctx
+		}
+	}
+	return baggage.ContextWithBaggage(ctx, b)
+}
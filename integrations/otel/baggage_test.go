@@ -0,0 +1,65 @@
+package otel_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/baggage"
+
+	"github.com/aponysus/recourse/integrations/otel"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestAttemptBaggage_Retries(t *testing.T) {
+	exec := retry.NewExecutor(
+		retry.WithPolicy("svc.Get", policy.MaxAttempts(2), policy.InitialBackoff(time.Millisecond), policy.PolicyID("v7 canary")),
+		retry.WithAttemptContext(otel.AttemptBaggage),
+	)
+	member, _ := baggage.NewMember("tenant", "acme")
+	b, _ := baggage.New(member)
+	ctx := baggage.ContextWithBaggage(context.Background(), b)
+
+	var seen []baggage.Baggage
+	err := exec.Do(ctx, policy.ParseKey("svc.Get"), func(ctx context.Context) error {
+		seen = append(seen, baggage.FromContext(ctx))
+		if len(seen) == 1 {
+			return errors.New("boom")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(seen))
+	}
+	for i, b := range seen {
+		if got := b.Member(otel.AttemptKey).Value(); got != []string{"0", "1"}[i] {
+			t.Errorf("attempt %d: %s = %q", i, otel.AttemptKey, got)
+		}
+		if got := b.Member(otel.HedgeKey).Value(); got != "false" {
+			t.Errorf("attempt %d: %s = %q, want false", i, otel.HedgeKey, got)
+		}
+		if got := b.Member(otel.PolicyIDKey).Value(); got != "v7 canary" {
+			t.Errorf("attempt %d: %s = %q, want %q", i, otel.PolicyIDKey, got, "v7 canary")
+		}
+		if got := b.Member("tenant").Value(); got != "acme" {
+			t.Errorf("attempt %d: tenant = %q, want the caller's baggage kept", i, got)
+		}
+	}
+}
+
+func TestAttemptBaggage_Hedge(t *testing.T) {
+	ctx := otel.AttemptBaggage(context.Background(), observe.AttemptInfo{Attempt: 0, IsHedge: true, HedgeIndex: 1})
+	b := baggage.FromContext(ctx)
+	if got := b.Member(otel.HedgeKey).Value(); got != "true" {
+		t.Fatalf("%s = %q, want true", otel.HedgeKey, got)
+	}
+	if b.Member(otel.PolicyIDKey).Key() != "" {
+		t.Fatalf("baggage = %v, want no policy ID member", b)
+	}
+}
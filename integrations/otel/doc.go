@@ -0,0 +1,13 @@
+// Package otel propagates recourse attempt info as OpenTelemetry baggage.
+//
+// AttemptBaggage is a retry.AttemptContextFunc that writes each attempt's index, hedge
+// flag, and policy ID into the baggage of the attempt context. Baggage travels with
+// the request through instrumented clients, so every downstream span in the request
+// tree can be filtered by "was this inside a retry or hedge" without custom
+// instrumentation (for example with a span processor that copies baggage members to
+// span attributes).
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor(retry.WithAttemptContext(otel.AttemptBaggage))
+package otel
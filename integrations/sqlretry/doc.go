@@ -0,0 +1,20 @@
+// Package sqlretry wraps *sql.DB so statements and transactions run through a recourse
+// executor.
+//
+// Usage:
+//
+//	db := sqlretry.New(sqlDB, exec)
+//
+//	res, err := db.ExecContext(ctx, recourse.ParseKey("orders.Insert"), "INSERT ...", args...)
+//
+//	err = db.WithTx(ctx, recourse.ParseKey("orders.Transfer"), nil, func(ctx context.Context, tx *sql.Tx) error {
+//		// Every statement of the transaction; the whole closure is retried on
+//		// serialization failures and deadlocks.
+//		return nil
+//	})
+//
+// Errors are wrapped in *Error, which implements classify.SQLError, so executors using
+// classify.AutoClassifier (the default for retry.NewDefaultExecutor) apply
+// classify.SQLClassifier. Policies may also name it explicitly with
+// policy.Classifier(classify.ClassifierSQL).
+package sqlretry
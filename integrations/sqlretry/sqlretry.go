@@ -0,0 +1,144 @@
+package sqlretry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DB wraps a *sql.DB with retries.
+type DB struct {
+	db   *sql.DB
+	exec *retry.Executor
+}
+
+// New returns a DB that runs statements against db using exec.
+// If exec is nil, the global default executor is used.
+func New(db *sql.DB, exec *retry.Executor) *DB {
+	return &DB{db: db, exec: exec}
+}
+
+// DB returns the underlying *sql.DB.
+func (d *DB) DB() *sql.DB { return d.db }
+
+// ExecContext executes a statement, retrying it as a unit under key.
+//
+// Use it for statements that are safe to repeat, or rely on the classifier: only
+// failures that guarantee the statement did not take effect are retryable.
+func (d *DB) ExecContext(ctx context.Context, key policy.PolicyKey, query string, args ...any) (sql.Result, error) {
+	return retry.DoValue(ctx, d.executor(), key, func(ctx context.Context) (sql.Result, error) {
+		res, err := d.db.ExecContext(ctx, query, args...)
+		if err != nil {
+			return nil, &Error{Op: "exec", Err: err}
+		}
+		return res, nil
+	})
+}
+
+// QueryContext runs a query, retrying until the rows are returned.
+//
+// Only obtaining the rows is retried; errors surfaced later by Rows.Next or Rows.Err are
+// returned to the caller as-is. The rows stay usable until ctx is canceled or they are closed.
+func (d *DB) QueryContext(ctx context.Context, key policy.PolicyKey, query string, args ...any) (*sql.Rows, error) {
+	return retry.DoValue(ctx, d.executor(), key, func(attemptCtx context.Context) (*sql.Rows, error) {
+		// Rows outlive the attempt, so they must not be bound to the attempt context,
+		// which the executor cancels when the call returns.
+		qctx, detach, cancel := attemptContext(attemptCtx, ctx)
+		rows, err := d.db.QueryContext(qctx, query, args...)
+		if err != nil {
+			cancel()
+			return nil, &Error{Op: "query", Err: err}
+		}
+		detach()
+		return rows, nil
+	})
+}
+
+// WithTx runs fn in a transaction and retries the whole transaction under key.
+//
+// Statements inside a failed transaction must not be retried individually: after a
+// serialization failure or deadlock the transaction is aborted and only a fresh
+// transaction can succeed. WithTx begins a new transaction for every attempt, rolls it
+// back if fn fails or panics, and commits it otherwise. fn must not commit or roll back
+// tx itself, and must be safe to run more than once.
+func (d *DB) WithTx(ctx context.Context, key policy.PolicyKey, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) error {
+	return d.executor().Do(ctx, key, func(ctx context.Context) error {
+		return d.runTx(ctx, opts, fn)
+	})
+}
+
+func (d *DB) runTx(ctx context.Context, opts *sql.TxOptions, fn func(ctx context.Context, tx *sql.Tx) error) (err error) {
+	tx, err := d.db.BeginTx(ctx, opts)
+	if err != nil {
+		return &Error{Op: "begin", Err: err}
+	}
+
+	committed := false
+	defer func() {
+		if !committed {
+			_ = tx.Rollback()
+		}
+	}()
+
+	if err := fn(ctx, tx); err != nil {
+		return &Error{Op: "tx", Err: err}
+	}
+	if err := tx.Commit(); err != nil {
+		// The transaction is finished either way; skip the deferred rollback.
+		committed = true
+		return &Error{Op: "commit", Err: err}
+	}
+	committed = true
+	return nil
+}
+
+func (d *DB) executor() *retry.Executor {
+	if d.exec != nil {
+		return d.exec
+	}
+	return retry.DefaultExecutor()
+}
+
+// Error wraps a database error returned by an attempt.
+//
+// It implements classify.SQLError, so classify.AutoClassifier routes it to the SQL
+// classifier even for driver errors that carry no SQLSTATE (such as driver.ErrBadConn).
+type Error struct {
+	Op  string // "exec", "query", "begin", "tx", or "commit".
+	Err error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("sqlretry: %s: %v", e.Op, e.Err)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// SQLState returns the SQLSTATE of the wrapped error, or "" if it has none.
+func (e *Error) SQLState() string {
+	var se classify.SQLError
+	if errors.As(e.Err, &se) {
+		return se.SQLState()
+	}
+	return ""
+}
+
+// attemptContext derives the context for one attempt. It carries the values of the
+// attempt context and is canceled with it until detach is called; after that, only
+// the caller's context (or cancel) ends it.
+func attemptContext(attempt, caller context.Context) (ctx context.Context, detach, cancel func()) {
+	ctx, cancelFn := context.WithCancel(context.WithoutCancel(attempt))
+	stopAttempt := context.AfterFunc(attempt, cancelFn)
+	stopCaller := context.AfterFunc(caller, cancelFn)
+	cancel = func() {
+		stopAttempt()
+		stopCaller()
+		cancelFn()
+	}
+	return ctx, func() { stopAttempt() }, cancel
+}
@@ -0,0 +1,242 @@
+package sqlretry
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// stateError mimics a driver error carrying a SQLSTATE (e.g. pgconn.PgError).
+type stateError string
+
+func (e stateError) Error() string    { return "sqlstate " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+// fakeDriver is a minimal database/sql driver whose statements fail according to a script.
+type fakeDriver struct {
+	mu       sync.Mutex
+	failures map[string][]error // query -> errors returned by successive executions
+	execs    map[string]int
+	commits  int
+	rollback int
+}
+
+func newFakeDB(t *testing.T, failures map[string][]error) (*sql.DB, *fakeDriver) {
+	t.Helper()
+	d := &fakeDriver{failures: failures, execs: make(map[string]int)}
+	db := sql.OpenDB(d)
+	t.Cleanup(func() { db.Close() })
+	return db, d
+}
+
+func (d *fakeDriver) Connect(context.Context) (driver.Conn, error) { return &fakeConn{d: d}, nil }
+func (d *fakeDriver) Driver() driver.Driver                        { return nil }
+
+func (d *fakeDriver) next(query string) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.execs[query]++
+	if errs := d.failures[query]; len(errs) > 0 {
+		d.failures[query] = errs[1:]
+		return errs[0]
+	}
+	return nil
+}
+
+func (d *fakeDriver) count(query string) int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.execs[query]
+}
+
+type fakeConn struct{ d *fakeDriver }
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c *fakeConn) Close() error                        { return nil }
+func (c *fakeConn) Begin() (driver.Tx, error)           { return &fakeTx{d: c.d}, nil }
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	if err := c.d.next(query); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	if err := c.d.next(query); err != nil {
+		return nil, err
+	}
+	return &fakeRows{n: 2}, nil
+}
+
+type fakeTx struct{ d *fakeDriver }
+
+func (tx *fakeTx) Commit() error {
+	if err := tx.d.next("COMMIT"); err != nil {
+		return err
+	}
+	tx.d.mu.Lock()
+	tx.d.commits++
+	tx.d.mu.Unlock()
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	tx.d.mu.Lock()
+	tx.d.rollback++
+	tx.d.mu.Unlock()
+	return nil
+}
+
+type fakeRows struct{ n int }
+
+func (r *fakeRows) Columns() []string { return []string{"v"} }
+func (r *fakeRows) Close() error      { return nil }
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.n == 0 {
+		return io.EOF
+	}
+	dest[0] = int64(r.n)
+	r.n--
+	return nil
+}
+
+func newExec(key policy.PolicyKey) *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key,
+		policy.MaxAttempts(3),
+		policy.Backoff(time.Millisecond, time.Millisecond, 1),
+		policy.OverallTimeout(5*time.Second),
+	))
+}
+
+func TestExecContext_RetriesTransientErrors(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "orders", Name: "Insert"}
+	db, d := newFakeDB(t, map[string][]error{
+		"INSERT": {stateError("40P01"), driver.ErrBadConn},
+	})
+
+	res, err := New(db, newExec(key)).ExecContext(context.Background(), key, "INSERT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if n, _ := res.RowsAffected(); n != 1 {
+		t.Fatalf("rows affected=%d, want 1", n)
+	}
+	// database/sql itself retries driver.ErrBadConn on a fresh connection, so the
+	// driver may see more executions than recourse attempts.
+	if got := d.count("INSERT"); got < 3 {
+		t.Fatalf("executions=%d, want >= 3", got)
+	}
+}
+
+func TestExecContext_NonRetryableState(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "orders", Name: "Insert"}
+	db, d := newFakeDB(t, map[string][]error{
+		"INSERT": {stateError("23505")}, // unique_violation
+	})
+
+	_, err := New(db, newExec(key)).ExecContext(context.Background(), key, "INSERT")
+	var se stateError
+	if !errors.As(err, &se) || se != "23505" {
+		t.Fatalf("err=%v, want unique violation", err)
+	}
+	if got := d.count("INSERT"); got != 1 {
+		t.Fatalf("executions=%d, want 1", got)
+	}
+}
+
+func TestQueryContext_RowsOutliveAttempt(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "orders", Name: "List"}
+	db, d := newFakeDB(t, map[string][]error{
+		"SELECT": {stateError("40001")},
+	})
+
+	rows, err := New(db, newExec(key)).QueryContext(context.Background(), key, "SELECT")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	defer rows.Close()
+
+	var got []int64
+	for rows.Next() {
+		var v int64
+		if err := rows.Scan(&v); err != nil {
+			t.Fatalf("scan: %v", err)
+		}
+		got = append(got, v)
+	}
+	if err := rows.Err(); err != nil {
+		t.Fatalf("rows.Err=%v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("rows=%v, want 2", got)
+	}
+	if n := d.count("SELECT"); n != 2 {
+		t.Fatalf("executions=%d, want 2", n)
+	}
+}
+
+func TestWithTx_RetriesWholeTransaction(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "orders", Name: "Transfer"}
+	db, d := newFakeDB(t, map[string][]error{
+		"UPDATE b": {stateError("40001")},
+		"COMMIT":   {stateError("40001")},
+	})
+
+	runs := 0
+	err := New(db, newExec(key)).WithTx(context.Background(), key, nil, func(ctx context.Context, tx *sql.Tx) error {
+		runs++
+		if _, err := tx.ExecContext(ctx, "UPDATE a"); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, "UPDATE b")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// Attempt 1 fails on "UPDATE b", attempt 2 on commit, attempt 3 succeeds;
+	// each attempt replays every statement in a new transaction.
+	if runs != 3 {
+		t.Fatalf("runs=%d, want 3", runs)
+	}
+	if got := d.count("UPDATE a"); got != 3 {
+		t.Fatalf("UPDATE a executions=%d, want 3", got)
+	}
+	if d.commits != 1 {
+		t.Fatalf("commits=%d, want 1", d.commits)
+	}
+	if d.rollback != 1 {
+		t.Fatalf("rollbacks=%d, want 1", d.rollback)
+	}
+}
+
+func TestWithTx_ApplicationErrorNotRetried(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "orders", Name: "Transfer"}
+	db, d := newFakeDB(t, nil)
+
+	errInsufficient := errors.New("insufficient funds")
+	runs := 0
+	err := New(db, newExec(key)).WithTx(context.Background(), key, nil, func(context.Context, *sql.Tx) error {
+		runs++
+		return errInsufficient
+	})
+	if !errors.Is(err, errInsufficient) {
+		t.Fatalf("err=%v, want insufficient funds", err)
+	}
+	if runs != 1 {
+		t.Fatalf("runs=%d, want 1", runs)
+	}
+	if d.rollback != 1 || d.commits != 0 {
+		t.Fatalf("rollbacks=%d commits=%d, want 1/0", d.rollback, d.commits)
+	}
+}
@@ -0,0 +1,14 @@
+// Package twirp provides opt-in Twirp integrations for recourse.
+//
+// Usage:
+//
+//	exec := retry.NewDefaultExecutor(recoursetwirp.WithClassifier())
+//	client := haberdasher.NewHaberdasherProtobufClient(url, http.DefaultClient,
+//		twirp.WithClientInterceptors(recoursetwirp.ClientInterceptor(exec, nil)),
+//	)
+//
+// Twirp client hooks observe calls but cannot repeat them, so retries run in a client
+// interceptor.
+//
+// This package lives in a separate module to keep the Twirp dependency opt-in.
+package twirp
@@ -0,0 +1,12 @@
+module github.com/aponysus/recourse/integrations/twirp
+
+go 1.23.0
+
+replace github.com/aponysus/recourse => ../../
+
+require (
+	github.com/aponysus/recourse v0.0.0-00010101000000-000000000000
+	github.com/twitchtv/twirp v8.1.3+incompatible
+)
+
+require github.com/pkg/errors v0.9.1 // indirect
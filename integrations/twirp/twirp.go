@@ -0,0 +1,126 @@
+package twirp
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+
+	"github.com/twitchtv/twirp"
+
+	"github.com/aponysus/recourse/classify"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultKeyFunc maps RPCs to policy keys.
+// ("acme.hats.Haberdasher", "MakeHat") -> {Namespace: "acme.hats.Haberdasher", Name: "MakeHat"}
+func DefaultKeyFunc(service, method string) policy.PolicyKey {
+	return policy.PolicyKey{Namespace: service, Name: method}
+}
+
+// ClientInterceptor returns a twirp.Interceptor that runs each client RPC under its
+// policy. service is the fully-qualified service name ("<package>.<Service>").
+//
+// Each attempt carries the X-Recourse-Attempt and X-Recourse-Hedge request headers (see
+// integrations/http.Middleware). If exec is nil, the default executor is used; if
+// keyFunc is nil, DefaultKeyFunc is used.
+func ClientInterceptor(exec *retry.Executor, keyFunc func(service, method string) policy.PolicyKey) twirp.Interceptor {
+	if keyFunc == nil {
+		keyFunc = DefaultKeyFunc
+	}
+	return func(next twirp.Method) twirp.Method {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			e := exec
+			if e == nil {
+				e = retry.DefaultExecutor()
+			}
+			key := keyFunc(serviceName(ctx), methodName(ctx))
+			return retry.DoValue(ctx, e, key, func(ctx context.Context) (interface{}, error) {
+				return next(annotateAttempt(ctx), req)
+			})
+		}
+	}
+}
+
+func serviceName(ctx context.Context) string {
+	service, _ := twirp.ServiceName(ctx)
+	if pkg, _ := twirp.PackageName(ctx); pkg != "" {
+		return pkg + "." + service
+	}
+	return service
+}
+
+func methodName(ctx context.Context) string {
+	method, _ := twirp.MethodName(ctx)
+	return method
+}
+
+// annotateAttempt adds the attempt headers to the headers the caller set, if any.
+func annotateAttempt(ctx context.Context) context.Context {
+	h := http.Header{}
+	if prev, ok := twirp.HTTPRequestHeaders(ctx); ok {
+		h = prev.Clone()
+	}
+	recoursehttp.SetAttemptHeaders(ctx, h)
+	if out, err := twirp.WithHTTPRequestHeaders(ctx, h); err == nil {
+		return out
+	}
+	return ctx
+}
+
+// Classifier implements classify.Classifier for Twirp error codes.
+//
+// unavailable and resource_exhausted (which clients also report for 502-504 and 429
+// responses from proxies) are retryable; canceled aborts; every other code, such as
+// invalid_argument, is terminal. Internal errors caused by a failed dial are retryable,
+// since the request was never sent. Non-Twirp errors are delegated to the AutoClassifier.
+type Classifier struct{}
+
+func (Classifier) Classify(val any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "context_deadline_exceeded"}
+	}
+
+	var twerr twirp.Error
+	if !errors.As(err, &twerr) {
+		return classify.AutoClassifier{}.Classify(val, err)
+	}
+
+	code := twerr.Code()
+	out := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "twirp_" + string(code),
+		Attributes: map[string]string{"twirp_code": string(code)},
+	}
+	if status := twerr.Meta("status_code"); status != "" {
+		out.Attributes["status"] = status
+	}
+
+	switch code {
+	case twirp.Unavailable, twirp.ResourceExhausted:
+		out.Kind = classify.OutcomeRetryable
+	case twirp.Canceled:
+		out.Kind = classify.OutcomeAbort
+		out.Reason = "context_canceled"
+	case twirp.Internal:
+		var opErr *net.OpError
+		if errors.As(err, &opErr) && opErr.Op == "dial" {
+			out.Kind = classify.OutcomeRetryable
+			out.Reason = "twirp_connect_error"
+		}
+	}
+	return out
+}
+
+// WithClassifier returns an option to register the Twirp classifier as the default.
+func WithClassifier() retry.DefaultOption {
+	return retry.WithDefaultClassifier(Classifier{})
+}
@@ -0,0 +1,105 @@
+package twirp_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/twitchtv/twirp"
+	"github.com/twitchtv/twirp/ctxsetters"
+
+	"github.com/aponysus/recourse/classify"
+	recoursehttp "github.com/aponysus/recourse/integrations/http"
+	integration "github.com/aponysus/recourse/integrations/twirp"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+func clientContext() context.Context {
+	ctx := ctxsetters.WithPackageName(context.Background(), "acme.hats")
+	ctx = ctxsetters.WithServiceName(ctx, "Haberdasher")
+	return ctxsetters.WithMethodName(ctx, "MakeHat")
+}
+
+func newExec() *retry.Executor {
+	return retry.NewDefaultExecutor(
+		integration.WithClassifier(),
+		retry.WithPolicyKey(policy.PolicyKey{Namespace: "acme.hats.Haberdasher", Name: "MakeHat"},
+			policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Millisecond, 1)),
+	)
+}
+
+func TestClientInterceptor_RetriesUnavailable(t *testing.T) {
+	var attempts []string
+	method := func(ctx context.Context, req interface{}) (interface{}, error) {
+		h, _ := twirp.HTTPRequestHeaders(ctx)
+		attempts = append(attempts, h.Get(recoursehttp.HeaderAttempt)+"/"+h.Get("X-Caller"))
+		if len(attempts) < 3 {
+			return nil, twirp.NewError(twirp.Unavailable, "overloaded")
+		}
+		return "hat", nil
+	}
+
+	ctx, err := twirp.WithHTTPRequestHeaders(clientContext(), map[string][]string{"X-Caller": {"test"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := integration.ClientInterceptor(newExec(), nil)(method)(ctx, "size:12")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != "hat" {
+		t.Fatalf("got %v", resp)
+	}
+	if fmt.Sprint(attempts) != "[0/test 1/test 2/test]" {
+		t.Fatalf("unexpected attempt headers: %v", attempts)
+	}
+}
+
+func TestClientInterceptor_InvalidArgumentNotRetried(t *testing.T) {
+	calls := 0
+	method := func(context.Context, interface{}) (interface{}, error) {
+		calls++
+		return nil, twirp.InvalidArgumentError("inches", "must be positive")
+	}
+
+	_, err := integration.ClientInterceptor(newExec(), nil)(method)(clientContext(), nil)
+	var twerr twirp.Error
+	if !errors.As(err, &twerr) || twerr.Code() != twirp.InvalidArgument {
+		t.Fatalf("expected invalid_argument, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected 1 call, got %d", calls)
+	}
+}
+
+func TestClassifier(t *testing.T) {
+	dialErr := &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}
+	readErr := &net.OpError{Op: "read", Net: "tcp", Err: errors.New("connection reset")}
+
+	tests := []struct {
+		name   string
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{"unavailable", twirp.NewError(twirp.Unavailable, "down"), classify.OutcomeRetryable, "twirp_unavailable"},
+		{"resource exhausted", twirp.NewError(twirp.ResourceExhausted, "slow down"), classify.OutcomeRetryable, "twirp_resource_exhausted"},
+		{"invalid argument", twirp.NewError(twirp.InvalidArgument, "bad"), classify.OutcomeNonRetryable, "twirp_invalid_argument"},
+		{"canceled", twirp.NewError(twirp.Canceled, "canceled"), classify.OutcomeAbort, "context_canceled"},
+		{"dial failure", twirp.InternalErrorWith(dialErr), classify.OutcomeRetryable, "twirp_connect_error"},
+		{"read failure", twirp.InternalErrorWith(readErr), classify.OutcomeNonRetryable, "twirp_internal"},
+		{"context deadline", twirp.InternalErrorWith(context.DeadlineExceeded), classify.OutcomeRetryable, "context_deadline_exceeded"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := integration.Classifier{}.Classify(nil, tt.err)
+			if out.Kind != tt.kind || out.Reason != tt.reason {
+				t.Fatalf("got %v/%s, want %v/%s", out.Kind, out.Reason, tt.kind, tt.reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+// Package admission implements the server-side load shedding shared by the HTTP and
+// gRPC integrations: a per-key circuit breaker, concurrency limit, and admission
+// budget, resolved from each key's policy.
+package admission
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Shedding reasons.
+const (
+	ReasonCircuitOpen  = "shed_circuit_open"
+	ReasonConcurrency  = "shed_concurrency_limit"
+	ReasonBudgetDenied = "shed_budget_denied"
+)
+
+// Options configures a Controller.
+type Options struct {
+	// Provider resolves the policy for each key. Its Circuit settings enable a per-key
+	// breaker and its Retry.Budget names the admission budget. If nil, only the
+	// concurrency limit applies.
+	Provider controlplane.PolicyProvider
+	// Circuits holds per-key breakers. Defaults to a new registry.
+	Circuits *circuit.Registry
+	// Budgets resolves Retry.Budget references. Missing budgets admit the request.
+	Budgets *budget.Registry
+	// MaxConcurrent bounds in-flight requests per key. Zero disables it.
+	MaxConcurrent int
+}
+
+// Controller makes admission decisions. It is safe for concurrent use.
+type Controller struct {
+	provider controlplane.PolicyProvider
+	circuits *circuit.Registry
+	budgets  *budget.Registry
+	limit    int
+
+	mu       sync.Mutex
+	inflight map[policy.PolicyKey]int
+}
+
+// New returns a Controller with opts.
+func New(opts Options) *Controller {
+	c := &Controller{
+		provider: opts.Provider,
+		circuits: opts.Circuits,
+		budgets:  opts.Budgets,
+		limit:    opts.MaxConcurrent,
+		inflight: make(map[policy.PolicyKey]int),
+	}
+	if c.circuits == nil {
+		c.circuits = circuit.NewRegistry()
+	}
+	return c
+}
+
+// Admit runs the admission checks for key. If the request is admitted it returns a
+// completion callback, which must be called once with whether the server failed the
+// request; otherwise it returns the shedding reason.
+//
+// A half-open breaker's probe slot taken by a request that a later check sheds is freed
+// again, so the breaker can still probe.
+func (c *Controller) Admit(ctx context.Context, key policy.PolicyKey) (done func(failed bool), reason string) {
+	var pol policy.EffectivePolicy
+	if c.provider != nil {
+		// Resolution errors fall back to the concurrency limit only; shedding must not
+		// fail closed because the control plane is unavailable.
+		if p, err := c.provider.GetEffectivePolicy(ctx, key); err == nil || !isZeroPolicy(p) {
+			pol = p
+		}
+	}
+
+	var cb circuit.CircuitBreaker
+	probe := false
+	if pol.Circuit.Enabled {
+		cb = c.circuits.Get(key, pol.Circuit)
+		if cb != nil {
+			d := cb.Allow(ctx)
+			if !d.Allowed {
+				return nil, ReasonCircuitOpen
+			}
+			probe = d.State == circuit.StateHalfOpen
+		}
+	}
+	shed := func(reason string) (func(bool), string) {
+		if probe {
+			if pc, ok := cb.(circuit.ProbeCanceler); ok {
+				pc.CancelProbe(ctx)
+			}
+		}
+		return nil, reason
+	}
+
+	if !c.acquire(key) {
+		return shed(ReasonConcurrency)
+	}
+
+	var release func()
+	if ref := pol.Retry.Budget; ref.Name != "" && c.budgets != nil {
+		if b, ok := c.budgets.Get(ref.Name); ok && b != nil {
+			d := b.AllowAttempt(ctx, key, 0, budget.KindRetry, ref)
+			if !d.Allowed {
+				c.release(key)
+				return shed(ReasonBudgetDenied)
+			}
+			release = d.Release
+		}
+	}
+
+	return func(failed bool) {
+		if release != nil {
+			release()
+		}
+		c.release(key)
+		if cb != nil {
+			if failed {
+				cb.RecordFailure(ctx)
+			} else {
+				cb.RecordSuccess(ctx)
+			}
+		}
+	}, ""
+}
+
+func (c *Controller) acquire(key policy.PolicyKey) bool {
+	if c.limit <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inflight[key] >= c.limit {
+		return false
+	}
+	c.inflight[key]++
+	return true
+}
+
+func (c *Controller) release(key policy.PolicyKey) {
+	if c.limit <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if n := c.inflight[key] - 1; n > 0 {
+		c.inflight[key] = n
+	} else {
+		delete(c.inflight, key)
+	}
+}
+
+func isZeroPolicy(p policy.EffectivePolicy) bool {
+	return p.Key == (policy.PolicyKey{}) && p.ID == ""
+}
@@ -11,15 +11,38 @@ type AttemptInfo struct {
 	IsHedge    bool
 	HedgeIndex int
 	PolicyID   string
+	// IdempotencyKey is shared by all attempts of a hedged group when the policy sets
+	// Hedge.IdempotencyKeys; empty otherwise.
+	IdempotencyKey string
+}
+
+// attemptContext carries an AttemptInfo. It replaces context.WithValue so an attempt
+// context costs one allocation rather than two (the value node and the boxed info).
+type attemptContext struct {
+	context.Context
+	info AttemptInfo
+}
+
+func (c *attemptContext) Value(key any) any {
+	if key == (attemptInfoKey{}) {
+		return &c.info
+	}
+	return c.Context.Value(key)
+}
+
+func (c *attemptContext) String() string {
+	return "observe.WithAttemptInfo"
 }
 
 // WithAttemptInfo returns a context derived from ctx that carries info.
 func WithAttemptInfo(ctx context.Context, info AttemptInfo) context.Context {
-	return context.WithValue(ctx, attemptInfoKey{}, info)
+	return &attemptContext{Context: ctx, info: info}
 }
 
 // AttemptFromContext returns the AttemptInfo from ctx, if present.
 func AttemptFromContext(ctx context.Context) (AttemptInfo, bool) {
-	info, ok := ctx.Value(attemptInfoKey{}).(AttemptInfo)
-	return info, ok
+	if info, ok := ctx.Value(attemptInfoKey{}).(*AttemptInfo); ok {
+		return *info, true
+	}
+	return AttemptInfo{}, false
 }
@@ -0,0 +1,34 @@
+package observe
+
+import "context"
+
+type callObserverKey struct{}
+
+// WithObserver returns a context whose calls also report their events to o, alongside
+// the executor's observer. It scopes an expensive observer (verbose logging, tracing
+// every attempt) to one request rather than the whole executor. Calls made with ctx or
+// contexts derived from it, including nested calls made by their operations, report to
+// o. Observers attached to the same context chain all receive events.
+func WithObserver(ctx context.Context, o Observer) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	if o == nil {
+		return ctx
+	}
+	if prev, ok := ObserverFromContext(ctx); ok {
+		o = MultiObserver{Observers: []Observer{prev, o}}
+	}
+	return context.WithValue(ctx, callObserverKey{}, o)
+}
+
+// ObserverFromContext returns the observer attached with WithObserver, if any.
+//
+// This is primarily used by the retry executor.
+func ObserverFromContext(ctx context.Context) (Observer, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	o, ok := ctx.Value(callObserverKey{}).(Observer)
+	return o, ok
+}
@@ -0,0 +1,30 @@
+package observe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestWithObserver_Chains(t *testing.T) {
+	if _, ok := observe.ObserverFromContext(context.Background()); ok {
+		t.Fatal("ObserverFromContext found an observer in a bare context")
+	}
+
+	obsA := &countingObserver{}
+	obsB := &countingObserver{}
+	ctx := observe.WithObserver(context.Background(), obsA)
+	ctx = observe.WithObserver(ctx, nil)
+	ctx = observe.WithObserver(ctx, obsB)
+
+	o, ok := observe.ObserverFromContext(ctx)
+	if !ok {
+		t.Fatal("ObserverFromContext found no observer")
+	}
+	o.OnSuccess(ctx, policy.PolicyKey{}, observe.Timeline{})
+	if obsA.successes != 1 || obsB.successes != 1 {
+		t.Fatalf("successes = %d, %d; want both observers notified", obsA.successes, obsB.successes)
+	}
+}
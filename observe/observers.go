@@ -81,3 +81,39 @@ func (m MultiObserver) OnFailure(ctx context.Context, key policy.PolicyKey, tl T
 		}
 	}
 }
+
+// OnPolicyChange forwards the event to the observers that implement PolicyChangeObserver.
+func (m MultiObserver) OnPolicyChange(ctx context.Context, ev PolicyChangeEvent) {
+	for _, o := range m.Observers {
+		if pc, ok := o.(PolicyChangeObserver); ok {
+			pc.OnPolicyChange(ctx, ev)
+		}
+	}
+}
+
+// OnRetryStorm forwards the event to the observers that implement RetryStormObserver.
+func (m MultiObserver) OnRetryStorm(ctx context.Context, key policy.PolicyKey, stats KeyStats) {
+	for _, o := range m.Observers {
+		if rs, ok := o.(RetryStormObserver); ok {
+			rs.OnRetryStorm(ctx, key, stats)
+		}
+	}
+}
+
+// OnPolicyResolution forwards the event to the observers that implement ProviderObserver.
+func (m MultiObserver) OnPolicyResolution(ctx context.Context, ev PolicyResolutionEvent) {
+	for _, o := range m.Observers {
+		if po, ok := o.(ProviderObserver); ok {
+			po.OnPolicyResolution(ctx, ev)
+		}
+	}
+}
+
+// OnPolicyFallback forwards the event to the observers that implement PolicyFallbackObserver.
+func (m MultiObserver) OnPolicyFallback(ctx context.Context, ev PolicyFallbackEvent) {
+	for _, o := range m.Observers {
+		if pf, ok := o.(PolicyFallbackObserver); ok {
+			pf.OnPolicyFallback(ctx, ev)
+		}
+	}
+}
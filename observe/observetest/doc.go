@@ -0,0 +1,15 @@
+// Package observetest provides assertions on observe.Timeline values, so application
+// tests can check retry behavior without walking Timeline structs by hand:
+//
+//	ctx, capture := observe.RecordTimeline(ctx)
+//	_, _ = client.GetUser(ctx, id)
+//	tl := capture.Timeline()
+//
+//	observetest.AssertAttempts(t, tl, 3)
+//	observetest.AssertOutcomeReasons(t, tl, "context_deadline_exceeded", "context_deadline_exceeded", "success")
+//	observetest.AssertBackoffsWithin(t, tl, 10*time.Millisecond, 100*time.Millisecond)
+//	observetest.AssertGolden(t, tl, "testdata/get_user.golden.json")
+//
+// Golden files hold the stable parts of a timeline (see GoldenJSON). Run tests with
+// RECOURSE_UPDATE_GOLDEN=1 to write them.
+package observetest
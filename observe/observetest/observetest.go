@@ -0,0 +1,202 @@
+package observetest
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+)
+
+// UpdateGoldenEnv is the environment variable that makes AssertGolden write golden
+// files instead of comparing against them.
+const UpdateGoldenEnv = "RECOURSE_UPDATE_GOLDEN"
+
+// AssertAttempts fails t unless tl records n attempts, hedges included.
+func AssertAttempts(t testing.TB, tl observe.Timeline, n int) {
+	t.Helper()
+	if len(tl.Attempts) != n {
+		t.Errorf("timeline %s: %d attempts, want %d (reasons %q)", tl.Key, len(tl.Attempts), n, reasons(tl))
+	}
+}
+
+// AssertOutcomeReasons fails t unless tl's attempts have exactly the given outcome
+// reasons, in order.
+func AssertOutcomeReasons(t testing.TB, tl observe.Timeline, want ...string) {
+	t.Helper()
+	if got := reasons(tl); !slices.Equal(got, want) {
+		t.Errorf("timeline %s: outcome reasons %q, want %q", tl.Key, got, want)
+	}
+}
+
+// AssertBackoffsWithin fails t unless the backoff before every retry (each attempt
+// after the first that is not a hedge) is within [min, max].
+func AssertBackoffsWithin(t testing.TB, tl observe.Timeline, min, max time.Duration) {
+	t.Helper()
+	for _, a := range tl.Attempts {
+		if a.Attempt == 0 || a.IsHedge {
+			continue
+		}
+		if a.Backoff < min || a.Backoff > max {
+			t.Errorf("timeline %s: attempt %d backoff %v, want within [%v, %v]", tl.Key, a.Attempt, a.Backoff, min, max)
+		}
+	}
+}
+
+// GoldenOption configures GoldenJSON and AssertGolden.
+type GoldenOption func(*goldenConfig)
+
+type goldenConfig struct {
+	ignoreBackoff    bool
+	ignoreAttributes []string
+}
+
+// IgnoreBackoff leaves backoffs out of the golden form, for policies with jitter.
+func IgnoreBackoff() GoldenOption {
+	return func(c *goldenConfig) {
+		c.ignoreBackoff = true
+	}
+}
+
+// IgnoreAttributes leaves the named call attributes out of the golden form.
+func IgnoreAttributes(names ...string) GoldenOption {
+	return func(c *goldenConfig) {
+		c.ignoreAttributes = append(c.ignoreAttributes, names...)
+	}
+}
+
+type goldenTimeline struct {
+	Key        string            `json:"key"`
+	PolicyID   string            `json:"policy_id,omitempty"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+	Attempts   []goldenAttempt   `json:"attempts"`
+	Error      string            `json:"error,omitempty"`
+}
+
+type goldenAttempt struct {
+	Attempt    int    `json:"attempt"`
+	Hedge      bool   `json:"hedge,omitempty"`
+	HedgeIndex int    `json:"hedge_index,omitempty"`
+	Outcome    string `json:"outcome"`
+	Reason     string `json:"reason,omitempty"`
+	Error      string `json:"error,omitempty"`
+	Backoff    string `json:"backoff,omitempty"`
+	RetryAfter string `json:"retry_after,omitempty"`
+	Budget     string `json:"budget,omitempty"`
+}
+
+// GoldenJSON returns the stable parts of tl as indented JSON: the key, policy ID, call
+// attributes, final error, and per attempt its index, hedge flags, outcome, error,
+// backoff, retry-after hint, and budget reason. Times and measured durations are
+// omitted, and hedged attempts are ordered by attempt and hedge index, so the form is
+// the same across runs of a deterministic call.
+func GoldenJSON(tl observe.Timeline, opts ...GoldenOption) []byte {
+	var cfg goldenConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	g := goldenTimeline{
+		Key:      tl.Key.String(),
+		PolicyID: tl.PolicyID,
+		Attempts: make([]goldenAttempt, 0, len(tl.Attempts)),
+		Error:    errString(tl.FinalErr),
+	}
+	for k, v := range tl.Attributes {
+		if slices.Contains(cfg.ignoreAttributes, k) {
+			continue
+		}
+		if g.Attributes == nil {
+			g.Attributes = make(map[string]string)
+		}
+		g.Attributes[k] = v
+	}
+	attempts := slices.Clone(tl.Attempts)
+	slices.SortStableFunc(attempts, func(a, b observe.AttemptRecord) int {
+		if a.Attempt != b.Attempt {
+			return a.Attempt - b.Attempt
+		}
+		return a.HedgeIndex - b.HedgeIndex
+	})
+	for _, a := range attempts {
+		ga := goldenAttempt{
+			Attempt:    a.Attempt,
+			Hedge:      a.IsHedge,
+			HedgeIndex: a.HedgeIndex,
+			Outcome:    outcomeKind(a.Outcome.Kind),
+			Reason:     a.Outcome.Reason,
+			Error:      errString(a.Err),
+			Budget:     a.BudgetReason,
+		}
+		if a.Backoff > 0 && !cfg.ignoreBackoff {
+			ga.Backoff = a.Backoff.String()
+		}
+		if a.RetryAfter > 0 {
+			ga.RetryAfter = a.RetryAfter.String()
+		}
+		g.Attempts = append(g.Attempts, ga)
+	}
+	data, _ := json.MarshalIndent(g, "", "  ")
+	return append(data, '\n')
+}
+
+// AssertGolden fails t unless GoldenJSON(tl, opts...) matches the file at path. With
+// UpdateGoldenEnv set to a non-empty value it writes the file instead.
+func AssertGolden(t testing.TB, tl observe.Timeline, path string, opts ...GoldenOption) {
+	t.Helper()
+	got := GoldenJSON(tl, opts...)
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (set %s=1 to create it): %v", UpdateGoldenEnv, err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("timeline %s does not match %s (set %s=1 to update)\ngot:\n%s\nwant:\n%s", tl.Key, path, UpdateGoldenEnv, got, want)
+	}
+}
+
+func reasons(tl observe.Timeline) []string {
+	out := make([]string, len(tl.Attempts))
+	for i, a := range tl.Attempts {
+		out[i] = a.Outcome.Reason
+	}
+	return out
+}
+
+func outcomeKind(k classify.OutcomeKind) string {
+	switch k {
+	case classify.OutcomeSuccess:
+		return "success"
+	case classify.OutcomeRetryable:
+		return "retryable"
+	case classify.OutcomeNonRetryable:
+		return "non_retryable"
+	case classify.OutcomeAbort:
+		return "abort"
+	case classify.OutcomePartial:
+		return "partial"
+	case classify.OutcomeThrottled:
+		return "throttled"
+	default:
+		return "unknown"
+	}
+}
+
+func errString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,100 @@
+package observetest_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/observe/observetest"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/recoursetest"
+	"github.com/aponysus/recourse/retry"
+)
+
+// recorder is a testing.TB that records failures instead of failing the test.
+type recorder struct {
+	testing.TB
+	errors []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...any) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func retriedTimeline(t *testing.T) observe.Timeline {
+	t.Helper()
+	clock := recoursetest.NewClock(time.Time{})
+	exec := retry.NewExecutor(append(clock.Options(),
+		retry.WithPolicy("svc.Get", policy.MaxAttempts(3), policy.Backoff(10*time.Millisecond, time.Second, 2), policy.Jitter(policy.JitterNone)),
+	)...)
+	op := recoursetest.NewScript[int]().FailTimes(2, context.DeadlineExceeded).Succeed(1)
+
+	defer clock.AutoAdvance()()
+	ctx, capture := observe.RecordTimeline(context.Background())
+	if _, err := retry.DoValue(ctx, exec, policy.ParseKey("svc.Get"), op.Value); err != nil {
+		t.Fatal(err)
+	}
+	return *capture.Timeline()
+}
+
+func TestAssertions(t *testing.T) {
+	tl := retriedTimeline(t)
+
+	observetest.AssertAttempts(t, tl, 3)
+	observetest.AssertOutcomeReasons(t, tl, "context_deadline_exceeded", "context_deadline_exceeded", "success")
+	observetest.AssertBackoffsWithin(t, tl, 10*time.Millisecond, 20*time.Millisecond)
+
+	r := &recorder{TB: t}
+	observetest.AssertAttempts(r, tl, 2)
+	observetest.AssertOutcomeReasons(r, tl, "success")
+	observetest.AssertBackoffsWithin(r, tl, 0, 15*time.Millisecond)
+	if len(r.errors) != 3 {
+		t.Fatalf("recorded %d failures, want 3: %q", len(r.errors), r.errors)
+	}
+}
+
+func TestAssertGolden(t *testing.T) {
+	tl := retriedTimeline(t)
+	path := filepath.Join(t.TempDir(), "testdata", "get.golden.json")
+
+	t.Setenv(observetest.UpdateGoldenEnv, "1")
+	observetest.AssertGolden(t, tl, path)
+	t.Setenv(observetest.UpdateGoldenEnv, "")
+	observetest.AssertGolden(t, retriedTimeline(t), path)
+
+	changed := tl
+	changed.FinalErr = errors.New("boom")
+	r := &recorder{TB: t}
+	observetest.AssertGolden(r, changed, path)
+	if len(r.errors) != 1 {
+		t.Fatalf("recorded %d failures, want 1", len(r.errors))
+	}
+}
+
+func TestGoldenJSON_OmitsTimes(t *testing.T) {
+	tl := observe.Timeline{
+		Key:      policy.ParseKey("svc.Get"),
+		Start:    time.Now(),
+		Duration: time.Second,
+		Attempts: []observe.AttemptRecord{{Attempt: 0, StartTime: time.Now(), Duration: time.Millisecond}},
+	}
+	want := `{
+  "key": "svc.Get",
+  "attempts": [
+    {
+      "attempt": 0,
+      "outcome": "unknown"
+    }
+  ]
+}
+`
+	if got := string(observetest.GoldenJSON(tl)); got != want {
+		t.Fatalf("GoldenJSON =\n%s\nwant\n%s", got, want)
+	}
+}
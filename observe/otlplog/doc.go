@@ -0,0 +1,16 @@
+// Package otlplog exports recourse timelines as OpenTelemetry log records over OTLP/HTTP.
+//
+// Each finished call becomes one log record: call-level fields are record attributes and
+// the attempts are a structured body. Records are batched in the background and posted
+// using the OTLP/HTTP JSON encoding, so no OpenTelemetry SDK dependency is required:
+//
+//	exp, err := otlplog.New(otlplog.Options{
+//		Endpoint:    "http://otel-collector:4318",
+//		ServiceName: "checkout",
+//	})
+//	if err != nil {
+//		return err
+//	}
+//	defer exp.Shutdown(context.Background())
+//	exec := retry.NewDefaultExecutor(retry.WithObserver(exp))
+package otlplog
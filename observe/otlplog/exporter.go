@@ -0,0 +1,286 @@
+package otlplog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+const (
+	defaultBatchSize     = 128
+	defaultQueueSize     = 2048
+	defaultFlushInterval = 5 * time.Second
+	defaultTimeout       = 10 * time.Second
+
+	scopeName = "github.com/aponysus/recourse"
+)
+
+// ErrShutdown is returned by Flush after the exporter has been shut down.
+var ErrShutdown = errors.New("otlplog: exporter is shut down")
+
+// Options configures an Exporter.
+type Options struct {
+	// Endpoint is the OTLP/HTTP collector URL. If it has no path, "/v1/logs" is appended.
+	Endpoint string
+	// Headers are added to every export request (e.g. authentication).
+	Headers map[string]string
+	// Client is the HTTP client used for exports. Defaults to a client with a 10s timeout.
+	Client *http.Client
+
+	// ServiceName sets the "service.name" resource attribute.
+	ServiceName string
+	// ResourceAttributes are additional resource attributes.
+	ResourceAttributes map[string]string
+
+	// BatchSize is the maximum number of records per export request. Default 128.
+	BatchSize int
+	// QueueSize bounds the number of pending records; records are dropped when full. Default 2048.
+	QueueSize int
+	// FlushInterval is how often pending records are exported. Default 5s.
+	FlushInterval time.Duration
+}
+
+// Exporter is an Observer that ships one OTLP log record per finished call.
+//
+// Export failures and queue overflow never affect the observed call; they are
+// counted and reported by Dropped.
+type Exporter struct {
+	observe.BaseObserver
+
+	endpoint      string
+	headers       map[string]string
+	client        *http.Client
+	resource      []keyValue
+	batchSize     int
+	flushInterval time.Duration
+
+	queue   chan logRecord
+	flushCh chan chan error
+	done    chan struct{}
+	stopped chan struct{}
+	once    sync.Once
+
+	dropped atomic.Uint64
+}
+
+// New validates opts and starts the background exporter.
+func New(opts Options) (*Exporter, error) {
+	endpoint, err := logsEndpoint(opts.Endpoint)
+	if err != nil {
+		return nil, err
+	}
+
+	e := &Exporter{
+		endpoint:      endpoint,
+		headers:       opts.Headers,
+		client:        opts.Client,
+		batchSize:     opts.BatchSize,
+		flushInterval: opts.FlushInterval,
+		flushCh:       make(chan chan error),
+		done:          make(chan struct{}),
+		stopped:       make(chan struct{}),
+	}
+	if e.client == nil {
+		e.client = &http.Client{Timeout: defaultTimeout}
+	}
+	if e.batchSize <= 0 {
+		e.batchSize = defaultBatchSize
+	}
+	if e.flushInterval <= 0 {
+		e.flushInterval = defaultFlushInterval
+	}
+	queueSize := opts.QueueSize
+	if queueSize <= 0 {
+		queueSize = defaultQueueSize
+	}
+	e.queue = make(chan logRecord, queueSize)
+
+	if opts.ServiceName != "" {
+		e.resource = append(e.resource, stringKV("service.name", opts.ServiceName))
+	}
+	for _, k := range sortedKeys(opts.ResourceAttributes) {
+		e.resource = append(e.resource, stringKV(k, opts.ResourceAttributes[k]))
+	}
+
+	go e.run()
+	return e, nil
+}
+
+func (e *Exporter) OnSuccess(_ context.Context, key policy.PolicyKey, tl observe.Timeline) {
+	e.enqueue(key, tl, true)
+}
+
+func (e *Exporter) OnFailure(_ context.Context, key policy.PolicyKey, tl observe.Timeline) {
+	e.enqueue(key, tl, false)
+}
+
+// Dropped returns the number of records dropped due to a full queue or failed exports.
+func (e *Exporter) Dropped() uint64 {
+	if e == nil {
+		return 0
+	}
+	return e.dropped.Load()
+}
+
+// Flush exports all pending records.
+func (e *Exporter) Flush(ctx context.Context) error {
+	reply := make(chan error, 1)
+	select {
+	case e.flushCh <- reply:
+	case <-e.stopped:
+		return ErrShutdown
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	select {
+	case err := <-reply:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown exports pending records and stops the background goroutine.
+func (e *Exporter) Shutdown(ctx context.Context) error {
+	e.once.Do(func() { close(e.done) })
+	select {
+	case <-e.stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (e *Exporter) enqueue(key policy.PolicyKey, tl observe.Timeline, success bool) {
+	if e == nil {
+		return
+	}
+	select {
+	case <-e.done:
+		e.dropped.Add(1)
+		return
+	default:
+	}
+	rec := newLogRecord(key, tl, success, time.Now())
+	select {
+	case e.queue <- rec:
+	default:
+		e.dropped.Add(1)
+	}
+}
+
+func (e *Exporter) run() {
+	defer close(e.stopped)
+
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+
+	batch := make([]logRecord, 0, e.batchSize)
+	export := func() error {
+		var firstErr error
+		for {
+			// Drain what is queued right now, in batches.
+		drain:
+			for len(batch) < e.batchSize {
+				select {
+				case rec := <-e.queue:
+					batch = append(batch, rec)
+				default:
+					break drain
+				}
+			}
+			if len(batch) == 0 {
+				return firstErr
+			}
+			if err := e.post(batch); err != nil {
+				e.dropped.Add(uint64(len(batch)))
+				if firstErr == nil {
+					firstErr = err
+				}
+			}
+			batch = batch[:0]
+		}
+	}
+
+	for {
+		select {
+		case rec := <-e.queue:
+			batch = append(batch, rec)
+			if len(batch) >= e.batchSize {
+				_ = export()
+			}
+		case <-ticker.C:
+			_ = export()
+		case reply := <-e.flushCh:
+			reply <- export()
+		case <-e.done:
+			_ = export()
+			return
+		}
+	}
+}
+
+func (e *Exporter) post(batch []logRecord) error {
+	payload := exportRequest{ResourceLogs: []resourceLogs{{
+		Resource: resource{Attributes: e.resource},
+		ScopeLogs: []scopeLogs{{
+			Scope:      scope{Name: scopeName},
+			LogRecords: batch,
+		}},
+	}}}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("otlplog: export failed: %s", resp.Status)
+	}
+	return nil
+}
+
+func logsEndpoint(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("otlplog: endpoint is required")
+	}
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", fmt.Errorf("otlplog: invalid endpoint: %w", err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return "", fmt.Errorf("otlplog: invalid endpoint scheme %q", u.Scheme)
+	}
+	if u.Path == "" || u.Path == "/" {
+		u.Path = "/v1/logs"
+	}
+	return u.String(), nil
+}
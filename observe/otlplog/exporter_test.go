@@ -0,0 +1,210 @@
+package otlplog
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type collector struct {
+	mu       sync.Mutex
+	paths    []string
+	headers  []http.Header
+	requests []exportRequest
+	status   int
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	var req exportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	c.mu.Lock()
+	c.paths = append(c.paths, r.URL.Path)
+	c.headers = append(c.headers, r.Header.Clone())
+	c.requests = append(c.requests, req)
+	status := c.status
+	c.mu.Unlock()
+	if status != 0 {
+		w.WriteHeader(status)
+	}
+}
+
+func attr(kvs []keyValue, key string) (anyValue, bool) {
+	for _, kv := range kvs {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return anyValue{}, false
+}
+
+func TestExporter_ExportsTimelineAsLogRecord(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exp, err := New(Options{
+		Endpoint:           srv.URL,
+		Headers:            map[string]string{"Authorization": "Bearer token"},
+		ServiceName:        "checkout",
+		ResourceAttributes: map[string]string{"deployment.environment": "test"},
+		FlushInterval:      time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer exp.Shutdown(context.Background())
+
+	key := policy.PolicyKey{Namespace: "payments", Name: "Charge"}
+	start := time.Unix(1700000000, 0)
+	exp.OnFailure(context.Background(), key, observe.Timeline{
+		Key:        key,
+		PolicyID:   "p1",
+		Start:      start,
+		End:        start.Add(30 * time.Millisecond),
+		Duration:   30 * time.Millisecond,
+		Attributes: map[string]string{"tenant": "acme"},
+		Attempts: []observe.AttemptRecord{
+			{Attempt: 0, Outcome: classify.Outcome{Reason: "http_5xx"}, Duration: 10 * time.Millisecond, BudgetAllowed: true, Err: errors.New("boom")},
+			{Attempt: 1, Outcome: classify.Outcome{Reason: "http_5xx"}, Duration: 10 * time.Millisecond, Backoff: 10 * time.Millisecond, BudgetAllowed: true, Err: errors.New("boom")},
+		},
+		FinalErr: errors.New("boom"),
+	})
+
+	if err := exp.Flush(context.Background()); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.requests) != 1 {
+		t.Fatalf("requests=%d, want 1", len(c.requests))
+	}
+	if c.paths[0] != "/v1/logs" {
+		t.Fatalf("path=%q, want /v1/logs", c.paths[0])
+	}
+	if got := c.headers[0].Get("Authorization"); got != "Bearer token" {
+		t.Fatalf("Authorization=%q", got)
+	}
+	if got := c.headers[0].Get("Content-Type"); got != "application/json" {
+		t.Fatalf("Content-Type=%q", got)
+	}
+
+	rl := c.requests[0].ResourceLogs[0]
+	if v, ok := attr(rl.Resource.Attributes, "service.name"); !ok || *v.StringValue != "checkout" {
+		t.Fatalf("service.name missing: %+v", rl.Resource.Attributes)
+	}
+	if _, ok := attr(rl.Resource.Attributes, "deployment.environment"); !ok {
+		t.Fatalf("resource attribute missing: %+v", rl.Resource.Attributes)
+	}
+	if rl.ScopeLogs[0].Scope.Name != scopeName {
+		t.Fatalf("scope=%q", rl.ScopeLogs[0].Scope.Name)
+	}
+
+	rec := rl.ScopeLogs[0].LogRecords[0]
+	if rec.SeverityNumber != severityWarn || rec.SeverityText != "WARN" {
+		t.Fatalf("severity=%d/%s, want WARN", rec.SeverityNumber, rec.SeverityText)
+	}
+	if rec.TimeUnixNano != "1700000000000000000" {
+		t.Fatalf("timeUnixNano=%q", rec.TimeUnixNano)
+	}
+	checks := map[string]string{
+		"recourse.key":         "payments.Charge",
+		"recourse.result":      "failure",
+		"recourse.policy_id":   "p1",
+		"recourse.attr.tenant": "acme",
+		"exception.message":    "boom",
+	}
+	for k, want := range checks {
+		v, ok := attr(rec.Attributes, k)
+		if !ok || v.StringValue == nil || *v.StringValue != want {
+			t.Fatalf("attribute %s=%+v, want %q", k, v, want)
+		}
+	}
+	if v, ok := attr(rec.Attributes, "recourse.attempts"); !ok || *v.IntValue != "2" {
+		t.Fatalf("recourse.attempts=%+v, want 2", v)
+	}
+
+	attempts, ok := attr(rec.Body.KvlistValue.Values, "attempts")
+	if !ok || len(attempts.ArrayValue.Values) != 2 {
+		t.Fatalf("body attempts=%+v, want 2", attempts)
+	}
+	second := attempts.ArrayValue.Values[1].KvlistValue.Values
+	if v, _ := attr(second, "backoff_ms"); *v.DoubleValue != 10 {
+		t.Fatalf("backoff_ms=%v, want 10", *v.DoubleValue)
+	}
+}
+
+func TestExporter_FlushReportsExportErrors(t *testing.T) {
+	c := &collector{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exp, err := New(Options{Endpoint: srv.URL + "/custom", FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer exp.Shutdown(context.Background())
+
+	exp.OnSuccess(context.Background(), policy.PolicyKey{Name: "x"}, observe.Timeline{})
+	if err := exp.Flush(context.Background()); err == nil {
+		t.Fatalf("expected export error")
+	}
+	if exp.Dropped() != 1 {
+		t.Fatalf("Dropped=%d, want 1", exp.Dropped())
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.paths[0] != "/custom" {
+		t.Fatalf("path=%q, want /custom", c.paths[0])
+	}
+}
+
+func TestExporter_ShutdownExportsPendingRecords(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	exp, err := New(Options{Endpoint: srv.URL, FlushInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	exp.OnSuccess(context.Background(), policy.PolicyKey{Name: "x"}, observe.Timeline{})
+	if err := exp.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	c.mu.Lock()
+	n := len(c.requests)
+	c.mu.Unlock()
+	if n != 1 {
+		t.Fatalf("requests=%d, want 1", n)
+	}
+	if err := exp.Flush(context.Background()); !errors.Is(err, ErrShutdown) {
+		t.Fatalf("Flush after shutdown err=%v, want ErrShutdown", err)
+	}
+
+	exp.OnSuccess(context.Background(), policy.PolicyKey{Name: "x"}, observe.Timeline{})
+	if exp.Dropped() != 1 {
+		t.Fatalf("Dropped=%d, want 1", exp.Dropped())
+	}
+}
+
+func TestNew_InvalidEndpoint(t *testing.T) {
+	for _, ep := range []string{"", "ftp://collector", "://bad"} {
+		if _, err := New(Options{Endpoint: ep}); err == nil {
+			t.Fatalf("New(%q) expected error", ep)
+		}
+	}
+}
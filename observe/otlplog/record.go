@@ -0,0 +1,185 @@
+package otlplog
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// OTLP/HTTP JSON encoding of ExportLogsServiceRequest. 64-bit integers are encoded as
+// decimal strings, as required by the OTLP JSON mapping.
+
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes,omitempty"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+type anyValue struct {
+	StringValue *string      `json:"stringValue,omitempty"`
+	BoolValue   *bool        `json:"boolValue,omitempty"`
+	IntValue    *string      `json:"intValue,omitempty"`
+	DoubleValue *float64     `json:"doubleValue,omitempty"`
+	ArrayValue  *arrayValue  `json:"arrayValue,omitempty"`
+	KvlistValue *kvlistValue `json:"kvlistValue,omitempty"`
+}
+
+type arrayValue struct {
+	Values []anyValue `json:"values"`
+}
+
+type kvlistValue struct {
+	Values []keyValue `json:"values"`
+}
+
+// OTLP severity numbers.
+const (
+	severityInfo = 9
+	severityWarn = 13
+)
+
+func newLogRecord(key policy.PolicyKey, tl observe.Timeline, success bool, observed time.Time) logRecord {
+	ts := tl.Start
+	if ts.IsZero() {
+		ts = observed
+	}
+
+	result := "success"
+	sev, sevText := severityInfo, "INFO"
+	if !success {
+		result = "failure"
+		sev, sevText = severityWarn, "WARN"
+	}
+
+	attrs := []keyValue{
+		stringKV("recourse.key", key.String()),
+		stringKV("recourse.namespace", key.Namespace),
+		stringKV("recourse.name", key.Name),
+		stringKV("recourse.result", result),
+		intKV("recourse.attempts", int64(len(tl.Attempts))),
+		doubleKV("recourse.duration_ms", durationMillis(timelineDuration(tl))),
+	}
+	if tl.PolicyID != "" {
+		attrs = append(attrs, stringKV("recourse.policy_id", tl.PolicyID))
+	}
+	if tl.FinalErr != nil {
+		attrs = append(attrs, stringKV("exception.message", tl.FinalErr.Error()))
+	}
+	for _, k := range sortedKeys(tl.Attributes) {
+		attrs = append(attrs, stringKV("recourse.attr."+k, tl.Attributes[k]))
+	}
+
+	attempts := make([]anyValue, 0, len(tl.Attempts))
+	for _, rec := range tl.Attempts {
+		fields := []keyValue{
+			intKV("attempt", int64(rec.Attempt)),
+			boolKV("hedge", rec.IsHedge),
+			intKV("hedge_index", int64(rec.HedgeIndex)),
+			stringKV("reason", rec.Outcome.Reason),
+			doubleKV("duration_ms", durationMillis(attemptDuration(rec))),
+			doubleKV("backoff_ms", durationMillis(rec.Backoff)),
+			boolKV("budget_allowed", rec.BudgetAllowed),
+		}
+		if rec.RetryAfter > 0 {
+			fields = append(fields, doubleKV("retry_after_ms", durationMillis(rec.RetryAfter)))
+		}
+		if rec.BudgetReason != "" {
+			fields = append(fields, stringKV("budget_reason", rec.BudgetReason))
+		}
+		if rec.Err != nil {
+			fields = append(fields, stringKV("error", rec.Err.Error()))
+		}
+		attempts = append(attempts, anyValue{KvlistValue: &kvlistValue{Values: fields}})
+	}
+
+	body := anyValue{KvlistValue: &kvlistValue{Values: []keyValue{
+		stringKV("message", "recourse call "+result+": "+key.String()),
+		{Key: "attempts", Value: anyValue{ArrayValue: &arrayValue{Values: attempts}}},
+	}}}
+
+	return logRecord{
+		TimeUnixNano:         strconv.FormatInt(ts.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(observed.UnixNano(), 10),
+		SeverityNumber:       sev,
+		SeverityText:         sevText,
+		Body:                 body,
+		Attributes:           attrs,
+	}
+}
+
+func timelineDuration(tl observe.Timeline) time.Duration {
+	if tl.Duration > 0 || tl.Start.IsZero() || tl.End.IsZero() {
+		return tl.Duration
+	}
+	return tl.End.Sub(tl.Start)
+}
+
+func attemptDuration(rec observe.AttemptRecord) time.Duration {
+	if rec.Duration > 0 || rec.StartTime.IsZero() || rec.EndTime.IsZero() {
+		return rec.Duration
+	}
+	return rec.EndTime.Sub(rec.StartTime)
+}
+
+func durationMillis(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func stringKV(k, v string) keyValue {
+	return keyValue{Key: k, Value: anyValue{StringValue: &v}}
+}
+
+func boolKV(k string, v bool) keyValue {
+	return keyValue{Key: k, Value: anyValue{BoolValue: &v}}
+}
+
+func intKV(k string, v int64) keyValue {
+	s := strconv.FormatInt(v, 10)
+	return keyValue{Key: k, Value: anyValue{IntValue: &s}}
+}
+
+func doubleKV(k string, v float64) keyValue {
+	return keyValue{Key: k, Value: anyValue{DoubleValue: &v}}
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package observe
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/aponysus/recourse/classify"
+)
+
+// DefaultReasonPatterns are the reason prefixes a ReasonGuard bounds when none are given:
+// reasons built from an open-ended value, such as "http_<status>" and "grpc_<Code>".
+var DefaultReasonPatterns = []string{"http_", "grpc_"}
+
+// DefaultMaxReasonsPerPattern is the number of distinct reasons per pattern a
+// ReasonGuard reports before bucketing.
+const DefaultMaxReasonsPerPattern = 32
+
+// fixedReasons share a pattern prefix but are fixed strings; a ReasonGuard never buckets
+// them.
+var fixedReasons = map[string]struct{}{
+	classify.ReasonHTTP5xx:                {},
+	classify.ReasonHTTPTransportError:     {},
+	classify.ReasonHTTPNonIdempotent:      {},
+	classify.ReasonHTTPNonRetryableStatus: {},
+}
+
+// ReasonGuard bounds the cardinality of outcome reasons used as metric labels. For each
+// pattern (a reason prefix), the first max distinct reasons seen are reported as is;
+// rarer values that arrive after that are reported as "<prefix>other" (e.g.
+// "http_other"), so a misbehaving upstream cannot create unbounded label values.
+// Reasons that match no pattern pass through unchanged.
+//
+// A nil *ReasonGuard reports every reason as is. It is safe for concurrent use.
+type ReasonGuard struct {
+	max      int
+	patterns []string
+	other    map[string]string // pattern -> "<pattern>other"
+
+	mu      sync.RWMutex
+	allowed map[string]struct{}
+	counts  map[string]int // pattern -> distinct reasons allowed
+}
+
+// NewReasonGuard returns a ReasonGuard allowing maxPerPattern distinct reasons per
+// pattern (DefaultMaxReasonsPerPattern if <= 0). With no patterns it uses
+// DefaultReasonPatterns.
+func NewReasonGuard(maxPerPattern int, patterns ...string) *ReasonGuard {
+	if maxPerPattern <= 0 {
+		maxPerPattern = DefaultMaxReasonsPerPattern
+	}
+	if len(patterns) == 0 {
+		patterns = DefaultReasonPatterns
+	}
+	g := &ReasonGuard{
+		max:      maxPerPattern,
+		patterns: append([]string(nil), patterns...),
+		other:    make(map[string]string, len(patterns)),
+		allowed:  make(map[string]struct{}),
+		counts:   make(map[string]int, len(patterns)),
+	}
+	for _, p := range g.patterns {
+		g.other[p] = p + "other"
+	}
+	return g
+}
+
+// Label returns the label value to report for reason.
+func (g *ReasonGuard) Label(reason string) string {
+	if g == nil {
+		return reason
+	}
+	pattern, ok := g.match(reason)
+	if !ok {
+		return reason
+	}
+
+	g.mu.RLock()
+	_, seen := g.allowed[reason]
+	g.mu.RUnlock()
+	if seen {
+		return reason
+	}
+
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if _, seen := g.allowed[reason]; seen {
+		return reason
+	}
+	if g.counts[pattern] >= g.max {
+		return g.other[pattern]
+	}
+	g.counts[pattern]++
+	g.allowed[reason] = struct{}{}
+	return reason
+}
+
+func (g *ReasonGuard) match(reason string) (string, bool) {
+	if _, ok := fixedReasons[reason]; ok {
+		return "", false
+	}
+	for _, p := range g.patterns {
+		if strings.HasPrefix(reason, p) && reason != g.other[p] {
+			return p, true
+		}
+	}
+	return "", false
+}
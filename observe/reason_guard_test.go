@@ -0,0 +1,64 @@
+package observe_test
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+)
+
+func TestReasonGuard_BucketsRareValues(t *testing.T) {
+	g := observe.NewReasonGuard(2)
+
+	for _, tc := range []struct{ in, want string }{
+		{"http_429", "http_429"},
+		{"http_503", "http_503"},
+		{"http_418", "http_other"},
+		{"http_429", "http_429"}, // Already admitted.
+		{"http_5xx", "http_5xx"}, // Fixed reason, never bucketed.
+		{"grpc_Unavailable", "grpc_Unavailable"},
+		{"timeout", "timeout"}, // No pattern.
+		{"http_other", "http_other"},
+	} {
+		if got := g.Label(tc.in); got != tc.want {
+			t.Errorf("Label(%q)=%q, want %q", tc.in, got, tc.want)
+		}
+	}
+}
+
+func TestReasonGuard_CustomPatternsAndNil(t *testing.T) {
+	g := observe.NewReasonGuard(1, "twirp_")
+	g.Label("twirp_unavailable")
+	if got := g.Label("twirp_internal"); got != "twirp_other" {
+		t.Fatalf("Label=%q, want twirp_other", got)
+	}
+	if got := g.Label("http_999"); got != "http_999" {
+		t.Fatalf("Label=%q, want http_999 (not a configured pattern)", got)
+	}
+
+	var nilGuard *observe.ReasonGuard
+	if got := nilGuard.Label("http_418"); got != "http_418" {
+		t.Fatalf("nil guard Label=%q, want http_418", got)
+	}
+}
+
+func TestReasonGuard_ConcurrentBound(t *testing.T) {
+	g := observe.NewReasonGuard(5)
+	labels := sync.Map{}
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			labels.Store(g.Label(fmt.Sprintf("http_%d", 400+i)), struct{}{})
+		}(i)
+	}
+	wg.Wait()
+
+	n := 0
+	labels.Range(func(any, any) bool { n++; return true })
+	if n != 6 { // Five admitted reasons plus http_other.
+		t.Fatalf("distinct labels=%d, want 6", n)
+	}
+}
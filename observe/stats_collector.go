@@ -0,0 +1,280 @@
+package observe
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultStatsWindow is the number of recent calls retained per key when no window is given.
+const DefaultStatsWindow = 256
+
+// KeyStats is a rolling summary of the most recent calls for a single policy key.
+type KeyStats struct {
+	Key policy.PolicyKey `json:"key"` // Policy key the stats describe.
+
+	Calls     int `json:"calls"`     // Calls in the window.
+	Successes int `json:"successes"` // Calls that succeeded.
+	Failures  int `json:"failures"`  // Calls that failed.
+	Attempts  int `json:"attempts"`  // Attempts (including hedges) across the window.
+
+	RetriedCalls int `json:"retried_calls"` // Calls that needed more than one retry attempt.
+	HedgedCalls  int `json:"hedged_calls"`  // Calls that spawned at least one hedge.
+
+	SuccessRate float64 `json:"success_rate"` // Successes / Calls.
+	RetryRate   float64 `json:"retry_rate"`   // RetriedCalls / Calls.
+	HedgeRate   float64 `json:"hedge_rate"`   // HedgedCalls / Calls.
+
+	// RetryRatio is the number of attempts beyond each call's first, retries and hedges
+	// alike, per call: (Attempts - Calls) / Calls.
+	RetryRatio float64 `json:"retry_ratio"`
+	// AttemptRate is attempts per second over the span from the window's earliest call
+	// start to its latest call end. It is 0 when the calls carry no timestamps.
+	AttemptRate float64 `json:"attempt_rate"`
+
+	// RetrySuccessRate is the fraction of retried calls that eventually succeeded.
+	// It is 0 when no call in the window was retried.
+	RetrySuccessRate float64 `json:"retry_success_rate"`
+
+	P50 time.Duration `json:"p50"` // Median call latency.
+	P95 time.Duration `json:"p95"` // 95th percentile call latency.
+}
+
+type callSample struct {
+	success  bool
+	attempts int
+	retried  bool
+	hedged   bool
+	latency  time.Duration
+	start    time.Time
+	end      time.Time
+}
+
+type statsWindow struct {
+	samples []callSample
+	idx     int
+	full    bool
+}
+
+// StatsCollector is an Observer that maintains rolling per-key call statistics.
+//
+// Each key keeps the outcome of its most recent calls in a fixed-size ring buffer;
+// aggregates are computed on read. It is safe for concurrent use and implements
+// http.Handler so it can be mounted on an admin endpoint.
+type StatsCollector struct {
+	BaseObserver
+
+	window int
+
+	mu   sync.RWMutex
+	keys map[policy.PolicyKey]*statsWindow
+}
+
+// NewStatsCollector returns a collector retaining the last window calls per key.
+// A window <= 0 uses DefaultStatsWindow.
+func NewStatsCollector(window int) *StatsCollector {
+	if window <= 0 {
+		window = DefaultStatsWindow
+	}
+	return &StatsCollector{
+		window: window,
+		keys:   make(map[policy.PolicyKey]*statsWindow),
+	}
+}
+
+func (c *StatsCollector) OnSuccess(_ context.Context, key policy.PolicyKey, tl Timeline) {
+	c.record(key, tl, true)
+}
+
+func (c *StatsCollector) OnFailure(_ context.Context, key policy.PolicyKey, tl Timeline) {
+	c.record(key, tl, false)
+}
+
+func (c *StatsCollector) record(key policy.PolicyKey, tl Timeline, success bool) {
+	if c == nil {
+		return
+	}
+
+	s := callSample{success: success, attempts: len(tl.Attempts), latency: tl.Duration, start: tl.Start, end: tl.End}
+	if s.latency <= 0 && !tl.Start.IsZero() && tl.End.After(tl.Start) {
+		s.latency = tl.End.Sub(tl.Start)
+	}
+	for _, rec := range tl.Attempts {
+		if rec.IsHedge {
+			s.hedged = true
+		} else if rec.Attempt > 0 {
+			s.retried = true
+		}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.keys == nil {
+		c.keys = make(map[policy.PolicyKey]*statsWindow)
+	}
+	w, ok := c.keys[key]
+	if !ok {
+		size := c.window
+		if size <= 0 {
+			size = DefaultStatsWindow
+		}
+		w = &statsWindow{samples: make([]callSample, size)}
+		c.keys[key] = w
+	}
+	w.samples[w.idx] = s
+	w.idx++
+	if w.idx >= len(w.samples) {
+		w.idx = 0
+		w.full = true
+	}
+}
+
+// Stats returns the rolling stats for key, or false if no call has been observed.
+func (c *StatsCollector) Stats(key policy.PolicyKey) (KeyStats, bool) {
+	if c == nil {
+		return KeyStats{}, false
+	}
+
+	c.mu.RLock()
+	w, ok := c.keys[key]
+	var samples []callSample
+	if ok {
+		n := w.idx
+		if w.full {
+			n = len(w.samples)
+		}
+		samples = make([]callSample, n)
+		copy(samples, w.samples[:n])
+	}
+	c.mu.RUnlock()
+
+	if !ok || len(samples) == 0 {
+		return KeyStats{Key: key}, false
+	}
+	return summarize(key, samples), true
+}
+
+// Keys returns the observed keys in sorted order.
+func (c *StatsCollector) Keys() []policy.PolicyKey {
+	if c == nil {
+		return nil
+	}
+	c.mu.RLock()
+	keys := make([]policy.PolicyKey, 0, len(c.keys))
+	for k := range c.keys {
+		keys = append(keys, k)
+	}
+	c.mu.RUnlock()
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	return keys
+}
+
+// Snapshot returns stats for every observed key, sorted by key.
+func (c *StatsCollector) Snapshot() []KeyStats {
+	keys := c.Keys()
+	out := make([]KeyStats, 0, len(keys))
+	for _, k := range keys {
+		if s, ok := c.Stats(k); ok {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Reset discards all collected samples.
+func (c *StatsCollector) Reset() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.keys = make(map[policy.PolicyKey]*statsWindow)
+	c.mu.Unlock()
+}
+
+// ServeHTTP writes the collected stats as JSON.
+//
+// With a "key" query parameter (e.g. ?key=svc.Method) it returns that key's stats
+// (404 if unknown); otherwise it returns the stats for all keys.
+func (c *StatsCollector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if raw := strings.TrimSpace(r.URL.Query().Get("key")); raw != "" {
+		s, ok := c.Stats(policy.ParseKey(raw))
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			_ = json.NewEncoder(w).Encode(map[string]string{"error": "no stats for key"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(s)
+		return
+	}
+	_ = json.NewEncoder(w).Encode(c.Snapshot())
+}
+
+func summarize(key policy.PolicyKey, samples []callSample) KeyStats {
+	s := KeyStats{Key: key, Calls: len(samples)}
+	retriedSuccesses := 0
+	latencies := make([]time.Duration, 0, len(samples))
+	var first, last time.Time
+	for _, cs := range samples {
+		s.Attempts += cs.attempts
+		if !cs.start.IsZero() && (first.IsZero() || cs.start.Before(first)) {
+			first = cs.start
+		}
+		if cs.end.After(last) {
+			last = cs.end
+		}
+		if cs.success {
+			s.Successes++
+		} else {
+			s.Failures++
+		}
+		if cs.retried {
+			s.RetriedCalls++
+			if cs.success {
+				retriedSuccesses++
+			}
+		}
+		if cs.hedged {
+			s.HedgedCalls++
+		}
+		latencies = append(latencies, cs.latency)
+	}
+
+	calls := float64(s.Calls)
+	s.SuccessRate = float64(s.Successes) / calls
+	s.RetryRate = float64(s.RetriedCalls) / calls
+	s.HedgeRate = float64(s.HedgedCalls) / calls
+	s.RetryRatio = float64(s.Attempts-s.Calls) / calls
+	if !first.IsZero() && last.After(first) {
+		s.AttemptRate = float64(s.Attempts) / last.Sub(first).Seconds()
+	}
+	if s.RetriedCalls > 0 {
+		s.RetrySuccessRate = float64(retriedSuccesses) / float64(s.RetriedCalls)
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	s.P50 = quantile(latencies, 0.50)
+	s.P95 = quantile(latencies, 0.95)
+	return s
+}
+
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	idx := int(float64(len(sorted)-1) * q)
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
+}
@@ -0,0 +1,108 @@
+package observe_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestStatsCollector_Aggregates(t *testing.T) {
+	c := observe.NewStatsCollector(10)
+	key := policy.PolicyKey{Namespace: "svc", Name: "m"}
+	ctx := context.Background()
+
+	// 1 clean success, 1 success after retry, 1 hedged success, 1 failure after retry.
+	c.OnSuccess(ctx, key, observe.Timeline{Duration: 10 * time.Millisecond, Attempts: []observe.AttemptRecord{{Attempt: 0}}})
+	c.OnSuccess(ctx, key, observe.Timeline{Duration: 20 * time.Millisecond, Attempts: []observe.AttemptRecord{{Attempt: 0}, {Attempt: 1}}})
+	c.OnSuccess(ctx, key, observe.Timeline{Duration: 30 * time.Millisecond, Attempts: []observe.AttemptRecord{{Attempt: 0}, {Attempt: 0, IsHedge: true, HedgeIndex: 1}}})
+	c.OnFailure(ctx, key, observe.Timeline{Duration: 40 * time.Millisecond, FinalErr: errors.New("x"), Attempts: []observe.AttemptRecord{{Attempt: 0}, {Attempt: 1}}})
+
+	s, ok := c.Stats(key)
+	if !ok {
+		t.Fatal("expected stats")
+	}
+	if s.Calls != 4 || s.Successes != 3 || s.Failures != 1 || s.Attempts != 7 {
+		t.Fatalf("unexpected counts: %+v", s)
+	}
+	if s.SuccessRate != 0.75 || s.RetryRate != 0.5 || s.HedgeRate != 0.25 || s.RetrySuccessRate != 0.5 {
+		t.Fatalf("unexpected rates: %+v", s)
+	}
+	if s.P50 != 20*time.Millisecond || s.P95 != 30*time.Millisecond {
+		t.Fatalf("p50=%v p95=%v", s.P50, s.P95)
+	}
+	if s.RetryRatio != 0.75 || s.AttemptRate != 0 {
+		t.Fatalf("retry ratio=%v attempt rate=%v, want 0.75 and 0 without timestamps", s.RetryRatio, s.AttemptRate)
+	}
+
+	if _, ok := c.Stats(policy.PolicyKey{Name: "other"}); ok {
+		t.Fatal("expected no stats for unknown key")
+	}
+}
+
+func TestStatsCollector_AttemptRate(t *testing.T) {
+	c := observe.NewStatsCollector(10)
+	key := policy.PolicyKey{Name: "k"}
+	start := time.Unix(100, 0)
+
+	// 4 attempts over the 2s from the first call's start to the last call's end.
+	c.OnSuccess(context.Background(), key, observe.Timeline{Start: start, End: start.Add(time.Second), Attempts: make([]observe.AttemptRecord, 3)})
+	c.OnSuccess(context.Background(), key, observe.Timeline{Start: start.Add(time.Second), End: start.Add(2 * time.Second), Attempts: make([]observe.AttemptRecord, 1)})
+
+	if s, _ := c.Stats(key); s.AttemptRate != 2 || s.RetryRatio != 1 {
+		t.Fatalf("attempt rate=%v retry ratio=%v, want 2 and 1", s.AttemptRate, s.RetryRatio)
+	}
+}
+
+func TestStatsCollector_WindowRolls(t *testing.T) {
+	c := observe.NewStatsCollector(2)
+	key := policy.PolicyKey{Name: "k"}
+	ctx := context.Background()
+
+	c.OnFailure(ctx, key, observe.Timeline{})
+	c.OnSuccess(ctx, key, observe.Timeline{})
+	c.OnSuccess(ctx, key, observe.Timeline{})
+
+	s, _ := c.Stats(key)
+	if s.Calls != 2 || s.SuccessRate != 1 {
+		t.Fatalf("expected failure to roll out of window, got %+v", s)
+	}
+}
+
+func TestStatsCollector_ServeHTTP(t *testing.T) {
+	c := observe.NewStatsCollector(0)
+	key := policy.ParseKey("svc.Method")
+	c.OnSuccess(context.Background(), key, observe.Timeline{})
+
+	rr := httptest.NewRecorder()
+	c.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats?key=svc.Method", nil))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("status=%d", rr.Code)
+	}
+	var got observe.KeyStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if got.Key != key || got.Calls != 1 {
+		t.Fatalf("got %+v", got)
+	}
+
+	rr = httptest.NewRecorder()
+	c.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats?key=missing.Key", nil))
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("status=%d, want 404", rr.Code)
+	}
+
+	rr = httptest.NewRecorder()
+	c.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var all []observe.KeyStats
+	if err := json.Unmarshal(rr.Body.Bytes(), &all); err != nil || len(all) != 1 {
+		t.Fatalf("all=%+v err=%v", all, err)
+	}
+}
@@ -0,0 +1,12 @@
+// Package statsd provides an observer that emits DogStatsD metrics for recourse calls.
+//
+// It speaks the plain DogStatsD line protocol over UDP (or any io.Writer), so it has
+// no dependencies beyond the standard library:
+//
+//	obs, err := statsd.New("127.0.0.1:8125", statsd.Options{Tags: []string{"env:prod"}})
+//	if err != nil {
+//		return err
+//	}
+//	defer obs.Close()
+//	exec := retry.NewDefaultExecutor(retry.WithObserver(obs))
+package statsd
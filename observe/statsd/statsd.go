@@ -0,0 +1,199 @@
+package statsd
+
+import (
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultPrefix is prepended to metric names when Options.Prefix is empty.
+const DefaultPrefix = "recourse."
+
+// Options configures an Observer.
+type Options struct {
+	// Prefix is prepended to every metric name. Defaults to DefaultPrefix.
+	Prefix string
+	// Tags are constant tags (e.g. "env:prod") appended to every metric.
+	Tags []string
+	// MaxReasonsPerPattern bounds the distinct "outcome" tag values per reason pattern
+	// ("http_<status>", "grpc_<Code>"); rarer values are reported as "http_other" and
+	// so on (see observe.ReasonGuard). Zero uses observe.DefaultMaxReasonsPerPattern;
+	// negative disables the bound.
+	MaxReasonsPerPattern int
+}
+
+// Observer emits DogStatsD counters and timers for recourse calls.
+//
+// Metrics (with the default prefix):
+//   - recourse.calls (counter; namespace, name, result)
+//   - recourse.call.latency (timer; namespace, name, result)
+//   - recourse.attempts (counter; namespace, name, outcome, hedge)
+//   - recourse.attempt.latency (timer; namespace, name, hedge)
+//   - recourse.hedges (counter; namespace, name)
+//   - recourse.budget.decisions (counter; namespace, name, allowed, reason)
+//
+// Write errors are ignored: metrics must never affect the call being observed.
+type Observer struct {
+	observe.BaseObserver
+
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+	prefix string
+	tags   []string
+	guard  *observe.ReasonGuard
+}
+
+// New dials addr over UDP and returns an Observer writing to it.
+func New(addr string, opts Options) (*Observer, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	o := NewWithWriter(conn, opts)
+	o.closer = conn
+	return o, nil
+}
+
+// NewWithWriter returns an Observer that writes one metric per Write call to w.
+func NewWithWriter(w io.Writer, opts Options) *Observer {
+	prefix := opts.Prefix
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+	tags := make([]string, 0, len(opts.Tags))
+	for _, t := range opts.Tags {
+		if t = strings.TrimSpace(t); t != "" {
+			tags = append(tags, sanitize(t))
+		}
+	}
+	o := &Observer{w: w, prefix: prefix, tags: tags}
+	if opts.MaxReasonsPerPattern >= 0 {
+		o.guard = observe.NewReasonGuard(opts.MaxReasonsPerPattern)
+	}
+	return o
+}
+
+// Close closes the underlying connection if the Observer created it.
+func (o *Observer) Close() error {
+	if o == nil || o.closer == nil {
+		return nil
+	}
+	return o.closer.Close()
+}
+
+func (o *Observer) OnAttempt(_ context.Context, key policy.PolicyKey, rec observe.AttemptRecord) {
+	outcome := o.guard.Label(rec.Outcome.Reason)
+	if outcome == "" {
+		outcome = "unknown"
+	}
+	hedge := strconv.FormatBool(rec.IsHedge)
+	o.count("attempts", key, "outcome:"+outcome, "hedge:"+hedge)
+	if d, ok := attemptDuration(rec); ok {
+		o.timing("attempt.latency", d, key, "hedge:"+hedge)
+	}
+}
+
+func (o *Observer) OnHedgeSpawn(_ context.Context, key policy.PolicyKey, _ observe.AttemptRecord) {
+	o.count("hedges", key)
+}
+
+func (o *Observer) OnBudgetDecision(_ context.Context, ev observe.BudgetDecisionEvent) {
+	reason := ev.Reason
+	if reason == "" {
+		reason = "unknown"
+	}
+	o.count("budget.decisions", ev.Key, "allowed:"+strconv.FormatBool(ev.Allowed), "reason:"+reason)
+}
+
+func (o *Observer) OnSuccess(_ context.Context, key policy.PolicyKey, tl observe.Timeline) {
+	o.observeCall(key, tl, "success")
+}
+
+func (o *Observer) OnFailure(_ context.Context, key policy.PolicyKey, tl observe.Timeline) {
+	o.observeCall(key, tl, "failure")
+}
+
+func (o *Observer) observeCall(key policy.PolicyKey, tl observe.Timeline, result string) {
+	o.count("calls", key, "result:"+result)
+	if d, ok := callDuration(tl); ok {
+		o.timing("call.latency", d, key, "result:"+result)
+	}
+}
+
+func (o *Observer) count(name string, key policy.PolicyKey, tags ...string) {
+	o.emit(name, "1", "c", key, tags)
+}
+
+func (o *Observer) timing(name string, d time.Duration, key policy.PolicyKey, tags ...string) {
+	ms := strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', -1, 64)
+	o.emit(name, ms, "ms", key, tags)
+}
+
+func (o *Observer) emit(name, value, typ string, key policy.PolicyKey, tags []string) {
+	if o == nil || o.w == nil {
+		return
+	}
+
+	var b strings.Builder
+	b.WriteString(o.prefix)
+	b.WriteString(name)
+	b.WriteByte(':')
+	b.WriteString(value)
+	b.WriteByte('|')
+	b.WriteString(typ)
+	b.WriteString("|#namespace:")
+	b.WriteString(sanitize(key.Namespace))
+	b.WriteString(",name:")
+	b.WriteString(sanitize(key.Name))
+	for _, t := range tags {
+		b.WriteByte(',')
+		b.WriteString(sanitize(t))
+	}
+	for _, t := range o.tags {
+		b.WriteByte(',')
+		b.WriteString(t)
+	}
+
+	o.mu.Lock()
+	_, _ = io.WriteString(o.w, b.String())
+	o.mu.Unlock()
+}
+
+func attemptDuration(rec observe.AttemptRecord) (time.Duration, bool) {
+	if rec.Duration > 0 {
+		return rec.Duration, true
+	}
+	if rec.StartTime.IsZero() || rec.EndTime.IsZero() {
+		return 0, false
+	}
+	return rec.EndTime.Sub(rec.StartTime), true
+}
+
+func callDuration(tl observe.Timeline) (time.Duration, bool) {
+	if tl.Duration > 0 {
+		return tl.Duration, true
+	}
+	if tl.Start.IsZero() || tl.End.IsZero() {
+		return 0, false
+	}
+	return tl.End.Sub(tl.Start), true
+}
+
+// sanitize replaces characters that are reserved by the DogStatsD protocol.
+func sanitize(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '|', ',', '#', '\n':
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,119 @@
+package statsd
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type lineRecorder struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (r *lineRecorder) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, string(p))
+	return len(p), nil
+}
+
+func TestObserver_EmitsDogStatsDLines(t *testing.T) {
+	rec := &lineRecorder{}
+	obs := NewWithWriter(rec, Options{Tags: []string{"env:test"}})
+
+	key := policy.PolicyKey{Namespace: "svc", Name: "method"}
+	ctx := context.Background()
+
+	obs.OnAttempt(ctx, key, observe.AttemptRecord{
+		Outcome:  classify.Outcome{Reason: "http_5xx"},
+		Duration: 1500 * time.Microsecond,
+	})
+	obs.OnHedgeSpawn(ctx, key, observe.AttemptRecord{IsHedge: true})
+	obs.OnBudgetDecision(ctx, observe.BudgetDecisionEvent{Key: key, Allowed: false, Reason: "budget_denied"})
+	obs.OnFailure(ctx, key, observe.Timeline{Key: key, Duration: 20 * time.Millisecond})
+
+	want := []string{
+		"recourse.attempts:1|c|#namespace:svc,name:method,outcome:http_5xx,hedge:false,env:test",
+		"recourse.attempt.latency:1.5|ms|#namespace:svc,name:method,hedge:false,env:test",
+		"recourse.hedges:1|c|#namespace:svc,name:method,env:test",
+		"recourse.budget.decisions:1|c|#namespace:svc,name:method,allowed:false,reason:budget_denied,env:test",
+		"recourse.calls:1|c|#namespace:svc,name:method,result:failure,env:test",
+		"recourse.call.latency:20|ms|#namespace:svc,name:method,result:failure,env:test",
+	}
+	if len(rec.lines) != len(want) {
+		t.Fatalf("lines=%q, want %d lines", rec.lines, len(want))
+	}
+	for i := range want {
+		if rec.lines[i] != want[i] {
+			t.Errorf("line[%d]=%q, want %q", i, rec.lines[i], want[i])
+		}
+	}
+}
+
+func TestObserver_BoundsOutcomeReasons(t *testing.T) {
+	rec := &lineRecorder{}
+	obs := NewWithWriter(rec, Options{MaxReasonsPerPattern: 1})
+	key := policy.PolicyKey{Namespace: "svc", Name: "method"}
+
+	for _, reason := range []string{"http_429", "http_418", "http_429"} {
+		obs.OnAttempt(context.Background(), key, observe.AttemptRecord{Outcome: classify.Outcome{Reason: reason}})
+	}
+	var outcomes []string
+	for _, line := range rec.lines {
+		if i := strings.Index(line, "outcome:"); i >= 0 {
+			outcomes = append(outcomes, strings.SplitN(line[i:], ",", 2)[0])
+		}
+	}
+	want := []string{"outcome:http_429", "outcome:http_other", "outcome:http_429"}
+	if strings.Join(outcomes, " ") != strings.Join(want, " ") {
+		t.Fatalf("outcome tags=%v, want %v", outcomes, want)
+	}
+}
+
+func TestObserver_SanitizesTagValues(t *testing.T) {
+	rec := &lineRecorder{}
+	obs := NewWithWriter(rec, Options{Prefix: "app."})
+
+	obs.OnSuccess(context.Background(), policy.PolicyKey{Namespace: "a|b", Name: "c,d#e"}, observe.Timeline{})
+
+	if len(rec.lines) != 1 {
+		t.Fatalf("lines=%q, want 1 line", rec.lines)
+	}
+	if got, want := rec.lines[0], "app.calls:1|c|#namespace:a_b,name:c_d_e,result:success"; got != want {
+		t.Fatalf("line=%q, want %q", got, want)
+	}
+}
+
+func TestNew_WritesOverUDP(t *testing.T) {
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("udp unavailable: %v", err)
+	}
+	defer pc.Close()
+
+	obs, err := New(pc.LocalAddr().String(), Options{})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer obs.Close()
+
+	obs.OnSuccess(context.Background(), policy.PolicyKey{Name: "x"}, observe.Timeline{})
+
+	_ = pc.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, 512)
+	n, _, err := pc.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if got := string(buf[:n]); !strings.HasPrefix(got, "recourse.calls:1|c|") {
+		t.Fatalf("packet=%q", got)
+	}
+}
@@ -0,0 +1,67 @@
+package observe
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultTimelineBufferSize is the number of timelines a TimelineBuffer keeps when no
+// size is given.
+const DefaultTimelineBufferSize = 100
+
+// TimelineBuffer is an Observer that keeps the timelines of the most recent calls in a
+// ring buffer, e.g. for an admin endpoint. It is safe for concurrent use.
+type TimelineBuffer struct {
+	BaseObserver
+
+	mu   sync.Mutex
+	buf  []Timeline
+	next int
+	full bool
+}
+
+// NewTimelineBuffer returns a buffer keeping the last size timelines. A size <= 0 uses
+// DefaultTimelineBufferSize.
+func NewTimelineBuffer(size int) *TimelineBuffer {
+	if size <= 0 {
+		size = DefaultTimelineBufferSize
+	}
+	return &TimelineBuffer{buf: make([]Timeline, size)}
+}
+
+// OnSuccess records the timeline.
+func (b *TimelineBuffer) OnSuccess(_ context.Context, _ policy.PolicyKey, tl Timeline) {
+	b.add(tl)
+}
+
+// OnFailure records the timeline.
+func (b *TimelineBuffer) OnFailure(_ context.Context, _ policy.PolicyKey, tl Timeline) {
+	b.add(tl)
+}
+
+func (b *TimelineBuffer) add(tl Timeline) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.buf[b.next] = tl
+	b.next = (b.next + 1) % len(b.buf)
+	if b.next == 0 {
+		b.full = true
+	}
+}
+
+// Timelines returns the buffered timelines, newest first.
+func (b *TimelineBuffer) Timelines() []Timeline {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	n := b.next
+	if b.full {
+		n = len(b.buf)
+	}
+	out := make([]Timeline, 0, n)
+	for i := 1; i <= n; i++ {
+		out = append(out, b.buf[(b.next-i+len(b.buf))%len(b.buf)])
+	}
+	return out
+}
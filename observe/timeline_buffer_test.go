@@ -0,0 +1,23 @@
+package observe_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestTimelineBuffer(t *testing.T) {
+	b := observe.NewTimelineBuffer(3)
+	if got := b.Timelines(); len(got) != 0 {
+		t.Fatalf("empty buffer = %d timelines", len(got))
+	}
+	for _, id := range []string{"a", "b", "c", "d"} {
+		b.OnSuccess(context.Background(), policy.PolicyKey{Name: id}, observe.Timeline{PolicyID: id})
+	}
+	got := b.Timelines()
+	if len(got) != 3 || got[0].PolicyID != "d" || got[1].PolicyID != "c" || got[2].PolicyID != "b" {
+		t.Fatalf("timelines = %+v, want d, c, b", got)
+	}
+}
@@ -2,6 +2,7 @@ package observe
 
 import (
 	"context"
+	"slices"
 	"time"
 
 	"github.com/aponysus/recourse/budget"
@@ -21,37 +22,134 @@ type BudgetDecisionEvent struct {
 	Reason     string             // Decision reason (see budget reasons).
 }
 
+// KnownBudgetModes returns every BudgetDecisionEvent.Mode the executor reports, sorted.
+// The list is generated from source (see docs/reference/reason-codes.md).
+func KnownBudgetModes() []string { return slices.Clone(knownBudgetModes) }
+
+// PolicyChangeEvent describes a change to a key's effective policy, observed when the
+// executor resolves a policy that differs from the one it last resolved for the key.
+type PolicyChangeEvent struct {
+	Key    policy.PolicyKey       // Policy key whose policy changed.
+	Old    policy.EffectivePolicy // Previously resolved policy.
+	New    policy.EffectivePolicy // Newly resolved policy.
+	Source policy.PolicySource    // Source of the new policy (New.Meta.Source).
+	Diff   []policy.FieldChange   // Changed fields (see policy.Diff).
+}
+
+// PolicyChangeObserver is an optional Observer extension. When the executor's observer
+// implements it, the executor tracks the last policy resolved per key and reports
+// changes, giving operators an audit trail of retry behavior. The first resolution of a
+// key is not a change.
+type PolicyChangeObserver interface {
+	OnPolicyChange(ctx context.Context, ev PolicyChangeEvent)
+}
+
+// PolicyResolutionEvent describes one guarded policy provider lookup (see
+// retry.WithProviderProtection).
+type PolicyResolutionEvent struct {
+	Key      policy.PolicyKey // Policy key being resolved.
+	Duration time.Duration    // Time spent waiting for the provider.
+	Err      error            // Resolution error (nil on success; ErrPolicyNotFound counts as healthy).
+	TimedOut bool             // Whether the lookup was abandoned after the protection timeout.
+	Rejected bool             // Whether the provider's circuit was open and it was not called.
+}
+
+// ProviderObserver is an optional Observer extension that receives an event for every
+// policy lookup made through a protected provider, for resolution latency and error
+// rate metrics.
+type ProviderObserver interface {
+	OnPolicyResolution(ctx context.Context, ev PolicyResolutionEvent)
+}
+
+// Policy fallback reasons reported in PolicyFallbackEvent.Reason.
+const (
+	PolicyFallbackNotFound      = "policy_not_found" // The provider has no policy for the key.
+	PolicyFallbackProviderError = "provider_error"   // The provider failed.
+	PolicyFallbackProviderPanic = "provider_panic"   // The provider panicked (see retry.WithRecoverPanics).
+	PolicyFallbackInvalid       = "invalid_policy"   // The resolved policy failed normalization.
+)
+
+// PolicyFallbackEvent describes a call whose policy could not be resolved normally, and
+// what the executor's missing-policy mode (see retry.ExecutorOptions.MissingPolicyMode)
+// did about it.
+type PolicyFallbackEvent struct {
+	Key    policy.PolicyKey // Policy key being resolved.
+	Reason string           // Why resolution fell back (see the PolicyFallback reasons).
+	Mode   string           // Mode taken: "deny" fails the call, "allow" runs a single attempt, "fallback" uses the default policy.
+	Err    error            // Provider or normalization error.
+}
+
+// PolicyFallbackObserver is an optional Observer extension. When a call's observer
+// implements it, the executor reports each call whose policy resolution fell back, so
+// fail-open and fail-deny decisions show up in telemetry and not only in call errors.
+type PolicyFallbackObserver interface {
+	OnPolicyFallback(ctx context.Context, ev PolicyFallbackEvent)
+}
+
+// RetryStormObserver is an optional Observer extension. When the executor's observer
+// implements it and retry-storm detection is enabled (see retry.WithRetryStorm), the
+// executor reports each key that starts a retry storm, with the stats that breached the
+// thresholds. A storm is reported once, when detected, not on every call during it.
+type RetryStormObserver interface {
+	OnRetryStorm(ctx context.Context, key policy.PolicyKey, stats KeyStats)
+}
+
 // AttemptRecord describes a single attempt (or hedge) execution.
 type AttemptRecord struct {
-	Attempt   int       // Attempt index (0-based).
-	StartTime time.Time // Attempt start time.
-	EndTime   time.Time // Attempt end time.
+	Attempt   int           // Attempt index (0-based).
+	StartTime time.Time     // Attempt start time.
+	EndTime   time.Time     // Attempt end time.
+	Duration  time.Duration // Attempt duration measured with the monotonic clock.
 
 	IsHedge    bool // Whether this attempt is a hedge.
 	HedgeIndex int  // Hedge index within the attempt group.
 
+	IdempotencyKey string // Idempotency key shared by the attempt group (see policy.HedgeIdempotencyKeys).
+
 	Outcome classify.Outcome // Classification outcome for this attempt.
 
 	Err error // Error returned by the attempt (if any).
 
-	Backoff time.Duration // Backoff delay before this attempt.
+	Backoff       time.Duration // Backoff delay before this attempt.
+	BackoffActual time.Duration // Measured (monotonic) time spent sleeping before this attempt.
+	RetryAfter    time.Duration // Server-provided retry delay hint (e.g. Retry-After) from the outcome, if any.
 
 	BudgetAllowed bool   // Whether budget gating allowed this attempt.
 	BudgetReason  string // Budget decision reason (see budget reasons).
 }
 
+// ShadowDecision is what a shadow (candidate) policy would have decided after an
+// attempt group of a call executed under the current policy.
+type ShadowDecision struct {
+	Attempt int              // Attempt index (0-based) the decision follows.
+	Outcome classify.Outcome // Attempt outcome under the candidate's classifier.
+	Retry   bool             // Whether the candidate would have retried.
+	Backoff time.Duration    // Delay the candidate would have slept before retrying.
+	Actual  bool             // Whether the executor actually retried under the current policy.
+}
+
 // Timeline is the structured record of a single call and all of its attempts.
 type Timeline struct {
 	Key      policy.PolicyKey // Policy key for the call.
 	PolicyID string           // Policy identifier (if set).
 	Start    time.Time        // Call start time.
 	End      time.Time        // Call end time.
+	Duration time.Duration    // Call duration measured with the monotonic clock.
 
 	// Attributes holds call-level metadata (policy source, fallbacks, normalization notes, etc.).
 	Attributes map[string]string
 
 	Attempts []AttemptRecord // Per-attempt records in execution order.
 	FinalErr error           // Final error returned to the caller.
+
+	// DroppedAttempts counts the attempts whose records the executor dropped to stay
+	// within its memory bounds (see retry.MemoryBounds).
+	DroppedAttempts int
+
+	// Shadow holds the would-be decisions of the key's shadow policy, when the executor
+	// has a shadow provider (see retry.WithShadowProvider). Decisions stop where the
+	// candidate would have stopped.
+	Shadow []ShadowDecision
 }
 
 // Observer receives lifecycle callbacks for a single call.
@@ -0,0 +1,98 @@
+package policy
+
+import (
+	"slices"
+	"sync"
+)
+
+// normalizedFields lists the fields Normalize may change, in the order it visits them,
+// so a set of changes maps to the same ChangedFields order as marking them one by one.
+var normalizedFields = [...]string{
+	"retry.max_attempts",
+	"retry.initial_backoff",
+	"retry.max_backoff",
+	"retry.backoff_multiplier",
+	"retry.jitter",
+	"retry.backoff_overrides",
+	"retry.timeout_per_attempt",
+	"retry.overall_timeout",
+	"retry.budget.cost",
+	"hedge.budget.cost",
+	"limits.max_in_flight",
+	"limits.max_qps",
+	"limits.burst",
+	"rollout.percent",
+	"rollout.by",
+	"hedge.max_hedges",
+	"hedge.hedge_delay",
+	"circuit.threshold",
+	"circuit.cooldown",
+}
+
+// changedFields collects the fields one Normalize call changed, as a bit per
+// normalizedFields entry.
+type changedFields uint32
+
+func (c *changedFields) mark(field string) {
+	for i, f := range normalizedFields {
+		if f == field {
+			*c |= 1 << i
+			return
+		}
+	}
+	panic("policy: unknown normalized field " + field)
+}
+
+// changedFieldLists interns ChangedFields slices by change set: providers normalize
+// on every lookup, and most policies change the same fields every time, so sharing the
+// slice keeps policy resolution free of allocations.
+var changedFieldLists struct {
+	sync.RWMutex
+	m map[changedFields][]string
+}
+
+func (c changedFields) list() []string {
+	changedFieldLists.RLock()
+	l, ok := changedFieldLists.m[c]
+	changedFieldLists.RUnlock()
+	if ok {
+		return l
+	}
+
+	for i, f := range normalizedFields {
+		if c&(1<<i) != 0 {
+			l = append(l, f)
+		}
+	}
+	l = slices.Clip(l)
+	changedFieldLists.Lock()
+	defer changedFieldLists.Unlock()
+	if changedFieldLists.m == nil {
+		changedFieldLists.m = make(map[changedFields][]string)
+	}
+	if prev, ok := changedFieldLists.m[c]; ok {
+		return prev
+	}
+	changedFieldLists.m[c] = l
+	return l
+}
+
+// record adds the changes to norm. Fields already listed (when normalizing a
+// normalized policy) are kept first, without duplicates.
+func (c changedFields) record(norm *NormalizationInfo) {
+	if c == 0 {
+		return
+	}
+	norm.Changed = true
+	if len(norm.ChangedFields) == 0 {
+		norm.ChangedFields = c.list()
+		return
+	}
+	fields := slices.Clone(norm.ChangedFields)
+	for _, f := range c.list() {
+		if !slices.Contains(fields, f) {
+			fields = append(fields, f)
+		}
+	}
+	norm.ChangedFields = fields
+}
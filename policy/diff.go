@@ -0,0 +1,99 @@
+package policy
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// FieldChange is one field that differs between two policies.
+type FieldChange struct {
+	Field string // Dot-delimited JSON field path (e.g. "retry.max_attempts").
+	Old   string // Previous value, formatted with fmt.
+	New   string // New value, formatted with fmt.
+}
+
+// Diff returns the fields of the policy envelope (ID, retry, hedge, circuit, limits,
+// rollout percentage and subject) that differ between old and new, in declaration
+// order. Key, metadata, and a rollout's previous policy are not compared; a policy
+// without a rollout counts as fully rolled out.
+func Diff(old, new EffectivePolicy) []FieldChange {
+	var changes []FieldChange
+	if old.ID != new.ID {
+		changes = append(changes, FieldChange{Field: "id", Old: old.ID, New: new.ID})
+	}
+	changes = diffStruct(changes, "retry", reflect.ValueOf(old.Retry), reflect.ValueOf(new.Retry))
+	changes = diffStruct(changes, "hedge", reflect.ValueOf(old.Hedge), reflect.ValueOf(new.Hedge))
+	changes = diffStruct(changes, "circuit", reflect.ValueOf(old.Circuit), reflect.ValueOf(new.Circuit))
+	changes = diffStruct(changes, "limits", reflect.ValueOf(old.Limits), reflect.ValueOf(new.Limits))
+	oldPct, oldBy := rolloutFields(old.Rollout)
+	newPct, newBy := rolloutFields(new.Rollout)
+	if oldPct != newPct {
+		changes = append(changes, FieldChange{Field: "rollout.percent", Old: fmt.Sprint(oldPct), New: fmt.Sprint(newPct)})
+	}
+	if oldBy != newBy {
+		changes = append(changes, FieldChange{Field: "rollout.by", Old: string(oldBy), New: string(newBy)})
+	}
+	return changes
+}
+
+func diffStruct(changes []FieldChange, prefix string, a, b reflect.Value) []FieldChange {
+	t := a.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "" {
+			name = f.Name
+		}
+		path := prefix + "." + name
+		av, bv := a.Field(i), b.Field(i)
+		if f.Type.Kind() == reflect.Struct {
+			changes = diffStruct(changes, path, av, bv)
+			continue
+		}
+		if f.Type.Kind() == reflect.Map {
+			changes = diffMap(changes, path, av, bv)
+			continue
+		}
+		if av.Interface() != bv.Interface() {
+			changes = append(changes, FieldChange{Field: path, Old: fmt.Sprint(av.Interface()), New: fmt.Sprint(bv.Interface())})
+		}
+	}
+	return changes
+}
+
+// diffMap reports changed, added, and removed entries of two string-keyed maps with
+// comparable values, in key order.
+func diffMap(changes []FieldChange, prefix string, a, b reflect.Value) []FieldChange {
+	keys := make(map[string]struct{}, a.Len()+b.Len())
+	for _, k := range a.MapKeys() {
+		keys[k.String()] = struct{}{}
+	}
+	for _, k := range b.MapKeys() {
+		keys[k.String()] = struct{}{}
+	}
+	format := func(v reflect.Value) string {
+		if !v.IsValid() {
+			return ""
+		}
+		return fmt.Sprintf("%+v", v.Interface())
+	}
+	for _, k := range slices.Sorted(maps.Keys(keys)) {
+		kv := reflect.ValueOf(k).Convert(a.Type().Key())
+		av, bv := a.MapIndex(kv), b.MapIndex(kv)
+		if av.IsValid() && bv.IsValid() && av.Interface() == bv.Interface() {
+			continue
+		}
+		changes = append(changes, FieldChange{Field: prefix + "." + k, Old: format(av), New: format(bv)})
+	}
+	return changes
+}
+
+func rolloutFields(r *Rollout) (float64, RolloutBy) {
+	if r == nil {
+		return 100, ""
+	}
+	return r.Percent, r.By
+}
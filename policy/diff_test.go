@@ -0,0 +1,47 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiff(t *testing.T) {
+	old := New("svc.Get", MaxAttempts(3), InitialBackoff(10*time.Millisecond))
+	new := New("svc.Get", MaxAttempts(5), InitialBackoff(10*time.Millisecond), EnableHedging())
+	new.Meta.Source = PolicySourceRemote
+
+	got := Diff(old, new)
+	want := []FieldChange{
+		{Field: "retry.max_attempts", Old: "3", New: "5"},
+		{Field: "hedge.enabled", Old: "false", New: "true"},
+		{Field: "hedge.max_hedges", Old: "0", New: "2"},
+		{Field: "hedge.hedge_delay", Old: "0s", New: "200ms"},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Diff = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Diff[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if d := Diff(old, old); len(d) != 0 {
+		t.Errorf("Diff(old, old) = %+v, want none", d)
+	}
+}
+
+func TestDiff_BackoffOverrides(t *testing.T) {
+	old := New("svc.Get")
+	old.Retry.BackoffOverrides = map[string]BackoffSpec{"timeout": {InitialBackoff: time.Second}}
+	new := New("svc.Get")
+	new.Retry.BackoffOverrides = map[string]BackoffSpec{"http_429": {InitialBackoff: time.Second}}
+
+	got := Diff(old, new)
+	if len(got) != 2 || got[0].Field != "retry.backoff_overrides.http_429" || got[0].Old != "" ||
+		got[1].Field != "retry.backoff_overrides.timeout" || got[1].New != "" {
+		t.Fatalf("Diff = %+v, want http_429 added and timeout removed", got)
+	}
+	if old.Retry.Equal(new.Retry) {
+		t.Error("Equal ignored BackoffOverrides")
+	}
+}
@@ -0,0 +1,139 @@
+package envoyconfig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/aponysus/recourse/classify"
+)
+
+// Classifier applies Envoy retry_on conditions.
+//
+// HTTP errors (classify.HTTPError) are matched against 5xx, gateway-error, retriable-4xx,
+// and retriable-status-codes; status 0 (no response) and other non-gRPC errors match 5xx,
+// reset, connect-failure, and refused-stream. gRPC conditions apply when GRPCCode
+// recognizes the error. As in Envoy, the request method is not considered.
+type Classifier struct {
+	// GRPCCode extracts a gRPC status code name from err ("Unavailable", "UNAVAILABLE",
+	// or "unavailable"). Leave nil for HTTP-only routes.
+	GRPCCode func(err error) (string, bool)
+
+	conditions        map[string]struct{}
+	statusCodes       map[int]struct{}
+	respectRetryAfter bool
+}
+
+func (c *Classifier) Classify(_ any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess, Reason: "success"}
+	}
+	if errors.Is(err, context.Canceled) {
+		return classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}
+	}
+
+	if c.GRPCCode != nil {
+		if code, ok := c.GRPCCode(err); ok {
+			return c.classifyGRPC(code)
+		}
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		// Envoy reports an expired per-try timeout as a 504.
+		return c.match("context_deadline_exceeded", "5xx", "gateway-error")
+	}
+
+	var he classify.HTTPError
+	if !errors.As(err, &he) || he.HTTPStatusCode() == 0 {
+		return c.match("envoy_reset", "5xx", "reset", "connect-failure", "refused-stream")
+	}
+
+	status := he.HTTPStatusCode()
+	out := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "envoy_not_retriable",
+		Attributes: map[string]string{"status": strconv.Itoa(status)},
+	}
+	switch {
+	case c.has("retriable-status-codes") && c.hasStatus(status):
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "envoy_retriable_status_code"
+	case status >= 502 && status <= 504 && c.has("gateway-error"):
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "envoy_gateway_error"
+	case status >= 500 && status <= 599 && c.has("5xx"):
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "envoy_5xx"
+	case status == http.StatusConflict && c.has("retriable-4xx"):
+		out.Kind = classify.OutcomeRetryable
+		out.Reason = "envoy_retriable_4xx"
+	}
+	if out.Kind == classify.OutcomeRetryable && c.respectRetryAfter {
+		if d, ok := he.RetryAfter(); ok && d > 0 {
+			out.BackoffOverride = d
+			out.Attributes["retry_after"] = d.String()
+		}
+	}
+	return out
+}
+
+func (c *Classifier) classifyGRPC(code string) classify.Outcome {
+	cond := grpcCondition(code)
+	out := classify.Outcome{
+		Kind:       classify.OutcomeNonRetryable,
+		Reason:     "envoy_not_retriable",
+		Attributes: map[string]string{"grpc_code": cond},
+	}
+	switch cond {
+	case "cancelled", "deadline-exceeded", "internal", "resource-exhausted", "unavailable":
+		if c.has(cond) {
+			out.Kind = classify.OutcomeRetryable
+			out.Reason = "envoy_grpc_" + strings.ReplaceAll(cond, "-", "_")
+		}
+	}
+	return out
+}
+
+// match returns a retryable outcome if any of conditions is configured.
+func (c *Classifier) match(reason string, conditions ...string) classify.Outcome {
+	for _, cond := range conditions {
+		if c.has(cond) {
+			return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: reason}
+		}
+	}
+	return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "envoy_not_retriable"}
+}
+
+func (c *Classifier) has(cond string) bool {
+	_, ok := c.conditions[cond]
+	return ok
+}
+
+func (c *Classifier) hasStatus(status int) bool {
+	_, ok := c.statusCodes[status]
+	return ok
+}
+
+// grpcCondition converts a status code name to its retry_on spelling:
+// "DeadlineExceeded", "DEADLINE_EXCEEDED" -> "deadline-exceeded"; "Canceled" -> "cancelled".
+func grpcCondition(code string) string {
+	var b strings.Builder
+	for i, r := range code {
+		switch {
+		case r == '_':
+			b.WriteByte('-')
+		case unicode.IsUpper(r) && i > 0 && unicode.IsLower(rune(code[i-1])):
+			b.WriteByte('-')
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	if s := b.String(); s != "canceled" {
+		return s
+	}
+	return "cancelled"
+}
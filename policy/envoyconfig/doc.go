@@ -0,0 +1,25 @@
+// Package envoyconfig imports Envoy route retry policies as recourse policies.
+//
+// Meshes that move retries from the sidecar into the client can keep the Envoy route
+// configuration as the single source of truth. ParseRouteConfiguration reads a
+// RouteConfiguration (proto JSON, as served by xDS or dumped from /config_dump) and
+// converts each route's effective retry_policy: num_retries, per_try_timeout,
+// retry_back_off, and the route timeout. ParseRetryPolicy converts a single
+// retry_policy block.
+//
+// retry_on conditions cannot be expressed in an EffectivePolicy, so each policy names a
+// classifier (see Config.Classifiers) that applies those conditions to HTTP errors
+// (classify.HTTPError) and, given a GRPCCode func, to gRPC status errors.
+//
+// Usage:
+//
+//	cfg, err := envoyconfig.ParseRouteConfiguration(routeConfigJSON)
+//	if err != nil {
+//		return err
+//	}
+//	opts := []retry.ExecutorOption{retry.WithProvider(cfg)}
+//	for name, cls := range cfg.Classifiers() {
+//		opts = append(opts, retry.WithClassifier(name, cls))
+//	}
+//	exec := retry.NewDefaultExecutor(opts...)
+package envoyconfig
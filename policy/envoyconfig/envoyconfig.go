@@ -0,0 +1,424 @@
+package envoyconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+// ClassifierPrefix prefixes the classifier names produced for imported policies.
+const ClassifierPrefix = "envoy:"
+
+// Envoy defaults for retry_policy.
+const (
+	defaultNumRetries    = 1
+	defaultBaseInterval  = 25 * time.Millisecond
+	maxIntervalFactor    = 10
+	backoffMultiplier    = 2
+	defaultRouteTimeout  = 15 * time.Second
+	routeTimeoutDisabled = 0
+)
+
+// supportedConditions are the retry_on values the classifier understands.
+var supportedConditions = map[string]struct{}{
+	"5xx":                    {},
+	"gateway-error":          {},
+	"reset":                  {},
+	"connect-failure":        {},
+	"refused-stream":         {},
+	"retriable-4xx":          {},
+	"retriable-status-codes": {},
+	"cancelled":              {},
+	"deadline-exceeded":      {},
+	"internal":               {},
+	"resource-exhausted":     {},
+	"unavailable":            {},
+}
+
+// RetryPolicy is a converted Envoy retry_policy.
+type RetryPolicy struct {
+	// Retry holds num_retries, per_try_timeout, and retry_back_off. ClassifierName is set.
+	Retry policy.RetryPolicy
+
+	// Conditions are the supported retry_on conditions, sorted.
+	Conditions []string
+	// RetriableStatusCodes lists retriable_status_codes, sorted.
+	RetriableStatusCodes []int
+	// RespectRetryAfter is set when rate_limited_retry_back_off is configured.
+	RespectRetryAfter bool
+
+	// Unsupported lists retry_on conditions and fields that were ignored
+	// (retriable-headers, envoy-ratelimited, retry_host_predicate, ...).
+	Unsupported []string
+}
+
+// Classifier returns a classifier applying the policy's retry_on conditions.
+func (p *RetryPolicy) Classifier() *Classifier {
+	c := &Classifier{
+		conditions:        make(map[string]struct{}, len(p.Conditions)),
+		statusCodes:       make(map[int]struct{}, len(p.RetriableStatusCodes)),
+		respectRetryAfter: p.RespectRetryAfter,
+	}
+	for _, cond := range p.Conditions {
+		c.conditions[cond] = struct{}{}
+	}
+	for _, code := range p.RetriableStatusCodes {
+		c.statusCodes[code] = struct{}{}
+	}
+	return c
+}
+
+// Route is the imported policy for one route.
+type Route struct {
+	// Key is {Namespace: <virtual host name>, Name: <route name>}. A route without a name
+	// uses its cluster name. An empty Name holds the virtual host's own retry_policy.
+	Key    policy.PolicyKey
+	Policy policy.EffectivePolicy
+	Retry  *RetryPolicy
+}
+
+// Config is an imported RouteConfiguration.
+type Config struct {
+	Routes []Route
+
+	byKey       map[policy.PolicyKey]policy.EffectivePolicy
+	classifiers map[string]*Classifier
+}
+
+// ParseRetryPolicy converts a single Envoy retry_policy JSON object.
+func ParseRetryPolicy(data []byte) (*RetryPolicy, error) {
+	var raw retryPolicy
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("envoyconfig: %w", err)
+	}
+	return convertRetryPolicy(&raw, "retry_policy")
+}
+
+// ParseRouteConfiguration converts the retry policies of an Envoy RouteConfiguration.
+//
+// Route-level retry_policy replaces the virtual host's, as in Envoy; routes without one
+// inherit it. Routes with neither get single-attempt policies. The route action's timeout
+// (default 15s, "0s" disables) becomes the overall timeout.
+func ParseRouteConfiguration(data []byte) (*Config, error) {
+	var raw routeConfiguration
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("envoyconfig: %w", err)
+	}
+
+	cfg := &Config{
+		byKey:       make(map[policy.PolicyKey]policy.EffectivePolicy),
+		classifiers: make(map[string]*Classifier),
+	}
+	for i, vh := range raw.VirtualHosts {
+		field := fmt.Sprintf("virtual_hosts[%d]", i)
+		if vh.Name == "" {
+			return nil, fmt.Errorf("envoyconfig: %s.name: must not be empty", field)
+		}
+
+		var hostRetry *RetryPolicy
+		if vh.RetryPolicy != nil {
+			rp, err := convertRetryPolicy(vh.RetryPolicy, field+".retry_policy")
+			if err != nil {
+				return nil, err
+			}
+			hostRetry = rp
+			cfg.add(policy.PolicyKey{Namespace: vh.Name}, rp, defaultRouteTimeout)
+		}
+
+		for j, rt := range vh.Routes {
+			rfield := fmt.Sprintf("%s.routes[%d]", field, j)
+			if rt.Route == nil {
+				// Redirects and direct responses are never retried.
+				continue
+			}
+			name := rt.Name
+			if name == "" {
+				name = rt.Route.Cluster
+			}
+			if name == "" {
+				return nil, fmt.Errorf("envoyconfig: %s: route needs a name or cluster", rfield)
+			}
+
+			rp := hostRetry
+			if rt.Route.RetryPolicy != nil {
+				var err error
+				if rp, err = convertRetryPolicy(rt.Route.RetryPolicy, rfield+".route.retry_policy"); err != nil {
+					return nil, err
+				}
+			}
+
+			timeout := defaultRouteTimeout
+			if rt.Route.Timeout != nil {
+				d, err := parseDuration(*rt.Route.Timeout)
+				if err != nil {
+					return nil, fieldError(rfield+".route.timeout", err)
+				}
+				timeout = d
+			}
+			cfg.add(policy.PolicyKey{Namespace: vh.Name, Name: name}, rp, timeout)
+		}
+	}
+	return cfg, nil
+}
+
+// add records the policy for key. Envoy matches routes in order, so when several routes
+// share a key (unnamed routes to one cluster), the first one wins.
+func (c *Config) add(key policy.PolicyKey, rp *RetryPolicy, timeout time.Duration) {
+	if _, dup := c.byKey[key]; dup {
+		return
+	}
+	if rp == nil {
+		rp = &RetryPolicy{Retry: policy.RetryPolicy{MaxAttempts: 1}}
+		rp.Retry.ClassifierName = classifierName(nil, nil)
+	}
+
+	pol := policy.EffectivePolicy{
+		Key:   key,
+		Retry: rp.Retry,
+		Meta:  policy.Metadata{Source: policy.PolicySourceStatic},
+	}
+	pol.Retry.Budget.Cost = 1
+	pol.Hedge.Budget.Cost = 1
+	if timeout != routeTimeoutDisabled {
+		pol.Retry.OverallTimeout = timeout
+	}
+
+	c.byKey[key] = pol
+	c.Routes = append(c.Routes, Route{Key: key, Policy: pol, Retry: rp})
+	if _, ok := c.classifiers[pol.Retry.ClassifierName]; !ok {
+		c.classifiers[pol.Retry.ClassifierName] = rp.Classifier()
+	}
+}
+
+// Policies returns the imported policies by key, for use with controlplane.StaticProvider.
+func (c *Config) Policies() map[policy.PolicyKey]policy.EffectivePolicy {
+	out := make(map[policy.PolicyKey]policy.EffectivePolicy, len(c.byKey))
+	for k, v := range c.byKey {
+		out[k] = v
+	}
+	return out
+}
+
+// Classifiers returns a classifier for each classifier name used by the policies.
+// Register them on the executor with retry.WithClassifier.
+func (c *Config) Classifiers() map[string]classify.Classifier {
+	out := make(map[string]classify.Classifier, len(c.classifiers))
+	for name, cls := range c.classifiers {
+		out[name] = cls
+	}
+	return out
+}
+
+// SetGRPCCode sets the gRPC status extractor on every classifier (see Classifier.GRPCCode).
+// Call it before the classifiers are in use.
+func (c *Config) SetGRPCCode(fn func(error) (string, bool)) {
+	for _, cls := range c.classifiers {
+		cls.GRPCCode = fn
+	}
+}
+
+// GetEffectivePolicy implements controlplane.PolicyProvider.
+//
+// It returns the route's policy, else the virtual host's retry_policy, else a
+// single-attempt policy.
+func (c *Config) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	for _, k := range []policy.PolicyKey{key, {Namespace: key.Namespace}} {
+		if pol, ok := c.byKey[k]; ok {
+			pol.Key = key
+			return pol.Normalize()
+		}
+	}
+
+	pol := policy.DefaultPolicyFor(key)
+	pol.Retry.MaxAttempts = 1
+	pol.Meta.Source = policy.PolicySourceStatic
+	return pol.Normalize()
+}
+
+type routeConfiguration struct {
+	VirtualHosts []virtualHost `json:"virtual_hosts"`
+}
+
+type virtualHost struct {
+	Name        string       `json:"name"`
+	RetryPolicy *retryPolicy `json:"retry_policy"`
+	Routes      []route      `json:"routes"`
+}
+
+type route struct {
+	Name  string       `json:"name"`
+	Route *routeAction `json:"route"`
+}
+
+type routeAction struct {
+	Cluster     string       `json:"cluster"`
+	Timeout     *string      `json:"timeout"`
+	RetryPolicy *retryPolicy `json:"retry_policy"`
+}
+
+type retryPolicy struct {
+	RetryOn                 string          `json:"retry_on"`
+	NumRetries              *int            `json:"num_retries"`
+	PerTryTimeout           *string         `json:"per_try_timeout"`
+	RetryBackOff            *retryBackOff   `json:"retry_back_off"`
+	RetriableStatusCodes    []int           `json:"retriable_status_codes"`
+	RateLimitedRetryBackOff json.RawMessage `json:"rate_limited_retry_back_off"`
+	RetriableHeaders        json.RawMessage `json:"retriable_headers"`
+	RetryHostPredicate      json.RawMessage `json:"retry_host_predicate"`
+	RetryPriority           json.RawMessage `json:"retry_priority"`
+}
+
+type retryBackOff struct {
+	BaseInterval string  `json:"base_interval"`
+	MaxInterval  *string `json:"max_interval"`
+}
+
+func convertRetryPolicy(raw *retryPolicy, field string) (*RetryPolicy, error) {
+	rp := &RetryPolicy{}
+
+	numRetries := defaultNumRetries
+	if raw.NumRetries != nil {
+		numRetries = *raw.NumRetries
+	}
+	if numRetries < 0 {
+		return nil, fmt.Errorf("envoyconfig: %s.num_retries: must not be negative", field)
+	}
+	rp.Retry.MaxAttempts = numRetries + 1
+
+	if raw.PerTryTimeout != nil {
+		d, err := parseDuration(*raw.PerTryTimeout)
+		if err != nil {
+			return nil, fieldError(field+".per_try_timeout", err)
+		}
+		rp.Retry.TimeoutPerAttempt = d
+	}
+
+	base := defaultBaseInterval
+	var maxInterval time.Duration
+	if bo := raw.RetryBackOff; bo != nil {
+		d, err := parseDuration(bo.BaseInterval)
+		if err != nil || d <= 0 {
+			return nil, fieldError(field+".retry_back_off.base_interval", positive(err))
+		}
+		base = d
+		if bo.MaxInterval != nil {
+			if maxInterval, err = parseDuration(*bo.MaxInterval); err != nil {
+				return nil, fieldError(field+".retry_back_off.max_interval", err)
+			}
+			if maxInterval < base {
+				return nil, fmt.Errorf("envoyconfig: %s.retry_back_off.max_interval: must be at least base_interval", field)
+			}
+		}
+	}
+	if maxInterval == 0 {
+		maxInterval = maxIntervalFactor * base
+	}
+	rp.Retry.InitialBackoff = base
+	rp.Retry.MaxBackoff = maxInterval
+	rp.Retry.BackoffMultiplier = backoffMultiplier
+	// Envoy uses fully jittered exponential backoff.
+	rp.Retry.Jitter = policy.JitterFull
+
+	for _, cond := range strings.Split(raw.RetryOn, ",") {
+		cond = strings.TrimSpace(cond)
+		if cond == "" {
+			continue
+		}
+		if _, ok := supportedConditions[cond]; ok {
+			rp.Conditions = appendUnique(rp.Conditions, cond)
+		} else {
+			rp.Unsupported = appendUnique(rp.Unsupported, "retry_on:"+cond)
+		}
+	}
+	sort.Strings(rp.Conditions)
+
+	if rp.hasCondition("retriable-status-codes") {
+		for _, code := range raw.RetriableStatusCodes {
+			if code < 100 || code > 599 {
+				return nil, fmt.Errorf("envoyconfig: %s.retriable_status_codes: invalid status %d", field, code)
+			}
+			rp.RetriableStatusCodes = appendUnique(rp.RetriableStatusCodes, code)
+		}
+		sort.Ints(rp.RetriableStatusCodes)
+	}
+
+	rp.RespectRetryAfter = len(raw.RateLimitedRetryBackOff) > 0
+	for name, v := range map[string]json.RawMessage{
+		"retriable_headers":    raw.RetriableHeaders,
+		"retry_host_predicate": raw.RetryHostPredicate,
+		"retry_priority":       raw.RetryPriority,
+	} {
+		if len(v) > 0 {
+			rp.Unsupported = append(rp.Unsupported, name)
+		}
+	}
+	sort.Strings(rp.Unsupported)
+
+	rp.Retry.ClassifierName = classifierName(rp.Conditions, rp.RetriableStatusCodes)
+	if rp.RespectRetryAfter {
+		rp.Retry.ClassifierName += ";retry-after"
+	}
+	return rp, nil
+}
+
+func (p *RetryPolicy) hasCondition(name string) bool {
+	for _, c := range p.Conditions {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// classifierName encodes the conditions and status codes: "envoy:5xx,reset;409".
+func classifierName(conditions []string, codes []int) string {
+	name := ClassifierPrefix + strings.Join(conditions, ",")
+	if len(codes) > 0 {
+		s := make([]string, len(codes))
+		for i, c := range codes {
+			s[i] = strconv.Itoa(c)
+		}
+		name += ";" + strings.Join(s, ",")
+	}
+	return name
+}
+
+func appendUnique[T comparable](s []T, v T) []T {
+	for _, x := range s {
+		if x == v {
+			return s
+		}
+	}
+	return append(s, v)
+}
+
+// parseDuration parses a protobuf JSON duration ("0.25s").
+func parseDuration(s string) (time.Duration, error) {
+	num, ok := strings.CutSuffix(strings.TrimSpace(s), "s")
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	secs, err := strconv.ParseFloat(num, 64)
+	if err != nil || secs < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func positive(err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("must be positive")
+}
+
+func fieldError(field string, err error) error {
+	return fmt.Errorf("envoyconfig: %s: %w", field, err)
+}
@@ -0,0 +1,206 @@
+package envoyconfig
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+const routeConfig = `{
+  "name": "local_route",
+  "virtual_hosts": [
+    {
+      "name": "users",
+      "domains": ["users.internal"],
+      "retry_policy": {"retry_on": "connect-failure", "num_retries": 1},
+      "routes": [
+        {
+          "name": "get_user",
+          "match": {"prefix": "/users/"},
+          "route": {
+            "cluster": "users",
+            "timeout": "3s",
+            "retry_policy": {
+              "retry_on": "5xx,retriable-status-codes,retriable-headers",
+              "num_retries": 3,
+              "per_try_timeout": "0.25s",
+              "retry_back_off": {"base_interval": "0.05s", "max_interval": "1s"},
+              "retriable_status_codes": [409, 429]
+            }
+          }
+        },
+        {"match": {"prefix": "/"}, "route": {"cluster": "users_fallback"}},
+        {"match": {"prefix": "/old"}, "redirect": {"path_redirect": "/new"}}
+      ]
+    }
+  ]
+}`
+
+type statusError struct {
+	code int
+}
+
+func (e statusError) Error() string                     { return "status" }
+func (e statusError) HTTPStatusCode() int               { return e.code }
+func (e statusError) HTTPMethod() string                { return http.MethodPost }
+func (e statusError) RetryAfter() (time.Duration, bool) { return time.Second, true }
+
+func TestParseRouteConfiguration(t *testing.T) {
+	cfg, err := ParseRouteConfiguration([]byte(routeConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.Routes) != 3 {
+		t.Fatalf("expected 3 routes (host, get_user, users_fallback), got %d", len(cfg.Routes))
+	}
+
+	pol, err := cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "users", Name: "get_user"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := pol.Retry
+	if r.MaxAttempts != 4 || r.TimeoutPerAttempt != 250*time.Millisecond || r.OverallTimeout != 3*time.Second {
+		t.Fatalf("unexpected retry policy: %+v", r)
+	}
+	if r.InitialBackoff != 50*time.Millisecond || r.MaxBackoff != time.Second || r.BackoffMultiplier != 2 || r.Jitter != policy.JitterFull {
+		t.Fatalf("unexpected backoff: %+v", r)
+	}
+	if r.ClassifierName != "envoy:5xx,retriable-status-codes;409,429" {
+		t.Fatalf("unexpected classifier name %q", r.ClassifierName)
+	}
+	if got := cfg.Routes[1].Retry.Unsupported; len(got) != 1 || got[0] != "retry_on:retriable-headers" {
+		t.Fatalf("expected retriable-headers to be reported unsupported, got %v", got)
+	}
+
+	// Unnamed route inherits the virtual host policy and keeps the default route timeout.
+	pol, err = cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "users", Name: "users_fallback"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pol.Retry.MaxAttempts != 2 || pol.Retry.OverallTimeout != 15*time.Second || pol.Retry.ClassifierName != "envoy:connect-failure" {
+		t.Fatalf("unexpected inherited policy: %+v", pol.Retry)
+	}
+
+	// Unknown route in a known host: host policy. Unknown host: single attempt.
+	pol, _ = cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "users", Name: "other"})
+	if pol.Retry.MaxAttempts != 2 {
+		t.Fatalf("expected host policy, got %+v", pol.Retry)
+	}
+	pol, _ = cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "orders", Name: "x"})
+	if pol.Retry.MaxAttempts != 1 {
+		t.Fatalf("expected single attempt, got %+v", pol.Retry)
+	}
+
+	if _, ok := cfg.Classifiers()["envoy:5xx,retriable-status-codes;409,429"]; !ok {
+		t.Fatalf("expected classifier to be registered, got %v", cfg.Classifiers())
+	}
+}
+
+func TestParseRetryPolicy_Defaults(t *testing.T) {
+	rp, err := ParseRetryPolicy([]byte(`{"retry_on": "gateway-error"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rp.Retry.MaxAttempts != 2 || rp.Retry.InitialBackoff != 25*time.Millisecond || rp.Retry.MaxBackoff != 250*time.Millisecond {
+		t.Fatalf("unexpected defaults: %+v", rp.Retry)
+	}
+}
+
+func TestParseRetryPolicy_Errors(t *testing.T) {
+	tests := []struct{ name, json, want string }{
+		{"negative retries", `{"num_retries": -1}`, "num_retries"},
+		{"bad per try", `{"per_try_timeout": "250ms"}`, "per_try_timeout"},
+		{"bad base", `{"retry_back_off": {"base_interval": "0s"}}`, "base_interval"},
+		{"max below base", `{"retry_back_off": {"base_interval": "1s", "max_interval": "0.5s"}}`, "max_interval"},
+		{"bad status", `{"retry_on": "retriable-status-codes", "retriable_status_codes": [42]}`, "retriable_status_codes"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseRetryPolicy([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestClassifier_Conditions(t *testing.T) {
+	parse := func(js string) *Classifier {
+		rp, err := ParseRetryPolicy([]byte(js))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return rp.Classifier()
+	}
+
+	gateway := parse(`{"retry_on": "gateway-error"}`)
+	fiveXX := parse(`{"retry_on": "5xx,retriable-4xx"}`)
+	codes := parse(`{"retry_on": "retriable-status-codes", "retriable_status_codes": [429], "rate_limited_retry_back_off": {"reset_headers": [{"name": "Retry-After"}]}}`)
+	reset := parse(`{"retry_on": "reset"}`)
+
+	tests := []struct {
+		name   string
+		c      *Classifier
+		err    error
+		kind   classify.OutcomeKind
+		reason string
+	}{
+		{"gateway 503", gateway, statusError{503}, classify.OutcomeRetryable, "envoy_gateway_error"},
+		{"gateway 500", gateway, statusError{500}, classify.OutcomeNonRetryable, "envoy_not_retriable"},
+		{"5xx 500", fiveXX, statusError{500}, classify.OutcomeRetryable, "envoy_5xx"},
+		{"5xx reset", fiveXX, errors.New("connection reset"), classify.OutcomeRetryable, "envoy_reset"},
+		{"409", fiveXX, statusError{409}, classify.OutcomeRetryable, "envoy_retriable_4xx"},
+		{"429 listed", codes, statusError{429}, classify.OutcomeRetryable, "envoy_retriable_status_code"},
+		{"429 not listed", fiveXX, statusError{429}, classify.OutcomeNonRetryable, "envoy_not_retriable"},
+		{"reset only", reset, errors.New("eof"), classify.OutcomeRetryable, "envoy_reset"},
+		{"reset on status", reset, statusError{503}, classify.OutcomeNonRetryable, "envoy_not_retriable"},
+		{"per try timeout", gateway, context.DeadlineExceeded, classify.OutcomeRetryable, "context_deadline_exceeded"},
+		{"canceled", fiveXX, context.Canceled, classify.OutcomeAbort, "context_canceled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := tt.c.Classify(nil, tt.err)
+			if out.Kind != tt.kind || out.Reason != tt.reason {
+				t.Fatalf("got %v/%s, want %v/%s", out.Kind, out.Reason, tt.kind, tt.reason)
+			}
+		})
+	}
+
+	if out := codes.Classify(nil, statusError{429}); out.BackoffOverride != time.Second {
+		t.Fatalf("expected Retry-After to be honored, got %v", out.BackoffOverride)
+	}
+}
+
+func TestClassifier_GRPC(t *testing.T) {
+	rp, err := ParseRetryPolicy([]byte(`{"retry_on": "unavailable,cancelled,deadline-exceeded"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := rp.Classifier()
+	c.GRPCCode = func(err error) (string, bool) {
+		code, ok := strings.CutPrefix(err.Error(), "grpc:")
+		return code, ok
+	}
+
+	tests := []struct {
+		code string
+		kind classify.OutcomeKind
+	}{
+		{"Unavailable", classify.OutcomeRetryable},
+		{"DEADLINE_EXCEEDED", classify.OutcomeRetryable},
+		{"Canceled", classify.OutcomeRetryable},
+		{"Internal", classify.OutcomeNonRetryable},
+		{"InvalidArgument", classify.OutcomeNonRetryable},
+	}
+	for _, tt := range tests {
+		if got := c.Classify(nil, errors.New("grpc:"+tt.code)).Kind; got != tt.kind {
+			t.Errorf("%s: got %v, want %v", tt.code, got, tt.kind)
+		}
+	}
+}
@@ -0,0 +1,20 @@
+// Package grpcconfig imports retry and hedging policies from a gRPC service config.
+//
+// Parse reads the standard service config JSON (the same document passed to
+// grpc.WithDefaultServiceConfig or published via DNS/xDS) and converts each
+// methodConfig.retryPolicy or methodConfig.hedgingPolicy into an EffectivePolicy keyed
+// the way integrations/grpc keys calls: {Namespace: "<package.Service>", Name: "<Method>"}.
+//
+// The returned Config is a controlplane.PolicyProvider that resolves keys with gRPC's
+// precedence (method, then service, then the default entry). Status code lists cannot be
+// expressed in an EffectivePolicy, so each policy names a classifier (see ClassifierName)
+// that the gRPC integration registers from Config.Classifiers.
+//
+// Usage (with integrations/grpc):
+//
+//	cfg, err := grpcconfig.Parse(serviceConfigJSON)
+//	if err != nil {
+//		return err
+//	}
+//	exec := retry.NewDefaultExecutor(recoursegrpc.ServiceConfigOptions(cfg)...)
+package grpcconfig
@@ -0,0 +1,339 @@
+package grpcconfig
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// ClassifierPrefix prefixes the classifier names produced by ClassifierName.
+const ClassifierPrefix = "grpc_codes:"
+
+// maxAttemptsLimit is gRPC's own cap on retryPolicy and hedgingPolicy maxAttempts.
+const maxAttemptsLimit = 5
+
+// codeNames are the canonical gRPC status code names, indexed by code number.
+var codeNames = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED", "NOT_FOUND",
+	"ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED", "FAILED_PRECONDITION",
+	"ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS",
+	"UNAUTHENTICATED",
+}
+
+// Method is the imported configuration for one methodConfig name entry.
+type Method struct {
+	// Key is the policy key. An empty Name applies to every method of the service;
+	// an empty key is the service config's default entry.
+	Key policy.PolicyKey
+
+	// Policy is the converted policy. Its classifier is named by ClassifierName(StatusCodes).
+	Policy policy.EffectivePolicy
+
+	// StatusCodes lists retryableStatusCodes (retry) or nonFatalStatusCodes (hedging) as
+	// canonical names such as "UNAVAILABLE", sorted.
+	StatusCodes []string
+}
+
+// RetryThrottling mirrors the service config's retryThrottling block.
+// It is parsed for reference; recourse budgets are configured separately.
+type RetryThrottling struct {
+	MaxTokens  float64
+	TokenRatio float64
+}
+
+// Config is a parsed service config.
+type Config struct {
+	Methods         []Method
+	RetryThrottling *RetryThrottling
+
+	byKey map[policy.PolicyKey]policy.EffectivePolicy
+}
+
+// Parse converts a gRPC service config JSON document into recourse policies.
+//
+// Method configs with neither retryPolicy nor hedgingPolicy become single-attempt
+// policies (gRPC does not retry them), keeping their timeout. Invalid configs, such as a
+// method with both policies or a name listed twice, are rejected as gRPC rejects them.
+func Parse(serviceConfigJSON []byte) (*Config, error) {
+	var raw serviceConfig
+	if err := json.Unmarshal(serviceConfigJSON, &raw); err != nil {
+		return nil, fmt.Errorf("grpcconfig: %w", err)
+	}
+
+	cfg := &Config{byKey: make(map[policy.PolicyKey]policy.EffectivePolicy)}
+	if t := raw.RetryThrottling; t != nil {
+		cfg.RetryThrottling = &RetryThrottling{MaxTokens: t.MaxTokens, TokenRatio: t.TokenRatio}
+	}
+
+	for i, mc := range raw.MethodConfig {
+		field := fmt.Sprintf("methodConfig[%d]", i)
+		pol, codes, err := convert(mc, field)
+		if err != nil {
+			return nil, err
+		}
+		for j, name := range mc.Name {
+			if name.Service == "" && name.Method != "" {
+				return nil, fmt.Errorf("grpcconfig: %s.name[%d]: method %q without service", field, j, name.Method)
+			}
+			key := policy.PolicyKey{Namespace: name.Service, Name: name.Method}
+			if _, dup := cfg.byKey[key]; dup {
+				return nil, fmt.Errorf("grpcconfig: %s.name[%d]: duplicate name %q", field, j, key.String())
+			}
+			p := pol
+			p.Key = key
+			cfg.byKey[key] = p
+			cfg.Methods = append(cfg.Methods, Method{Key: key, Policy: p, StatusCodes: codes})
+		}
+	}
+	return cfg, nil
+}
+
+// Policies returns the imported policies by key, for use with controlplane.StaticProvider.
+// Service-wide and default entries are included under their partial keys; use the Config
+// itself as the provider to get gRPC's lookup precedence.
+func (c *Config) Policies() map[policy.PolicyKey]policy.EffectivePolicy {
+	out := make(map[policy.PolicyKey]policy.EffectivePolicy, len(c.byKey))
+	for k, v := range c.byKey {
+		out[k] = v
+	}
+	return out
+}
+
+// Classifiers returns the status codes for each classifier name used by the policies.
+func (c *Config) Classifiers() map[string][]string {
+	out := make(map[string][]string)
+	for _, m := range c.Methods {
+		out[m.Policy.Retry.ClassifierName] = m.StatusCodes
+	}
+	return out
+}
+
+// GetEffectivePolicy implements controlplane.PolicyProvider.
+//
+// It returns the policy for the exact method, else for the method's service, else the
+// default entry. Keys matching nothing get a single-attempt policy, as gRPC does not retry
+// methods without a retry or hedging policy.
+func (c *Config) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	candidates := []policy.PolicyKey{key, {Namespace: key.Namespace}, {}}
+	for _, k := range candidates {
+		if pol, ok := c.byKey[k]; ok {
+			pol.Key = key
+			return pol.Normalize()
+		}
+	}
+
+	pol := policy.DefaultPolicyFor(key)
+	pol.Retry.MaxAttempts = 1
+	pol.Meta.Source = policy.PolicySourceStatic
+	return pol.Normalize()
+}
+
+// ClassifierName returns the classifier name for a set of status codes:
+// "grpc_codes:ABORTED,UNAVAILABLE". Codes are expected in canonical, sorted form.
+func ClassifierName(codes []string) string {
+	return ClassifierPrefix + strings.Join(codes, ",")
+}
+
+// ParseClassifierName returns the status codes encoded in a ClassifierName result.
+func ParseClassifierName(name string) ([]string, bool) {
+	rest, ok := strings.CutPrefix(name, ClassifierPrefix)
+	if !ok {
+		return nil, false
+	}
+	if rest == "" {
+		return []string{}, true
+	}
+	return strings.Split(rest, ","), true
+}
+
+type serviceConfig struct {
+	MethodConfig    []methodConfig   `json:"methodConfig"`
+	RetryThrottling *retryThrottling `json:"retryThrottling"`
+}
+
+type retryThrottling struct {
+	MaxTokens  float64 `json:"maxTokens"`
+	TokenRatio float64 `json:"tokenRatio"`
+}
+
+type methodName struct {
+	Service string `json:"service"`
+	Method  string `json:"method"`
+}
+
+type methodConfig struct {
+	Name          []methodName   `json:"name"`
+	Timeout       *string        `json:"timeout"`
+	RetryPolicy   *retryPolicy   `json:"retryPolicy"`
+	HedgingPolicy *hedgingPolicy `json:"hedgingPolicy"`
+}
+
+type retryPolicy struct {
+	MaxAttempts           int               `json:"maxAttempts"`
+	InitialBackoff        string            `json:"initialBackoff"`
+	MaxBackoff            string            `json:"maxBackoff"`
+	BackoffMultiplier     float64           `json:"backoffMultiplier"`
+	RetryableStatusCodes  []json.RawMessage `json:"retryableStatusCodes"`
+	PerAttemptRecvTimeout *string           `json:"perAttemptRecvTimeout"`
+}
+
+type hedgingPolicy struct {
+	MaxAttempts         int               `json:"maxAttempts"`
+	HedgingDelay        *string           `json:"hedgingDelay"`
+	NonFatalStatusCodes []json.RawMessage `json:"nonFatalStatusCodes"`
+}
+
+func convert(mc methodConfig, field string) (policy.EffectivePolicy, []string, error) {
+	pol := policy.EffectivePolicy{Meta: policy.Metadata{Source: policy.PolicySourceStatic}}
+	pol.Retry.MaxAttempts = 1
+	pol.Retry.Budget.Cost = 1
+	pol.Hedge.Budget.Cost = 1
+
+	if mc.Timeout != nil {
+		d, err := parseDuration(*mc.Timeout)
+		if err != nil {
+			return pol, nil, fieldError(field+".timeout", err)
+		}
+		pol.Retry.OverallTimeout = d
+	}
+
+	if mc.RetryPolicy != nil && mc.HedgingPolicy != nil {
+		return pol, nil, fmt.Errorf("grpcconfig: %s: retryPolicy and hedgingPolicy are mutually exclusive", field)
+	}
+
+	codes := []string{}
+	switch {
+	case mc.RetryPolicy != nil:
+		rp := mc.RetryPolicy
+		field += ".retryPolicy"
+		if rp.MaxAttempts < 2 {
+			return pol, nil, fmt.Errorf("grpcconfig: %s.maxAttempts: must be at least 2, got %d", field, rp.MaxAttempts)
+		}
+		initial, err := parseDuration(rp.InitialBackoff)
+		if err != nil || initial <= 0 {
+			return pol, nil, fieldError(field+".initialBackoff", positive(err))
+		}
+		maxBackoff, err := parseDuration(rp.MaxBackoff)
+		if err != nil || maxBackoff <= 0 {
+			return pol, nil, fieldError(field+".maxBackoff", positive(err))
+		}
+		if rp.BackoffMultiplier <= 0 {
+			return pol, nil, fmt.Errorf("grpcconfig: %s.backoffMultiplier: must be positive", field)
+		}
+		if len(rp.RetryableStatusCodes) == 0 {
+			return pol, nil, fmt.Errorf("grpcconfig: %s.retryableStatusCodes: must not be empty", field)
+		}
+		codes, err = parseCodes(rp.RetryableStatusCodes)
+		if err != nil {
+			return pol, nil, fieldError(field+".retryableStatusCodes", err)
+		}
+		if rp.PerAttemptRecvTimeout != nil {
+			d, err := parseDuration(*rp.PerAttemptRecvTimeout)
+			if err != nil {
+				return pol, nil, fieldError(field+".perAttemptRecvTimeout", err)
+			}
+			pol.Retry.TimeoutPerAttempt = d
+		}
+
+		pol.Retry.MaxAttempts = min(rp.MaxAttempts, maxAttemptsLimit)
+		pol.Retry.InitialBackoff = initial
+		pol.Retry.MaxBackoff = maxBackoff
+		pol.Retry.BackoffMultiplier = rp.BackoffMultiplier
+		// gRPC picks each delay uniformly from [0, current backoff].
+		pol.Retry.Jitter = policy.JitterFull
+
+	case mc.HedgingPolicy != nil:
+		hp := mc.HedgingPolicy
+		field += ".hedgingPolicy"
+		if hp.MaxAttempts < 2 {
+			return pol, nil, fmt.Errorf("grpcconfig: %s.maxAttempts: must be at least 2, got %d", field, hp.MaxAttempts)
+		}
+		var err error
+		if hp.HedgingDelay != nil {
+			if pol.Hedge.HedgeDelay, err = parseDuration(*hp.HedgingDelay); err != nil {
+				return pol, nil, fieldError(field+".hedgingDelay", err)
+			}
+		}
+		if codes, err = parseCodes(hp.NonFatalStatusCodes); err != nil {
+			return pol, nil, fieldError(field+".nonFatalStatusCodes", err)
+		}
+
+		pol.Hedge.Enabled = true
+		pol.Hedge.MaxHedges = min(hp.MaxAttempts, maxAttemptsLimit) - 1
+		// A fatal status code commits the call in gRPC, ending outstanding hedges.
+		pol.Hedge.CancelOnFirstTerminal = true
+	}
+
+	pol.Retry.ClassifierName = ClassifierName(codes)
+	return pol, codes, nil
+}
+
+func parseCodes(raw []json.RawMessage) ([]string, error) {
+	seen := make(map[string]struct{}, len(raw))
+	codes := make([]string, 0, len(raw))
+	for _, r := range raw {
+		name, err := parseCode(r)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := seen[name]; ok {
+			continue
+		}
+		seen[name] = struct{}{}
+		codes = append(codes, name)
+	}
+	sort.Strings(codes)
+	return codes, nil
+}
+
+// parseCode accepts a status code as a name ("UNAVAILABLE") or a number (14).
+func parseCode(raw json.RawMessage) (string, error) {
+	var n int
+	if err := json.Unmarshal(raw, &n); err == nil {
+		if n <= 0 || n >= len(codeNames) {
+			return "", fmt.Errorf("invalid status code %d", n)
+		}
+		return codeNames[n], nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil {
+		return "", fmt.Errorf("invalid status code %s", raw)
+	}
+	name := strings.ToUpper(strings.TrimSpace(s))
+	for i, c := range codeNames {
+		if i > 0 && c == name {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("invalid status code %q", s)
+}
+
+// parseDuration parses a protobuf JSON duration ("1.5s").
+func parseDuration(s string) (time.Duration, error) {
+	num, ok := strings.CutSuffix(strings.TrimSpace(s), "s")
+	if !ok || num == "" {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	secs, err := strconv.ParseFloat(num, 64)
+	if err != nil || secs < 0 {
+		return 0, fmt.Errorf("invalid duration %q", s)
+	}
+	return time.Duration(secs * float64(time.Second)), nil
+}
+
+func positive(err error) error {
+	if err != nil {
+		return err
+	}
+	return fmt.Errorf("must be positive")
+}
+
+func fieldError(field string, err error) error {
+	return fmt.Errorf("grpcconfig: %s: %w", field, err)
+}
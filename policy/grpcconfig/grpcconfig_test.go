@@ -0,0 +1,171 @@
+package grpcconfig
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+const testServiceConfig = `{
+  "methodConfig": [
+    {
+      "name": [{"service": "acme.users.v1.Users", "method": "Get"}],
+      "timeout": "2s",
+      "retryPolicy": {
+        "maxAttempts": 4,
+        "initialBackoff": "0.1s",
+        "maxBackoff": "1s",
+        "backoffMultiplier": 2,
+        "retryableStatusCodes": ["UNAVAILABLE", 10]
+      }
+    },
+    {
+      "name": [{"service": "acme.users.v1.Users"}],
+      "hedgingPolicy": {
+        "maxAttempts": 3,
+        "hedgingDelay": "0.05s",
+        "nonFatalStatusCodes": ["UNAVAILABLE"]
+      }
+    },
+    {
+      "name": [{}],
+      "timeout": "5s"
+    }
+  ],
+  "retryThrottling": {"maxTokens": 10, "tokenRatio": 0.1}
+}`
+
+func TestParse_RetryPolicy(t *testing.T) {
+	cfg, err := Parse([]byte(testServiceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key := policy.PolicyKey{Namespace: "acme.users.v1.Users", Name: "Get"}
+	pol, err := cfg.GetEffectivePolicy(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := pol.Retry
+	if r.MaxAttempts != 4 || r.InitialBackoff != 100*time.Millisecond || r.MaxBackoff != time.Second || r.BackoffMultiplier != 2 {
+		t.Fatalf("unexpected retry policy: %+v", r)
+	}
+	if r.Jitter != policy.JitterFull {
+		t.Fatalf("expected full jitter, got %q", r.Jitter)
+	}
+	if r.OverallTimeout != 2*time.Second {
+		t.Fatalf("expected 2s overall timeout, got %v", r.OverallTimeout)
+	}
+	if r.ClassifierName != "grpc_codes:ABORTED,UNAVAILABLE" {
+		t.Fatalf("unexpected classifier name %q", r.ClassifierName)
+	}
+	if pol.Hedge.Enabled {
+		t.Fatal("did not expect hedging")
+	}
+	if cfg.RetryThrottling == nil || cfg.RetryThrottling.MaxTokens != 10 {
+		t.Fatalf("expected retry throttling to be parsed, got %+v", cfg.RetryThrottling)
+	}
+}
+
+func TestParse_HedgingPolicyAndPrecedence(t *testing.T) {
+	cfg, err := Parse([]byte(testServiceConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Method not listed: falls back to the service entry.
+	key := policy.PolicyKey{Namespace: "acme.users.v1.Users", Name: "List"}
+	pol, err := cfg.GetEffectivePolicy(context.Background(), key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pol.Key != key {
+		t.Fatalf("expected key %v, got %v", key, pol.Key)
+	}
+	h := pol.Hedge
+	if !h.Enabled || h.MaxHedges != 2 || h.HedgeDelay != 50*time.Millisecond || !h.CancelOnFirstTerminal {
+		t.Fatalf("unexpected hedge policy: %+v", h)
+	}
+	if pol.Retry.MaxAttempts != 1 {
+		t.Fatalf("expected hedged calls not to retry, got %d attempts", pol.Retry.MaxAttempts)
+	}
+
+	// Other services: the default entry.
+	pol, err = cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "acme.orders.v1.Orders", Name: "Get"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pol.Retry.MaxAttempts != 1 || pol.Retry.OverallTimeout != 5*time.Second {
+		t.Fatalf("unexpected default policy: %+v", pol.Retry)
+	}
+
+	if got := len(cfg.Policies()); got != 3 {
+		t.Fatalf("expected 3 policies, got %d", got)
+	}
+	classifiers := cfg.Classifiers()
+	if codes := classifiers["grpc_codes:UNAVAILABLE"]; len(codes) != 1 || codes[0] != "UNAVAILABLE" {
+		t.Fatalf("unexpected classifiers: %v", classifiers)
+	}
+}
+
+func TestParse_NoMatchingEntry(t *testing.T) {
+	cfg, err := Parse([]byte(`{"methodConfig": [{"name": [{"service": "a.B"}], "timeout": "1s"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol, err := cfg.GetEffectivePolicy(context.Background(), policy.PolicyKey{Namespace: "c.D", Name: "E"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pol.Retry.MaxAttempts != 1 {
+		t.Fatalf("expected a single attempt, got %d", pol.Retry.MaxAttempts)
+	}
+}
+
+func TestParse_CapsMaxAttempts(t *testing.T) {
+	cfg, err := Parse([]byte(`{"methodConfig": [{"name": [{"service": "a.B"}], "retryPolicy": {
+		"maxAttempts": 9, "initialBackoff": "1s", "maxBackoff": "2s", "backoffMultiplier": 1.5,
+		"retryableStatusCodes": ["unavailable"]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Methods[0].Policy.Retry.MaxAttempts; got != 5 {
+		t.Fatalf("expected maxAttempts capped at 5, got %d", got)
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	tests := []struct {
+		name, json, want string
+	}{
+		{"both policies", `{"methodConfig": [{"name": [{"service": "a.B"}], "retryPolicy": {}, "hedgingPolicy": {}}]}`, "mutually exclusive"},
+		{"max attempts", `{"methodConfig": [{"name": [{"service": "a.B"}], "retryPolicy": {"maxAttempts": 1}}]}`, "retryPolicy.maxAttempts"},
+		{"bad backoff", `{"methodConfig": [{"name": [{"service": "a.B"}], "retryPolicy": {"maxAttempts": 2, "initialBackoff": "100ms"}}]}`, "initialBackoff"},
+		{"no codes", `{"methodConfig": [{"name": [{"service": "a.B"}], "retryPolicy": {"maxAttempts": 2, "initialBackoff": "1s", "maxBackoff": "1s", "backoffMultiplier": 2}}]}`, "retryableStatusCodes"},
+		{"bad code", `{"methodConfig": [{"name": [{"service": "a.B"}], "hedgingPolicy": {"maxAttempts": 2, "nonFatalStatusCodes": ["NOPE"]}}]}`, "NOPE"},
+		{"duplicate", `{"methodConfig": [{"name": [{"service": "a.B"}]}, {"name": [{"service": "a.B"}]}]}`, "duplicate"},
+		{"method without service", `{"methodConfig": [{"name": [{"method": "C"}]}]}`, "without service"},
+		{"invalid json", `{`, "grpcconfig"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse([]byte(tt.json))
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected error containing %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestClassifierNameRoundTrip(t *testing.T) {
+	codes, ok := ParseClassifierName(ClassifierName([]string{"ABORTED", "UNAVAILABLE"}))
+	if !ok || len(codes) != 2 || codes[0] != "ABORTED" || codes[1] != "UNAVAILABLE" {
+		t.Fatalf("unexpected round trip: %v %v", codes, ok)
+	}
+	if _, ok := ParseClassifierName("http"); ok {
+		t.Fatal("expected non-grpcconfig name to be rejected")
+	}
+}
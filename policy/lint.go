@@ -0,0 +1,155 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Lint checks reported by Warning.Check.
+const (
+	LintAmplification      = "amplification"        // Worst-case attempts per call exceed LintOptions.MaxAmplification.
+	LintTimeout            = "timeout"              // Timeouts cut off the backoff schedule or hedges.
+	LintUnknownBudget      = "unknown_budget"       // A referenced budget is not registered.
+	LintNonIdempotentHedge = "non_idempotent_hedge" // Hedging without idempotency keys on a likely non-idempotent key.
+)
+
+// DefaultMaxAmplification is the LintOptions.MaxAmplification used when it is zero.
+const DefaultMaxAmplification = 10
+
+// Warning is a hazard Lint found in a policy. Warnings are advisory: the policy is
+// valid and the executor runs it as configured.
+type Warning struct {
+	Key     PolicyKey
+	Check   string // One of the Lint* checks.
+	Field   string // Dot-delimited JSON field path the warning is about (e.g. "hedge.enabled").
+	Message string
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("%s: %s: %s", w.Key, w.Field, w.Message)
+}
+
+// LintOptions configures Lint. The zero value checks a single retrying layer and
+// skips the budget check.
+type LintOptions struct {
+	// Layers is the number of retrying layers a call passes through, this one included
+	// (e.g. 2 when a gateway and the service behind it both retry); default 1. Each
+	// layer multiplies the attempts the layers below it make.
+	Layers int
+	// MaxAmplification is the most attempts one call may cause across all layers before
+	// Lint warns; default DefaultMaxAmplification.
+	MaxAmplification int
+	// HasBudget reports whether a budget name is registered. If nil, budget references
+	// are not checked.
+	HasBudget func(name string) bool
+	// NonIdempotent reports whether a key's operation is likely not idempotent. If nil,
+	// keys whose name starts with a verb such as Create, Charge, or Send count as not
+	// idempotent.
+	NonIdempotent func(key PolicyKey) bool
+}
+
+// Lint returns the hazards in pol: retry amplification (retries times hedges, raised to
+// the number of retrying layers), timeouts incompatible with the backoff schedule or
+// hedge delay, budget references that are not registered, and hedging without
+// idempotency keys on likely non-idempotent keys. pol is checked as it would be
+// normalized.
+func Lint(pol EffectivePolicy, opts LintOptions) []Warning {
+	if n, err := pol.Normalize(); err == nil {
+		pol = n
+	}
+	if opts.Layers <= 0 {
+		opts.Layers = 1
+	}
+	if opts.MaxAmplification <= 0 {
+		opts.MaxAmplification = DefaultMaxAmplification
+	}
+	if opts.NonIdempotent == nil {
+		opts.NonIdempotent = likelyNonIdempotent
+	}
+
+	var warnings []Warning
+	warn := func(check, field, format string, args ...any) {
+		warnings = append(warnings, Warning{Key: pol.Key, Check: check, Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+	retry, hedge := pol.Retry, pol.Hedge
+
+	perLayer := retry.MaxAttempts
+	if hedge.Enabled {
+		perLayer *= 1 + hedge.MaxHedges
+	}
+	total := 1
+	for range opts.Layers {
+		total *= perLayer
+		if total > opts.MaxAmplification {
+			break
+		}
+	}
+	if total > opts.MaxAmplification {
+		field := "retry.max_attempts"
+		if hedge.Enabled {
+			field = "hedge.max_hedges"
+		}
+		warn(LintAmplification, field, "up to %d attempts per layer across %d layer(s) exceed %d attempts per call",
+			perLayer, opts.Layers, opts.MaxAmplification)
+	}
+
+	if retry.OverallTimeout > 0 && retry.MaxAttempts > 1 {
+		if retry.TimeoutPerAttempt >= retry.OverallTimeout {
+			warn(LintTimeout, "retry.overall_timeout", "overall timeout %v leaves no room for retries after a %v attempt",
+				retry.OverallTimeout, retry.TimeoutPerAttempt)
+		} else if n := reachableAttempts(retry); n < retry.MaxAttempts {
+			warn(LintTimeout, "retry.max_attempts", "backoff schedule reaches the %v overall timeout after %d of %d attempts",
+				retry.OverallTimeout, n, retry.MaxAttempts)
+		}
+	}
+	if hedge.Enabled && retry.TimeoutPerAttempt > 0 && hedge.HedgeDelay >= retry.TimeoutPerAttempt {
+		warn(LintTimeout, "hedge.hedge_delay", "hedge delay %v is not shorter than the %v attempt timeout, so hedges never start",
+			hedge.HedgeDelay, retry.TimeoutPerAttempt)
+	}
+
+	if opts.HasBudget != nil {
+		if name := retry.Budget.Name; name != "" && !opts.HasBudget(name) {
+			warn(LintUnknownBudget, "retry.budget.name", "budget %q is not registered", name)
+		}
+		if name := hedge.Budget.Name; hedge.Enabled && name != "" && !opts.HasBudget(name) {
+			warn(LintUnknownBudget, "hedge.budget.name", "budget %q is not registered", name)
+		}
+	}
+
+	if hedge.Enabled && !hedge.IdempotencyKeys && opts.NonIdempotent(pol.Key) {
+		warn(LintNonIdempotentHedge, "hedge.enabled", "hedging runs the operation concurrently, but %q looks non-idempotent; enable hedge.idempotency_keys if the server deduplicates", pol.Key.Name)
+	}
+	return warnings
+}
+
+// reachableAttempts returns how many attempts start within the overall timeout when
+// every attempt fails instantly and waits the unjittered backoff.
+func reachableAttempts(r RetryPolicy) int {
+	var elapsed time.Duration
+	backoff := r.InitialBackoff
+	for n := 1; n < r.MaxAttempts; n++ {
+		elapsed += backoff
+		if elapsed >= r.OverallTimeout {
+			return n
+		}
+		backoff = min(time.Duration(float64(backoff)*r.BackoffMultiplier), r.MaxBackoff)
+	}
+	return r.MaxAttempts
+}
+
+// nonIdempotentVerbs start key names that likely change state on every call.
+var nonIdempotentVerbs = []string{
+	"append", "charge", "create", "enqueue", "increment", "insert", "publish", "send",
+	"submit", "transfer",
+}
+
+func likelyNonIdempotent(key PolicyKey) bool {
+	name := strings.ToLower(key.Name)
+	for _, verb := range nonIdempotentVerbs {
+		if strings.HasPrefix(name, verb) {
+			return true
+		}
+	}
+	return false
+}
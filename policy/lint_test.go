@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"testing"
+	"time"
+)
+
+func lintChecks(ws []Warning) map[string]string {
+	m := make(map[string]string, len(ws))
+	for _, w := range ws {
+		m[w.Check] = w.Field
+	}
+	return m
+}
+
+func TestLint(t *testing.T) {
+	if ws := Lint(New("svc.Get"), LintOptions{}); len(ws) != 0 {
+		t.Fatalf("Lint(defaults) = %v, want none", ws)
+	}
+
+	tests := []struct {
+		name  string
+		pol   EffectivePolicy
+		opts  LintOptions
+		check string
+		field string
+	}{
+		{"amplification", New("svc.Get", MaxAttempts(5), EnableHedging()), LintOptions{}, LintAmplification, "hedge.max_hedges"},
+		{"layers", New("svc.Get", MaxAttempts(4)), LintOptions{Layers: 2}, LintAmplification, "retry.max_attempts"},
+		{"no room for retries", New("svc.Get", PerAttemptTimeout(time.Second), OverallTimeout(time.Second)), LintOptions{}, LintTimeout, "retry.overall_timeout"},
+		{"schedule cut off", New("svc.Get", MaxAttempts(5), InitialBackoff(100*time.Millisecond), MaxBackoff(time.Second), OverallTimeout(250*time.Millisecond)), LintOptions{}, LintTimeout, "retry.max_attempts"},
+		{"hedges never start", New("svc.Get", EnableHedging(), HedgeDelay(time.Second), PerAttemptTimeout(500*time.Millisecond)), LintOptions{}, LintTimeout, "hedge.hedge_delay"},
+		{"unknown budget", New("svc.Get", Budget("retries")), LintOptions{HasBudget: func(string) bool { return false }}, LintUnknownBudget, "retry.budget.name"},
+		{"non-idempotent hedge", New("payments.ChargeCard", EnableHedging()), LintOptions{}, LintNonIdempotentHedge, "hedge.enabled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := lintChecks(Lint(tt.pol, tt.opts))
+			if field, ok := got[tt.check]; !ok || field != tt.field {
+				t.Fatalf("Lint checks = %v, want %s on %s", got, tt.check, tt.field)
+			}
+		})
+	}
+
+	// A custom idempotency check replaces the name heuristic.
+	pol := New("payments.ChargeCard", EnableHedging())
+	if ws := Lint(pol, LintOptions{NonIdempotent: func(PolicyKey) bool { return false }}); len(ws) != 0 {
+		t.Fatalf("Lint = %v, want none", ws)
+	}
+
+	// Idempotency keys let the server deduplicate hedged attempts.
+	pol = New("payments.ChargeCard", EnableHedging(), HedgeIdempotencyKeys(true))
+	if ws := Lint(pol, LintOptions{}); len(ws) != 0 {
+		t.Fatalf("Lint = %v, want none", ws)
+	}
+}
@@ -94,6 +94,14 @@ func OverallTimeout(d time.Duration) Option {
 	}
 }
 
+// SliceOverallTimeout divides the overall timeout across the remaining attempts: each
+// attempt times out after its share of the time left (see RetryPolicy.SliceOverallTimeout).
+func SliceOverallTimeout() Option {
+	return func(p *EffectivePolicy) {
+		p.Retry.SliceOverallTimeout = true
+	}
+}
+
 // Classifier sets the classifier name for this policy.
 func Classifier(name string) Option {
 	return func(p *EffectivePolicy) {
@@ -101,6 +109,25 @@ func Classifier(name string) Option {
 	}
 }
 
+// AcceptPartial makes calls return an attempt classified classify.OutcomePartial,
+// with its value and error, instead of retrying it for a complete result.
+func AcceptPartial() Option {
+	return func(p *EffectivePolicy) {
+		p.Retry.AcceptPartial = true
+	}
+}
+
+// BackoffOverride sets the backoff curve for retries after outcomes with the given
+// reason (see RetryPolicy.BackoffOverrides).
+func BackoffOverride(reason string, spec BackoffSpec) Option {
+	return func(p *EffectivePolicy) {
+		if p.Retry.BackoffOverrides == nil {
+			p.Retry.BackoffOverrides = make(map[string]BackoffSpec)
+		}
+		p.Retry.BackoffOverrides[reason] = spec
+	}
+}
+
 // Budget sets the budget reference for retry attempts.
 func Budget(name string) Option {
 	return func(p *EffectivePolicy) {
@@ -174,6 +201,40 @@ func HedgeCancelOnTerminal(cancel bool) Option {
 	}
 }
 
+// ReserveBudget makes calls reserve their retry and hedge budgets for the worst-case
+// attempt count up front (see BudgetRef.Reserve), for strict admission where starting
+// and then abandoning work is costly.
+func ReserveBudget() Option {
+	return func(p *EffectivePolicy) {
+		p.Retry.Budget.Reserve = true
+		p.Hedge.Budget.Reserve = true
+	}
+}
+
+// HedgeIdempotencyKeys gives all attempts of a hedged group the same idempotency key,
+// so servers can deduplicate hedged writes (see retry.WithIdempotencyKeys).
+func HedgeIdempotencyKeys(enabled bool) Option {
+	return func(p *EffectivePolicy) {
+		p.Hedge.IdempotencyKeys = enabled
+	}
+}
+
+// MaxInFlight caps the calls for this key that may run at once (see LimitsPolicy).
+func MaxInFlight(n int) Option {
+	return func(p *EffectivePolicy) {
+		p.Limits.MaxInFlight = n
+	}
+}
+
+// MaxQPS caps the calls for this key that may start per second, allowing bursts of up
+// to burst calls (0 for the default, qps rounded up; see LimitsPolicy).
+func MaxQPS(qps float64, burst int) Option {
+	return func(p *EffectivePolicy) {
+		p.Limits.MaxQPS = qps
+		p.Limits.Burst = burst
+	}
+}
+
 // --- Presets ---
 
 // ExponentialBackoff returns options for exponential backoff with equal jitter.
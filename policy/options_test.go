@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"slices"
+	"strings"
 	"testing"
 	"time"
 )
@@ -81,3 +83,53 @@ func TestExponentialBackoff(t *testing.T) {
 		t.Errorf("expected JitterEqual, got %v", p.Retry.Jitter)
 	}
 }
+
+func TestNormalize_BackoffOverrides(t *testing.T) {
+	p := New("test.overrides", InitialBackoff(10*time.Millisecond), MaxBackoff(time.Second))
+	p.Retry.BackoffOverrides = map[string]BackoffSpec{
+		"http_429": {InitialBackoff: 500 * time.Millisecond, MaxBackoff: 100 * time.Millisecond},
+	}
+	got, err := p.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	spec := got.Retry.BackoffOverrides["http_429"]
+	if spec.InitialBackoff != 500*time.Millisecond || spec.MaxBackoff != 500*time.Millisecond {
+		t.Errorf("spec backoff = %v..%v, want 500ms..500ms", spec.InitialBackoff, spec.MaxBackoff)
+	}
+	if spec.BackoffMultiplier != p.Retry.BackoffMultiplier || spec.Jitter != p.Retry.Jitter {
+		t.Errorf("spec = %+v, want multiplier and jitter from the retry policy", spec)
+	}
+	if p.Retry.BackoffOverrides["http_429"].MaxBackoff != 100*time.Millisecond {
+		t.Error("Normalize modified the caller's map")
+	}
+
+	p.Retry.BackoffOverrides = map[string]BackoffSpec{"": {}}
+	if _, err := p.Normalize(); err == nil {
+		t.Error("Normalize accepted an empty reason")
+	}
+	p.Retry.BackoffOverrides = map[string]BackoffSpec{"timeout": {Jitter: "bogus"}}
+	if _, err := p.Normalize(); err == nil {
+		t.Error("Normalize accepted an invalid jitter")
+	}
+}
+
+func TestNormalize_Limits(t *testing.T) {
+	p := New("test.limits", MaxInFlight(-1), MaxQPS(2.5, 0))
+	if p.Limits != (LimitsPolicy{MaxQPS: 2.5, Burst: 3}) {
+		t.Errorf("limits = %+v, want no in-flight cap and burst 3", p.Limits)
+	}
+
+	p = New("test.limits", MaxQPS(0, 5))
+	if p.Limits.Burst != 0 {
+		t.Errorf("burst = %d without a rate cap, want 0", p.Limits.Burst)
+	}
+
+	p = New("test.limits", MaxInFlight(4), MaxQPS(100, 10))
+	if p.Limits != (LimitsPolicy{MaxInFlight: 4, MaxQPS: 100, Burst: 10}) {
+		t.Errorf("limits = %+v, want the configured caps unchanged", p.Limits)
+	}
+	if p.Meta.Normalization.Changed && slices.ContainsFunc(p.Meta.Normalization.ChangedFields, func(f string) bool { return strings.HasPrefix(f, "limits.") }) {
+		t.Errorf("changed fields = %v, want no limits fields", p.Meta.Normalization.ChangedFields)
+	}
+}
@@ -0,0 +1,74 @@
+package policy
+
+import (
+	"hash/fnv"
+	"strconv"
+)
+
+// RolloutBy selects what a rollout hashes to pick the policy version for a call.
+type RolloutBy string
+
+const (
+	// RolloutByHost buckets by host, so a host uses one version for all its calls.
+	RolloutByHost RolloutBy = "host"
+	// RolloutByRequest buckets by the call's rollout key (retry.WithRolloutKey), so
+	// retries of one request, and all calls carrying its key, use one version.
+	RolloutByRequest RolloutBy = "request"
+)
+
+// Rollout serves a policy to Percent of calls as a canary; the remaining calls use
+// Previous, the prior version.
+type Rollout struct {
+	Percent  float64          `json:"percent"`            // Share of calls (0-100) that use the new policy.
+	By       RolloutBy        `json:"by,omitempty"`       // Bucketing subject (default "host").
+	Previous *EffectivePolicy `json:"previous,omitempty"` // Policy for the other calls (default: built-in defaults).
+}
+
+// Canary reports whether subject falls in the rollout's canary share for key. The
+// bucket is a stable hash of key and subject, so a subject keeps its version as long as
+// Percent does not drop below its bucket.
+func (r *Rollout) Canary(key PolicyKey, subject string) bool {
+	if r == nil || r.Percent >= 100 {
+		return true
+	}
+	if r.Percent <= 0 {
+		return false
+	}
+	h := fnv.New64a()
+	h.Write([]byte(key.String()))
+	h.Write([]byte{0})
+	h.Write([]byte(subject))
+	return float64(h.Sum64()%10000) < r.Percent*100
+}
+
+func (r *Rollout) normalize(markChanged func(string)) (*Rollout, error) {
+	n := *r
+	if n.Percent < 0 {
+		n.Percent = 0
+		markChanged("rollout.percent")
+	} else if n.Percent > 100 {
+		n.Percent = 100
+		markChanged("rollout.percent")
+	}
+
+	switch n.By {
+	case "":
+		n.By = RolloutByHost
+		markChanged("rollout.by")
+	case RolloutByHost, RolloutByRequest:
+	default:
+		return nil, &NormalizeError{Field: "rollout.by", Value: string(n.By)}
+	}
+
+	if n.Previous != nil {
+		if n.Previous.Rollout != nil {
+			return nil, &NormalizeError{Field: "rollout.previous.rollout", Value: strconv.FormatFloat(n.Previous.Rollout.Percent, 'g', -1, 64)}
+		}
+		prev, err := n.Previous.Normalize()
+		if err != nil {
+			return nil, err
+		}
+		n.Previous = &prev
+	}
+	return &n, nil
+}
@@ -0,0 +1,56 @@
+package policy
+
+import (
+	"errors"
+	"strconv"
+	"testing"
+)
+
+func TestRollout_Canary(t *testing.T) {
+	key := ParseKey("svc.Get")
+	r := &Rollout{Percent: 25}
+	canary := 0
+	for i := range 10000 {
+		subject := strconv.Itoa(i)
+		got := r.Canary(key, subject)
+		if got != r.Canary(key, subject) {
+			t.Fatalf("subject %s: unstable bucket", subject)
+		}
+		if got {
+			canary++
+		}
+	}
+	if canary < 2200 || canary > 2800 {
+		t.Fatalf("canary share = %d/10000, want about 25%%", canary)
+	}
+
+	// Raising the percentage keeps existing canary subjects in the canary.
+	wider := &Rollout{Percent: 50}
+	for i := range 1000 {
+		subject := strconv.Itoa(i)
+		if r.Canary(key, subject) && !wider.Canary(key, subject) {
+			t.Fatalf("subject %s left the canary when the rollout widened", subject)
+		}
+	}
+}
+
+func TestRollout_Normalize(t *testing.T) {
+	pol := New("svc.Get", MaxAttempts(5))
+	pol.Rollout = &Rollout{Percent: 150, Previous: &EffectivePolicy{Retry: RetryPolicy{MaxAttempts: 2}}}
+	n, err := pol.Normalize()
+	if err != nil {
+		t.Fatalf("Normalize: %v", err)
+	}
+	if n.Rollout.Percent != 100 || n.Rollout.By != RolloutByHost || n.Rollout.Previous.Retry.InitialBackoff == 0 {
+		t.Fatalf("rollout = %+v, want clamped percent, host bucketing, normalized previous", n.Rollout)
+	}
+	if pol.Rollout.Percent != 150 {
+		t.Fatal("Normalize modified the input rollout")
+	}
+
+	pol.Rollout = &Rollout{Percent: 10, By: "moon"}
+	var ne *NormalizeError
+	if _, err := pol.Normalize(); !errors.As(err, &ne) || ne.Field != "rollout.by" {
+		t.Fatalf("invalid by: err = %v, want NormalizeError for rollout.by", err)
+	}
+}
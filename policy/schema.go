@@ -1,6 +1,8 @@
 package policy
 
 import (
+	"maps"
+	"math"
 	"time"
 )
 
@@ -13,37 +15,91 @@ const (
 )
 
 type BudgetRef struct {
-	Name string `json:"name"`          // Budget registry name.
+	Name string `json:"name"`           // Budget registry name.
 	Cost int    `json:"cost,omitempty"` // Units consumed per attempt (min 1).
+
+	// Reserve takes the units for the call's worst-case attempts from the budget before
+	// its first attempt, failing the call up front if they are not available, and
+	// returns the unused units when it completes.
+	Reserve bool `json:"reserve,omitempty"`
 }
 
 type RetryPolicy struct {
-	MaxAttempts       int           `json:"max_attempts"`        // Maximum attempts per call.
-	InitialBackoff    time.Duration `json:"initial_backoff"`     // Starting backoff before retries.
-	MaxBackoff        time.Duration `json:"max_backoff"`         // Upper bound for backoff delays.
-	BackoffMultiplier float64       `json:"backoff_multiplier"`  // Exponential backoff multiplier.
-	Jitter            JitterKind    `json:"jitter"`              // Backoff jitter strategy.
+	MaxAttempts       int           `json:"max_attempts"`       // Maximum attempts per call.
+	InitialBackoff    time.Duration `json:"initial_backoff"`    // Starting backoff before retries.
+	MaxBackoff        time.Duration `json:"max_backoff"`        // Upper bound for backoff delays.
+	BackoffMultiplier float64       `json:"backoff_multiplier"` // Exponential backoff multiplier.
+	Jitter            JitterKind    `json:"jitter"`             // Backoff jitter strategy.
 
 	TimeoutPerAttempt time.Duration `json:"timeout_per_attempt"` // Per-attempt timeout (0 disables).
 	OverallTimeout    time.Duration `json:"overall_timeout"`     // Total timeout for all attempts (0 disables).
 
+	// SliceOverallTimeout gives each attempt an even share of the time left before the
+	// call's deadline (OverallTimeout, or the caller's deadline if sooner): remaining /
+	// attempts_left, so fast early failures leave more time to later attempts. With
+	// TimeoutPerAttempt too, the shorter timeout applies.
+	SliceOverallTimeout bool `json:"slice_overall_timeout,omitempty"`
+
 	ClassifierName string    `json:"classifier_name,omitempty"` // Classifier registry name.
 	Budget         BudgetRef `json:"budget,omitempty"`          // Budget gating for retry attempts.
+
+	AcceptPartial bool `json:"accept_partial,omitempty"` // Return OutcomePartial results instead of retrying them.
+
+	BackoffOverrides map[string]BackoffSpec `json:"backoff_overrides,omitempty"` // Backoff curves by outcome reason (e.g. "http_429").
+}
+
+// Equal reports whether r and o are the same retry policy. RetryPolicy values are not
+// comparable with == because of BackoffOverrides.
+func (r RetryPolicy) Equal(o RetryPolicy) bool {
+	return r.MaxAttempts == o.MaxAttempts &&
+		r.InitialBackoff == o.InitialBackoff &&
+		r.MaxBackoff == o.MaxBackoff &&
+		r.BackoffMultiplier == o.BackoffMultiplier &&
+		r.Jitter == o.Jitter &&
+		r.TimeoutPerAttempt == o.TimeoutPerAttempt &&
+		r.OverallTimeout == o.OverallTimeout &&
+		r.SliceOverallTimeout == o.SliceOverallTimeout &&
+		r.ClassifierName == o.ClassifierName &&
+		r.Budget == o.Budget &&
+		r.AcceptPartial == o.AcceptPartial &&
+		maps.Equal(r.BackoffOverrides, o.BackoffOverrides)
+}
+
+// BackoffSpec is the backoff curve for retries after one outcome reason (see
+// RetryPolicy.BackoffOverrides). Each reason's curve advances on its own; zero fields
+// take the retry policy's value.
+type BackoffSpec struct {
+	InitialBackoff    time.Duration `json:"initial_backoff,omitempty"`    // Backoff after the reason's first occurrence.
+	MaxBackoff        time.Duration `json:"max_backoff,omitempty"`        // Upper bound for the reason's backoff.
+	BackoffMultiplier float64       `json:"backoff_multiplier,omitempty"` // Growth per further occurrence.
+	Jitter            JitterKind    `json:"jitter,omitempty"`             // Jitter strategy.
 }
 
 type HedgePolicy struct {
-	Enabled               bool          `json:"enabled"`                     // Enable hedging for this key.
-	MaxHedges             int           `json:"max_hedges"`                  // Maximum additional hedged attempts.
-	HedgeDelay            time.Duration `json:"hedge_delay"`                 // Delay before spawning a hedge.
-	TriggerName           string        `json:"trigger_name,omitempty"`      // Optional dynamic trigger name.
-	CancelOnFirstTerminal bool          `json:"cancel_on_first_terminal"`    // Cancel on any terminal outcome.
-	Budget                BudgetRef     `json:"budget,omitempty"`            // Budget gating for hedged attempts.
+	Enabled               bool          `json:"enabled"`                    // Enable hedging for this key.
+	MaxHedges             int           `json:"max_hedges"`                 // Maximum additional hedged attempts.
+	HedgeDelay            time.Duration `json:"hedge_delay"`                // Delay before spawning a hedge.
+	TriggerName           string        `json:"trigger_name,omitempty"`     // Optional dynamic trigger name.
+	CancelOnFirstTerminal bool          `json:"cancel_on_first_terminal"`   // Cancel on any terminal outcome.
+	Budget                BudgetRef     `json:"budget,omitempty"`           // Budget gating for hedged attempts.
+	IdempotencyKeys       bool          `json:"idempotency_keys,omitempty"` // Share one idempotency key across a group's attempts.
 }
 
 type CircuitPolicy struct {
 	Enabled   bool          `json:"enabled"`   // Enable circuit breaking for this key.
 	Threshold int           `json:"threshold"` // Consecutive failures to open the circuit.
 	Cooldown  time.Duration `json:"cooldown"`  // Cooldown before a half-open probe.
+
+	// ProbeAcceptPartial counts a half-open probe with a partial outcome as a success.
+	ProbeAcceptPartial bool `json:"probe_accept_partial,omitempty"`
+}
+
+// LimitsPolicy caps the traffic an executor sends for a key. The caps count calls, not
+// attempts; calls over a cap fail at once with a shed error. Zero fields set no cap.
+type LimitsPolicy struct {
+	MaxInFlight int     `json:"max_in_flight,omitempty"` // Calls that may run at once.
+	MaxQPS      float64 `json:"max_qps,omitempty"`       // Calls that may start per second.
+	Burst       int     `json:"burst,omitempty"`         // Calls that may start at once within MaxQPS (default: MaxQPS rounded up).
 }
 
 type PolicySource string
@@ -58,20 +114,23 @@ const (
 
 type NormalizationInfo struct {
 	Changed       bool     `json:"-"` // Whether normalization changed any field.
-	ChangedFields []string `json:"-"` // Dot-delimited field paths that were changed.
+	ChangedFields []string `json:"-"` // Dot-delimited field paths that were changed (shared; do not modify).
 }
 
 type Metadata struct {
 	Source        PolicySource      `json:"-"` // Policy resolution source.
+	Provider      string            `json:"-"` // Provider that served the policy (set by controlplane chains).
 	Normalization NormalizationInfo `json:"-"` // Normalization metadata.
 }
 
 type EffectivePolicy struct {
-	Key     PolicyKey     `json:"key"`           // Policy key this policy applies to.
-	ID      string        `json:"id,omitempty"`  // Optional policy identifier.
-	Retry   RetryPolicy   `json:"retry"`         // Retry envelope configuration.
-	Hedge   HedgePolicy   `json:"hedge"`         // Hedging configuration.
-	Circuit CircuitPolicy `json:"circuit"`       // Circuit breaker configuration.
+	Key     PolicyKey     `json:"key"`               // Policy key this policy applies to.
+	ID      string        `json:"id,omitempty"`      // Optional policy identifier.
+	Retry   RetryPolicy   `json:"retry"`             // Retry envelope configuration.
+	Hedge   HedgePolicy   `json:"hedge"`             // Hedging configuration.
+	Circuit CircuitPolicy `json:"circuit"`           // Circuit breaker configuration.
+	Limits  LimitsPolicy  `json:"limits"`            // Per-key traffic caps.
+	Rollout *Rollout      `json:"rollout,omitempty"` // Optional percentage rollout (canary) of this policy.
 
 	Meta Metadata `json:"-"` // Resolution metadata (source, normalization).
 }
@@ -126,17 +185,8 @@ const (
 
 func (p EffectivePolicy) Normalize() (EffectivePolicy, error) {
 	normalized := p
-	norm := &normalized.Meta.Normalization
-
-	markChanged := func(field string) {
-		norm.Changed = true
-		for _, f := range norm.ChangedFields {
-			if f == field {
-				return
-			}
-		}
-		norm.ChangedFields = append(norm.ChangedFields, field)
-	}
+	var changed changedFields
+	markChanged := changed.mark
 
 	if normalized.Retry.MaxAttempts == 0 {
 		normalized.Retry.MaxAttempts = 3
@@ -193,6 +243,14 @@ func (p EffectivePolicy) Normalize() (EffectivePolicy, error) {
 		return EffectivePolicy{}, &NormalizeError{Field: "retry.jitter", Value: string(normalized.Retry.Jitter)}
 	}
 
+	if len(normalized.Retry.BackoffOverrides) > 0 {
+		overrides, err := normalizeBackoffOverrides(normalized.Retry, markChanged)
+		if err != nil {
+			return EffectivePolicy{}, err
+		}
+		normalized.Retry.BackoffOverrides = overrides
+	}
+
 	if normalized.Retry.TimeoutPerAttempt < 0 {
 		normalized.Retry.TimeoutPerAttempt = 0
 		markChanged("retry.timeout_per_attempt")
@@ -229,7 +287,32 @@ func (p EffectivePolicy) Normalize() (EffectivePolicy, error) {
 		markChanged("hedge.budget.cost")
 	}
 
+	if normalized.Limits.MaxInFlight < 0 {
+		normalized.Limits.MaxInFlight = 0
+		markChanged("limits.max_in_flight")
+	}
+	if !(normalized.Limits.MaxQPS >= 0) { // Also catches NaN.
+		normalized.Limits.MaxQPS = 0
+		markChanged("limits.max_qps")
+	}
+	if normalized.Limits.MaxQPS == 0 && normalized.Limits.Burst != 0 {
+		normalized.Limits.Burst = 0
+		markChanged("limits.burst")
+	} else if normalized.Limits.MaxQPS > 0 && normalized.Limits.Burst <= 0 {
+		normalized.Limits.Burst = int(min(math.Ceil(normalized.Limits.MaxQPS), math.MaxInt32))
+		markChanged("limits.burst")
+	}
+
+	if normalized.Rollout != nil {
+		r, err := normalized.Rollout.normalize(markChanged)
+		if err != nil {
+			return EffectivePolicy{}, err
+		}
+		normalized.Rollout = r
+	}
+
 	if !normalized.Hedge.Enabled {
+		changed.record(&normalized.Meta.Normalization)
 		return normalized, nil
 	}
 
@@ -255,6 +338,7 @@ func (p EffectivePolicy) Normalize() (EffectivePolicy, error) {
 	}
 
 	if !normalized.Circuit.Enabled {
+		changed.record(&normalized.Meta.Normalization)
 		return normalized, nil
 	}
 
@@ -276,5 +360,43 @@ func (p EffectivePolicy) Normalize() (EffectivePolicy, error) {
 		markChanged("circuit.cooldown")
 	}
 
+	changed.record(&normalized.Meta.Normalization)
 	return normalized, nil
 }
+
+// normalizeBackoffOverrides returns a normalized copy of r.BackoffOverrides, filling
+// zero fields from r (already normalized) and applying the policy's bounds.
+func normalizeBackoffOverrides(r RetryPolicy, markChanged func(string)) (map[string]BackoffSpec, error) {
+	out := make(map[string]BackoffSpec, len(r.BackoffOverrides))
+	for reason, spec := range r.BackoffOverrides {
+		if reason == "" {
+			return nil, &NormalizeError{Field: "retry.backoff_overrides", Value: reason}
+		}
+		field := "retry.backoff_overrides." + reason
+		orig := spec
+		if spec.InitialBackoff <= 0 {
+			spec.InitialBackoff = r.InitialBackoff
+		}
+		spec.InitialBackoff = min(max(spec.InitialBackoff, minBackoffFloor), maxBackoffCeiling)
+		if spec.MaxBackoff <= 0 {
+			spec.MaxBackoff = r.MaxBackoff
+		}
+		spec.MaxBackoff = min(max(spec.MaxBackoff, spec.InitialBackoff), maxBackoffCeiling)
+		if spec.BackoffMultiplier == 0 {
+			spec.BackoffMultiplier = r.BackoffMultiplier
+		}
+		spec.BackoffMultiplier = min(max(spec.BackoffMultiplier, 1), maxBackoffMultiplier)
+		switch spec.Jitter {
+		case "":
+			spec.Jitter = r.Jitter
+		case JitterNone, JitterFull, JitterEqual:
+		default:
+			return nil, &NormalizeError{Field: field + ".jitter", Value: string(spec.Jitter)}
+		}
+		if spec != orig {
+			markChanged("retry.backoff_overrides")
+		}
+		out[reason] = spec
+	}
+	return out, nil
+}
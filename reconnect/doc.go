@@ -0,0 +1,29 @@
+// Package reconnect keeps a long-lived connection (WebSocket, gRPC stream, message
+// subscription) up with recourse policies.
+//
+// Run loops connect → run → disconnect. Each epoch is one retry.DoValue call, so
+// connect failures get the key's backoff, jitter, budget gating, and circuit protection,
+// and each epoch produces its own timeline. A connection that drops before StableAfter
+// counts as a failed attempt of the same epoch, so a flapping server is backed off like
+// one that refuses connections; one that stayed up longer starts a fresh epoch.
+//
+// Usage:
+//
+//	err := reconnect.Run(ctx, exec, key,
+//		func(ctx context.Context) (*websocket.Conn, error) {
+//			conn, _, err := websocket.DefaultDialer.DialContext(ctx, url, nil)
+//			return conn, err
+//		},
+//		func(ctx context.Context, conn *websocket.Conn) error {
+//			defer conn.Close()
+//			for {
+//				_, msg, err := conn.ReadMessage()
+//				if err != nil {
+//					return err
+//				}
+//				handle(msg)
+//			}
+//		},
+//		reconnect.Options{StableAfter: time.Minute},
+//	)
+package reconnect
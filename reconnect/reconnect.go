@@ -0,0 +1,123 @@
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultStableAfter is the uptime after which a dropped connection is not counted as a
+// failed attempt.
+const DefaultStableAfter = 30 * time.Second
+
+// ErrStop ends Run with a nil error when returned (possibly wrapped) by run.
+var ErrStop = errors.New("reconnect: stop")
+
+// ErrClosedEarly is the attempt error recorded when run returns nil before StableAfter.
+var ErrClosedEarly = errors.New("reconnect: connection closed before it was stable")
+
+// Options configures Run.
+type Options struct {
+	// StableAfter is how long a connection must stay up for its loss to start a new
+	// epoch rather than count as a failed attempt. Zero means DefaultStableAfter.
+	StableAfter time.Duration
+
+	// OnEpoch, if set, is called after each epoch.
+	OnEpoch func(Epoch)
+}
+
+// Epoch describes one DoValue call: the connect attempts up to a connection that was
+// stable, stopped, or the last one the policy allowed.
+type Epoch struct {
+	Index    int
+	Timeline observe.Timeline
+
+	// Uptime is how long the last connection of the epoch stayed up (zero if connect
+	// never succeeded).
+	Uptime time.Duration
+
+	// Err is the error that ended the epoch: the executor's error if it gave up, else
+	// the error returned by run for a stable connection (nil if it closed cleanly).
+	Err error
+}
+
+// Run connects and runs until ctx is done, run returns ErrStop, or an epoch fails.
+//
+// connect receives the attempt context, which carries the policy's per-attempt timeout;
+// use it to bound dialing only. run receives ctx and owns the connection: it should
+// close it before returning. Its error is classified like any attempt error, so a
+// non-retryable error ends Run.
+//
+// Run returns nil after ErrStop, ctx.Err() once ctx is done, and otherwise the error of
+// the epoch that failed (attempts exhausted, budget denied, circuit open, or a
+// non-retryable error). A nil exec uses retry.DefaultExecutor(). Policies used with Run
+// should not enable hedging.
+func Run[C any](ctx context.Context, exec *retry.Executor, key policy.PolicyKey, connect func(context.Context) (C, error), run func(context.Context, C) error, opts Options) error {
+	if exec == nil {
+		exec = retry.DefaultExecutor()
+	}
+	stableAfter := opts.StableAfter
+	if stableAfter <= 0 {
+		stableAfter = DefaultStableAfter
+	}
+
+	for index := 0; ; index++ {
+		var (
+			stopped bool
+			uptime  time.Duration
+			runErr  error
+		)
+		op := func(attemptCtx context.Context) (struct{}, error) {
+			uptime = 0
+			conn, err := connect(attemptCtx)
+			if err != nil {
+				return struct{}{}, err
+			}
+
+			start := time.Now()
+			err = run(ctx, conn)
+			uptime = time.Since(start)
+
+			switch {
+			case errors.Is(err, ErrStop):
+				stopped = true
+				return struct{}{}, nil
+			case ctx.Err() != nil:
+				return struct{}{}, ctx.Err()
+			case uptime >= stableAfter:
+				runErr = err
+				return struct{}{}, nil
+			case err == nil:
+				return struct{}{}, ErrClosedEarly
+			}
+			return struct{}{}, err
+		}
+
+		epochCtx, capture := observe.RecordTimeline(ctx)
+		_, err := retry.DoValue(epochCtx, exec, key, op)
+
+		if opts.OnEpoch != nil {
+			ep := Epoch{Index: index, Uptime: uptime, Err: err}
+			if err == nil {
+				ep.Err = runErr
+			}
+			if tl := capture.Timeline(); tl != nil {
+				ep.Timeline = *tl
+			}
+			opts.OnEpoch(ep)
+		}
+
+		switch {
+		case stopped:
+			return nil
+		case ctx.Err() != nil:
+			return ctx.Err()
+		case err != nil:
+			return err
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package reconnect
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+type conn struct{ id int }
+
+func newTestExecutor(key policy.PolicyKey, attempts int) *retry.Executor {
+	return retry.NewDefaultExecutor(retry.WithPolicyKey(key, policy.MaxAttempts(attempts), policy.Backoff(time.Microsecond, time.Microsecond, 1)))
+}
+
+func TestRun_RetriesConnectThenStops(t *testing.T) {
+	key := policy.PolicyKey{Name: "stream"}
+	exec := newTestExecutor(key, 3)
+
+	dials := 0
+	connect := func(context.Context) (*conn, error) {
+		dials++
+		if dials < 3 {
+			return nil, errors.New("refused")
+		}
+		return &conn{id: dials}, nil
+	}
+	run := func(_ context.Context, c *conn) error {
+		if c.id != 3 {
+			t.Fatalf("unexpected connection %d", c.id)
+		}
+		return ErrStop
+	}
+
+	var epochs []Epoch
+	err := Run(context.Background(), exec, key, connect, run, Options{OnEpoch: func(e Epoch) { epochs = append(epochs, e) }})
+	if err != nil {
+		t.Fatalf("expected nil after ErrStop, got %v", err)
+	}
+	if len(epochs) != 1 || len(epochs[0].Timeline.Attempts) != 3 {
+		t.Fatalf("expected one epoch with 3 attempts, got %+v", epochs)
+	}
+}
+
+func TestRun_StableConnectionStartsNewEpoch(t *testing.T) {
+	key := policy.PolicyKey{Name: "stream"}
+	exec := newTestExecutor(key, 1)
+	dropped := errors.New("connection lost")
+
+	runs := 0
+	connect := func(context.Context) (int, error) { return 0, nil }
+	run := func(context.Context, int) error {
+		runs++
+		if runs == 3 {
+			return ErrStop
+		}
+		time.Sleep(2 * time.Millisecond)
+		return dropped
+	}
+
+	var epochs []Epoch
+	err := Run(context.Background(), exec, key, connect, run, Options{
+		StableAfter: time.Millisecond,
+		OnEpoch:     func(e Epoch) { epochs = append(epochs, e) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// A single-attempt policy still reconnects after stable connections.
+	if len(epochs) != 3 {
+		t.Fatalf("expected 3 epochs, got %d", len(epochs))
+	}
+	if !errors.Is(epochs[0].Err, dropped) || epochs[0].Uptime < time.Millisecond || epochs[1].Index != 1 {
+		t.Fatalf("unexpected epoch: %+v", epochs[0])
+	}
+}
+
+func TestRun_FlappingConnectionExhaustsAttempts(t *testing.T) {
+	key := policy.PolicyKey{Name: "stream"}
+	exec := newTestExecutor(key, 2)
+
+	runs := 0
+	connect := func(context.Context) (int, error) { return 0, nil }
+	run := func(context.Context, int) error {
+		runs++
+		return nil
+	}
+
+	err := Run(context.Background(), exec, key, connect, run, Options{StableAfter: time.Hour})
+	if !errors.Is(err, ErrClosedEarly) {
+		t.Fatalf("expected ErrClosedEarly, got %v", err)
+	}
+	if runs != 2 {
+		t.Fatalf("expected 2 connections, got %d", runs)
+	}
+}
+
+func TestRun_ContextCancel(t *testing.T) {
+	key := policy.PolicyKey{Name: "stream"}
+	exec := newTestExecutor(key, 5)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	connect := func(context.Context) (int, error) { return 0, nil }
+	run := func(ctx context.Context, _ int) error {
+		cancel()
+		<-ctx.Done()
+		return errors.New("read: use of closed connection")
+	}
+
+	if err := Run(ctx, exec, key, connect, run, Options{}); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
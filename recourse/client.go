@@ -0,0 +1,184 @@
+package recourse
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/hedge"
+	"github.com/aponysus/recourse/internal"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+)
+
+// Client is a ready executor together with the registries it was built with, so
+// budgets, classifiers, triggers, and circuits can be inspected or extended later.
+type Client struct {
+	*retry.Executor
+
+	Budgets     *budget.Registry
+	Classifiers *classify.Registry
+	Triggers    *hedge.Registry
+	Circuits    *circuit.Registry
+	// Timelines holds recent call timelines when the Client was built by FromConfig
+	// with observers.timelines set.
+	Timelines *observe.TimelineBuffer
+
+	runners []func(context.Context) error
+}
+
+// ClientBuilder assembles a Client. Create one with NewClient, chain its methods, and
+// call Build. Errors from the chained methods are reported by Build.
+type ClientBuilder struct {
+	client    Client
+	provider  controlplane.PolicyProvider
+	observers []observe.Observer
+	policies  []retry.ExecutorOption
+	opts      []retry.ExecutorOption
+	runners   []func(context.Context) error
+	err       error
+}
+
+// NewClient starts a builder with the defaults of retry.NewDefaultExecutor: built-in
+// classifiers, the "unlimited" budget, and the built-in hedge triggers.
+//
+//	client, err := recourse.NewClient().
+//		WithProvider(provider).
+//		WithBudget("db", budget.NewTokenBucketBudget(100, 10)).
+//		WithObserver(obs).
+//		Build()
+//	user, err := retry.DoValue(ctx, client.Executor, key, op)
+func NewClient() *ClientBuilder {
+	return &ClientBuilder{client: Client{
+		Budgets:     retry.DefaultBudgets(),
+		Classifiers: retry.DefaultClassifiers(),
+		Triggers:    retry.DefaultHedgeTriggers(),
+		Circuits:    circuit.NewRegistry(),
+	}}
+}
+
+// WithProvider sets the policy provider. It cannot be combined with WithPolicy.
+func (b *ClientBuilder) WithProvider(p controlplane.PolicyProvider) *ClientBuilder {
+	b.provider = p
+	return b
+}
+
+// WithPolicy adds a static policy for key (e.g. "svc.Method"). It cannot be combined
+// with WithProvider; put the policy in the provider instead.
+func (b *ClientBuilder) WithPolicy(key string, opts ...policy.Option) *ClientBuilder {
+	b.policies = append(b.policies, retry.WithPolicy(key, opts...))
+	return b
+}
+
+// WithBudget registers bud under name, replacing a default of the same name.
+func (b *ClientBuilder) WithBudget(name string, bud budget.Budget) *ClientBuilder {
+	if err := b.client.Budgets.Register(name, bud); err != nil {
+		b.fail(fmt.Errorf("budget %q: %w", name, err))
+	}
+	return b
+}
+
+// WithClassifier registers c under name, for policies' classifier_name.
+func (b *ClientBuilder) WithClassifier(name string, c classify.Classifier) *ClientBuilder {
+	if strings.TrimSpace(name) == "" || internal.IsTypedNil(c) {
+		b.fail(errors.New("classifier needs a name and a non-nil value"))
+		return b
+	}
+	b.client.Classifiers.Register(name, c)
+	return b
+}
+
+// WithTrigger registers t under name, for policies' hedge trigger_name.
+func (b *ClientBuilder) WithTrigger(name string, t hedge.Trigger) *ClientBuilder {
+	if strings.TrimSpace(name) == "" || internal.IsTypedNil(t) {
+		b.fail(errors.New("hedge trigger needs a name and a non-nil value"))
+		return b
+	}
+	b.client.Triggers.Register(name, t)
+	return b
+}
+
+// WithObserver adds an observer. Several observers are combined with
+// observe.MultiObserver, in the order added.
+func (b *ClientBuilder) WithObserver(o observe.Observer) *ClientBuilder {
+	if internal.IsTypedNil(o) {
+		b.fail(errors.New("observer is nil"))
+		return b
+	}
+	b.observers = append(b.observers, o)
+	return b
+}
+
+// WithOptions adds executor options for settings the builder has no method for. They
+// are applied last, so they override the builder's own settings.
+func (b *ClientBuilder) WithOptions(opts ...retry.ExecutorOption) *ClientBuilder {
+	b.opts = append(b.opts, opts...)
+	return b
+}
+
+func (b *ClientBuilder) fail(err error) {
+	if b.err == nil {
+		b.err = fmt.Errorf("recourse: NewClient: %w", err)
+	}
+}
+
+// Build returns the Client, or the first error from the builder's methods.
+func (b *ClientBuilder) Build() (*Client, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if b.provider != nil && len(b.policies) > 0 {
+		return nil, errors.New("recourse: NewClient: WithPolicy cannot be combined with WithProvider")
+	}
+
+	c := b.client
+	opts := []retry.ExecutorOption{
+		retry.WithClassifiers(c.Classifiers),
+		retry.WithDefaultClassifier(classify.AutoClassifier{}),
+		retry.WithBudgetRegistry(c.Budgets),
+		retry.WithHedgeTriggerRegistry(c.Triggers),
+		retry.WithCircuitRegistry(c.Circuits),
+	}
+	if b.provider != nil {
+		opts = append(opts, retry.WithProvider(b.provider))
+	}
+	switch len(b.observers) {
+	case 0:
+	case 1:
+		opts = append(opts, retry.WithObserver(b.observers[0]))
+	default:
+		opts = append(opts, retry.WithObserver(&observe.MultiObserver{Observers: b.observers}))
+	}
+	opts = append(opts, b.policies...)
+	opts = append(opts, b.opts...)
+
+	c.Executor = retry.NewDefaultExecutor(opts...)
+	c.runners = b.runners
+	return &c, nil
+}
+
+// Run keeps the Client's remote policies fresh, reloading a configured bundle file or
+// polling a configured URL, until ctx is done. It returns ctx.Err(), or nil at once
+// when there is nothing to refresh.
+func (c *Client) Run(ctx context.Context) error {
+	if len(c.runners) == 0 {
+		return nil
+	}
+	var wg sync.WaitGroup
+	for _, run := range c.runners {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_ = run(ctx)
+		}()
+	}
+	wg.Wait()
+	return ctx.Err()
+}
@@ -0,0 +1,64 @@
+package recourse_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/recourse"
+	"github.com/aponysus/recourse/retry"
+)
+
+type attemptCounter struct {
+	observe.BaseObserver
+	n atomic.Int32
+}
+
+func (o *attemptCounter) OnAttempt(context.Context, policy.PolicyKey, observe.AttemptRecord) {
+	o.n.Add(1)
+}
+
+func TestNewClient(t *testing.T) {
+	var a, b attemptCounter
+	client, err := recourse.NewClient().
+		WithBudget("db", budget.NewTokenBucketBudget(2, 0)).
+		WithPolicy("db.Query", policy.MaxAttempts(5), policy.ConstantBackoff(time.Millisecond), policy.Budget("db")).
+		WithObserver(&a).
+		WithObserver(&b).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: %v", err)
+	}
+	if _, ok := client.Budgets.Get("unlimited"); !ok {
+		t.Fatal("default budgets missing")
+	}
+
+	err = client.Do(context.Background(), policy.ParseKey("db.Query"), func(context.Context) error {
+		return errors.New("fail")
+	})
+	if !errors.Is(err, retry.ErrBudgetDenied) {
+		t.Fatalf("Do = %v, want ErrBudgetDenied after the budget's 2 tokens", err)
+	}
+	if a.n.Load() == 0 || a.n.Load() != b.n.Load() {
+		t.Fatalf("observers saw %d and %d attempts, want both to see every attempt", a.n.Load(), b.n.Load())
+	}
+}
+
+func TestNewClient_Errors(t *testing.T) {
+	for name, builder := range map[string]*recourse.ClientBuilder{
+		"nil budget":          recourse.NewClient().WithBudget("db", nil),
+		"unnamed classifier":  recourse.NewClient().WithClassifier("", nil),
+		"nil observer":        recourse.NewClient().WithObserver(nil),
+		"provider and policy": recourse.NewClient().WithProvider(&controlplane.StaticProvider{}).WithPolicy("svc.Get"),
+	} {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("%s: Build succeeded, want error", name)
+		}
+	}
+}
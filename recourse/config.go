@@ -0,0 +1,408 @@
+package recourse
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/observe/statsd"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/retry"
+	"github.com/aponysus/recourse/shed"
+)
+
+// Config is a declarative configuration of the whole subsystem: where policies come
+// from, budgets, circuit parameters, classifiers, and observers. It decodes from JSON
+// (see FromConfigFile):
+//
+//	{
+//	  "bundle": "/etc/recourse/policies.json",
+//	  "reload_interval": "30s",
+//	  "lkg": "/var/lib/recourse/lkg.json",
+//	  "budgets": {"db": {"capacity": 100, "refill_per_second": 10}},
+//	  "classifiers": {"payments": "http"},
+//	  "missing_policy": "fallback",
+//	  "observers": {"statsd": {"addr": "127.0.0.1:8125", "tags": ["env:prod"]}, "timelines": 100}
+//	}
+//
+// Durations are strings ("30s") or nanoseconds.
+type Config struct {
+	// Bundle is the path of a bundle document served by a controlplane.FileProvider.
+	Bundle string `json:"bundle,omitempty"`
+	// URL is a bundle URL served by a controlplane.HTTPProvider.
+	URL string `json:"url,omitempty"`
+	// Header is added to every bundle request (e.g. Authorization).
+	Header map[string]string `json:"header,omitempty"`
+	// ReloadInterval is how often Client.Run re-reads Bundle or polls URL. Zero reads
+	// Bundle once; URL defaults to one minute.
+	ReloadInterval Duration `json:"reload_interval,omitempty"`
+	// LKG is the path of a last-known-good snapshot for the Bundle or URL provider.
+	LKG string `json:"lkg,omitempty"`
+	// Policies are static policies, in bundle entry form, used when neither Bundle nor
+	// URL is set.
+	Policies []policy.EffectivePolicy `json:"policies,omitempty"`
+
+	// Budgets are registered by name in addition to the default "unlimited" budget.
+	Budgets map[string]controlplane.BudgetSpec `json:"budgets,omitempty"`
+	// Circuit overrides every policy's circuit threshold and cooldown. With Bundle or
+	// URL, use the bundle's "circuit" section instead.
+	Circuit *controlplane.CircuitSpec `json:"circuit,omitempty"`
+
+	// Shed, if set, enables admission control with a shed.Limiter.
+	Shed *ShedConfig `json:"shed,omitempty"`
+
+	// Classifiers registers aliases of registered classifiers (e.g. "payments": "http").
+	Classifiers map[string]string `json:"classifiers,omitempty"`
+	// DefaultClassifier names the classifier for policies without classifier_name.
+	DefaultClassifier string `json:"default_classifier,omitempty"`
+	// MissingPolicy is the missing-policy mode: "deny" (default), "allow",
+	// "fallback", or "allow_unsafe".
+	MissingPolicy string `json:"missing_policy,omitempty"`
+	// ProviderTimeout, if set, enables provider protection with this lookup timeout.
+	ProviderTimeout Duration `json:"provider_timeout,omitempty"`
+	// RecoverPanics converts panics in operations and extensions into errors.
+	RecoverPanics bool `json:"recover_panics,omitempty"`
+	// ErrorSummary appends the attempt history to failed calls' error messages.
+	ErrorSummary bool `json:"error_summary,omitempty"`
+	// ProfilerLabels tags operations with their policy key in pprof profiles.
+	ProfilerLabels bool `json:"profiler_labels,omitempty"`
+	// ClockResolution, if set, timestamps attempts with a coarse clock of this
+	// resolution instead of reading the system clock each time.
+	ClockResolution Duration `json:"clock_resolution,omitempty"`
+	// AdaptiveAttempts, if set, lowers MaxAttempts for keys whose retries stop
+	// succeeding (see retry.WithAdaptiveAttempts).
+	AdaptiveAttempts *AdaptiveAttemptsConfig `json:"adaptive_attempts,omitempty"`
+	// RetryStorm, if set, detects keys in retry storms (see retry.WithRetryStorm).
+	RetryStorm *RetryStormConfig `json:"retry_storm,omitempty"`
+	// MemoryBounds, if set, caps the data kept in call timelines (see
+	// retry.WithMemoryBounds).
+	MemoryBounds *MemoryBoundsConfig `json:"memory_bounds,omitempty"`
+	// StragglerGrace, if set, bounds how long canceled hedged attempts hold budget
+	// reservations (see retry.WithStragglerGrace).
+	StragglerGrace Duration `json:"straggler_grace,omitempty"`
+
+	Observers ObserversConfig `json:"observers,omitempty"`
+}
+
+// AdaptiveAttemptsConfig configures adaptive MaxAttempts (see retry.AdaptiveAttempts).
+// Zero fields use the retry package defaults.
+type AdaptiveAttemptsConfig struct {
+	MinRetriedCalls    int      `json:"min_retried_calls,omitempty"`
+	ReduceBelow        float64  `json:"reduce_below,omitempty"`
+	RestoreAbove       float64  `json:"restore_above,omitempty"`
+	ReducedMaxAttempts int      `json:"reduced_max_attempts,omitempty"`
+	Interval           Duration `json:"interval,omitempty"`
+}
+
+// RetryStormConfig configures retry-storm detection (see retry.RetryStorm). Zero
+// fields use the retry package defaults.
+type RetryStormConfig struct {
+	MinCalls             int      `json:"min_calls,omitempty"`
+	MaxRetryRatio        float64  `json:"max_retry_ratio,omitempty"`
+	MaxAttemptRate       float64  `json:"max_attempt_rate,omitempty"`
+	Mitigate             bool     `json:"mitigate,omitempty"`
+	MitigatedMaxAttempts int      `json:"mitigated_max_attempts,omitempty"`
+	Cooldown             Duration `json:"cooldown,omitempty"`
+	Interval             Duration `json:"interval,omitempty"`
+}
+
+// MemoryBoundsConfig caps observability data (see retry.MemoryBounds). Zero fields
+// are not capped.
+type MemoryBoundsConfig struct {
+	MaxAttemptsPerCall int   `json:"max_attempts_per_call,omitempty"`
+	MaxTimelineBytes   int64 `json:"max_timeline_bytes,omitempty"`
+	MaxAttributes      int   `json:"max_attributes,omitempty"`
+	MaxAttributeSize   int   `json:"max_attribute_size,omitempty"`
+}
+
+// ObserversConfig selects the built-in observers.
+type ObserversConfig struct {
+	// StatsD emits DogStatsD metrics (see observe/statsd).
+	StatsD *StatsDConfig `json:"statsd,omitempty"`
+	// Timelines, if positive, keeps that many recent timelines in an
+	// observe.TimelineBuffer, available as Client.Timelines.
+	Timelines int `json:"timelines,omitempty"`
+}
+
+// StatsDConfig configures the StatsD observer.
+type StatsDConfig struct {
+	Addr   string   `json:"addr"`
+	Prefix string   `json:"prefix,omitempty"`
+	Tags   []string `json:"tags,omitempty"`
+	// MaxReasonsPerPattern bounds distinct outcome tags per reason pattern
+	// (see statsd.Options).
+	MaxReasonsPerPattern int `json:"max_reasons_per_pattern,omitempty"`
+}
+
+// ShedConfig configures a shed.Limiter (see shed.Options).
+type ShedConfig struct {
+	MaxInFlight  int      `json:"max_in_flight"`
+	MaxQueueWait Duration `json:"max_queue_wait,omitempty"`
+	MaxQueue     int      `json:"max_queue,omitempty"`
+	// MaxHeapBytes, if set, sheds while the live heap exceeds it (shed.HeapSignal).
+	MaxHeapBytes uint64 `json:"max_heap_bytes,omitempty"`
+}
+
+// Duration is a time.Duration that decodes from a duration string ("250ms") or a
+// number of nanoseconds.
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		v, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		*d = Duration(v)
+		return nil
+	}
+	var n int64
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("duration must be a string or nanoseconds: %s", data)
+	}
+	*d = Duration(n)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// FromConfig builds a Client from cfg. Call Client.Run to keep remote policies fresh
+// when Bundle is reloaded or URL is polled.
+func FromConfig(cfg Config) (*Client, error) {
+	b, err := cfg.Builder()
+	if err != nil {
+		return nil, err
+	}
+	return b.Build()
+}
+
+// FromConfigFile reads a JSON Config from path and builds a Client from it.
+func FromConfigFile(path string) (*Client, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("recourse: read config: %w", err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("recourse: decode config %s: %w", path, err)
+	}
+	return FromConfig(cfg)
+}
+
+// Builder returns a ClientBuilder configured from cfg, for adding settings that
+// cannot be expressed in configuration (custom budgets, classifiers, observers)
+// before Build. A Bundle is loaded, and a URL fetched, once before it returns; a
+// failed fetch is not an error when LKG is set.
+func (cfg Config) Builder() (*ClientBuilder, error) {
+	fail := func(format string, args ...any) (*ClientBuilder, error) {
+		return nil, fmt.Errorf("recourse: config: "+format, args...)
+	}
+	b := NewClient()
+
+	switch {
+	case cfg.Bundle != "" && cfg.URL != "":
+		return fail("bundle and url are exclusive")
+	case (cfg.Bundle != "" || cfg.URL != "") && len(cfg.Policies) > 0:
+		return fail("policies cannot be combined with bundle or url")
+	case cfg.LKG != "" && cfg.Bundle == "" && cfg.URL == "":
+		return fail("lkg needs a bundle or url")
+	case cfg.Circuit != nil && (cfg.Bundle != "" || cfg.URL != ""):
+		return fail("circuit comes from the bundle's circuit section when bundle or url is set")
+	}
+
+	var provider controlplane.PolicyProvider
+	var loadErr error
+	switch {
+	case cfg.Bundle != "":
+		p := controlplane.NewFileProvider(cfg.Bundle)
+		loadErr = p.Reload()
+		if interval := time.Duration(cfg.ReloadInterval); interval > 0 {
+			b.runners = append(b.runners, func(ctx context.Context) error { return p.Run(ctx, interval) })
+		}
+		provider = p
+	case cfg.URL != "":
+		interval := time.Duration(cfg.ReloadInterval)
+		if interval <= 0 {
+			interval = time.Minute
+		}
+		header := make(http.Header, len(cfg.Header))
+		for k, v := range cfg.Header {
+			header.Set(k, v)
+		}
+		p := controlplane.NewHTTPProvider(cfg.URL, interval, controlplane.HTTPProviderOptions{Header: header})
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		loadErr = p.Refresh(ctx)
+		cancel()
+		b.runners = append(b.runners, p.Run)
+		provider = p
+	case len(cfg.Policies) > 0:
+		static := &controlplane.StaticProvider{Policies: make(map[policy.PolicyKey]policy.EffectivePolicy, len(cfg.Policies))}
+		for i, pol := range cfg.Policies {
+			if pol.Key == (policy.PolicyKey{}) {
+				return fail("policy %d has no key", i)
+			}
+			if _, err := pol.Normalize(); err != nil {
+				return fail("policy %q: %v", pol.Key, err)
+			}
+			static.Policies[pol.Key] = pol
+		}
+		provider = static
+	}
+	if cfg.LKG != "" {
+		lkg, err := controlplane.NewLKGProvider(provider, cfg.LKG)
+		if err != nil {
+			return fail("%v", err)
+		}
+		provider, loadErr = lkg, nil
+	}
+	if loadErr != nil {
+		return fail("load policies: %v", loadErr)
+	}
+	if provider != nil {
+		b.WithProvider(provider)
+	}
+
+	for name, spec := range cfg.Budgets {
+		bud, err := budgetFromSpec(spec)
+		if err != nil {
+			return fail("budget %q: %v", name, err)
+		}
+		b.WithBudget(name, bud)
+	}
+	if c := cfg.Circuit; c != nil {
+		if c.Threshold < 0 || c.Cooldown < 0 {
+			return fail("circuit parameters must not be negative")
+		}
+		b.client.Circuits.SetOverride(c.Threshold, c.Cooldown)
+	}
+
+	for alias, name := range cfg.Classifiers {
+		c, ok := b.client.Classifiers.Get(name)
+		if !ok {
+			return fail("classifier %q: unknown classifier %q", alias, name)
+		}
+		b.WithClassifier(alias, c)
+	}
+	if cfg.DefaultClassifier != "" {
+		c, ok := b.client.Classifiers.Get(cfg.DefaultClassifier)
+		if !ok {
+			return fail("unknown default classifier %q", cfg.DefaultClassifier)
+		}
+		b.WithOptions(retry.WithDefaultClassifier(c))
+	}
+	if cfg.MissingPolicy != "" {
+		mode, ok := failureModes[strings.ToLower(cfg.MissingPolicy)]
+		if !ok {
+			return fail("unknown missing_policy %q", cfg.MissingPolicy)
+		}
+		b.WithOptions(retry.WithMissingPolicyMode(mode))
+	}
+	if cfg.ProviderTimeout > 0 {
+		b.WithOptions(retry.WithProviderProtection(retry.ProviderProtection{Timeout: time.Duration(cfg.ProviderTimeout)}))
+	}
+	if cfg.RecoverPanics {
+		b.WithOptions(retry.WithRecoverPanics(true))
+	}
+	if s := cfg.Shed; s != nil {
+		opts := shed.Options{MaxInFlight: s.MaxInFlight, MaxQueueWait: time.Duration(s.MaxQueueWait), MaxQueue: s.MaxQueue}
+		if s.MaxHeapBytes > 0 {
+			opts.Signals = append(opts.Signals, shed.HeapSignal(s.MaxHeapBytes))
+		}
+		b.WithOptions(retry.WithShedder(shed.NewLimiter(opts)))
+	}
+	if cfg.ErrorSummary {
+		b.WithOptions(retry.WithErrorSummary(true))
+	}
+	if cfg.ProfilerLabels {
+		b.WithOptions(retry.WithProfilerLabels(true))
+	}
+	if cfg.ClockResolution > 0 {
+		b.WithOptions(retry.WithCoarseClock(time.Duration(cfg.ClockResolution)))
+	}
+	if cfg.StragglerGrace > 0 {
+		b.WithOptions(retry.WithStragglerGrace(time.Duration(cfg.StragglerGrace)))
+	}
+	if a := cfg.AdaptiveAttempts; a != nil {
+		b.WithOptions(retry.WithAdaptiveAttempts(retry.AdaptiveAttempts{
+			MinRetriedCalls:    a.MinRetriedCalls,
+			ReduceBelow:        a.ReduceBelow,
+			RestoreAbove:       a.RestoreAbove,
+			ReducedMaxAttempts: a.ReducedMaxAttempts,
+			Interval:           time.Duration(a.Interval),
+		}))
+	}
+	if r := cfg.RetryStorm; r != nil {
+		b.WithOptions(retry.WithRetryStorm(retry.RetryStorm{
+			MinCalls:             r.MinCalls,
+			MaxRetryRatio:        r.MaxRetryRatio,
+			MaxAttemptRate:       r.MaxAttemptRate,
+			Mitigate:             r.Mitigate,
+			MitigatedMaxAttempts: r.MitigatedMaxAttempts,
+			Cooldown:             time.Duration(r.Cooldown),
+			Interval:             time.Duration(r.Interval),
+		}))
+	}
+	if m := cfg.MemoryBounds; m != nil {
+		b.WithOptions(retry.WithMemoryBounds(retry.MemoryBounds{
+			MaxAttemptsPerCall: m.MaxAttemptsPerCall,
+			MaxTimelineBytes:   m.MaxTimelineBytes,
+			MaxAttributes:      m.MaxAttributes,
+			MaxAttributeSize:   m.MaxAttributeSize,
+		}))
+	}
+
+	if s := cfg.Observers.StatsD; s != nil {
+		o, err := statsd.New(s.Addr, statsd.Options{Prefix: s.Prefix, Tags: s.Tags, MaxReasonsPerPattern: s.MaxReasonsPerPattern})
+		if err != nil {
+			return fail("statsd: %v", err)
+		}
+		b.WithObserver(o)
+	}
+	if n := cfg.Observers.Timelines; n > 0 {
+		b.client.Timelines = observe.NewTimelineBuffer(n)
+		b.WithObserver(b.client.Timelines)
+	}
+	return b, nil
+}
+
+var failureModes = map[string]retry.FailureMode{
+	"deny":         retry.FailureDeny,
+	"allow":        retry.FailureAllow,
+	"fallback":     retry.FailureFallback,
+	"allow_unsafe": retry.FailureAllowUnsafe,
+}
+
+func budgetFromSpec(spec controlplane.BudgetSpec) (budget.Budget, error) {
+	switch spec.Type {
+	case "", controlplane.BudgetTypeTokenBucket:
+		if spec.Capacity <= 0 || spec.RefillPerSecond < 0 {
+			return nil, errors.New("token bucket needs a positive capacity and a non-negative refill")
+		}
+		for _, t := range []float64{spec.LowPriorityThreshold, spec.NormalPriorityThreshold} {
+			if t < 0 || t > 1 {
+				return nil, errors.New("priority thresholds must be between 0 and 1")
+			}
+		}
+		return budget.NewTokenBucketBudget(spec.Capacity, spec.RefillPerSecond).WithPriorityThresholds(budget.PriorityThresholds{
+			Low:    spec.LowPriorityThreshold,
+			Normal: spec.NormalPriorityThreshold,
+		}), nil
+	case controlplane.BudgetTypeUnlimited:
+		return budget.UnlimitedBudget{}, nil
+	default:
+		return nil, fmt.Errorf("unknown type %q", spec.Type)
+	}
+}
@@ -0,0 +1,127 @@
+package recourse_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/recourse"
+	"github.com/aponysus/recourse/retry"
+)
+
+func TestFromConfigFile(t *testing.T) {
+	dir := t.TempDir()
+	bundle := filepath.Join(dir, "policies.json")
+	writeFile(t, bundle, `{"policies": [
+		{"key": {"namespace": "db", "name": "Query"}, "retry": {"max_attempts": 5, "initial_backoff": 1000000, "classifier_name": "db", "budget": {"name": "db"}}}
+	]}`)
+	cfgPath := filepath.Join(dir, "recourse.json")
+	writeFile(t, cfgPath, `{
+		"bundle": "`+filepath.ToSlash(bundle)+`",
+		"reload_interval": "10ms",
+		"budgets": {"db": {"capacity": 2}},
+		"classifiers": {"db": "always"},
+		"missing_policy": "allow",
+		"observers": {"timelines": 10}
+	}`)
+
+	client, err := recourse.FromConfigFile(cfgPath)
+	if err != nil {
+		t.Fatalf("FromConfigFile: %v", err)
+	}
+	if _, ok := client.Classifiers.Get("db"); !ok {
+		t.Fatal("classifier alias not registered")
+	}
+
+	calls := 0
+	err = client.Do(context.Background(), policy.ParseKey("db.Query"), func(context.Context) error {
+		calls++
+		return errors.New("fail")
+	})
+	if !errors.Is(err, retry.ErrBudgetDenied) || calls != 2 {
+		t.Fatalf("Do = %v after %d calls, want ErrBudgetDenied after the budget's 2 tokens", err, calls)
+	}
+	if got := len(client.Timelines.Timelines()); got != 1 {
+		t.Fatalf("timelines = %d, want 1", got)
+	}
+	if err := client.Do(context.Background(), policy.ParseKey("other.Call"), func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("missing policy with mode allow: %v", err)
+	}
+
+	// Run reloads the bundle.
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- client.Run(ctx) }()
+	writeFile(t, bundle, `{"policies": [{"key": {"namespace": "db", "name": "Query"}, "retry": {"max_attempts": 1}}]}`)
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pol, err := client.EffectivePolicy(context.Background(), policy.ParseKey("db.Query"))
+		if err == nil && pol.Retry.MaxAttempts == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("bundle not reloaded: max_attempts = %d, err = %v", pol.Retry.MaxAttempts, err)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run = %v, want context.Canceled", err)
+	}
+}
+
+func TestFromConfig_InlinePolicies(t *testing.T) {
+	var cfg recourse.Config
+	if err := json.Unmarshal([]byte(`{
+		"policies": [{"key": {"name": "svc.Get"}, "retry": {"max_attempts": 3, "initial_backoff": 1000000}}],
+		"provider_timeout": 50000000
+	}`), &cfg); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if got := time.Duration(cfg.ProviderTimeout); got != 50*time.Millisecond {
+		t.Fatalf("provider_timeout = %v, want 50ms", got)
+	}
+	client, err := recourse.FromConfig(cfg)
+	if err != nil {
+		t.Fatalf("FromConfig: %v", err)
+	}
+	calls := 0
+	_ = client.Do(context.Background(), policy.ParseKey("svc.Get"), func(context.Context) error {
+		calls++
+		return errors.New("fail")
+	})
+	if calls != 3 {
+		t.Fatalf("calls = %d, want 3", calls)
+	}
+	if err := client.Run(context.Background()); err != nil {
+		t.Fatalf("Run with nothing to refresh = %v, want nil", err)
+	}
+}
+
+func TestFromConfig_Errors(t *testing.T) {
+	for name, cfg := range map[string]recourse.Config{
+		"bundle and url":     {Bundle: "a.json", URL: "http://example.invalid"},
+		"lkg without source": {LKG: "lkg.json"},
+		"missing bundle":     {Bundle: filepath.Join(t.TempDir(), "missing.json")},
+		"bad budget":         {Budgets: map[string]controlplane.BudgetSpec{"db": {Capacity: 0}}},
+		"unknown classifier": {Classifiers: map[string]string{"db": "nope"}},
+		"unknown mode":       {MissingPolicy: "sometimes"},
+	} {
+		if _, err := recourse.FromConfig(cfg); err == nil {
+			t.Errorf("%s: FromConfig succeeded", name)
+		}
+	}
+}
+
+func writeFile(t *testing.T, path, data string) {
+	t.Helper()
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -1,5 +1,12 @@
 // Package recourse is the facade package that re-exports key types and provides helpers.
 //
+// Do and DoValue run on a default executor, created on first use with
+// retry.NewDefaultExecutor(). Configure it once at startup:
+//
+//	recourse.Configure(retry.WithProvider(provider), retry.WithObserver(obs))
+//
+// or install a prebuilt executor with SetDefault.
+//
 // To capture timelines for debugging or observability:
 //
 //	ctx, capture := observe.RecordTimeline(ctx)
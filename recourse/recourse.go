@@ -2,6 +2,7 @@ package recourse
 
 import (
 	"context"
+	"time"
 
 	"github.com/aponysus/recourse/policy"
 	"github.com/aponysus/recourse/retry"
@@ -10,6 +11,41 @@ import (
 // Key is the structured form of a policy key.
 type Key = policy.PolicyKey
 
+// CallError is the error returned for failed calls; it wraps the final error
+// with the key, attempt count, elapsed time, last outcome reason, and timeline.
+type CallError = retry.CallError
+
+// Failure sentinels for branching with errors.Is on errors returned by Do/DoValue.
+var (
+	// ErrAttemptsExhausted matches calls that failed after using every allowed attempt.
+	ErrAttemptsExhausted = retry.ErrAttemptsExhausted
+	// ErrBudgetDenied matches calls that stopped because a budget denied an attempt.
+	ErrBudgetDenied = retry.ErrBudgetDenied
+	// ErrCircuitOpen matches calls rejected by an open (or probing) circuit breaker.
+	ErrCircuitOpen = retry.ErrCircuitOpen
+	// ErrOverallTimeout matches calls that exceeded the policy's overall timeout.
+	ErrOverallTimeout = retry.ErrOverallTimeout
+	// ErrTargetUnhealthy matches calls whose retries were skipped because a health
+	// source marked the target unhealthy.
+	ErrTargetUnhealthy = retry.ErrTargetUnhealthy
+	// ErrNoPolicy matches calls denied because no policy could be resolved.
+	ErrNoPolicy = retry.ErrNoPolicy
+	// ErrShed matches calls rejected by admission control (see retry.WithShedder).
+	ErrShed = retry.ErrShed
+	// ErrPartialResult matches calls that returned an accepted partial value alongside
+	// the error (see policy.RetryPolicy.AcceptPartial).
+	ErrPartialResult = retry.ErrPartialResult
+)
+
+// RetryAfterError wraps err so the executor retries it after exactly d, ahead of the
+// classifier and the policy's backoff (see retry.RetryAfter). It returns nil if err is
+// nil.
+func RetryAfterError(err error, d time.Duration) error { return retry.RetryAfter(err, d) }
+
+// NoRetryError wraps err so the executor never retries it, ahead of the classifier
+// (see retry.NoRetry). It returns nil if err is nil.
+func NoRetryError(err error) error { return retry.NoRetry(err) }
+
 // ParseKey parses "namespace.name" into a Key.
 func ParseKey(s string) Key { return policy.ParseKey(s) }
 
@@ -19,6 +55,25 @@ func Init(exec *retry.Executor) {
 	retry.SetGlobal(exec)
 }
 
+// Configure replaces the default executor used by Do and DoValue with
+// retry.NewDefaultExecutor(opts...), e.g. to install a policy provider or observer.
+// Unlike Init it may be called at any time; calls already running are unaffected.
+func Configure(opts ...retry.ExecutorOption) {
+	retry.SetDefault(retry.NewDefaultExecutor(opts...))
+}
+
+// SetDefault replaces the default executor used by Do and DoValue.
+// Unlike Init it may be called at any time; calls already running are unaffected.
+func SetDefault(exec *retry.Executor) {
+	retry.SetDefault(exec)
+}
+
+// Default returns the executor used by Do and DoValue. Until Init, Configure, or
+// SetDefault is called it is lazily initialized with retry.NewDefaultExecutor().
+func Default() *retry.Executor {
+	return retry.DefaultExecutor()
+}
+
 // Do executes op using the default executor and the policy for key.
 func Do(ctx context.Context, key string, op retry.Operation) error {
 	return retry.DefaultExecutor().Do(ctx, policy.ParseKey(key), op)
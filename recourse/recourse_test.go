@@ -25,6 +25,8 @@ func newTestExecutor() *retry.Executor {
 		policy.ParseKey("recourse.success"):  testPolicy(2),
 		policy.ParseKey("recourse.retry"):    testPolicy(2),
 		policy.ParseKey("recourse.timeline"): testPolicy(2),
+		policy.ParseKey("recourse.exhaust"):  testPolicy(2),
+		policy.ParseKey("recourse.hint"):     testPolicy(3),
 	}
 	provider := &controlplane.StaticProvider{Policies: policies}
 	return retry.NewExecutor(retry.WithProvider(provider))
@@ -105,6 +107,55 @@ func TestDoValue_WithTimeline(t *testing.T) {
 	}
 }
 
+func TestDo_FailureSentinels(t *testing.T) {
+	opErr := errors.New("always fails")
+	err := recourse.Do(context.Background(), "recourse.exhaust", func(ctx context.Context) error {
+		return opErr
+	})
+	if !errors.Is(err, recourse.ErrAttemptsExhausted) {
+		t.Fatalf("expected ErrAttemptsExhausted, got %v", err)
+	}
+	if !errors.Is(err, opErr) {
+		t.Fatalf("expected wrapped op error, got %v", err)
+	}
+	if errors.Is(err, recourse.ErrBudgetDenied) || errors.Is(err, recourse.ErrCircuitOpen) || errors.Is(err, recourse.ErrOverallTimeout) {
+		t.Fatalf("unexpected sentinel match for %v", err)
+	}
+
+	var ce *recourse.CallError
+	if !errors.As(err, &ce) || ce.Attempts != 2 {
+		t.Fatalf("expected CallError with 2 attempts, got %+v", ce)
+	}
+}
+
+func TestNoRetryError_StopsRetries(t *testing.T) {
+	errGone := errors.New("gone")
+	var attempts int32
+	err := recourse.Do(context.Background(), "recourse.hint", func(context.Context) error {
+		atomic.AddInt32(&attempts, 1)
+		return recourse.NoRetryError(errGone)
+	})
+	if !errors.Is(err, errGone) {
+		t.Fatalf("err=%v, want it to wrap %v", err, errGone)
+	}
+	if attempts != 1 {
+		t.Fatalf("expected 1 attempt, got %d", attempts)
+	}
+}
+
+func TestRetryAfterError_Retries(t *testing.T) {
+	var attempts int32
+	got, err := recourse.DoValue(context.Background(), "recourse.hint", func(context.Context) (int, error) {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			return 0, recourse.RetryAfterError(errors.New("busy"), time.Millisecond)
+		}
+		return 5, nil
+	})
+	if err != nil || got != 5 || attempts != 2 {
+		t.Fatalf("got=%d err=%v attempts=%d, want 5, nil, 2", got, err, attempts)
+	}
+}
+
 func TestParseKey_VariousFormats(t *testing.T) {
 	cases := []struct {
 		input string
@@ -124,3 +175,35 @@ func TestParseKey_VariousFormats(t *testing.T) {
 		}
 	}
 }
+
+func TestConfigure_ReplacesDefault(t *testing.T) {
+	prev := recourse.Default()
+	defer recourse.SetDefault(prev)
+
+	provider := &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		policy.ParseKey("recourse.configured"): testPolicy(3),
+	}}
+	recourse.Configure(retry.WithProvider(provider))
+	if recourse.Default() == prev {
+		t.Fatal("Configure did not replace the default executor")
+	}
+
+	var calls int32
+	err := recourse.Do(context.Background(), "recourse.configured", func(ctx context.Context) error {
+		atomic.AddInt32(&calls, 1)
+		return errors.New("fail")
+	})
+	if !errors.Is(err, recourse.ErrAttemptsExhausted) || atomic.LoadInt32(&calls) != 3 {
+		t.Fatalf("Do = %v after %d calls, want exhausted after 3", err, calls)
+	}
+
+	exec := newTestExecutor()
+	recourse.SetDefault(exec)
+	if recourse.Default() != exec {
+		t.Fatal("SetDefault did not replace the default executor")
+	}
+	recourse.SetDefault(nil)
+	if recourse.Default() != exec {
+		t.Fatal("SetDefault(nil) changed the default executor")
+	}
+}
@@ -0,0 +1,192 @@
+package recoursetest
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aponysus/recourse/retry"
+)
+
+// DefaultStart is the initial time of a Clock created with a zero start.
+var DefaultStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is a fake clock. It implements retry.Sleeper; waits complete only when the
+// clock is advanced past their deadline. It is safe for concurrent use.
+type Clock struct {
+	mu      sync.Mutex
+	now     time.Time
+	timers  []*timer
+	changed chan struct{} // closed and replaced whenever timers are added
+}
+
+type timer struct {
+	at time.Time
+	ch chan time.Time
+}
+
+// NewClock returns a clock set to start, or DefaultStart if start is zero.
+func NewClock(start time.Time) *Clock {
+	if start.IsZero() {
+		start = DefaultStart
+	}
+	return &Clock{now: start, changed: make(chan struct{})}
+}
+
+// Options returns the executor options that put an executor on this clock
+// (retry.WithClock and retry.WithSleeper).
+func (c *Clock) Options() []retry.ExecutorOption {
+	return []retry.ExecutorOption{retry.WithClock(c.Now), retry.WithSleeper(c)}
+}
+
+// Now returns the clock's current time.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// After returns a channel that receives the clock's time once it has advanced by d.
+// A non-positive d fires immediately.
+func (c *Clock) After(d time.Duration) <-chan time.Time {
+	return c.newTimer(d).ch
+}
+
+// Sleep waits until the clock has advanced by d or ctx is done.
+func (c *Clock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	t := c.newTimer(d)
+	select {
+	case <-t.ch:
+		return nil
+	case <-ctx.Done():
+		c.remove(t)
+		return ctx.Err()
+	}
+}
+
+func (c *Clock) newTimer(d time.Duration) *timer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	t := &timer{at: c.now.Add(d), ch: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.ch <- c.now
+		return t
+	}
+	c.timers = append(c.timers, t)
+	close(c.changed)
+	c.changed = make(chan struct{})
+	return t
+}
+
+func (c *Clock) remove(t *timer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, p := range c.timers {
+		if p == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Advance moves the clock forward by d, firing every pending wait whose deadline is
+// reached, earliest first.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+	sort.SliceStable(c.timers, func(i, j int) bool { return c.timers[i].at.Before(c.timers[j].at) })
+	n := 0
+	for _, t := range c.timers {
+		if t.at.After(c.now) {
+			break
+		}
+		t.ch <- c.now
+		n++
+	}
+	c.timers = append(c.timers[:0], c.timers[n:]...)
+}
+
+// AdvanceToNext moves the clock to the earliest pending deadline and fires the waits
+// due then. It returns the distance advanced, or false if nothing is waiting.
+func (c *Clock) AdvanceToNext() (time.Duration, bool) {
+	c.mu.Lock()
+	if len(c.timers) == 0 {
+		c.mu.Unlock()
+		return 0, false
+	}
+	next := c.timers[0].at
+	for _, t := range c.timers[1:] {
+		if t.at.Before(next) {
+			next = t.at
+		}
+	}
+	d := next.Sub(c.now)
+	c.mu.Unlock()
+	c.Advance(d)
+	return d, true
+}
+
+// Waiters returns the number of pending waits.
+func (c *Clock) Waiters() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+// BlockUntil waits until at least n waits are pending, e.g. until an executor is
+// sleeping before its next attempt or waiting to launch a hedge. It returns ctx.Err()
+// if ctx is done first.
+func (c *Clock) BlockUntil(ctx context.Context, n int) error {
+	for {
+		c.mu.Lock()
+		pending, changed := len(c.timers), c.changed
+		c.mu.Unlock()
+		if pending >= n {
+			return nil
+		}
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// AutoAdvance starts advancing the clock to each wait as soon as it is registered, so
+// sequential retry flows run without real delays, and returns a function that stops
+// it. Concurrent waits (hedging) fire in deadline order only if they are registered
+// together; use BlockUntil and Advance when their order matters.
+func (c *Clock) AutoAdvance() (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			c.mu.Lock()
+			changed := c.changed
+			c.mu.Unlock()
+			if _, ok := c.AdvanceToNext(); ok {
+				continue
+			}
+			select {
+			case <-changed:
+			case <-done:
+				return
+			}
+		}
+	}()
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-stopped
+		})
+	}
+}
+
+var _ retry.Sleeper = (*Clock)(nil)
@@ -0,0 +1,21 @@
+// Package recoursetest provides deterministic test doubles for code that runs
+// operations through recourse executors.
+//
+// Clock is a fake clock and Sleeper: executors built with its Options never wait in
+// real time for backoff or hedge delays. Time moves only when the test calls Advance
+// (or AdvanceToNext, or AutoAdvance). Script builds operations with scripted outcomes:
+//
+//	clock := recoursetest.NewClock(time.Time{})
+//	exec := retry.NewExecutor(append(clock.Options(), retry.WithPolicy("svc.Get", policy.MaxAttempts(3)))...)
+//	op := recoursetest.NewScript[string]().FailTimes(2, errUnavailable).Succeed("ok")
+//
+//	defer clock.AutoAdvance()()
+//	v, err := retry.DoValue(ctx, exec, policy.ParseKey("svc.Get"), op.Value)
+//	// v == "ok", op.Calls() == 3
+//
+// For hedging, drive the hedge timer explicitly so the race between attempts is fixed:
+// wait for the pending timers with BlockUntil, then Advance past the hedge delay.
+//
+// Per-attempt timeouts, time slices and overall timeouts run on the clock too, and the
+// durations an executor reports are measured on it.
+package recoursetest
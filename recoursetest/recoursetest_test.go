@@ -0,0 +1,184 @@
+package recoursetest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/recoursetest"
+	"github.com/aponysus/recourse/retry"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+func TestScript_RetriesOnFakeClock(t *testing.T) {
+	clock := recoursetest.NewClock(time.Time{})
+	exec := retry.NewExecutor(append(clock.Options(),
+		retry.WithPolicy("svc.Get", policy.MaxAttempts(3), policy.Backoff(time.Second, 10*time.Second, 2), policy.Jitter(policy.JitterNone)),
+	)...)
+	op := recoursetest.NewScript[string]().FailTimes(2, errUnavailable).Succeed("ok")
+
+	stop := clock.AutoAdvance()
+	defer stop()
+	v, err := retry.DoValue(context.Background(), exec, policy.ParseKey("svc.Get"), op.Value)
+	if err != nil || v != "ok" || op.Calls() != 3 {
+		t.Fatalf("DoValue = %q, %v after %d calls; want ok after 3", v, err, op.Calls())
+	}
+	if elapsed := clock.Now().Sub(recoursetest.DefaultStart); elapsed != 3*time.Second {
+		t.Fatalf("clock advanced %v, want 3s of backoff", elapsed)
+	}
+}
+
+func TestScript_HedgeOnFakeClock(t *testing.T) {
+	clock := recoursetest.NewClock(time.Time{})
+	exec := retry.NewExecutor(append(clock.Options(),
+		retry.WithPolicy("svc.Get", policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1), policy.HedgeDelay(100*time.Millisecond)),
+	)...)
+	op := recoursetest.NewScript[string]().WithClock(clock).
+		SucceedAfter(time.Second, "primary").
+		Succeed("hedge")
+
+	type result struct {
+		v   string
+		err error
+	}
+	done := make(chan result, 1)
+	go func() {
+		v, err := retry.DoValue(context.Background(), exec, policy.ParseKey("svc.Get"), op.Value)
+		done <- result{v, err}
+	}()
+
+	// The primary attempt's delay and the hedge timer.
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := clock.BlockUntil(ctx, 2); err != nil {
+		t.Fatalf("BlockUntil: %v (waiters %d)", err, clock.Waiters())
+	}
+	clock.Advance(100 * time.Millisecond)
+
+	r := <-done
+	if r.err != nil || r.v != "hedge" || op.Calls() != 2 {
+		t.Fatalf("DoValue = %q, %v after %d calls; want the hedge to win", r.v, r.err, op.Calls())
+	}
+}
+
+func TestScript_HedgedTimeSlicedCallOnFakeClock(t *testing.T) {
+	clock := recoursetest.NewClock(time.Time{})
+	exec := retry.NewExecutor(append(clock.Options(),
+		retry.WithPolicy("svc.Get",
+			policy.MaxAttempts(2), policy.OverallTimeout(4*time.Second), policy.SliceOverallTimeout(),
+			policy.Backoff(time.Second, time.Second, 1), policy.Jitter(policy.JitterNone),
+			policy.EnableHedging(), policy.HedgeMaxAttempts(1), policy.HedgeDelay(500*time.Millisecond)),
+	)...)
+	started := make(chan struct{}, 4)
+	hang := func(ctx context.Context) (string, error) {
+		started <- struct{}{}
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	op := recoursetest.NewScript[string]().Then(hang)
+
+	ctx, capture := observe.RecordTimeline(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := retry.DoValue(ctx, exec, policy.ParseKey("svc.Get"), op.Value)
+		done <- err
+	}()
+
+	wait, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	block := func(n int) {
+		t.Helper()
+		if err := clock.BlockUntil(wait, n); err != nil {
+			t.Fatalf("BlockUntil(%d): %v (waiters %d)", n, err, clock.Waiters())
+		}
+	}
+
+	// Attempt 1 gets half the 4s overall timeout; its hedge launches at 500ms.
+	block(3) // Overall deadline, slice deadline, hedge timer.
+	<-started
+	clock.Advance(500 * time.Millisecond)
+	<-started
+	clock.Advance(1500 * time.Millisecond)
+
+	// 1s of backoff, then attempt 2 gets the 1s left, hedged at 500ms again.
+	block(2) // Overall deadline, backoff.
+	clock.Advance(time.Second)
+	block(3)
+	<-started
+	clock.Advance(500 * time.Millisecond)
+	<-started
+	clock.Advance(500 * time.Millisecond)
+
+	if err := <-done; !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("DoValue = %v, want the overall timeout", err)
+	}
+	tl := capture.Timeline()
+	if tl == nil || tl.Duration != 4*time.Second || len(tl.Attempts) != 4 {
+		t.Fatalf("timeline = %+v, want 4 attempts over 4s", tl)
+	}
+	// Durations by attempt index, primary then hedge.
+	want := [2][2]time.Duration{{2 * time.Second, 1500 * time.Millisecond}, {time.Second, 500 * time.Millisecond}}
+	for _, a := range tl.Attempts {
+		if w := want[a.Attempt][a.HedgeIndex]; a.Duration != w {
+			t.Errorf("attempt %d hedge %d took %v, want %v", a.Attempt, a.HedgeIndex, a.Duration, w)
+		}
+	}
+}
+
+func TestClock(t *testing.T) {
+	clock := recoursetest.NewClock(time.Time{})
+	a := clock.After(2 * time.Second)
+	b := clock.After(time.Second)
+	select {
+	case <-clock.After(0):
+	default:
+		t.Fatal("After(0) did not fire immediately")
+	}
+
+	if d, ok := clock.AdvanceToNext(); !ok || d != time.Second {
+		t.Fatalf("AdvanceToNext = %v, %v; want 1s", d, ok)
+	}
+	select {
+	case <-b:
+	default:
+		t.Fatal("1s timer did not fire")
+	}
+	select {
+	case <-a:
+		t.Fatal("2s timer fired early")
+	default:
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := clock.Sleep(ctx, time.Second); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Sleep with canceled ctx = %v", err)
+	}
+	if n := clock.Waiters(); n != 1 {
+		t.Fatalf("waiters = %d, want 1 (canceled sleep removed)", n)
+	}
+	clock.Advance(time.Second)
+	if _, ok := clock.AdvanceToNext(); ok || clock.Waiters() != 0 {
+		t.Fatal("waits pending after advancing past every deadline")
+	}
+}
+
+func TestScript_RepeatsLastStep(t *testing.T) {
+	op := recoursetest.NewScript[int]().Fail(errUnavailable).Succeed(7)
+	ctx := context.Background()
+	if err := op.Call(ctx); !errors.Is(err, errUnavailable) {
+		t.Fatalf("call 1 = %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if v, err := op.Value(ctx); v != 7 || err != nil {
+			t.Fatalf("call %d = %d, %v; want 7", i+2, v, err)
+		}
+	}
+	if op.Calls() != 3 {
+		t.Fatalf("Calls = %d, want 3", op.Calls())
+	}
+}
@@ -0,0 +1,127 @@
+package recoursetest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Script is an operation with scripted outcomes, e.g. "fail twice with X, then
+// succeed". Each call, including concurrent hedged attempts, takes the next step; once
+// the steps run out the last one repeats. A script with no steps succeeds with the zero
+// value. Build it before use; calling it is safe for concurrent use.
+//
+// Pass Value as a retry.OperationValue[T] or Call as a retry.Operation.
+type Script[T any] struct {
+	clock *Clock
+	steps []func(ctx context.Context) (T, error)
+
+	mu    sync.Mutex
+	calls int
+}
+
+// NewScript returns an empty script.
+func NewScript[T any]() *Script[T] {
+	return &Script[T]{}
+}
+
+// WithClock makes the script's delays (FailAfter, SucceedAfter) wait on c instead of
+// real time.
+func (s *Script[T]) WithClock(c *Clock) *Script[T] {
+	s.clock = c
+	return s
+}
+
+// Then adds a step that runs fn.
+func (s *Script[T]) Then(fn func(ctx context.Context) (T, error)) *Script[T] {
+	s.steps = append(s.steps, fn)
+	return s
+}
+
+// Fail adds a step that returns err.
+func (s *Script[T]) Fail(err error) *Script[T] {
+	return s.FailTimes(1, err)
+}
+
+// FailTimes adds n steps that return err.
+func (s *Script[T]) FailTimes(n int, err error) *Script[T] {
+	for i := 0; i < n; i++ {
+		s.Then(func(context.Context) (T, error) {
+			var zero T
+			return zero, err
+		})
+	}
+	return s
+}
+
+// Succeed adds a step that returns v.
+func (s *Script[T]) Succeed(v T) *Script[T] {
+	return s.Then(func(context.Context) (T, error) { return v, nil })
+}
+
+// FailAfter adds a step that returns err after d, or ctx.Err() if the attempt is
+// canceled first.
+func (s *Script[T]) FailAfter(d time.Duration, err error) *Script[T] {
+	return s.Then(func(ctx context.Context) (T, error) {
+		var zero T
+		if werr := s.wait(ctx, d); werr != nil {
+			return zero, werr
+		}
+		return zero, err
+	})
+}
+
+// SucceedAfter adds a step that returns v after d, or ctx.Err() if the attempt is
+// canceled first (e.g. a hedge won).
+func (s *Script[T]) SucceedAfter(d time.Duration, v T) *Script[T] {
+	return s.Then(func(ctx context.Context) (T, error) {
+		if err := s.wait(ctx, d); err != nil {
+			var zero T
+			return zero, err
+		}
+		return v, nil
+	})
+}
+
+func (s *Script[T]) wait(ctx context.Context, d time.Duration) error {
+	if s.clock != nil {
+		return s.clock.Sleep(ctx, d)
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Value runs the next step.
+func (s *Script[T]) Value(ctx context.Context) (T, error) {
+	s.mu.Lock()
+	i := s.calls
+	s.calls++
+	s.mu.Unlock()
+	if len(s.steps) == 0 {
+		var zero T
+		return zero, nil
+	}
+	if i >= len(s.steps) {
+		i = len(s.steps) - 1
+	}
+	return s.steps[i](ctx)
+}
+
+// Call runs the next step, discarding its value.
+func (s *Script[T]) Call(ctx context.Context) error {
+	_, err := s.Value(ctx)
+	return err
+}
+
+// Calls returns the number of calls so far.
+func (s *Script[T]) Calls() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
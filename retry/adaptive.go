@@ -0,0 +1,124 @@
+package retry
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for AdaptiveAttempts fields left zero.
+const (
+	DefaultAdaptiveMinRetriedCalls    = 20
+	DefaultAdaptiveReduceBelow        = 0.05
+	DefaultAdaptiveRestoreAbove       = 0.2
+	DefaultAdaptiveReducedMaxAttempts = 2
+	DefaultAdaptiveInterval           = time.Second
+)
+
+// AdaptiveAttempts configures success-rate adaptive MaxAttempts (see
+// WithAdaptiveAttempts).
+//
+// A key's MaxAttempts is reduced to ReducedMaxAttempts once its recent retried calls
+// almost never succeed (RetrySuccessRate below ReduceBelow), because its retries only
+// add load, and restored once retried calls succeed again (RetrySuccessRate at or
+// above RestoreAbove). Decisions need MinRetriedCalls retried calls in the stats
+// window; with fewer, the policy's MaxAttempts applies.
+type AdaptiveAttempts struct {
+	// Stats supplies per-key retry success rates. It must observe the executor's calls;
+	// if nil, the executor creates one and adds it to its observers.
+	Stats *observe.StatsCollector
+
+	MinRetriedCalls    int           // Default DefaultAdaptiveMinRetriedCalls.
+	ReduceBelow        float64       // Default DefaultAdaptiveReduceBelow.
+	RestoreAbove       float64       // Default DefaultAdaptiveRestoreAbove; never below ReduceBelow.
+	ReducedMaxAttempts int           // Default DefaultAdaptiveReducedMaxAttempts.
+	Interval           time.Duration // How often a key's decision is re-evaluated; default DefaultAdaptiveInterval.
+}
+
+func (a AdaptiveAttempts) normalize() AdaptiveAttempts {
+	if a.MinRetriedCalls <= 0 {
+		a.MinRetriedCalls = DefaultAdaptiveMinRetriedCalls
+	}
+	if a.ReduceBelow <= 0 {
+		a.ReduceBelow = DefaultAdaptiveReduceBelow
+	}
+	if a.RestoreAbove <= 0 {
+		a.RestoreAbove = DefaultAdaptiveRestoreAbove
+	}
+	a.RestoreAbove = max(a.RestoreAbove, a.ReduceBelow)
+	if a.ReducedMaxAttempts <= 0 {
+		a.ReducedMaxAttempts = DefaultAdaptiveReducedMaxAttempts
+	}
+	if a.Interval <= 0 {
+		a.Interval = DefaultAdaptiveInterval
+	}
+	return a
+}
+
+// WithAdaptiveAttempts enables success-rate adaptive MaxAttempts.
+//
+// With ReducedMaxAttempts of 1 a reduced key stops retrying altogether, so it is
+// restored only once its retried calls age out of the stats window; the default of 2
+// keeps one retry per call as a probe of whether retries help again.
+func WithAdaptiveAttempts(cfg AdaptiveAttempts) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.AdaptiveAttempts = &cfg
+	}
+}
+
+// adaptiveAttempts holds the per-key adaptive MaxAttempts decisions.
+type adaptiveAttempts struct {
+	cfg  AdaptiveAttempts
+	keys sync.Map // policy.PolicyKey -> *adaptiveKey
+}
+
+type adaptiveKey struct {
+	next    atomic.Int64 // Unix nanoseconds of the next evaluation.
+	reduced atomic.Bool
+}
+
+func newAdaptiveAttempts(cfg AdaptiveAttempts) *adaptiveAttempts {
+	return &adaptiveAttempts{cfg: cfg.normalize()}
+}
+
+// reduce reports whether key's MaxAttempts should currently be reduced, re-evaluating
+// the key's stats at most once per interval.
+func (a *adaptiveAttempts) reduce(key policy.PolicyKey, now time.Time) bool {
+	v, ok := a.keys.Load(key)
+	if !ok {
+		v, _ = a.keys.LoadOrStore(key, &adaptiveKey{})
+	}
+	k := v.(*adaptiveKey)
+
+	next := k.next.Load()
+	if now.UnixNano() < next || !k.next.CompareAndSwap(next, now.Add(a.cfg.Interval).UnixNano()) {
+		return k.reduced.Load()
+	}
+	s, ok := a.cfg.Stats.Stats(key)
+	switch {
+	case !ok || s.RetriedCalls < a.cfg.MinRetriedCalls:
+		k.reduced.Store(false)
+	case s.RetrySuccessRate < a.cfg.ReduceBelow:
+		k.reduced.Store(true)
+	case s.RetrySuccessRate >= a.cfg.RestoreAbove:
+		k.reduced.Store(false)
+	}
+	return k.reduced.Load()
+}
+
+// applyAdaptive lowers pol's MaxAttempts while key's retries are not helping.
+func (e *Executor) applyAdaptive(key policy.PolicyKey, pol policy.EffectivePolicy, attrs map[string]string) policy.EffectivePolicy {
+	a := e.adaptive
+	if a == nil || pol.Retry.MaxAttempts <= a.cfg.ReducedMaxAttempts || !a.reduce(key, e.clock()) {
+		return pol
+	}
+	pol.Retry.MaxAttempts = a.cfg.ReducedMaxAttempts
+	if attrs != nil {
+		attrs["adaptive_max_attempts"] = strconv.Itoa(a.cfg.ReducedMaxAttempts)
+	}
+	return pol
+}
@@ -0,0 +1,58 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestAdaptiveAttempts_ReducesAndRestores(t *testing.T) {
+	key := policy.PolicyKey{Name: "adaptive"}
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{MaxAttempts: 4}},
+		}},
+		AdaptiveAttempts: &AdaptiveAttempts{MinRetriedCalls: 5, Interval: time.Nanosecond},
+	})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	// call runs one call whose attempts fail until the succeedOn-th, and returns the
+	// number of attempts.
+	call := func(succeedOn int) int {
+		t.Helper()
+		n := 0
+		_ = exec.Do(context.Background(), key, func(context.Context) error {
+			n++
+			if n == succeedOn {
+				return nil
+			}
+			return errors.New("down")
+		})
+		return n
+	}
+
+	for i := 0; i < 5; i++ {
+		if n := call(0); n != 4 {
+			t.Fatalf("call %d: attempts=%d, want 4 before enough retried calls", i, n)
+		}
+	}
+	if n := call(0); n != DefaultAdaptiveReducedMaxAttempts {
+		t.Fatalf("attempts=%d, want %d once retries stopped helping", n, DefaultAdaptiveReducedMaxAttempts)
+	}
+	pol, err := exec.EffectivePolicy(context.Background(), key)
+	if err != nil || pol.Retry.MaxAttempts != DefaultAdaptiveReducedMaxAttempts {
+		t.Fatalf("EffectivePolicy MaxAttempts=%d err=%v, want the reduced value", pol.Retry.MaxAttempts, err)
+	}
+
+	// Retries succeed again: 3 successes out of 9 retried calls lifts the rate over 0.2.
+	for i := 0; i < 3; i++ {
+		call(2)
+	}
+	if n := call(0); n != 4 {
+		t.Fatalf("attempts=%d, want MaxAttempts restored to 4", n)
+	}
+}
@@ -0,0 +1,42 @@
+package retry
+
+import (
+	"context"
+	"sort"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Keys returns the policy keys that calls have been made for, sorted. Executors sharing
+// a Runtime share this set.
+func (e *Executor) Keys() []policy.PolicyKey {
+	keys := []policy.PolicyKey{}
+	e.trackers.m.Range(func(k, _ any) bool {
+		keys = append(keys, k.(policy.PolicyKey))
+		return true
+	})
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+	return keys
+}
+
+// EffectivePolicy resolves the policy a call for key would start with, including key
+// overrides and the kill switch. A rollout is reported as configured, not applied.
+func (e *Executor) EffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	pol, _, err := resolvePolicyWithAttributes(ctx, e, key, nil)
+	if err != nil {
+		return pol, err
+	}
+	return e.applyOverrides(ctx, key, pol, nil, nil), nil
+}
+
+// Circuits returns the executor's circuit breaker registry.
+func (e *Executor) Circuits() *circuit.Registry { return e.circuits }
+
+// Budgets returns the executor's budget registry, or nil if it has none.
+func (e *Executor) Budgets() *budget.Registry { return e.budgets }
+
+// Observer returns the executor's observer.
+func (e *Executor) Observer() observe.Observer { return e.observer }
@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/observe"
+)
+
+// AttemptContextFunc derives the context an attempt's operation runs with from the
+// attempt context the executor built and the attempt's info. See WithAttemptContext.
+type AttemptContextFunc func(ctx context.Context, info observe.AttemptInfo) context.Context
+
+// WithAttemptContext sets a function that decorates every attempt's context, hedges
+// included, before the operation runs; for example to propagate attempt info as
+// tracing baggage (see integrations/otel) so downstream spans can tell retries and
+// hedges apart.
+func WithAttemptContext(fn AttemptContextFunc) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.AttemptContext = fn
+	}
+}
+
+// WithAttemptInfo sets whether attempts run with observe.AttemptInfo in their context
+// (on by default). Operations and integrations read it with observe.AttemptFromContext,
+// for example to send attempt headers or dedupe hedged writes, so turn it off only for
+// executors whose operations never do. Without it, a successful call that takes the
+// fast path (no observer or timeline capture, no hedging or circuit breaking) makes no
+// heap allocations. Calls on the full path, and executors with WithAttemptContext,
+// always attach it.
+func WithAttemptInfo(enabled bool) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.OmitAttemptInfo = !enabled
+	}
+}
+
+// withAttemptContext wraps op to run with the context fn derives from its attempt
+// context.
+func withAttemptContext[T any](fn AttemptContextFunc, op OperationValue[T]) OperationValue[T] {
+	return func(ctx context.Context) (T, error) {
+		if info, ok := observe.AttemptFromContext(ctx); ok {
+			ctx = fn(ctx, info)
+		}
+		return op(ctx)
+	}
+}
@@ -0,0 +1,54 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type attemptTagKey struct{}
+
+func TestWithAttemptContext_Hedges(t *testing.T) {
+	key := policy.ParseKey("svc.Tagged")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.EnableHedging(), policy.HedgeDelay(time.Millisecond), policy.PolicyID("p1")),
+		WithAttemptContext(func(ctx context.Context, info observe.AttemptInfo) context.Context {
+			return context.WithValue(ctx, attemptTagKey{}, info)
+		}),
+	)
+
+	var mu sync.Mutex
+	var infos []observe.AttemptInfo
+	release := make(chan struct{})
+	_, err := DoValue(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		info, _ := ctx.Value(attemptTagKey{}).(observe.AttemptInfo)
+		mu.Lock()
+		infos = append(infos, info)
+		n := len(infos)
+		mu.Unlock()
+		if n == 1 {
+			// Hold the primary until the hedge has started.
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return 0, ctx.Err()
+		}
+		if n == 2 {
+			close(release)
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(infos) < 2 || infos[0].IsHedge || !infos[1].IsHedge || infos[1].PolicyID != "p1" {
+		t.Fatalf("infos = %+v, want the primary then a hedge, tagged with policy p1", infos)
+	}
+}
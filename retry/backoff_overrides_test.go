@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+// reasonClassifier classifies every error as retryable, with the error text as reason.
+type reasonClassifier struct{}
+
+func (reasonClassifier) Classify(_ any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess}
+	}
+	return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: err.Error()}
+}
+
+func TestBackoffOverrides_PerReasonCurve(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	pol, err := policy.NewFromKey(key,
+		policy.MaxAttempts(5),
+		policy.InitialBackoff(10*time.Millisecond),
+		policy.MaxBackoff(time.Second),
+		policy.Classifier("reason"),
+	).Normalize()
+	if err != nil {
+		t.Fatal(err)
+	}
+	pol.Retry.BackoffOverrides = map[string]policy.BackoffSpec{
+		"http_429": {InitialBackoff: 200 * time.Millisecond, MaxBackoff: 300 * time.Millisecond},
+	}
+	if pol, err = pol.Normalize(); err != nil {
+		t.Fatal(err)
+	}
+	exec := newTestExecutor(t, key, pol)
+	exec.classifiers.Register("reason", reasonClassifier{})
+
+	for _, timeline := range []bool{false, true} {
+		var sleeps []time.Duration
+		exec.sleep = func(_ context.Context, d time.Duration) error {
+			sleeps = append(sleeps, d)
+			return nil
+		}
+		reasons := []string{"http_429", "timeout", "http_429", "http_429"}
+		calls := 0
+		op := func(context.Context) (int, error) {
+			calls++
+			if calls <= len(reasons) {
+				return 0, errors.New(reasons[calls-1])
+			}
+			return 1, nil
+		}
+		if timeline {
+			_, _, err = doValueInternal(context.Background(), exec, key, op, true)
+		} else {
+			_, err = DoValue(context.Background(), exec, key, op)
+		}
+		if err != nil {
+			t.Fatalf("timeline=%v: err=%v", timeline, err)
+		}
+		// http_429 follows its own curve, capped at 300ms; timeout follows the policy's,
+		// which advances on every retry.
+		want := []time.Duration{200 * time.Millisecond, 20 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond}
+		if len(sleeps) != len(want) {
+			t.Fatalf("timeline=%v: sleeps=%v, want %v", timeline, sleeps, want)
+		}
+		for i := range want {
+			if sleeps[i] != want[i] {
+				t.Fatalf("timeline=%v: sleeps=%v, want %v", timeline, sleeps, want)
+			}
+		}
+	}
+}
@@ -11,15 +11,70 @@ import (
 	"github.com/aponysus/recourse/policy"
 )
 
-func (e *Executor) allowAttempt(ctx context.Context, key policy.PolicyKey, ref policy.BudgetRef, attemptIdx int, kind budget.AttemptKind) (decision budget.Decision, allowed bool) {
-	if e == nil {
-		return budget.Decision{Allowed: true, Reason: budget.ReasonNoBudget}, true
-	}
+// callBudget is a budget reference of a call, resolved once before its first attempt
+// so attempts need no registry lookup.
+type callBudget struct {
+	ref     policy.BudgetRef // Name trimmed.
+	budget  budget.Budget    // Nil if the reference names no budget or it is missing.
+	missing string           // Why the named budget is missing, if it is.
+	res     *reservation     // The call's up-front reservation (BudgetRef.Reserve), if any.
+}
 
+// resolveBudget looks up the budget ref names.
+func (e *Executor) resolveBudget(ref policy.BudgetRef) callBudget {
 	ref.Name = strings.TrimSpace(ref.Name)
-	if ref.Name == "" {
+	cb := callBudget{ref: ref}
+	if e == nil || ref.Name == "" {
+		return cb
+	}
+	if e.budgets == nil {
+		cb.missing = budget.ReasonBudgetRegistryNil
+	} else if b, ok := e.budgets.Get(ref.Name); !ok {
+		cb.missing = budget.ReasonBudgetNotFound
+	} else if internal.IsTypedNil(b) {
+		cb.missing = budget.ReasonBudgetNil
+	} else {
+		cb.budget = b
+	}
+	return cb
+}
+
+// allowAttempt gates one attempt on its budget and counts it in the executor's stats.
+func (e *Executor) allowAttempt(ctx context.Context, key policy.PolicyKey, ref policy.BudgetRef, attemptIdx int, kind budget.AttemptKind) (budget.Decision, bool) {
+	cb := e.resolveBudget(ref)
+	return e.allowCallAttempt(ctx, key, &cb, attemptIdx, kind)
+}
+
+// allowCallAttempt gates one attempt of a call on its resolved budget, taking it from
+// the call's reservation while that lasts, and counts it in the executor's stats.
+func (e *Executor) allowCallAttempt(ctx context.Context, key policy.PolicyKey, cb *callBudget, attemptIdx int, kind budget.AttemptKind) (budget.Decision, bool) {
+	var decision budget.Decision
+	var allowed bool
+	switch {
+	case !allowFanOutRetry(ctx, attemptIdx, kind):
+		decision = budget.Decision{Allowed: false, Reason: ReasonFanOutBudgetExhausted}
+	case cb.res.take():
+		decision, allowed = budget.Decision{Allowed: true, Reason: budget.ReasonReserved}, true
+	default:
+		decision, allowed = e.decideBudget(ctx, key, cb, attemptIdx, kind)
+	}
+	if e != nil {
+		e.stats.attempt(attemptIdx, kind, allowed)
+	}
+	return decision, allowed
+}
+
+func (e *Executor) checkBudget(ctx context.Context, key policy.PolicyKey, ref policy.BudgetRef, attemptIdx int, kind budget.AttemptKind) (budget.Decision, bool) {
+	cb := e.resolveBudget(ref)
+	return e.decideBudget(ctx, key, &cb, attemptIdx, kind)
+}
+
+// decideBudget asks cb's budget for one decision and reports it to the observer.
+func (e *Executor) decideBudget(ctx context.Context, key policy.PolicyKey, cb *callBudget, attemptIdx int, kind budget.AttemptKind) (decision budget.Decision, allowed bool) {
+	if e == nil || cb.ref.Name == "" {
 		return budget.Decision{Allowed: true, Reason: budget.ReasonNoBudget}, true
 	}
+	ref := cb.ref
 
 	// Prepare event (Mode and Outcome will be filled later)
 	event := observe.BudgetDecisionEvent{
@@ -41,23 +96,11 @@ func (e *Executor) allowAttempt(ctx context.Context, key policy.PolicyKey, ref p
 			if event.Mode == "" {
 				event.Mode = "standard"
 			}
-			e.observer.OnBudgetDecision(ctx, event)
+			e.observerFor(ctx).OnBudgetDecision(ctx, event)
 		}
 	}
 
-	var missingReason string
-	var b budget.Budget
-	var ok bool
-
-	if e.budgets == nil {
-		missingReason = budget.ReasonBudgetRegistryNil
-	} else if b, ok = e.budgets.Get(ref.Name); !ok {
-		missingReason = budget.ReasonBudgetNotFound
-	} else if internal.IsTypedNil(b) {
-		missingReason = budget.ReasonBudgetNil
-	}
-
-	if missingReason != "" {
+	if missingReason := cb.missing; missingReason != "" {
 		event.Mode = failureModeString(e.missingBudgetMode)
 		if e.missingBudgetMode == FailureAllow || e.missingBudgetMode == FailureAllowUnsafe {
 			// Allow unsafe
@@ -85,7 +128,7 @@ func (e *Executor) allowAttempt(ctx context.Context, key policy.PolicyKey, ref p
 		}()
 	}
 
-	decision = b.AllowAttempt(ctx, key, attemptIdx, kind, ref)
+	decision = cb.budget.AllowAttempt(ctx, key, attemptIdx, kind, ref)
 	if decision.Reason == "" {
 		if decision.Allowed {
 			decision.Reason = budget.ReasonAllowed
@@ -100,6 +143,9 @@ func (e *Executor) allowAttempt(ctx context.Context, key policy.PolicyKey, ref p
 		decision.Release = func() {
 			once.Do(originalRelease)
 		}
+		if e.leaks != nil && decision.Allowed {
+			decision.Release = e.trackRelease(key, ref.Name, kind, attemptIdx, decision.Release)
+		}
 	}
 
 	emit(decision, decision.Allowed)
@@ -72,7 +72,7 @@ func TestExecutor_BudgetDeniesSecondAttempt_StopsRetryAndReturnsLastErr(t *testi
 	if calls != 1 {
 		t.Fatalf("calls=%d, want 1", calls)
 	}
-	if err != opErr {
+	if !errors.Is(err, opErr) {
 		t.Fatalf("err=%v, want %v", err, opErr)
 	}
 	if tl.FinalErr != opErr {
@@ -260,3 +260,38 @@ func TestExecutor_AllowAttempt_ReleaseIsIdempotent(t *testing.T) {
 		t.Fatalf("releases=%d, want 1", releases)
 	}
 }
+
+func TestExecutor_PriorityShedsLowPriorityRetries(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", budget.NewTokenBucketBudget(5, 0).WithPriorityThresholds(budget.PriorityThresholds{Low: 0.5}))
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Budgets: budgets,
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{MaxAttempts: 2, Budget: policy.BudgetRef{Name: "b"}}},
+		}},
+	})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	fail := func(context.Context) error { return errors.New("down") }
+	lowCtx := budget.WithPriority(context.Background(), budget.PriorityLow)
+
+	// The first low-priority call spends 2 of 5 tokens. The second's first attempt leaves
+	// 2, and its retry would dip below the 2.5 reserved for higher priorities.
+	_ = exec.Do(lowCtx, key, fail)
+	err := exec.Do(lowCtx, key, fail)
+	var ce *CallError
+	if !errors.As(err, &ce) || ce.LastReason != budget.ReasonPriorityShed || !errors.Is(err, ErrBudgetDenied) {
+		t.Fatalf("err=%v, want a budget denial with reason %q", err, budget.ReasonPriorityShed)
+	}
+
+	// A normal-priority call still gets its retry from the reserve.
+	calls := 0
+	_ = exec.Do(context.Background(), key, func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if calls != 2 {
+		t.Fatalf("normal-priority calls=%d, want 2", calls)
+	}
+}
@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/hedge"
+	"github.com/aponysus/recourse/policy"
+)
+
+// callDeps holds what a call needs from the executor's registries, resolved once
+// before its first attempt rather than per attempt. Registry changes during the call
+// apply from the next call.
+type callDeps struct {
+	classifier classify.Classifier
+	cmeta      classifierMeta
+	retry      callBudget
+	hedge      callBudget
+	trigger    hedge.Trigger // Nil unless the policy hedges.
+}
+
+// resolveCallDeps resolves the call's budgets and hedge trigger into deps and takes the
+// budget reservations pol asks for (BudgetRef.Reserve): MaxAttempts retry attempts
+// and, with hedging, MaxHedges hedges for each of them. It reports the denying
+// decision if a budget cannot cover its reservation.
+func (e *Executor) resolveCallDeps(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy, deps *callDeps) (budget.Decision, bool) {
+	deps.retry = e.resolveBudget(pol.Retry.Budget)
+	hedging := pol.Hedge.Enabled && pol.Hedge.MaxHedges > 0
+	if hedging {
+		deps.hedge = e.resolveBudget(pol.Hedge.Budget)
+		deps.trigger = e.resolveTrigger(pol.Hedge)
+	}
+
+	attempts := int64(max(pol.Retry.MaxAttempts, 1))
+	if pol.Retry.Budget.Reserve {
+		if decision, ok := e.reserve(ctx, key, budget.KindRetry, &deps.retry, attempts); !ok {
+			return decision, false
+		}
+	}
+	if hedging && pol.Hedge.Budget.Reserve {
+		if decision, ok := e.reserve(ctx, key, budget.KindHedge, &deps.hedge, attempts*int64(pol.Hedge.MaxHedges)); !ok {
+			deps.finish(ctx)
+			return decision, false
+		}
+	}
+	return budget.Decision{}, true
+}
+
+// budgetFor returns the call's budget for attempts of kind.
+func (d *callDeps) budgetFor(kind budget.AttemptKind) *callBudget {
+	if kind == budget.KindHedge {
+		return &d.hedge
+	}
+	return &d.retry
+}
+
+// finish ends the call's budget reservations; see reservation.finish.
+func (d *callDeps) finish(ctx context.Context) {
+	d.retry.res.finish(ctx)
+	d.hedge.res.finish(ctx)
+}
+
+// resolveTrigger returns the hedge trigger hp names, falling back to a fixed delay when
+// it names none or one that is not registered.
+func (e *Executor) resolveTrigger(hp policy.HedgePolicy) hedge.Trigger {
+	if hp.TriggerName != "" && e.triggers != nil {
+		if trig, ok := e.triggers.Get(hp.TriggerName); ok {
+			return trig
+		}
+	}
+	return hedge.FixedDelayTrigger{Delay: hp.HedgeDelay}
+}
@@ -0,0 +1,63 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_BudgetResolvedOncePerCall(t *testing.T) {
+	for _, wantTimeline := range []bool{false, true} {
+		key := policy.ParseKey("svc.ResolveOnce")
+		budgets := budget.NewRegistry()
+		budgets.MustRegister("b", &budget.UnlimitedBudget{})
+		exec := NewExecutor(
+			WithPolicy(key.String(), policy.MaxAttempts(3), policy.Budget("b")),
+			WithBudgetRegistry(budgets),
+		)
+
+		// Replacing the budget mid-call applies from the next call.
+		calls := 0
+		_, _, err := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+			calls++
+			budgets.Unregister("b")
+			budgets.MustRegister("b", denySecondAttemptBudget{})
+			return 0, errors.New("unavailable")
+		}, wantTimeline)
+		if errors.Is(err, ErrBudgetDenied) || calls != 3 {
+			t.Fatalf("timeline=%v: calls=%d err=%v, want 3 attempts under the call's original budget", wantTimeline, calls, err)
+		}
+
+		calls = 0
+		_, _, err = doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+			calls++
+			return 0, errors.New("unavailable")
+		}, wantTimeline)
+		if !errors.Is(err, ErrBudgetDenied) || calls != 1 {
+			t.Fatalf("timeline=%v: calls=%d err=%v, want the next call denied its retry", wantTimeline, calls, err)
+		}
+	}
+}
+
+func TestExecutor_ClassifierFallbackAttributesShared(t *testing.T) {
+	key := policy.ParseKey("svc.FallbackShared")
+	exec := NewExecutor(WithPolicy(key.String(), policy.MaxAttempts(2), policy.Classifier("missing")))
+
+	_, tl, _ := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+		return 0, errors.New("unavailable")
+	}, true)
+	if len(tl.Attempts) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(tl.Attempts))
+	}
+	a, b := tl.Attempts[0].Outcome.Attributes, tl.Attempts[1].Outcome.Attributes
+	if a["classifier_fallback"] != "default" || a["classifier_name"] != "missing" {
+		t.Fatalf("attributes = %v, want the classifier fallback annotations", a)
+	}
+	if reflect.ValueOf(a).UnsafePointer() != reflect.ValueOf(b).UnsafePointer() {
+		t.Fatal("each attempt built its own fallback annotations, want them built once per call")
+	}
+}
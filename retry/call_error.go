@@ -0,0 +1,233 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/health"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// CallError wraps the final error of a failed call with a summary of the call.
+//
+// Error returns the wrapped error's message unchanged, and Unwrap exposes it, so
+// errors.Is and errors.As continue to match the underlying error (context errors,
+// CircuitOpenError, NoPolicyError, operation errors, ...). With WithErrorSummary,
+// Error appends the attempt history:
+//
+//	connection refused (after 3 attempts over 1.2s; reasons: timeout×2, http_503)
+type CallError struct {
+	Key        policy.PolicyKey  // Policy key for the call.
+	Attempts   int               // Attempts that ran the operation (including hedges).
+	Elapsed    time.Duration     // Total call duration (monotonic).
+	LastReason string            // Outcome reason of the last attempt (or rejection reason).
+	Timeline   *observe.Timeline // Call timeline, when one was recorded.
+	Err        error             // Underlying final error.
+
+	class   error  // Failure sentinel (ErrAttemptsExhausted, ErrBudgetDenied, ...), if any.
+	summary string // Attempt history appended by Error, with WithErrorSummary.
+}
+
+func (e *CallError) Error() string {
+	if e == nil || e.Err == nil {
+		return "recourse: call failed"
+	}
+	if e.summary != "" {
+		return e.Err.Error() + " (" + e.summary + ")"
+	}
+	return e.Err.Error()
+}
+
+func (e *CallError) Unwrap() error {
+	if e == nil {
+		return nil
+	}
+	return e.Err
+}
+
+// Is reports whether target is the failure sentinel for this call
+// (ErrAttemptsExhausted, ErrBudgetDenied, ErrOverallTimeout, or ErrPartialResult).
+// ErrCircuitOpen, ErrTargetUnhealthy, and ErrShed are matched by the wrapped
+// CircuitOpenError, TargetUnhealthyError, and ShedError.
+func (e *CallError) Is(target error) bool {
+	return e != nil && e.class != nil && target == e.class
+}
+
+// WithErrorSummary sets whether failed calls' errors append their attempt history to
+// the message, so a plain log line shows how the call failed without timeline export:
+//
+//	connection refused (after 3 attempts over 1.2s; reasons: timeout×2, http_503)
+//
+// Only CallError.Error changes; errors.Is, errors.As, and Unwrap behave as before.
+func WithErrorSummary(enabled bool) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.ErrorSummary = enabled
+	}
+}
+
+// callSummary carries the fields of a CallError that the fast path tracks without a timeline.
+type callSummary struct {
+	attempts   int
+	lastReason string
+	class      error
+	coolOff    bool // Whether a retry cool-off limited the call to one attempt.
+
+	trackReasons bool     // Whether to keep every attempt's reason (for WithErrorSummary).
+	reasons      []string // Attempt outcome reasons, in order, when tracked.
+	reasonsHint  int      // Capacity for reasons when first allocated; see attemptCapacity.
+}
+
+// addReason records an attempt's outcome reason.
+func (s *callSummary) addReason(reason string) {
+	s.lastReason = reason
+	if s.trackReasons && reason != "" {
+		if s.reasons == nil {
+			s.reasons = make([]string, 0, max(s.reasonsHint, 1))
+		}
+		s.reasons = append(s.reasons, reason)
+	}
+}
+
+// addTimeline fills attempt counts and reasons from a finished timeline.
+func (s *callSummary) addTimeline(tl *observe.Timeline) {
+	s.attempts = tl.DroppedAttempts
+	s.reasons = s.reasons[:0]
+	if s.trackReasons && cap(s.reasons) < len(tl.Attempts) {
+		s.reasons = make([]string, 0, len(tl.Attempts))
+	}
+	// The call ended on its last primary attempt; a hedge's budget denial, even one
+	// recorded after it, does not decide the call.
+	var primary *observe.AttemptRecord
+	for i, rec := range tl.Attempts {
+		if rec.BudgetAllowed {
+			s.attempts++
+		}
+		if rec.Outcome.Reason != "" {
+			s.lastReason = rec.Outcome.Reason
+			s.reasons = append(s.reasons, rec.Outcome.Reason)
+		}
+		if !rec.IsHedge && (primary == nil || rec.Attempt >= primary.Attempt) {
+			primary = &tl.Attempts[i]
+		}
+	}
+	if s.class == nil && primary != nil && !primary.BudgetAllowed {
+		s.class = ErrBudgetDenied
+	}
+}
+
+// overallTimeoutClass returns ErrOverallTimeout if ctx (derived from parent with the
+// policy's overall timeout) expired while the caller's context is still live.
+func overallTimeoutClass(parent, ctx context.Context) error {
+	if ctx.Err() == context.DeadlineExceeded && parent.Err() == nil {
+		return ErrOverallTimeout
+	}
+	return nil
+}
+
+// exhaustedClass classifies a call that ran out of attempts; the overall timeout takes
+// precedence when it cut the final attempt short.
+func exhaustedClass(parent, ctx context.Context) error {
+	if class := overallTimeoutClass(parent, ctx); class != nil {
+		return class
+	}
+	return ErrAttemptsExhausted
+}
+
+// newCallError wraps a failed call's error; summarize appends the attempt history to
+// its message.
+func newCallError(key policy.PolicyKey, err error, sum callSummary, elapsed time.Duration, tl *observe.Timeline, summarize bool) error {
+	if err == nil {
+		return nil
+	}
+	if sum.lastReason == "" {
+		var coe CircuitOpenError
+		if errors.As(err, &coe) {
+			sum.lastReason = coe.Reason
+		}
+	}
+	var tue *TargetUnhealthyError
+	if errors.As(err, &tue) {
+		sum.lastReason = health.ReasonTargetUnhealthy
+	}
+	var se *ShedError
+	if errors.As(err, &se) {
+		sum.lastReason = se.Reason
+	}
+	if sum.coolOff && sum.class == ErrAttemptsExhausted {
+		sum.lastReason = ReasonRetryCoolOff
+	}
+	ce := &CallError{
+		Key:        key,
+		Attempts:   sum.attempts,
+		Elapsed:    elapsed,
+		LastReason: sum.lastReason,
+		Timeline:   tl,
+		Err:        err,
+		class:      sum.class,
+	}
+	if summarize {
+		reasons := sum.reasons
+		if len(reasons) == 0 && sum.lastReason != "" {
+			reasons = []string{sum.lastReason}
+		}
+		ce.summary = summarizeCall(sum.attempts, elapsed, reasons)
+	}
+	return ce
+}
+
+// summarizeCall formats a call's attempt history, e.g.
+// "after 3 attempts over 1.2s; reasons: timeout×2, http_503". Repeated reasons are
+// counted in order of first appearance.
+func summarizeCall(attempts int, elapsed time.Duration, reasons []string) string {
+	var b strings.Builder
+	b.WriteString("after ")
+	b.WriteString(strconv.Itoa(attempts))
+	if attempts == 1 {
+		b.WriteString(" attempt over ")
+	} else {
+		b.WriteString(" attempts over ")
+	}
+	b.WriteString(roundElapsed(elapsed).String())
+	if len(reasons) == 0 {
+		return b.String()
+	}
+
+	var order []string
+	counts := make(map[string]int, len(reasons))
+	for _, r := range reasons {
+		if counts[r] == 0 {
+			order = append(order, r)
+		}
+		counts[r]++
+	}
+	b.WriteString("; reasons: ")
+	for i, r := range order {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(r)
+		if n := counts[r]; n > 1 {
+			b.WriteString("×")
+			b.WriteString(strconv.Itoa(n))
+		}
+	}
+	return b.String()
+}
+
+// roundElapsed keeps about two significant digits for readable messages.
+func roundElapsed(d time.Duration) time.Duration {
+	switch {
+	case d >= 10*time.Second:
+		return d.Round(time.Second)
+	case d >= time.Second:
+		return d.Round(100 * time.Millisecond)
+	case d >= 10*time.Millisecond:
+		return d.Round(time.Millisecond)
+	default:
+		return d.Round(10 * time.Microsecond)
+	}
+}
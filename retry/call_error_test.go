@@ -0,0 +1,277 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestCallError_FastPath(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "m"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:   key,
+		Retry: policy.RetryPolicy{MaxAttempts: 3},
+	})
+
+	opErr := errors.New("boom")
+	_, err := DoValue[int](context.Background(), exec, key, func(context.Context) (int, error) {
+		return 0, opErr
+	})
+
+	var ce *CallError
+	if !errors.As(err, &ce) {
+		t.Fatalf("err=%T, want *CallError", err)
+	}
+	if !errors.Is(err, opErr) {
+		t.Fatalf("errors.Is(err, opErr)=false")
+	}
+	if err.Error() != opErr.Error() {
+		t.Fatalf("Error()=%q, want %q", err.Error(), opErr.Error())
+	}
+	if ce.Key != key || ce.Attempts != 3 || ce.LastReason != "retryable_error" {
+		t.Fatalf("unexpected CallError: %+v", ce)
+	}
+	if ce.Elapsed <= 0 {
+		t.Fatalf("Elapsed=%v, want > 0", ce.Elapsed)
+	}
+	if ce.Timeline != nil {
+		t.Fatalf("expected no timeline on fast path")
+	}
+}
+
+func TestCallError_TimelinePath(t *testing.T) {
+	key := policy.PolicyKey{Name: "m"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:   key,
+		Retry: policy.RetryPolicy{MaxAttempts: 2},
+	})
+
+	ctx, capture := observe.RecordTimeline(context.Background())
+	_, err := DoValue[int](ctx, exec, key, func(context.Context) (int, error) {
+		return 0, context.DeadlineExceeded
+	})
+
+	var ce *CallError
+	if !errors.As(err, &ce) {
+		t.Fatalf("err=%T, want *CallError", err)
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded in chain")
+	}
+	if ce.Attempts != 2 || ce.LastReason != "context_deadline_exceeded" {
+		t.Fatalf("unexpected CallError: %+v", ce)
+	}
+	if ce.Timeline == nil || ce.Timeline != capture.Timeline() {
+		t.Fatalf("expected CallError.Timeline to be the captured timeline")
+	}
+	if ce.Timeline.FinalErr != context.DeadlineExceeded {
+		t.Fatalf("tl.FinalErr=%v, want unwrapped error", ce.Timeline.FinalErr)
+	}
+}
+
+func TestCallError_SuccessIsNil(t *testing.T) {
+	key := policy.PolicyKey{Name: "ok"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{Key: key})
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+}
+
+func TestCallError_Sentinels(t *testing.T) {
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("deny_second", denySecondAttemptBudget{})
+
+	tests := []struct {
+		name     string
+		pol      policy.EffectivePolicy
+		timeline bool
+		op       func(context.Context) (int, error)
+		want     error
+		notWant  []error
+	}{
+		{
+			name:    "exhausted",
+			pol:     policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 2}},
+			op:      func(context.Context) (int, error) { return 0, errors.New("nope") },
+			want:    ErrAttemptsExhausted,
+			notWant: []error{ErrBudgetDenied, ErrOverallTimeout, ErrCircuitOpen},
+		},
+		{
+			name:     "exhausted_timeline",
+			pol:      policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 2}},
+			timeline: true,
+			op:       func(context.Context) (int, error) { return 0, errors.New("nope") },
+			want:     ErrAttemptsExhausted,
+		},
+		{
+			name:    "non_retryable_is_not_exhausted",
+			pol:     policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 3}},
+			op:      func(context.Context) (int, error) { return 0, context.Canceled },
+			notWant: []error{ErrAttemptsExhausted, ErrOverallTimeout},
+		},
+		{
+			name: "budget_denied",
+			pol: policy.EffectivePolicy{Retry: policy.RetryPolicy{
+				MaxAttempts: 3,
+				Budget:      policy.BudgetRef{Name: "deny_second", Cost: 1},
+			}},
+			op:      func(context.Context) (int, error) { return 0, errors.New("nope") },
+			want:    ErrBudgetDenied,
+			notWant: []error{ErrAttemptsExhausted},
+		},
+		{
+			name: "budget_denied_timeline",
+			pol: policy.EffectivePolicy{Retry: policy.RetryPolicy{
+				MaxAttempts: 3,
+				Budget:      policy.BudgetRef{Name: "deny_second", Cost: 1},
+			}},
+			timeline: true,
+			op:       func(context.Context) (int, error) { return 0, errors.New("nope") },
+			want:     ErrBudgetDenied,
+		},
+		{
+			name: "overall_timeout",
+			pol: policy.EffectivePolicy{Retry: policy.RetryPolicy{
+				MaxAttempts:    3,
+				OverallTimeout: 20 * time.Millisecond,
+			}},
+			op: func(ctx context.Context) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+			want:    ErrOverallTimeout,
+			notWant: []error{ErrAttemptsExhausted},
+		},
+		{
+			name: "overall_timeout_timeline",
+			pol: policy.EffectivePolicy{Retry: policy.RetryPolicy{
+				MaxAttempts:    3,
+				OverallTimeout: 20 * time.Millisecond,
+			}},
+			timeline: true,
+			op: func(ctx context.Context) (int, error) {
+				<-ctx.Done()
+				return 0, ctx.Err()
+			},
+			want: ErrOverallTimeout,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := policy.PolicyKey{Name: tt.name}
+			tt.pol.Key = key
+			exec := NewExecutorFromOptions(ExecutorOptions{
+				Provider: &controlplane.StaticProvider{
+					Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: tt.pol},
+				},
+				Budgets: budgets,
+			})
+			exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+			ctx := context.Background()
+			if tt.timeline {
+				ctx, _ = observe.RecordTimeline(ctx)
+			}
+			_, err := DoValue[int](ctx, exec, key, tt.op)
+			if err == nil {
+				t.Fatal("expected error")
+			}
+			if tt.want != nil && !errors.Is(err, tt.want) {
+				t.Fatalf("errors.Is(%v, %v)=false", err, tt.want)
+			}
+			for _, nw := range tt.notWant {
+				if errors.Is(err, nw) {
+					t.Fatalf("errors.Is(%v, %v)=true, want false", err, nw)
+				}
+			}
+		})
+	}
+}
+
+func TestCallError_CircuitOpenSentinel(t *testing.T) {
+	key := policy.PolicyKey{Name: "circuit_sentinel"}
+	pol := policy.EffectivePolicy{
+		Key:     key,
+		Retry:   policy.RetryPolicy{MaxAttempts: 1},
+		Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: time.Minute},
+	}
+	exec := newTestExecutor(t, key, pol)
+
+	_ = exec.Do(context.Background(), key, func(context.Context) error { return errors.New("fail") })
+	err := exec.Do(context.Background(), key, func(context.Context) error { return nil })
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("errors.Is(%v, ErrCircuitOpen)=false", err)
+	}
+	var ce *CallError
+	if !errors.As(err, &ce) || ce.LastReason != circuit.ReasonCircuitOpen || ce.Attempts != 0 {
+		t.Fatalf("unexpected CallError: %+v", ce)
+	}
+}
+
+func TestCallSummary_BudgetDeniedFromPrimary(t *testing.T) {
+	primary := observe.AttemptRecord{Attempt: 0, BudgetAllowed: true}
+	deniedHedge := observe.AttemptRecord{Attempt: 0, IsHedge: true, HedgeIndex: 1}
+	deniedRetry := observe.AttemptRecord{Attempt: 1}
+
+	var sum callSummary
+	sum.addTimeline(&observe.Timeline{Attempts: []observe.AttemptRecord{primary, deniedHedge}})
+	if sum.class != nil {
+		t.Fatalf("class = %v with only a hedge denied, want none", sum.class)
+	}
+
+	sum = callSummary{}
+	sum.addTimeline(&observe.Timeline{Attempts: []observe.AttemptRecord{primary, deniedRetry, deniedHedge}})
+	if sum.class != ErrBudgetDenied {
+		t.Fatalf("class = %v with the retry denied, want ErrBudgetDenied", sum.class)
+	}
+}
+
+func TestCallError_Summary(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "m"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:   key,
+		Retry: policy.RetryPolicy{MaxAttempts: 3},
+	})
+	exec.errorSummary = true
+	summary := regexp.MustCompile(`^boom \(after 3 attempts over [0-9.]+[µnm]?s; reasons: retryable_error×3\)$`)
+
+	for name, ctx := range map[string]context.Context{
+		"fast":     context.Background(),
+		"timeline": func() context.Context { ctx, _ := observe.RecordTimeline(context.Background()); return ctx }(),
+	} {
+		opErr := errors.New("boom")
+		err := exec.Do(ctx, key, func(context.Context) error { return opErr })
+		if !summary.MatchString(err.Error()) {
+			t.Errorf("%s: Error()=%q, want a summary", name, err.Error())
+		}
+		if !errors.Is(err, opErr) || !errors.Is(err, ErrAttemptsExhausted) {
+			t.Errorf("%s: summary changed error matching: %v", name, err)
+		}
+	}
+}
+
+func TestSummarizeCall(t *testing.T) {
+	for _, tc := range []struct {
+		attempts int
+		elapsed  time.Duration
+		reasons  []string
+		want     string
+	}{
+		{3, 1234 * time.Millisecond, []string{"timeout", "http_503", "timeout"}, "after 3 attempts over 1.2s; reasons: timeout×2, http_503"},
+		{1, 3456 * time.Microsecond, []string{"http_400"}, "after 1 attempt over 3.46ms; reasons: http_400"},
+		{0, 42 * time.Millisecond, nil, "after 0 attempts over 42ms"},
+	} {
+		if got := summarizeCall(tc.attempts, tc.elapsed, tc.reasons); got != tc.want {
+			t.Errorf("summarizeCall(%d, %v, %v) = %q, want %q", tc.attempts, tc.elapsed, tc.reasons, got, tc.want)
+		}
+	}
+}
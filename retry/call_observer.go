@@ -0,0 +1,29 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/observe"
+)
+
+type callObserverKey struct{}
+
+// withCallObserver returns ctx carrying the call's observer, the executor's observer
+// combined with the one attached by observe.WithObserver, if ctx has one. It reports
+// whether it did.
+func (e *Executor) withCallObserver(ctx context.Context) (context.Context, bool) {
+	o, ok := observe.ObserverFromContext(ctx)
+	if !ok {
+		return ctx, false
+	}
+	var obs observe.Observer = observe.MultiObserver{Observers: []observe.Observer{e.observer, o}}
+	return context.WithValue(ctx, callObserverKey{}, obs), true
+}
+
+// observerFor returns the observer for events of the call running under ctx.
+func (e *Executor) observerFor(ctx context.Context) observe.Observer {
+	if o, ok := ctx.Value(callObserverKey{}).(observe.Observer); ok {
+		return o
+	}
+	return e.observer
+}
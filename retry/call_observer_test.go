@@ -0,0 +1,55 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_CallObserver(t *testing.T) {
+	key := policy.ParseKey("svc.CallObserver")
+	base := &testObserver{}
+	exec := NewExecutor(WithPolicy(key.String(), policy.MaxAttempts(2)), WithObserver(base))
+
+	call := &testObserver{}
+	ctx := observe.WithObserver(context.Background(), call)
+	n := 0
+	err := exec.Do(ctx, key, func(context.Context) error {
+		if n++; n == 1 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	for name, o := range map[string]*testObserver{"call": call, "executor": base} {
+		if o.starts != 1 || len(o.attempts) != 2 || o.successes != 1 {
+			t.Fatalf("%s observer: starts=%d attempts=%d successes=%d, want 1, 2, 1", name, o.starts, len(o.attempts), o.successes)
+		}
+	}
+
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if call.starts != 1 || base.starts != 2 {
+		t.Fatalf("starts = %d (call), %d (executor); want the call observer scoped to its context", call.starts, base.starts)
+	}
+}
+
+func TestExecutor_CallObserver_NoopExecutorObserver(t *testing.T) {
+	key := policy.ParseKey("svc.CallObserverFast")
+	exec := NewExecutor(WithPolicy(key.String(), policy.MaxAttempts(1)))
+
+	call := &testObserver{}
+	ctx := observe.WithObserver(context.Background(), call)
+	if err := exec.Do(ctx, key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if call.starts != 1 || len(call.attempts) != 1 || call.successes != 1 {
+		t.Fatalf("call observer: starts=%d attempts=%d successes=%d, want 1, 1, 1", call.starts, len(call.attempts), call.successes)
+	}
+}
@@ -0,0 +1,72 @@
+package retry
+
+import (
+	"context"
+	"runtime/debug"
+
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+// WithCircuitProbe registers a lightweight operation (e.g. a health check) that probes
+// key's half-open circuit breaker in place of a caller's request. While the breaker is
+// half-open, the call it admits runs probe once, under the policy's per-attempt timeout
+// and classifier: if the probe's outcome counts as a success, the breaker closes and
+// the call proceeds; otherwise the breaker reopens and the call fails with a
+// CircuitOpenError. Without a probe, the admitted call itself is the probe.
+func WithCircuitProbe(key policy.PolicyKey, probe Operation) ExecutorOption {
+	return func(c *executorConfig) {
+		if c.opts.CircuitProbes == nil {
+			c.opts.CircuitProbes = make(map[policy.PolicyKey]Operation)
+		}
+		c.opts.CircuitProbes[key] = probe
+	}
+}
+
+// probeSuccess reports whether a half-open probe's outcome closes the breaker: success,
+// and partial results when the policy sets Circuit.ProbeAcceptPartial.
+func probeSuccess(pol policy.CircuitPolicy, out classify.Outcome) bool {
+	return out.Kind == classify.OutcomeSuccess || (out.Kind == classify.OutcomePartial && pol.ProbeAcceptPartial)
+}
+
+// runCircuitProbe runs key's registered probe and records its verdict on cb. It reports
+// whether the breaker closed; a probe that ends without a verdict (it was canceled)
+// does not.
+func (e *Executor) runCircuitProbe(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy, cb circuit.CircuitBreaker, classifier classify.Classifier, probe Operation) bool {
+	if pol.Retry.TimeoutPerAttempt > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = e.timing.withTimeout(ctx, pol.Retry.TimeoutPerAttempt)
+		defer cancel()
+	}
+
+	err := func() (err error) {
+		if e.recoverPanics {
+			defer func() {
+				if r := recover(); r != nil {
+					err = &PanicError{Component: "circuit_probe", Key: key, Value: r, Stack: debug.Stack()}
+				}
+			}()
+		}
+		return probe(ctx)
+	}()
+
+	out, panicErr := classifyWithRecovery(e.recoverPanics, classifier, nil, err, key)
+	switch {
+	case panicErr == nil && probeSuccess(pol.Circuit, out):
+		cb.RecordSuccess(ctx)
+		return true
+	case panicErr == nil && out.Kind == classify.OutcomeAbort:
+		cancelProbe(ctx, cb)
+	default:
+		cb.RecordFailure(ctx)
+	}
+	return false
+}
+
+// cancelProbe frees cb's half-open probe slot, if it supports that.
+func cancelProbe(ctx context.Context, cb circuit.CircuitBreaker) {
+	if pc, ok := cb.(circuit.ProbeCanceler); ok {
+		pc.CancelProbe(ctx)
+	}
+}
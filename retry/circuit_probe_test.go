@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// halfOpenExecutor returns an executor whose breaker for key is half-open.
+func halfOpenExecutor(t *testing.T, key policy.PolicyKey, circ policy.CircuitPolicy, opts ExecutorOptions) (*Executor, circuit.CircuitBreaker) {
+	t.Helper()
+	circ.Enabled, circ.Threshold, circ.Cooldown = true, 1, time.Millisecond
+	pol := policy.EffectivePolicy{Key: key, Retry: policy.RetryPolicy{MaxAttempts: 1}, Circuit: circ}
+	opts.Provider = &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: pol}}
+	opts.Circuits = circuit.NewRegistry()
+	exec := NewExecutorFromOptions(opts)
+
+	_ = exec.Do(context.Background(), key, func(context.Context) error { return errors.New("down") })
+	cb := opts.Circuits.Get(key, pol.Circuit)
+	time.Sleep(5 * time.Millisecond)
+	if cb.State() != circuit.StateHalfOpen {
+		t.Fatalf("breaker state = %v, want half-open", cb.State())
+	}
+	return exec, cb
+}
+
+func TestCircuitProbe_Registered(t *testing.T) {
+	key := policy.PolicyKey{Name: "probed"}
+	healthy := false
+	exec, cb := halfOpenExecutor(t, key, policy.CircuitPolicy{}, ExecutorOptions{
+		CircuitProbes: map[policy.PolicyKey]Operation{key: func(context.Context) error {
+			if !healthy {
+				return errors.New("still down")
+			}
+			return nil
+		}},
+	})
+
+	calls := 0
+	op := func(context.Context) error { calls++; return nil }
+	err := exec.Do(context.Background(), key, op)
+	var coe CircuitOpenError
+	if !errors.As(err, &coe) || coe.Reason != circuit.ReasonCircuitProbeFailed || calls != 0 {
+		t.Fatalf("err=%v calls=%d, want a failed probe and no call", err, calls)
+	}
+	if cb.State() != circuit.StateOpen {
+		t.Fatalf("breaker state = %v, want open after the failed probe", cb.State())
+	}
+
+	healthy = true
+	time.Sleep(5 * time.Millisecond)
+	if err := exec.Do(context.Background(), key, op); err != nil || calls != 1 {
+		t.Fatalf("err=%v calls=%d, want the call to run after a passing probe", err, calls)
+	}
+	if cb.State() != circuit.StateClosed {
+		t.Fatalf("breaker state = %v, want closed", cb.State())
+	}
+}
+
+func TestCircuitProbe_CanceledProbeFreesSlot(t *testing.T) {
+	key := policy.PolicyKey{Name: "canceled"}
+	exec, cb := halfOpenExecutor(t, key, policy.CircuitPolicy{}, ExecutorOptions{})
+
+	err := exec.Do(context.Background(), key, func(context.Context) error { return context.Canceled })
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("err = %v, want context.Canceled", err)
+	}
+	if cb.State() != circuit.StateHalfOpen {
+		t.Fatalf("breaker state = %v, want half-open", cb.State())
+	}
+	// Without a verdict the probe slot is free, so the next call probes.
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("second probe: %v", err)
+	}
+	if cb.State() != circuit.StateClosed {
+		t.Fatalf("breaker state = %v, want closed", cb.State())
+	}
+}
+
+type alwaysPartial struct{}
+
+func (alwaysPartial) Classify(any, error) classify.Outcome {
+	return classify.Outcome{Kind: classify.OutcomePartial, Reason: "partial"}
+}
+
+func TestCircuitProbe_PartialOutcome(t *testing.T) {
+	partial := alwaysPartial{}
+	for _, accept := range []bool{false, true} {
+		key := policy.PolicyKey{Name: "partial"}
+		exec, cb := halfOpenExecutor(t, key, policy.CircuitPolicy{ProbeAcceptPartial: accept}, ExecutorOptions{DefaultClassifier: partial})
+		_ = exec.Do(context.Background(), key, func(context.Context) error { return nil })
+		want := circuit.StateOpen
+		if accept {
+			want = circuit.StateClosed
+		}
+		if got := cb.State(); got != want {
+			t.Errorf("ProbeAcceptPartial=%v: breaker state = %v, want %v", accept, got, want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// DefaultClockResolution is the CoarseClock resolution used when none is given.
+const DefaultClockResolution = time.Millisecond
+
+// CoarseClock is a clock that returns a cached timestamp, refreshed every resolution by
+// a background goroutine, instead of reading the system clock on each call. Executors
+// read their clock for the start and end of every attempt and call; in very hot paths
+// a coarse clock trades that accuracy for fewer time.Now calls.
+//
+// Timestamps lag real time by up to about twice the resolution and carry no monotonic
+// reading. Attempt and call durations are unaffected: executors measure them with the
+// monotonic clock regardless.
+//
+// The refresh goroutine starts on first use and exits after a resolution with no
+// reads, so an idle CoarseClock costs nothing and needs no Close.
+type CoarseClock struct {
+	resolution time.Duration
+
+	now     atomic.Int64 // Unix nanoseconds.
+	read    atomic.Bool  // Whether Now was called since the last refresh.
+	running atomic.Bool  // Whether the refresh goroutine is running.
+}
+
+// NewCoarseClock returns a CoarseClock refreshed every resolution. Resolutions <= 0 use
+// DefaultClockResolution.
+func NewCoarseClock(resolution time.Duration) *CoarseClock {
+	if resolution <= 0 {
+		resolution = DefaultClockResolution
+	}
+	return &CoarseClock{resolution: resolution}
+}
+
+// Resolution returns how often the clock is refreshed.
+func (c *CoarseClock) Resolution() time.Duration { return c.resolution }
+
+// Now returns the cached time.
+func (c *CoarseClock) Now() time.Time {
+	if !c.read.Load() {
+		c.read.Store(true)
+	}
+	if !c.running.Load() && c.running.CompareAndSwap(false, true) {
+		c.now.Store(time.Now().UnixNano())
+		go c.refresh()
+	}
+	return time.Unix(0, c.now.Load())
+}
+
+func (c *CoarseClock) refresh() {
+	ticker := time.NewTicker(c.resolution)
+	defer ticker.Stop()
+	for range ticker.C {
+		if !c.read.Swap(false) {
+			c.running.Store(false)
+			return
+		}
+		c.now.Store(time.Now().UnixNano())
+	}
+}
+
+// WithCoarseClock makes the executor timestamp attempts and calls with a CoarseClock of
+// the given resolution (DefaultClockResolution if <= 0) instead of time.Now. It replaces
+// any clock set with WithClock.
+func WithCoarseClock(resolution time.Duration) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Clock = NewCoarseClock(resolution).Now
+	}
+}
@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestCoarseClock_TracksTime(t *testing.T) {
+	c := NewCoarseClock(time.Millisecond)
+	if c.Resolution() != time.Millisecond {
+		t.Fatalf("Resolution()=%v, want 1ms", c.Resolution())
+	}
+
+	first := c.Now()
+	if d := time.Since(first); d < 0 || d > time.Second {
+		t.Fatalf("Now() is %v from time.Now", d)
+	}
+	deadline := time.Now().Add(time.Second)
+	for !c.Now().After(first) {
+		if time.Now().After(deadline) {
+			t.Fatalf("CoarseClock did not advance")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestCoarseClock_StopsWhenIdle(t *testing.T) {
+	c := NewCoarseClock(time.Millisecond)
+	c.Now()
+	if !c.running.Load() {
+		t.Fatalf("refresh goroutine not started")
+	}
+	deadline := time.Now().Add(time.Second)
+	for c.running.Load() {
+		if time.Now().After(deadline) {
+			t.Fatalf("refresh goroutine still running after the clock went idle")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// A read after going idle restarts it with a fresh timestamp.
+	time.Sleep(10 * time.Millisecond)
+	if d := time.Since(c.Now()); d > 5*time.Millisecond {
+		t.Fatalf("Now() after restart is %v stale", d)
+	}
+}
+
+func TestCoarseClock_DefaultResolution(t *testing.T) {
+	if got := NewCoarseClock(0).Resolution(); got != DefaultClockResolution {
+		t.Fatalf("Resolution()=%v, want %v", got, DefaultClockResolution)
+	}
+}
+
+func TestWithCoarseClock_TimestampsAttempts(t *testing.T) {
+	key := policy.ParseKey("svc.Coarse")
+	exec := NewExecutor(WithCoarseClock(time.Hour))
+
+	before := time.Now()
+	ctx, capture := observe.RecordTimeline(context.Background())
+	_, _ = DoValue(ctx, exec, key, func(context.Context) (int, error) {
+		time.Sleep(5 * time.Millisecond)
+		return 1, nil
+	})
+
+	tl := capture.Timeline()
+	if tl == nil || len(tl.Attempts) == 0 {
+		t.Fatalf("no timeline captured")
+	}
+	rec := tl.Attempts[0]
+	if rec.StartTime.Sub(before) > time.Millisecond || before.Sub(rec.StartTime) > time.Millisecond {
+		t.Fatalf("StartTime=%v, want the clock's first reading near %v", rec.StartTime, before)
+	}
+	if !rec.EndTime.Equal(rec.StartTime) {
+		t.Fatalf("EndTime=%v, want the cached StartTime %v with an hour resolution", rec.EndTime, rec.StartTime)
+	}
+}
@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// With returns a shallow clone of e with opts applied on top of the options e was
+// built from, so a subsystem can change the observer, limits, or classifier defaults
+// without duplicating shared state.
+//
+// Unless opts replace them, the clone shares e's policy provider, budget, circuit
+// breaker, and hedge trigger registries, and latency trackers. Classifiers are shared
+// too; WithClassifier registers into a copy, leaving e's registry unchanged. Policies
+// added with WithPolicy or WithPolicyKey take precedence over the provider for their
+// keys. An observer passed with WithObserver replaces e's. State kept per executor,
+// such as Stats, overrides, and coalescing, starts fresh.
+//
+// Per-key traffic state is shared, so e and its clones enforce one set of caps: the
+// limiters behind policy limits (policy.LimitsPolicy) always, and retry cool-off and
+// client throttle state unless opts set WithRetryCoolOff or WithClientThrottle.
+//
+// Clone executors when wiring subsystems, not per call: each clone is a new executor.
+// To observe a single call, see observe.WithObserver.
+func (e *Executor) With(opts ...ExecutorOption) *Executor {
+	cfg := &executorConfig{opts: e.opts, sharedClassifiers: true}
+	if cfg.opts.CircuitProbes != nil {
+		// WithCircuitProbe and WithNamespaceDefaults write into these maps; copy them so
+		// e's are left unchanged.
+		probes := make(map[policy.PolicyKey]Operation, len(cfg.opts.CircuitProbes))
+		for k, op := range cfg.opts.CircuitProbes {
+			probes[k] = op
+		}
+		cfg.opts.CircuitProbes = probes
+	}
+	if cfg.opts.NamespaceDefaults != nil {
+		defaults := make(map[string][]policy.Option, len(cfg.opts.NamespaceDefaults))
+		for ns, o := range cfg.opts.NamespaceDefaults {
+			defaults[ns] = o
+		}
+		cfg.opts.NamespaceDefaults = defaults
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if len(cfg.staticPolicies) > 0 {
+		cfg.opts.Provider = &overlayProvider{
+			static: &controlplane.StaticProvider{Policies: cfg.staticPolicies},
+			next:   cfg.opts.Provider,
+		}
+	}
+
+	clone := NewExecutorFromOptions(cfg.opts)
+	if cfg.opts.Runtime == e.opts.Runtime {
+		clone.trackers = e.trackers
+	}
+	clone.limits = e.limits
+	if cfg.opts.RetryCoolOff == e.opts.RetryCoolOff {
+		clone.coolOff = e.coolOff
+	}
+	if cfg.opts.ClientThrottle == e.opts.ClientThrottle {
+		clone.throttle = e.throttle
+	}
+	return clone
+}
+
+// overlayProvider serves static policies for its keys and defers to next for the rest.
+type overlayProvider struct {
+	static *controlplane.StaticProvider
+	next   controlplane.PolicyProvider
+}
+
+func (p *overlayProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	if _, ok := p.static.Policies[key]; ok || p.next == nil {
+		return p.static.GetEffectivePolicy(ctx, key)
+	}
+	return p.next.GetEffectivePolicy(ctx, key)
+}
+
+// KillSwitch reports whether the wrapped provider's kill switch is on.
+func (p *overlayProvider) KillSwitch() bool { return controlplane.KillSwitch(p.next) }
+
+// WatchResources forwards to the wrapped provider, if it is a ResourceProvider.
+func (p *overlayProvider) WatchResources(fn func(controlplane.Resources)) {
+	if rp, ok := p.next.(controlplane.ResourceProvider); ok {
+		rp.WatchResources(fn)
+	}
+}
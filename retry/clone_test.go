@@ -0,0 +1,102 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+func TestExecutor_With_SharesState(t *testing.T) {
+	keyA := policy.ParseKey("svc.A")
+	keyB := policy.ParseKey("svc.B")
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", &budget.UnlimitedBudget{})
+	parentObs := &testObserver{}
+	parent := NewExecutor(
+		WithPolicy(keyA.String(), policy.MaxAttempts(3)),
+		WithBudgetRegistry(budgets),
+		WithObserver(parentObs),
+	)
+
+	cloneObs := &testObserver{}
+	clone := parent.With(
+		WithObserver(cloneObs),
+		WithPolicy(keyB.String(), policy.MaxAttempts(1)),
+		WithClassifier("custom", classify.AlwaysRetryOnError{}),
+	)
+
+	if clone.Budgets() != parent.Budgets() || clone.Circuits() != parent.Circuits() {
+		t.Fatal("clone does not share the parent's budget and circuit registries")
+	}
+	if clone.trackers != parent.trackers {
+		t.Fatal("clone does not share the parent's latency trackers")
+	}
+	if _, ok := parent.classifiers.Get("custom"); ok {
+		t.Fatal("WithClassifier on the clone registered into the parent's registry")
+	}
+	if _, ok := clone.classifiers.Get("custom"); !ok {
+		t.Fatal("clone is missing the classifier registered with WithClassifier")
+	}
+	if _, ok := clone.classifiers.Get(classify.ClassifierHTTP); !ok {
+		t.Fatal("clone is missing the parent's classifiers")
+	}
+
+	ctx := context.Background()
+	for _, tc := range []struct {
+		exec *Executor
+		key  policy.PolicyKey
+		want int
+	}{
+		{clone, keyA, 3},
+		{clone, keyB, 1},
+		{parent, keyB, policy.DefaultPolicyFor(keyB).Retry.MaxAttempts},
+	} {
+		pol, err := tc.exec.EffectivePolicy(ctx, tc.key)
+		if err != nil {
+			t.Fatalf("EffectivePolicy(%s): %v", tc.key, err)
+		}
+		if pol.Retry.MaxAttempts != tc.want {
+			t.Fatalf("EffectivePolicy(%s).MaxAttempts = %d, want %d", tc.key, pol.Retry.MaxAttempts, tc.want)
+		}
+	}
+
+	if err := clone.Do(ctx, keyA, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if cloneObs.starts != 1 || parentObs.starts != 0 {
+		t.Fatalf("starts = %d (clone), %d (parent); want the clone's observer to replace the parent's", cloneObs.starts, parentObs.starts)
+	}
+}
+
+func TestExecutor_With_SharesLimits(t *testing.T) {
+	key := policy.ParseKey("svc.Limited")
+	parent := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.MaxInFlight(1)),
+		WithRetryCoolOff(RetryCoolOff{}),
+	)
+	clone := parent.With(WithObserver(&testObserver{}))
+	if clone.coolOff != parent.coolOff || clone.throttle != parent.throttle {
+		t.Fatal("clone does not share the parent's cool-off and client throttle state")
+	}
+	if parent.With(WithClientThrottle(ClientThrottle{})).throttle == parent.throttle {
+		t.Fatal("clone with its own WithClientThrottle shares the parent's throttle state")
+	}
+
+	err := parent.Do(context.Background(), key, func(ctx context.Context) error {
+		ran := false
+		err := clone.Do(ctx, key, func(context.Context) error { ran = true; return nil })
+		var se *ShedError
+		if ran || !errors.As(err, &se) || se.Reason != shed.ReasonInFlight {
+			t.Errorf("clone call while parent holds the slot: ran=%v err=%v, want ShedError %s", ran, err, shed.ReasonInFlight)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+}
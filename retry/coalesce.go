@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"context"
+	"reflect"
+	"sync"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// CoalescedAttribute is the timeline attribute set to "true" on the timelines of
+// calls that shared one attempt chain.
+const CoalescedAttribute = "coalesced"
+
+// WithCoalescing deduplicates identical in-flight calls, like singleflight.
+//
+// keyFunc returns a request key for each call, typically read from a context value
+// the caller set. Concurrent calls with the same policy key, request key, and result
+// type share one attempt chain: the first call runs it and the others wait for its
+// result. An empty request key opts a call out.
+//
+// Every caller receives the same value and error, so results must be safe to share.
+// The timelines captured by callers of a shared call carry CoalescedAttribute.
+// Observers see the shared call once.
+//
+// The shared call keeps running while any caller waits for it. A caller whose context
+// ends returns its context error; the call is cancelled when the last caller leaves.
+func WithCoalescing(keyFunc func(ctx context.Context) string) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Coalesce = keyFunc
+	}
+}
+
+type coalescer struct {
+	keyFunc func(ctx context.Context) string
+
+	mu      sync.Mutex
+	flights map[coalesceKey]*flight
+}
+
+type coalesceKey struct {
+	key    policy.PolicyKey
+	req    string
+	result reflect.Type
+}
+
+// flight is one shared call.
+type flight struct {
+	done   chan struct{}
+	cancel context.CancelFunc
+
+	// Guarded by coalescer.mu.
+	callers int
+	shared  bool
+
+	// Set before done is closed.
+	val      any
+	tl       observe.Timeline
+	err      error
+	panicked bool
+	panicVal any
+}
+
+func newCoalescer(keyFunc func(ctx context.Context) string) *coalescer {
+	return &coalescer{keyFunc: keyFunc, flights: make(map[coalesceKey]*flight)}
+}
+
+func coalesceValue[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, reqKey string, op OperationValue[T]) (T, observe.Timeline, error) {
+	c := exec.coalescer
+	ck := coalesceKey{key: key, req: reqKey, result: reflect.TypeFor[T]()}
+
+	c.mu.Lock()
+	f, ok := c.flights[ck]
+	if ok {
+		f.shared = true
+	} else {
+		// The shared call outlives the caller that started it, so it gets the caller's
+		// values but not its cancellation, and captures go to each caller below.
+		shared, cancel := context.WithCancel(observe.WithoutTimelineCapture(context.WithoutCancel(ctx)))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		c.flights[ck] = f
+		go c.run(ck, f, func() (any, observe.Timeline, error) {
+			return doValueCall(shared, exec, key, op, true)
+		})
+	}
+	f.callers++
+	c.mu.Unlock()
+
+	select {
+	case <-f.done:
+	case <-ctx.Done():
+		c.mu.Lock()
+		f.callers--
+		if f.callers == 0 {
+			if c.flights[ck] == f {
+				delete(c.flights, ck)
+			}
+			f.cancel()
+		}
+		c.mu.Unlock()
+		var zero T
+		return zero, observe.Timeline{}, ctx.Err()
+	}
+
+	if f.panicked {
+		panic(f.panicVal)
+	}
+
+	// No caller can join once done is closed, so shared is final.
+	c.mu.Lock()
+	shared := f.shared
+	c.mu.Unlock()
+
+	tl := f.tl
+	if shared {
+		attrs := make(map[string]string, len(tl.Attributes)+1)
+		for k, v := range tl.Attributes {
+			attrs[k] = v
+		}
+		attrs[CoalescedAttribute] = "true"
+		tl.Attributes = attrs
+	}
+	if capture, ok := observe.TimelineCaptureFromContext(ctx); ok {
+		observe.StoreTimelineCapture(capture, &tl)
+	}
+
+	val, _ := f.val.(T)
+	return val, tl, f.err
+}
+
+// run executes the shared call and publishes its result. A panic raised on this
+// goroutine (e.g. by an observer) is re-raised in every waiting caller.
+func (c *coalescer) run(ck coalesceKey, f *flight, call func() (any, observe.Timeline, error)) {
+	defer func() {
+		if r := recover(); r != nil {
+			f.panicked, f.panicVal = true, r
+		}
+		c.mu.Lock()
+		if c.flights[ck] == f {
+			delete(c.flights, ck)
+		}
+		c.mu.Unlock()
+		f.cancel()
+		close(f.done)
+	}()
+	f.val, f.tl, f.err = call()
+}
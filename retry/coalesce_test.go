@@ -0,0 +1,151 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type reqKeyCtx struct{}
+
+func withReqKey(ctx context.Context, k string) context.Context {
+	return context.WithValue(ctx, reqKeyCtx{}, k)
+}
+
+func reqKeyFunc(ctx context.Context) string {
+	k, _ := ctx.Value(reqKeyCtx{}).(string)
+	return k
+}
+
+func newCoalescingExecutor(key policy.PolicyKey) *Executor {
+	return NewExecutor(
+		WithCoalescing(reqKeyFunc),
+		WithPolicyKey(key, policy.MaxAttempts(3), policy.Backoff(time.Microsecond, time.Microsecond, 1)),
+	)
+}
+
+func TestCoalescing_SharesOneAttemptChain(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	exec := newCoalescingExecutor(key)
+
+	var calls atomic.Int32
+	release := make(chan struct{})
+	op := func(ctx context.Context) (string, error) {
+		if calls.Add(1) == 1 {
+			<-release
+			return "", errors.New("transient")
+		}
+		return "value", nil
+	}
+
+	const n = 5
+	var (
+		wg    sync.WaitGroup
+		vals  [n]string
+		errs  [n]error
+		caps  [n]*observe.TimelineCapture
+		ready sync.WaitGroup
+	)
+	ready.Add(n)
+	for i := 0; i < n; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, capture := observe.RecordTimeline(withReqKey(context.Background(), "user-1"))
+			caps[i] = capture
+			ready.Done()
+			vals[i], errs[i] = DoValue(ctx, exec, key, op)
+		}(i)
+	}
+	ready.Wait()
+	// Let every caller join before the first attempt fails.
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		exec.coalescer.mu.Lock()
+		joined := 0
+		for _, f := range exec.coalescer.flights {
+			joined = f.callers
+		}
+		exec.coalescer.mu.Unlock()
+		if joined == n {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if got := calls.Load(); got != 2 {
+		t.Fatalf("op calls = %d, want 2 (one shared chain)", got)
+	}
+	for i := 0; i < n; i++ {
+		if errs[i] != nil || vals[i] != "value" {
+			t.Fatalf("caller %d got %q, %v", i, vals[i], errs[i])
+		}
+		tl := caps[i].Timeline()
+		if tl == nil || len(tl.Attempts) != 2 || tl.Attributes[CoalescedAttribute] != "true" {
+			t.Fatalf("caller %d timeline = %+v", i, tl)
+		}
+	}
+	if len(exec.coalescer.flights) != 0 {
+		t.Fatalf("flights left behind: %d", len(exec.coalescer.flights))
+	}
+}
+
+func TestCoalescing_DistinctKeysRunSeparately(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	exec := newCoalescingExecutor(key)
+
+	var calls atomic.Int32
+	op := func(ctx context.Context) error {
+		calls.Add(1)
+		return nil
+	}
+	for _, k := range []string{"a", "b", ""} {
+		ctx, capture := observe.RecordTimeline(withReqKey(context.Background(), k))
+		if err := exec.Do(ctx, key, op); err != nil {
+			t.Fatalf("Do(%q): %v", k, err)
+		}
+		if tl := capture.Timeline(); tl == nil || tl.Attributes[CoalescedAttribute] != "" {
+			t.Fatalf("uncontended call %q marked coalesced: %+v", k, tl)
+		}
+	}
+	if got := calls.Load(); got != 3 {
+		t.Fatalf("op calls = %d, want 3", got)
+	}
+}
+
+func TestCoalescing_CallerCancellation(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	exec := newCoalescingExecutor(key)
+
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	op := func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(stopped)
+		return ctx.Err()
+	}
+
+	ctx, cancel := context.WithCancel(withReqKey(context.Background(), "k"))
+	errc := make(chan error, 1)
+	go func() { errc <- exec.Do(ctx, key, op) }()
+	<-started
+	cancel()
+
+	if err := <-errc; !errors.Is(err, context.Canceled) {
+		t.Fatalf("Do = %v, want context.Canceled", err)
+	}
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("shared call not cancelled after its last caller left")
+	}
+}
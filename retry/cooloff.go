@@ -0,0 +1,148 @@
+package retry
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for RetryCoolOff fields left zero.
+const (
+	DefaultCoolOffThreshold = 5
+	DefaultCoolOffCooldown  = 30 * time.Second
+)
+
+// ReasonRetryCoolOff is the CallError.LastReason of a call that failed its single
+// attempt during a retry cool-off, when the policy would otherwise have retried it.
+const ReasonRetryCoolOff = "retry_cooloff"
+
+// RetryCoolOff configures per-key retry cool-off (see WithRetryCoolOff).
+//
+// Once Threshold consecutive calls for a key exhaust all their attempts, the key's calls
+// make a single attempt for Cooldown, since retrying them only adds load. Successful
+// calls reset the count and end a cool-off early; other failures leave the count
+// unchanged, and calls made during a cool-off do not count.
+type RetryCoolOff struct {
+	Threshold int           // Consecutive exhausted calls that start a cool-off; default DefaultCoolOffThreshold.
+	Cooldown  time.Duration // Cool-off duration; default DefaultCoolOffCooldown.
+}
+
+func (c RetryCoolOff) normalize() RetryCoolOff {
+	if c.Threshold <= 0 {
+		c.Threshold = DefaultCoolOffThreshold
+	}
+	if c.Cooldown <= 0 {
+		c.Cooldown = DefaultCoolOffCooldown
+	}
+	return c
+}
+
+// WithRetryCoolOff enables retry cool-off, a lighter alternative to circuit breaking for
+// keys whose policy does not enable it: cooled-off keys still make one attempt per call
+// instead of failing fast. Keys whose policy enables circuit breaking are left to their
+// circuit breaker. Calls during a cool-off record the timeline attribute
+// "retry_cooloff" with the time the cool-off ends, and those that fail report
+// ReasonRetryCoolOff as their CallError.LastReason.
+func WithRetryCoolOff(cfg RetryCoolOff) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.RetryCoolOff = &cfg
+	}
+}
+
+// coolOff holds the per-key cool-off state. Keys are dropped once they have no streak
+// and no cool-off left: on success, and when a cool-off is found expired.
+type coolOff struct {
+	cfg  RetryCoolOff
+	keys sync.Map // policy.PolicyKey -> *coolOffKey
+}
+
+type coolOffKey struct {
+	streak atomic.Int64 // Consecutive exhausted calls.
+	until  atomic.Int64 // Unix nanoseconds the current cool-off ends.
+}
+
+func newCoolOff(cfg RetryCoolOff) *coolOff {
+	return &coolOff{cfg: cfg.normalize()}
+}
+
+// record counts a finished call for key.
+func (c *coolOff) record(key policy.PolicyKey, err error, exhausted bool, now time.Time) {
+	if err == nil {
+		c.keys.Delete(key)
+		return
+	}
+	if !exhausted {
+		return
+	}
+	v, ok := c.keys.Load(key)
+	if !ok {
+		v, _ = c.keys.LoadOrStore(key, &coolOffKey{})
+	}
+	k := v.(*coolOffKey)
+	if now.UnixNano() < k.until.Load() {
+		return
+	}
+	if k.streak.Add(1) >= int64(c.cfg.Threshold) {
+		k.streak.Store(0)
+		k.until.Store(now.Add(c.cfg.Cooldown).UnixNano())
+		c.sweep(now)
+	}
+}
+
+// sweep drops the keys whose cool-off ended with no exhausted calls since. It runs
+// when a cool-off starts, so keys that are never called again do not stay forever.
+func (c *coolOff) sweep(now time.Time) {
+	c.keys.Range(func(key, v any) bool {
+		if k := v.(*coolOffKey); k.streak.Load() == 0 && now.UnixNano() >= k.until.Load() {
+			c.keys.CompareAndDelete(key, v)
+		}
+		return true
+	})
+}
+
+// active reports whether key is cooling off at now, and until when.
+func (c *coolOff) active(key policy.PolicyKey, now time.Time) (time.Time, bool) {
+	v, ok := c.keys.Load(key)
+	if !ok {
+		return time.Time{}, false
+	}
+	k := v.(*coolOffKey)
+	until := k.until.Load()
+	if now.UnixNano() >= until {
+		if k.streak.Load() == 0 {
+			c.keys.CompareAndDelete(key, v)
+		}
+		return time.Time{}, false
+	}
+	return time.Unix(0, until), true
+}
+
+// applyCoolOff makes pol's calls single-attempt while key is cooling off, noting it in
+// sum when it is non-nil.
+func (e *Executor) applyCoolOff(key policy.PolicyKey, pol policy.EffectivePolicy, attrs map[string]string, sum *callSummary) policy.EffectivePolicy {
+	c := e.coolOff
+	if c == nil || pol.Circuit.Enabled || pol.Retry.MaxAttempts <= 1 {
+		return pol
+	}
+	until, ok := c.active(key, e.clock())
+	if !ok {
+		return pol
+	}
+	pol.Retry.MaxAttempts = 1
+	if sum != nil {
+		sum.coolOff = true
+	}
+	if attrs != nil {
+		attrs["retry_cooloff"] = until.UTC().Format(time.RFC3339Nano)
+	}
+	return pol
+}
+
+// recordCoolOff counts a finished call toward key's cool-off.
+func (e *Executor) recordCoolOff(key policy.PolicyKey, err error, sum callSummary) {
+	if e.coolOff != nil {
+		e.coolOff.record(key, err, sum.class == ErrAttemptsExhausted, e.clock())
+	}
+}
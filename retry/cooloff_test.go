@@ -0,0 +1,124 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestRetryCoolOff(t *testing.T) {
+	key := policy.ParseKey("svc.CoolOff")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(3)),
+		WithRetryCoolOff(RetryCoolOff{Threshold: 2, Cooldown: time.Minute}),
+	)
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+	now := time.Unix(1000, 0)
+	exec.clock = func() time.Time { return now }
+
+	fail := true
+	call := func() (attempts int, cooloff string) {
+		t.Helper()
+		_, tl, _ := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+			attempts++
+			if fail {
+				return 0, errors.New("fail")
+			}
+			return 1, nil
+		}, true)
+		return attempts, tl.Attributes["retry_cooloff"]
+	}
+
+	for i := 0; i < 2; i++ {
+		if n, _ := call(); n != 3 {
+			t.Fatalf("call %d made %d attempts before the cool-off, want 3", i, n)
+		}
+	}
+	n, cooloff := call()
+	if n != 1 || cooloff != now.Add(time.Minute).UTC().Format(time.RFC3339Nano) {
+		t.Fatalf("cooled-off call made %d attempts with retry_cooloff=%q, want 1 until the cooldown ends", n, cooloff)
+	}
+	var ce *CallError
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return errors.New("fail") }); !errors.As(err, &ce) || ce.LastReason != ReasonRetryCoolOff {
+		t.Fatalf("cooled-off Do = %v, want LastReason %q", err, ReasonRetryCoolOff)
+	}
+	pol, err := exec.EffectivePolicy(context.Background(), key)
+	if err != nil || pol.Retry.MaxAttempts != 1 {
+		t.Fatalf("EffectivePolicy = %d attempts, %v; want the cool-off applied", pol.Retry.MaxAttempts, err)
+	}
+
+	now = now.Add(time.Minute)
+	if n, cooloff := call(); n != 3 || cooloff != "" {
+		t.Fatalf("call after the cooldown made %d attempts with retry_cooloff=%q, want 3", n, cooloff)
+	}
+
+	// A success ends the cool-off early.
+	call()
+	if n, _ := call(); n != 1 {
+		t.Fatalf("call made %d attempts, want a new cool-off", n)
+	}
+	fail = false
+	call()
+	if n, _ := call(); n != 1 {
+		t.Fatalf("succeeding call made %d attempts, want 1", n)
+	}
+	fail = true
+	if n, _ := call(); n != 3 {
+		t.Fatalf("call after a success made %d attempts, want the cool-off ended", n)
+	}
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return errors.New("fail") }); !errors.As(err, &ce) || ce.LastReason == ReasonRetryCoolOff {
+		t.Fatalf("Do outside a cool-off = %v, want the attempt's reason", err)
+	}
+}
+
+func TestRetryCoolOff_EvictsIdleKeys(t *testing.T) {
+	c := newCoolOff(RetryCoolOff{Threshold: 1, Cooldown: time.Minute})
+	now := time.Unix(1000, 0)
+	fail := errors.New("fail")
+	size := func() (n int) {
+		c.keys.Range(func(any, any) bool { n++; return true })
+		return n
+	}
+
+	c.record(policy.ParseKey("svc.A"), fail, true, now)
+	c.record(policy.ParseKey("svc.B"), fail, true, now)
+	c.record(policy.ParseKey("svc.B"), nil, false, now)
+	if n := size(); n != 1 {
+		t.Fatalf("keys = %d after a success, want 1", n)
+	}
+
+	// svc.A's cool-off has expired by the time svc.C starts one.
+	now = now.Add(time.Minute)
+	c.record(policy.ParseKey("svc.C"), fail, true, now)
+	if _, ok := c.keys.Load(policy.ParseKey("svc.A")); ok || size() != 1 {
+		t.Fatalf("keys = %d, want the expired cool-off dropped", size())
+	}
+	if _, ok := c.active(policy.ParseKey("svc.C"), now.Add(time.Minute)); ok || size() != 0 {
+		t.Fatalf("keys = %d, want the cool-off dropped once found expired", size())
+	}
+}
+
+func TestRetryCoolOff_LeavesCircuitKeysAlone(t *testing.T) {
+	key := policy.ParseKey("svc.CoolOffCircuit")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(2), func(p *policy.EffectivePolicy) {
+			p.Circuit = policy.CircuitPolicy{Enabled: true, Threshold: 100, Cooldown: time.Minute}
+		}),
+		WithRetryCoolOff(RetryCoolOff{Threshold: 1}),
+	)
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	for i := 0; i < 3; i++ {
+		attempts := 0
+		_ = exec.Do(context.Background(), key, func(context.Context) error {
+			attempts++
+			return errors.New("fail")
+		})
+		if attempts != 2 {
+			t.Fatalf("call %d made %d attempts, want 2", i, attempts)
+		}
+	}
+}
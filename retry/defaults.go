@@ -27,28 +27,42 @@ func NewDefaultExecutor(opts ...DefaultOption) *Executor {
 	}
 
 	// 2. Registries (constructed fresh to avoid shared mutable state defaults)
-
-	// Classifiers
-	classifierReg := classify.NewRegistry()
-	classify.RegisterBuiltins(classifierReg)
-	defaultOpts = append(defaultOpts, WithClassifiers(classifierReg))
-	defaultOpts = append(defaultOpts, WithDefaultClassifier(classify.AutoClassifier{}))
-
-	// Budgets
-	budgetReg := budget.NewRegistry()
-	budgetReg.MustRegister("unlimited", &budget.UnlimitedBudget{})
-	defaultOpts = append(defaultOpts, WithBudgetRegistry(budgetReg))
-
-	// Triggers
-	triggerReg := hedge.NewRegistry()
-	triggerReg.Register("fixed_delay", &hedge.FixedDelayTrigger{}) // Delay comes from policy
-	triggerReg.Register("p90", &hedge.LatencyTrigger{Percentile: "p90"})
-	triggerReg.Register("p95", &hedge.LatencyTrigger{Percentile: "p95"})
-	triggerReg.Register("p99", &hedge.LatencyTrigger{Percentile: "p99"})
-	defaultOpts = append(defaultOpts, WithHedgeTriggerRegistry(triggerReg))
+	defaultOpts = append(defaultOpts,
+		WithClassifiers(DefaultClassifiers()),
+		WithDefaultClassifier(classify.AutoClassifier{}),
+		WithBudgetRegistry(DefaultBudgets()),
+		WithHedgeTriggerRegistry(DefaultHedgeTriggers()),
+	)
 
 	// 3. User overrides
 	defaultOpts = append(defaultOpts, opts...)
 
 	return NewExecutor(defaultOpts...)
 }
+
+// DefaultClassifiers returns a new classifier registry with the built-in classifiers
+// (Generic, HTTP) registered, as used by NewDefaultExecutor.
+func DefaultClassifiers() *classify.Registry {
+	r := classify.NewRegistry()
+	classify.RegisterBuiltins(r)
+	return r
+}
+
+// DefaultBudgets returns a new budget registry with the "unlimited" budget registered,
+// as used by NewDefaultExecutor.
+func DefaultBudgets() *budget.Registry {
+	r := budget.NewRegistry()
+	r.MustRegister("unlimited", &budget.UnlimitedBudget{})
+	return r
+}
+
+// DefaultHedgeTriggers returns a new hedge trigger registry with the "fixed_delay",
+// "p90", "p95", and "p99" triggers registered, as used by NewDefaultExecutor.
+func DefaultHedgeTriggers() *hedge.Registry {
+	r := hedge.NewRegistry()
+	r.Register("fixed_delay", &hedge.FixedDelayTrigger{}) // Delay comes from policy
+	r.Register("p90", &hedge.LatencyTrigger{Percentile: "p90"})
+	r.Register("p95", &hedge.LatencyTrigger{Percentile: "p95"})
+	r.Register("p99", &hedge.LatencyTrigger{Percentile: "p99"})
+	return r
+}
@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Do runs error-only operations through the same machinery as DoValue.
+
+func TestDo_HedgesAndCapturesTimeline(t *testing.T) {
+	key := policy.ParseKey("do.hedge")
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:   key,
+		Retry: policy.RetryPolicy{MaxAttempts: 1},
+		Hedge: policy.HedgePolicy{Enabled: true, MaxHedges: 1, HedgeDelay: time.Millisecond},
+	})
+
+	ctx, capture := observe.RecordTimeline(context.Background())
+	var calls atomic.Int32
+	err := exec.Do(ctx, key, func(ctx context.Context) error {
+		if calls.Add(1) == 1 {
+			<-ctx.Done() // The primary stalls until the hedge wins.
+			return ctx.Err()
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do = %v, want the hedge's success", err)
+	}
+	tl := capture.Timeline()
+	if tl == nil {
+		t.Fatal("no timeline captured")
+	}
+	hedged := false
+	for _, a := range tl.Attempts {
+		hedged = hedged || (a.IsHedge && a.Outcome.Reason == "success")
+	}
+	if !hedged {
+		t.Fatalf("attempts=%+v, want a winning hedge", tl.Attempts)
+	}
+}
+
+func TestDo_EnforcesBudget(t *testing.T) {
+	key := policy.ParseKey("do.budget")
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", denySecondAttemptBudget{})
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Budgets: budgets,
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{MaxAttempts: 3, Budget: policy.BudgetRef{Name: "b", Cost: 1}}},
+		}},
+	})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	calls := 0
+	err := exec.Do(context.Background(), key, func(context.Context) error {
+		calls++
+		return errors.New("fail")
+	})
+	if calls != 1 || !errors.Is(err, ErrBudgetDenied) {
+		t.Fatalf("Do = %v after %d calls, want budget denial after 1", err, calls)
+	}
+}
+
+func TestDo_OpensCircuit(t *testing.T) {
+	key := policy.ParseKey("do.circuit")
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:     key,
+		Retry:   policy.RetryPolicy{MaxAttempts: 1},
+		Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: time.Minute},
+	})
+
+	calls := 0
+	op := func(context.Context) error {
+		calls++
+		return errors.New("fail")
+	}
+	_ = exec.Do(context.Background(), key, op)
+	err := exec.Do(context.Background(), key, op)
+	var open CircuitOpenError
+	if calls != 1 || !errors.As(err, &open) || open.Reason != circuit.ReasonCircuitOpen {
+		t.Fatalf("Do = %v after %d calls, want an open circuit after 1", err, calls)
+	}
+}
@@ -8,21 +8,42 @@ import (
 	"runtime/debug"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/aponysus/recourse/budget"
 	"github.com/aponysus/recourse/circuit"
 	"github.com/aponysus/recourse/classify"
 	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/health"
 	"github.com/aponysus/recourse/hedge"
 	"github.com/aponysus/recourse/observe"
 	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
 )
 
 var (
 	// ErrNoPolicy is returned when no policy is found and missing policy mode is FailureDeny.
 	ErrNoPolicy = errors.New("recourse: no policy found")
 
+	// ErrAttemptsExhausted matches calls that failed after using every allowed attempt.
+	ErrAttemptsExhausted = errors.New("recourse: attempts exhausted")
+	// ErrBudgetDenied matches calls that stopped because a budget denied an attempt.
+	ErrBudgetDenied = errors.New("recourse: budget denied")
+	// ErrCircuitOpen matches calls rejected by an open (or probing) circuit breaker.
+	ErrCircuitOpen = errors.New("recourse: circuit open")
+	// ErrOverallTimeout matches calls that exceeded the policy's overall timeout.
+	ErrOverallTimeout = errors.New("recourse: overall timeout")
+	// ErrTargetUnhealthy matches calls whose retries were skipped because a health
+	// source marked the target unhealthy (see WithHealth).
+	ErrTargetUnhealthy = errors.New("recourse: target unhealthy")
+	// ErrShed matches calls rejected by the executor's Shedder (see WithShedder) or by
+	// their policy's limits (see policy.LimitsPolicy).
+	ErrShed = errors.New("recourse: call shed")
+	// ErrPartialResult matches calls that returned a partial value alongside the error
+	// of an attempt classified classify.OutcomePartial, under a policy with AcceptPartial.
+	ErrPartialResult = errors.New("recourse: partial result")
+
 	// errHedgingRequiresTimeline is an internal sentinel used to switch from fast path to strict path.
 	errHedgingRequiresTimeline = errors.New("recourse: hedging requires timeline")
 )
@@ -61,6 +82,7 @@ type Executor struct {
 	observer              observe.Observer
 	clock                 func() time.Time
 	sleep                 func(context.Context, time.Duration) error
+	after                 func(time.Duration) <-chan time.Time
 	classifiers           *classify.Registry
 	defaultClassifier     classify.Classifier
 	budgets               *budget.Registry
@@ -71,14 +93,48 @@ type Executor struct {
 	missingBudgetMode     FailureMode
 	missingTriggerMode    FailureMode
 	recoverPanics         bool
-
-	trackerMu sync.RWMutex
-	trackers  map[policy.PolicyKey]hedge.LatencyTracker
+	profilerLabels        bool
+	attemptContext        AttemptContextFunc
+	faults                FaultInjector
+	leaks                 *LeakDetector
+	coolOff               *coolOff
+	stragglerGrace        time.Duration
+	namespaceDefaults     map[string]policy.EffectivePolicy
+	bounds                *memoryBounds
+	idempotencyKeys       IdempotencyKeyFunc
+	circuitProbes         map[policy.PolicyKey]Operation
+	errorSummary          bool
+	omitAttemptInfo       bool
+	shedder               shed.Shedder
+	health                *health.Registry
+	shadow                controlplane.PolicyProvider
+	killSwitchProvider    controlplane.KillSwitchProvider
+	override              atomic.Pointer[GlobalOverride]
+	keyOverrides          atomic.Pointer[map[policy.PolicyKey]KeyOverride]
+	keyOverridesMu        sync.Mutex
+	remoteBudgets         remoteBudgets
+	stats                 executorStats
+	adaptive              *adaptiveAttempts
+	storms                *stormDetector
+	throttle              *clientThrottle
+	timing                timeSource
+	limits                *keyLimits
+
+	trackers      *latencyTrackers
+	coalescer     *coalescer
+	policyChanges *policyChangeTracker
+	reaper        reaper
+
+	opts ExecutorOptions // Options e was built from, with defaults resolved; see With.
 }
 
 type executorConfig struct {
 	opts           ExecutorOptions
 	staticPolicies map[policy.PolicyKey]policy.EffectivePolicy
+
+	// sharedClassifiers is set while opts.Classifiers is another executor's registry
+	// (see Executor.With), which WithClassifier copies before changing.
+	sharedClassifiers bool
 }
 
 // ExecutorOptions configures an Executor.
@@ -86,6 +142,7 @@ type ExecutorOptions struct {
 	Provider              controlplane.PolicyProvider
 	Observer              observe.Observer
 	Clock                 func() time.Time
+	Sleeper               Sleeper
 	Classifiers           *classify.Registry
 	DefaultClassifier     classify.Classifier
 	Budgets               *budget.Registry
@@ -96,6 +153,86 @@ type ExecutorOptions struct {
 	MissingBudgetMode     FailureMode
 	MissingTriggerMode    FailureMode
 	RecoverPanics         bool
+
+	// ProfilerLabels runs operations under pprof labels naming their policy key. See
+	// WithProfilerLabels.
+	ProfilerLabels bool
+
+	// AttemptContext, if set, decorates each attempt's context. See WithAttemptContext.
+	AttemptContext AttemptContextFunc
+
+	// ErrorSummary appends the call's attempt history to final error messages. See
+	// WithErrorSummary.
+	ErrorSummary bool
+	// OmitAttemptInfo runs fast-path attempts without observe.AttemptInfo in their
+	// contexts. See WithAttemptInfo.
+	OmitAttemptInfo bool
+
+	// Shedder, if set, admits or rejects calls before their first attempt. See
+	// WithShedder.
+	Shedder shed.Shedder
+
+	// Health, if set, pauses retries to keys marked unhealthy. See WithHealth.
+	Health *health.Registry
+
+	// Coalesce, if set, returns a request key for each call. Concurrent calls with the
+	// same policy key and a non-empty request key share one call. See WithCoalescing.
+	Coalesce func(ctx context.Context) string
+
+	// Shadow, if set, supplies candidate policies evaluated in shadow mode alongside
+	// each call. See WithShadowProvider.
+	Shadow controlplane.PolicyProvider
+
+	// ProviderProtection, if set, bounds policy lookups with a timeout and circuit
+	// breaker. See WithProviderProtection.
+	ProviderProtection *ProviderProtection
+
+	// AdaptiveAttempts, if set, reduces MaxAttempts for keys whose retries are not
+	// succeeding. See WithAdaptiveAttempts.
+	AdaptiveAttempts *AdaptiveAttempts
+
+	// RetryStorm, if set, detects keys in retry storms and optionally lowers their
+	// MaxAttempts. See WithRetryStorm.
+	RetryStorm *RetryStorm
+
+	// ClientThrottle, if set, configures the pacing of keys after throttled attempts.
+	// See WithClientThrottle.
+	ClientThrottle *ClientThrottle
+
+	// NamespaceDefaults maps namespaces to the policy options for their keys that
+	// resolve only to the package default policy. See WithNamespaceDefaults.
+	NamespaceDefaults map[string][]policy.Option
+
+	// IdempotencyKeys generates the idempotency keys of hedged groups whose policy
+	// sets Hedge.IdempotencyKeys; default RandomIdempotencyKey. See WithIdempotencyKeys.
+	IdempotencyKeys IdempotencyKeyFunc
+
+	// CircuitProbes maps keys to operations that probe their half-open circuit
+	// breakers in place of callers' requests. See WithCircuitProbe.
+	CircuitProbes map[policy.PolicyKey]Operation
+
+	// MemoryBounds, if set, caps the observability data kept in call timelines. See
+	// WithMemoryBounds.
+	MemoryBounds *MemoryBounds
+
+	// StragglerGrace bounds how long canceled hedged-group attempts hold their budget
+	// reservations; zero means DefaultStragglerGrace. See WithStragglerGrace.
+	StragglerGrace time.Duration
+
+	// FaultInjector, if set, injects faults into every call. See WithFaultInjection.
+	FaultInjector FaultInjector
+
+	// LeakDetector, if set, reports budget decisions that are not released in time.
+	// See WithLeakDetector.
+	LeakDetector *LeakDetector
+
+	// RetryCoolOff, if set, limits keys whose calls keep exhausting their attempts to a
+	// single attempt for a while. See WithRetryCoolOff.
+	RetryCoolOff *RetryCoolOff
+
+	// Runtime supplies shared budgets, circuits, hedge triggers, latency
+	// trackers, and observers for any of those left unset above.
+	Runtime *Runtime
 }
 
 // NewExecutor creates an Executor with default options.
@@ -117,6 +254,11 @@ func NewExecutor(opts ...ExecutorOption) *Executor {
 
 // NewExecutorFromOptions creates an Executor from a config struct.
 func NewExecutorFromOptions(opts ExecutorOptions) *Executor {
+	built := opts
+	if opts.Runtime != nil {
+		opts = opts.Runtime.apply(opts)
+	}
+
 	e := &Executor{
 		provider:              opts.Provider,
 		observer:              opts.Observer,
@@ -131,7 +273,66 @@ func NewExecutorFromOptions(opts ExecutorOptions) *Executor {
 		missingBudgetMode:     normalizeFailureMode(opts.MissingBudgetMode, FailureDeny),
 		missingTriggerMode:    normalizeFailureMode(opts.MissingTriggerMode, FailureFallback),
 		recoverPanics:         opts.RecoverPanics,
-		trackers:              make(map[policy.PolicyKey]hedge.LatencyTracker),
+		profilerLabels:        opts.ProfilerLabels,
+		attemptContext:        opts.AttemptContext,
+		faults:                opts.FaultInjector,
+		stragglerGrace:        opts.StragglerGrace,
+		namespaceDefaults:     newNamespaceDefaults(opts.NamespaceDefaults),
+		idempotencyKeys:       opts.IdempotencyKeys,
+		circuitProbes:         opts.CircuitProbes,
+		errorSummary:          opts.ErrorSummary,
+		omitAttemptInfo:       opts.OmitAttemptInfo && opts.AttemptContext == nil,
+		shedder:               opts.Shedder,
+		health:                opts.Health,
+		shadow:                opts.Shadow,
+	}
+	if opts.Sleeper != nil {
+		e.sleep = opts.Sleeper.Sleep
+		e.after = opts.Sleeper.After
+	}
+	e.timing = newTimeSource(opts.Clock, opts.Sleeper)
+	if opts.Runtime != nil {
+		e.trackers = opts.Runtime.trackers
+	} else {
+		e.trackers = newLatencyTrackers()
+	}
+	if opts.Coalesce != nil {
+		e.coalescer = newCoalescer(opts.Coalesce)
+	}
+
+	if opts.ClientThrottle != nil {
+		e.throttle = newClientThrottle(*opts.ClientThrottle)
+	} else {
+		e.throttle = newClientThrottle(ClientThrottle{})
+	}
+	// Adaptive attempts and storm detection share one collector when neither brings
+	// its own.
+	var stats *observe.StatsCollector
+	ownStats := func() *observe.StatsCollector {
+		if stats == nil {
+			stats = observe.NewStatsCollector(0)
+			if e.observer != nil {
+				e.observer = observe.MultiObserver{Observers: []observe.Observer{e.observer, stats}}
+			} else {
+				e.observer = stats
+			}
+		}
+		return stats
+	}
+	if opts.AdaptiveAttempts != nil {
+		cfg := *opts.AdaptiveAttempts
+		if cfg.Stats == nil {
+			cfg.Stats = ownStats()
+		}
+		e.adaptive = newAdaptiveAttempts(cfg)
+	}
+	var storm *RetryStorm
+	if opts.RetryStorm != nil {
+		cfg := *opts.RetryStorm
+		if cfg.Stats == nil {
+			cfg.Stats = ownStats()
+		}
+		storm = &cfg
 	}
 
 	if e.provider == nil {
@@ -140,12 +341,46 @@ func NewExecutorFromOptions(opts ExecutorOptions) *Executor {
 	if e.observer == nil {
 		e.observer = &observe.NoopObserver{}
 	}
+	if storm != nil {
+		e.storms = newStormDetector(*storm, e.observer)
+	}
+	if ks, ok := e.provider.(controlplane.KillSwitchProvider); ok {
+		e.killSwitchProvider = ks
+	}
+	resources, _ := e.provider.(controlplane.ResourceProvider)
+	built.Provider = e.provider
+	if opts.ProviderProtection != nil {
+		e.provider = newGuardedProvider(e.provider, *opts.ProviderProtection, e.observer, e.timing)
+	}
+	if obs, ok := e.observer.(observe.PolicyChangeObserver); ok {
+		e.policyChanges = newPolicyChangeTracker(obs)
+	}
 	if e.clock == nil {
 		e.clock = time.Now
 	}
 	if e.sleep == nil {
 		e.sleep = sleepWithContext
 	}
+	if e.after == nil {
+		e.after = time.After
+	}
+	if opts.MemoryBounds != nil {
+		e.bounds = &memoryBounds{cfg: *opts.MemoryBounds, stats: &e.stats}
+	}
+	if opts.RetryCoolOff != nil {
+		e.coolOff = newCoolOff(*opts.RetryCoolOff)
+	}
+	e.limits = &keyLimits{}
+	if opts.LeakDetector != nil {
+		leaks := opts.LeakDetector.normalize()
+		e.leaks = &leaks
+	}
+	if e.idempotencyKeys == nil {
+		e.idempotencyKeys = RandomIdempotencyKey
+	}
+	if e.stragglerGrace <= 0 {
+		e.stragglerGrace = DefaultStragglerGrace
+	}
 	if e.classifiers == nil {
 		e.classifiers = classify.NewRegistry()
 		classify.RegisterBuiltins(e.classifiers)
@@ -159,6 +394,19 @@ func NewExecutorFromOptions(opts ExecutorOptions) *Executor {
 	if e.defaultClassifier == nil {
 		e.defaultClassifier = classify.AlwaysRetryOnError{}
 	}
+	if resources != nil {
+		e.watchResources(resources)
+		if fw, ok := e.faults.(faultWatcher); ok {
+			fw.Watch(resources)
+		}
+	}
+
+	built.Classifiers = e.classifiers
+	built.DefaultClassifier = e.defaultClassifier
+	built.Budgets = e.budgets
+	built.Triggers = e.triggers
+	built.Circuits = e.circuits
+	e.opts = built
 
 	return e
 }
@@ -200,6 +448,23 @@ func (e *NoClassifierError) Error() string {
 	return fmt.Sprintf("recourse: classifier not found: %s", e.Name)
 }
 
+// TargetUnhealthyError is returned when retries stop because a health source marked
+// the target unhealthy. It wraps the error of the last attempt.
+type TargetUnhealthyError struct {
+	Reason string // Reason given by the health source.
+	Err    error  // Error of the last attempt.
+}
+
+func (e *TargetUnhealthyError) Error() string {
+	return fmt.Sprintf("recourse: target unhealthy (%s): %v", e.Reason, e.Err)
+}
+
+func (e *TargetUnhealthyError) Unwrap() error { return e.Err }
+
+func (e *TargetUnhealthyError) Is(target error) bool {
+	return target == ErrTargetUnhealthy
+}
+
 // CircuitOpenError is returned when a circuit breaker prevents execution.
 type CircuitOpenError struct {
 	State  circuit.State
@@ -210,6 +475,10 @@ func (e CircuitOpenError) Error() string {
 	return fmt.Sprintf("recourse: circuit %s: %s", e.State, e.Reason)
 }
 
+func (e CircuitOpenError) Is(target error) bool {
+	return target == ErrCircuitOpen
+}
+
 // ExecutorOption configures an Executor.
 type ExecutorOption func(*executorConfig)
 
@@ -227,7 +496,10 @@ func WithObserver(o observe.Observer) ExecutorOption {
 	}
 }
 
-// WithClock sets the clock function.
+// WithClock sets the clock the executor reads for timeline timestamps, client throttle
+// pacing windows and retry cool-offs. It defaults to time.Now. Together with
+// WithSleeper it also times durations, attempt timeouts and overall timeouts, letting
+// tests and simulations drive an executor's time deterministically.
 func WithClock(f func() time.Time) ExecutorOption {
 	return func(c *executorConfig) {
 		c.opts.Clock = f
@@ -253,7 +525,10 @@ func WithClassifier(name string, cls classify.Classifier) ExecutorOption {
 	return func(c *executorConfig) {
 		if c.opts.Classifiers == nil {
 			c.opts.Classifiers = classify.NewRegistry()
+		} else if c.sharedClassifiers {
+			c.opts.Classifiers = c.opts.Classifiers.Clone()
 		}
+		c.sharedClassifiers = false
 		c.opts.Classifiers.Register(name, cls)
 	}
 }
@@ -300,6 +575,16 @@ func WithMissingBudgetMode(mode FailureMode) ExecutorOption {
 	}
 }
 
+// WithHealth pauses retries to keys that r marks unhealthy: a failed attempt is not
+// retried (or hedged) while its key, or the key's namespace, is unhealthy, and the call
+// fails with a *TargetUnhealthyError and reason "target_unhealthy". First attempts
+// still run.
+func WithHealth(r *health.Registry) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Health = r
+	}
+}
+
 // WithRecoverPanics sets whether to capture and report panics in user code.
 func WithRecoverPanics(recover bool) ExecutorOption {
 	return func(c *executorConfig) {
@@ -338,6 +623,9 @@ func normalizeFailureMode(mode FailureMode, defaultMode FailureMode) FailureMode
 	}
 }
 
+// Do runs op under key's policy. It is DoValue for operations that return only an
+// error: retries, hedging, budgets, circuit breaking, observers, and timeline capture
+// all apply alike.
 func (e *Executor) Do(ctx context.Context, key policy.PolicyKey, op Operation) error {
 	_, err := DoValue[struct{}](ctx, e, key, func(ctx context.Context) (struct{}, error) {
 		return struct{}{}, op(ctx)
@@ -345,6 +633,8 @@ func (e *Executor) Do(ctx context.Context, key policy.PolicyKey, op Operation) e
 	return err
 }
 
+// DoValue runs op under key's policy with exec and returns its result. A nil exec runs
+// the call with NewExecutor().
 func DoValue[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T]) (T, error) {
 	val, _, err := doValueInternal(ctx, exec, key, op, false)
 	return val, err
@@ -372,25 +662,76 @@ func doValueInternal[T any](ctx context.Context, exec *Executor, key policy.Poli
 			MissingClassifierMode: exec.missingClassifierMode,
 			MissingTriggerMode:    exec.missingTriggerMode,
 			RecoverPanics:         exec.recoverPanics,
+			ProfilerLabels:        exec.profilerLabels,
+			AttemptContext:        exec.attemptContext,
+			FaultInjector:         exec.faults,
+			StragglerGrace:        exec.stragglerGrace,
+			IdempotencyKeys:       exec.idempotencyKeys,
+			ErrorSummary:          exec.errorSummary,
+			OmitAttemptInfo:       exec.omitAttemptInfo,
+			Shedder:               exec.shedder,
+			Health:                exec.health,
 		})
 	}
 
+	if exec.coalescer != nil {
+		if reqKey := exec.coalescer.keyFunc(ctx); reqKey != "" {
+			return coalesceValue(ctx, exec, key, reqKey, op)
+		}
+	}
+	return doValueCall(ctx, exec, key, op, wantTimeline)
+}
+
+// doValueCall runs one call through the executor.
+func doValueCall[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T], wantTimeline bool) (T, observe.Timeline, error) {
+	if exec.faults != nil {
+		op = withFaults(exec.faults, key, op)
+	}
+	if exec.attemptContext != nil {
+		op = withAttemptContext(exec.attemptContext, op)
+	}
+	if exec.profilerLabels {
+		op = withProfilerLabels(key, op)
+	}
 	capture, hasCapture := observe.TimelineCaptureFromContext(ctx)
-	fullTimeline := wantTimeline || hasCapture || !isNoopObserver(exec.observer)
+	ctx, hasCallObserver := exec.withCallObserver(ctx)
+	fullTimeline := wantTimeline || hasCapture || hasCallObserver || !isNoopObserver(exec.observer)
+	callStart := exec.timing.now()
+
+	exec.pace(ctx, key)
+	if exec.shedder != nil {
+		release, err := exec.admit(ctx, key)
+		if err != nil {
+			var zero T
+			tl := exec.shedTimeline(ctx, key, exec.clock(), callStart, err, fullTimeline)
+			if capture != nil {
+				observe.StoreTimelineCapture(capture, &tl)
+			}
+			exec.stats.call(err)
+			var tlp *observe.Timeline
+			if fullTimeline {
+				tlp = &tl
+			}
+			return zero, tl, newCallError(key, err, callSummary{}, tl.Duration, tlp, exec.errorSummary)
+		}
+		if release != nil {
+			defer release()
+		}
+	}
 
 	if !fullTimeline {
-		// Use a wrapped op that suppresses capture to prevent implicit capture in nested calls.
-		fastOp := func(c context.Context) (T, error) {
-			return op(observe.WithoutTimelineCapture(c))
-		}
-		val, err := doValueFast(ctx, exec, key, fastOp)
+		// No capture is active in ctx, so nested calls cannot capture into this one and
+		// op needs no wrapping (which would cost a context allocation per call).
+		val, sum, err := doValueFast(ctx, exec, key, op)
 
 		// Fallback check
 		if err == errHedgingRequiresTimeline {
 			// Fall through to fullTimeline path
 			fullTimeline = true
 		} else {
-			return val, observe.Timeline{}, err
+			exec.stats.call(err)
+			exec.recordCoolOff(key, err, sum)
+			return val, observe.Timeline{}, newCallError(key, err, sum, exec.timing.since(callStart), nil, exec.errorSummary)
 		}
 	}
 
@@ -400,56 +741,79 @@ func doValueInternal[T any](ctx context.Context, exec *Executor, key policy.Poli
 		return op(observe.WithoutTimelineCapture(c))
 	}
 
-	val, tl, err := doValueWithTimeline(ctx, exec, key, safeOp)
+	val, tl, sum, err := doValueWithTimeline(ctx, exec, key, safeOp)
+	exec.stats.call(err)
+	exec.recordCoolOff(key, err, sum)
 	if capture != nil {
 		observe.StoreTimelineCapture(capture, &tl)
 	}
+	if err != nil {
+		sum.addTimeline(&tl)
+		err = newCallError(key, err, sum, tl.Duration, &tl, exec.errorSummary)
+	}
 	// Record latency if we have a valid policy key and tracking is enabled.
 	return val, tl, err
 }
 
-func (e *Executor) getTracker(key policy.PolicyKey) hedge.LatencyTracker {
-	e.trackerMu.RLock()
-	t, ok := e.trackers[key]
-	e.trackerMu.RUnlock()
-	if ok {
-		return t
-	}
+// maxPreallocAttempts caps the attempt storage reserved up front, so a policy that
+// bypassed normalization with a huge MaxAttempts or MaxHedges cannot make every call
+// allocate for it. Calls that run more attempts grow the storage as usual.
+const maxPreallocAttempts = 64
 
-	e.trackerMu.Lock()
-	defer e.trackerMu.Unlock()
-	// Double check
-	if t, ok = e.trackers[key]; ok {
-		return t
+// attemptCapacity returns how many attempt records a call under pol can produce,
+// MaxAttempts × (1+MaxHedges), capped at maxPreallocAttempts.
+func attemptCapacity(pol policy.EffectivePolicy) int {
+	attempts := max(pol.Retry.MaxAttempts, 1)
+	perAttempt := 1
+	if pol.Hedge.Enabled && pol.Hedge.MaxHedges > 0 {
+		perAttempt += pol.Hedge.MaxHedges
 	}
-	t = hedge.NewRingBufferTracker(256)
-	e.trackers[key] = t
-	return t
+	if attempts > maxPreallocAttempts/perAttempt {
+		return maxPreallocAttempts
+	}
+	return attempts * perAttempt
 }
 
-func doValueFast[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T]) (T, error) {
+func (e *Executor) getTracker(key policy.PolicyKey) hedge.LatencyTracker {
+	return e.trackers.get(key)
+}
+
+func doValueFast[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T]) (T, callSummary, error) {
 	var zero T
+	sum := callSummary{trackReasons: exec.errorSummary}
 
 	pol, err := resolvePolicyFast(ctx, exec, key)
 	if err != nil {
-		return zero, err
+		return zero, sum, err
+	}
+	if pol.Rollout != nil {
+		pol, _ = applyRollout(ctx, key, pol)
 	}
+	pol = exec.applyOverrides(ctx, key, pol, nil, &sum)
 
 	if pol.Hedge.Enabled {
-		return zero, errHedgingRequiresTimeline
+		return zero, sum, errHedgingRequiresTimeline
 	}
-	if pol.Circuit.Enabled {
-		return zero, errHedgingRequiresTimeline // Reuse sentinel for now to force full path
+	if pol.Circuit.Enabled || exec.circuitForcedOpen(key) {
+		return zero, sum, errHedgingRequiresTimeline // Reuse sentinel for now to force full path
+	}
+	release, shedErr := exec.limitCall(ctx, key, pol.Limits)
+	if shedErr != nil {
+		return zero, sum, shedErr
+	}
+	if release != nil {
+		defer release()
 	}
 
-	classifier, _, err := resolveClassifier(exec, pol)
+	classifier, cmeta, err := resolveClassifier(exec, pol)
 	if err != nil {
-		return zero, err
+		return zero, sum, err
 	}
 
+	parent := ctx
 	if pol.Retry.OverallTimeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, pol.Retry.OverallTimeout)
+		ctx, cancel = exec.timing.withTimeout(ctx, pol.Retry.OverallTimeout)
 		defer cancel()
 	}
 
@@ -457,38 +821,60 @@ func doValueFast[T any](ctx context.Context, exec *Executor, key policy.PolicyKe
 	if maxAttempts <= 0 {
 		maxAttempts = 1
 	}
+	sum.reasonsHint = attemptCapacity(pol)
+
+	deps := callDeps{classifier: classifier, cmeta: cmeta}
+	if decision, ok := exec.resolveCallDeps(ctx, key, pol, &deps); !ok {
+		sum.addReason(decision.Reason)
+		sum.class = ErrBudgetDenied
+		return zero, sum, errors.New(decision.Reason)
+	}
+	defer deps.finish(ctx)
 
 	backoff := pol.Retry.InitialBackoff
 
 	var last T
 	var lastErr error
+	var partial T
+	hasPartial := false
+	var reasonBackoffs map[string]time.Duration
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
 		if err := ctx.Err(); err != nil {
-			return last, err
+			sum.class = overallTimeoutClass(parent, ctx)
+			return last, sum, err
+		}
+		if attempt > 0 {
+			if err := exec.checkHealth(key, lastErr); err != nil {
+				return last, sum, err
+			}
 		}
 
-		decision, ok := exec.allowAttempt(ctx, key, pol.Retry.Budget, attempt, budget.KindRetry)
+		decision, ok := exec.allowCallAttempt(ctx, key, &deps.retry, attempt, budget.KindRetry)
 		// Check if attempt is allowed by budget.
 		if !ok {
-			return last, errors.New(decision.Reason)
+			sum.addReason(decision.Reason)
+			sum.class = ErrBudgetDenied
+			return last, sum, errors.New(decision.Reason)
 		}
 
 		release := decision.Release
 
 		attemptCtx := ctx
 		cancelAttempt := func() {}
-		if pol.Retry.TimeoutPerAttempt > 0 {
-			attemptCtx, cancelAttempt = context.WithTimeout(ctx, pol.Retry.TimeoutPerAttempt)
+		if slice := exec.attemptSlice(ctx, pol.Retry, maxAttempts-attempt); pol.Retry.TimeoutPerAttempt > 0 || !slice.IsZero() {
+			attemptCtx, cancelAttempt = exec.withAttemptTimeout(ctx, pol.Retry.TimeoutPerAttempt, slice)
 		}
 
 		// Inject attempt info for observability.
-		attemptCtx = observe.WithAttemptInfo(attemptCtx, observe.AttemptInfo{
-			RetryIndex: attempt,
-			Attempt:    attempt,
-			IsHedge:    false,
-			PolicyID:   pol.ID,
-		})
+		if !exec.omitAttemptInfo {
+			attemptCtx = observe.WithAttemptInfo(attemptCtx, observe.AttemptInfo{
+				RetryIndex: attempt,
+				Attempt:    attempt,
+				IsHedge:    false,
+				PolicyID:   pol.ID,
+			})
+		}
 
 		var val T
 		var err error
@@ -498,74 +884,146 @@ func doValueFast[T any](ctx context.Context, exec *Executor, key policy.PolicyKe
 			if release != nil {
 				defer release()
 			}
-			start := exec.clock()
+			start := exec.timing.now()
 			val, err = op(attemptCtx)
 			// Feed latency tracker
-			exec.getTracker(key).Observe(exec.clock().Sub(start))
+			exec.getTracker(key).Observe(exec.timing.since(start))
 		}()
 
 		last = val
 		lastErr = err
+		sum.attempts++
 
 		out, panicErr := classifyWithRecovery(exec.recoverPanics, classifier, val, err, key)
 		if panicErr != nil {
-			return last, panicErr
+			return last, sum, panicErr
 		}
+		sum.addReason(out.Reason)
 
 		if out.Kind == classify.OutcomeSuccess {
-			return val, nil
+			return val, sum, nil
 		}
 
 		switch out.Kind {
 		case classify.OutcomeRetryable:
 			// continue
+		case classify.OutcomeThrottled:
+			exec.throttle.throttled(key, out.BackoffOverride, exec.clock())
+		case classify.OutcomePartial:
+			if pol.Retry.AcceptPartial {
+				sum.class = ErrPartialResult
+				return val, sum, terminalError(ctx, lastErr, out)
+			}
+			partial, hasPartial = val, true
 		case classify.OutcomeNonRetryable, classify.OutcomeAbort, classify.OutcomeUnknown:
-			return last, terminalError(ctx, lastErr, out)
+			sum.class = overallTimeoutClass(parent, ctx)
+			return last, sum, terminalError(ctx, lastErr, out)
 		default:
-			return last, terminalError(ctx, lastErr, out)
+			sum.class = overallTimeoutClass(parent, ctx)
+			return last, sum, terminalError(ctx, lastErr, out)
 		}
 
 		if attempt == maxAttempts-1 {
-			return last, terminalError(ctx, lastErr, out)
+			if hasPartial {
+				last = partial
+			}
+			sum.class = exhaustedClass(parent, ctx)
+			return last, sum, terminalError(ctx, lastErr, out)
 		}
 
-		sleepFor := computeSleep(backoff, pol.Retry, out)
+		sleepFor := computeSleep(backoff, pol.Retry, out, &reasonBackoffs)
 		if sleepFor > 0 {
 			if err := exec.sleep(ctx, sleepFor); err != nil {
-				return last, err
+				sum.class = overallTimeoutClass(parent, ctx)
+				return last, sum, err
 			}
 		}
 
 		backoff = nextBackoff(backoff, pol.Retry.BackoffMultiplier, pol.Retry.MaxBackoff)
 	}
 
-	return last, lastErr
+	return last, sum, lastErr
 }
 
-func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T]) (T, observe.Timeline, error) {
+func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.PolicyKey, op OperationValue[T]) (T, observe.Timeline, callSummary, error) {
 	var zero T
+	var sum callSummary
+	obs := exec.observerFor(ctx)
 
 	start := exec.clock()
+	// Durations use a monotonic reading so they stay meaningful when the clock is frozen or skewed.
+	mono := exec.timing.now()
 
 	// 1. Resolve Policy
-	pol, attrs, err := resolvePolicyWithAttributes(ctx, exec, key)
+	pol, attrs, err := resolvePolicyWithAttributes(ctx, exec, key, obs)
 	if err != nil {
 		tl := observe.Timeline{
 			Key:        key,
 			PolicyID:   pol.ID,
 			Start:      start,
 			End:        exec.clock(),
+			Duration:   exec.timing.since(mono),
 			Attributes: attrs,
 			Attempts:   nil,
 			FinalErr:   err,
 		}
-		exec.observer.OnStart(ctx, key, pol)
-		exec.observer.OnFailure(ctx, key, tl)
-		return zero, tl, err
+		obs.OnStart(ctx, key, pol)
+		exec.bounds.trim(&tl)
+		obs.OnFailure(ctx, key, tl)
+		return zero, tl, sum, err
+	}
+
+	if exec.policyChanges != nil {
+		exec.policyChanges.record(ctx, key, pol)
+	}
+	if pol.Rollout != nil {
+		var version string
+		pol, version = applyRollout(ctx, key, pol)
+		attrs["policy_version"] = version
+	}
+	pol = exec.applyOverrides(ctx, key, pol, attrs, &sum)
+
+	release, shedErr := exec.limitCall(ctx, key, pol.Limits)
+	if shedErr != nil {
+		tl := observe.Timeline{
+			Key:        key,
+			PolicyID:   pol.ID,
+			Start:      start,
+			End:        exec.clock(),
+			Duration:   exec.timing.since(mono),
+			Attributes: attrs,
+			FinalErr:   shedErr,
+		}
+		tl.Attributes["shed_reason"] = shedErr.Reason
+		obs.OnStart(ctx, key, pol)
+		exec.bounds.trim(&tl)
+		obs.OnFailure(ctx, key, tl)
+		return zero, tl, sum, tl.FinalErr
+	}
+	if release != nil {
+		defer release()
 	}
 
 	// 2. Check Circuit Breaker
+	if exec.circuitForcedOpen(key) {
+		tl := observe.Timeline{
+			Key:        key,
+			PolicyID:   pol.ID,
+			Start:      start,
+			End:        exec.clock(),
+			Duration:   exec.timing.since(mono),
+			Attributes: attrs,
+			FinalErr:   CircuitOpenError{State: circuit.StateOpen, Reason: circuit.ReasonCircuitForcedOpen},
+		}
+		tl.Attributes["circuit_state"] = circuit.StateOpen.String()
+		obs.OnStart(ctx, key, pol)
+		exec.bounds.trim(&tl)
+		obs.OnFailure(ctx, key, tl)
+		return zero, tl, sum, tl.FinalErr
+	}
 	var cb circuit.CircuitBreaker
+	var probing bool   // The call was admitted as the half-open breaker's probe.
+	var cbVerdict bool // A success or failure was recorded on cb.
 	if pol.Circuit.Enabled {
 		cb = exec.circuits.Get(key, pol.Circuit)
 		if cb != nil {
@@ -576,23 +1034,33 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 					PolicyID:   pol.ID,
 					Start:      start,
 					End:        exec.clock(),
+					Duration:   exec.timing.since(mono),
 					Attributes: attrs,
 					Attempts:   nil,
 					FinalErr:   CircuitOpenError{State: decision.State, Reason: decision.Reason},
 				}
 				tl.Attributes["circuit_state"] = decision.State.String()
-				exec.observer.OnStart(ctx, key, pol)
-				exec.observer.OnFailure(ctx, key, tl)
+				obs.OnStart(ctx, key, pol)
+				exec.bounds.trim(&tl)
+				obs.OnFailure(ctx, key, tl)
 				// Record failure? IP says: "On OutcomeAbort: Do not report".
 				// Circuit rejection is arguably a failure of availability, but we didn't attempt.
 				// Usually we don't record failure to the breaker if the breaker itself rejected it
 				// (preventing feedback loop).
-				return zero, tl, tl.FinalErr
+				return zero, tl, sum, tl.FinalErr
 			}
 			// If allowed, we proceed.
 			// Half-open state might affect hedging later.
 			if decision.State == circuit.StateHalfOpen {
-				pol.Hedge.Enabled = false
+				probing = true
+				defer func() {
+					if !cbVerdict {
+						cancelProbe(ctx, cb)
+					}
+				}()
+				if exec.circuitProbes[key] == nil {
+					pol.Hedge.Enabled = false
+				}
 			}
 		}
 	}
@@ -604,6 +1072,7 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 			PolicyID:   pol.ID,
 			Start:      start,
 			End:        exec.clock(),
+			Duration:   exec.timing.since(mono),
 			Attributes: attrs,
 			Attempts:   nil,
 			FinalErr:   err,
@@ -612,14 +1081,43 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 			tl.Attributes["classifier_name"] = cmeta.requested
 		}
 		tl.Attributes["classifier_error"] = "classifier_not_found"
-		exec.observer.OnStart(ctx, key, pol)
-		exec.observer.OnFailure(ctx, key, tl)
-		return zero, tl, err
+		obs.OnStart(ctx, key, pol)
+		exec.bounds.trim(&tl)
+		obs.OnFailure(ctx, key, tl)
+		return zero, tl, sum, err
+	}
+
+	if probe := exec.circuitProbes[key]; probing && probe != nil {
+		cbVerdict = true
+		if !exec.runCircuitProbe(ctx, key, pol, cb, classifier, probe) {
+			tl := observe.Timeline{
+				Key:        key,
+				PolicyID:   pol.ID,
+				Start:      start,
+				End:        exec.clock(),
+				Duration:   exec.timing.since(mono),
+				Attributes: attrs,
+				FinalErr:   CircuitOpenError{State: cb.State(), Reason: circuit.ReasonCircuitProbeFailed},
+			}
+			tl.Attributes["circuit_probe"] = "failed"
+			obs.OnStart(ctx, key, pol)
+			exec.bounds.trim(&tl)
+			obs.OnFailure(ctx, key, tl)
+			return zero, tl, sum, tl.FinalErr
+		}
+		attrs["circuit_probe"] = "passed"
+		probing = false
 	}
 
+	var shadow *shadowEval
+	if exec.shadow != nil {
+		shadow = newShadowEval(ctx, exec, key, attrs)
+	}
+
+	parent := ctx
 	if pol.Retry.OverallTimeout > 0 {
 		var cancel context.CancelFunc
-		ctx, cancel = context.WithTimeout(ctx, pol.Retry.OverallTimeout)
+		ctx, cancel = exec.timing.withTimeout(ctx, pol.Retry.OverallTimeout)
 		defer cancel()
 	}
 
@@ -633,30 +1131,58 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 		PolicyID:   pol.ID,
 		Start:      start,
 		Attributes: attrs,
-		Attempts:   make([]observe.AttemptRecord, 0, maxAttempts),
+		Attempts:   make([]observe.AttemptRecord, 0, attemptCapacity(pol)),
 	}
-	exec.observer.OnStart(ctx, key, pol)
+	obs.OnStart(ctx, key, pol)
+
+	deps := callDeps{classifier: classifier, cmeta: cmeta}
+	if decision, ok := exec.resolveCallDeps(ctx, key, pol, &deps); !ok {
+		tl.End = exec.clock()
+		tl.Duration = exec.timing.since(mono)
+		tl.FinalErr = errors.New(decision.Reason)
+		tl.Attributes["budget_reservation"] = decision.Reason
+		exec.bounds.trim(&tl)
+		obs.OnFailure(ctx, key, tl)
+		sum.class = ErrBudgetDenied
+		return zero, tl, sum, tl.FinalErr
+	}
+	defer deps.finish(ctx)
 
 	backoff := pol.Retry.InitialBackoff
+	var reasonBackoffs map[string]time.Duration
 
 	var last T
 	var lastErr error
 	var lastBackoff time.Duration
+	var lastBackoffActual time.Duration
 
 	var tlMu sync.Mutex
 	var done bool
+	var held int64 // Bytes held against the executor's memory bounds.
+	defer func() {
+		tlMu.Lock()
+		exec.bounds.release(held)
+		tlMu.Unlock()
+	}()
 	recordAttempt := func(ctx context.Context, rec observe.AttemptRecord) {
 		tlMu.Lock()
 		defer tlMu.Unlock()
 		if done {
 			return
 		}
-		tl.Attempts = append(tl.Attempts, rec)
-		exec.observer.OnAttempt(ctx, key, rec)
+		exec.bounds.add(&tl, rec, &held)
+		obs.OnAttempt(ctx, key, rec)
 
 		// Feed latency tracker
 		tracker := exec.getTracker(key)
-		tracker.Observe(rec.EndTime.Sub(rec.StartTime))
+		tracker.Observe(rec.Duration)
+	}
+	recordShadow := func(attempt int, val any, err error, retried bool) {
+		if d, ok := shadow.decide(attempt, val, err, retried); ok {
+			tlMu.Lock()
+			tl.Shadow = append(tl.Shadow, d)
+			tlMu.Unlock()
+		}
 	}
 
 	for attempt := 0; attempt < maxAttempts; attempt++ {
@@ -664,13 +1190,30 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 			tlMu.Lock()
 			done = true
 			tl.End = exec.clock()
+			tl.Duration = exec.timing.since(mono)
 			tl.FinalErr = err
 			tlMu.Unlock()
-			exec.observer.OnFailure(ctx, key, tl)
+			exec.bounds.trim(&tl)
+			obs.OnFailure(ctx, key, tl)
 			// Context canceled before attempt.
 			// Should we report this to breaker?
 			// Usually Context Canceled is OutcomeAbort, which we don't report.
-			return last, tl, err
+			sum.class = overallTimeoutClass(parent, ctx)
+			return last, tl, sum, err
+		}
+		if attempt > 0 {
+			if err := exec.checkHealth(key, lastErr); err != nil {
+				tlMu.Lock()
+				done = true
+				tl.End = exec.clock()
+				tl.Duration = exec.timing.since(mono)
+				tl.FinalErr = err
+				tl.Attributes["target_unhealthy"] = err.(*TargetUnhealthyError).Reason
+				tlMu.Unlock()
+				exec.bounds.trim(&tl)
+				obs.OnFailure(ctx, key, tl)
+				return last, tl, sum, err
+			}
 		}
 
 		opAny := func(c context.Context) (any, error) { return op(c) }
@@ -680,40 +1223,79 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 			key,
 			opAny,
 			pol,
+			&deps,
 			attempt,
-			classifier,
-			cmeta,
 			lastBackoff,
+			lastBackoffActual,
 			recordAttempt,
 		)
 
 		if success {
+			recordShadow(attempt, valAny, err, false)
+
 			// Record success to circuit breaker
 			if cb != nil {
 				cb.RecordSuccess(ctx)
+				cbVerdict = true
 			}
 
 			tlMu.Lock()
 			done = true
 			tl.End = exec.clock()
+			tl.Duration = exec.timing.since(mono)
 			tl.FinalErr = nil
 			tlMu.Unlock()
-			exec.observer.OnSuccess(ctx, key, tl)
-			return valAny.(T), tl, nil
+			exec.bounds.trim(&tl)
+			obs.OnSuccess(ctx, key, tl)
+			// Comma-ok: a nil result for an interface T has no dynamic type to assert.
+			val, _ := valAny.(T)
+			return val, tl, sum, nil
 		}
 
 		prevErr := lastErr
 		lastErr = err
 
+		if outcome.Kind == classify.OutcomePartial {
+			// Keep the partial value: it is the call's result if accepted, or if every
+			// remaining attempt fails.
+			last, _ = valAny.(T)
+			if pol.Retry.AcceptPartial {
+				recordShadow(attempt, valAny, err, false)
+				if cb != nil {
+					if probing && !probeSuccess(pol.Circuit, outcome) {
+						cb.RecordFailure(ctx)
+					} else {
+						cb.RecordSuccess(ctx)
+					}
+					cbVerdict = true
+				}
+
+				terr := terminalError(ctx, lastErr, outcome)
+				tlMu.Lock()
+				done = true
+				tl.End = exec.clock()
+				tl.Duration = exec.timing.since(mono)
+				tl.FinalErr = terr
+				tl.Attributes["partial"] = "true"
+				tlMu.Unlock()
+				exec.bounds.trim(&tl)
+				obs.OnSuccess(ctx, key, tl)
+				sum.class = ErrPartialResult
+				return last, tl, sum, terr
+			}
+		}
+
 		isTerminal := false
 		if outcome.Kind == classify.OutcomeAbort || outcome.Kind == classify.OutcomeNonRetryable {
 			isTerminal = true
 		}
+		recordShadow(attempt, valAny, err, !isTerminal && attempt < maxAttempts-1)
 
 		if isTerminal {
 			// Record failure to circuit breaker (unless it's an abort/cancellation).
 			if cb != nil && outcome.Kind != classify.OutcomeAbort {
 				cb.RecordFailure(ctx)
+				cbVerdict = true
 			}
 
 			terr := terminalError(ctx, lastErr, outcome)
@@ -724,39 +1306,58 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 			tlMu.Lock()
 			done = true
 			tl.End = exec.clock()
+			tl.Duration = exec.timing.since(mono)
 			tl.FinalErr = terr
 			tlMu.Unlock()
-			exec.observer.OnFailure(ctx, key, tl)
+			exec.bounds.trim(&tl)
+			obs.OnFailure(ctx, key, tl)
 
-			return last, tl, terr
+			sum.class = overallTimeoutClass(parent, ctx)
+			return last, tl, sum, terr
 		}
 		if attempt == maxAttempts-1 {
-			// Max attempts reached, still failing.
+			// Max attempts reached, still failing. A probe's partial result may still
+			// show the target recovered.
 			if cb != nil && outcome.Kind != classify.OutcomeAbort {
-				cb.RecordFailure(ctx)
+				if probing && probeSuccess(pol.Circuit, outcome) {
+					cb.RecordSuccess(ctx)
+				} else {
+					cb.RecordFailure(ctx)
+				}
+				cbVerdict = true
 			}
 
 			terr := terminalError(ctx, lastErr, outcome)
 			tlMu.Lock()
 			done = true
 			tl.End = exec.clock()
+			tl.Duration = exec.timing.since(mono)
 			tl.FinalErr = terr
 			tlMu.Unlock()
-			exec.observer.OnFailure(ctx, key, tl)
-			return last, tl, terr
+			exec.bounds.trim(&tl)
+			obs.OnFailure(ctx, key, tl)
+			sum.class = exhaustedClass(parent, ctx)
+			return last, tl, sum, terr
 		}
 
-		sleepFor := computeSleep(backoff, pol.Retry, outcome)
+		sleepFor := computeSleep(backoff, pol.Retry, outcome, &reasonBackoffs)
 		lastBackoff = sleepFor
+		lastBackoffActual = 0
 		if sleepFor > 0 {
-			if err := exec.sleep(ctx, sleepFor); err != nil {
+			sleepStart := exec.timing.now()
+			err := exec.sleep(ctx, sleepFor)
+			lastBackoffActual = exec.timing.since(sleepStart)
+			if err != nil {
 				tlMu.Lock()
 				done = true
 				tl.End = exec.clock()
+				tl.Duration = exec.timing.since(mono)
 				tl.FinalErr = err
 				tlMu.Unlock()
-				exec.observer.OnFailure(ctx, key, tl)
-				return last, tl, err
+				exec.bounds.trim(&tl)
+				obs.OnFailure(ctx, key, tl)
+				sum.class = overallTimeoutClass(parent, ctx)
+				return last, tl, sum, err
 			}
 		}
 
@@ -766,13 +1367,17 @@ func doValueWithTimeline[T any](ctx context.Context, exec *Executor, key policy.
 	tlMu.Lock()
 	done = true
 	tl.End = exec.clock()
+	tl.Duration = exec.timing.since(mono)
 	tl.FinalErr = lastErr
 	tlMu.Unlock()
-	exec.observer.OnFailure(ctx, key, tl)
-	return last, tl, lastErr
+	exec.bounds.trim(&tl)
+	obs.OnFailure(ctx, key, tl)
+	return last, tl, sum, lastErr
 }
 
-func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy.PolicyKey) (policy.EffectivePolicy, map[string]string, error) {
+// resolvePolicyWithAttributes resolves key's policy, applying the missing-policy mode
+// when resolution fails. Fallbacks are reported to obs if it is non-nil.
+func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy.PolicyKey, obs observe.Observer) (policy.EffectivePolicy, map[string]string, error) {
 	attrs := make(map[string]string)
 
 	var pol policy.EffectivePolicy
@@ -795,6 +1400,7 @@ func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy
 	}()
 
 	if err != nil {
+		exec.reportPolicyFallback(ctx, obs, key, policyFallbackReason(err), err, attrs)
 		switch exec.missingPolicyMode {
 		case FailureDeny:
 			return policy.EffectivePolicy{}, attrs, &NoPolicyError{Key: key, Err: err}
@@ -813,6 +1419,7 @@ func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy
 
 	pol, normErr := pol.Normalize()
 	if normErr != nil {
+		exec.reportPolicyFallback(ctx, obs, key, observe.PolicyFallbackInvalid, normErr, attrs)
 		switch exec.missingPolicyMode {
 		case FailureDeny:
 			return policy.EffectivePolicy{}, attrs, &NoPolicyError{Key: key, Err: normErr}
@@ -825,6 +1432,10 @@ func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy
 		}
 		attrs["policy_error"] = fmt.Sprintf("normalization_failed: %v", normErr)
 	}
+	pol = exec.applyNamespaceDefaults(key, pol)
+	if pol.Meta.Source == policy.PolicySourceLKG {
+		attrs["policy_source"] = string(policy.PolicySourceLKG)
+	}
 
 	return pol, attrs, nil
 }
@@ -832,6 +1443,7 @@ func resolvePolicyWithAttributes(ctx context.Context, exec *Executor, key policy
 func resolvePolicyFast(ctx context.Context, exec *Executor, key policy.PolicyKey) (policy.EffectivePolicy, error) {
 	// Fast path avoids attributes map and defer overhead if possible.
 	// But provider might panic.
+	// It only runs without an observer, so there is no one to report fallbacks to.
 
 	var pol policy.EffectivePolicy
 	var err error
@@ -882,10 +1494,35 @@ func resolvePolicyFast(ctx context.Context, exec *Executor, key policy.PolicyKey
 			pol, _ = pol.Normalize()
 		}
 	}
+	pol = exec.applyNamespaceDefaults(key, pol)
 
 	return pol, nil
 }
 
+// policyFallbackReason maps a provider failure to an observe.PolicyFallbackEvent reason.
+func policyFallbackReason(err error) string {
+	var pe *PanicError
+	switch {
+	case errors.As(err, &pe):
+		return observe.PolicyFallbackProviderPanic
+	case errors.Is(err, controlplane.ErrPolicyNotFound):
+		return observe.PolicyFallbackNotFound
+	default:
+		return observe.PolicyFallbackProviderError
+	}
+}
+
+// reportPolicyFallback records a policy resolution fallback in attrs and reports it to
+// obs if it is a PolicyFallbackObserver.
+func (e *Executor) reportPolicyFallback(ctx context.Context, obs observe.Observer, key policy.PolicyKey, reason string, err error, attrs map[string]string) {
+	mode := failureModeString(e.missingPolicyMode)
+	attrs["policy_fallback"] = reason
+	attrs["missing_policy_mode"] = mode
+	if pf, ok := obs.(observe.PolicyFallbackObserver); ok {
+		pf.OnPolicyFallback(ctx, observe.PolicyFallbackEvent{Key: key, Reason: reason, Mode: mode, Err: err})
+	}
+}
+
 func policyErrorKind(err error) string {
 	switch {
 	case errors.Is(err, controlplane.ErrPolicyNotFound):
@@ -911,8 +1548,9 @@ func isNoopObserver(obs observe.Observer) bool {
 func isZeroEffectivePolicy(pol policy.EffectivePolicy) bool {
 	return pol.Key == (policy.PolicyKey{}) &&
 		pol.ID == "" &&
-		pol.Retry == (policy.RetryPolicy{}) &&
-		pol.Hedge == (policy.HedgePolicy{})
+		pol.Retry.Equal(policy.RetryPolicy{}) &&
+		pol.Hedge == (policy.HedgePolicy{}) &&
+		pol.Limits == (policy.LimitsPolicy{})
 }
 
 func sleepWithContext(ctx context.Context, d time.Duration) error {
@@ -967,6 +1605,11 @@ func applyJitter(backoff time.Duration, kind policy.JitterKind) time.Duration {
 type classifierMeta struct {
 	requested string
 	notFound  bool
+
+	// fallback holds the attributes annotating outcomes of the default classifier used
+	// in place of a missing one. Built once per call and shared by its attempts'
+	// outcomes, which treat it as read-only.
+	fallback map[string]string
 }
 
 func resolveClassifier(exec *Executor, pol policy.EffectivePolicy) (classify.Classifier, classifierMeta, error) {
@@ -1004,6 +1647,7 @@ func resolveClassifier(exec *Executor, pol policy.EffectivePolicy) (classify.Cla
 			return nil, meta, panicErr
 		default:
 			// Fallback
+			meta.fallback = classifierFallbackAttributes(meta.requested)
 			return classifier, meta, nil
 		}
 	}
@@ -1017,20 +1661,30 @@ func resolveClassifier(exec *Executor, pol policy.EffectivePolicy) (classify.Cla
 	case FailureDeny:
 		return nil, meta, &NoClassifierError{Name: meta.requested}
 	default:
+		meta.fallback = classifierFallbackAttributes(meta.requested)
 		return classifier, meta, nil
 	}
 }
 
+func classifierFallbackAttributes(requested string) map[string]string {
+	return map[string]string{
+		"classifier_not_found": "true",
+		"classifier_name":      requested,
+		"classifier_fallback":  "default",
+	}
+}
+
 func annotateClassifierFallback(out *classify.Outcome, meta classifierMeta) {
 	if out == nil || !meta.notFound || meta.requested == "" {
 		return
 	}
 	if out.Attributes == nil {
-		out.Attributes = make(map[string]string, 3)
+		out.Attributes = meta.fallback
+		return
+	}
+	for k, v := range meta.fallback {
+		out.Attributes[k] = v
 	}
-	out.Attributes["classifier_not_found"] = "true"
-	out.Attributes["classifier_name"] = meta.requested
-	out.Attributes["classifier_fallback"] = "default"
 }
 
 func classifyWithRecovery(recoverPanics bool, classifier classify.Classifier, value any, err error, key policy.PolicyKey) (out classify.Outcome, panicErr error) {
@@ -1047,6 +1701,9 @@ func classifyWithRecovery(recoverPanics bool, classifier classify.Classifier, va
 			}
 		}()
 	}
+	if hint, ok := classifyHint(err); ok {
+		return hint, nil
+	}
 	out = classifier.Classify(value, err)
 	if out.Kind == classify.OutcomeUnknown {
 		if out.Reason == "" {
@@ -1064,6 +1721,10 @@ func classifyWithRecovery(recoverPanics bool, classifier classify.Classifier, va
 			out.Reason = "non_retryable_error"
 		case classify.OutcomeAbort:
 			out.Reason = "abort"
+		case classify.OutcomePartial:
+			out.Reason = classify.ReasonPartialResult
+		case classify.OutcomeThrottled:
+			out.Reason = classify.ReasonThrottled
 		default:
 			out.Reason = "unknown_outcome"
 		}
@@ -1086,11 +1747,30 @@ func terminalError(ctx context.Context, opErr error, out classify.Outcome) error
 	return errors.New("recourse: operation failed")
 }
 
-func computeSleep(backoff time.Duration, pol policy.RetryPolicy, out classify.Outcome) time.Duration {
+// computeSleep returns the wait before the next attempt. backoff is the current point
+// on the policy's curve; reasons holds the current points on the curves of
+// pol.BackoffOverrides, keyed by reason, and is advanced when out's reason has one.
+func computeSleep(backoff time.Duration, pol policy.RetryPolicy, out classify.Outcome, reasons *map[string]time.Duration) time.Duration {
+	if out.Reason == ReasonRetryAfterHint {
+		// The operation asked for exactly this wait (see RetryAfter).
+		return out.BackoffOverride
+	}
+	maxBackoff, jitter := pol.MaxBackoff, pol.Jitter
+	if spec, ok := pol.BackoffOverrides[out.Reason]; ok {
+		cur, seen := (*reasons)[out.Reason]
+		if !seen {
+			cur = spec.InitialBackoff
+		}
+		if *reasons == nil {
+			*reasons = make(map[string]time.Duration, len(pol.BackoffOverrides))
+		}
+		(*reasons)[out.Reason] = nextBackoff(cur, spec.BackoffMultiplier, spec.MaxBackoff)
+		backoff, maxBackoff, jitter = cur, spec.MaxBackoff, spec.Jitter
+	}
 	if out.BackoffOverride > 0 {
-		return capBackoff(out.BackoffOverride, pol.MaxBackoff)
+		return capBackoff(out.BackoffOverride, maxBackoff)
 	}
-	return capBackoff(applyJitter(backoff, pol.Jitter), pol.MaxBackoff)
+	return capBackoff(applyJitter(backoff, jitter), maxBackoff)
 }
 
 func capBackoff(d, max time.Duration) time.Duration {
@@ -3,9 +3,12 @@ package retry
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	"github.com/aponysus/recourse/budget"
 	"github.com/aponysus/recourse/controlplane"
 	"github.com/aponysus/recourse/observe"
 	"github.com/aponysus/recourse/policy"
@@ -111,3 +114,111 @@ func BenchmarkDoValue_FastPath_NoTimeline(b *testing.B) {
 		_, _ = DoValue(ctx, exec, key, op)
 	}
 }
+
+func BenchmarkDoValue_FastPath_NoAttemptInfo(b *testing.B) {
+	key := policy.ParseKey("bench.fast_no_info")
+	provider := &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: benchmarkPolicy(1)}}
+	exec := NewExecutor(WithProvider(provider), WithAttemptInfo(false))
+	ctx := context.Background()
+	op := func(context.Context) (int, error) { return 1, nil }
+
+	b.ReportAllocs()
+	_, _ = DoValue(ctx, exec, key, op)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		_, _ = DoValue(ctx, exec, key, op)
+	}
+}
+
+func TestDoValue_FastPathAllocations(t *testing.T) {
+	key := policy.ParseKey("alloc.single_success")
+	provider := &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: benchmarkPolicy(1)}}
+	exec := NewExecutor(WithProvider(provider), WithAttemptInfo(false))
+	ctx := context.Background()
+	op := func(context.Context) (int, error) { return 1, nil }
+	_, _ = DoValue(ctx, exec, key, op)
+
+	if n := testing.AllocsPerRun(100, func() { _, _ = DoValue(ctx, exec, key, op) }); n != 0 {
+		t.Fatalf("successful single-attempt call allocates %v times, want 0", n)
+	}
+	_, _ = DoValue(ctx, exec, key, func(ctx context.Context) (int, error) {
+		if _, ok := observe.AttemptFromContext(ctx); ok {
+			t.Error("attempt info attached with WithAttemptInfo(false)")
+		}
+		return 1, nil
+	})
+}
+
+func TestDoValue_FastPathAttemptInfoByDefault(t *testing.T) {
+	key := policy.ParseKey("alloc.attempt_info")
+	exec := benchmarkExecutor(key, benchmarkPolicy(1), &observe.NoopObserver{})
+	ctx := context.Background()
+	op := func(ctx context.Context) (int, error) {
+		if _, ok := observe.AttemptFromContext(ctx); !ok {
+			t.Error("fast-path attempt has no attempt info")
+		}
+		return 1, nil
+	}
+	_, _ = DoValue(ctx, exec, key, op)
+
+	// The attempt context carrying observe.AttemptInfo is the only allocation.
+	if n := testing.AllocsPerRun(100, func() { _, _ = DoValue(ctx, exec, key, op) }); n > 1 {
+		t.Fatalf("successful single-attempt call allocates %v times, want at most 1", n)
+	}
+}
+
+// BenchmarkDoValue_Parallel_ManyKeys measures contention on the per-key circuit,
+// budget, and latency tracker state when many goroutines share one executor.
+func BenchmarkDoValue_Parallel_ManyKeys(b *testing.B) {
+	const numKeys = 64
+	keys := make([]policy.PolicyKey, numKeys)
+	policies := make(map[policy.PolicyKey]policy.EffectivePolicy, numKeys)
+	for i := range keys {
+		keys[i] = policy.ParseKey(fmt.Sprintf("bench.parallel_%d", i))
+		pol := benchmarkPolicy(1)
+		pol.Retry.Budget = policy.BudgetRef{Name: "bench", Cost: 1}
+		pol.Circuit = policy.CircuitPolicy{Enabled: true, Threshold: 5, Cooldown: time.Second}
+		policies[keys[i]] = pol
+	}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("bench", budget.UnlimitedBudget{})
+	exec := NewExecutor(
+		WithProvider(&controlplane.StaticProvider{Policies: policies}),
+		WithBudgetRegistry(budgets),
+		WithObserver(&observe.NoopObserver{}),
+	)
+	ctx := context.Background()
+	op := func(context.Context) (int, error) { return 1, nil }
+	for _, key := range keys {
+		_, _ = DoValue(ctx, exec, key, op)
+	}
+
+	var next atomic.Uint32
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int(next.Add(1))
+		for pb.Next() {
+			_, _ = DoValue(ctx, exec, keys[i%numKeys], op)
+			i++
+		}
+	})
+}
+
+func BenchmarkDoValue_TimeoutPerAttempt_Timeline(b *testing.B) {
+	key := policy.ParseKey("bench.timeout_timeline")
+	pol := benchmarkPolicy(1)
+	pol.Retry.TimeoutPerAttempt = time.Second
+	exec := benchmarkExecutor(key, pol, &observe.NoopObserver{})
+	baseCtx := context.Background()
+	op := func(context.Context) (int, error) { return 1, nil }
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		ctx, _ := observe.RecordTimeline(baseCtx)
+		_, _ = DoValue(ctx, exec, key, op)
+	}
+}
@@ -249,6 +249,41 @@ func TestExecutor_TimeoutPerAttempt_Retries(t *testing.T) {
 	}
 }
 
+func TestExecutor_TimeoutPerAttempt_TimelinePath(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key: key,
+		Retry: policy.RetryPolicy{
+			MaxAttempts:       2,
+			TimeoutPerAttempt: 5 * time.Millisecond,
+		},
+		// The circuit forces the timeline path.
+		Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 100, Cooldown: time.Second},
+	})
+
+	var attemptCtxs []context.Context
+	_, tl, _, err := doValueWithTimeline(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		attemptCtxs = append(attemptCtxs, ctx)
+		if len(attemptCtxs) == 1 {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("attempt context has no deadline")
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+	if len(tl.Attempts) != 2 {
+		t.Fatalf("attempts=%d, want 2", len(tl.Attempts))
+	}
+	if attemptCtxs[1].Err() == nil {
+		t.Fatalf("attempt context not canceled after the attempt returned")
+	}
+}
+
 func TestExecutor_OverallTimeout_StopsLoop(t *testing.T) {
 	key := policy.PolicyKey{Name: "x"}
 	exec := newTestExecutor(t, key, policy.EffectivePolicy{
@@ -433,3 +468,53 @@ type stubProvider struct {
 func (p stubProvider) GetEffectivePolicy(context.Context, policy.PolicyKey) (policy.EffectivePolicy, error) {
 	return p.pol, p.err
 }
+
+func TestAttemptCapacity(t *testing.T) {
+	hedged := func(attempts, hedges int) policy.EffectivePolicy {
+		return policy.EffectivePolicy{
+			Retry: policy.RetryPolicy{MaxAttempts: attempts},
+			Hedge: policy.HedgePolicy{Enabled: true, MaxHedges: hedges},
+		}
+	}
+	tests := []struct {
+		name string
+		pol  policy.EffectivePolicy
+		want int
+	}{
+		{"zero attempts", policy.EffectivePolicy{}, 1},
+		{"retries only", policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 3}}, 3},
+		{"hedge disabled", policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 3}, Hedge: policy.HedgePolicy{MaxHedges: 2}}, 3},
+		{"hedged", hedged(3, 2), 9},
+		{"capped attempts", policy.EffectivePolicy{Retry: policy.RetryPolicy{MaxAttempts: 1 << 30}}, maxPreallocAttempts},
+		{"capped product", hedged(1<<20, 1<<20), maxPreallocAttempts},
+	}
+	for _, tt := range tests {
+		if got := attemptCapacity(tt.pol); got != tt.want {
+			t.Errorf("%s: attemptCapacity = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestDoValue_TimelinePreallocatesAttempts(t *testing.T) {
+	key := policy.ParseKey("svc.Prealloc")
+	pol := policy.EffectivePolicy{Key: key, Retry: policy.RetryPolicy{MaxAttempts: 4}}
+	exec := NewExecutor(WithProvider(&controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: pol}}))
+
+	_, tl, _, err := doValueWithTimeline(context.Background(), exec, key, func(context.Context) (int, error) { return 1, nil })
+	if err != nil {
+		t.Fatalf("doValueWithTimeline: %v", err)
+	}
+	if len(tl.Attempts) != 1 || cap(tl.Attempts) != 4 {
+		t.Fatalf("Attempts len=%d cap=%d, want 1 and 4", len(tl.Attempts), cap(tl.Attempts))
+	}
+}
+
+func TestDoValue_NilInterfaceResultOnTimelinePath(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{Key: key, Retry: policy.RetryPolicy{MaxAttempts: 1}})
+
+	val, _, _, err := doValueWithTimeline(context.Background(), exec, key, func(context.Context) (any, error) { return nil, nil })
+	if err != nil || val != nil {
+		t.Fatalf("val=%v err=%v, want nil, nil", val, err)
+	}
+}
@@ -0,0 +1,171 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// ReasonFanOutBudgetExhausted is the budget decision reason for retries and hedges
+// denied because a FanOut's shared RetryBudget is spent.
+const ReasonFanOutBudgetExhausted = "fanout_budget_exhausted"
+
+// KeyedOp is one operation of a FanOut, run under its own policy key.
+type KeyedOp struct {
+	Key policy.PolicyKey
+	Op  OperationValue[any]
+}
+
+// FanOutOptions configures FanOut.
+type FanOutOptions struct {
+	// MaxConcurrency limits how many operations run at once; <= 0 runs them all at once.
+	MaxConcurrency int
+	// Timeout, if set, bounds the whole fan-out.
+	Timeout time.Duration
+	// FailFast cancels the remaining operations after the first failure.
+	FailFast bool
+	// RetryBudget, if > 0, caps the retries and hedges of all operations together, in
+	// addition to each policy's own budget: once spent, further retries are denied with
+	// ReasonFanOutBudgetExhausted. This keeps a failing dependency from multiplying a
+	// wide fan-out's load by MaxAttempts.
+	RetryBudget int
+}
+
+// FanOutResult is the outcome of one KeyedOp.
+type FanOutResult struct {
+	Key      policy.PolicyKey
+	Value    any
+	Err      error
+	Timeline observe.Timeline
+}
+
+// FanOutSummary combines the results of a FanOut.
+type FanOutSummary struct {
+	// Results are in the order of the requests. Operations that never started (after a
+	// FailFast cancellation or the fan-out's context ending) carry the context error
+	// and an empty timeline.
+	Results   []FanOutResult
+	Succeeded int
+	Failed    int
+	// Attempts counts attempt records across all timelines, including hedges and
+	// denied attempts.
+	Attempts int
+	// RetriesDenied counts retries and hedges denied by RetryBudget.
+	RetriesDenied int
+	Duration      time.Duration
+}
+
+// FanOut runs requests concurrently through exec, each as its own call under its key,
+// for scatter-gather callers that would otherwise use an errgroup around exec.Do and
+// lose the shared limits. Operations share a concurrency limit and, optionally, a retry
+// budget, and every call's timeline is returned in the summary.
+//
+// FanOut waits for every started operation. It returns an error joining the failed
+// operations' errors (each a *CallError naming its key), or nil if all succeeded.
+func FanOut(ctx context.Context, exec *Executor, requests []KeyedOp, opts FanOutOptions) (FanOutSummary, error) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var timing timeSource
+	if exec != nil {
+		timing = exec.timing
+	}
+	start := timing.now()
+	sum := FanOutSummary{Results: make([]FanOutResult, len(requests))}
+
+	var cancel context.CancelFunc
+	if opts.Timeout > 0 {
+		ctx, cancel = timing.withTimeout(ctx, opts.Timeout)
+	} else {
+		ctx, cancel = context.WithCancel(ctx)
+	}
+	defer cancel()
+
+	var shared *fanOutBudget
+	if opts.RetryBudget > 0 {
+		shared = &fanOutBudget{}
+		shared.remaining.Store(int64(opts.RetryBudget))
+		ctx = context.WithValue(ctx, fanOutBudgetKey{}, shared)
+	}
+
+	limit := len(requests)
+	if opts.MaxConcurrency > 0 && opts.MaxConcurrency < limit {
+		limit = opts.MaxConcurrency
+	}
+	sem := make(chan struct{}, max(limit, 1))
+
+	var wg sync.WaitGroup
+	for i, req := range requests {
+		sum.Results[i].Key = req.Key
+		select {
+		case sem <- struct{}{}:
+			if err := ctx.Err(); err != nil {
+				<-sem
+				sum.Results[i].Err = err
+				continue
+			}
+		case <-ctx.Done():
+			sum.Results[i].Err = ctx.Err()
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			val, tl, err := doValueInternal(ctx, exec, req.Key, req.Op, true)
+			sum.Results[i] = FanOutResult{Key: req.Key, Value: val, Err: err, Timeline: tl}
+			if err != nil && opts.FailFast {
+				cancel()
+			}
+		}()
+	}
+	wg.Wait()
+
+	errs := make([]error, 0, len(requests))
+	for _, res := range sum.Results {
+		sum.Attempts += len(res.Timeline.Attempts)
+		if res.Err != nil {
+			sum.Failed++
+			errs = append(errs, res.Err)
+		} else {
+			sum.Succeeded++
+		}
+	}
+	if shared != nil {
+		sum.RetriesDenied = int(shared.denied.Load())
+	}
+	sum.Duration = timing.since(start)
+	return sum, errors.Join(errs...)
+}
+
+type fanOutBudgetKey struct{}
+
+// fanOutBudget is a FanOut's shared retry budget.
+type fanOutBudget struct {
+	remaining atomic.Int64
+	denied    atomic.Int64
+}
+
+// allowFanOutRetry gates a retry or hedge on the FanOut budget carried by ctx, if any.
+// First attempts never consult it, so calls that succeed first time pay no context
+// lookup.
+func allowFanOutRetry(ctx context.Context, attemptIdx int, kind budget.AttemptKind) bool {
+	if attemptIdx == 0 && kind != budget.KindHedge {
+		return true
+	}
+	b, _ := ctx.Value(fanOutBudgetKey{}).(*fanOutBudget)
+	if b == nil {
+		return true
+	}
+	if b.remaining.Add(-1) >= 0 {
+		return true
+	}
+	b.denied.Add(1)
+	return false
+}
@@ -0,0 +1,122 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+func newFanOutExecutor(maxAttempts int, keys ...policy.PolicyKey) *Executor {
+	policies := make(map[policy.PolicyKey]policy.EffectivePolicy, len(keys))
+	for _, key := range keys {
+		policies[key] = policy.EffectivePolicy{Key: key, Retry: policy.RetryPolicy{MaxAttempts: maxAttempts}}
+	}
+	exec := NewExecutor(WithProvider(&controlplane.StaticProvider{Policies: policies}))
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+	return exec
+}
+
+func TestFanOut_RunsHeterogeneousOps(t *testing.T) {
+	users, orders := policy.ParseKey("users.Get"), policy.ParseKey("orders.List")
+	exec := newFanOutExecutor(2, users, orders)
+
+	errOrders := errors.New("orders down")
+	sum, err := FanOut(context.Background(), exec, []KeyedOp{
+		{Key: users, Op: func(context.Context) (any, error) { return "alice", nil }},
+		{Key: orders, Op: func(context.Context) (any, error) { return nil, errOrders }},
+	}, FanOutOptions{})
+
+	if !errors.Is(err, errOrders) {
+		t.Fatalf("err=%v, want it to wrap %v", err, errOrders)
+	}
+	var ce *CallError
+	if !errors.As(err, &ce) || ce.Key != orders {
+		t.Fatalf("err=%v, want a CallError for %v", err, orders)
+	}
+	if sum.Succeeded != 1 || sum.Failed != 1 || sum.Attempts != 3 {
+		t.Fatalf("summary succeeded=%d failed=%d attempts=%d, want 1, 1, 3", sum.Succeeded, sum.Failed, sum.Attempts)
+	}
+	if r := sum.Results[0]; r.Key != users || r.Value != "alice" || r.Err != nil || len(r.Timeline.Attempts) != 1 {
+		t.Fatalf("Results[0]=%+v", r)
+	}
+	if r := sum.Results[1]; r.Key != orders || len(r.Timeline.Attempts) != 2 {
+		t.Fatalf("Results[1] key=%v attempts=%d, want %v and 2", r.Key, len(r.Timeline.Attempts), orders)
+	}
+}
+
+func TestFanOut_LimitsConcurrency(t *testing.T) {
+	key := policy.ParseKey("svc.Get")
+	exec := newFanOutExecutor(1, key)
+
+	var running, peak atomic.Int32
+	op := func(context.Context) (any, error) {
+		n := running.Add(1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(2 * time.Millisecond)
+		running.Add(-1)
+		return nil, nil
+	}
+	reqs := make([]KeyedOp, 10)
+	for i := range reqs {
+		reqs[i] = KeyedOp{Key: key, Op: op}
+	}
+
+	if _, err := FanOut(context.Background(), exec, reqs, FanOutOptions{MaxConcurrency: 3}); err != nil {
+		t.Fatalf("FanOut: %v", err)
+	}
+	if p := peak.Load(); p > 3 || p < 1 {
+		t.Fatalf("peak concurrency=%d, want 1..3", p)
+	}
+}
+
+func TestFanOut_SharedRetryBudget(t *testing.T) {
+	key := policy.ParseKey("svc.Flaky")
+	exec := newFanOutExecutor(3, key)
+
+	var calls atomic.Int32
+	op := func(context.Context) (any, error) {
+		calls.Add(1)
+		return nil, errors.New("flaky")
+	}
+	reqs := []KeyedOp{{Key: key, Op: op}, {Key: key, Op: op}, {Key: key, Op: op}}
+
+	sum, err := FanOut(context.Background(), exec, reqs, FanOutOptions{MaxConcurrency: 1, RetryBudget: 2})
+	if err == nil {
+		t.Fatalf("FanOut returned nil error")
+	}
+	// Three first attempts plus the two retries the shared budget allows.
+	if got := calls.Load(); got != 5 {
+		t.Fatalf("calls=%d, want 5", got)
+	}
+	if sum.RetriesDenied != 2 {
+		t.Fatalf("RetriesDenied=%d, want 2", sum.RetriesDenied)
+	}
+}
+
+func TestFanOut_FailFastSkipsRemaining(t *testing.T) {
+	key := policy.ParseKey("svc.Get")
+	exec := newFanOutExecutor(1, key)
+
+	var calls atomic.Int32
+	reqs := []KeyedOp{
+		{Key: key, Op: func(context.Context) (any, error) { calls.Add(1); return nil, errors.New("boom") }},
+		{Key: key, Op: func(context.Context) (any, error) { calls.Add(1); return nil, nil }},
+	}
+	sum, _ := FanOut(context.Background(), exec, reqs, FanOutOptions{MaxConcurrency: 1, FailFast: true})
+	if calls.Load() != 1 {
+		t.Fatalf("calls=%d, want 1", calls.Load())
+	}
+	if !errors.Is(sum.Results[1].Err, context.Canceled) || sum.Failed != 2 {
+		t.Fatalf("Results[1].Err=%v failed=%d, want context.Canceled and 2", sum.Results[1].Err, sum.Failed)
+	}
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// FaultInjector injects faults into the attempts of an executor's calls, for game days
+// and chaos tests; chaos.Injector implements it. See WithFaultInjection.
+type FaultInjector interface {
+	// Inject runs before each attempt of a call for key, hedges included. It may wait or
+	// panic; a non-nil error fails the attempt with it instead of running the operation.
+	Inject(ctx context.Context, key policy.PolicyKey) error
+
+	// CircuitForcedOpen reports whether calls for key are rejected as if its circuit
+	// were open.
+	CircuitForcedOpen(key policy.PolicyKey) bool
+}
+
+// WithFaultInjection makes the executor inject fi's faults into every call, without
+// wrapping operations. Injected errors are classified like any other, so they exercise
+// the retries, hedges, budgets, and circuit breakers of the policy under test; a key
+// whose circuit is forced open fails fast with CircuitOpenError.
+//
+// If fi also watches control-plane resources (like chaos.Injector) and the executor's
+// provider supplies them, the executor feeds it the provider's "faults", so game days
+// can be orchestrated centrally. Leave fault injection disabled in production builds;
+// a nil fi disables it.
+func WithFaultInjection(fi FaultInjector) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.FaultInjector = fi
+	}
+}
+
+// faultWatcher is implemented by fault injectors that apply control-plane faults.
+type faultWatcher interface {
+	Watch(rp controlplane.ResourceProvider)
+}
+
+// withFaults wraps op to run after fi's faults for key.
+func withFaults[T any](fi FaultInjector, key policy.PolicyKey, op OperationValue[T]) OperationValue[T] {
+	return func(ctx context.Context) (T, error) {
+		if err := fi.Inject(ctx, key); err != nil {
+			var zero T
+			return zero, err
+		}
+		return op(ctx)
+	}
+}
+
+// circuitForcedOpen reports whether fault injection forces key's circuit open.
+func (e *Executor) circuitForcedOpen(key policy.PolicyKey) bool {
+	return e.faults != nil && e.faults.CircuitForcedOpen(key)
+}
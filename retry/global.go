@@ -3,22 +3,40 @@ package retry
 import (
 	"log"
 	"sync"
+	"sync/atomic"
 )
 
 var (
-	globalExec *Executor
-	globalOnce sync.Once
+	globalExec atomic.Pointer[Executor]
+	globalMu   sync.Mutex
 )
 
 // DefaultExecutor returns the shared, lazy-initialized default executor.
-// It uses NewDefaultExecutor() if SetGlobal has not been called.
+// It uses NewDefaultExecutor() if neither SetGlobal nor SetDefault has been called.
 func DefaultExecutor() *Executor {
-	globalOnce.Do(func() {
-		if globalExec == nil {
-			globalExec = NewDefaultExecutor()
-		}
-	})
-	return globalExec
+	if exec := globalExec.Load(); exec != nil {
+		return exec
+	}
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if exec := globalExec.Load(); exec != nil {
+		return exec
+	}
+	exec := NewDefaultExecutor()
+	globalExec.Store(exec)
+	return exec
+}
+
+// SetDefault replaces the default executor returned by DefaultExecutor. Unlike
+// SetGlobal it takes effect at any time; calls already running keep the executor they
+// started with. A nil exec is ignored.
+func SetDefault(exec *Executor) {
+	if exec == nil {
+		return
+	}
+	globalMu.Lock()
+	globalExec.Store(exec)
+	globalMu.Unlock()
 }
 
 // SetGlobal configures the default executor.
@@ -28,15 +46,9 @@ func SetGlobal(exec *Executor) {
 	if exec == nil {
 		return
 	}
-
-	// Check if already initialized to provide a warning.
-	// Note: This check is not strictly race-free vs DefaultExecutor, but sufficient for startup-time verification.
-	if globalExec != nil {
+	globalMu.Lock()
+	defer globalMu.Unlock()
+	if !globalExec.CompareAndSwap(nil, exec) {
 		log.Printf("retry: SetGlobal called after global executor already initialized; ignoring.")
-		return
 	}
-
-	globalOnce.Do(func() {
-		globalExec = exec
-	})
 }
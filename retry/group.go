@@ -3,7 +3,6 @@ package retry
 import (
 	"context"
 	"errors"
-	"sync/atomic"
 	"time"
 
 	"github.com/aponysus/recourse/budget"
@@ -32,10 +31,10 @@ func (e *Executor) doRetryGroup(
 	// Generic helper for concurrent operations.
 	op OperationValue[any],
 	pol policy.EffectivePolicy,
+	deps *callDeps,
 	retryIdx int,
-	classifier classify.Classifier,
-	cmeta classifierMeta,
 	lastBackoff time.Duration,
+	lastBackoffActual time.Duration,
 	recordAttempt func(context.Context, observe.AttemptRecord),
 ) (any, error, classify.Outcome, bool) {
 
@@ -45,220 +44,204 @@ func (e *Executor) doRetryGroup(
 	if pol.Hedge.Enabled {
 		maxHedges = pol.Hedge.MaxHedges
 	}
+	var idemKey string
+	if maxHedges > 0 && pol.Hedge.IdempotencyKeys {
+		idemKey = e.idempotencyKeys(ctx, key)
+	}
+	slice := e.attemptSlice(ctx, pol.Retry, max(pol.Retry.MaxAttempts, 1)-retryIdx)
+
+	// run executes one attempt (the primary when idx is 0, else a hedge) under ctx.
+	// In a hedged group, fl tracks the attempt; the attempt's own record is dropped if
+	// the group has already accounted for it (see abandon).
+	run := func(ctx context.Context, idx int, isHedge bool, fl *inflight) groupResult[any] {
+		start := e.clock()
+		mono := e.timing.now()
+
+		// Budget Check
+		budgetKind := budget.KindRetry
+		if isHedge {
+			budgetKind = budget.KindHedge
+		}
 
-	results := make(chan groupResult[any], 1+maxHedges)
-
-	// Group-level context for cancellation.
-	groupCtx, cancelGroup := context.WithCancel(ctx)
-	defer cancelGroup()
-
-	// Track active attempts
-	var activeAttempts atomic.Int32
-	var attemptsLaunched atomic.Int32
-
-	// Helper to launch attempt
-	launch := func(idx int, isHedge bool) {
-		activeAttempts.Add(1)
-		attemptsLaunched.Add(1)
-
-		go func() {
-			defer activeAttempts.Add(-1)
-
-			start := e.clock()
-
-			// Budget Check
-			budgetKind := budget.KindRetry
-			budgetRef := pol.Retry.Budget
+		// Check budget for this attempt.
+		decision, allowed := e.allowCallAttempt(ctx, key, deps.budgetFor(budgetKind), retryIdx, budgetKind) // retryIdx is constant for group
+		if !allowed {
+			// Record budget denial
+			rec := observe.AttemptRecord{
+				Attempt:        retryIdx,
+				StartTime:      start,
+				EndTime:        e.clock(),
+				Duration:       e.timing.since(mono),
+				IsHedge:        isHedge,
+				HedgeIndex:     idx, // 0 for primary, 1..N for hedges
+				IdempotencyKey: idemKey,
+				Outcome:        classify.Outcome{Kind: classify.OutcomeAbort, Reason: decision.Reason},
+				BudgetAllowed:  false,
+				BudgetReason:   decision.Reason,
+				Backoff:        lastBackoff, // For primary only?
+				BackoffActual:  lastBackoffActual,
+			}
 			if isHedge {
-				budgetKind = budget.KindHedge
-				budgetRef = pol.Hedge.Budget
+				rec.Backoff = 0 // Hedges don't strictly have "backoff" from previous retry
+				rec.BackoffActual = 0
 			}
 
-			// Check budget for this attempt.
-
-			// AllowAttempt
-			decision, allowed := e.allowAttempt(groupCtx, key, budgetRef, retryIdx, budgetKind) // retryIdx is constant for group
-			if !allowed {
-				// Record budget denial
-				rec := observe.AttemptRecord{
-					Attempt:       retryIdx,
-					StartTime:     start,
-					EndTime:       e.clock(),
-					IsHedge:       isHedge,
-					HedgeIndex:    idx, // 0 for primary, 1..N for hedges
-					Outcome:       classify.Outcome{Kind: classify.OutcomeAbort, Reason: decision.Reason},
-					BudgetAllowed: false,
-					BudgetReason:  decision.Reason,
-					Backoff:       lastBackoff, // For primary only?
-				}
-				if isHedge {
-					rec.Backoff = 0 // Hedges don't strictly have "backoff" from previous retry
-				}
-
-				recordAttempt(groupCtx, rec)
-				results <- groupResult[any]{
-					err:     errors.New(decision.Reason),
-					outcome: classify.Outcome{Kind: classify.OutcomeAbort, Reason: decision.Reason},
-					start:   start,
-					end:     e.clock(),
-					isHedge: isHedge,
-					idx:     idx,
-				}
-				return
+			if fl == nil || fl.accounted.CompareAndSwap(false, true) {
+				recordAttempt(ctx, rec)
+			}
+			return groupResult[any]{
+				err:     errors.New(decision.Reason),
+				outcome: classify.Outcome{Kind: classify.OutcomeAbort, Reason: decision.Reason},
+				start:   start,
+				end:     e.clock(),
+				isHedge: isHedge,
+				idx:     idx,
 			}
+		}
 
-			release := decision.Release
-			defer func() {
-				if release != nil {
-					release()
-				}
-			}()
+		release := decision.Release
+		defer func() {
+			if release != nil {
+				release()
+			}
+		}()
+		if fl != nil {
+			defer e.watchStraggler(ctx, release)()
+		}
 
-			// Attempt Context
-			attemptCtx := groupCtx
+		// Attempt Context; a timer-backed context only when the policy sets a timeout.
+		attemptCtx := ctx
+		if pol.Retry.TimeoutPerAttempt > 0 || !slice.IsZero() {
 			var cancelAttempt context.CancelFunc
-			if pol.Retry.TimeoutPerAttempt > 0 {
-				attemptCtx, cancelAttempt = context.WithTimeout(groupCtx, pol.Retry.TimeoutPerAttempt)
-			} else {
-				// Ensure we can cancel this specific attempt if needed?
-				// groupCtx handles it.
-				cancelAttempt = func() {}
-			}
+			attemptCtx, cancelAttempt = e.withAttemptTimeout(ctx, pol.Retry.TimeoutPerAttempt, slice)
 			defer cancelAttempt()
+		}
 
-			attemptCtx = observe.WithAttemptInfo(attemptCtx, observe.AttemptInfo{
-				RetryIndex: retryIdx,
-				Attempt:    retryIdx,
-				IsHedge:    isHedge,
-				HedgeIndex: idx,
-				PolicyID:   pol.ID,
+		attemptCtx = observe.WithAttemptInfo(attemptCtx, observe.AttemptInfo{
+			RetryIndex:     retryIdx,
+			Attempt:        retryIdx,
+			IsHedge:        isHedge,
+			HedgeIndex:     idx,
+			PolicyID:       pol.ID,
+			IdempotencyKey: idemKey,
+		})
+
+		if isHedge {
+			e.observerFor(attemptCtx).OnHedgeSpawn(attemptCtx, key, observe.AttemptRecord{
+				Attempt:        retryIdx,
+				IsHedge:        true,
+				HedgeIndex:     idx,
+				IdempotencyKey: idemKey,
 			})
+		}
 
-			if isHedge {
-				e.observer.OnHedgeSpawn(attemptCtx, key, observe.AttemptRecord{
-					Attempt:    retryIdx,
-					IsHedge:    true,
-					HedgeIndex: idx,
-				})
-			}
-
-			// Execute
-			var val any
-			var err error
-			val, err = op(attemptCtx)
+		// Execute
+		var val any
+		var err error
+		val, err = op(attemptCtx)
 
-			end := e.clock()
+		end := e.clock()
+		duration := e.timing.since(mono)
 
-			// Classify
-			outcome, panicErr := classifyWithRecovery(e.recoverPanics, classifier, val, err, key)
-			annotateClassifierFallback(&outcome, cmeta)
+		// Classify
+		outcome, panicErr := classifyWithRecovery(e.recoverPanics, deps.classifier, val, err, key)
+		annotateClassifierFallback(&outcome, deps.cmeta)
+		if outcome.Kind == classify.OutcomeThrottled {
+			e.throttle.throttled(key, outcome.BackoffOverride, e.clock())
+		}
 
-			// Record
-			rec := observe.AttemptRecord{
-				Attempt:       retryIdx,
-				StartTime:     start,
-				EndTime:       end,
-				Outcome:       outcome,
-				Err:           err,
-				Backoff:       lastBackoff, // Only meaningful for primary
-				BudgetAllowed: true,
-				BudgetReason:  decision.Reason,
-				IsHedge:       isHedge,
-				HedgeIndex:    idx,
-			}
-			if isHedge {
-				rec.Backoff = 0
-			}
+		// Record
+		rec := observe.AttemptRecord{
+			Attempt:        retryIdx,
+			StartTime:      start,
+			EndTime:        end,
+			Duration:       duration,
+			Outcome:        outcome,
+			Err:            err,
+			Backoff:        lastBackoff, // Only meaningful for primary
+			BackoffActual:  lastBackoffActual,
+			RetryAfter:     outcome.BackoffOverride,
+			BudgetAllowed:  true,
+			BudgetReason:   decision.Reason,
+			IsHedge:        isHedge,
+			HedgeIndex:     idx,
+			IdempotencyKey: idemKey,
+		}
+		if isHedge {
+			rec.Backoff = 0
+			rec.BackoffActual = 0
+		}
+		if fl == nil || fl.accounted.CompareAndSwap(false, true) {
 			recordAttempt(attemptCtx, rec)
-
-			res := groupResult[any]{
-				val:      val,
-				err:      err,
-				outcome:  outcome,
-				start:    start,
-				end:      end,
-				isHedge:  isHedge,
-				idx:      idx,
-				panicErr: panicErr,
-			}
-
-			// Send result
-			// Non-blocking send? No, buffered channel.
-			results <- res
-		}()
-	}
-
-	// 1. Launch Primary
-	launch(0, false)
-
-	// 2. Hedge Loop
-	start := e.clock()
-	go func() {
-		// Assuming single threaded coordination for spawning
-		if !pol.Hedge.Enabled {
-			return
 		}
 
-		// Find trigger
-		var trig hedge.Trigger
-		if pol.Hedge.TriggerName != "" && e.triggers != nil {
-			var ok bool
-			trig, ok = e.triggers.Get(pol.Hedge.TriggerName)
-			_ = ok // If not found, fall back to FixedDelay? Or just rely on loop?
+		return groupResult[any]{
+			val:      val,
+			err:      err,
+			outcome:  outcome,
+			start:    start,
+			end:      end,
+			isHedge:  isHedge,
+			idx:      idx,
+			panicErr: panicErr,
 		}
+	}
 
-		// Fallback to fixed delay if no trigger found or Logic
-		if trig == nil {
-			trig = hedge.FixedDelayTrigger{Delay: pol.Hedge.HedgeDelay}
+	// Without hedges there is nothing to race: run the attempt on the caller's goroutine
+	// and skip the group context, result channel, and hedge loop.
+	if maxHedges <= 0 {
+		res := run(ctx, 0, false, nil)
+		if res.outcome.Kind == classify.OutcomeSuccess {
+			return res.val, nil, res.outcome, true
 		}
+		return res.val, res.err, res.outcome, false
+	}
 
-		// Loop
-		hedgesLaunched := 0
-		// Use a Timer based on nextCheck values from the trigger.
-		// Start with immediate check.
-		timer := time.NewTimer(0)
-		defer timer.Stop()
-
-		for {
-			select {
-			case <-groupCtx.Done():
-				return
-			case <-timer.C:
-				if hedgesLaunched >= maxHedges {
-					return
-				}
+	results := make(chan groupResult[any], 1+maxHedges)
 
-				state := hedge.HedgeState{
-					AttemptStart:     start,
-					AttemptsLaunched: 1 + hedgesLaunched, // Primary + previous hedges
-					MaxHedges:        maxHedges,
-					Elapsed:          e.clock().Sub(start),
-					Snapshot:         e.getTracker(key).Snapshot(),
-					HedgeDelay:       pol.Hedge.HedgeDelay,
-				}
+	// Group-level context for cancellation.
+	groupCtx, cancelGroup := context.WithCancel(ctx)
+	defer cancelGroup()
 
-				should, nextCheck := trig.ShouldSpawnHedge(state)
-				if should {
-					hedgesLaunched++
-					launch(hedgesLaunched, true)
+	// The coordinator below is the group's only long-lived part: it launches the
+	// attempts, spawns hedges, and collects results. Each attempt runs on a goroutine
+	// supervised by the executor's reaper. The group returns once every attempt it
+	// launched has reported, or with a winner (or terminal result, or canceled context),
+	// detaching the attempts still running to the reaper, so none carries over into the
+	// next retry unaccounted for.
+	attempts := make([]*inflight, 0, 1+maxHedges)
+	active := 0 // Attempts launched that have not reported.
+	cancelReason := CancelReasonLost
+	defer func() {
+		e.reaper.detach(attempts)
+		e.abandon(ctx, key, retryIdx, idemKey, attempts, cancelReason, recordAttempt)
+	}()
 
-					// Re-check immediately to allow back-to-back hedges.
-					if hedgesLaunched < maxHedges {
-						timer.Reset(0)
-					}
-					continue
-				}
+	launch := func(idx int, isHedge bool) {
+		fl := &inflight{start: e.clock(), mono: e.timing.now(), isHedge: isHedge, idx: idx}
+		attempts = append(attempts, fl)
+		active++
+		e.reaper.start()
+		go func() {
+			defer e.reaper.exit(fl)
+			// Buffered for every attempt, so the send never blocks.
+			results <- run(groupCtx, idx, isHedge, fl)
+		}()
+	}
 
-				// If we shouldn't spawn yet, wait using the returned nextCheck.
-				if nextCheck <= 0 {
-					// Trigger didn't return a wait time (e.g. waiting for stats or invalid).
-					// Poll to avoid stalling if stats might appear.
-					nextCheck = 25 * time.Millisecond
-				}
-				timer.Reset(nextCheck)
-			}
-		}
-	}()
+	// 1. Launch Primary
+	launch(0, false)
+
+	// 2. Hedge trigger, resolved with the call's budgets.
+	trig := deps.trigger
+	if trig == nil {
+		trig = e.resolveTrigger(pol.Hedge)
+	}
+	start := e.clock()
+	hedgesLaunched := 0
+	// Wait based on nextCheck values from the trigger, starting with an immediate
+	// check; nil once no more hedges will be spawned.
+	wait := e.after(0)
 
 	// Wait for results. We return as soon as:
 	// 1. A success is received (wins).
@@ -267,11 +250,49 @@ func (e *Executor) doRetryGroup(
 	// 4. Fail-fast threshold is reached.
 
 	var lastRel groupResult[any]
+	var partial *groupResult[any] // First partial result, preferred over later failures.
 	failures := 0
 
 	for {
 		select {
+		case <-wait:
+			wait = nil
+			// Hedges are extra attempts; stop spawning them to an unhealthy target.
+			if _, unhealthy := e.health.Unhealthy(key); unhealthy {
+				continue
+			}
+
+			state := hedge.HedgeState{
+				AttemptStart:     start,
+				AttemptsLaunched: 1 + hedgesLaunched, // Primary + previous hedges
+				MaxHedges:        maxHedges,
+				Elapsed:          e.clock().Sub(start),
+				Snapshot:         e.getTracker(key).Snapshot(),
+				HedgeDelay:       pol.Hedge.HedgeDelay,
+			}
+
+			should, nextCheck := trig.ShouldSpawnHedge(state)
+			if should {
+				hedgesLaunched++
+				launch(hedgesLaunched, true)
+
+				// Re-check immediately to allow back-to-back hedges.
+				if hedgesLaunched < maxHedges {
+					wait = e.after(0)
+				}
+				continue
+			}
+
+			// If we shouldn't spawn yet, wait using the returned nextCheck.
+			if nextCheck <= 0 {
+				// Trigger didn't return a wait time (e.g. waiting for stats or invalid).
+				// Poll to avoid stalling if stats might appear.
+				nextCheck = 25 * time.Millisecond
+			}
+			wait = e.after(nextCheck)
+
 		case res := <-results:
+			active--
 			if res.outcome.Kind == classify.OutcomeSuccess {
 				return res.val, nil, res.outcome, true
 			}
@@ -279,16 +300,18 @@ func (e *Executor) doRetryGroup(
 			// It's a failure
 			lastRel = res
 			failures++
+			if res.outcome.Kind == classify.OutcomePartial && partial == nil {
+				partial = &res
+			}
 
 			// Fail Fast check
 			if pol.Hedge.CancelOnFirstTerminal {
 				if res.outcome.Kind == classify.OutcomeNonRetryable || res.outcome.Kind == classify.OutcomeAbort {
+					cancelReason = CancelReasonTerminal
 					return res.val, res.err, res.outcome, false
 				}
 			}
 
-			// Check if we are done
-			active := activeAttempts.Load()
 			// Check if all active attempts have finished.
 			// If active=0, it means all launched attempts (primary + any hedges so far) have failed.
 			// While valid hedges *might* spawn later if we waited, failure of the Primary
@@ -297,12 +320,16 @@ func (e *Executor) doRetryGroup(
 
 			if active == 0 {
 				// All launched attempts failed.
+				if partial != nil {
+					lastRel = *partial
+				}
 				return lastRel.val, lastRel.err, lastRel.outcome, false
 			}
 
 			// If active > 0, we have hope. Continue waiting.
 
 		case <-ctx.Done(): // Outer context cancelled
+			cancelReason = CancelReasonContext
 			return nil, ctx.Err(), classify.Outcome{Kind: classify.OutcomeAbort, Reason: "context_canceled"}, false
 		}
 	}
@@ -0,0 +1,15 @@
+package retry
+
+import (
+	"github.com/aponysus/recourse/policy"
+)
+
+// checkHealth returns a *TargetUnhealthyError wrapping lastErr if a health source
+// marked key unhealthy, so the retry is skipped.
+func (e *Executor) checkHealth(key policy.PolicyKey, lastErr error) error {
+	st, unhealthy := e.health.Unhealthy(key)
+	if !unhealthy {
+		return nil
+	}
+	return &TargetUnhealthyError{Reason: st.Reason, Err: lastErr}
+}
@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/health"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestHealth_PausesRetries(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "svc", Name: "Get"}
+	reg := health.NewRegistry()
+	exec := NewExecutor(
+		WithHealth(reg),
+		WithPolicyKey(key, policy.MaxAttempts(3), policy.Backoff(time.Microsecond, time.Microsecond, 1)),
+	)
+	errDown := errors.New("connection refused")
+
+	for _, withTimeline := range []bool{false, true} {
+		reg.MarkUnhealthy(policy.PolicyKey{Namespace: "svc"}, "not_serving")
+
+		ctx := context.Background()
+		var capture *observe.TimelineCapture
+		if withTimeline {
+			ctx, capture = observe.RecordTimeline(ctx)
+		}
+
+		calls := 0
+		err := exec.Do(ctx, key, func(context.Context) error {
+			calls++
+			return errDown
+		})
+		if calls != 1 {
+			t.Fatalf("timeline=%v: calls = %d, want 1", withTimeline, calls)
+		}
+		if !errors.Is(err, ErrTargetUnhealthy) || !errors.Is(err, errDown) || errors.Is(err, ErrAttemptsExhausted) {
+			t.Fatalf("timeline=%v: err = %v", withTimeline, err)
+		}
+		var ce *CallError
+		if !errors.As(err, &ce) || ce.LastReason != health.ReasonTargetUnhealthy || ce.Attempts != 1 {
+			t.Fatalf("timeline=%v: CallError = %+v", withTimeline, ce)
+		}
+		if withTimeline {
+			if tl := capture.Timeline(); tl == nil || tl.Attributes["target_unhealthy"] != "not_serving" {
+				t.Fatalf("timeline = %+v", tl)
+			}
+		}
+
+		// Retries resume once the target recovers.
+		reg.MarkHealthy(policy.PolicyKey{Namespace: "svc"})
+		calls = 0
+		err = exec.Do(ctx, key, func(context.Context) error {
+			calls++
+			return errDown
+		})
+		if calls != 3 || !errors.Is(err, ErrAttemptsExhausted) {
+			t.Fatalf("timeline=%v: healthy call made %d calls, err %v", withTimeline, calls, err)
+		}
+	}
+}
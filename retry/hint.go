@@ -0,0 +1,71 @@
+package retry
+
+import (
+	"errors"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+)
+
+// Outcome reasons for attempts whose error carried a RetryHint.
+const (
+	ReasonRetryAfterHint = "retry_after_hint"
+	ReasonNoRetryHint    = "no_retry_hint"
+)
+
+// RetryHint wraps an operation's error with an instruction for the executor, for
+// operations that know better than any classifier: a server's own retry-after, or an
+// error that must never be retried. Create one with RetryAfter or NoRetry.
+//
+// The executor checks for a RetryHint anywhere in the error chain before consulting the
+// classifier, and the hint wins. The call's final error still wraps Err, so errors.Is
+// and errors.As see the original error.
+type RetryHint struct {
+	Err error
+	// After is the exact wait before the next attempt, when NoRetry is false.
+	After time.Duration
+	// NoRetry makes the attempt terminal.
+	NoRetry bool
+}
+
+func (h *RetryHint) Error() string { return h.Err.Error() }
+func (h *RetryHint) Unwrap() error { return h.Err }
+
+// RetryAfter returns err marked retryable after exactly d, regardless of the classifier
+// and of the policy's backoff and MaxBackoff. The policy's MaxAttempts, budgets, and
+// OverallTimeout still apply. It returns nil if err is nil.
+func RetryAfter(err error, d time.Duration) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryHint{Err: err, After: max(d, 0)}
+}
+
+// NoRetry returns err marked terminal: the call fails with it without further attempts,
+// whatever the classifier would say. It returns nil if err is nil.
+func NoRetry(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &RetryHint{Err: err, NoRetry: true}
+}
+
+// classifyHint returns the outcome a RetryHint in err's chain dictates, if any.
+func classifyHint(err error) (classify.Outcome, bool) {
+	if err == nil {
+		return classify.Outcome{}, false
+	}
+	var h *RetryHint
+	if !errors.As(err, &h) {
+		return classify.Outcome{}, false
+	}
+	if h.NoRetry {
+		return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: ReasonNoRetryHint}, true
+	}
+	return classify.Outcome{
+		Kind:            classify.OutcomeRetryable,
+		Reason:          ReasonRetryAfterHint,
+		BackoffOverride: h.After,
+		Attributes:      map[string]string{"retry_after": h.After.String()},
+	}, true
+}
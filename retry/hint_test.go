@@ -0,0 +1,88 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+// neverRetryClassifier treats every error as terminal.
+type neverRetryClassifier struct{}
+
+func (neverRetryClassifier) Classify(_ any, err error) classify.Outcome {
+	if err == nil {
+		return classify.Outcome{Kind: classify.OutcomeSuccess}
+	}
+	return classify.Outcome{Kind: classify.OutcomeNonRetryable, Reason: "never"}
+}
+
+func TestRetryHint_OverridesClassifierAndBackoff(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key: key,
+		Retry: policy.RetryPolicy{
+			MaxAttempts:    3,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Millisecond,
+			ClassifierName: "never",
+		},
+	})
+	exec.classifiers.Register("never", neverRetryClassifier{})
+	var slept []time.Duration
+	exec.sleep = func(_ context.Context, d time.Duration) error {
+		slept = append(slept, d)
+		return nil
+	}
+
+	errBusy := errors.New("busy")
+	calls := 0
+	_, tl, _, err := doValueWithTimeline(context.Background(), exec, key, func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, fmt.Errorf("wrapped: %w", RetryAfter(errBusy, 2*time.Second))
+		}
+		return 1, nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("calls=%d err=%v, want 2 and nil", calls, err)
+	}
+	if len(slept) != 1 || slept[0] != 2*time.Second {
+		t.Fatalf("slept=%v, want exactly [2s] despite MaxBackoff", slept)
+	}
+	if r := tl.Attempts[0].Outcome.Reason; r != ReasonRetryAfterHint {
+		t.Fatalf("reason=%q, want %q", r, ReasonRetryAfterHint)
+	}
+}
+
+func TestNoRetryHint_StopsRetries(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{Key: key, Retry: policy.RetryPolicy{MaxAttempts: 5}})
+
+	errGone := errors.New("gone")
+	calls := 0
+	err := exec.Do(context.Background(), key, func(context.Context) error {
+		calls++
+		return NoRetry(errGone)
+	})
+	if calls != 1 {
+		t.Fatalf("calls=%d, want 1", calls)
+	}
+	if !errors.Is(err, errGone) {
+		t.Fatalf("err=%v, want it to wrap %v", err, errGone)
+	}
+	var ce *CallError
+	if !errors.As(err, &ce) || ce.LastReason != ReasonNoRetryHint {
+		t.Fatalf("err=%v, want LastReason %q", err, ReasonNoRetryHint)
+	}
+}
+
+func TestRetryHint_NilError(t *testing.T) {
+	if RetryAfter(nil, time.Second) != nil || NoRetry(nil) != nil {
+		t.Fatalf("hints of a nil error must be nil")
+	}
+}
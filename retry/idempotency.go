@@ -0,0 +1,31 @@
+package retry
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// IdempotencyKeyFunc returns the idempotency key for the attempts of one hedged group
+// of a call for key.
+type IdempotencyKeyFunc func(ctx context.Context, key policy.PolicyKey) string
+
+// WithIdempotencyKeys sets how idempotency keys are generated for policies with
+// Hedge.IdempotencyKeys (see policy.HedgeIdempotencyKeys). Every attempt of a hedged
+// group carries the same key in its observe.AttemptInfo, which the HTTP and gRPC
+// integrations send to servers so they can deduplicate hedged writes, and in its
+// AttemptRecord. By default keys are 128 random bits in hex.
+func WithIdempotencyKeys(f IdempotencyKeyFunc) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.IdempotencyKeys = f
+	}
+}
+
+// RandomIdempotencyKey is the default IdempotencyKeyFunc.
+func RandomIdempotencyKey(context.Context, policy.PolicyKey) string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
@@ -0,0 +1,79 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestIdempotencyKeys_SharedByHedgedGroup(t *testing.T) {
+	key := policy.ParseKey("svc.CreateOrder")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.EnableHedging(), policy.HedgeDelay(10*time.Millisecond), policy.HedgeIdempotencyKeys(true)),
+		WithIdempotencyKeys(func(_ context.Context, k policy.PolicyKey) string { return "k-" + k.Name }),
+	)
+
+	var mu sync.Mutex
+	var keys []string
+	release := make(chan struct{})
+	_, tl, err := doValueInternal(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		info, _ := observe.AttemptFromContext(ctx)
+		mu.Lock()
+		keys = append(keys, info.IdempotencyKey)
+		n := len(keys)
+		mu.Unlock()
+		if n == 1 {
+			// Hold the primary until the hedge has started.
+			select {
+			case <-release:
+			case <-ctx.Done():
+			}
+			return 0, ctx.Err()
+		}
+		if n == 2 {
+			close(release)
+		}
+		return 1, nil
+	}, true)
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(keys) < 2 || keys[0] != "k-CreateOrder" || keys[1] != keys[0] {
+		t.Fatalf("attempt keys = %q, want k-CreateOrder on every attempt", keys)
+	}
+	for _, rec := range tl.Attempts {
+		if rec.IdempotencyKey != "k-CreateOrder" {
+			t.Fatalf("record %+v has idempotency key %q, want k-CreateOrder", rec, rec.IdempotencyKey)
+		}
+	}
+}
+
+func TestIdempotencyKeys_Disabled(t *testing.T) {
+	key := policy.ParseKey("svc.Get")
+	exec := NewExecutor(WithPolicy(key.String(), policy.EnableHedging()))
+
+	var got string
+	_, err := DoValue(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		info, _ := observe.AttemptFromContext(ctx)
+		got = info.IdempotencyKey
+		return 1, nil
+	})
+	if err != nil || got != "" {
+		t.Fatalf("err=%v key=%q, want no idempotency key", err, got)
+	}
+}
+
+func TestRandomIdempotencyKey(t *testing.T) {
+	a := RandomIdempotencyKey(context.Background(), policy.PolicyKey{})
+	b := RandomIdempotencyKey(context.Background(), policy.PolicyKey{})
+	if len(a) != 32 || a == b {
+		t.Fatalf("keys %q and %q, want distinct 32-character keys", a, b)
+	}
+}
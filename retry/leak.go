@@ -0,0 +1,74 @@
+package retry
+
+import (
+	"log"
+	"runtime/debug"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultLeakThreshold is the LeakDetector threshold used when none is set.
+const DefaultLeakThreshold = time.Minute
+
+// LeakDetector configures budget leak detection (see WithLeakDetector): the executor
+// tracks every allowed budget decision that has a Release and reports those still
+// unreleased after Threshold. Budgets hold capacity, such as concurrency slots, until
+// released, so a leaked decision slowly starves the budget. Tracking records a stack
+// per attempt; enable it while debugging, not in production.
+type LeakDetector struct {
+	// Threshold is how long a decision may stay unreleased before it is reported;
+	// default DefaultLeakThreshold. Set it above the longest attempt you expect.
+	Threshold time.Duration
+
+	// Report is called once for each leaked decision, on its own goroutine. If nil,
+	// leaks are written with the log package.
+	Report func(BudgetLeak)
+}
+
+// BudgetLeak describes a budget decision not released within the LeakDetector
+// threshold. The decision may still be released later.
+type BudgetLeak struct {
+	Key     policy.PolicyKey
+	Budget  string             // Budget registry name.
+	Kind    budget.AttemptKind // Retry or hedge attempt.
+	Attempt int                // Attempt index (0-based).
+	Held    time.Duration      // How long the decision has been held.
+	Stack   []byte             // Stack of the goroutine that took the decision.
+}
+
+// WithLeakDetector reports budget decisions that are not released in time.
+func WithLeakDetector(cfg LeakDetector) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.LeakDetector = &cfg
+	}
+}
+
+func (d LeakDetector) normalize() LeakDetector {
+	if d.Threshold <= 0 {
+		d.Threshold = DefaultLeakThreshold
+	}
+	if d.Report == nil {
+		d.Report = func(l BudgetLeak) {
+			log.Printf("retry: budget %q decision for %s attempt %d unreleased after %v\n%s", l.Budget, l.Key, l.Attempt, l.Held, l.Stack)
+		}
+	}
+	return d
+}
+
+// trackRelease wraps release, which must be safe to call twice, so the decision is
+// reported if it is still unreleased after the leak threshold.
+func (e *Executor) trackRelease(key policy.PolicyKey, name string, kind budget.AttemptKind, attemptIdx int, release func()) func() {
+	leak := BudgetLeak{Key: key, Budget: name, Kind: kind, Attempt: attemptIdx, Stack: debug.Stack()}
+	start := e.timing.now()
+	stop := e.timing.afterFunc(e.leaks.Threshold, func() {
+		leak.Held = e.timing.since(start)
+		e.stats.budgetLeaks.Add(1)
+		e.leaks.Report(leak)
+	})
+	return func() {
+		stop()
+		release()
+	}
+}
@@ -0,0 +1,90 @@
+package retry
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestLeakDetector_ReportsUnreleasedDecisions(t *testing.T) {
+	key := policy.ParseKey("svc.Leak")
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", &countingReleaseBudget{})
+	leaks := make(chan BudgetLeak, 2)
+	exec := NewExecutor(
+		WithBudgetRegistry(budgets),
+		WithLeakDetector(LeakDetector{Threshold: 10 * time.Millisecond, Report: func(l BudgetLeak) { leaks <- l }}),
+	)
+	ref := policy.BudgetRef{Name: "b", Cost: 1}
+
+	released, _ := exec.checkBudget(context.Background(), key, ref, 0, budget.KindRetry)
+	released.Release()
+	if _, ok := exec.checkBudget(context.Background(), key, ref, 1, budget.KindHedge); !ok {
+		t.Fatal("decision denied")
+	}
+
+	select {
+	case l := <-leaks:
+		if l.Key != key || l.Budget != "b" || l.Kind != budget.KindHedge || l.Attempt != 1 || l.Held < 10*time.Millisecond {
+			t.Fatalf("leak = %+v, want the unreleased hedge decision", l)
+		}
+		if !strings.Contains(string(l.Stack), "TestLeakDetector_ReportsUnreleasedDecisions") {
+			t.Fatalf("leak stack does not show where the decision was taken:\n%s", l.Stack)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("leak not reported")
+	}
+	select {
+	case l := <-leaks:
+		t.Fatalf("released decision reported: %+v", l)
+	case <-time.After(50 * time.Millisecond):
+	}
+	if got := exec.Stats().BudgetLeaks; got != 1 {
+		t.Fatalf("Stats().BudgetLeaks = %d, want 1", got)
+	}
+}
+
+func TestExecutor_ReleasesCanceledAttemptsPromptly(t *testing.T) {
+	key := policy.ParseKey("svc.Prompt")
+	cb := &countingReleaseBudget{}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", cb)
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1),
+			policy.HedgeDelay(10*time.Millisecond), policy.Budget("b"), policy.HedgeBudget("b")),
+		WithBudgetRegistry(budgets),
+		WithStragglerGrace(time.Hour),
+		WithLeakDetector(LeakDetector{Threshold: time.Hour}),
+	)
+
+	block := make(chan struct{})
+	defer close(block)
+	var calls atomic.Int32
+	_, err := DoValue(context.Background(), exec, key, func(context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			<-block // The primary ignores cancellation.
+			return 0, context.Canceled
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+
+	// The losing primary is still running, but its reservation is freed with the group.
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&cb.releases) < 2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("releases = %d, want the canceled primary's reservation released", atomic.LoadInt32(&cb.releases))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if got := exec.Stats().Stragglers; got != 0 {
+		t.Fatalf("stragglers = %d, want 0 within the grace period", got)
+	}
+}
@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+// keyLimits enforces the per-key traffic caps of resolved policies
+// (policy.LimitsPolicy), with a shed.Limiter and a shed.RateLimiter per key.
+//
+// Idle limiters (no calls in flight, full rate bucket) hold no state a new limiter
+// would not, so they are dropped whenever the number of keys doubles: keys that are no
+// longer called, or whose policy no longer sets caps, do not accumulate. A call racing
+// a sweep may be admitted by the dropped limiter, briefly letting one extra call in.
+type keyLimits struct {
+	keys    sync.Map // policy.PolicyKey -> *keyLimit
+	size    atomic.Int64
+	sweepAt atomic.Int64 // Size at which the next sweep runs; minLimitSweep if zero.
+	sweepMu sync.Mutex
+}
+
+// minLimitSweep is the number of keys below which keyLimits never sweeps.
+const minLimitSweep = 64
+
+// keyLimit holds one key's limiters, built from cfg. A key whose caps change gets new
+// limiters: calls already admitted release their slots to the old ones.
+type keyLimit struct {
+	cfg      policy.LimitsPolicy
+	inFlight *shed.Limiter     // nil without an in-flight cap.
+	rate     *shed.RateLimiter // nil without a rate cap.
+}
+
+// limitCall admits a call to key under lim, the caps of its resolved policy. It returns
+// the release func for the call's in-flight slot, or a *ShedError if a cap refuses the
+// call.
+func (e *Executor) limitCall(ctx context.Context, key policy.PolicyKey, lim policy.LimitsPolicy) (func(), *ShedError) {
+	if lim == (policy.LimitsPolicy{}) {
+		return nil, nil
+	}
+	l := e.keyLimit(key, lim)
+
+	var release func()
+	if l.inFlight != nil {
+		d := l.inFlight.Admit(ctx, key)
+		if !d.Admitted {
+			return nil, &ShedError{Reason: d.Reason}
+		}
+		release = d.Release
+	}
+	if l.rate != nil {
+		if d := l.rate.Admit(ctx, key); !d.Admitted {
+			if release != nil {
+				release()
+			}
+			return nil, &ShedError{Reason: d.Reason}
+		}
+	}
+	return release, nil
+}
+
+// keyLimit returns key's limiters for lim, replacing them if key's caps changed.
+func (e *Executor) keyLimit(key policy.PolicyKey, lim policy.LimitsPolicy) *keyLimit {
+	if v, ok := e.limits.keys.Load(key); ok {
+		if l := v.(*keyLimit); l.cfg == lim {
+			return l
+		}
+	}
+	l := &keyLimit{cfg: lim}
+	if lim.MaxInFlight > 0 {
+		l.inFlight = shed.NewLimiter(shed.Options{MaxInFlight: lim.MaxInFlight})
+	}
+	if lim.MaxQPS > 0 {
+		l.rate = shed.NewRateLimiter(shed.RateOptions{QPS: lim.MaxQPS, Burst: lim.Burst, Now: e.clock})
+	}
+	for {
+		v, loaded := e.limits.keys.LoadOrStore(key, l)
+		if !loaded {
+			e.limits.added()
+			return l
+		}
+		cur := v.(*keyLimit)
+		if cur.cfg == lim {
+			return cur
+		}
+		if e.limits.keys.CompareAndSwap(key, cur, l) {
+			return l
+		}
+	}
+}
+
+// idle reports whether l holds no calls and no spent rate, so a new keyLimit for the
+// same caps would behave the same.
+func (l *keyLimit) idle() bool {
+	if l.inFlight != nil && (l.inFlight.InFlight() > 0 || l.inFlight.Waiting() > 0) {
+		return false
+	}
+	return l.rate == nil || l.rate.Full()
+}
+
+// added counts a new key and sweeps idle keys once the count reaches the sweep mark,
+// moving the mark to twice the keys left.
+func (k *keyLimits) added() {
+	n := k.size.Add(1)
+	if n < max(k.sweepAt.Load(), minLimitSweep) || !k.sweepMu.TryLock() {
+		return
+	}
+	defer k.sweepMu.Unlock()
+	k.keys.Range(func(key, v any) bool {
+		if v.(*keyLimit).idle() && k.keys.CompareAndDelete(key, v) {
+			n = k.size.Add(-1)
+		}
+		return true
+	})
+	k.sweepAt.Store(2 * n)
+}
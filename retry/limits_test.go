@@ -0,0 +1,123 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+func TestExecutor_PolicyMaxInFlight(t *testing.T) {
+	key := policy.ParseKey("svc.Limited")
+	pol, err := controlplane.DecodePolicy(key, []byte(`{"retry": {"max_attempts": 1}, "limits": {"max_in_flight": 1}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: pol}},
+	})
+
+	err = exec.Do(context.Background(), key, func(ctx context.Context) error {
+		ran := false
+		err := exec.Do(ctx, key, func(context.Context) error { ran = true; return nil })
+		var se *ShedError
+		if ran || !errors.Is(err, ErrShed) || !errors.As(err, &se) || se.Reason != shed.ReasonInFlight {
+			t.Errorf("nested call: ran=%v err=%v, want ShedError %s", ran, err, shed.ReasonInFlight)
+		}
+
+		ctx, capture := observe.RecordTimeline(ctx)
+		if err := exec.Do(ctx, key, func(context.Context) error { return nil }); !errors.Is(err, ErrShed) {
+			t.Errorf("nested call with timeline = %v, want ErrShed", err)
+		}
+		if tl := capture.Timeline(); tl == nil || tl.Attributes["shed_reason"] != shed.ReasonInFlight {
+			t.Errorf("timeline = %+v, want shed_reason", tl)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if err := exec.Do(context.Background(), key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("call after release = %v", err)
+	}
+}
+
+func TestExecutor_PolicyMaxQPS(t *testing.T) {
+	key := policy.ParseKey("svc.Rated")
+	now := time.Unix(1_700_000_000, 0)
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.MaxQPS(1, 2)),
+		WithClock(func() time.Time { return now }),
+	)
+	ok := func(context.Context) error { return nil }
+
+	for i := 0; i < 2; i++ {
+		if err := exec.Do(context.Background(), key, ok); err != nil {
+			t.Fatalf("call %d within burst = %v", i, err)
+		}
+	}
+	var se *ShedError
+	if err := exec.Do(context.Background(), key, ok); !errors.As(err, &se) || se.Reason != shed.ReasonRateLimited {
+		t.Fatalf("call over rate = %v, want ShedError %s", err, shed.ReasonRateLimited)
+	}
+
+	now = now.Add(time.Second)
+	if err := exec.Do(context.Background(), key, ok); err != nil {
+		t.Fatalf("call after refill = %v", err)
+	}
+}
+
+func TestExecutor_PolicyLimitsChange(t *testing.T) {
+	key := policy.ParseKey("svc.Changing")
+	provider := &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		key: policy.NewFromKey(key, policy.MaxAttempts(1), policy.MaxInFlight(1)),
+	}}
+	exec := NewExecutorFromOptions(ExecutorOptions{Provider: provider})
+
+	err := exec.Do(context.Background(), key, func(ctx context.Context) error {
+		if err := exec.Do(ctx, key, func(context.Context) error { return nil }); !errors.Is(err, ErrShed) {
+			t.Errorf("nested call under cap 1 = %v, want ErrShed", err)
+		}
+		provider.Policies[key] = policy.NewFromKey(key, policy.MaxAttempts(1), policy.MaxInFlight(2))
+		if err := exec.Do(ctx, key, func(context.Context) error { return nil }); err != nil {
+			t.Errorf("nested call under raised cap = %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+}
+
+func TestExecutor_PolicyLimitsEvictIdleKeys(t *testing.T) {
+	exec := NewExecutor()
+	lim := policy.LimitsPolicy{MaxInFlight: 1}
+	held := policy.ParseKey("svc.Held")
+	release, se := exec.limitCall(context.Background(), held, lim)
+	if se != nil {
+		t.Fatalf("limitCall: %v", se)
+	}
+	for i := 0; i < 4*minLimitSweep; i++ {
+		r, se := exec.limitCall(context.Background(), policy.ParseKey(fmt.Sprintf("svc.Op%d", i)), lim)
+		if se != nil {
+			t.Fatalf("limitCall %d: %v", i, se)
+		}
+		r()
+	}
+
+	n := 0
+	exec.limits.keys.Range(func(any, any) bool { n++; return true })
+	if n > 2*minLimitSweep || int64(n) != exec.limits.size.Load() {
+		t.Fatalf("keys = %d (size %d), want idle keys swept", n, exec.limits.size.Load())
+	}
+	if _, se := exec.limitCall(context.Background(), held, lim); se == nil {
+		t.Fatal("held key admitted a second call, want its limiter kept")
+	}
+	release()
+}
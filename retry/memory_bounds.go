@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"sort"
+	"sync/atomic"
+	"unicode/utf8"
+	"unsafe"
+
+	"github.com/aponysus/recourse/observe"
+)
+
+// MemoryBounds caps the observability data the executor keeps in call timelines, so a
+// pathological policy or an error flood cannot grow memory through the observe
+// pipeline. Zero fields are not capped. Dropped data is counted in
+// Timeline.DroppedAttempts and in the executor's Stats; observers still receive every
+// OnAttempt.
+type MemoryBounds struct {
+	// MaxAttemptsPerCall caps the attempt records a timeline keeps. When full, the
+	// oldest record after the first is dropped to make room; budget denials are kept.
+	MaxAttemptsPerCall int
+	// MaxTimelineBytes caps the estimated size of the attempt records held by the
+	// timelines of all of the executor's in-flight calls. While it is reached, new
+	// records (other than budget denials) are dropped.
+	MaxTimelineBytes int64
+	// MaxAttributes caps the attributes a timeline keeps; the attributes past the first
+	// MaxAttributes keys in sorted order are dropped.
+	MaxAttributes int
+	// MaxAttributeSize caps an attribute value's length in bytes; longer values are
+	// truncated.
+	MaxAttributeSize int
+}
+
+// WithMemoryBounds caps the observability data kept in call timelines.
+func WithMemoryBounds(b MemoryBounds) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.MemoryBounds = &b
+	}
+}
+
+// memoryBounds enforces MemoryBounds for an executor.
+type memoryBounds struct {
+	cfg   MemoryBounds
+	bytes atomic.Int64 // Estimated bytes held by in-flight timelines.
+	stats *executorStats
+}
+
+// recordBytes estimates the memory an attempt record holds.
+func recordBytes(rec *observe.AttemptRecord) int64 {
+	n := int64(unsafe.Sizeof(*rec)) + int64(len(rec.Outcome.Reason)+len(rec.BudgetReason))
+	if rec.Err != nil {
+		n += int64(len(rec.Err.Error()))
+	}
+	return n
+}
+
+// add appends rec to tl within the bounds, adding the bytes it holds to held.
+func (m *memoryBounds) add(tl *observe.Timeline, rec observe.AttemptRecord, held *int64) {
+	if m == nil {
+		tl.Attempts = append(tl.Attempts, rec)
+		return
+	}
+	var size int64
+	if m.cfg.MaxTimelineBytes > 0 {
+		size = recordBytes(&rec)
+		if rec.BudgetAllowed && m.bytes.Load()+size > m.cfg.MaxTimelineBytes {
+			m.drop(tl)
+			return
+		}
+	}
+	if limit := m.cfg.MaxAttemptsPerCall; limit > 0 && len(tl.Attempts) >= limit && rec.BudgetAllowed {
+		i := 1
+		for i < len(tl.Attempts) && !tl.Attempts[i].BudgetAllowed {
+			i++
+		}
+		if i >= len(tl.Attempts) {
+			m.drop(tl)
+			return
+		}
+		if m.cfg.MaxTimelineBytes > 0 {
+			evicted := recordBytes(&tl.Attempts[i])
+			m.bytes.Add(-evicted)
+			*held -= evicted
+		}
+		tl.Attempts = append(tl.Attempts[:i], tl.Attempts[i+1:]...)
+		m.drop(tl)
+	}
+	tl.Attempts = append(tl.Attempts, rec)
+	if size > 0 {
+		m.bytes.Add(size)
+		*held += size
+	}
+}
+
+func (m *memoryBounds) drop(tl *observe.Timeline) {
+	tl.DroppedAttempts++
+	m.stats.droppedAttempts.Add(1)
+}
+
+// release returns the bytes a finished call's timeline held.
+func (m *memoryBounds) release(held int64) {
+	if m != nil && held != 0 {
+		m.bytes.Add(-held)
+	}
+}
+
+// trim applies the attribute bounds to a finished timeline.
+func (m *memoryBounds) trim(tl *observe.Timeline) {
+	if m == nil || len(tl.Attributes) == 0 {
+		return
+	}
+	if size := m.cfg.MaxAttributeSize; size > 0 {
+		for k, v := range tl.Attributes {
+			if len(v) > size {
+				tl.Attributes[k] = truncateUTF8(v, size)
+				m.stats.droppedAttributes.Add(1)
+			}
+		}
+	}
+	if limit := m.cfg.MaxAttributes; limit > 0 && len(tl.Attributes) > limit {
+		keys := make([]string, 0, len(tl.Attributes))
+		for k := range tl.Attributes {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys[limit:] {
+			delete(tl.Attributes, k)
+		}
+		m.stats.droppedAttributes.Add(uint64(len(keys) - limit))
+	}
+}
+
+// truncateUTF8 cuts s to at most n bytes without splitting a rune.
+func truncateUTF8(s string, n int) string {
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
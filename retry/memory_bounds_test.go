@@ -0,0 +1,70 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestMemoryBounds_AttemptsPerCall(t *testing.T) {
+	key := policy.ParseKey("svc.Flood")
+	exec := newTestExecutor(t, key, policy.New(key.String(), policy.MaxAttempts(5)))
+	exec.bounds = &memoryBounds{cfg: MemoryBounds{MaxAttemptsPerCall: 3}, stats: &exec.stats}
+
+	_, tl, err := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+		return 0, errors.New("down")
+	}, true)
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if len(tl.Attempts) != 3 || tl.DroppedAttempts != 2 {
+		t.Fatalf("kept %d attempts, dropped %d; want 3 and 2", len(tl.Attempts), tl.DroppedAttempts)
+	}
+	if got := []int{tl.Attempts[0].Attempt, tl.Attempts[1].Attempt, tl.Attempts[2].Attempt}; got[0] != 0 || got[1] != 3 || got[2] != 4 {
+		t.Fatalf("kept attempts %v, want the first and the latest: [0 3 4]", got)
+	}
+	var ce *CallError
+	if !errors.As(err, &ce) || ce.Attempts != 5 {
+		t.Fatalf("err = %v, want a CallError counting all 5 attempts", err)
+	}
+	if got := exec.Stats().DroppedAttempts; got != 2 {
+		t.Fatalf("Stats().DroppedAttempts = %d, want 2", got)
+	}
+}
+
+func TestMemoryBounds_TimelineBytes(t *testing.T) {
+	key := policy.ParseKey("svc.Big")
+	exec := newTestExecutor(t, key, policy.New(key.String(), policy.MaxAttempts(3)))
+	huge := errors.New(strings.Repeat("x", 4096))
+	rec := observe.AttemptRecord{Err: huge, BudgetAllowed: true}
+	exec.bounds = &memoryBounds{cfg: MemoryBounds{MaxTimelineBytes: 5 * recordBytes(&rec) / 2}, stats: &exec.stats}
+
+	_, tl, _ := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+		return 0, huge
+	}, true)
+	if len(tl.Attempts) != 2 || tl.DroppedAttempts != 1 {
+		t.Fatalf("kept %d attempts, dropped %d; want 2 and 1", len(tl.Attempts), tl.DroppedAttempts)
+	}
+	if got := exec.bounds.bytes.Load(); got != 0 {
+		t.Fatalf("bytes held after the call = %d, want 0", got)
+	}
+}
+
+func TestMemoryBounds_Attributes(t *testing.T) {
+	m := &memoryBounds{cfg: MemoryBounds{MaxAttributes: 2, MaxAttributeSize: 4}, stats: &executorStats{}}
+	tl := observe.Timeline{Attributes: map[string]string{"a": "short", "b": "ok", "c": "héllo"}}
+	m.trim(&tl)
+	if len(tl.Attributes) != 2 || tl.Attributes["a"] != "shor" || tl.Attributes["b"] != "ok" {
+		t.Fatalf("attributes = %v, want a truncated and c dropped", tl.Attributes)
+	}
+	if got := m.stats.droppedAttributes.Load(); got != 3 {
+		t.Fatalf("droppedAttributes = %d, want 3 (two truncated, one dropped)", got)
+	}
+	if got := truncateUTF8("héllo", 2); got != "h" {
+		t.Fatalf("truncateUTF8 = %q, want %q", got, "h")
+	}
+}
@@ -0,0 +1,53 @@
+package retry
+
+import "github.com/aponysus/recourse/policy"
+
+// WithNamespaceDefaults sets the policy options for keys in namespace (e.g. "db" for
+// "db.Query") that resolve only to the package default policy, e.g.
+// WithNamespaceDefaults("db", policy.DatabaseDefaults()). The options are applied on
+// top of policy.DefaultPolicyFor, as policy.New applies them.
+func WithNamespaceDefaults(namespace string, opts ...policy.Option) ExecutorOption {
+	return func(c *executorConfig) {
+		if c.opts.NamespaceDefaults == nil {
+			c.opts.NamespaceDefaults = make(map[string][]policy.Option)
+		}
+		c.opts.NamespaceDefaults[namespace] = opts
+	}
+}
+
+// packageDefault is policy.DefaultPolicyFor normalized, for isPackageDefault.
+var packageDefault, _ = policy.DefaultPolicyFor(policy.PolicyKey{}).Normalize()
+
+// isPackageDefault reports whether pol, normalized, is configured as the package
+// default policy, as it is when the provider has no policy for its key.
+func isPackageDefault(pol policy.EffectivePolicy) bool {
+	return pol.ID == "" &&
+		pol.Rollout == nil &&
+		pol.Retry.Equal(packageDefault.Retry) &&
+		pol.Hedge == packageDefault.Hedge &&
+		pol.Circuit == packageDefault.Circuit &&
+		pol.Limits == packageDefault.Limits
+}
+
+func newNamespaceDefaults(opts map[string][]policy.Option) map[string]policy.EffectivePolicy {
+	if len(opts) == 0 {
+		return nil
+	}
+	defaults := make(map[string]policy.EffectivePolicy, len(opts))
+	for ns, o := range opts {
+		defaults[ns] = policy.NewFromKey(policy.PolicyKey{Namespace: ns}, o...)
+	}
+	return defaults
+}
+
+// applyNamespaceDefaults replaces pol, normalized, with key's namespace default if pol
+// is the package default policy.
+func (e *Executor) applyNamespaceDefaults(key policy.PolicyKey, pol policy.EffectivePolicy) policy.EffectivePolicy {
+	def, ok := e.namespaceDefaults[key.Namespace]
+	if !ok || !isPackageDefault(pol) {
+		return pol
+	}
+	def.Key = key
+	def.Meta.Source, def.Meta.Provider = pol.Meta.Source, pol.Meta.Provider
+	return def
+}
@@ -0,0 +1,56 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestNamespaceDefaults(t *testing.T) {
+	exec := NewExecutor(
+		WithPolicy("db.Special", policy.MaxAttempts(2)),
+		WithNamespaceDefaults("db", policy.DatabaseDefaults()),
+	)
+	ctx := context.Background()
+
+	tests := []struct {
+		key     string
+		timeout time.Duration
+	}{
+		{"db.Query", 60 * time.Second}, // Namespace default.
+		{"db.Special", 0},              // Configured policy.
+		{"cache.Get", 0},               // No namespace default.
+	}
+	for _, tt := range tests {
+		pol, err := exec.EffectivePolicy(ctx, policy.ParseKey(tt.key))
+		if err != nil {
+			t.Fatalf("%s: EffectivePolicy: %v", tt.key, err)
+		}
+		if pol.Retry.OverallTimeout != tt.timeout || pol.Key.String() != tt.key {
+			t.Errorf("%s: key=%s overall timeout=%v, want %v", tt.key, pol.Key, pol.Retry.OverallTimeout, tt.timeout)
+		}
+	}
+}
+
+func TestNamespaceDefaults_Fallback(t *testing.T) {
+	key := policy.ParseKey("db.Query")
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider:          stubProvider{err: controlplane.ErrProviderUnavailable},
+		MissingPolicyMode: FailureFallback,
+		NamespaceDefaults: map[string][]policy.Option{"db": {policy.MaxAttempts(5)}},
+	})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	calls := 0
+	err := exec.Do(context.Background(), key, func(context.Context) error {
+		calls++
+		return errors.New("down")
+	})
+	if err == nil || calls != 5 {
+		t.Fatalf("err=%v calls=%d, want 5 attempts from the namespace default", err, calls)
+	}
+}
@@ -160,6 +160,63 @@ func TestDoValue_FastPath_AttemptInfoInContext(t *testing.T) {
 	}
 }
 
+func TestDoValueWithTimeline_MonotonicDurations_FrozenClock(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	frozen := time.Unix(1700000000, 0)
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider: &controlplane.StaticProvider{
+			Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+				key: {
+					Key: key,
+					Retry: policy.RetryPolicy{
+						MaxAttempts:    2,
+						InitialBackoff: 5 * time.Millisecond,
+						MaxBackoff:     5 * time.Millisecond,
+						Jitter:         policy.JitterNone,
+					},
+				},
+			},
+		},
+		Clock: func() time.Time { return frozen },
+	})
+
+	calls := 0
+	ctx, capture := observe.RecordTimeline(context.Background())
+	_, err := DoValue[int](ctx, exec, key, func(context.Context) (int, error) {
+		calls++
+		time.Sleep(2 * time.Millisecond)
+		if calls < 2 {
+			return 0, errors.New("nope")
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("err=%v, want nil", err)
+	}
+
+	tl := capture.Timeline()
+	if tl == nil || len(tl.Attempts) != 2 {
+		t.Fatalf("expected 2 attempts, got %+v", tl)
+	}
+	if !tl.End.Equal(tl.Start) {
+		t.Fatalf("expected frozen wall clock, got start=%v end=%v", tl.Start, tl.End)
+	}
+	for i, rec := range tl.Attempts {
+		if rec.Duration < 2*time.Millisecond {
+			t.Fatalf("attempt[%d].Duration=%v, want >= 2ms", i, rec.Duration)
+		}
+	}
+	if tl.Attempts[0].BackoffActual != 0 {
+		t.Fatalf("attempt[0].BackoffActual=%v, want 0", tl.Attempts[0].BackoffActual)
+	}
+	if tl.Attempts[1].BackoffActual < 5*time.Millisecond {
+		t.Fatalf("attempt[1].BackoffActual=%v, want >= 5ms", tl.Attempts[1].BackoffActual)
+	}
+	if tl.Duration < tl.Attempts[0].Duration+tl.Attempts[1].Duration {
+		t.Fatalf("tl.Duration=%v, want >= sum of attempt durations", tl.Duration)
+	}
+}
+
 func TestExecutor_Observer_BudgetDecisions(t *testing.T) {
 	key := policy.PolicyKey{Name: "budget_event"}
 	obs := &testObserver{}
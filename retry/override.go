@@ -0,0 +1,136 @@
+package retry
+
+import (
+	"context"
+	"maps"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// GlobalOverrideKillSwitch is the value of the "global_override" timeline attribute on
+// calls whose retries and hedging were suppressed by a kill switch. The
+// "global_override_source" attribute is "executor" (SetGlobalOverride) or
+// "control_plane" (controlplane.KillSwitchProvider).
+const GlobalOverrideKillSwitch = "kill_switch"
+
+// GlobalOverride is an executor-wide override applied to every key's policy.
+type GlobalOverride struct {
+	// KillSwitch forces MaxAttempts=1 and disables hedging for every key, e.g. to stop
+	// retry amplification during an incident.
+	KillSwitch bool
+}
+
+// SetGlobalOverride applies o to every call the executor starts from now on, replacing
+// any previous override; calls already in flight keep their policy. The zero
+// GlobalOverride clears the override.
+//
+// A provider implementing controlplane.KillSwitchProvider can also turn the kill
+// switch on; either source suffices.
+func (e *Executor) SetGlobalOverride(o GlobalOverride) {
+	e.override.Store(&o)
+}
+
+// GlobalOverride returns the override set with SetGlobalOverride.
+func (e *Executor) GlobalOverride() GlobalOverride {
+	if o := e.override.Load(); o != nil {
+		return *o
+	}
+	return GlobalOverride{}
+}
+
+// killSwitch returns the source of an active kill switch, or "".
+func (e *Executor) killSwitch() string {
+	if o := e.override.Load(); o != nil && o.KillSwitch {
+		return "executor"
+	}
+	if e.killSwitchProvider != nil && e.killSwitchProvider.KillSwitch() {
+		return "control_plane"
+	}
+	return ""
+}
+
+// KeyOverride temporarily overrides part of one key's policy, e.g. from an operator
+// tool. Calls for the key record it in the "key_override" timeline attribute.
+type KeyOverride struct {
+	// MaxAttempts replaces the policy's Retry.MaxAttempts (normalized as usual).
+	MaxAttempts int `json:"max_attempts"`
+	// Until is when the override expires, by the executor's clock. Zero never expires.
+	Until time.Time `json:"until,omitempty"`
+}
+
+// SetKeyOverride overrides key's policy for calls started from now on, replacing any
+// previous override for the key.
+func (e *Executor) SetKeyOverride(key policy.PolicyKey, o KeyOverride) {
+	e.keyOverridesMu.Lock()
+	defer e.keyOverridesMu.Unlock()
+	m := make(map[policy.PolicyKey]KeyOverride)
+	if cur := e.keyOverrides.Load(); cur != nil {
+		maps.Copy(m, *cur)
+	}
+	m[key] = o
+	e.keyOverrides.Store(&m)
+}
+
+// ClearKeyOverride removes key's override.
+func (e *Executor) ClearKeyOverride(key policy.PolicyKey) {
+	e.keyOverridesMu.Lock()
+	defer e.keyOverridesMu.Unlock()
+	cur := e.keyOverrides.Load()
+	if cur == nil {
+		return
+	}
+	if _, ok := (*cur)[key]; !ok {
+		return
+	}
+	m := maps.Clone(*cur)
+	delete(m, key)
+	if len(m) == 0 {
+		e.keyOverrides.Store(nil)
+		return
+	}
+	e.keyOverrides.Store(&m)
+}
+
+// KeyOverrides returns the overrides that have not expired.
+func (e *Executor) KeyOverrides() map[policy.PolicyKey]KeyOverride {
+	out := make(map[policy.PolicyKey]KeyOverride)
+	if cur := e.keyOverrides.Load(); cur != nil {
+		now := e.clock()
+		for k, o := range *cur {
+			if o.Until.IsZero() || now.Before(o.Until) {
+				out[k] = o
+			}
+		}
+	}
+	return out
+}
+
+// applyOverrides applies adaptive MaxAttempts, retry-storm mitigation, retry cool-off,
+// an active key override, and the kill switch to pol, recording them in attrs and sum
+// when they are non-nil.
+func (e *Executor) applyOverrides(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy, attrs map[string]string, sum *callSummary) policy.EffectivePolicy {
+	pol = e.applyAdaptive(key, pol, attrs)
+	pol = e.applyStorm(ctx, key, pol, attrs)
+	pol = e.applyCoolOff(key, pol, attrs, sum)
+	if cur := e.keyOverrides.Load(); cur != nil {
+		if o, ok := (*cur)[key]; ok && (o.Until.IsZero() || e.clock().Before(o.Until)) && o.MaxAttempts != 0 {
+			pol.Retry.MaxAttempts = o.MaxAttempts
+			if n, err := pol.Normalize(); err == nil {
+				pol = n
+			}
+			if attrs != nil {
+				attrs["key_override"] = "max_attempts"
+			}
+		}
+	}
+	if src := e.killSwitch(); src != "" {
+		pol.Retry.MaxAttempts = 1
+		pol.Hedge.Enabled = false
+		if attrs != nil {
+			attrs["global_override"] = GlobalOverrideKillSwitch
+			attrs["global_override_source"] = src
+		}
+	}
+	return pol
+}
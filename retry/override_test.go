@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type killSwitchProvider struct {
+	controlplane.StaticProvider
+	on atomic.Bool
+}
+
+func (p *killSwitchProvider) KillSwitch() bool { return p.on.Load() }
+
+func TestExecutor_GlobalOverride(t *testing.T) {
+	key := policy.ParseKey("svc.Kill")
+	provider := &killSwitchProvider{}
+	provider.Policies = map[policy.PolicyKey]policy.EffectivePolicy{
+		key: policy.NewFromKey(key, policy.MaxAttempts(3), policy.EnableHedging()),
+	}
+	exec := NewExecutor(WithProvider(provider))
+
+	run := func() (attempts int32, tl observe.Timeline) {
+		ctx, capture := observe.RecordTimeline(context.Background())
+		_ = exec.Do(ctx, key, func(context.Context) error {
+			atomic.AddInt32(&attempts, 1)
+			return errors.New("boom")
+		})
+		return atomic.LoadInt32(&attempts), *capture.Timeline()
+	}
+
+	exec.SetGlobalOverride(GlobalOverride{KillSwitch: true})
+	attempts, tl := run()
+	if attempts != 1 {
+		t.Fatalf("attempts = %d, want 1 with the kill switch on", attempts)
+	}
+	if tl.Attributes["global_override"] != GlobalOverrideKillSwitch || tl.Attributes["global_override_source"] != "executor" {
+		t.Fatalf("attributes = %v, want kill switch from executor", tl.Attributes)
+	}
+	// The fast path honors the override too.
+	calls := 0
+	_ = exec.Do(context.Background(), key, func(context.Context) error { calls++; return errors.New("boom") })
+	if calls != 1 {
+		t.Fatalf("fast path attempts = %d, want 1", calls)
+	}
+
+	exec.SetGlobalOverride(GlobalOverride{})
+	provider.on.Store(true)
+	if _, tl = run(); tl.Attributes["global_override_source"] != "control_plane" {
+		t.Fatalf("attributes = %v, want kill switch from control plane", tl.Attributes)
+	}
+
+	provider.on.Store(false)
+	attempts, tl = run()
+	if attempts < 3 || tl.Attributes["global_override"] != "" {
+		t.Fatalf("attempts = %d, attributes = %v; want full retries without override", attempts, tl.Attributes)
+	}
+}
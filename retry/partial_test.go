@@ -0,0 +1,119 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+var errShardDown = errors.New("shard down")
+
+// partialClassifier classifies a non-empty value returned with an error as partial.
+type partialClassifier struct{}
+
+func (partialClassifier) Classify(val any, err error) classify.Outcome {
+	switch {
+	case err == nil:
+		return classify.Outcome{Kind: classify.OutcomeSuccess}
+	case val != nil && len(val.([]string)) > 0:
+		return classify.Outcome{Kind: classify.OutcomePartial}
+	default:
+		return classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "failed"}
+	}
+}
+
+func newPartialExecutor(t *testing.T, key policy.PolicyKey, accept bool) *Executor {
+	t.Helper()
+	exec := newTestExecutor(t, key, policy.EffectivePolicy{
+		Key:   key,
+		Retry: policy.RetryPolicy{MaxAttempts: 3, ClassifierName: "partial", AcceptPartial: accept},
+	})
+	exec.classifiers.Register("partial", partialClassifier{})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+	return exec
+}
+
+func TestPartial_Accepted(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newPartialExecutor(t, key, true)
+
+	for _, timeline := range []bool{false, true} {
+		calls := 0
+		op := func(context.Context) ([]string, error) {
+			calls++
+			return []string{"a"}, errShardDown
+		}
+		var val []string
+		var err error
+		if timeline {
+			var tl observe.Timeline
+			val, tl, err = doValueInternal(context.Background(), exec, key, op, true)
+			if tl.Attributes["partial"] != "true" {
+				t.Fatalf("timeline attributes=%v, want partial=true", tl.Attributes)
+			}
+		} else {
+			val, err = DoValue(context.Background(), exec, key, op)
+		}
+		if calls != 1 || len(val) != 1 {
+			t.Fatalf("timeline=%v: calls=%d val=%v, want 1 call and the partial value", timeline, calls, val)
+		}
+		if !errors.Is(err, errShardDown) {
+			t.Fatalf("timeline=%v: err=%v, want it to wrap %v", timeline, err, errShardDown)
+		}
+		if !errors.Is(err, ErrPartialResult) {
+			t.Fatalf("timeline=%v: err=%v, want ErrPartialResult", timeline, err)
+		}
+	}
+}
+
+func TestPartial_RetriedForCompleteResult(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newPartialExecutor(t, key, false)
+
+	calls := 0
+	val, err := DoValue(context.Background(), exec, key, func(context.Context) ([]string, error) {
+		calls++
+		if calls == 1 {
+			return []string{"a"}, errShardDown
+		}
+		return []string{"a", "b"}, nil
+	})
+	if err != nil || calls != 2 || len(val) != 2 {
+		t.Fatalf("calls=%d val=%v err=%v, want the complete result on attempt 2", calls, val, err)
+	}
+}
+
+func TestPartial_ExhaustedReturnsLastPartial(t *testing.T) {
+	key := policy.PolicyKey{Name: "x"}
+	exec := newPartialExecutor(t, key, false)
+
+	for _, timeline := range []bool{false, true} {
+		calls := 0
+		op := func(context.Context) ([]string, error) {
+			calls++
+			if calls == 1 {
+				return []string{"a"}, errShardDown
+			}
+			return nil, errShardDown
+		}
+		ctx := context.Background()
+		var val []string
+		var err error
+		if timeline {
+			val, _, err = doValueInternal(ctx, exec, key, op, true)
+		} else {
+			val, err = DoValue(ctx, exec, key, op)
+		}
+		if calls != 3 || len(val) != 1 {
+			t.Fatalf("timeline=%v: calls=%d val=%v, want 3 calls and the partial value", timeline, calls, val)
+		}
+		if errors.Is(err, ErrPartialResult) {
+			t.Fatalf("timeline=%v: err=%v, want not ErrPartialResult", timeline, err)
+		}
+	}
+}
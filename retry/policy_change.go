@@ -0,0 +1,45 @@
+package retry
+
+import (
+	"context"
+	"sync"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// policyChangeTracker remembers the last policy resolved per key and reports changes
+// to an observe.PolicyChangeObserver.
+type policyChangeTracker struct {
+	obs observe.PolicyChangeObserver
+
+	mu   sync.Mutex
+	last map[policy.PolicyKey]policy.EffectivePolicy
+}
+
+func newPolicyChangeTracker(obs observe.PolicyChangeObserver) *policyChangeTracker {
+	return &policyChangeTracker{obs: obs, last: make(map[policy.PolicyKey]policy.EffectivePolicy)}
+}
+
+func (t *policyChangeTracker) record(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy) {
+	t.mu.Lock()
+	old, seen := t.last[key]
+	var diff []policy.FieldChange
+	if seen && (old.ID != pol.ID || !old.Retry.Equal(pol.Retry) || old.Hedge != pol.Hedge || old.Circuit != pol.Circuit || old.Limits != pol.Limits || old.Rollout != pol.Rollout) {
+		diff = policy.Diff(old, pol)
+	}
+	if !seen || len(diff) > 0 {
+		t.last[key] = pol
+	}
+	t.mu.Unlock()
+
+	if len(diff) > 0 {
+		t.obs.OnPolicyChange(ctx, observe.PolicyChangeEvent{
+			Key:    key,
+			Old:    old,
+			New:    pol,
+			Source: pol.Meta.Source,
+			Diff:   diff,
+		})
+	}
+}
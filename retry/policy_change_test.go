@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type policyChangeRecorder struct {
+	observe.BaseObserver
+	mu     sync.Mutex
+	events []observe.PolicyChangeEvent
+}
+
+func (r *policyChangeRecorder) OnPolicyChange(_ context.Context, ev observe.PolicyChangeEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func TestExecutor_PolicyChangeEvents(t *testing.T) {
+	key := policy.ParseKey("svc.Audit")
+	provider := &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		key: policy.New("svc.Audit", policy.MaxAttempts(3)),
+	}}
+	rec := &policyChangeRecorder{}
+	exec := NewExecutor(WithProvider(provider), WithObserver(observe.MultiObserver{Observers: []observe.Observer{rec}}))
+	op := func(context.Context) error { return nil }
+
+	for range 2 {
+		if err := exec.Do(context.Background(), key, op); err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+	}
+	if len(rec.events) != 0 {
+		t.Fatalf("events before change = %+v, want none", rec.events)
+	}
+
+	provider.Policies[key] = policy.New("svc.Audit", policy.MaxAttempts(5))
+	for range 2 {
+		if err := exec.Do(context.Background(), key, op); err != nil {
+			t.Fatalf("Do: %v", err)
+		}
+	}
+	if len(rec.events) != 1 {
+		t.Fatalf("events = %+v, want one change", rec.events)
+	}
+	ev := rec.events[0]
+	if ev.Key != key || ev.Old.Retry.MaxAttempts != 3 || ev.New.Retry.MaxAttempts != 5 || ev.Source != policy.PolicySourceDefault {
+		t.Fatalf("event = %+v", ev)
+	}
+	if len(ev.Diff) != 1 || ev.Diff[0] != (policy.FieldChange{Field: "retry.max_attempts", Old: "3", New: "5"}) {
+		t.Fatalf("diff = %+v", ev.Diff)
+	}
+}
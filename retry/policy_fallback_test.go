@@ -0,0 +1,95 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type fallbackObserver struct {
+	observe.NoopObserver
+	events []observe.PolicyFallbackEvent
+}
+
+func (o *fallbackObserver) OnPolicyFallback(_ context.Context, ev observe.PolicyFallbackEvent) {
+	o.events = append(o.events, ev)
+}
+
+func TestPolicyFallback_ReportsReasonAndMode(t *testing.T) {
+	tests := []struct {
+		name       string
+		provider   controlplane.PolicyProvider
+		mode       FailureMode
+		wantReason string
+		wantMode   string
+		wantCalls  int
+	}{
+		{"not_found_allow", stubProvider{err: controlplane.ErrPolicyNotFound}, FailureAllow, observe.PolicyFallbackNotFound, "allow", 1},
+		{"provider_error_deny", stubProvider{err: controlplane.ErrProviderUnavailable}, FailureDeny, observe.PolicyFallbackProviderError, "deny", 0},
+		{"provider_panic_fallback", panicProvider{}, FailureFallback, observe.PolicyFallbackProviderPanic, "fallback", 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key := policy.PolicyKey{Name: "fallback"}
+			obs := &fallbackObserver{}
+			exec := NewExecutorFromOptions(ExecutorOptions{
+				Provider:          tt.provider,
+				Observer:          obs,
+				MissingPolicyMode: tt.mode,
+				RecoverPanics:     true,
+			})
+			exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+			calls := 0
+			_, tl, _ := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+				calls++
+				return 0, errors.New("nope")
+			}, true)
+			if calls != tt.wantCalls {
+				t.Fatalf("calls=%d, want %d", calls, tt.wantCalls)
+			}
+			if len(obs.events) != 1 {
+				t.Fatalf("events=%d, want 1", len(obs.events))
+			}
+			ev := obs.events[0]
+			if ev.Key != key || ev.Reason != tt.wantReason || ev.Mode != tt.wantMode || ev.Err == nil {
+				t.Fatalf("event=%+v, want reason %q mode %q", ev, tt.wantReason, tt.wantMode)
+			}
+			if got := tl.Attributes["policy_fallback"]; got != tt.wantReason {
+				t.Fatalf("policy_fallback=%q, want %q", got, tt.wantReason)
+			}
+			if got := tl.Attributes["missing_policy_mode"]; got != tt.wantMode {
+				t.Fatalf("missing_policy_mode=%q, want %q", got, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestPolicyFallback_NotReportedOnSuccess(t *testing.T) {
+	obs := &fallbackObserver{}
+	exec := NewExecutorFromOptions(ExecutorOptions{Observer: obs})
+	if err := exec.Do(context.Background(), policy.PolicyKey{Name: "ok"}, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("err=%v", err)
+	}
+	if len(obs.events) != 0 {
+		t.Fatalf("events=%v, want none", obs.events)
+	}
+}
+
+func TestPolicyFallback_PerCallObserver(t *testing.T) {
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider:          stubProvider{err: controlplane.ErrPolicyNotFound},
+		MissingPolicyMode: FailureAllow,
+	})
+	obs := &fallbackObserver{}
+	ctx := observe.WithObserver(context.Background(), obs)
+	_ = exec.Do(ctx, policy.PolicyKey{Name: "x"}, func(context.Context) error { return nil })
+	if len(obs.events) != 1 || obs.events[0].Reason != observe.PolicyFallbackNotFound {
+		t.Fatalf("events=%+v, want one policy_not_found", obs.events)
+	}
+}
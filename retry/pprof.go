@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"context"
+	"runtime/pprof"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// ProfilerLabelKey is the pprof label that carries the policy key of the call an
+// operation runs for (see WithProfilerLabels).
+const ProfilerLabelKey = "recourse_key"
+
+// WithProfilerLabels sets whether operations run with the pprof label
+// ProfilerLabelKey set to the call's policy key, so CPU and goroutine profiles
+// attribute time spent in retried operations, including hedges, to their keys
+// (e.g. `go tool pprof -tagfocus=recourse_key=payments.Charge`). Labels are added to
+// any the caller's context already carries. Off by default: each attempt then
+// allocates a label set.
+func WithProfilerLabels(enabled bool) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.ProfilerLabels = enabled
+	}
+}
+
+// withProfilerLabels wraps op to run under the key's pprof labels, in whichever
+// goroutine runs the attempt.
+func withProfilerLabels[T any](key policy.PolicyKey, op OperationValue[T]) OperationValue[T] {
+	labels := pprof.Labels(ProfilerLabelKey, key.String())
+	return func(ctx context.Context) (val T, err error) {
+		pprof.Do(ctx, labels, func(ctx context.Context) {
+			val, err = op(ctx)
+		})
+		return val, err
+	}
+}
@@ -0,0 +1,33 @@
+package retry
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestWithProfilerLabels(t *testing.T) {
+	key := policy.ParseKey("svc.Profiled")
+	run := func(exec *Executor) (label string, ok bool, outer string) {
+		ctx := pprof.WithLabels(context.Background(), pprof.Labels("caller", "test"))
+		_ = exec.Do(ctx, key, func(ctx context.Context) error {
+			label, ok = pprof.Label(ctx, ProfilerLabelKey)
+			outer, _ = pprof.Label(ctx, "caller")
+			return nil
+		})
+		return label, ok, outer
+	}
+
+	if _, ok, _ := run(NewExecutor(WithPolicy(key.String()))); ok {
+		t.Fatal("label set without WithProfilerLabels")
+	}
+	label, ok, outer := run(NewExecutor(WithPolicy(key.String()), WithProfilerLabels(true)))
+	if !ok || label != key.String() {
+		t.Fatalf("label = %q, %v; want %q", label, ok, key.String())
+	}
+	if outer != "test" {
+		t.Fatalf("caller label = %q, want the caller's labels kept", outer)
+	}
+}
@@ -0,0 +1,134 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultProviderTimeout bounds each policy lookup when ProviderProtection.Timeout is unset.
+const DefaultProviderTimeout = 250 * time.Millisecond
+
+// ProviderProtection configures the guard placed around the executor's policy
+// provider by WithProviderProtection.
+type ProviderProtection struct {
+	// Timeout bounds each lookup. Defaults to DefaultProviderTimeout.
+	Timeout time.Duration
+	// Threshold is the number of consecutive failed lookups that open the provider's
+	// circuit. Defaults to 5.
+	Threshold int
+	// Cooldown is how long the circuit stays open before a probe lookup. Defaults to 10s.
+	Cooldown time.Duration
+}
+
+// WithProviderProtection guards policy lookups so a degraded control plane cannot
+// slow down or destabilize the calls it configures.
+//
+// Each lookup is bounded by Timeout: a provider that has not answered by then is
+// abandoned and the lookup fails with ErrProviderUnavailable, even if the provider
+// ignores its context. Consecutive failures (anything but ErrPolicyNotFound) open a
+// circuit breaker around the provider; while open, lookups fail immediately without
+// calling it. Failed lookups are then handled by the missing policy mode.
+//
+// When the observer implements observe.ProviderObserver, every lookup is reported with
+// its latency and error.
+func WithProviderProtection(p ProviderProtection) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.ProviderProtection = &p
+	}
+}
+
+// guardedProvider applies ProviderProtection to a provider.
+type guardedProvider struct {
+	provider controlplane.PolicyProvider
+	timeout  time.Duration
+	breaker  circuit.CircuitBreaker
+	obs      observe.ProviderObserver
+	timing   timeSource
+}
+
+func newGuardedProvider(p controlplane.PolicyProvider, prot ProviderProtection, obs observe.Observer, timing timeSource) *guardedProvider {
+	g := &guardedProvider{
+		provider: p,
+		timeout:  prot.Timeout,
+		breaker:  circuit.NewConsecutiveFailureBreaker(prot.Threshold, prot.Cooldown),
+		timing:   timing,
+	}
+	if g.timeout <= 0 {
+		g.timeout = DefaultProviderTimeout
+	}
+	g.obs, _ = obs.(observe.ProviderObserver)
+	return g
+}
+
+type resolution struct {
+	pol      policy.EffectivePolicy
+	err      error
+	panicked any
+}
+
+func (g *guardedProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	if d := g.breaker.Allow(ctx); !d.Allowed {
+		err := fmt.Errorf("%w: provider circuit %s", controlplane.ErrProviderUnavailable, d.State)
+		g.report(ctx, observe.PolicyResolutionEvent{Key: key, Err: err, Rejected: true})
+		return policy.EffectivePolicy{}, err
+	}
+
+	start := g.timing.now()
+	lookupCtx, cancel := g.timing.withTimeout(ctx, g.timeout)
+	defer cancel()
+
+	done := make(chan resolution, 1)
+	go func() {
+		var r resolution
+		defer func() {
+			if p := recover(); p != nil {
+				r.panicked = p
+			}
+			done <- r
+		}()
+		r.pol, r.err = g.provider.GetEffectivePolicy(lookupCtx, key)
+	}()
+
+	var r resolution
+	ev := observe.PolicyResolutionEvent{Key: key}
+	select {
+	case r = <-done:
+	case <-lookupCtx.Done():
+		if ctx.Err() != nil {
+			r.err = ctx.Err()
+		} else {
+			r.err = fmt.Errorf("%w: lookup timed out after %v", controlplane.ErrProviderUnavailable, g.timeout)
+			ev.TimedOut = true
+		}
+	}
+	ev.Duration = g.timing.since(start)
+
+	if r.panicked != nil {
+		g.breaker.RecordFailure(ctx)
+		ev.Err = fmt.Errorf("%w: provider panicked", controlplane.ErrProviderUnavailable)
+		g.report(ctx, ev)
+		// Re-raise on the calling goroutine, where the executor may recover it.
+		panic(r.panicked)
+	}
+	if r.err == nil || errors.Is(r.err, controlplane.ErrPolicyNotFound) {
+		g.breaker.RecordSuccess(ctx)
+	} else {
+		g.breaker.RecordFailure(ctx)
+	}
+	ev.Err = r.err
+	g.report(ctx, ev)
+	return r.pol, r.err
+}
+
+func (g *guardedProvider) report(ctx context.Context, ev observe.PolicyResolutionEvent) {
+	if g.obs != nil {
+		g.obs.OnPolicyResolution(ctx, ev)
+	}
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// hangingProvider blocks until release is closed, ignoring its context.
+type hangingProvider struct {
+	calls   atomic.Int32
+	release chan struct{}
+}
+
+func (p *hangingProvider) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.calls.Add(1)
+	<-p.release
+	return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+}
+
+type resolutionRecorder struct {
+	observe.BaseObserver
+	mu     sync.Mutex
+	events []observe.PolicyResolutionEvent
+}
+
+func (r *resolutionRecorder) OnPolicyResolution(_ context.Context, ev observe.PolicyResolutionEvent) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, ev)
+}
+
+func TestExecutor_ProviderProtection(t *testing.T) {
+	key := policy.ParseKey("svc.Guarded")
+	provider := &hangingProvider{release: make(chan struct{})}
+	defer close(provider.release)
+	rec := &resolutionRecorder{}
+	exec := NewExecutor(
+		WithProvider(provider),
+		WithObserver(rec),
+		WithMissingPolicyMode(FailureFallback),
+		WithProviderProtection(ProviderProtection{Timeout: 20 * time.Millisecond, Threshold: 2, Cooldown: time.Minute}),
+	)
+
+	for i := range 3 {
+		start := time.Now()
+		if err := exec.Do(context.Background(), key, func(context.Context) error { return nil }); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+		if elapsed := time.Since(start); elapsed > time.Second {
+			t.Fatalf("call %d took %v; the hanging provider was not bounded", i, elapsed)
+		}
+	}
+	if got := provider.calls.Load(); got != 2 {
+		t.Fatalf("provider calls = %d, want 2 before the circuit opened", got)
+	}
+
+	rec.mu.Lock()
+	defer rec.mu.Unlock()
+	if len(rec.events) != 3 {
+		t.Fatalf("events = %d, want 3", len(rec.events))
+	}
+	for i, ev := range rec.events[:2] {
+		if !ev.TimedOut || !errors.Is(ev.Err, controlplane.ErrProviderUnavailable) || ev.Duration < 20*time.Millisecond {
+			t.Fatalf("event %d = %+v, want a timeout", i, ev)
+		}
+	}
+	if ev := rec.events[2]; !ev.Rejected || !errors.Is(ev.Err, controlplane.ErrProviderUnavailable) {
+		t.Fatalf("event 2 = %+v, want a rejection by the open circuit", ev)
+	}
+}
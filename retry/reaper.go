@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// States of an attempt goroutine in a hedged group (inflight.state).
+const (
+	attemptRunning int32 = iota
+	attemptExited
+	attemptDetached // Still running after its group returned; the reaper waits for it.
+)
+
+// reaper supervises the goroutines that run hedged-group attempts. A group detaches
+// the attempts still running when it returns instead of waiting for them, and the
+// reaper tracks them until they exit.
+type reaper struct {
+	mu      sync.Mutex
+	running int           // Attempt goroutines running, detached or not.
+	idle    chan struct{} // Closed when running drops to zero; nil if nobody waits.
+
+	detached atomic.Int64
+}
+
+// start registers an attempt goroutine about to start.
+func (r *reaper) start() {
+	r.mu.Lock()
+	r.running++
+	r.mu.Unlock()
+}
+
+// exit is deferred by the goroutine of attempt fl.
+func (r *reaper) exit(fl *inflight) {
+	if !fl.state.CompareAndSwap(attemptRunning, attemptExited) {
+		r.detached.Add(-1)
+	}
+	r.mu.Lock()
+	r.running--
+	if r.running == 0 && r.idle != nil {
+		close(r.idle)
+		r.idle = nil
+	}
+	r.mu.Unlock()
+}
+
+// detach hands the attempts of a returning group that are still running to the reaper.
+func (r *reaper) detach(attempts []*inflight) {
+	for _, fl := range attempts {
+		if fl.state.CompareAndSwap(attemptRunning, attemptDetached) {
+			r.detached.Add(1)
+		}
+	}
+}
+
+// Drain waits until every goroutine the executor started for hedged-group attempts has
+// exited, including attempts detached from calls that already returned, or until ctx
+// ends. Call it at shutdown, or in tests that check for leaked goroutines. Attempts
+// that ignore cancellation keep it waiting; see WithStragglerGrace.
+func (e *Executor) Drain(ctx context.Context) error {
+	r := &e.reaper
+	r.mu.Lock()
+	if r.running == 0 {
+		r.mu.Unlock()
+		return nil
+	}
+	if r.idle == nil {
+		r.idle = make(chan struct{})
+	}
+	idle := r.idle
+	r.mu.Unlock()
+
+	select {
+	case <-idle:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
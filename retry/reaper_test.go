@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_DetachedAttempts_Drain(t *testing.T) {
+	key := policy.ParseKey("svc.Detach")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1),
+			policy.HedgeDelay(10*time.Millisecond)),
+	)
+
+	block := make(chan struct{})
+	var calls atomic.Int32
+	_, err := DoValue(context.Background(), exec, key, func(context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			<-block // The primary ignores cancellation.
+			return 0, context.Canceled
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+	if got := exec.Stats().DetachedAttempts; got != 1 {
+		t.Fatalf("DetachedAttempts = %d, want the losing primary", got)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := exec.Drain(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Drain = %v with the primary still running, want DeadlineExceeded", err)
+	}
+
+	close(block)
+	if err := exec.Drain(context.Background()); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+	if got := exec.Stats().DetachedAttempts; got != 0 {
+		t.Fatalf("DetachedAttempts = %d after Drain, want 0", got)
+	}
+}
+
+func TestExecutor_HedgedRetries_JoinAttempts(t *testing.T) {
+	key := policy.ParseKey("svc.Join")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(3), policy.ConstantBackoff(time.Millisecond),
+			policy.EnableHedging(), policy.HedgeMaxAttempts(2), policy.HedgeDelay(time.Millisecond)),
+	)
+
+	var running, maxRunning atomic.Int32
+	err := exec.Do(context.Background(), key, func(context.Context) error {
+		n := running.Add(1)
+		defer running.Add(-1)
+		for {
+			m := maxRunning.Load()
+			if n <= m || maxRunning.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(5 * time.Millisecond)
+		return errors.New("unavailable")
+	})
+	if err == nil {
+		t.Fatal("Do succeeded, want the attempts' failure")
+	}
+	// A retry starts only after every attempt of the previous group has reported, so
+	// no more than one group's attempts ever run at once.
+	if got := maxRunning.Load(); got > 3 {
+		t.Fatalf("max concurrent attempts = %d, want at most 3 (primary + 2 hedges)", got)
+	}
+	if got := exec.Stats().DetachedAttempts; got != 0 {
+		t.Fatalf("DetachedAttempts = %d, want 0 for groups that waited for every attempt", got)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := exec.Drain(ctx); err != nil {
+		t.Fatalf("Drain: %v", err)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"time"
 
 	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
 	"github.com/aponysus/recourse/policy"
 )
 
@@ -126,3 +127,18 @@ func TestExecutor_RemoteProvider_NegativeLink(t *testing.T) {
 		t.Errorf("expected 1 source call (cached negative), got %d", source.Calls)
 	}
 }
+
+func TestExecutor_LKGPolicySourceAttribute(t *testing.T) {
+	key := policy.ParseKey("remote.lkg")
+	pol := policy.New("remote.lkg", policy.MaxAttempts(2))
+	pol.Meta.Source = policy.PolicySourceLKG
+	exec := NewExecutor(WithProvider(stubProvider{pol: pol}))
+
+	ctx, capture := observe.RecordTimeline(context.Background())
+	if err := exec.Do(ctx, key, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := capture.Timeline().Attributes["policy_source"]; got != "lkg" {
+		t.Fatalf("policy_source = %q, want lkg", got)
+	}
+}
@@ -0,0 +1,78 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/policy"
+)
+
+// reservation is the part of a budget a call reserved up front
+// (policy.BudgetRef.Reserve) for one kind of its attempts.
+type reservation struct {
+	budget  budget.Budget // Refunded the unused attempts if it is a budget.Refunder.
+	key     policy.PolicyKey
+	kind    budget.AttemptKind
+	ref     policy.BudgetRef // Per-attempt reference; Cost is at least 1.
+	units   int64            // Attempts reserved.
+	used    atomic.Int64     // Attempts taken; -1 once the call finished.
+	release func()
+}
+
+// reserve takes units attempts' worth of cb's budget in a single decision and records
+// the reservation in cb.
+func (e *Executor) reserve(ctx context.Context, key policy.PolicyKey, kind budget.AttemptKind, cb *callBudget, units int64) (budget.Decision, bool) {
+	if cb.ref.Name == "" {
+		return budget.Decision{}, true
+	}
+	ref := cb.ref
+	ref.Cost = max(ref.Cost, 1)
+	total := *cb
+	total.ref.Cost = int(units) * ref.Cost
+	decision, ok := e.decideBudget(ctx, key, &total, 0, kind)
+	if !ok {
+		return decision, false
+	}
+	cb.res = &reservation{budget: cb.budget, key: key, kind: kind, ref: ref, units: units, release: decision.Release}
+	return decision, true
+}
+
+// take claims one reserved attempt. It reports false without a reservation, once the
+// reservation is used up, or once the call finished.
+func (r *reservation) take() bool {
+	if r == nil {
+		return false
+	}
+	for {
+		used := r.used.Load()
+		if used < 0 || used >= r.units {
+			return false
+		}
+		if r.used.CompareAndSwap(used, used+1) {
+			return true
+		}
+	}
+}
+
+// finish ends the reservation, refunding the unused attempts to budgets that implement
+// budget.Refunder and releasing it.
+func (r *reservation) finish(ctx context.Context) {
+	if r == nil {
+		return
+	}
+	used := r.used.Swap(-1)
+	if used < 0 {
+		return
+	}
+	if unused := r.units - used; unused > 0 {
+		if refunder, ok := r.budget.(budget.Refunder); ok {
+			ref := r.ref
+			ref.Cost = int(unused) * r.ref.Cost
+			refunder.Refund(ctx, r.key, r.kind, ref)
+		}
+	}
+	if r.release != nil {
+		r.release()
+	}
+}
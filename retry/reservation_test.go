@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_ReserveBudget_RefundsUnusedAttempts(t *testing.T) {
+	for _, wantTimeline := range []bool{false, true} {
+		key := policy.ParseKey("svc.Reserve")
+		b := budget.NewTokenBucketBudget(4, 0)
+		budgets := budget.NewRegistry()
+		budgets.MustRegister("b", b)
+		exec := NewExecutor(
+			WithPolicy(key.String(), policy.MaxAttempts(3), policy.Budget("b"), policy.ReserveBudget()),
+			WithBudgetRegistry(budgets),
+		)
+
+		// The first call reserves 3 of the 4 tokens and spends 1; the refund lets the
+		// second call reserve 3 again.
+		for i := 0; i < 2; i++ {
+			if _, _, err := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+				return 1, nil
+			}, wantTimeline); err != nil {
+				t.Fatalf("timeline=%v call %d: %v", wantTimeline, i, err)
+			}
+			if available, _ := b.Tokens(); available != 4-float64(i+1) {
+				t.Fatalf("timeline=%v call %d: available = %v, want %d", wantTimeline, i, available, 4-(i+1))
+			}
+		}
+	}
+}
+
+func TestExecutor_ReserveBudget_DeniedUpFront(t *testing.T) {
+	for _, wantTimeline := range []bool{false, true} {
+		key := policy.ParseKey("svc.ReserveDenied")
+		b := budget.NewTokenBucketBudget(2, 0)
+		budgets := budget.NewRegistry()
+		budgets.MustRegister("b", b)
+		exec := NewExecutor(
+			WithPolicy(key.String(), policy.MaxAttempts(3), policy.Budget("b"), policy.ReserveBudget()),
+			WithBudgetRegistry(budgets),
+		)
+
+		calls := 0
+		_, tl, err := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+			calls++
+			return 1, nil
+		}, wantTimeline)
+		if !errors.Is(err, ErrBudgetDenied) {
+			t.Fatalf("timeline=%v: err = %v, want ErrBudgetDenied", wantTimeline, err)
+		}
+		if calls != 0 {
+			t.Fatalf("timeline=%v: op ran %d times, want 0", wantTimeline, calls)
+		}
+		if available, _ := b.Tokens(); available != 2 {
+			t.Fatalf("timeline=%v: available = %v, want the bucket untouched", wantTimeline, available)
+		}
+		if wantTimeline && tl.Attributes["budget_reservation"] != budget.ReasonBudgetDenied {
+			t.Fatalf("attributes = %v, want budget_reservation=%s", tl.Attributes, budget.ReasonBudgetDenied)
+		}
+	}
+}
+
+func TestExecutor_ReserveBudget_Hedges(t *testing.T) {
+	key := policy.ParseKey("svc.ReserveHedge")
+	retryBudget := budget.NewTokenBucketBudget(10, 0)
+	hedgeBudget := budget.NewTokenBucketBudget(10, 0)
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("retry", retryBudget)
+	budgets.MustRegister("hedge", hedgeBudget)
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(2), policy.Budget("retry"),
+			policy.EnableHedging(), policy.HedgeMaxAttempts(2), policy.HedgeDelay(5*time.Millisecond),
+			policy.HedgeBudget("hedge"), policy.ReserveBudget()),
+		WithBudgetRegistry(budgets),
+	)
+
+	_, tl, err := doValueInternal(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		time.Sleep(8 * time.Millisecond)
+		return 1, nil
+	}, true)
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+	hedges := 0
+	for _, a := range tl.Attempts {
+		if a.BudgetReason != budget.ReasonReserved {
+			t.Fatalf("attempt %+v: budget reason %q, want %q", a, a.BudgetReason, budget.ReasonReserved)
+		}
+		if a.IsHedge {
+			hedges++
+		}
+	}
+	if available, _ := retryBudget.Tokens(); available != 9 {
+		t.Fatalf("retry budget available = %v, want 9", available)
+	}
+	if available, _ := hedgeBudget.Tokens(); available != 10-float64(hedges) {
+		t.Fatalf("hedge budget available = %v, want %d after %d hedges", available, 10-hedges, hedges)
+	}
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"sync"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/controlplane"
+)
+
+// remoteBudgets tracks the budgets an executor registered from control-plane resources.
+type remoteBudgets struct {
+	mu    sync.Mutex
+	specs map[string]controlplane.BudgetSpec
+}
+
+// watchResources materializes the provider's resources into the executor's budget and
+// circuit registries now and on every change.
+func (e *Executor) watchResources(rp controlplane.ResourceProvider) {
+	if e.budgets == nil {
+		e.budgets = budget.NewRegistry()
+	}
+	rp.WatchResources(e.applyResources)
+}
+
+// applyResources registers the remote budgets and sets the global circuit parameters.
+// A budget whose spec is unchanged keeps its instance, and so its state; budgets
+// dropped from the control plane are unregistered. Budgets registered locally under
+// other names are left alone, while a remote budget replaces a local one of the same name.
+func (e *Executor) applyResources(r controlplane.Resources) {
+	e.remoteBudgets.mu.Lock()
+	defer e.remoteBudgets.mu.Unlock()
+
+	for name := range e.remoteBudgets.specs {
+		if _, ok := r.Budgets[name]; !ok {
+			e.budgets.Unregister(name)
+		}
+	}
+	for name, spec := range r.Budgets {
+		if prev, ok := e.remoteBudgets.specs[name]; ok && prev == spec {
+			continue
+		}
+		var b budget.Budget
+		switch spec.Type {
+		case controlplane.BudgetTypeUnlimited:
+			b = budget.UnlimitedBudget{}
+		default:
+			b = budget.NewTokenBucketBudget(spec.Capacity, spec.RefillPerSecond).WithPriorityThresholds(budget.PriorityThresholds{
+				Low:    spec.LowPriorityThreshold,
+				Normal: spec.NormalPriorityThreshold,
+			})
+		}
+		_ = e.budgets.Register(name, b)
+	}
+	e.remoteBudgets.specs = r.Budgets
+
+	if r.Circuit != nil {
+		e.circuits.SetOverride(r.Circuit.Threshold, r.Circuit.Cooldown)
+	} else {
+		e.circuits.SetOverride(0, 0)
+	}
+}
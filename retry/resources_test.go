@@ -0,0 +1,80 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// resourceProvider is a StaticProvider whose resources the test pushes directly.
+type resourceProvider struct {
+	controlplane.StaticProvider
+	fns []func(controlplane.Resources)
+}
+
+func (p *resourceProvider) WatchResources(fn func(controlplane.Resources)) {
+	p.fns = append(p.fns, fn)
+}
+
+func (p *resourceProvider) publish(r controlplane.Resources) {
+	for _, fn := range p.fns {
+		fn(r)
+	}
+}
+
+func TestExecutor_RemoteResources(t *testing.T) {
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("local", budget.UnlimitedBudget{})
+	p := &resourceProvider{}
+	exec := NewExecutorFromOptions(ExecutorOptions{Provider: p, Budgets: budgets})
+	if len(p.fns) != 1 {
+		t.Fatalf("executor registered %d resource watchers, want 1", len(p.fns))
+	}
+
+	bucket := controlplane.BudgetSpec{Capacity: 5, RefillPerSecond: 1}
+	p.publish(controlplane.Resources{
+		Budgets: map[string]controlplane.BudgetSpec{"remote": bucket},
+		Circuit: &controlplane.CircuitSpec{Threshold: 1, Cooldown: time.Minute},
+	})
+	b, ok := budgets.Get("remote")
+	tb, isBucket := b.(*budget.TokenBucketBudget)
+	if !ok || !isBucket {
+		t.Fatalf("remote budget = %T, %v; want token bucket", b, ok)
+	}
+	if _, capacity := tb.Tokens(); capacity != 5 {
+		t.Fatalf("capacity = %v, want 5", capacity)
+	}
+
+	// The override replaces the policy's threshold of 10.
+	key := policy.ParseKey("svc.Get")
+	cb := exec.circuits.Get(key, policy.CircuitPolicy{Enabled: true, Threshold: 10, Cooldown: time.Second})
+	cb.RecordFailure(context.Background())
+	if state := cb.(*circuit.ConsecutiveFailureBreaker).State(); state != circuit.StateOpen {
+		t.Fatalf("breaker state = %v after one failure, want open", state)
+	}
+
+	// An unchanged spec keeps the budget instance; a dropped one is unregistered.
+	p.publish(controlplane.Resources{Budgets: map[string]controlplane.BudgetSpec{"remote": bucket}})
+	if again, _ := budgets.Get("remote"); again != b {
+		t.Fatal("unchanged budget spec replaced the budget")
+	}
+	p.publish(controlplane.Resources{})
+	if _, ok := budgets.Get("remote"); ok {
+		t.Fatal("remote budget still registered after removal")
+	}
+	if _, ok := budgets.Get("local"); !ok {
+		t.Fatal("local budget unregistered")
+	}
+
+	// Clearing the circuit parameters restores the policy's threshold.
+	cb = exec.circuits.Get(key, policy.CircuitPolicy{Enabled: true, Threshold: 10, Cooldown: time.Second})
+	cb.RecordFailure(context.Background())
+	if state := cb.(*circuit.ConsecutiveFailureBreaker).State(); state != circuit.StateClosed {
+		t.Fatalf("breaker state = %v after clearing override, want closed", state)
+	}
+}
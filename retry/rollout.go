@@ -0,0 +1,62 @@
+package retry
+
+import (
+	"context"
+	"math/rand"
+	"os"
+	"sync"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Values of the "policy_version" timeline attribute for policies with a rollout.
+const (
+	PolicyVersionCanary = "canary"
+	PolicyVersionStable = "stable"
+)
+
+type rolloutKeyContextKey struct{}
+
+// WithRolloutKey returns a context whose calls are bucketed by key under
+// policy.RolloutByRequest rollouts, e.g. a request ID, so every call made for one
+// request uses the same policy version. Without a key such calls are bucketed randomly.
+func WithRolloutKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, rolloutKeyContextKey{}, key)
+}
+
+var rolloutHost = sync.OnceValue(func() string {
+	h, _ := os.Hostname()
+	return h
+})
+
+// applyRollout picks the version of pol (which has a rollout) for this call: pol itself
+// for canary calls, or its previous version (default policy if unset) otherwise.
+func applyRollout(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy) (policy.EffectivePolicy, string) {
+	r := pol.Rollout
+	var canary bool
+	switch subject, _ := ctx.Value(rolloutKeyContextKey{}).(string); {
+	case r.By != policy.RolloutByRequest:
+		canary = r.Canary(key, rolloutHost())
+	case subject != "":
+		canary = r.Canary(key, subject)
+	default:
+		canary = rand.Float64()*100 < r.Percent
+	}
+	if canary {
+		return pol, PolicyVersionCanary
+	}
+
+	var prev policy.EffectivePolicy
+	if r.Previous != nil {
+		prev = *r.Previous
+	} else {
+		prev = policy.DefaultPolicyFor(key)
+	}
+	prev.Key = key
+	prev, err := prev.Normalize()
+	if err != nil {
+		return pol, PolicyVersionCanary
+	}
+	prev.Meta.Source, prev.Meta.Provider = pol.Meta.Source, pol.Meta.Provider
+	return prev, PolicyVersionStable
+}
@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_Rollout(t *testing.T) {
+	key := policy.ParseKey("svc.Canary")
+	pol := policy.New("svc.Canary", policy.MaxAttempts(4))
+	pol.ID = "v2"
+	pol.Rollout = &policy.Rollout{
+		Percent:  30,
+		By:       policy.RolloutByRequest,
+		Previous: &policy.EffectivePolicy{ID: "v1", Retry: policy.RetryPolicy{MaxAttempts: 2}},
+	}
+	exec := NewExecutor(WithProvider(&controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{key: pol}}))
+
+	run := func(reqID string) (attempts int, version, policyID string) {
+		ctx, capture := observe.RecordTimeline(WithRolloutKey(context.Background(), reqID))
+		err := exec.Do(ctx, key, func(ctx context.Context) error {
+			attempts++
+			return context.DeadlineExceeded
+		})
+		if err == nil {
+			t.Fatal("expected failure")
+		}
+		tl := capture.Timeline()
+		return attempts, tl.Attributes["policy_version"], tl.PolicyID
+	}
+
+	canary := 0
+	for i := range 200 {
+		reqID := strconv.Itoa(i)
+		attempts, version, id := run(reqID)
+		switch version {
+		case PolicyVersionCanary:
+			canary++
+			if attempts != 4 || id != "v2" {
+				t.Fatalf("canary call: %d attempts, policy %q; want 4, v2", attempts, id)
+			}
+		case PolicyVersionStable:
+			if attempts != 2 || id != "v1" {
+				t.Fatalf("stable call: %d attempts, policy %q; want 2, v1", attempts, id)
+			}
+		default:
+			t.Fatalf("policy_version = %q", version)
+		}
+		if _, again, _ := run(reqID); again != version {
+			t.Fatalf("request %s switched version: %s then %s", reqID, version, again)
+		}
+	}
+	if canary < 30 || canary > 90 {
+		t.Fatalf("canary calls = %d/200, want about 30%%", canary)
+	}
+}
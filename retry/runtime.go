@@ -0,0 +1,162 @@
+package retry
+
+import (
+	"sync"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/hedge"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+// RuntimeOptions configures a Runtime.
+type RuntimeOptions struct {
+	// Budgets is the shared budget registry.
+	// Defaults to a registry with the "unlimited" budget registered.
+	Budgets *budget.Registry
+	// Circuits is the shared circuit breaker registry. Defaults to an empty registry.
+	Circuits *circuit.Registry
+	// Triggers is the shared hedge trigger registry.
+	// Defaults to a registry with "fixed_delay", "p90", "p95", and "p99" registered.
+	Triggers *hedge.Registry
+	// Observer receives events from every executor created with the runtime.
+	// Defaults to NoopObserver.
+	Observer observe.Observer
+	// Shedder, if set, admits calls for every executor created with the runtime, so
+	// one shed.Limiter bounds the process. See WithShedder.
+	Shedder shed.Shedder
+}
+
+// Runtime is process-wide state shared by several executors.
+//
+// Executors built from the same Runtime (for example, one wrapping an HTTP client,
+// one in a gRPC interceptor, and one around a database) share budgets, circuit
+// breakers, hedge triggers, latency trackers, and observers. A policy key that
+// trips a circuit through one executor is therefore open for all of them, and
+// a shared budget is drawn down by all of them.
+//
+// A Runtime is safe for concurrent use.
+type Runtime struct {
+	budgets  *budget.Registry
+	circuits *circuit.Registry
+	triggers *hedge.Registry
+	observer observe.Observer
+	shedder  shed.Shedder
+	trackers *latencyTrackers
+}
+
+// NewRuntime creates a Runtime, filling unset options with the same defaults
+// as NewDefaultExecutor.
+func NewRuntime(opts RuntimeOptions) *Runtime {
+	r := &Runtime{
+		budgets:  opts.Budgets,
+		circuits: opts.Circuits,
+		triggers: opts.Triggers,
+		observer: opts.Observer,
+		shedder:  opts.Shedder,
+		trackers: newLatencyTrackers(),
+	}
+
+	if r.budgets == nil {
+		r.budgets = budget.NewRegistry()
+		r.budgets.MustRegister("unlimited", &budget.UnlimitedBudget{})
+	}
+	if r.circuits == nil {
+		r.circuits = circuit.NewRegistry()
+	}
+	if r.triggers == nil {
+		r.triggers = hedge.NewRegistry()
+		r.triggers.Register("fixed_delay", &hedge.FixedDelayTrigger{}) // Delay comes from policy
+		r.triggers.Register("p90", &hedge.LatencyTrigger{Percentile: "p90"})
+		r.triggers.Register("p95", &hedge.LatencyTrigger{Percentile: "p95"})
+		r.triggers.Register("p99", &hedge.LatencyTrigger{Percentile: "p99"})
+	}
+	if r.observer == nil {
+		r.observer = &observe.NoopObserver{}
+	}
+
+	return r
+}
+
+// Budgets returns the shared budget registry.
+func (r *Runtime) Budgets() *budget.Registry { return r.budgets }
+
+// Circuits returns the shared circuit breaker registry.
+func (r *Runtime) Circuits() *circuit.Registry { return r.circuits }
+
+// Triggers returns the shared hedge trigger registry.
+func (r *Runtime) Triggers() *hedge.Registry { return r.triggers }
+
+// Observer returns the shared observer.
+func (r *Runtime) Observer() observe.Observer { return r.observer }
+
+// NewExecutor creates an Executor that uses the runtime's shared state.
+//
+// Classifiers are per-executor: built-ins are registered and AutoClassifier is the
+// default, as in NewDefaultExecutor. Options are applied last, so an executor can
+// still override a shared registry or add its own observer. An observer passed
+// with WithObserver receives events alongside the runtime observer rather than
+// replacing it.
+func (r *Runtime) NewExecutor(opts ...ExecutorOption) *Executor {
+	classifierReg := classify.NewRegistry()
+	classify.RegisterBuiltins(classifierReg)
+
+	defaultOpts := []ExecutorOption{
+		WithClassifiers(classifierReg),
+		WithDefaultClassifier(classify.AutoClassifier{}),
+		WithRuntime(r),
+	}
+	return NewExecutor(append(defaultOpts, opts...)...)
+}
+
+// WithRuntime sets the shared runtime.
+// Budgets, circuits, and triggers not set explicitly are taken from the runtime.
+func WithRuntime(r *Runtime) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Runtime = r
+	}
+}
+
+// apply fills unset options from the runtime.
+func (r *Runtime) apply(opts ExecutorOptions) ExecutorOptions {
+	if opts.Budgets == nil {
+		opts.Budgets = r.budgets
+	}
+	if opts.Circuits == nil {
+		opts.Circuits = r.circuits
+	}
+	if opts.Triggers == nil {
+		opts.Triggers = r.triggers
+	}
+	if opts.Shedder == nil {
+		opts.Shedder = r.shedder
+	}
+	switch {
+	case opts.Observer == nil || isNoopObserver(opts.Observer):
+		opts.Observer = r.observer
+	case !isNoopObserver(r.observer):
+		opts.Observer = observe.MultiObserver{Observers: []observe.Observer{r.observer, opts.Observer}}
+	}
+	return opts
+}
+
+// latencyTrackers holds per-key latency trackers used by percentile hedge triggers.
+// Lookups are lock-free; trackers are created once per key and never removed.
+type latencyTrackers struct {
+	m sync.Map // policy.PolicyKey -> hedge.LatencyTracker
+}
+
+func newLatencyTrackers() *latencyTrackers {
+	return &latencyTrackers{}
+}
+
+func (l *latencyTrackers) get(key policy.PolicyKey) hedge.LatencyTracker {
+	if t, ok := l.m.Load(key); ok {
+		return t.(hedge.LatencyTracker)
+	}
+	t, _ := l.m.LoadOrStore(key, hedge.LatencyTracker(hedge.NewRingBufferTracker(256)))
+	return t.(hedge.LatencyTracker)
+}
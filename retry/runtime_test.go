@@ -0,0 +1,111 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/circuit"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type countingObserver struct {
+	observe.BaseObserver
+	failures atomic.Int32
+}
+
+func (o *countingObserver) OnFailure(context.Context, policy.PolicyKey, observe.Timeline) {
+	o.failures.Add(1)
+}
+
+func TestRuntime_SharesCircuitAcrossExecutors(t *testing.T) {
+	key := policy.PolicyKey{Namespace: "db", Name: "query"}
+	pol := func(p *policy.EffectivePolicy) {
+		p.Retry.MaxAttempts = 1
+		p.Circuit = policy.CircuitPolicy{Enabled: true, Threshold: 2, Cooldown: time.Minute}
+	}
+
+	rt := NewRuntime(RuntimeOptions{})
+	a := rt.NewExecutor(WithPolicyKey(key, pol))
+	b := rt.NewExecutor(WithPolicyKey(key, pol))
+
+	fail := func(context.Context) error { return errors.New("fail") }
+	_ = a.Do(context.Background(), key, fail)
+	_ = b.Do(context.Background(), key, fail)
+
+	cb := rt.Circuits().Get(key, policy.CircuitPolicy{Enabled: true, Threshold: 2, Cooldown: time.Minute})
+	if cb.State() != circuit.StateOpen {
+		t.Fatalf("expected shared circuit to be open, got %v", cb.State())
+	}
+
+	calls := 0
+	err := a.Do(context.Background(), key, func(context.Context) error {
+		calls++
+		return nil
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("expected ErrCircuitOpen, got %v", err)
+	}
+	if calls != 0 {
+		t.Fatalf("expected no calls while open, got %d", calls)
+	}
+}
+
+func TestRuntime_SharesTrackersAndRegistries(t *testing.T) {
+	rt := NewRuntime(RuntimeOptions{})
+	a := rt.NewExecutor()
+	b := rt.NewExecutor()
+
+	if a.budgets != b.budgets || a.triggers != b.triggers || a.circuits != b.circuits {
+		t.Fatal("expected executors to share registries")
+	}
+	if _, ok := a.budgets.Get("unlimited"); !ok {
+		t.Fatal("expected unlimited budget to be registered")
+	}
+
+	key := policy.PolicyKey{Name: "tracked"}
+	if a.getTracker(key) != b.getTracker(key) {
+		t.Fatal("expected executors to share latency trackers")
+	}
+	if a.classifiers == b.classifiers {
+		t.Fatal("expected classifier registries to be per-executor")
+	}
+}
+
+func TestRuntime_ObserverFanOut(t *testing.T) {
+	shared := &countingObserver{}
+	local := &countingObserver{}
+	rt := NewRuntime(RuntimeOptions{Observer: shared})
+
+	key := policy.PolicyKey{Name: "observed"}
+	pol := func(p *policy.EffectivePolicy) { p.Retry.MaxAttempts = 1 }
+	a := rt.NewExecutor(WithPolicyKey(key, pol))
+	b := rt.NewExecutor(WithPolicyKey(key, pol), WithObserver(local))
+
+	fail := func(context.Context) error { return errors.New("fail") }
+	_ = a.Do(context.Background(), key, fail)
+	_ = b.Do(context.Background(), key, fail)
+
+	if got := shared.failures.Load(); got != 2 {
+		t.Fatalf("expected runtime observer to see 2 failures, got %d", got)
+	}
+	if got := local.failures.Load(); got != 1 {
+		t.Fatalf("expected executor observer to see 1 failure, got %d", got)
+	}
+}
+
+func TestRuntime_ExplicitOptionsWin(t *testing.T) {
+	rt := NewRuntime(RuntimeOptions{})
+	own := circuit.NewRegistry()
+	exec := rt.NewExecutor(WithCircuitRegistry(own))
+
+	if exec.circuits != own {
+		t.Fatal("expected explicit circuit registry to override the runtime")
+	}
+	if exec.budgets != rt.Budgets() {
+		t.Fatal("expected budgets to still come from the runtime")
+	}
+}
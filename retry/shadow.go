@@ -0,0 +1,87 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// WithShadowProvider evaluates candidate policies from p in shadow mode. Each call still
+// runs under its current policy; the candidate for the call's key is resolved alongside
+// it and, after every attempt, what the candidate would have decided (retry or stop,
+// and with what backoff) is recorded in Timeline.Shadow next to what the executor did.
+// This validates a policy change against real traffic before it is enabled.
+//
+// The candidate's ID is recorded in the "shadow_policy_id" timeline attribute, and a
+// failure to resolve it in "shadow_error". Keys for which p returns ErrPolicyNotFound
+// are not shadowed; note that StaticProvider falls back to defaults instead. Shadow
+// evaluation covers classification, attempt limits, and backoff; the candidate's
+// hedging, budgets, circuit, and timeouts are not simulated. It runs only for calls
+// that build a timeline (an observer or timeline capture).
+func WithShadowProvider(p controlplane.PolicyProvider) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Shadow = p
+	}
+}
+
+// shadowEval tracks a candidate policy's would-be decisions over one call.
+type shadowEval struct {
+	key        policy.PolicyKey
+	pol        policy.EffectivePolicy
+	classifier classify.Classifier
+	backoff    time.Duration
+	reasons    map[string]time.Duration // Per-reason backoff curves (see computeSleep).
+	stopped    bool
+}
+
+// newShadowEval resolves the shadow policy for key, recording its ID or resolution
+// error in attrs. It returns nil when the call is not shadowed.
+func newShadowEval(ctx context.Context, exec *Executor, key policy.PolicyKey, attrs map[string]string) *shadowEval {
+	pol, err := exec.shadow.GetEffectivePolicy(ctx, key)
+	if errors.Is(err, controlplane.ErrPolicyNotFound) {
+		return nil
+	}
+	if err != nil {
+		attrs["shadow_error"] = policyErrorKind(err)
+		return nil
+	}
+	pol.Key = key
+	pol, err = pol.Normalize()
+	if err != nil {
+		attrs["shadow_error"] = fmt.Sprintf("normalization_failed: %v", err)
+		return nil
+	}
+	classifier, _, err := resolveClassifier(exec, pol)
+	if err != nil {
+		attrs["shadow_error"] = "classifier_not_found"
+		return nil
+	}
+	attrs["shadow_policy_id"] = pol.ID
+	return &shadowEval{key: key, pol: pol, classifier: classifier, backoff: pol.Retry.InitialBackoff}
+}
+
+// decide classifies the result of an attempt group under the candidate policy. actual
+// is whether the executor retried. It reports false once the candidate has stopped.
+func (s *shadowEval) decide(attempt int, val any, err error, actual bool) (observe.ShadowDecision, bool) {
+	if s == nil || s.stopped {
+		return observe.ShadowDecision{}, false
+	}
+	out, _ := classifyWithRecovery(true, s.classifier, val, err, s.key)
+	d := observe.ShadowDecision{Attempt: attempt, Outcome: out, Actual: actual}
+	retryable := out.Kind == classify.OutcomeRetryable || out.Kind == classify.OutcomeThrottled ||
+		(out.Kind == classify.OutcomePartial && !s.pol.Retry.AcceptPartial)
+	if retryable && attempt+1 < s.pol.Retry.MaxAttempts {
+		d.Retry = true
+		d.Backoff = computeSleep(s.backoff, s.pol.Retry, out, &s.reasons)
+		s.backoff = nextBackoff(s.backoff, s.pol.Retry.BackoffMultiplier, s.pol.Retry.MaxBackoff)
+	} else {
+		s.stopped = true
+	}
+	return d, true
+}
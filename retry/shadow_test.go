@@ -0,0 +1,76 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// candidates is a provider that knows only its own keys.
+type candidates map[policy.PolicyKey]policy.EffectivePolicy
+
+func (c candidates) GetEffectivePolicy(_ context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	if pol, ok := c[key]; ok {
+		return pol, nil
+	}
+	return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+}
+
+func TestExecutor_ShadowProvider(t *testing.T) {
+	key := policy.ParseKey("svc.Shadow")
+	other := policy.ParseKey("svc.Unshadowed")
+	exec := NewExecutor(
+		WithPolicyKey(key, policy.MaxAttempts(4), policy.ConstantBackoff(time.Millisecond)),
+		WithPolicyKey(other, policy.MaxAttempts(2), policy.ConstantBackoff(time.Millisecond)),
+		WithShadowProvider(candidates{
+			key: policy.NewFromKey(key, policy.PolicyID("candidate"), policy.MaxAttempts(2), policy.ConstantBackoff(5*time.Millisecond)),
+		}),
+	)
+
+	calls := 0
+	ctx, capture := observe.RecordTimeline(context.Background())
+	err := exec.Do(ctx, key, func(context.Context) error {
+		calls++
+		if calls < 4 {
+			return errors.New("transient")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	tl := capture.Timeline()
+	if len(tl.Attempts) != 4 {
+		t.Fatalf("attempts = %d, want 4 under the current policy", len(tl.Attempts))
+	}
+	if got := tl.Attributes["shadow_policy_id"]; got != "candidate" {
+		t.Fatalf("shadow_policy_id = %q, want candidate", got)
+	}
+	want := []observe.ShadowDecision{
+		{Attempt: 0, Retry: true, Backoff: 5 * time.Millisecond, Actual: true},
+		{Attempt: 1, Retry: false, Actual: true},
+	}
+	if len(tl.Shadow) != len(want) {
+		t.Fatalf("shadow decisions = %+v, want %d", tl.Shadow, len(want))
+	}
+	for i, d := range tl.Shadow {
+		if d.Attempt != want[i].Attempt || d.Retry != want[i].Retry || d.Backoff != want[i].Backoff || d.Actual != want[i].Actual {
+			t.Fatalf("decision %d = %+v, want %+v", i, d, want[i])
+		}
+		if d.Outcome.Kind != classify.OutcomeRetryable {
+			t.Fatalf("decision %d outcome = %v, want retryable", i, d.Outcome.Kind)
+		}
+	}
+
+	ctx, capture = observe.RecordTimeline(context.Background())
+	_ = exec.Do(ctx, other, func(context.Context) error { return nil })
+	if tl := capture.Timeline(); tl.Shadow != nil || tl.Attributes["shadow_policy_id"] != "" {
+		t.Fatalf("key without a candidate was shadowed: %+v", tl)
+	}
+}
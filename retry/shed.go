@@ -0,0 +1,61 @@
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+// ShedError is returned for calls rejected by the executor's Shedder before their
+// first attempt.
+type ShedError struct {
+	Reason string // Shedder's reason (see the shed package reasons).
+}
+
+func (e *ShedError) Error() string {
+	return "recourse: call shed: " + e.Reason
+}
+
+func (e *ShedError) Is(target error) bool {
+	return target == ErrShed
+}
+
+// WithShedder sets the admission controller consulted before each call. Rejected calls
+// fail with a *ShedError without resolving a policy or running an attempt; admitted
+// calls release their admission when they return. Coalesced calls are admitted once.
+func WithShedder(s shed.Shedder) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Shedder = s
+	}
+}
+
+// admit asks the executor's shedder to admit a call. It returns the admission's
+// release func, or a *ShedError.
+func (e *Executor) admit(ctx context.Context, key policy.PolicyKey) (func(), *ShedError) {
+	d := e.shedder.Admit(ctx, key)
+	if !d.Admitted {
+		return nil, &ShedError{Reason: d.Reason}
+	}
+	return d.Release, nil
+}
+
+// shedTimeline records a call rejected at admission.
+func (e *Executor) shedTimeline(ctx context.Context, key policy.PolicyKey, start, mono time.Time, err *ShedError, notify bool) observe.Timeline {
+	tl := observe.Timeline{
+		Key:        key,
+		Start:      start,
+		End:        e.clock(),
+		Duration:   e.timing.since(mono),
+		Attributes: map[string]string{"shed_reason": err.Reason},
+		FinalErr:   err,
+	}
+	if notify {
+		obs := e.observerFor(ctx)
+		obs.OnStart(ctx, key, policy.EffectivePolicy{Key: key})
+		obs.OnFailure(ctx, key, tl)
+	}
+	return tl
+}
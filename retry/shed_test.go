@@ -0,0 +1,43 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+func TestExecutor_Shedder(t *testing.T) {
+	key := policy.ParseKey("svc.Shed")
+	limiter := shed.NewLimiter(shed.Options{MaxInFlight: 1})
+	exec := NewExecutor(WithPolicy(key.String(), policy.MaxAttempts(1)), WithShedder(limiter))
+
+	err := exec.Do(context.Background(), key, func(ctx context.Context) error {
+		if limiter.InFlight() != 1 {
+			t.Errorf("in flight during call = %d, want 1", limiter.InFlight())
+		}
+		ran := false
+		ctx, capture := observe.RecordTimeline(ctx)
+		err := exec.Do(ctx, key, func(context.Context) error { ran = true; return nil })
+		if ran {
+			t.Error("shed call ran")
+		}
+		var se *ShedError
+		if !errors.Is(err, ErrShed) || !errors.As(err, &se) || se.Reason != shed.ReasonInFlight {
+			t.Errorf("nested call = %v, want ShedError %s", err, shed.ReasonInFlight)
+		}
+		if tl := capture.Timeline(); tl == nil || tl.Attributes["shed_reason"] != shed.ReasonInFlight {
+			t.Errorf("timeline = %+v, want shed_reason", tl)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if limiter.InFlight() != 0 {
+		t.Fatalf("in flight after call = %d, want 0", limiter.InFlight())
+	}
+}
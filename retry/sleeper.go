@@ -0,0 +1,29 @@
+package retry
+
+import (
+	"context"
+	"time"
+)
+
+// Sleeper performs an executor's waits: the backoff between retry attempts and the
+// delays before hedged attempts. Together with WithClock it lets tests and simulations
+// drive an executor's time deterministically (see the recoursetest package).
+//
+// With both a Sleeper and a clock (WithClock) set, the executor also measures durations
+// on the clock and enforces per-attempt timeouts, time slices and overall timeouts with
+// the Sleeper, so no part of a call waits in real time. With only one of them, durations
+// and timeouts use the monotonic system clock.
+type Sleeper interface {
+	// Sleep waits for d, or until ctx is done, in which case it returns ctx.Err().
+	Sleep(ctx context.Context, d time.Duration) error
+	// After returns a channel that receives once d has elapsed, like time.After.
+	After(d time.Duration) <-chan time.Time
+}
+
+// WithSleeper sets the Sleeper used for backoff and hedge delays. It defaults to real
+// timers.
+func WithSleeper(s Sleeper) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.Sleeper = s
+	}
+}
@@ -0,0 +1,106 @@
+package retry
+
+import (
+	"expvar"
+	"sync/atomic"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/circuit"
+)
+
+// Stats is a snapshot of an executor's cumulative counters, a health signal for
+// services that run no metrics observer. Counters start at zero when the executor is
+// created and never reset.
+type Stats struct {
+	Calls     uint64 `json:"calls"`     // Calls made (Do, DoValue, and coalesced leaders).
+	Successes uint64 `json:"successes"` // Calls that returned no error.
+	Failures  uint64 `json:"failures"`  // Calls that returned an error.
+
+	Attempts      uint64 `json:"attempts"`       // Attempts run, including retries and hedges.
+	Retries       uint64 `json:"retries"`        // Attempts after the first, excluding hedges.
+	Hedges        uint64 `json:"hedges"`         // Hedged attempts run.
+	BudgetDenials uint64 `json:"budget_denials"` // Attempts denied by a retry or hedge budget.
+	// Stragglers counts canceled hedged-group attempts still running after the straggler
+	// grace period. Their budget reservations were released when they were canceled.
+	Stragglers uint64 `json:"stragglers"`
+	// BudgetLeaks counts budget decisions reported unreleased by the executor's
+	// LeakDetector.
+	BudgetLeaks uint64 `json:"budget_leaks"`
+	// DroppedAttempts and DroppedAttributes count timeline data dropped (or, for
+	// attributes, truncated) to stay within the executor's MemoryBounds.
+	DroppedAttempts   uint64 `json:"dropped_attempts"`
+	DroppedAttributes uint64 `json:"dropped_attributes"`
+
+	// OpenCircuits is the number of circuit breakers open when the snapshot was taken.
+	OpenCircuits int `json:"open_circuits"`
+	// DetachedAttempts is the number of hedged-group attempts still running after their
+	// group returned (losing hedges, late primaries). See Executor.Drain.
+	DetachedAttempts int64 `json:"detached_attempts"`
+}
+
+// executorStats holds an executor's counters.
+type executorStats struct {
+	calls, successes, failures atomic.Uint64
+	attempts, retries, hedges  atomic.Uint64
+	budgetDenials, stragglers  atomic.Uint64
+	budgetLeaks                atomic.Uint64
+
+	droppedAttempts, droppedAttributes atomic.Uint64
+}
+
+func (s *executorStats) call(err error) {
+	s.calls.Add(1)
+	if err == nil {
+		s.successes.Add(1)
+	} else {
+		s.failures.Add(1)
+	}
+}
+
+func (s *executorStats) attempt(idx int, kind budget.AttemptKind, allowed bool) {
+	switch {
+	case !allowed:
+		s.budgetDenials.Add(1)
+		return
+	case kind == budget.KindHedge:
+		s.hedges.Add(1)
+	case idx > 0:
+		s.retries.Add(1)
+	}
+	s.attempts.Add(1)
+}
+
+// Stats returns a snapshot of the executor's counters.
+func (e *Executor) Stats() Stats {
+	st := Stats{
+		Calls:         e.stats.calls.Load(),
+		Successes:     e.stats.successes.Load(),
+		Failures:      e.stats.failures.Load(),
+		Attempts:      e.stats.attempts.Load(),
+		Retries:       e.stats.retries.Load(),
+		Hedges:        e.stats.hedges.Load(),
+		BudgetDenials: e.stats.budgetDenials.Load(),
+		Stragglers:    e.stats.stragglers.Load(),
+		BudgetLeaks:   e.stats.budgetLeaks.Load(),
+
+		DroppedAttempts:   e.stats.droppedAttempts.Load(),
+		DroppedAttributes: e.stats.droppedAttributes.Load(),
+
+		DetachedAttempts: e.reaper.detached.Load(),
+	}
+	if e.circuits != nil {
+		for _, key := range e.circuits.Keys() {
+			if cb, ok := e.circuits.Lookup(key); ok && cb.State() == circuit.StateOpen {
+				st.OpenCircuits++
+			}
+		}
+	}
+	return st
+}
+
+// PublishExpvar publishes the executor's Stats under name in the expvar registry, so
+// they are served at /debug/vars. Like expvar.Publish, it panics if name is already
+// published; publish each executor once, under its own name.
+func (e *Executor) PublishExpvar(name string) {
+	expvar.Publish(name, expvar.Func(func() any { return e.Stats() }))
+}
@@ -0,0 +1,59 @@
+package retry
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"expvar"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+var expvarSeq atomic.Int32
+
+func TestExecutor_Stats(t *testing.T) {
+	limited := policy.ParseKey("svc.Limited")
+	breaker := policy.ParseKey("svc.Breaker")
+	ok := policy.ParseKey("svc.OK")
+	br := policy.NewFromKey(breaker, policy.MaxAttempts(1))
+	br.Circuit = policy.CircuitPolicy{Enabled: true, Threshold: 1, Cooldown: time.Minute}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("small", budget.NewTokenBucketBudget(2, 0))
+	exec := NewExecutor(
+		WithProvider(&controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			limited: policy.NewFromKey(limited, policy.MaxAttempts(3), policy.ConstantBackoff(0), policy.Budget("small")),
+			breaker: br,
+			ok:      policy.NewFromKey(ok, policy.MaxAttempts(1)),
+		}}),
+		WithBudgetRegistry(budgets),
+	)
+	fail := func(context.Context) error { return errors.New("boom") }
+
+	_ = exec.Do(context.Background(), limited, fail)
+	_ = exec.Do(context.Background(), breaker, fail)
+	if err := exec.Do(context.Background(), ok, func(context.Context) error { return nil }); err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+
+	want := Stats{Calls: 3, Successes: 1, Failures: 2, Attempts: 4, Retries: 1, BudgetDenials: 1, OpenCircuits: 1}
+	if got := exec.Stats(); got != want {
+		t.Fatalf("Stats = %+v, want %+v", got, want)
+	}
+
+	// expvar names are process-global; keep them unique under -count.
+	name := fmt.Sprintf("recourse_test_stats_%d", expvarSeq.Add(1))
+	exec.PublishExpvar(name)
+	var published Stats
+	if err := json.Unmarshal([]byte(expvar.Get(name).String()), &published); err != nil {
+		t.Fatalf("expvar value: %v", err)
+	}
+	if published != want {
+		t.Fatalf("published %+v, want %+v", published, want)
+	}
+}
@@ -0,0 +1,138 @@
+package retry
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for RetryStorm fields left zero.
+const (
+	DefaultStormMinCalls             = 20
+	DefaultStormMaxRetryRatio        = 0.5
+	DefaultStormMitigatedMaxAttempts = 1
+	DefaultStormCooldown             = 30 * time.Second
+	DefaultStormInterval             = time.Second
+)
+
+// RetryStorm configures retry-storm detection (see WithRetryStorm).
+//
+// A key is in a retry storm while its recent calls make more than MaxRetryRatio extra
+// attempts per call (KeyStats.RetryRatio), or more than MaxAttemptRate attempts per
+// second, once the stats window holds MinCalls calls. When a storm starts, the executor
+// reports it to its observer if it implements observe.RetryStormObserver. With Mitigate
+// set, the key's MaxAttempts is also lowered to MitigatedMaxAttempts until the storm
+// has been over for Cooldown.
+type RetryStorm struct {
+	// Stats supplies per-key retry ratios and attempt rates. It must observe the
+	// executor's calls; if nil, the executor creates one and adds it to its observers.
+	Stats *observe.StatsCollector
+
+	MinCalls       int     // Default DefaultStormMinCalls.
+	MaxRetryRatio  float64 // Default DefaultStormMaxRetryRatio.
+	MaxAttemptRate float64 // Attempts per second; 0 does not check the attempt rate.
+
+	Mitigate             bool
+	MitigatedMaxAttempts int           // Default DefaultStormMitigatedMaxAttempts.
+	Cooldown             time.Duration // Default DefaultStormCooldown.
+	Interval             time.Duration // How often a key is re-evaluated; default DefaultStormInterval.
+}
+
+func (r RetryStorm) normalize() RetryStorm {
+	if r.MinCalls <= 0 {
+		r.MinCalls = DefaultStormMinCalls
+	}
+	if r.MaxRetryRatio <= 0 {
+		r.MaxRetryRatio = DefaultStormMaxRetryRatio
+	}
+	if r.MitigatedMaxAttempts <= 0 {
+		r.MitigatedMaxAttempts = DefaultStormMitigatedMaxAttempts
+	}
+	if r.Cooldown <= 0 {
+		r.Cooldown = DefaultStormCooldown
+	}
+	if r.Interval <= 0 {
+		r.Interval = DefaultStormInterval
+	}
+	return r
+}
+
+// WithRetryStorm enables retry-storm detection.
+//
+// With the default MitigatedMaxAttempts of 1 a mitigated key stops retrying, so its
+// retry ratio falls as its stats window refills; Cooldown keeps the mitigation in place
+// meanwhile, so it is not lifted as soon as the storm stops showing.
+func WithRetryStorm(cfg RetryStorm) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.RetryStorm = &cfg
+	}
+}
+
+// stormDetector holds the per-key retry-storm state.
+type stormDetector struct {
+	cfg  RetryStorm
+	obs  observe.RetryStormObserver // nil if the observer does not take storm events
+	keys sync.Map                   // policy.PolicyKey -> *stormKey
+}
+
+type stormKey struct {
+	next     atomic.Int64 // Unix nanoseconds of the next evaluation.
+	storming atomic.Bool
+	until    atomic.Int64 // Unix nanoseconds until which MaxAttempts is lowered.
+}
+
+func newStormDetector(cfg RetryStorm, obs observe.Observer) *stormDetector {
+	d := &stormDetector{cfg: cfg.normalize()}
+	d.obs, _ = obs.(observe.RetryStormObserver)
+	return d
+}
+
+// storm reports whether stats breach the storm thresholds.
+func (d *stormDetector) storm(s observe.KeyStats) bool {
+	if s.Calls < d.cfg.MinCalls {
+		return false
+	}
+	return s.RetryRatio > d.cfg.MaxRetryRatio || (d.cfg.MaxAttemptRate > 0 && s.AttemptRate > d.cfg.MaxAttemptRate)
+}
+
+// mitigate re-evaluates key at most once per interval, reporting storms as they start,
+// and reports whether key's MaxAttempts should currently be lowered.
+func (d *stormDetector) mitigate(ctx context.Context, key policy.PolicyKey, now time.Time) bool {
+	v, ok := d.keys.Load(key)
+	if !ok {
+		v, _ = d.keys.LoadOrStore(key, &stormKey{})
+	}
+	k := v.(*stormKey)
+
+	next := k.next.Load()
+	if now.UnixNano() >= next && k.next.CompareAndSwap(next, now.Add(d.cfg.Interval).UnixNano()) {
+		s, ok := d.cfg.Stats.Stats(key)
+		if ok && d.storm(s) {
+			k.until.Store(now.Add(d.cfg.Cooldown).UnixNano())
+			if !k.storming.Swap(true) && d.obs != nil {
+				d.obs.OnRetryStorm(ctx, key, s)
+			}
+		} else {
+			k.storming.Store(false)
+		}
+	}
+	return d.cfg.Mitigate && now.UnixNano() < k.until.Load()
+}
+
+// applyStorm lowers pol's MaxAttempts while key is mitigated for a retry storm.
+func (e *Executor) applyStorm(ctx context.Context, key policy.PolicyKey, pol policy.EffectivePolicy, attrs map[string]string) policy.EffectivePolicy {
+	d := e.storms
+	if d == nil || !d.mitigate(ctx, key, e.clock()) || pol.Retry.MaxAttempts <= d.cfg.MitigatedMaxAttempts {
+		return pol
+	}
+	pol.Retry.MaxAttempts = d.cfg.MitigatedMaxAttempts
+	if attrs != nil {
+		attrs["retry_storm_max_attempts"] = strconv.Itoa(d.cfg.MitigatedMaxAttempts)
+	}
+	return pol
+}
@@ -0,0 +1,88 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type stormRecorder struct {
+	observe.BaseObserver
+	storms []observe.KeyStats
+}
+
+func (r *stormRecorder) OnRetryStorm(_ context.Context, _ policy.PolicyKey, s observe.KeyStats) {
+	r.storms = append(r.storms, s)
+}
+
+func TestRetryStorm_DetectsAndMitigates(t *testing.T) {
+	key := policy.PolicyKey{Name: "storm"}
+	now := time.Unix(1000, 0)
+	rec := &stormRecorder{}
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{MaxAttempts: 3}},
+		}},
+		Observer:   rec,
+		Clock:      func() time.Time { return now },
+		RetryStorm: &RetryStorm{MinCalls: 4, Mitigate: true, Cooldown: time.Minute, Interval: time.Nanosecond},
+	})
+	exec.sleep = func(context.Context, time.Duration) error { return nil }
+
+	call := func() int {
+		t.Helper()
+		n := 0
+		_ = exec.Do(context.Background(), key, func(context.Context) error {
+			n++
+			return errors.New("down")
+		})
+		now = now.Add(time.Millisecond)
+		return n
+	}
+
+	for i := 0; i < 4; i++ {
+		if n := call(); n != 3 {
+			t.Fatalf("call %d: attempts=%d, want 3 before the storm is detected", i, n)
+		}
+	}
+	if n := call(); n != DefaultStormMitigatedMaxAttempts {
+		t.Fatalf("attempts=%d, want %d during the storm", n, DefaultStormMitigatedMaxAttempts)
+	}
+	if len(rec.storms) != 1 || rec.storms[0].RetryRatio != 2 {
+		t.Fatalf("storms=%+v, want one event with retry ratio 2", rec.storms)
+	}
+	call()
+	if len(rec.storms) != 1 {
+		t.Fatalf("storms=%d, want the ongoing storm reported once", len(rec.storms))
+	}
+
+	// Unretried calls bring the ratio down; the cooldown then lifts the mitigation.
+	for i := 0; i < 256; i++ {
+		call()
+	}
+	if n := call(); n != DefaultStormMitigatedMaxAttempts {
+		t.Fatalf("attempts=%d, want the mitigation held through the cooldown", n)
+	}
+	now = now.Add(time.Minute)
+	if n := call(); n != 3 {
+		t.Fatalf("attempts=%d, want MaxAttempts restored after the cooldown", n)
+	}
+}
+
+func TestRetryStorm_AttemptRate(t *testing.T) {
+	d := newStormDetector(RetryStorm{MaxRetryRatio: 10, MaxAttemptRate: 100}, nil)
+	if d.storm(observe.KeyStats{Calls: 50, RetryRatio: 0.1, AttemptRate: 50}) {
+		t.Fatal("storm below both thresholds")
+	}
+	if !d.storm(observe.KeyStats{Calls: 50, RetryRatio: 0.1, AttemptRate: 150}) {
+		t.Fatal("no storm above the attempt rate threshold")
+	}
+	if d.storm(observe.KeyStats{Calls: 5, RetryRatio: 0.1, AttemptRate: 150}) {
+		t.Fatal("storm with fewer than MinCalls calls")
+	}
+}
@@ -0,0 +1,93 @@
+package retry
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultStragglerGrace is the straggler grace period used when none is set.
+const DefaultStragglerGrace = time.Second
+
+// Reasons passed to Observer.OnHedgeCancel for attempts of a hedged group that were
+// still running when the group finished.
+const (
+	CancelReasonLost     = "lost"     // Another attempt of the group succeeded.
+	CancelReasonTerminal = "terminal" // Another attempt was terminal (Hedge.CancelOnFirstTerminal).
+	CancelReasonContext  = "context"  // The call's context ended.
+)
+
+// WithStragglerGrace sets how long attempts of a hedged group that are still running
+// when the group finishes (losing hedges, late primaries) may take to return after
+// they are canceled. Their budget reservations are released as soon as they are
+// canceled; operations that ignore cancellation and outlast the grace period are
+// stragglers, counted in Stats.Stragglers. The default is DefaultStragglerGrace.
+func WithStragglerGrace(d time.Duration) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.StragglerGrace = d
+	}
+}
+
+// inflight tracks one attempt of a hedged group, so the group can account for it when
+// it finishes first.
+type inflight struct {
+	start     time.Time // Wall clock, for the record's StartTime.
+	mono      time.Time // e.timing, for its Duration.
+	isHedge   bool
+	idx       int
+	accounted atomic.Bool  // Set by whichever of the attempt and the group records it.
+	state     atomic.Int32 // attemptRunning, attemptExited, or attemptDetached; see reaper.
+}
+
+// watchStraggler releases an attempt's budget reservation as soon as ctx, its group
+// context, ends, rather than when the operation returns, and counts the attempt as a
+// straggler if it is still running a grace period later. release must be safe to call
+// twice, as allowAttempt's releases are. It returns a function the attempt calls when
+// it finishes.
+func (e *Executor) watchStraggler(ctx context.Context, release func()) (finish func()) {
+	finished := make(chan struct{})
+	stop := context.AfterFunc(ctx, func() {
+		if release != nil {
+			release()
+		}
+		select {
+		case <-finished:
+		case <-e.after(e.stragglerGrace):
+			e.stats.stragglers.Add(1)
+		}
+	})
+	return func() {
+		stop()
+		close(finished)
+	}
+}
+
+// abandon records the attempts in flight as canceled, in the timeline and with
+// OnHedgeCancel, so a group that finishes first still accounts for them. Their own
+// records, if they finish later, are dropped.
+func (e *Executor) abandon(ctx context.Context, key policy.PolicyKey, retryIdx int, idemKey string, attempts []*inflight, reason string, recordAttempt func(context.Context, observe.AttemptRecord)) {
+	end := e.clock()
+	for _, fl := range attempts {
+		if fl == nil || !fl.accounted.CompareAndSwap(false, true) {
+			continue
+		}
+		rec := observe.AttemptRecord{
+			Attempt:        retryIdx,
+			StartTime:      fl.start,
+			EndTime:        end,
+			Duration:       e.timing.since(fl.mono),
+			IsHedge:        fl.isHedge,
+			HedgeIndex:     fl.idx,
+			IdempotencyKey: idemKey,
+			Outcome:        classify.Outcome{Kind: classify.OutcomeAbort, Reason: classify.ReasonContextCanceled},
+			Err:            context.Canceled,
+			BudgetAllowed:  true,
+		}
+		recordAttempt(ctx, rec)
+		e.observerFor(ctx).OnHedgeCancel(ctx, key, rec, reason)
+	}
+}
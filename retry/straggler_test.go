@@ -0,0 +1,125 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+type hedgeCancelRecorder struct {
+	observe.BaseObserver
+	mu      sync.Mutex
+	reasons []string
+}
+
+func (r *hedgeCancelRecorder) OnHedgeCancel(_ context.Context, _ policy.PolicyKey, _ observe.AttemptRecord, reason string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.reasons = append(r.reasons, reason)
+}
+
+func TestExecutor_StragglerGrace(t *testing.T) {
+	key := policy.ParseKey("svc.Straggle")
+	cb := &countingReleaseBudget{}
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("b", cb)
+	obs := &hedgeCancelRecorder{}
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1),
+			policy.HedgeDelay(10*time.Millisecond), policy.Budget("b"), policy.HedgeBudget("b")),
+		WithBudgetRegistry(budgets),
+		WithObserver(obs),
+		WithStragglerGrace(20*time.Millisecond),
+	)
+
+	block := make(chan struct{})
+	defer close(block)
+	var calls atomic.Int32
+	_, tl, err := doValueInternal(context.Background(), exec, key, func(context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			<-block // The primary ignores cancellation.
+			return 0, context.Canceled
+		}
+		return 1, nil
+	}, true)
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+
+	if len(tl.Attempts) != 2 {
+		t.Fatalf("attempts = %+v, want the primary and the hedge", tl.Attempts)
+	}
+	var primary observe.AttemptRecord
+	for _, a := range tl.Attempts {
+		if !a.IsHedge {
+			primary = a
+		}
+	}
+	if primary.Outcome.Kind != classify.OutcomeAbort || primary.Outcome.Reason != classify.ReasonContextCanceled {
+		t.Fatalf("primary outcome = %+v, want an abort for cancellation", primary.Outcome)
+	}
+	obs.mu.Lock()
+	reasons := obs.reasons
+	obs.mu.Unlock()
+	if len(reasons) != 1 || reasons[0] != CancelReasonLost {
+		t.Fatalf("OnHedgeCancel reasons = %v, want [%s]", reasons, CancelReasonLost)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&cb.releases) < 2 || exec.Stats().Stragglers < 1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("releases=%d stragglers=%d, want the primary's reservation force-released",
+				atomic.LoadInt32(&cb.releases), exec.Stats().Stragglers)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestExecutor_StragglerGrace_CooperativeAttempt(t *testing.T) {
+	key := policy.ParseKey("svc.Cooperate")
+	exec := NewExecutor(
+		WithPolicy(key.String(), policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1),
+			policy.HedgeDelay(10*time.Millisecond)),
+		WithStragglerGrace(10*time.Millisecond),
+	)
+
+	var calls atomic.Int32
+	_, err := DoValue(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+		if calls.Add(1) == 1 {
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 1, nil
+	})
+	if err != nil {
+		t.Fatalf("DoValue: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if got := exec.Stats().Stragglers; got != 0 {
+		t.Fatalf("stragglers = %d, want 0 for an attempt that honors cancellation", got)
+	}
+}
+
+func TestExecutor_Abandon_MonotonicDuration(t *testing.T) {
+	frozen := time.Unix(1_700_000_000, 0)
+	exec := NewExecutor(WithClock(func() time.Time { return frozen }))
+	fl := &inflight{start: exec.clock(), mono: exec.timing.now(), isHedge: true, idx: 1}
+	time.Sleep(5 * time.Millisecond)
+
+	var recs []observe.AttemptRecord
+	exec.abandon(context.Background(), policy.ParseKey("svc.Abandon"), 0, "", []*inflight{fl}, CancelReasonLost,
+		func(_ context.Context, rec observe.AttemptRecord) { recs = append(recs, rec) })
+	if len(recs) != 1 {
+		t.Fatalf("records = %+v, want one for the abandoned hedge", recs)
+	}
+	if recs[0].Duration < 5*time.Millisecond || !recs[0].StartTime.Equal(frozen) {
+		t.Fatalf("record = %+v, want the duration on the monotonic clock and the start on the wall clock", recs[0])
+	}
+}
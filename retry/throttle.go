@@ -0,0 +1,155 @@
+package retry
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Defaults for ClientThrottle fields left zero.
+const (
+	DefaultThrottleWindow   = time.Second
+	DefaultThrottleInterval = 50 * time.Millisecond
+)
+
+// ClientThrottle configures client-side pacing after throttled attempts, in the spirit
+// of the AWS SDKs' client-side rate limiting.
+//
+// When an attempt is classified classify.OutcomeThrottled, its call backs off and
+// retries as for any retryable outcome, and the key is also paced for Window: new calls
+// start at most one per Interval, waiting (within their context) for their slot, so a
+// throttled dependency sees a trickle instead of the full request rate. A throttled
+// outcome whose BackoffOverride (a server's Retry-After) exceeds Window paces the key
+// for that long instead. Each further throttled attempt restarts the window.
+type ClientThrottle struct {
+	Window   time.Duration // Default DefaultThrottleWindow.
+	Interval time.Duration // Default DefaultThrottleInterval.
+	// MaxDelay caps how long a single call waits for its slot; default Window. Calls
+	// beyond it start after MaxDelay anyway, so pacing never parks callers for long.
+	MaxDelay time.Duration
+}
+
+func (c ClientThrottle) normalize() ClientThrottle {
+	if c.Window <= 0 {
+		c.Window = DefaultThrottleWindow
+	}
+	if c.Interval <= 0 {
+		c.Interval = DefaultThrottleInterval
+	}
+	if c.MaxDelay <= 0 {
+		c.MaxDelay = c.Window
+	}
+	return c
+}
+
+// WithClientThrottle sets how the executor paces keys after throttled attempts.
+// Executors pace with the defaults when this option is not set; pacing only takes
+// effect for classifiers that report classify.OutcomeThrottled.
+func WithClientThrottle(cfg ClientThrottle) ExecutorOption {
+	return func(c *executorConfig) {
+		c.opts.ClientThrottle = &cfg
+	}
+}
+
+// clientThrottle holds the keys being paced.
+//
+// Keys whose window and last reserved slot have both passed are paced no more than
+// keys never throttled, so, as in keyLimits, they are dropped whenever the number of
+// keys doubles.
+type clientThrottle struct {
+	cfg     ClientThrottle
+	keys    sync.Map // policy.PolicyKey -> *throttledKey
+	size    atomic.Int64
+	sweepAt atomic.Int64 // Size at which the next sweep runs; minLimitSweep if zero.
+	sweepMu sync.Mutex
+}
+
+type throttledKey struct {
+	mu      sync.Mutex
+	until   time.Time // End of the pacing window.
+	next    time.Time // Start of the next free slot.
+	removed bool      // Dropped by a sweep; throttled stores a new entry instead.
+}
+
+func newClientThrottle(cfg ClientThrottle) *clientThrottle {
+	return &clientThrottle{cfg: cfg.normalize()}
+}
+
+// throttled starts or extends key's pacing window after a throttled attempt.
+func (t *clientThrottle) throttled(key policy.PolicyKey, retryAfter time.Duration, now time.Time) {
+	for {
+		v, ok := t.keys.Load(key)
+		if !ok {
+			var loaded bool
+			if v, loaded = t.keys.LoadOrStore(key, &throttledKey{}); !loaded {
+				t.added(now)
+			}
+		}
+		k := v.(*throttledKey)
+		k.mu.Lock()
+		if !k.removed {
+			k.until = now.Add(max(t.cfg.Window, retryAfter))
+			k.mu.Unlock()
+			return
+		}
+		k.mu.Unlock()
+	}
+}
+
+// added counts a new key and sweeps keys idle at now once the count reaches the sweep
+// mark, moving the mark to twice the keys left.
+func (t *clientThrottle) added(now time.Time) {
+	n := t.size.Add(1)
+	if n < max(t.sweepAt.Load(), minLimitSweep) || !t.sweepMu.TryLock() {
+		return
+	}
+	defer t.sweepMu.Unlock()
+	t.keys.Range(func(key, v any) bool {
+		k := v.(*throttledKey)
+		k.mu.Lock()
+		if now.Before(k.until) || now.Before(k.next) {
+			k.mu.Unlock()
+			return true
+		}
+		k.removed = true
+		k.mu.Unlock()
+		if t.keys.CompareAndDelete(key, v) {
+			n = t.size.Add(-1)
+		}
+		return true
+	})
+	t.sweepAt.Store(2 * n)
+}
+
+// delay reserves a start slot for a new call to key and returns how long the call must
+// wait for it; 0 if key is not being paced.
+func (t *clientThrottle) delay(key policy.PolicyKey, now time.Time) time.Duration {
+	v, ok := t.keys.Load(key)
+	if !ok {
+		return 0
+	}
+	k := v.(*throttledKey)
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if !now.Before(k.until) {
+		return 0
+	}
+	slot := k.next
+	if slot.Before(now) {
+		slot = now
+	}
+	wait := min(slot.Sub(now), t.cfg.MaxDelay)
+	k.next = now.Add(wait + t.cfg.Interval)
+	return wait
+}
+
+// pace waits for key's next start slot while key is being paced. If ctx ends first,
+// the call goes on to fail with ctx's error as it would without pacing.
+func (e *Executor) pace(ctx context.Context, key policy.PolicyKey) {
+	if d := e.throttle.delay(key, e.clock()); d > 0 {
+		_ = e.sleep(ctx, d)
+	}
+}
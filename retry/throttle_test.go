@@ -0,0 +1,121 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+func newThrottleExecutor(t *testing.T, key policy.PolicyKey, cfg ClientThrottle) (*Executor, *[]time.Duration) {
+	t.Helper()
+	exec := NewExecutorFromOptions(ExecutorOptions{
+		Provider: &controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			key: {Key: key, Retry: policy.RetryPolicy{MaxAttempts: 2, ClassifierName: "http-throttle"}},
+		}},
+		ClientThrottle: &cfg,
+	})
+	exec.classifiers.Register("http-throttle", classify.HTTPClassifier{ThrottleStatuses: map[int]struct{}{429: {}}})
+	slept := &[]time.Duration{}
+	exec.sleep = func(_ context.Context, d time.Duration) error {
+		*slept = append(*slept, d)
+		return nil
+	}
+	return exec, slept
+}
+
+func TestClientThrottle_PacesLaterCalls(t *testing.T) {
+	key := policy.PolicyKey{Name: "throttled"}
+	const interval = time.Hour // Far beyond the test's run time, so waits are exact.
+	exec, slept := newThrottleExecutor(t, key, ClientThrottle{Window: time.Hour, Interval: interval, MaxDelay: 2 * interval})
+
+	ok := func(context.Context) (int, error) { return 1, nil }
+	calls := 0
+	_, err := DoValue(context.Background(), exec, key, func(context.Context) (int, error) {
+		calls++
+		if calls == 1 {
+			return 0, stubHTTPError{status: 429, method: "GET", retryAfter: time.Millisecond, hasRetry: true}
+		}
+		return 1, nil
+	})
+	if err != nil || calls != 2 {
+		t.Fatalf("calls=%d err=%v, want the throttled attempt retried", calls, err)
+	}
+	if len(*slept) != 1 || (*slept)[0] != time.Millisecond {
+		t.Fatalf("slept=%v, want only the throttled attempt's backoff", *slept)
+	}
+
+	*slept = nil
+	for i := 0; i < 4; i++ {
+		if _, err := DoValue(context.Background(), exec, key, ok); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// The first paced call takes the open slot; the next ones wait one interval per
+	// call ahead of them, capped at MaxDelay.
+	if len(*slept) != 3 {
+		t.Fatalf("slept=%v, want 3 paced waits", *slept)
+	}
+	for i, want := range []time.Duration{interval, 2 * interval, 2 * interval} {
+		if got := (*slept)[i]; got > want || got < want-time.Second {
+			t.Fatalf("wait %d = %v, want about %v", i, got, want)
+		}
+	}
+}
+
+func TestClientThrottle_WindowExpires(t *testing.T) {
+	key := policy.PolicyKey{Name: "throttled"}
+	exec, slept := newThrottleExecutor(t, key, ClientThrottle{Window: time.Millisecond, Interval: time.Hour})
+
+	exec.throttle.throttled(key, 0, exec.clock())
+	time.Sleep(5 * time.Millisecond)
+	for i := 0; i < 3; i++ {
+		if _, err := DoValue(context.Background(), exec, key, func(context.Context) (int, error) { return 1, nil }); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(*slept) != 0 {
+		t.Fatalf("slept=%v, want no pacing after the window", *slept)
+	}
+}
+
+func TestClientThrottle_UsesExecutorClock(t *testing.T) {
+	key := policy.PolicyKey{Name: "throttled"}
+	exec, _ := newThrottleExecutor(t, key, ClientThrottle{Window: time.Hour, Interval: time.Minute})
+	now := time.Unix(1_700_000_000, 0)
+	exec.clock = func() time.Time { return now }
+
+	exec.throttle.throttled(key, 0, exec.clock())
+	exec.throttle.delay(key, exec.clock())
+	if d := exec.throttle.delay(key, exec.clock()); d != time.Minute {
+		t.Fatalf("delay=%v, want %v inside the window", d, time.Minute)
+	}
+	now = now.Add(2 * time.Hour)
+	if d := exec.throttle.delay(key, exec.clock()); d != 0 {
+		t.Fatalf("delay=%v, want 0 once the executor clock passes the window", d)
+	}
+}
+
+func TestClientThrottle_EvictsIdleKeys(t *testing.T) {
+	throttle := newClientThrottle(ClientThrottle{Window: time.Second})
+	now := time.Unix(1_700_000_000, 0)
+	held := policy.PolicyKey{Name: "held"}
+	throttle.throttled(held, time.Hour, now)
+	for i := 0; i < 4*minLimitSweep; i++ {
+		throttle.throttled(policy.PolicyKey{Name: fmt.Sprintf("op%d", i)}, 0, now)
+		now = now.Add(2 * time.Second)
+	}
+
+	n := 0
+	throttle.keys.Range(func(any, any) bool { n++; return true })
+	if n > 2*minLimitSweep || int64(n) != throttle.size.Load() {
+		t.Fatalf("keys = %d (size %d), want idle keys swept", n, throttle.size.Load())
+	}
+	if _, ok := throttle.keys.Load(held); !ok {
+		t.Fatal("held key was swept inside its window, want it kept")
+	}
+}
@@ -0,0 +1,36 @@
+package retry
+
+import (
+	"context"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// attemptSlice returns the deadline of an attempt with attemptsLeft attempts left,
+// itself included, when pol slices the overall timeout: an even share of the time left
+// before ctx's deadline. It returns the zero time when pol does not slice or ctx has no
+// deadline. Hedges share the slice of the attempt they hedge.
+func (e *Executor) attemptSlice(ctx context.Context, pol policy.RetryPolicy, attemptsLeft int) time.Time {
+	if !pol.SliceOverallTimeout {
+		return time.Time{}
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return time.Time{}
+	}
+	now := e.timing.now()
+	return now.Add(deadline.Sub(now) / time.Duration(max(attemptsLeft, 1)))
+}
+
+// withAttemptTimeout derives an attempt context that ends after timeout (if positive)
+// or at slice (if set), whichever is sooner.
+func (e *Executor) withAttemptTimeout(ctx context.Context, timeout time.Duration, slice time.Time) (context.Context, context.CancelFunc) {
+	deadline := slice
+	if timeout > 0 {
+		if d := e.timing.now().Add(timeout); deadline.IsZero() || d.Before(deadline) {
+			deadline = d
+		}
+	}
+	return e.timing.withDeadline(ctx, deadline)
+}
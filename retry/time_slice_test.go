@@ -0,0 +1,73 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+func TestExecutor_SliceOverallTimeout(t *testing.T) {
+	for _, wantTimeline := range []bool{false, true} {
+		key := policy.ParseKey("svc.Slice")
+		exec := NewExecutor(WithPolicy(key.String(),
+			policy.MaxAttempts(3),
+			policy.ConstantBackoff(time.Millisecond),
+			policy.OverallTimeout(600*time.Millisecond),
+			policy.SliceOverallTimeout(),
+		))
+
+		var slices []time.Duration
+		_, _, err := doValueInternal(context.Background(), exec, key, func(ctx context.Context) (int, error) {
+			deadline, ok := ctx.Deadline()
+			if !ok {
+				t.Fatal("attempt context has no deadline")
+			}
+			slices = append(slices, time.Until(deadline))
+			if len(slices) == 1 {
+				return 0, errors.New("fast failure")
+			}
+			return 1, nil
+		}, wantTimeline)
+		if err != nil {
+			t.Fatalf("timeline=%v: DoValue: %v", wantTimeline, err)
+		}
+
+		// The first attempt gets a third of the timeout. It fails fast, so the second
+		// gets half of the time left, not another third.
+		if len(slices) != 2 {
+			t.Fatalf("timeline=%v: attempts = %d, want 2", wantTimeline, len(slices))
+		}
+		if slices[0] > 200*time.Millisecond || slices[0] < 150*time.Millisecond {
+			t.Fatalf("timeline=%v: first attempt slice = %v, want about 200ms", wantTimeline, slices[0])
+		}
+		if slices[1] > 300*time.Millisecond || slices[1] < 250*time.Millisecond {
+			t.Fatalf("timeline=%v: second attempt slice = %v, want about 300ms", wantTimeline, slices[1])
+		}
+	}
+}
+
+func TestExecutor_SliceOverallTimeout_PerAttemptTimeoutWins(t *testing.T) {
+	key := policy.ParseKey("svc.SliceCapped")
+	exec := NewExecutor(WithPolicy(key.String(),
+		policy.MaxAttempts(2),
+		policy.PerAttemptTimeout(50*time.Millisecond),
+		policy.OverallTimeout(time.Second),
+		policy.SliceOverallTimeout(),
+	))
+
+	var slice time.Duration
+	err := exec.Do(context.Background(), key, func(ctx context.Context) error {
+		deadline, _ := ctx.Deadline()
+		slice = time.Until(deadline)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Do: %v", err)
+	}
+	if slice > 50*time.Millisecond {
+		t.Fatalf("attempt slice = %v, want the shorter per-attempt timeout", slice)
+	}
+}
@@ -0,0 +1,108 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// timeSource measures an executor's durations and runs its deadlines and timers. By
+// default it uses the monotonic system clock, so durations stay meaningful when the
+// executor's wall clock (WithClock) is frozen or skewed. When time is simulated, with
+// both WithClock and WithSleeper set, it reads the injected clock and waits on the
+// Sleeper instead, so tests and simulations control every duration, attempt timeout,
+// time slice, and overall timeout.
+type timeSource struct {
+	clock func() time.Time // Simulated clock; nil for the system clock.
+	after func(time.Duration) <-chan time.Time
+}
+
+// newTimeSource returns the time source for an executor with clock and sleeper.
+func newTimeSource(clock func() time.Time, sleeper Sleeper) timeSource {
+	if clock == nil || sleeper == nil {
+		return timeSource{}
+	}
+	return timeSource{clock: clock, after: sleeper.After}
+}
+
+func (s timeSource) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+	return s.clock()
+}
+
+func (s timeSource) since(t time.Time) time.Duration {
+	return s.now().Sub(t)
+}
+
+func (s timeSource) withTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	return s.withDeadline(ctx, s.now().Add(timeout))
+}
+
+// withDeadline is context.WithDeadline on the source's clock. Simulated deadlines are
+// enforced with the Sleeper, so a caller's own deadline should be simulated too.
+func (s timeSource) withDeadline(ctx context.Context, deadline time.Time) (context.Context, context.CancelFunc) {
+	if s.clock == nil {
+		return context.WithDeadline(ctx, deadline)
+	}
+	if parent, ok := ctx.Deadline(); ok && parent.Before(deadline) {
+		deadline = parent
+	}
+	inner, cancel := context.WithCancelCause(ctx)
+	c := &simulatedDeadline{Context: inner, deadline: deadline}
+	if d := deadline.Sub(s.clock()); d <= 0 {
+		cancel(context.DeadlineExceeded)
+	} else {
+		timer := s.after(d)
+		go func() {
+			select {
+			case <-timer:
+				cancel(context.DeadlineExceeded)
+			case <-inner.Done():
+			}
+		}()
+	}
+	return c, func() { cancel(context.Canceled) }
+}
+
+// afterFunc calls f in its own goroutine after d, like time.AfterFunc. stop prevents
+// the call if it has not started.
+func (s timeSource) afterFunc(d time.Duration, f func()) (stop func()) {
+	if s.clock == nil {
+		t := time.AfterFunc(d, f)
+		return func() { t.Stop() }
+	}
+	done := make(chan struct{})
+	var once sync.Once
+	timer := s.after(d)
+	go func() {
+		select {
+		case <-timer:
+			f()
+		case <-done:
+		}
+	}()
+	return func() { once.Do(func() { close(done) }) }
+}
+
+// simulatedDeadline is a context ended by a timeSource at a simulated deadline. Like a
+// context.WithDeadline context, it reports the deadline and, once it passes,
+// context.DeadlineExceeded.
+type simulatedDeadline struct {
+	context.Context // Canceled with cause context.DeadlineExceeded at the deadline.
+	deadline        time.Time
+}
+
+func (c *simulatedDeadline) Deadline() (time.Time, bool) {
+	return c.deadline, true
+}
+
+func (c *simulatedDeadline) Err() error {
+	err := c.Context.Err()
+	if err != nil && errors.Is(context.Cause(c.Context), context.DeadlineExceeded) {
+		return context.DeadlineExceeded
+	}
+	return err
+}
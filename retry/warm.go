@@ -0,0 +1,64 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Warm resolves and normalizes the policies for keys ahead of traffic, typically at
+// startup, so the first call per key does not pay for a control-plane round trip.
+// Resolution goes through the executor's provider, filling caching providers
+// (controlplane.RemoteProvider, SWRProvider, LKGProvider); Warm also creates each key's
+// circuit breaker and latency tracker, which makes the keys visible to Keys and the
+// admin tools before they are called.
+//
+// Keys are warmed concurrently. Warm returns an error joining one entry per key whose
+// policy could not be resolved or whose policy names a budget that is not registered;
+// the remaining keys are still warmed, so callers may log the error and continue.
+func (e *Executor) Warm(ctx context.Context, keys ...policy.PolicyKey) error {
+	errs := make([]error, len(keys))
+	var wg sync.WaitGroup
+	for i, key := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := e.warmKey(ctx, key); err != nil {
+				errs[i] = fmt.Errorf("warm %s: %w", key, err)
+			}
+		}()
+	}
+	wg.Wait()
+	return errors.Join(errs...)
+}
+
+func (e *Executor) warmKey(ctx context.Context, key policy.PolicyKey) error {
+	pol, err := e.EffectivePolicy(ctx, key)
+	if err != nil {
+		return err
+	}
+	e.getTracker(key)
+	if pol.Circuit.Enabled {
+		e.circuits.Get(key, pol.Circuit)
+	}
+
+	refs := []policy.BudgetRef{pol.Retry.Budget}
+	if pol.Hedge.Enabled {
+		refs = append(refs, pol.Hedge.Budget)
+	}
+	var missing []error
+	for _, ref := range refs {
+		name := strings.TrimSpace(ref.Name)
+		if name == "" {
+			continue
+		}
+		if _, ok := e.budgets.Get(name); !ok {
+			missing = append(missing, fmt.Errorf("budget %q is not registered", name))
+		}
+	}
+	return errors.Join(missing...)
+}
@@ -0,0 +1,85 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/budget"
+	"github.com/aponysus/recourse/controlplane"
+	"github.com/aponysus/recourse/policy"
+)
+
+// countingProvider serves Policies, failing for other keys, and counts lookups.
+type countingProvider struct {
+	controlplane.StaticProvider
+	calls atomic.Int32
+}
+
+func (p *countingProvider) GetEffectivePolicy(ctx context.Context, key policy.PolicyKey) (policy.EffectivePolicy, error) {
+	p.calls.Add(1)
+	if _, ok := p.Policies[key]; !ok {
+		return policy.EffectivePolicy{}, controlplane.ErrPolicyNotFound
+	}
+	return p.StaticProvider.GetEffectivePolicy(ctx, key)
+}
+
+func TestWarm_ResolvesAndCreatesState(t *testing.T) {
+	circuitKey := policy.ParseKey("svc.Circuit")
+	plainKey := policy.ParseKey("svc.Plain")
+	provider := &countingProvider{StaticProvider: controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+		circuitKey: {Key: circuitKey, Circuit: policy.CircuitPolicy{Enabled: true, Threshold: 3, Cooldown: time.Second}},
+		plainKey:   {Key: plainKey},
+	}}}
+	exec := NewExecutor(WithProvider(provider))
+
+	if err := exec.Warm(context.Background(), circuitKey, plainKey); err != nil {
+		t.Fatalf("Warm: %v", err)
+	}
+	if got := provider.calls.Load(); got != 2 {
+		t.Fatalf("provider calls=%d, want 2", got)
+	}
+	if _, ok := exec.Circuits().Lookup(circuitKey); !ok {
+		t.Fatalf("no circuit breaker for %v after Warm", circuitKey)
+	}
+	if _, ok := exec.Circuits().Lookup(plainKey); ok {
+		t.Fatalf("circuit breaker created for %v without a circuit policy", plainKey)
+	}
+	if keys := exec.Keys(); len(keys) != 2 {
+		t.Fatalf("Keys()=%v, want both warmed keys", keys)
+	}
+}
+
+func TestWarm_ReportsFailuresAndWarmsTheRest(t *testing.T) {
+	okKey := policy.ParseKey("svc.Ok")
+	budgetKey := policy.ParseKey("svc.Budget")
+	missingKey := policy.ParseKey("svc.Missing")
+	budgets := budget.NewRegistry()
+	budgets.MustRegister("known", budget.UnlimitedBudget{})
+	exec := NewExecutor(
+		WithProvider(&countingProvider{StaticProvider: controlplane.StaticProvider{Policies: map[policy.PolicyKey]policy.EffectivePolicy{
+			okKey:     {Key: okKey, Retry: policy.RetryPolicy{Budget: policy.BudgetRef{Name: "known"}}},
+			budgetKey: {Key: budgetKey, Retry: policy.RetryPolicy{Budget: policy.BudgetRef{Name: "unknown"}}},
+		}}}),
+		WithBudgetRegistry(budgets),
+		WithMissingPolicyMode(FailureDeny),
+	)
+
+	err := exec.Warm(context.Background(), okKey, budgetKey, missingKey)
+	if err == nil {
+		t.Fatalf("Warm returned nil, want errors for %v and %v", budgetKey, missingKey)
+	}
+	var noPolicy *NoPolicyError
+	if !errors.As(err, &noPolicy) || noPolicy.Key != missingKey {
+		t.Fatalf("err=%v, want a NoPolicyError for %v", err, missingKey)
+	}
+	if msg := err.Error(); !strings.Contains(msg, `budget "unknown" is not registered`) || strings.Contains(msg, "svc.Ok") {
+		t.Fatalf("err=%q, want only the unknown budget and the missing policy", msg)
+	}
+	if keys := exec.Keys(); len(keys) != 2 {
+		t.Fatalf("Keys()=%v, want the two resolvable keys", keys)
+	}
+}
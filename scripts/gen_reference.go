@@ -5,6 +5,7 @@ import (
 	"flag"
 	"fmt"
 	"go/ast"
+	"go/format"
 	"go/parser"
 	"go/printer"
 	"go/token"
@@ -88,6 +89,13 @@ func generateReasonCodes(root, outPath string) error {
 	}
 
 	outcomeReasons := newReasonSet()
+	classifyReasons, err := collectReasonConsts(filepath.Join(root, "classify", "reasons.go"))
+	if err != nil {
+		return err
+	}
+	for _, r := range classifyReasons {
+		outcomeReasons.Static[r] = struct{}{}
+	}
 	paths := []string{
 		filepath.Join(root, "classify"),
 		filepath.Join(root, "retry"),
@@ -134,7 +142,56 @@ func generateReasonCodes(root, outPath string) error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(outPath, content, 0o644)
+	if err := os.WriteFile(outPath, content, 0o644); err != nil {
+		return err
+	}
+
+	catalogs := []struct {
+		path string
+		pkg  string
+		vars []catalogVar
+	}{
+		{"classify", "classify", []catalogVar{
+			{"knownReasons", "fixed outcome reasons", setToSorted(outcomeReasons.Static)},
+			{"knownReasonPatterns", "open-ended outcome reason patterns", setToSorted(outcomeReasons.Patterns)},
+		}},
+		{"budget", "budget", []catalogVar{{"knownReasons", "budget decision reasons", budgetReasons}}},
+		{"circuit", "circuit", []catalogVar{{"knownReasons", "circuit rejection reasons", circuitReasons}}},
+		{"observe", "observe", []catalogVar{{"knownBudgetModes", "budget decision modes", setToSorted(modeReasons)}}},
+	}
+	for _, c := range catalogs {
+		if err := writeCatalog(filepath.Join(root, c.path, "reasons_gen.go"), c.pkg, c.vars); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// catalogVar is a string slice written to a generated catalog file.
+type catalogVar struct {
+	Name   string
+	Doc    string
+	Values []string
+}
+
+// writeCatalog writes the Go file backing a package's KnownReasons-style functions.
+func writeCatalog(path, pkg string, vars []catalogVar) error {
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by scripts/gen_reference.go; DO NOT EDIT.\n\n")
+	buf.WriteString("package " + pkg + "\n")
+	for _, v := range vars {
+		buf.WriteString("\n// " + v.Name + " lists the " + v.Doc + ", sorted.\n")
+		buf.WriteString("var " + v.Name + " = []string{\n")
+		for _, val := range v.Values {
+			buf.WriteString(strconv.Quote(val) + ",\n")
+		}
+		buf.WriteString("}\n")
+	}
+	content, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, content, 0o644)
 }
 
 func generatePolicySchema(root, outPath string) error {
@@ -151,6 +208,7 @@ func generatePolicySchema(root, outPath string) error {
 		"RetryPolicy",
 		"HedgePolicy",
 		"CircuitPolicy",
+		"LimitsPolicy",
 		"NormalizationInfo",
 		"Metadata",
 		"EffectivePolicy",
@@ -325,6 +383,19 @@ func collectReasonAssignments(path string, rs *reasonSet) error {
 
 	ast.Inspect(f, func(n ast.Node) bool {
 		switch v := n.(type) {
+		case *ast.FuncDecl:
+			// Reason builders, such as classify.HTTPStatusReason, return pattern reasons.
+			if v.Body == nil || !strings.HasSuffix(v.Name.Name, "Reason") {
+				return true
+			}
+			ast.Inspect(v.Body, func(n ast.Node) bool {
+				if ret, ok := n.(*ast.ReturnStmt); ok {
+					for _, r := range ret.Results {
+						addReasonExpr(r, rs)
+					}
+				}
+				return true
+			})
 		case *ast.KeyValueExpr:
 			if keyIdent, ok := v.Key.(*ast.Ident); ok && keyIdent.Name == "Reason" {
 				addReasonExpr(v.Value, rs)
@@ -1006,6 +1077,7 @@ func renderReasonsMarkdown(budgetReasons, circuitReasons []string, outcome reaso
 
 	buf.WriteString("Generated from: `budget/reasons.go`, `circuit/types.go`, `classify/`, `retry/`, `integrations/grpc/grpc.go`, `observe/types.go`.\n\n")
 	buf.WriteString("These reason codes and timeline fields are part of the v1 telemetry contract. Changes are breaking.\n\n")
+	buf.WriteString("The same lists are available at run time from `classify.KnownReasons()`, `classify.KnownReasonPatterns()`, `budget.KnownReasons()`, `circuit.KnownReasons()`, and `observe.KnownBudgetModes()`, so dashboards and validation tools need not parse source. `make docs-reference` regenerates both.\n\n")
 
 	buf.WriteString("## Outcome reasons\n\n")
 	buf.WriteString("These values appear in `observe.AttemptRecord.Outcome.Reason`.\n\n")
@@ -1070,6 +1142,7 @@ func renderPolicySchemaMarkdown(structs map[string][]structField, defaults map[s
 	writeStructWithTags(&buf, "policy.RetryPolicy", structs["RetryPolicy"])
 	writeStructWithTags(&buf, "policy.HedgePolicy", structs["HedgePolicy"])
 	writeStructWithTags(&buf, "policy.CircuitPolicy", structs["CircuitPolicy"])
+	writeStructWithTags(&buf, "policy.LimitsPolicy", structs["LimitsPolicy"])
 	writeStructWithTags(&buf, "policy.NormalizationInfo", structs["NormalizationInfo"])
 	writeStructWithTags(&buf, "policy.Metadata", structs["Metadata"])
 	writeStructWithTags(&buf, "policy.EffectivePolicy", structs["EffectivePolicy"])
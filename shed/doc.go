@@ -0,0 +1,19 @@
+// Package shed provides process-level admission control: it decides whether a call
+// may start at all, before its first attempt.
+//
+// Retries amplify overload: a saturated process that admits every call only adds
+// retries to its backlog. An executor configured with retry.WithShedder asks its
+// Shedder before resolving a call's policy and rejects refused calls with a
+// *retry.ShedError (matching retry.ErrShed) without running them. Admitted calls hold
+// their admission, an in-flight slot for a Limiter, until the call returns.
+//
+// Limiter admits calls up to an in-flight limit, optionally queueing callers for a
+// bounded wait, and refuses every call while any of its Signals reports overload.
+// Signals are pluggable; HeapSignal and GoroutineSignal are built in, and a
+// SignalFunc adapts a CPU or cgroup pressure reading. RateLimiter admits calls at a
+// steady rate with bursts.
+//
+// Executors also enforce the per-key caps of a policy's limits section
+// (policy.LimitsPolicy) with a Limiter and a RateLimiter per key, after resolving the
+// call's policy; calls over a cap fail with a *retry.ShedError too.
+package shed
@@ -0,0 +1,90 @@
+package shed
+
+import (
+	"context"
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// RateOptions configures a RateLimiter.
+type RateOptions struct {
+	// QPS is the number of calls that may start per second. Zero or less means no limit.
+	QPS float64
+	// Burst is the number of calls that may start at once. Zero or less defaults to
+	// QPS rounded up (at least 1).
+	Burst int
+	// Now reads the clock that refills the limiter; nil uses time.Now.
+	Now func() time.Time
+}
+
+// RateLimiter is a Shedder that admits calls at a steady rate: a token bucket holding
+// up to Burst calls, refilled at QPS per second. Calls that find the bucket empty are
+// rejected at once.
+type RateLimiter struct {
+	opts RateOptions
+	shed atomic.Uint64
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time // When tokens was last refilled.
+}
+
+// NewRateLimiter returns a RateLimiter with opts, starting with a full bucket.
+func NewRateLimiter(opts RateOptions) *RateLimiter {
+	if opts.QPS < 0 {
+		opts.QPS = 0
+	}
+	if opts.Burst <= 0 {
+		opts.Burst = max(1, int(min(math.Ceil(opts.QPS), math.MaxInt32)))
+	}
+	if opts.Now == nil {
+		opts.Now = time.Now
+	}
+	return &RateLimiter{opts: opts, tokens: float64(opts.Burst), last: opts.Now()}
+}
+
+// Admit admits the call if the bucket holds a token for it.
+func (l *RateLimiter) Admit(_ context.Context, _ policy.PolicyKey) Decision {
+	if l.opts.QPS <= 0 {
+		return Decision{Admitted: true, Reason: ReasonAdmitted}
+	}
+	now := l.opts.Now()
+	l.mu.Lock()
+	if elapsed := now.Sub(l.last); elapsed > 0 {
+		l.tokens = min(float64(l.opts.Burst), l.tokens+elapsed.Seconds()*l.opts.QPS)
+		l.last = now
+	}
+	ok := l.tokens >= 1
+	if ok {
+		l.tokens--
+	}
+	l.mu.Unlock()
+	if !ok {
+		l.shed.Add(1)
+		return Decision{Admitted: false, Reason: ReasonRateLimited}
+	}
+	return Decision{Admitted: true, Reason: ReasonAdmitted}
+}
+
+// Full reports whether the bucket is full, i.e. the limiter would admit a full burst
+// now, as a new RateLimiter with the same options would.
+func (l *RateLimiter) Full() bool {
+	if l.opts.QPS <= 0 {
+		return true
+	}
+	now := l.opts.Now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.tokens+now.Sub(l.last).Seconds()*l.opts.QPS >= float64(l.opts.Burst)
+}
+
+// Shed returns the number of calls the RateLimiter has rejected.
+func (l *RateLimiter) Shed() uint64 {
+	return l.shed.Load()
+}
+
+var _ Shedder = (*RateLimiter)(nil)
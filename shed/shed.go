@@ -0,0 +1,151 @@
+package shed
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+)
+
+// Standard Decision.Reason strings.
+const (
+	ReasonAdmitted     = "admitted"
+	ReasonInFlight     = "shed_in_flight"     // The in-flight limit was reached.
+	ReasonQueueFull    = "shed_queue_full"    // The in-flight limit was reached and too many calls were waiting.
+	ReasonQueueTimeout = "shed_queue_timeout" // No slot freed up within the queue wait (or the caller gave up).
+	ReasonOverloaded   = "shed_overloaded"    // A Signal reported overload.
+	ReasonRateLimited  = "shed_rate_limited"  // A RateLimiter had no token for the call.
+)
+
+// Decision is the result of an admission check.
+type Decision struct {
+	Admitted bool
+	Reason   string
+
+	// Release, when non-nil, is called exactly once after an admitted call finishes.
+	Release func()
+}
+
+// Shedder decides whether calls may start. Implementations must be safe for
+// concurrent use.
+type Shedder interface {
+	Admit(ctx context.Context, key policy.PolicyKey) Decision
+}
+
+// ShedderFunc adapts a function to a Shedder.
+type ShedderFunc func(ctx context.Context, key policy.PolicyKey) Decision
+
+func (f ShedderFunc) Admit(ctx context.Context, key policy.PolicyKey) Decision {
+	return f(ctx, key)
+}
+
+// Options configures a Limiter.
+type Options struct {
+	// MaxInFlight is the number of calls that may run at once. Zero means no limit.
+	MaxInFlight int
+	// MaxQueueWait is how long a call may wait for an in-flight slot when all are taken.
+	// Zero rejects such calls at once.
+	MaxQueueWait time.Duration
+	// MaxQueue is the number of calls that may wait for a slot at once; further calls
+	// are rejected at once. Zero defaults to MaxInFlight.
+	MaxQueue int
+	// Signals refuse every call while any of them reports overload.
+	Signals []Signal
+}
+
+// Limiter is a Shedder that bounds in-flight calls and consults overload signals.
+// Share one Limiter across executors (or use retry.RuntimeOptions.Shedder) to bound
+// the whole process.
+type Limiter struct {
+	opts    Options
+	slots   chan struct{} // Holds a token per in-flight call; nil without a limit.
+	waiting atomic.Int64
+	shed    atomic.Uint64
+}
+
+// NewLimiter returns a Limiter with opts.
+func NewLimiter(opts Options) *Limiter {
+	if opts.MaxInFlight < 0 {
+		opts.MaxInFlight = 0
+	}
+	if opts.MaxQueueWait < 0 {
+		opts.MaxQueueWait = 0
+	}
+	if opts.MaxQueue <= 0 {
+		opts.MaxQueue = opts.MaxInFlight
+	}
+	l := &Limiter{opts: opts}
+	if opts.MaxInFlight > 0 {
+		l.slots = make(chan struct{}, opts.MaxInFlight)
+	}
+	return l
+}
+
+// Admit admits the call if no signal reports overload and an in-flight slot is free,
+// waiting up to MaxQueueWait for one.
+func (l *Limiter) Admit(ctx context.Context, _ policy.PolicyKey) Decision {
+	for _, s := range l.opts.Signals {
+		if s.Overloaded() {
+			return l.reject(ReasonOverloaded)
+		}
+	}
+	if l.slots == nil {
+		return Decision{Admitted: true, Reason: ReasonAdmitted}
+	}
+
+	select {
+	case l.slots <- struct{}{}:
+		return l.admitted()
+	default:
+	}
+	if l.opts.MaxQueueWait <= 0 {
+		return l.reject(ReasonInFlight)
+	}
+	if l.waiting.Add(1) > int64(l.opts.MaxQueue) {
+		l.waiting.Add(-1)
+		return l.reject(ReasonQueueFull)
+	}
+	defer l.waiting.Add(-1)
+
+	t := time.NewTimer(l.opts.MaxQueueWait)
+	defer t.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return l.admitted()
+	case <-t.C:
+	case <-ctx.Done():
+	}
+	return l.reject(ReasonQueueTimeout)
+}
+
+func (l *Limiter) admitted() Decision {
+	var released atomic.Bool
+	return Decision{Admitted: true, Reason: ReasonAdmitted, Release: func() {
+		if released.CompareAndSwap(false, true) {
+			<-l.slots
+		}
+	}}
+}
+
+func (l *Limiter) reject(reason string) Decision {
+	l.shed.Add(1)
+	return Decision{Admitted: false, Reason: reason}
+}
+
+// InFlight returns the number of admitted calls that have not finished.
+func (l *Limiter) InFlight() int {
+	return len(l.slots)
+}
+
+// Waiting returns the number of calls waiting for an in-flight slot.
+func (l *Limiter) Waiting() int {
+	return int(l.waiting.Load())
+}
+
+// Shed returns the number of calls the Limiter has rejected.
+func (l *Limiter) Shed() uint64 {
+	return l.shed.Load()
+}
+
+var _ Shedder = (*Limiter)(nil)
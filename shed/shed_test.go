@@ -0,0 +1,131 @@
+package shed_test
+
+import (
+	"context"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/shed"
+)
+
+var key = policy.ParseKey("svc.Call")
+
+func TestLimiter_InFlight(t *testing.T) {
+	l := shed.NewLimiter(shed.Options{MaxInFlight: 2})
+	a := l.Admit(context.Background(), key)
+	b := l.Admit(context.Background(), key)
+	if !a.Admitted || !b.Admitted || l.InFlight() != 2 {
+		t.Fatalf("first two calls: %+v %+v, in flight %d", a, b, l.InFlight())
+	}
+	if d := l.Admit(context.Background(), key); d.Admitted || d.Reason != shed.ReasonInFlight {
+		t.Fatalf("third call = %+v, want %s", d, shed.ReasonInFlight)
+	}
+
+	a.Release()
+	a.Release() // Idempotent.
+	if l.InFlight() != 1 {
+		t.Fatalf("in flight after release = %d, want 1", l.InFlight())
+	}
+	if d := l.Admit(context.Background(), key); !d.Admitted {
+		t.Fatalf("call after release = %+v, want admitted", d)
+	}
+	if l.Shed() != 1 {
+		t.Fatalf("Shed() = %d, want 1", l.Shed())
+	}
+}
+
+func TestLimiter_Queue(t *testing.T) {
+	l := shed.NewLimiter(shed.Options{MaxInFlight: 1, MaxQueueWait: time.Second, MaxQueue: 1})
+	held := l.Admit(context.Background(), key)
+
+	queued := make(chan shed.Decision)
+	go func() { queued <- l.Admit(context.Background(), key) }()
+	for l.Waiting() != 1 {
+		time.Sleep(time.Millisecond)
+	}
+	if d := l.Admit(context.Background(), key); d.Admitted || d.Reason != shed.ReasonQueueFull {
+		t.Fatalf("call beyond MaxQueue = %+v, want %s", d, shed.ReasonQueueFull)
+	}
+
+	held.Release()
+	if d := <-queued; !d.Admitted {
+		t.Fatalf("queued call = %+v, want admitted once the slot freed", d)
+	}
+}
+
+func TestLimiter_QueueTimeout(t *testing.T) {
+	l := shed.NewLimiter(shed.Options{MaxInFlight: 1, MaxQueueWait: 10 * time.Millisecond})
+	l.Admit(context.Background(), key)
+	if d := l.Admit(context.Background(), key); d.Admitted || d.Reason != shed.ReasonQueueTimeout {
+		t.Fatalf("queued call = %+v, want %s", d, shed.ReasonQueueTimeout)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	l = shed.NewLimiter(shed.Options{MaxInFlight: 1, MaxQueueWait: time.Hour})
+	l.Admit(context.Background(), key)
+	if d := l.Admit(ctx, key); d.Admitted {
+		t.Fatal("canceled caller admitted")
+	}
+}
+
+func TestLimiter_Signals(t *testing.T) {
+	var overloaded atomic.Bool
+	l := shed.NewLimiter(shed.Options{Signals: []shed.Signal{shed.SignalFunc(overloaded.Load)}})
+	if d := l.Admit(context.Background(), key); !d.Admitted {
+		t.Fatalf("Admit = %+v, want admitted", d)
+	}
+	overloaded.Store(true)
+	if d := l.Admit(context.Background(), key); d.Admitted || d.Reason != shed.ReasonOverloaded {
+		t.Fatalf("Admit = %+v, want %s", d, shed.ReasonOverloaded)
+	}
+
+	if shed.GoroutineSignal(1 << 30).Overloaded() {
+		t.Fatal("GoroutineSignal overloaded below its limit")
+	}
+	runtime.GC() // The live heap is measured at garbage collection.
+	if !shed.HeapSignal(0).Overloaded() {
+		t.Fatal("HeapSignal(0) not overloaded")
+	}
+}
+
+func TestRateLimiter(t *testing.T) {
+	now := time.Unix(1_700_000_000, 0)
+	l := shed.NewRateLimiter(shed.RateOptions{QPS: 2, Now: func() time.Time { return now }})
+	for i := 0; i < 2; i++ {
+		if d := l.Admit(context.Background(), key); !d.Admitted {
+			t.Fatalf("call %d within burst = %+v, want admitted", i, d)
+		}
+	}
+	if d := l.Admit(context.Background(), key); d.Admitted || d.Reason != shed.ReasonRateLimited {
+		t.Fatalf("call over rate = %+v, want %s", d, shed.ReasonRateLimited)
+	}
+
+	now = now.Add(500 * time.Millisecond)
+	if d := l.Admit(context.Background(), key); !d.Admitted {
+		t.Fatalf("call after refill = %+v, want admitted", d)
+	}
+	if d := l.Admit(context.Background(), key); d.Admitted {
+		t.Fatalf("second call after one refill = %+v, want rejected", d)
+	}
+	if l.Shed() != 2 {
+		t.Fatalf("Shed() = %d, want 2", l.Shed())
+	}
+	if l.Full() {
+		t.Fatal("Full() with an empty bucket")
+	}
+	now = now.Add(time.Second)
+	if !l.Full() {
+		t.Fatal("Full() = false after a burst's worth of refill")
+	}
+
+	unlimited := shed.NewRateLimiter(shed.RateOptions{})
+	for i := 0; i < 100; i++ {
+		if d := unlimited.Admit(context.Background(), key); !d.Admitted {
+			t.Fatalf("call without a rate = %+v, want admitted", d)
+		}
+	}
+}
@@ -0,0 +1,56 @@
+package shed
+
+import (
+	"runtime"
+	"runtime/metrics"
+	"sync"
+	"time"
+)
+
+// Signal reports whether the process is overloaded. Overloaded is called on every
+// admission check, so it must be cheap; cache expensive readings.
+type Signal interface {
+	Overloaded() bool
+}
+
+// SignalFunc adapts a function to a Signal, e.g. one comparing a CPU or cgroup
+// pressure reading to a threshold.
+type SignalFunc func() bool
+
+func (f SignalFunc) Overloaded() bool { return f() }
+
+// GoroutineSignal reports overload while more than max goroutines are running.
+func GoroutineSignal(max int) Signal {
+	return SignalFunc(func() bool { return runtime.NumGoroutine() > max })
+}
+
+// heapSampleInterval bounds how often HeapSignal reads runtime metrics.
+const heapSampleInterval = 100 * time.Millisecond
+
+// HeapSignal reports overload while the live heap, as of the last garbage collection,
+// exceeds maxBytes. The reading is refreshed at most every 100ms.
+func HeapSignal(maxBytes uint64) Signal {
+	return &heapSignal{max: maxBytes, sample: []metrics.Sample{{Name: "/gc/heap/live:bytes"}}}
+}
+
+type heapSignal struct {
+	max uint64
+
+	mu         sync.Mutex
+	sample     []metrics.Sample
+	read       time.Time
+	overloaded bool
+}
+
+func (s *heapSignal) Overloaded() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if now := time.Now(); now.Sub(s.read) >= heapSampleInterval {
+		s.read = now
+		metrics.Read(s.sample)
+		if v := s.sample[0].Value; v.Kind() == metrics.KindUint64 {
+			s.overloaded = v.Uint64() > s.max
+		}
+	}
+	return s.overloaded
+}
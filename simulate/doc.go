@@ -0,0 +1,23 @@
+// Package simulate evaluates a policy offline. Given an EffectivePolicy and a model of
+// the dependency's latency and errors, it plays many calls on a virtual clock and
+// reports the distribution of call latency, attempt counts, and budget consumption:
+//
+//	report, err := simulate.Run(simulate.Config{
+//		Policy: pol,
+//		Model:  simulate.Service{Latency: simulate.Percentiles(20*time.Millisecond, 250*time.Millisecond), ErrorRate: 0.05},
+//		Calls:  100000,
+//	})
+//	fmt.Println(report.SuccessRate, report.Latency.P99, report.Amplification)
+//
+// Runs are deterministic for a given Seed, so reports can be compared in CI when a
+// policy changes.
+//
+// The simulation follows the executor's retry loop: backoff with jitter, per-attempt
+// and overall timeouts, classification, and fixed-delay hedging (hedge triggers other
+// than the policy's HedgeDelay are not modeled). Budgets are counted, not enforced, and
+// circuit breakers are not modeled.
+//
+// Replay plays a captured observe.Timeline under a candidate policy instead of a model,
+// reporting how that call would have differed: fewer attempts, an earlier success, or
+// a deadline violation.
+package simulate
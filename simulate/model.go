@@ -0,0 +1,89 @@
+package simulate
+
+import (
+	"errors"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// ErrSimulated is the error of failed attempts in a Service model without its own Err.
+var ErrSimulated = errors.New("recourse: simulated failure")
+
+// Sample is the result of one simulated attempt.
+type Sample struct {
+	Latency time.Duration
+	Err     error // nil for success
+}
+
+// Model samples simulated attempts. Implementations must draw randomness only from r
+// so that runs are reproducible.
+type Model interface {
+	Sample(r *rand.Rand) Sample
+}
+
+// ModelFunc adapts a function to a Model.
+type ModelFunc func(r *rand.Rand) Sample
+
+// Sample calls f(r).
+func (f ModelFunc) Sample(r *rand.Rand) Sample { return f(r) }
+
+// Distribution samples a latency.
+type Distribution func(r *rand.Rand) time.Duration
+
+// Fixed returns a distribution that always yields d.
+func Fixed(d time.Duration) Distribution {
+	return func(*rand.Rand) time.Duration { return d }
+}
+
+// Uniform returns a distribution uniform over [min, max).
+func Uniform(min, max time.Duration) Distribution {
+	return func(r *rand.Rand) time.Duration {
+		if max <= min {
+			return min
+		}
+		return min + time.Duration(r.Int64N(int64(max-min)))
+	}
+}
+
+// Percentiles returns a log-normal distribution with the given median and 99th
+// percentile, a common fit for service latency with a long tail.
+func Percentiles(p50, p99 time.Duration) Distribution {
+	mu := math.Log(float64(p50))
+	sigma := 0.0
+	if p99 > p50 && p50 > 0 {
+		sigma = (math.Log(float64(p99)) - mu) / 2.3263478740 // z-score of the 99th percentile
+	}
+	return func(r *rand.Rand) time.Duration {
+		return time.Duration(math.Exp(mu + sigma*r.NormFloat64()))
+	}
+}
+
+// Service models a dependency whose attempts take Latency and fail independently
+// with probability ErrorRate, returning Err (ErrSimulated if nil). Failed attempts take
+// FailureLatency if set, else Latency.
+type Service struct {
+	Latency        Distribution
+	ErrorRate      float64
+	Err            error
+	FailureLatency Distribution
+}
+
+// Sample draws one attempt.
+func (s Service) Sample(r *rand.Rand) Sample {
+	var out Sample
+	if s.ErrorRate > 0 && r.Float64() < s.ErrorRate {
+		out.Err = s.Err
+		if out.Err == nil {
+			out.Err = ErrSimulated
+		}
+		if s.FailureLatency != nil {
+			out.Latency = s.FailureLatency(r)
+			return out
+		}
+	}
+	if s.Latency != nil {
+		out.Latency = s.Latency(r)
+	}
+	return out
+}
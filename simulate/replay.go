@@ -0,0 +1,192 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sort"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+)
+
+// ReplayCall summarizes one call, as recorded or as replayed.
+type ReplayCall struct {
+	Attempts int // Attempts made, hedges included.
+	Hedges   int // Hedged attempts launched.
+	Success  bool
+	Latency  time.Duration
+	// TimedOut reports that the call ended at its overall timeout (for the recorded
+	// call: that it failed with context.DeadlineExceeded).
+	TimedOut bool
+}
+
+// ReplayReport compares a recorded call with its replay under a candidate policy.
+type ReplayReport struct {
+	Recorded  ReplayCall
+	Candidate ReplayCall
+	// Exhausted reports that the candidate needed the outcome of an attempt the timeline
+	// did not record, such as a retry beyond the recorded ones. Candidate then describes
+	// the call up to that attempt, which it does not count: Success is false and Latency
+	// is a lower bound.
+	Exhausted bool
+	// Differences describes how the candidate would have behaved differently, e.g.
+	// "2 fewer attempts" or "succeeds 120ms earlier"; empty if it behaves the same.
+	Differences []string
+}
+
+// Replay re-runs the attempts of a captured timeline under candidate and reports how
+// the call would have differed. It returns an error if candidate does not normalize or
+// the timeline has no attempts.
+//
+// Each attempt of the replay takes the recorded latency and outcome of the attempt with
+// the same retry and hedge index, or else of the earliest recorded attempt not yet
+// replayed, so a candidate with more hedges replays later retries as hedges. Outcomes
+// are replayed as classified when recorded; the candidate's classifier is not
+// consulted. Attempts canceled when another attempt won, and attempts their budget
+// denied, have no outcome to replay and are not counted as recorded attempts.
+// Backoff follows the candidate's schedule, with jitter drawn from a fixed seed.
+func Replay(tl observe.Timeline, candidate policy.EffectivePolicy) (ReplayReport, error) {
+	pol, err := candidate.Normalize()
+	if err != nil {
+		return ReplayReport{}, err
+	}
+	if len(tl.Attempts) == 0 {
+		return ReplayReport{}, errors.New("simulate: timeline has no attempts")
+	}
+
+	rep := ReplayReport{Recorded: recordedCall(tl)}
+	s := &sim{
+		pol: pol,
+		r:   rand.New(rand.NewPCG(0, 0x9e3779b97f4a7c15)),
+		report: Report{
+			Attempts:    make(map[int]int),
+			BudgetUnits: make(map[string]int),
+		},
+		script: newScript(tl.Attempts),
+	}
+	latency := s.call()
+	rep.Candidate = ReplayCall{
+		Hedges:   s.report.Hedges,
+		Success:  s.report.Successes > 0,
+		Latency:  latency,
+		TimedOut: s.report.TimedOut > 0,
+	}
+	rep.Candidate.Attempts = s.script.replayed
+	rep.Exhausted = s.script.missing > 0 && !rep.Candidate.Success
+	rep.Differences = differences(rep, pol)
+	return rep, nil
+}
+
+func recordedCall(tl observe.Timeline) ReplayCall {
+	c := ReplayCall{
+		Success:  tl.FinalErr == nil,
+		Latency:  tl.Duration,
+		TimedOut: errors.Is(tl.FinalErr, context.DeadlineExceeded),
+	}
+	if c.Latency <= 0 {
+		c.Latency = tl.End.Sub(tl.Start)
+	}
+	for _, a := range tl.Attempts {
+		if !a.BudgetAllowed {
+			continue // Denied by its budget; the operation never ran.
+		}
+		c.Attempts++
+		if a.IsHedge {
+			c.Hedges++
+		}
+	}
+	return c
+}
+
+func differences(rep ReplayReport, pol policy.EffectivePolicy) []string {
+	rec, cand := rep.Recorded, rep.Candidate
+	if rep.Exhausted {
+		return []string{fmt.Sprintf("needs an attempt the timeline did not record after %d attempts", cand.Attempts)}
+	}
+	var diffs []string
+	switch d := cand.Attempts - rec.Attempts; {
+	case d < 0:
+		diffs = append(diffs, fmt.Sprintf("%d fewer attempts", -d))
+	case d > 0:
+		diffs = append(diffs, fmt.Sprintf("%d more attempts", d))
+	}
+	switch {
+	case cand.Success && !rec.Success:
+		diffs = append(diffs, "succeeds where the recorded call failed")
+	case !cand.Success && rec.Success:
+		diffs = append(diffs, "fails where the recorded call succeeded")
+	case cand.Success && cand.Latency < rec.Latency:
+		diffs = append(diffs, fmt.Sprintf("succeeds %v earlier", rec.Latency-cand.Latency))
+	case cand.Success && cand.Latency > rec.Latency:
+		diffs = append(diffs, fmt.Sprintf("succeeds %v later", cand.Latency-rec.Latency))
+	}
+	if cand.TimedOut && !rec.TimedOut {
+		diffs = append(diffs, fmt.Sprintf("hits the %v overall timeout", pol.Retry.OverallTimeout))
+	}
+	return diffs
+}
+
+// script serves recorded attempts to a replay (see sim.attempt).
+type script struct {
+	attempts []observe.AttemptRecord // Replayable attempts, in start order.
+	used     []bool
+	replayed int // Recorded attempts handed out.
+	missing  int // Attempts requested with none left to replay.
+}
+
+func newScript(records []observe.AttemptRecord) *script {
+	var attempts []observe.AttemptRecord
+	for _, a := range records {
+		if !a.BudgetAllowed || errors.Is(a.Err, context.Canceled) || a.Outcome.Reason == classify.ReasonContextCanceled {
+			continue
+		}
+		attempts = append(attempts, a)
+	}
+	sort.SliceStable(attempts, func(i, j int) bool { return attempts[i].StartTime.Before(attempts[j].StartTime) })
+	return &script{attempts: attempts, used: make([]bool, len(attempts))}
+}
+
+// attempt replays attempt hedgeIdx of retry step retryIdx, started at start, cutting it
+// off at the per-attempt timeout as the executor would.
+func (sc *script) attempt(start time.Duration, retryIdx, hedgeIdx int, timeout time.Duration) running {
+	i := sc.find(retryIdx, hedgeIdx)
+	if i < 0 {
+		sc.missing++
+		return running{end: start, out: classify.Outcome{Kind: classify.OutcomeAbort, Reason: "replay_exhausted"}}
+	}
+	sc.used[i] = true
+	sc.replayed++
+	rec := sc.attempts[i]
+	latency := rec.Duration
+	if latency <= 0 {
+		latency = max(rec.EndTime.Sub(rec.StartTime), 0)
+	}
+	out := rec.Outcome
+	if timeout > 0 && latency > timeout {
+		latency = timeout
+		out = classify.Outcome{Kind: classify.OutcomeRetryable, Reason: classify.ReasonContextDeadlineExceeded}
+	}
+	return running{end: start + latency, out: out}
+}
+
+// find returns the unused recorded attempt with the given indexes, or else the earliest
+// unused one; -1 if none is left.
+func (sc *script) find(retryIdx, hedgeIdx int) int {
+	first := -1
+	for i, a := range sc.attempts {
+		if sc.used[i] {
+			continue
+		}
+		if a.Attempt == retryIdx && a.HedgeIndex == hedgeIdx {
+			return i
+		}
+		if first < 0 {
+			first = i
+		}
+	}
+	return first
+}
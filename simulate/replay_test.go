@@ -0,0 +1,128 @@
+package simulate_test
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/observe"
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/simulate"
+)
+
+// recorded returns a timeline of attempts that took 20ms each, 10ms apart, failing
+// until the last one.
+func recorded(attempts int) observe.Timeline {
+	start := time.Unix(0, 0)
+	tl := observe.Timeline{Key: policy.ParseKey("svc.Get"), Start: start}
+	at := start
+	for i := range attempts {
+		out := classify.Outcome{Kind: classify.OutcomeRetryable, Reason: "http_503"}
+		if i == attempts-1 {
+			out = classify.Outcome{Kind: classify.OutcomeSuccess, Reason: classify.ReasonSuccess}
+		}
+		tl.Attempts = append(tl.Attempts, observe.AttemptRecord{
+			Attempt: i, StartTime: at, EndTime: at.Add(20 * time.Millisecond), Duration: 20 * time.Millisecond, Outcome: out,
+			BudgetAllowed: true,
+		})
+		at = at.Add(30 * time.Millisecond)
+	}
+	tl.End = at.Add(-10 * time.Millisecond)
+	tl.Duration = tl.End.Sub(start)
+	return tl
+}
+
+func TestReplay(t *testing.T) {
+	tl := recorded(3) // 3 attempts, success after 80ms
+
+	tests := []struct {
+		name      string
+		pol       policy.EffectivePolicy
+		want      []string
+		exhausted bool
+	}{
+		{
+			name: "same behavior",
+			pol:  policy.New("svc.Get", policy.MaxAttempts(3), policy.Backoff(10*time.Millisecond, time.Second, 1), policy.Jitter(policy.JitterNone)),
+		},
+		{
+			name: "fewer attempts",
+			pol:  policy.New("svc.Get", policy.MaxAttempts(2), policy.Backoff(10*time.Millisecond, time.Second, 1), policy.Jitter(policy.JitterNone)),
+			want: []string{"1 fewer attempts", "fails where the recorded call succeeded"},
+		},
+		{
+			name: "earlier success",
+			pol:  policy.New("svc.Get", policy.MaxAttempts(3), policy.Backoff(time.Millisecond, time.Second, 1), policy.Jitter(policy.JitterNone)),
+			want: []string{"succeeds 18ms earlier"},
+		},
+		{
+			name: "deadline violation",
+			pol: policy.New("svc.Get", policy.MaxAttempts(3), policy.Backoff(10*time.Millisecond, time.Second, 1),
+				policy.Jitter(policy.JitterNone), policy.OverallTimeout(50*time.Millisecond)),
+			want: []string{"1 fewer attempts", "fails where the recorded call succeeded", "hits the 50ms overall timeout"},
+		},
+		{
+			name:      "beyond the recording",
+			pol:       policy.New("svc.Get", policy.MaxAttempts(5), policy.Backoff(10*time.Millisecond, time.Second, 1), policy.Jitter(policy.JitterNone)),
+			want:      []string{"needs an attempt the timeline did not record after 2 attempts"},
+			exhausted: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := tl
+			if tt.exhausted {
+				input = recorded(3)
+				input.Attempts = input.Attempts[:2]
+				input.FinalErr = errors.New("boom")
+			}
+			rep, err := simulate.Replay(input, tt.pol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if rep.Exhausted != tt.exhausted || !reflect.DeepEqual(rep.Differences, tt.want) {
+				t.Fatalf("report = %+v, want differences %q (exhausted %v)", rep, tt.want, tt.exhausted)
+			}
+		})
+	}
+}
+
+func TestReplay_HedgesReplayLaterAttempts(t *testing.T) {
+	// Attempt 0 is slow and fails; attempt 1 is fast and succeeds. A candidate that
+	// hedges after 10ms wins with the recorded attempt 1 as its hedge.
+	tl := recorded(2)
+	tl.Attempts[0].Duration = 100 * time.Millisecond
+	pol := policy.New("svc.Get", policy.MaxAttempts(1), policy.EnableHedging(), policy.HedgeMaxAttempts(1), policy.HedgeDelay(10*time.Millisecond))
+
+	rep, err := simulate.Replay(tl, pol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Candidate.Success || rep.Candidate.Hedges != 1 || rep.Candidate.Latency != 30*time.Millisecond {
+		t.Fatalf("candidate = %+v, want success via the hedge at 30ms", rep.Candidate)
+	}
+}
+
+func TestReplay_SkipsBudgetDenials(t *testing.T) {
+	// Attempt 1's first try was denied by its budget and did not run; the recorded call
+	// made 3 attempts.
+	tl := recorded(3)
+	denied := observe.AttemptRecord{
+		Attempt:   1,
+		StartTime: tl.Attempts[1].StartTime,
+		EndTime:   tl.Attempts[1].StartTime,
+		Outcome:   classify.Outcome{Kind: classify.OutcomeAbort, Reason: "budget_denied"},
+	}
+	tl.Attempts = append(tl.Attempts[:1], append([]observe.AttemptRecord{denied}, tl.Attempts[1:]...)...)
+	pol := policy.New("svc.Get", policy.MaxAttempts(3), policy.Backoff(10*time.Millisecond, time.Second, 1), policy.Jitter(policy.JitterNone))
+
+	rep, err := simulate.Replay(tl, pol)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Recorded.Attempts != 3 || rep.Candidate.Attempts != 3 || !rep.Candidate.Success || rep.Exhausted || len(rep.Differences) != 0 {
+		t.Fatalf("report = %+v, want the denial skipped on both sides", rep)
+	}
+}
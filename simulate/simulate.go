@@ -0,0 +1,317 @@
+package simulate
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aponysus/recourse/classify"
+	"github.com/aponysus/recourse/policy"
+)
+
+// DefaultCalls is the number of calls simulated when Config.Calls is zero.
+const DefaultCalls = 10000
+
+// Config describes a simulation.
+type Config struct {
+	// Policy is the policy under evaluation. It is normalized first, as by the executor.
+	Policy policy.EffectivePolicy
+	// Model samples the dependency's attempts.
+	Model Model
+	// Classifier classifies attempt errors. Defaults to classify.AlwaysRetryOnError.
+	Classifier classify.Classifier
+	// Calls is the number of calls to simulate. Defaults to DefaultCalls.
+	Calls int
+	// Seed seeds the random source; equal configurations with equal seeds produce
+	// equal reports.
+	Seed uint64
+}
+
+// LatencyStats summarizes simulated call latencies.
+type LatencyStats struct {
+	Mean, P50, P90, P99, Max time.Duration
+}
+
+// Report is the result of a simulation.
+type Report struct {
+	Calls     int
+	Successes int
+	// SuccessRate is Successes / Calls.
+	SuccessRate float64
+	// Latency summarizes the latency of every call, successful or not.
+	Latency LatencyStats
+	// Attempts counts calls by the number of attempts they made, hedges included.
+	Attempts map[int]int
+	// Hedges is the number of hedged attempts launched.
+	Hedges int
+	// Amplification is the mean number of attempts per call.
+	Amplification float64
+	// TimedOut counts calls that ended at the policy's overall timeout.
+	TimedOut int
+	// BudgetUnits is the budget cost consumed, by budget name, assuming every attempt is
+	// admitted. Attempts without a budget are not counted.
+	BudgetUnits map[string]int
+}
+
+// Run simulates cfg.Calls calls under cfg.Policy. It returns an error if the policy
+// does not normalize or no model is given.
+func Run(cfg Config) (Report, error) {
+	if cfg.Model == nil {
+		return Report{}, errors.New("simulate: no model")
+	}
+	pol, err := cfg.Policy.Normalize()
+	if err != nil {
+		return Report{}, err
+	}
+	if cfg.Classifier == nil {
+		cfg.Classifier = classify.AlwaysRetryOnError{}
+	}
+	if cfg.Calls <= 0 {
+		cfg.Calls = DefaultCalls
+	}
+
+	s := &sim{
+		pol: pol,
+		cfg: cfg,
+		r:   rand.New(rand.NewPCG(cfg.Seed, cfg.Seed^0x9e3779b97f4a7c15)),
+		report: Report{
+			Calls:       cfg.Calls,
+			Attempts:    make(map[int]int),
+			BudgetUnits: make(map[string]int),
+		},
+	}
+	latencies := make([]time.Duration, cfg.Calls)
+	for i := range latencies {
+		latencies[i] = s.call()
+	}
+	s.report.Latency = stats(latencies)
+	s.report.SuccessRate = float64(s.report.Successes) / float64(cfg.Calls)
+	total := 0
+	for n, calls := range s.report.Attempts {
+		total += n * calls
+	}
+	s.report.Amplification = float64(total) / float64(cfg.Calls)
+	return s.report, nil
+}
+
+type sim struct {
+	pol    policy.EffectivePolicy
+	cfg    Config
+	r      *rand.Rand
+	report Report
+	script *script // Recorded attempts to replay instead of sampling Model (see Replay).
+}
+
+// call simulates one call and returns its latency.
+func (s *sim) call() time.Duration {
+	retry := s.pol.Retry
+	var deadline time.Duration // zero: none
+	if retry.OverallTimeout > 0 {
+		deadline = retry.OverallTimeout
+	}
+	maxAttempts := retry.MaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	now := time.Duration(0)
+	attempts := 0
+	backoff := retry.InitialBackoff
+	var reasons map[string]time.Duration
+	defer func() { s.report.Attempts[attempts]++ }()
+
+	for i := 0; i < maxAttempts; i++ {
+		end, out, n, timedOut := s.group(i, now, deadline)
+		attempts += n
+		now = end
+		if s.script != nil && s.script.missing > 0 && out.Kind != classify.OutcomeSuccess {
+			return now
+		}
+		if timedOut {
+			s.report.TimedOut++
+			return now
+		}
+		if out.Kind == classify.OutcomeSuccess {
+			s.report.Successes++
+			return now
+		}
+		retryable := out.Kind == classify.OutcomeRetryable || out.Kind == classify.OutcomeThrottled ||
+			(out.Kind == classify.OutcomePartial && !retry.AcceptPartial)
+		if !retryable || i == maxAttempts-1 {
+			return now
+		}
+
+		sleep := s.sleepFor(backoff, out, &reasons)
+		if deadline > 0 && now+sleep >= deadline {
+			s.report.TimedOut++
+			return deadline
+		}
+		now += sleep
+		backoff = nextBackoff(backoff, retry.BackoffMultiplier, retry.MaxBackoff)
+	}
+	return now
+}
+
+type running struct {
+	end time.Duration
+	out classify.Outcome
+}
+
+// group simulates retry step retryIdx starting at start: the primary attempt and any
+// hedges. It returns the step's end time and outcome, the number of attempts launched,
+// and whether the call hit its overall deadline.
+func (s *sim) group(retryIdx int, start, deadline time.Duration) (time.Duration, classify.Outcome, int, bool) {
+	pol := s.pol
+	maxHedges := 0
+	if pol.Hedge.Enabled {
+		maxHedges = pol.Hedge.MaxHedges
+	}
+
+	var active []running
+	launched := 0
+	launch := func(at time.Duration) {
+		ref := pol.Retry.Budget
+		if launched > 0 {
+			ref = pol.Hedge.Budget
+			s.report.Hedges++
+		}
+		s.consume(ref)
+		active = append(active, s.attempt(at, retryIdx, launched))
+		launched++
+	}
+	launch(start)
+
+	var last classify.Outcome
+	for {
+		// Earliest finishing attempt.
+		next := 0
+		for i := range active {
+			if active[i].end < active[next].end {
+				next = i
+			}
+		}
+		if launched <= maxHedges {
+			at := start + pol.Hedge.HedgeDelay*time.Duration(launched)
+			if at < active[next].end && (deadline == 0 || at < deadline) {
+				launch(at)
+				continue
+			}
+		}
+
+		done := active[next]
+		if deadline > 0 && done.end >= deadline {
+			return deadline, classify.Outcome{Kind: classify.OutcomeAbort, Reason: "overall_timeout"}, launched, true
+		}
+		active = append(active[:next], active[next+1:]...)
+		if done.out.Kind == classify.OutcomeSuccess {
+			return done.end, done.out, launched, false
+		}
+		last = done.out
+		terminal := done.out.Kind == classify.OutcomeNonRetryable || done.out.Kind == classify.OutcomeAbort
+		if len(active) == 0 || (pol.Hedge.CancelOnFirstTerminal && terminal) {
+			return done.end, last, launched, false
+		}
+	}
+}
+
+// attempt samples attempt hedgeIdx (0 for the primary) of retry step retryIdx, started
+// at start.
+func (s *sim) attempt(start time.Duration, retryIdx, hedgeIdx int) running {
+	if s.script != nil {
+		return s.script.attempt(start, retryIdx, hedgeIdx, s.pol.Retry.TimeoutPerAttempt)
+	}
+	sample := s.cfg.Model.Sample(s.r)
+	if sample.Latency < 0 {
+		sample.Latency = 0
+	}
+	if t := s.pol.Retry.TimeoutPerAttempt; t > 0 && sample.Latency > t {
+		sample = Sample{Latency: t, Err: context.DeadlineExceeded}
+	}
+	return running{end: start + sample.Latency, out: s.cfg.Classifier.Classify(nil, sample.Err)}
+}
+
+func (s *sim) consume(ref policy.BudgetRef) {
+	name := strings.TrimSpace(ref.Name)
+	if name == "" {
+		return
+	}
+	cost := ref.Cost
+	if cost <= 0 {
+		cost = 1
+	}
+	s.report.BudgetUnits[name] += cost
+}
+
+// sleepFor mirrors the executor's backoff: the classifier's override, or the backoff
+// with jitter, capped at MaxBackoff. Reasons with BackoffOverrides follow their own
+// curves, whose current points reasons holds.
+func (s *sim) sleepFor(backoff time.Duration, out classify.Outcome, reasons *map[string]time.Duration) time.Duration {
+	retry := s.pol.Retry
+	if spec, ok := retry.BackoffOverrides[out.Reason]; ok {
+		cur, seen := (*reasons)[out.Reason]
+		if !seen {
+			cur = spec.InitialBackoff
+		}
+		if *reasons == nil {
+			*reasons = make(map[string]time.Duration, len(retry.BackoffOverrides))
+		}
+		(*reasons)[out.Reason] = nextBackoff(cur, spec.BackoffMultiplier, spec.MaxBackoff)
+		backoff = cur
+		retry.MaxBackoff, retry.Jitter = spec.MaxBackoff, spec.Jitter
+	}
+	d := backoff
+	if out.BackoffOverride > 0 {
+		d = out.BackoffOverride
+	} else {
+		switch retry.Jitter {
+		case policy.JitterFull:
+			d = time.Duration(s.r.Float64() * float64(backoff))
+		case policy.JitterEqual:
+			half := float64(backoff) / 2
+			d = time.Duration(half + s.r.Float64()*half)
+		}
+	}
+	if d < 0 {
+		d = 0
+	}
+	if retry.MaxBackoff > 0 && d > retry.MaxBackoff {
+		d = retry.MaxBackoff
+	}
+	return d
+}
+
+func nextBackoff(current time.Duration, multiplier float64, max time.Duration) time.Duration {
+	next := time.Duration(float64(current) * multiplier)
+	if next < 0 {
+		next = 0
+	}
+	if max > 0 && next > max {
+		return max
+	}
+	return next
+}
+
+func stats(latencies []time.Duration) LatencyStats {
+	if len(latencies) == 0 {
+		return LatencyStats{}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	var sum time.Duration
+	for _, d := range latencies {
+		sum += d
+	}
+	at := func(p float64) time.Duration {
+		i := int(p * float64(len(latencies)-1))
+		return latencies[i]
+	}
+	return LatencyStats{
+		Mean: sum / time.Duration(len(latencies)),
+		P50:  at(0.50),
+		P90:  at(0.90),
+		P99:  at(0.99),
+		Max:  latencies[len(latencies)-1],
+	}
+}
@@ -0,0 +1,109 @@
+package simulate_test
+
+import (
+	"math"
+	"math/rand/v2"
+	"testing"
+	"time"
+
+	"github.com/aponysus/recourse/policy"
+	"github.com/aponysus/recourse/simulate"
+)
+
+func TestRun_RetriesUntilSuccess(t *testing.T) {
+	pol := policy.New("svc.Get",
+		policy.MaxAttempts(3),
+		policy.Backoff(10*time.Millisecond, time.Second, 2),
+		policy.Jitter(policy.JitterNone),
+		policy.Budget("retries"),
+	)
+	report, err := simulate.Run(simulate.Config{
+		Policy: pol,
+		Model:  simulate.Service{Latency: simulate.Fixed(5 * time.Millisecond), ErrorRate: 1},
+		Calls:  100,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Successes != 0 || report.Attempts[3] != 100 || report.Amplification != 3 {
+		t.Fatalf("report = %+v, want every call to exhaust 3 attempts", report)
+	}
+	// 3 attempts of 5ms plus 10ms and 20ms of backoff.
+	if report.Latency.P50 != 45*time.Millisecond || report.Latency.Max != 45*time.Millisecond {
+		t.Fatalf("latency = %+v, want 45ms", report.Latency)
+	}
+	if report.BudgetUnits["retries"] != 300 {
+		t.Fatalf("budget units = %v, want 300", report.BudgetUnits)
+	}
+}
+
+func TestRun_OverallTimeout(t *testing.T) {
+	pol := policy.New("svc.Get",
+		policy.MaxAttempts(5),
+		policy.ConstantBackoff(20*time.Millisecond),
+		policy.OverallTimeout(50*time.Millisecond),
+	)
+	report, err := simulate.Run(simulate.Config{
+		Policy: pol,
+		Model:  simulate.Service{Latency: simulate.Fixed(10 * time.Millisecond), ErrorRate: 1},
+		Calls:  10,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10ms + 20ms + 10ms, then the next backoff crosses the 50ms deadline.
+	if report.TimedOut != 10 || report.Attempts[2] != 10 || report.Latency.Max != 50*time.Millisecond {
+		t.Fatalf("report = %+v", report)
+	}
+}
+
+func TestRun_Hedging(t *testing.T) {
+	pol := policy.New("svc.Get",
+		policy.MaxAttempts(1),
+		policy.EnableHedging(),
+		policy.HedgeMaxAttempts(1),
+		policy.HedgeDelay(30*time.Millisecond),
+	)
+	// Primary attempts alternate between slow and fast.
+	slow := true
+	model := simulate.ModelFunc(func(*rand.Rand) simulate.Sample {
+		d := 10 * time.Millisecond
+		if slow {
+			d = 200 * time.Millisecond
+		}
+		slow = !slow
+		return simulate.Sample{Latency: d}
+	})
+	report, err := simulate.Run(simulate.Config{Policy: pol, Model: model, Calls: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The slow primary is overtaken by the hedge launched at 30ms.
+	if report.Successes != 1 || report.Hedges != 1 || report.Latency.Max != 40*time.Millisecond {
+		t.Fatalf("report = %+v, want the hedge to win at 40ms", report)
+	}
+}
+
+func TestRun_Deterministic(t *testing.T) {
+	cfg := simulate.Config{
+		Policy: policy.New("svc.Get", policy.MaxAttempts(3), policy.Jitter(policy.JitterFull)),
+		Model:  simulate.Service{Latency: simulate.Percentiles(20*time.Millisecond, 200*time.Millisecond), ErrorRate: 0.2},
+		Calls:  2000,
+		Seed:   42,
+	}
+	a, err := simulate.Run(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := simulate.Run(cfg)
+	if a.Latency != b.Latency || a.Successes != b.Successes {
+		t.Fatalf("runs differ: %+v vs %+v", a, b)
+	}
+	// 0.2^3 of calls fail every attempt.
+	if rate := 1 - a.SuccessRate; math.Abs(rate-0.008) > 0.006 {
+		t.Fatalf("failure rate = %v, want about 0.008", rate)
+	}
+	if a.Latency.P50 < 15*time.Millisecond || a.Latency.P50 > 30*time.Millisecond {
+		t.Fatalf("p50 = %v, want about 20ms", a.Latency.P50)
+	}
+}